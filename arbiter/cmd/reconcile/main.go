@@ -27,13 +27,13 @@ func main() {
 		log.Fatal("GITHUB_REPOSITORY is required")
 	}
 
-	issueNumStr := os.Getenv("ISSUE_NUMBER")
-	if issueNumStr == "" {
-		log.Fatal("ISSUE_NUMBER is required")
-	}
-	issueNum, err := strconv.Atoi(issueNumStr)
-	if err != nil {
-		log.Fatalf("invalid ISSUE_NUMBER: %v", err)
+	dryRun := false
+	if v := os.Getenv("DRY_RUN"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("invalid DRY_RUN: %v", err)
+		}
+		dryRun = b
 	}
 
 	parts := strings.SplitN(repoFull, "/", 2)
@@ -42,9 +42,16 @@ func main() {
 	}
 	owner, repo := parts[0], parts[1]
 
-	client := github.NewClient(token)
+	// Actions sets GITHUB_API_URL on both github.com and Enterprise Server.
+	client := github.NewClientWithBaseURL(token, os.Getenv("GITHUB_API_URL"))
 	ctx := context.Background()
 
+	issueNums, err := issueNumbers(ctx, client, owner, repo,
+		os.Getenv("ISSUE_NUMBER"), os.Getenv("ISSUE_NUMBERS"), os.Getenv("LABEL"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Check repo visibility to determine trusted-author filtering.
 	filterUntrusted := false
 	ghRepo, err := client.GetRepo(ctx, owner, repo)
@@ -55,42 +62,128 @@ func main() {
 		log.Printf("public repo detected, filtering untrusted author comments")
 	}
 
+	failed := 0
+	for _, issueNum := range issueNums {
+		if err := reconcileIssue(ctx, client, owner, repo, issueNum, filterUntrusted, dryRun); err != nil {
+			log.Printf("issue #%d: %v", issueNum, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("%d of %d issues failed to reconcile", failed, len(issueNums))
+	}
+}
+
+// issueNumbers returns the issues to reconcile: ISSUE_NUMBER if set, else
+// the comma-separated ISSUE_NUMBERS, else every open issue carrying label
+// (the default tracking label if empty).
+func issueNumbers(ctx context.Context, client github.Client, owner, repo, single, list, label string) ([]int, error) {
+	if single != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(single))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ISSUE_NUMBER: %w", err)
+		}
+		return []int{n}, nil
+	}
+	if list != "" {
+		var nums []int
+		for _, field := range strings.Split(list, ",") {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ISSUE_NUMBERS entry %q: %w", field, err)
+			}
+			nums = append(nums, n)
+		}
+		return nums, nil
+	}
+
+	if label == "" {
+		label = github.TrackingLabel
+	}
+	issues, _, err := client.ListIssues(ctx, owner, repo, github.ListOpts{Labels: label, State: "open"})
+	if err != nil {
+		return nil, fmt.Errorf("list %s issues: %w", label, err)
+	}
+	nums := make([]int, 0, len(issues))
+	for _, iss := range issues {
+		nums = append(nums, iss.Number)
+	}
+	log.Printf("reconciling %d open issues labeled %s", len(nums), label)
+	return nums, nil
+}
+
+// reconcileIssue reconciles one issue and writes the result back: the new
+// body, then the open or closed state. With dryRun it prints what it would
+// write instead and makes no changes.
+func reconcileIssue(ctx context.Context, client github.Client, owner, repo string, issueNum int, filterUntrusted, dryRun bool) error {
 	newBody, replayed, err := reconcile(ctx, client, owner, repo, issueNum, filterUntrusted)
 	if err != nil {
-		log.Fatalf("reconcile: %v", err)
+		return fmt.Errorf("reconcile: %w", err)
 	}
 	if replayed == nil {
-		return
+		return nil
+	}
+
+	if dryRun {
+		ghIssue, err := client.GetIssue(ctx, owner, repo, issueNum)
+		if err != nil {
+			return fmt.Errorf("get issue for state sync: %w", err)
+		}
+		state := targetState(replayed, ghIssue)
+		if state == "" {
+			state = ghIssue.State + " (unchanged)"
+		}
+		fmt.Printf("dry run: issue #%d: status=%s, priority=%d, owner=%s, state=%s\n",
+			issueNum, replayed.Status, replayed.Priority, replayed.Owner, state)
+		if newBody == ghIssue.Body {
+			fmt.Println("body unchanged")
+		} else {
+			fmt.Printf("new body:\n%s\n", newBody)
+		}
+		return nil
 	}
 
 	if err := client.UpdateIssueBody(ctx, owner, repo, issueNum, newBody); err != nil {
-		log.Fatalf("update issue body: %v", err)
+		return fmt.Errorf("update issue body: %w", err)
 	}
 
 	// Close or reopen the GitHub issue to match replayed state.
 	ghIssue, err := client.GetIssue(ctx, owner, repo, issueNum)
 	if err != nil {
-		log.Fatalf("get issue for state sync: %v", err)
+		return fmt.Errorf("get issue for state sync: %w", err)
 	}
 	if err := syncIssueState(ctx, client, owner, repo, issueNum, replayed, ghIssue); err != nil {
-		log.Fatalf("sync issue state: %v", err)
+		return fmt.Errorf("sync issue state: %w", err)
 	}
 
 	fmt.Printf("reconciled issue #%d: status=%s, priority=%d, owner=%s\n",
 		issueNum, replayed.Status, replayed.Priority, replayed.Owner)
+	return nil
 }
 
-// syncIssueState closes or reopens the GitHub issue to match the replayed state.
-func syncIssueState(ctx context.Context, client github.Client, owner, repo string, issueNum int, replayed *model.Issue, ghIssue *github.GitHubIssue) error {
+// targetState returns the GitHub state ("open" or "closed") the issue must
+// move to to match the replayed state, or "" if it already matches.
+func targetState(replayed *model.Issue, ghIssue *github.GitHubIssue) string {
 	if replayed.Status == model.StatusClosed || replayed.Status == model.StatusDeleted {
 		if ghIssue.State == "open" {
-			return client.UpdateIssueState(ctx, owner, repo, issueNum, "closed")
+			return "closed"
 		}
 	} else {
 		if ghIssue.State == "closed" {
-			return client.UpdateIssueState(ctx, owner, repo, issueNum, "open")
+			return "open"
 		}
 	}
+	return ""
+}
+
+// syncIssueState closes or reopens the GitHub issue to match the replayed state.
+func syncIssueState(ctx context.Context, client github.Client, owner, repo string, issueNum int, replayed *model.Issue, ghIssue *github.GitHubIssue) error {
+	if state := targetState(replayed, ghIssue); state != "" {
+		return client.UpdateIssueState(ctx, owner, repo, issueNum, state)
+	}
 	return nil
 }
 
@@ -116,6 +209,9 @@ func reconcile(ctx context.Context, client github.Client, owner, repo string, is
 		if err != nil || ev == nil {
 			continue // Skip non-boxofrocks comments
 		}
+		if ev.Action == model.ActionSnapshot {
+			continue // Snapshots are local to a daemon's database
+		}
 		ev.ID = c.ID // Use comment ID for ordering
 		ev.IssueID = issueNum
 		commentID := c.ID
@@ -157,17 +253,6 @@ func reconcile(ctx context.Context, client github.Client, owner, repo string, is
 	}
 
 	// 5. Build metadata and write back
-	meta := &github.MetadataBlock{
-		Status:    string(replayed.Status),
-		Priority:  replayed.Priority,
-		IssueType: string(replayed.IssueType),
-		Owner:     replayed.Owner,
-		Labels:    replayed.Labels,
-	}
-	if meta.Labels == nil {
-		meta.Labels = []string{}
-	}
-
-	newBody := github.RenderBody(humanText, meta)
+	newBody := github.RenderBody(humanText, github.IssueMetadata(replayed))
 	return newBody, replayed, nil
 }
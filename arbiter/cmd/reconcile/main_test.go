@@ -16,12 +16,15 @@ import (
 type mockClient struct {
 	comments     []*github.GitHubComment
 	issue        *github.GitHubIssue
-	updated      string // captured body from UpdateIssueBody
-	updatedState string // captured state from UpdateIssueState
+	issues       []*github.GitHubIssue // returned by ListIssues
+	listOpts     github.ListOpts       // captured opts from ListIssues
+	updated      string                // captured body from UpdateIssueBody
+	updatedState string                // captured state from UpdateIssueState
 }
 
 func (m *mockClient) ListIssues(ctx context.Context, owner, repo string, opts github.ListOpts) ([]*github.GitHubIssue, string, error) {
-	return nil, "", nil
+	m.listOpts = opts
+	return m.issues, "", nil
 }
 
 func (m *mockClient) GetIssue(ctx context.Context, owner, repo string, number int) (*github.GitHubIssue, error) {
@@ -48,11 +51,31 @@ func (m *mockClient) CreateComment(ctx context.Context, owner, repo string, numb
 	return &github.GitHubComment{ID: 1, Body: body, CreatedAt: time.Now()}, nil
 }
 
+func (m *mockClient) GetComment(ctx context.Context, owner, repo string, commentID int) (*github.GitHubComment, error) {
+	return nil, fmt.Errorf("comment %d not found", commentID)
+}
+
 func (m *mockClient) AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) error {
 	return nil
 }
 
-func (m *mockClient) CreateLabel(ctx context.Context, owner, repo, name, color, description string) error {
+func (m *mockClient) AddAssignees(ctx context.Context, owner, repo string, number int, logins []string) error {
+	return nil
+}
+
+func (m *mockClient) RemoveAssignees(ctx context.Context, owner, repo string, number int, logins []string) error {
+	return nil
+}
+
+func (m *mockClient) CreateLabel(ctx context.Context, owner, repo, name, color, description string) (bool, error) {
+	return true, nil
+}
+
+func (m *mockClient) UpdateLabel(ctx context.Context, owner, repo, name, color, description string) error {
+	return nil
+}
+
+func (m *mockClient) RemoveLabelFromIssue(ctx context.Context, owner, repo string, number int, label string) error {
 	return nil
 }
 
@@ -65,6 +88,10 @@ func (m *mockClient) GetRepo(ctx context.Context, owner, repo string) (*github.G
 	return &github.GitHubRepo{Private: true}, nil
 }
 
+func (m *mockClient) ListCollaborators(ctx context.Context, owner, repo string) ([]string, error) {
+	return nil, nil
+}
+
 func (m *mockClient) GetRateLimit() github.RateLimit {
 	return github.RateLimit{Remaining: 5000, Reset: time.Now().Add(time.Hour)}
 }
@@ -401,3 +428,61 @@ func TestReconcileFilterUntrustedAuthors(t *testing.T) {
 		t.Errorf("owner: want bob (trusted assign applied), got %s", replayed.Owner)
 	}
 }
+
+func TestReconcileIssueDryRunWritesNothing(t *testing.T) {
+	t0 := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	newMock := func() *mockClient {
+		return &mockClient{
+			comments: []*github.GitHubComment{
+				makeComment(1, model.ActionCreate, makeCreatePayload("Dry", ""), t0),
+				makeComment(2, model.ActionClose, makeStatusPayload(model.StatusClosed), t0.Add(time.Hour)),
+			},
+			issue: &github.GitHubIssue{Number: 1, Title: "Dry", Body: "", State: "open"},
+		}
+	}
+
+	mc := newMock()
+	if err := reconcileIssue(context.Background(), mc, "owner", "repo", 1, false, true); err != nil {
+		t.Fatalf("reconcileIssue dry run: %v", err)
+	}
+	if mc.updated != "" || mc.updatedState != "" {
+		t.Errorf("dry run wrote to GitHub: body %q, state %q", mc.updated, mc.updatedState)
+	}
+
+	mc = newMock()
+	if err := reconcileIssue(context.Background(), mc, "owner", "repo", 1, false, false); err != nil {
+		t.Fatalf("reconcileIssue: %v", err)
+	}
+	if !strings.Contains(mc.updated, `"status":"closed"`) || mc.updatedState != "closed" {
+		t.Errorf("expected closed body and state, got body %q, state %q", mc.updated, mc.updatedState)
+	}
+}
+
+func TestIssueNumbers(t *testing.T) {
+	ctx := context.Background()
+	mc := &mockClient{issues: []*github.GitHubIssue{{Number: 4}, {Number: 9}}}
+
+	nums, err := issueNumbers(ctx, mc, "owner", "repo", "7", "1,2", "")
+	if err != nil || len(nums) != 1 || nums[0] != 7 {
+		t.Errorf("ISSUE_NUMBER: got %v, %v; want [7]", nums, err)
+	}
+	nums, err = issueNumbers(ctx, mc, "owner", "repo", "", " 1, 2,,3 ", "")
+	if err != nil || fmt.Sprint(nums) != "[1 2 3]" {
+		t.Errorf("ISSUE_NUMBERS: got %v, %v; want [1 2 3]", nums, err)
+	}
+	if _, err := issueNumbers(ctx, mc, "owner", "repo", "", "1,x", ""); err == nil {
+		t.Error("expected an error for a non-numeric ISSUE_NUMBERS entry")
+	}
+
+	nums, err = issueNumbers(ctx, mc, "owner", "repo", "", "", "")
+	if err != nil || fmt.Sprint(nums) != "[4 9]" {
+		t.Errorf("all issues: got %v, %v; want [4 9]", nums, err)
+	}
+	if mc.listOpts.Labels != github.TrackingLabel || mc.listOpts.State != "open" {
+		t.Errorf("expected open issues labeled %s, got %+v", github.TrackingLabel, mc.listOpts)
+	}
+	issueNumbers(ctx, mc, "owner", "repo", "", "", "tracked")
+	if mc.listOpts.Labels != "tracked" {
+		t.Errorf("expected the LABEL override, got %q", mc.listOpts.Labels)
+	}
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+)
+
+func runAbandon(args []string, gf globalFlags) error {
+	const usage = "usage: bor abandon <id> --reason R [--keep-status]"
+	if len(args) < 1 {
+		return fmt.Errorf(usage)
+	}
+
+	id, err := issueID(gf, args[0])
+	if err != nil {
+		return fmt.Errorf("invalid issue id %q: %w", args[0], err)
+	}
+
+	fs := flag.NewFlagSet("abandon", flag.ContinueOnError)
+	reason := fs.String("reason", "", "Why the issue is being returned to the pool (required)")
+	keepStatus := fs.Bool("keep-status", false, "Leave an in_progress issue in_progress instead of reopening it")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *reason == "" {
+		return fmt.Errorf(usage)
+	}
+
+	client := newClient(gf)
+
+	issue, err := client.AbandonIssue(id, *reason, *keepStatus)
+	if err != nil {
+		return fmt.Errorf("abandon issue: %w", err)
+	}
+
+	printIssue(issue, gf.pretty)
+	return nil
+}
@@ -1,6 +1,19 @@
 package cli
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
+
+// stringsFlag is a repeatable string flag: each occurrence appends a value.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
 
 // reorderArgs moves flag arguments before positional arguments so that
 // Go's flag package (which stops at the first non-flag) parses them all.
@@ -33,3 +46,17 @@ func reorderArgs(args []string) []string {
 	}
 	return append(flags, positional...)
 }
+
+// issueID resolves an issue argument to its ID. The argument is a bare ID,
+// or one prefixed with the repo's key prefix such as BOR-42, which the
+// daemon resolves and checks against the issue's repo.
+func issueID(gf globalFlags, ref string) (int, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, nil
+	}
+	issue, err := newClient(gf).GetIssueRef(ref)
+	if err != nil {
+		return 0, err
+	}
+	return issue.ID, nil
+}
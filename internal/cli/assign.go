@@ -1,16 +1,13 @@
 package cli
 
-import (
-	"fmt"
-	"strconv"
-)
+import "fmt"
 
 func runAssign(args []string, gf globalFlags) error {
 	if len(args) < 2 {
 		return fmt.Errorf("usage: bor assign <id> <owner>")
 	}
 
-	id, err := strconv.Atoi(args[0])
+	id, err := issueID(gf, args[0])
 	if err != nil {
 		return fmt.Errorf("invalid issue id %q: %w", args[0], err)
 	}
@@ -6,11 +6,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jmaddaus/boxofrocks/internal/config"
+	"github.com/jmaddaus/boxofrocks/internal/daemon"
 	"github.com/jmaddaus/boxofrocks/internal/model"
+	"github.com/jmaddaus/boxofrocks/internal/store"
 )
 
 // Client is an HTTP client wrapper for communicating with the daemon.
@@ -18,6 +23,11 @@ type Client struct {
 	baseURL    string
 	http       *http.Client
 	workingDir string // sent as X-Working-Dir for path-based repo resolution
+	agent      string // sent as X-Agent; what owner=@me resolves to
+	authToken  string // sent as a bearer token when the daemon requires one
+
+	warnOut       io.Writer // destination for API version skew warnings
+	versionWarned bool      // only warn about version skew once per client
 }
 
 // NewClient creates a new Client targeting the given daemon host.
@@ -27,12 +37,46 @@ func NewClient(host string) *Client {
 	return &Client{
 		baseURL:    host,
 		workingDir: wd,
+		agent:      os.Getenv("BOR_AGENT"),
+		authToken:  authToken(),
+		warnOut:    os.Stderr,
 		http: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
 }
 
+// authToken returns the daemon auth token from BOR_AUTH_TOKEN, or else from
+// the local daemon config.
+func authToken() string {
+	if token := os.Getenv("BOR_AUTH_TOKEN"); token != "" {
+		return token
+	}
+	if cfg, err := config.Load(); err == nil {
+		return cfg.AuthToken
+	}
+	return ""
+}
+
+// checkAPIVersion warns once if the daemon advertises an API version that
+// differs from the one this CLI was built against. Daemons predating the
+// header are not flagged.
+func (c *Client) checkAPIVersion(resp *http.Response) {
+	if c.versionWarned || c.warnOut == nil {
+		return
+	}
+	h := resp.Header.Get(daemon.APIVersionHeader)
+	if h == "" {
+		return
+	}
+	v, err := strconv.Atoi(h)
+	if err != nil || v == daemon.APIVersion {
+		return
+	}
+	c.versionWarned = true
+	fmt.Fprintf(c.warnOut, "warning: daemon API version %d differs from CLI API version %d; restart the daemon with: bor daemon stop && bor daemon start\n", v, daemon.APIVersion)
+}
+
 // Do executes an HTTP request to the daemon and returns the response.
 // If body is non-nil it is JSON-encoded.
 func (c *Client) Do(method, path string, body interface{}) (*http.Response, error) {
@@ -57,6 +101,12 @@ func (c *Client) Do(method, path string, body interface{}) (*http.Response, erro
 	if c.workingDir != "" {
 		req.Header.Set("X-Working-Dir", c.workingDir)
 	}
+	if c.agent != "" {
+		req.Header.Set(daemon.AgentHeader, c.agent)
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
 
 	resp, err := c.http.Do(req)
 	if err != nil {
@@ -65,6 +115,7 @@ func (c *Client) Do(method, path string, body interface{}) (*http.Response, erro
 		}
 		return nil, fmt.Errorf("request failed (is the daemon running?): %w", err)
 	}
+	c.checkAPIVersion(resp)
 	return resp, nil
 }
 
@@ -126,8 +177,10 @@ type CreateIssueRequest struct {
 	Title       string   `json:"title"`
 	Description string   `json:"description,omitempty"`
 	Priority    *int     `json:"priority,omitempty"`
+	Estimate    *int     `json:"estimate,omitempty"`
 	IssueType   string   `json:"issue_type,omitempty"`
 	Labels      []string `json:"labels,omitempty"`
+	ParentID    *int     `json:"parent_id,omitempty"`
 }
 
 // CreateIssue creates a new issue in the given repo.
@@ -147,11 +200,55 @@ func (c *Client) CreateIssue(repo string, req CreateIssueRequest) (*model.Issue,
 	return &issue, nil
 }
 
+// BulkCreateResult holds the response from POST /issues/bulk.
+type BulkCreateResult struct {
+	Created int              `json:"created"`
+	Failed  int              `json:"failed"`
+	Results []BulkCreateItem `json:"results"`
+}
+
+// BulkCreateItem is the outcome of one entry of a bulk create, in request
+// order: the created issue, or why the entry was rejected.
+type BulkCreateItem struct {
+	Index int          `json:"index"`
+	Issue *model.Issue `json:"issue,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// BulkCreateIssues creates every issue in issues, a JSON array of create
+// requests, in one transaction. Invalid entries are reported in the result
+// without stopping the rest.
+func (c *Client) BulkCreateIssues(repo string, issues []byte) (*BulkCreateResult, error) {
+	if !json.Valid(issues) {
+		return nil, fmt.Errorf("issues are not valid JSON")
+	}
+	path := "/issues/bulk"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("POST", path, json.RawMessage(issues))
+	if err != nil {
+		return nil, err
+	}
+	var result BulkCreateResult
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // ListOpts holds query parameters for listing issues.
 type ListOpts struct {
-	Status   string
-	Priority string
-	All      bool
+	Status         string
+	Priority       string
+	All            bool
+	IncludeSnoozed bool
+	Owner          string   // "@me" resolves to $BOR_AGENT or the daemon's identity
+	Labels         []string // issues must carry every label
+	Sort           string   // priority (default), created or updated
+	Desc           bool
+	Limit          int // 0 uses the daemon's default page size
+	Offset         int
 }
 
 // ListIssues returns issues for the given repo, filtered by opts.
@@ -170,6 +267,27 @@ func (c *Client) ListIssues(repo string, opts ListOpts) ([]*model.Issue, error)
 	if opts.All {
 		params += "all=true&"
 	}
+	if opts.IncludeSnoozed {
+		params += "include_snoozed=true&"
+	}
+	if opts.Owner != "" {
+		params += "owner=" + url.QueryEscape(opts.Owner) + "&"
+	}
+	for _, label := range opts.Labels {
+		params += "label=" + url.QueryEscape(label) + "&"
+	}
+	if opts.Sort != "" {
+		params += "sort=" + url.QueryEscape(opts.Sort) + "&"
+	}
+	if opts.Desc {
+		params += "order=desc&"
+	}
+	if opts.Limit > 0 {
+		params += "limit=" + strconv.Itoa(opts.Limit) + "&"
+	}
+	if opts.Offset > 0 {
+		params += "offset=" + strconv.Itoa(opts.Offset) + "&"
+	}
 	path += params
 
 	resp, err := c.Do("GET", path, nil)
@@ -185,7 +303,13 @@ func (c *Client) ListIssues(repo string, opts ListOpts) ([]*model.Issue, error)
 
 // GetIssue retrieves a single issue by ID.
 func (c *Client) GetIssue(id int) (*model.Issue, error) {
-	path := fmt.Sprintf("/issues/%d", id)
+	return c.GetIssueRef(strconv.Itoa(id))
+}
+
+// GetIssueRef fetches an issue by a bare ID or a key-prefixed reference
+// such as BOR-42.
+func (c *Client) GetIssueRef(ref string) (*model.Issue, error) {
+	path := "/issues/" + url.PathEscape(ref)
 	resp, err := c.Do("GET", path, nil)
 	if err != nil {
 		return nil, err
@@ -236,6 +360,137 @@ func (c *Client) AssignIssue(id int, owner string) (*model.Issue, error) {
 	return &issue, nil
 }
 
+// AbandonIssue unassigns an issue and records why. Unless keepStatus is
+// set, an in_progress issue is moved back to open.
+func (c *Client) AbandonIssue(id int, reason string, keepStatus bool) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/%d/abandon", id)
+	body := map[string]interface{}{"reason": reason, "keep_status": keepStatus}
+	resp, err := c.Do("POST", path, body)
+	if err != nil {
+		return nil, err
+	}
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// ReopenIssue moves a closed issue back to open.
+func (c *Client) ReopenIssue(id int) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/%d/reopen", id)
+	resp, err := c.Do("POST", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// RestoreIssue brings a deleted issue back as open.
+func (c *Client) RestoreIssue(id int) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/%d/restore", id)
+	resp, err := c.Do("POST", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// AddLabel adds one label to an issue with a label_add event.
+func (c *Client) AddLabel(id int, label string) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/%d/labels", id)
+	resp, err := c.Do("POST", path, map[string]string{"label": label})
+	if err != nil {
+		return nil, err
+	}
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// RemoveLabel removes one label from an issue with a label_remove event.
+func (c *Client) RemoveLabel(id int, label string) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/%d/labels?label=%s", id, url.QueryEscape(label))
+	resp, err := c.Do("DELETE", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// AddDependency marks an issue as blocked by another with an add_dependency
+// event.
+func (c *Client) AddDependency(id, blockerID int) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/%d/dependencies", id)
+	resp, err := c.Do("POST", path, map[string]int{"blocker_id": blockerID})
+	if err != nil {
+		return nil, err
+	}
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// RemoveDependency drops one of an issue's blockers with a remove_dependency
+// event.
+func (c *Client) RemoveDependency(id, blockerID int) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/%d/dependencies?blocker_id=%d", id, blockerID)
+	resp, err := c.Do("DELETE", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// WatchIssue adds a watcher to an issue with a watch event. watcher may be
+// "@me".
+func (c *Client) WatchIssue(id int, watcher string) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/%d/watch", id)
+	resp, err := c.Do("POST", path, map[string]string{"watcher": watcher})
+	if err != nil {
+		return nil, err
+	}
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// UnwatchIssue removes a watcher from an issue with an unwatch event.
+func (c *Client) UnwatchIssue(id int, watcher string) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/%d/watch?watcher=%s", id, url.QueryEscape(watcher))
+	resp, err := c.Do("DELETE", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
 // CommentIssue adds a comment to an issue.
 func (c *Client) CommentIssue(id int, comment string) (*model.Issue, error) {
 	path := fmt.Sprintf("/issues/%d/comment", id)
@@ -251,6 +506,22 @@ func (c *Client) CommentIssue(id int, comment string) (*model.Issue, error) {
 	return &issue, nil
 }
 
+// SnoozeIssue hides an issue from next/list until the given time.
+// A nil until clears the snooze.
+func (c *Client) SnoozeIssue(id int, until *time.Time) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/%d/snooze", id)
+	body := map[string]interface{}{"until": until}
+	resp, err := c.Do("POST", path, body)
+	if err != nil {
+		return nil, err
+	}
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
 // NextIssue retrieves the highest-priority open issue for the given repo.
 func (c *Client) NextIssue(repo string) (*model.Issue, error) {
 	path := "/issues/next"
@@ -268,117 +539,723 @@ func (c *Client) NextIssue(repo string) (*model.Issue, error) {
 	return &issue, nil
 }
 
-// Health pings the daemon health endpoint.
-func (c *Client) Health() (map[string]interface{}, error) {
-	resp, err := c.Do("GET", "/health", nil)
+// NextIssues retrieves up to n open unassigned issues in next order.
+func (c *Client) NextIssues(repo string, n int) ([]*model.Issue, error) {
+	path := fmt.Sprintf("/issues/next?count=%d", n)
+	if repo != "" {
+		path += "&repo=" + repo
+	}
+	resp, err := c.Do("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
-	var result map[string]interface{}
-	if err := decodeOrError(resp, &result); err != nil {
+	var issues []*model.Issue
+	if err := decodeOrError(resp, &issues); err != nil {
 		return nil, err
 	}
-	return result, nil
+	return issues, nil
 }
 
-// UpdateRepo updates repo settings (e.g., trusted_authors_only).
-func (c *Client) UpdateRepo(repo string, fields map[string]interface{}) (*model.RepoConfig, error) {
-	path := "/repos"
+// ClaimIssue assigns the next issue to owner in one step and returns it.
+// owner may be "@me".
+func (c *Client) ClaimIssue(repo, owner string) (*model.Issue, error) {
+	path := "/issues/claim?owner=" + url.QueryEscape(owner)
 	if repo != "" {
-		path += "?repo=" + repo
+		path += "&repo=" + repo
 	}
-	resp, err := c.Do("PATCH", path, fields)
+	resp, err := c.Do("POST", path, nil)
 	if err != nil {
 		return nil, err
 	}
-	var rc model.RepoConfig
-	if err := decodeOrError(resp, &rc); err != nil {
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
 		return nil, err
 	}
-	return &rc, nil
+	return &issue, nil
 }
 
-// AddRepoPath registers a local path (worktree) for a repo.
-func (c *Client) AddRepoPath(repo string, body map[string]interface{}) (*model.RepoConfig, error) {
-	path := "/repos/paths"
+// NextIssueForOwner returns the unfinished issue the owner should resume,
+// in-progress first. owner may be "@me".
+func (c *Client) NextIssueForOwner(repo, owner string) (*model.Issue, error) {
+	path := "/issues/next?owner=" + url.QueryEscape(owner)
 	if repo != "" {
-		path += "?repo=" + repo
+		path += "&repo=" + repo
 	}
-	resp, err := c.Do("POST", path, body)
+	resp, err := c.Do("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
-	var rc model.RepoConfig
-	if err := decodeOrError(resp, &rc); err != nil {
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
 		return nil, err
 	}
-	return &rc, nil
+	return &issue, nil
 }
 
-// RemoveRepoPath removes a local path (worktree) from a repo.
-func (c *Client) RemoveRepoPath(repo string, body map[string]interface{}) (*model.RepoConfig, error) {
-	path := "/repos/paths"
+// NextIssueWithinBudget retrieves the highest-priority open issue whose
+// estimate fits within budget.
+func (c *Client) NextIssueWithinBudget(repo string, budget int) (*model.Issue, error) {
+	path := fmt.Sprintf("/issues/next?budget=%d", budget)
 	if repo != "" {
-		path += "?repo=" + repo
+		path += "&repo=" + repo
 	}
-	resp, err := c.Do("DELETE", path, body)
+	resp, err := c.Do("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
-	var rc model.RepoConfig
-	if err := decodeOrError(resp, &rc); err != nil {
+	var issue model.Issue
+	if err := decodeOrError(resp, &issue); err != nil {
 		return nil, err
 	}
-	return &rc, nil
+	return &issue, nil
 }
 
-// ForceSync triggers a sync for the given repo.
-func (c *Client) ForceSync(repo string) error {
-	path := "/sync"
+// NextExplanation holds the response from the next endpoint with explain=true.
+type NextExplanation struct {
+	Next     *model.Issue         `json:"next"`
+	Reason   string               `json:"reason,omitempty"`
+	Excluded store.NextExclusions `json:"excluded"`
+}
+
+// NextIssueExplained is like NextIssue (or NextIssueWithinBudget, when
+// budget >= 0) but, when nothing is eligible, reports why instead of failing.
+func (c *Client) NextIssueExplained(repo string, budget int) (*NextExplanation, error) {
+	path := "/issues/next?explain=true"
+	if budget >= 0 {
+		path += fmt.Sprintf("&budget=%d", budget)
+	}
 	if repo != "" {
-		path += "?repo=" + repo
+		path += "&repo=" + repo
 	}
-	resp, err := c.Do("POST", path, nil)
+	resp, err := c.Do("GET", path, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return decodeOrError(resp, nil)
+	var result NextExplanation
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
 }
 
-// ImportIssues labels all open GitHub issues with "boxofrocks" and triggers a sync.
-func (c *Client) ImportIssues(repo string) (*ImportResult, error) {
-	path := "/repos/import"
+// SearchIssues returns issues whose title or description matches query.
+func (c *Client) SearchIssues(repo, query string) ([]*model.Issue, error) {
+	path := "/issues/search?q=" + url.QueryEscape(query)
 	if repo != "" {
-		path += "?repo=" + repo
+		path += "&repo=" + repo
 	}
-	resp, err := c.Do("POST", path, nil)
+	resp, err := c.Do("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
-	var result ImportResult
+	var issues []*model.Issue
+	if err := decodeOrError(resp, &issues); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// TrendingResult holds the response from the trending endpoint.
+type TrendingResult struct {
+	Days   int                    `json:"days"`
+	Since  string                 `json:"since"`
+	Issues []*store.IssueActivity `json:"issues"`
+}
+
+// TrendingIssues ranks open issues by activity over the last days.
+func (c *Client) TrendingIssues(repo string, days, limit int) (*TrendingResult, error) {
+	path := fmt.Sprintf("/issues/trending?days=%d&limit=%d", days, limit)
+	if repo != "" {
+		path += "&repo=" + repo
+	}
+	resp, err := c.Do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result TrendingResult
 	if err := decodeOrError(resp, &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
 }
 
-// ImportResult holds the response from the import endpoint.
-type ImportResult struct {
-	Status  string `json:"status"`
-	Repo    string `json:"repo"`
-	Labeled int    `json:"labeled"`
-	Total   int    `json:"total"`
+// StaleResult holds the response from the stale endpoint.
+type StaleResult struct {
+	Days   int            `json:"days"`
+	Before string         `json:"before"`
+	Issues []*model.Issue `json:"issues"`
 }
 
-// ForceSyncFull triggers a full replay sync for the given repo.
-func (c *Client) ForceSyncFull(repo string) error {
-	path := "/sync?full=true"
+// StaleIssues lists open and blocked issues not updated in the last days.
+func (c *Client) StaleIssues(repo string, days int) (*StaleResult, error) {
+	path := fmt.Sprintf("/issues/stale?days=%d", days)
 	if repo != "" {
 		path += "&repo=" + repo
 	}
-	resp, err := c.Do("POST", path, nil)
+	resp, err := c.Do("GET", path, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return decodeOrError(resp, nil)
+	var result StaleResult
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PlanResult holds the response from the plan endpoint.
+type PlanResult struct {
+	Budget int            `json:"budget"`
+	Total  int            `json:"total"`
+	Issues []*model.Issue `json:"issues"`
+}
+
+// PlanIssues returns a greedy set of open issues whose estimates fit within budget.
+func (c *Client) PlanIssues(repo string, budget int) (*PlanResult, error) {
+	path := fmt.Sprintf("/issues/plan?budget=%d", budget)
+	if repo != "" {
+		path += "&repo=" + repo
+	}
+	resp, err := c.Do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result PlanResult
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ReorderIssues assigns priorities to the given issues in order and returns
+// them with their new priorities.
+func (c *Client) ReorderIssues(repo string, order []int) ([]*model.Issue, error) {
+	path := "/issues/reorder"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("POST", path, map[string]interface{}{"order": order})
+	if err != nil {
+		return nil, err
+	}
+	var issues []*model.Issue
+	if err := decodeOrError(resp, &issues); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// ChangedIssues returns issues updated at or after since, including deleted
+// issues so a local cache can evict them.
+func (c *Client) ChangedIssues(repo string, since time.Time) ([]*model.Issue, error) {
+	path := "/issues/changed?since=" + url.QueryEscape(since.UTC().Format(time.RFC3339))
+	if repo != "" {
+		path += "&repo=" + repo
+	}
+	resp, err := c.Do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var issues []*model.Issue
+	if err := decodeOrError(resp, &issues); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// Health pings the daemon health endpoint.
+func (c *Client) Health() (map[string]interface{}, error) {
+	resp, err := c.Do("GET", "/health", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// HealthInfo is the response from GET /health. SyncStatus is keyed by repo
+// full name and is absent when the daemon runs without sync.
+type HealthInfo struct {
+	Status     string                  `json:"status"`
+	Mode       string                  `json:"mode"`
+	Uptime     string                  `json:"uptime,omitempty"`
+	Repos      []string                `json:"repos"`
+	SyncStatus map[string]RepoSyncInfo `json:"sync_status,omitempty"`
+}
+
+// RepoSyncInfo is one repo's entry in the /health sync_status block.
+type RepoSyncInfo struct {
+	LastSync       string `json:"last_sync,omitempty"`
+	PendingEvents  int    `json:"pending_events"`
+	Syncing        bool   `json:"syncing"`
+	PollIntervalMs int64  `json:"poll_interval_ms"`
+	LastError      string `json:"last_error,omitempty"`
+}
+
+// SyncHealth fetches GET /health, including per-repo sync status.
+func (c *Client) SyncHealth() (*HealthInfo, error) {
+	resp, err := c.Do("GET", "/health", nil)
+	if err != nil {
+		return nil, err
+	}
+	var info HealthInfo
+	if err := decodeOrError(resp, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// VersionInfo is the response from GET /version.
+type VersionInfo struct {
+	Version         string `json:"version"`
+	APIVersion      int    `json:"api_version"`
+	DBSchemaVersion int    `json:"db_schema_version"`
+}
+
+// Version fetches the daemon's binary, API, and schema versions.
+func (c *Client) Version() (*VersionInfo, error) {
+	resp, err := c.Do("GET", "/version", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result VersionInfo
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpdateRepo updates repo settings (e.g., trusted_authors_only).
+func (c *Client) UpdateRepo(repo string, fields map[string]interface{}) (*model.RepoConfig, error) {
+	path := "/repos"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("PATCH", path, fields)
+	if err != nil {
+		return nil, err
+	}
+	var rc model.RepoConfig
+	if err := decodeOrError(resp, &rc); err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
+
+// IssueTemplates returns the repo's issue templates keyed by issue type.
+func (c *Client) IssueTemplates(repo string) (map[string]string, error) {
+	path := "/repos/templates"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Templates map[string]string `json:"templates"`
+	}
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	if result.Templates == nil {
+		result.Templates = map[string]string{}
+	}
+	return result.Templates, nil
+}
+
+// AddRepoPath registers a local path (worktree) for a repo.
+func (c *Client) AddRepoPath(repo string, body map[string]interface{}) (*model.RepoConfig, error) {
+	path := "/repos/paths"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("POST", path, body)
+	if err != nil {
+		return nil, err
+	}
+	var rc model.RepoConfig
+	if err := decodeOrError(resp, &rc); err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
+
+// UpdateRepoPath changes the socket/queue flags of a single registered local
+// path. Body must include "local_path"; omitted flags are left unchanged.
+func (c *Client) UpdateRepoPath(repo string, body map[string]interface{}) (*model.RepoConfig, error) {
+	path := "/repos/paths"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("PATCH", path, body)
+	if err != nil {
+		return nil, err
+	}
+	var rc model.RepoConfig
+	if err := decodeOrError(resp, &rc); err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
+
+// RemoveRepoPath removes a local path (worktree) from a repo.
+func (c *Client) RemoveRepoPath(repo string, body map[string]interface{}) (*model.RepoConfig, error) {
+	path := "/repos/paths"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("DELETE", path, body)
+	if err != nil {
+		return nil, err
+	}
+	var rc model.RepoConfig
+	if err := decodeOrError(resp, &rc); err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
+
+// ForceSync triggers a sync for the given repo.
+func (c *Client) ForceSync(repo string) error {
+	path := "/sync"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("POST", path, nil)
+	if err != nil {
+		return err
+	}
+	return decodeOrError(resp, nil)
+}
+
+// ImportIssues labels all open GitHub issues with "boxofrocks" and triggers a sync.
+func (c *Client) ImportIssues(repo string) (*ImportResult, error) {
+	path := "/repos/import"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("POST", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result ImportResult
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ImportResult holds the response from the import endpoint.
+type ImportResult struct {
+	Status  string `json:"status"`
+	Repo    string `json:"repo"`
+	Labeled int    `json:"labeled"`
+	Total   int    `json:"total"`
+}
+
+// DeleteRepo unregisters a repo and deletes its local issues and events.
+func (c *Client) DeleteRepo(repo string) (*model.RepoConfig, error) {
+	resp, err := c.Do("DELETE", "/repos?repo="+url.QueryEscape(repo), nil)
+	if err != nil {
+		return nil, err
+	}
+	var result model.RepoConfig
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// EnsureLabelsResult holds the response from the ensure-labels endpoint.
+type EnsureLabelsResult struct {
+	Repo     string   `json:"repo"`
+	Created  []string `json:"created"`
+	Existing []string `json:"existing"`
+}
+
+// EnsureLabels creates the repo's tracking label on GitHub if it is missing.
+func (c *Client) EnsureLabels(repo string) (*EnsureLabelsResult, error) {
+	path := "/repos/ensure-labels"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("POST", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result EnsureLabelsResult
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RepairResult holds the response from the repair endpoint.
+type RepairResult struct {
+	Repo     string `json:"repo"`
+	Checked  int    `json:"checked"`
+	Repaired []int  `json:"repaired"`
+	Skipped  []struct {
+		IssueID int    `json:"issue_id"`
+		Reason  string `json:"reason"`
+	} `json:"skipped"`
+}
+
+// RepairRepo rebuilds every issue row that drifted from its events.
+func (c *Client) RepairRepo(repo string) (*RepairResult, error) {
+	path := "/repos/repair"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("POST", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result RepairResult
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ForceSyncFull triggers a full replay sync for the given repo.
+func (c *Client) ForceSyncFull(repo string) error {
+	path := "/sync?full=true"
+	if repo != "" {
+		path += "&repo=" + repo
+	}
+	resp, err := c.Do("POST", path, nil)
+	if err != nil {
+		return err
+	}
+	return decodeOrError(resp, nil)
+}
+
+// SyncCycle is a single sync cycle outcome as reported by the daemon.
+type SyncCycle struct {
+	StartedAt time.Time `json:"started_at"`
+	Full      bool      `json:"full"`
+	Pushed    int       `json:"pushed"`
+	Pulled    int       `json:"pulled"`
+	Ignored   int       `json:"ignored,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// SyncLogResult holds the response from the sync log endpoint.
+type SyncLogResult struct {
+	Repo   string      `json:"repo"`
+	Cycles []SyncCycle `json:"cycles"`
+}
+
+// SyncLog returns the recent sync cycle outcomes for the given repo.
+func (c *Client) SyncLog(repo string) (*SyncLogResult, error) {
+	path := "/sync/log"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result SyncLogResult
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PendingEvent is an event waiting to be pushed to GitHub.
+type PendingEvent struct {
+	ID         int       `json:"id"`
+	IssueID    int       `json:"issue_id"`
+	IssueTitle string    `json:"issue_title"`
+	Action     string    `json:"action"`
+	Agent      string    `json:"agent,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	AgeSeconds int64     `json:"age_seconds"`
+}
+
+// PendingEventsResult holds the response from the pending events endpoint.
+type PendingEventsResult struct {
+	Repo   string         `json:"repo"`
+	Events []PendingEvent `json:"events"`
+}
+
+// PendingEvents returns the unsynced events for the given repo, oldest first.
+func (c *Client) PendingEvents(repo string) (*PendingEventsResult, error) {
+	path := "/events/pending"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result PendingEventsResult
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StatsResult holds the response from the stats endpoint.
+type StatsResult struct {
+	Repo string `json:"repo"`
+	store.IssueStats
+	OldestOpenAgeSeconds int64 `json:"oldest_open_age_seconds,omitempty"`
+}
+
+// Stats returns issue counts and sync backlog for the given repo.
+func (c *Client) Stats(repo string) (*StatsResult, error) {
+	path := "/stats"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result StatsResult
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Notifications returns the changes to issues owner watches in the given
+// repo, newest first. owner may be "@me".
+func (c *Client) Notifications(repo, owner string) ([]*store.Notification, error) {
+	q := url.Values{"owner": {owner}}
+	if repo != "" {
+		q.Set("repo", repo)
+	}
+	resp, err := c.Do("GET", "/notifications?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var notifications []*store.Notification
+	if err := decodeOrError(resp, &notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// ActiveSync describes a repo whose sync cycle is currently running.
+type ActiveSync struct {
+	RepoID         int       `json:"repo_id"`
+	RepoName       string    `json:"repo_name"`
+	StartedAt      time.Time `json:"started_at"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+}
+
+// SyncActive returns the repos that are currently mid-cycle.
+func (c *Client) SyncActive() ([]ActiveSync, error) {
+	resp, err := c.Do("GET", "/sync/active", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Active []ActiveSync `json:"active"`
+	}
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return result.Active, nil
+}
+
+// CancelSync interrupts the in-flight sync cycle for the given repo.
+func (c *Client) CancelSync(repo string) error {
+	path := "/sync/cancel"
+	if repo != "" {
+		path += "?repo=" + repo
+	}
+	resp, err := c.Do("POST", path, nil)
+	if err != nil {
+		return err
+	}
+	return decodeOrError(resp, nil)
+}
+
+// FieldChange is one value an issue field took on, and who set it.
+type FieldChange struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Value     interface{} `json:"value"`
+	Agent     string      `json:"agent"`
+	EventID   int         `json:"event_id"`
+}
+
+// FieldHistory returns how a single issue field changed over time.
+func (c *Client) FieldHistory(id int, field string) ([]FieldChange, error) {
+	resp, err := c.Do("GET", fmt.Sprintf("/issues/%d/field-history?field=%s", id, url.QueryEscape(field)), nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		History []FieldChange `json:"history"`
+	}
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return result.History, nil
+}
+
+// TimelineEvent is one event of an issue's timeline, with the
+// human-readable text of its GitHub comment.
+type TimelineEvent struct {
+	model.Event
+	Text string `json:"text"`
+}
+
+// IssueEvents returns an issue's events in the order they were recorded.
+func (c *Client) IssueEvents(id int) ([]TimelineEvent, error) {
+	resp, err := c.Do("GET", fmt.Sprintf("/issues/%d/events", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	var events []TimelineEvent
+	if err := decodeOrError(resp, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Export streams the daemon's export document for repo, or for every repo
+// if repo is empty, to w.
+func (c *Client) Export(repo string, w io.Writer) error {
+	path := "/export"
+	if repo != "" {
+		path += "?repo=" + url.QueryEscape(repo)
+	}
+	resp, err := c.Do("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return decodeOrError(resp, nil)
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("read export: %w", err)
+	}
+	return nil
+}
+
+// Import loads an export document into the daemon's store.
+func (c *Client) Import(dump []byte) (*store.ImportResult, error) {
+	if !json.Valid(dump) {
+		return nil, fmt.Errorf("dump is not valid JSON")
+	}
+	resp, err := c.Do("POST", "/import", json.RawMessage(dump))
+	if err != nil {
+		return nil, err
+	}
+	var result store.ImportResult
+	if err := decodeOrError(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
 }
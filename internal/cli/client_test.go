@@ -1,13 +1,17 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jmaddaus/boxofrocks/internal/daemon"
 	"github.com/jmaddaus/boxofrocks/internal/model"
 )
 
@@ -193,6 +197,31 @@ func TestAssignIssue(t *testing.T) {
 	}
 }
 
+func TestAbandonIssue(t *testing.T) {
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/issues/3/abandon" {
+			t.Errorf("path: want /issues/3/abandon, got %s", r.URL.Path)
+		}
+		var req struct {
+			Reason     string `json:"reason"`
+			KeepStatus bool   `json:"keep_status"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Reason != "blocked on creds" || !req.KeepStatus {
+			t.Errorf("body: got %+v", req)
+		}
+		json.NewEncoder(w).Encode(model.Issue{ID: 3, Status: model.StatusInProgress})
+	})
+
+	issue, err := c.AbandonIssue(3, "blocked on creds", true)
+	if err != nil {
+		t.Fatalf("AbandonIssue: %v", err)
+	}
+	if issue.Owner != "" {
+		t.Errorf("owner: want empty, got %s", issue.Owner)
+	}
+}
+
 func TestNextIssue(t *testing.T) {
 	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" {
@@ -264,3 +293,244 @@ func TestDecodeOrError_ErrorResponse(t *testing.T) {
 		t.Error("expected non-empty error message")
 	}
 }
+
+func TestVersion(t *testing.T) {
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			t.Errorf("path: want /version, got %s", r.URL.Path)
+		}
+		w.Header().Set(daemon.APIVersionHeader, strconv.Itoa(daemon.APIVersion))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"version": "1.2.3", "api_version": daemon.APIVersion, "db_schema_version": 7,
+		})
+	})
+	var warn bytes.Buffer
+	c.warnOut = &warn
+
+	info, err := c.Version()
+	if err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+	if info.Version != "1.2.3" || info.APIVersion != daemon.APIVersion || info.DBSchemaVersion != 7 {
+		t.Errorf("unexpected version info: %+v", info)
+	}
+	if warn.Len() != 0 {
+		t.Errorf("unexpected warning: %q", warn.String())
+	}
+}
+
+func TestAPIVersionSkewWarnsOnce(t *testing.T) {
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(daemon.APIVersionHeader, strconv.Itoa(daemon.APIVersion+1))
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
+	})
+	var warn bytes.Buffer
+	c.warnOut = &warn
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Health(); err != nil {
+			t.Fatalf("Health: %v", err)
+		}
+	}
+	if n := strings.Count(warn.String(), "warning:"); n != 1 {
+		t.Errorf("expected exactly one warning, got %d: %q", n, warn.String())
+	}
+}
+
+func TestEnsureLabels(t *testing.T) {
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("method: want POST, got %s", r.Method)
+		}
+		if r.URL.Path != "/repos/ensure-labels" {
+			t.Errorf("path: want /repos/ensure-labels, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("repo") != "o/r" {
+			t.Errorf("repo: want o/r, got %s", r.URL.Query().Get("repo"))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"repo": "o/r", "created": []string{"boxofrocks"}, "existing": []string{},
+		})
+	})
+
+	result, err := c.EnsureLabels("o/r")
+	if err != nil {
+		t.Fatalf("EnsureLabels: %v", err)
+	}
+	if len(result.Created) != 1 || result.Created[0] != "boxofrocks" {
+		t.Errorf("created: want [boxofrocks], got %v", result.Created)
+	}
+}
+
+func TestFieldHistory(t *testing.T) {
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/issues/7/field-history" {
+			t.Errorf("path: want /issues/7/field-history, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("field") != "priority" {
+			t.Errorf("field: want priority, got %s", r.URL.Query().Get("field"))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issue_id": 7,
+			"field":    "priority",
+			"history":  []map[string]interface{}{{"value": 0, "agent": "alice", "event_id": 3}},
+		})
+	})
+
+	history, err := c.FieldHistory(7, "priority")
+	if err != nil {
+		t.Fatalf("FieldHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Agent != "alice" || history[0].EventID != 3 {
+		t.Errorf("unexpected history: %+v", history)
+	}
+}
+
+func TestReorderIssues(t *testing.T) {
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/issues/reorder" {
+			t.Errorf("want POST /issues/reorder, got %s %s", r.Method, r.URL.Path)
+		}
+		var body struct {
+			Order []int `json:"order"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Order) != 2 || body.Order[0] != 3 || body.Order[1] != 1 {
+			t.Errorf("order: want [3 1], got %v", body.Order)
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"id": 3, "priority": 0}, {"id": 1, "priority": 5}})
+	})
+
+	issues, err := c.ReorderIssues("o/r", []int{3, 1})
+	if err != nil {
+		t.Fatalf("ReorderIssues: %v", err)
+	}
+	if len(issues) != 2 || issues[1].Priority != 5 {
+		t.Errorf("unexpected issues: %+v", issues)
+	}
+}
+
+func TestAgentHeaderAndOwnerMe(t *testing.T) {
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get(daemon.AgentHeader); got != "agent-a" {
+			t.Errorf("%s: want agent-a, got %q", daemon.AgentHeader, got)
+		}
+		if got := r.URL.Query().Get("owner"); got != "@me" {
+			t.Errorf("owner: want @me, got %q", got)
+		}
+		if r.URL.Path == "/issues/next" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 2})
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"id": 1}})
+	})
+	c.agent = "agent-a"
+
+	if _, err := c.ListIssues("", ListOpts{Owner: "@me"}); err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	issue, err := c.NextIssueForOwner("", "@me")
+	if err != nil {
+		t.Fatalf("NextIssueForOwner: %v", err)
+	}
+	if issue.ID != 2 {
+		t.Errorf("expected issue 2, got %d", issue.ID)
+	}
+}
+
+func TestAuthTokenHeader(t *testing.T) {
+	t.Setenv("BOR_AUTH_TOKEN", "s3cret")
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer s3cret" {
+			t.Errorf("Authorization: want Bearer s3cret, got %q", got)
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{})
+	})
+
+	if _, err := c.ListIssues("", ListOpts{}); err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+}
+
+func TestChangedIssues(t *testing.T) {
+	since := time.Date(2026, 3, 1, 12, 0, 0, 0, time.FixedZone("EST", -5*3600))
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/issues/changed" {
+			t.Errorf("path: want /issues/changed, got %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("since"); got != "2026-03-01T17:00:00Z" {
+			t.Errorf("since: want 2026-03-01T17:00:00Z, got %s", got)
+		}
+		if got := r.URL.Query().Get("repo"); got != "o/r" {
+			t.Errorf("repo: want o/r, got %s", got)
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"id": 4, "status": "deleted"}})
+	})
+
+	issues, err := c.ChangedIssues("o/r", since)
+	if err != nil {
+		t.Fatalf("ChangedIssues: %v", err)
+	}
+	if len(issues) != 1 || issues[0].ID != 4 || issues[0].Status != model.StatusDeleted {
+		t.Errorf("unexpected issues: %+v", issues)
+	}
+}
+
+func TestPendingEvents(t *testing.T) {
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events/pending" {
+			t.Errorf("path: want /events/pending, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("repo") != "owner/name" {
+			t.Errorf("repo query: want owner/name, got %s", r.URL.Query().Get("repo"))
+		}
+		json.NewEncoder(w).Encode(PendingEventsResult{
+			Repo:   "owner/name",
+			Events: []PendingEvent{{ID: 9, IssueID: 2, IssueTitle: "Stuck", Action: "update", AgeSeconds: 600}},
+		})
+	})
+
+	result, err := c.PendingEvents("owner/name")
+	if err != nil {
+		t.Fatalf("PendingEvents: %v", err)
+	}
+	if len(result.Events) != 1 || result.Events[0].IssueTitle != "Stuck" || result.Events[0].AgeSeconds != 600 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestSyncLog(t *testing.T) {
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("method: want GET, got %s", r.Method)
+		}
+		if r.URL.Path != "/sync/log" {
+			t.Errorf("path: want /sync/log, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("repo") != "owner/name" {
+			t.Errorf("repo query: want owner/name, got %s", r.URL.Query().Get("repo"))
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(SyncLogResult{
+			Repo: "owner/name",
+			Cycles: []SyncCycle{
+				{StartedAt: time.Now(), Pushed: 2, Pulled: 1},
+				{StartedAt: time.Now(), Error: "pull: boom"},
+			},
+		})
+	})
+
+	result, err := c.SyncLog("owner/name")
+	if err != nil {
+		t.Fatalf("SyncLog: %v", err)
+	}
+	if len(result.Cycles) != 2 {
+		t.Fatalf("expected 2 cycles, got %d", len(result.Cycles))
+	}
+	if result.Cycles[0].Pushed != 2 || result.Cycles[0].Pulled != 1 {
+		t.Errorf("unexpected first cycle: %+v", result.Cycles[0])
+	}
+	if result.Cycles[1].Error != "pull: boom" {
+		t.Errorf("expected error on second cycle, got %q", result.Cycles[1].Error)
+	}
+}
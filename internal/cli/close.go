@@ -1,16 +1,13 @@
 package cli
 
-import (
-	"fmt"
-	"strconv"
-)
+import "fmt"
 
 func runClose(args []string, gf globalFlags) error {
 	if len(args) == 0 {
 		return fmt.Errorf("usage: bor close <id>")
 	}
 
-	id, err := strconv.Atoi(args[0])
+	id, err := issueID(gf, args[0])
 	if err != nil {
 		return fmt.Errorf("invalid issue id %q: %w", args[0], err)
 	}
@@ -28,3 +25,45 @@ func runClose(args []string, gf globalFlags) error {
 	printIssue(issue, gf.pretty)
 	return nil
 }
+
+func runReopen(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor reopen <id>")
+	}
+
+	id, err := issueID(gf, args[0])
+	if err != nil {
+		return fmt.Errorf("invalid issue id %q: %w", args[0], err)
+	}
+
+	client := newClient(gf)
+
+	issue, err := client.ReopenIssue(id)
+	if err != nil {
+		return fmt.Errorf("reopen issue: %w", err)
+	}
+
+	printIssue(issue, gf.pretty)
+	return nil
+}
+
+func runRestore(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor restore <id>")
+	}
+
+	id, err := issueID(gf, args[0])
+	if err != nil {
+		return fmt.Errorf("invalid issue id %q: %w", args[0], err)
+	}
+
+	client := newClient(gf)
+
+	issue, err := client.RestoreIssue(id)
+	if err != nil {
+		return fmt.Errorf("restore issue: %w", err)
+	}
+
+	printIssue(issue, gf.pretty)
+	return nil
+}
@@ -2,7 +2,6 @@ package cli
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 )
 
@@ -11,7 +10,7 @@ func runComment(args []string, gf globalFlags) error {
 		return fmt.Errorf("usage: bor comment <id> <message>")
 	}
 
-	id, err := strconv.Atoi(args[0])
+	id, err := issueID(gf, args[0])
 	if err != nil {
 		return fmt.Errorf("invalid issue id %q: %w", args[0], err)
 	}
@@ -0,0 +1,376 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+// completionFlags lists each command's flags, for completing after a "-".
+// Go's flag package accepts one or two dashes; the scripts offer two.
+var completionFlags = map[string][]string{
+	"abandon":  {"--reason", "--keep-status"},
+	"claim":    {"--owner"},
+	"create":   {"-p", "-t", "-d", "-e", "--parent", "--from-file"},
+	"export":   {"--repo"},
+	"init":     {"--repo", "--offline", "--socket", "--json", "--update-arbiter", "--import-all", "--path"},
+	"list":     {"--all", "--status", "--priority", "--include-snoozed", "--owner", "--label", "--sort", "--desc", "--limit", "--offset"},
+	"login":    {"--token", "--status"},
+	"next":     {"--budget", "--owner", "--explain", "--count"},
+	"plan":     {"--budget"},
+	"stale":    {"--days"},
+	"sync":     {"--full"},
+	"trending": {"--days", "--limit"},
+	"update":   {"--status", "--priority", "--estimate", "--title", "--description", "--comment", "--parent", "--if-version"},
+}
+
+// completionSubcommands lists the words that may follow a command.
+var completionSubcommands = map[string][]string{
+	"completion": {"bash", "zsh", "fish"},
+	"config": {"trusted-authors-only", "trusted-authors", "allowed-inbound-actions", "issue-types", "epic-rollup",
+		"next-strategy", "sync-direction", "ingest-human-comments", "label", "assignee-logins", "wip-limit", "validate-assignee", "key-prefix"},
+	"daemon":   {"start", "stop", "status", "logs"},
+	"db":       {"version", "check", "upgrade", "downgrade", "vacuum", "compact"},
+	"depend":   {"add", "remove"},
+	"label":    {"add", "remove"},
+	"repos":    {"ensure-labels", "remove"},
+	"sync":     {"log", "active", "cancel"},
+	"template": {"show", "set", "remove"},
+	"watch":    {"add", "remove"},
+}
+
+// completionIDCommands take issue IDs as arguments, after their
+// subcommand if they have one. IDs are fetched with "bor completion ids".
+var completionIDCommands = []string{
+	"abandon", "assign", "close", "comment", "depend", "history", "label",
+	"reopen", "restore", "show", "snooze", "update", "watch",
+}
+
+const completionUsage = "usage: bor completion <bash|zsh|fish>"
+
+// runCompletion writes a completion script for the given shell. The hidden
+// "ids" target prints the repo's issue IDs for the scripts to offer.
+func runCompletion(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf(completionUsage)
+	}
+	switch args[0] {
+	case "bash", "zsh", "fish":
+		return writeCompletion(args[0])
+	case "ids":
+		return printCompletionIDs(gf)
+	case "--help", "-h":
+		fmt.Println(completionUsage + "\n\n" +
+			"Load the script in your shell, e.g. in ~/.bashrc:\n" +
+			"  source <(bor completion bash)\n" +
+			"in ~/.zshrc, after compinit:\n" +
+			"  source <(bor completion zsh)\n" +
+			"or for fish:\n" +
+			"  bor completion fish > ~/.config/fish/completions/bor.fish")
+		return nil
+	default:
+		return fmt.Errorf("unknown shell %q\n%s", args[0], completionUsage)
+	}
+}
+
+// printCompletionIDs prints the repo's issue IDs one per line. It prints
+// nothing, and does not fail, when the daemon cannot be reached, so a
+// completion never shows an error.
+func printCompletionIDs(gf globalFlags) error {
+	client := newClient(gf)
+	client.warnOut = nil
+	issues, err := client.ListIssues(resolveRepo(gf), ListOpts{})
+	if err != nil {
+		return nil
+	}
+	for _, iss := range issues {
+		fmt.Println(iss.ID)
+	}
+	return nil
+}
+
+// completionCommand is one top-level command and its one-line description.
+type completionCommand struct {
+	Name string
+	Desc string
+}
+
+// completionCommands reads the commands from the usage text, so the scripts
+// offer exactly what "bor help" lists.
+func completionCommands() []completionCommand {
+	section := usage[strings.Index(usage, "Commands:\n")+len("Commands:\n"):]
+	section = section[:strings.Index(section, "\n\n")]
+	var cmds []completionCommand
+	for _, line := range strings.Split(section, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		cmds = append(cmds, completionCommand{Name: fields[0], Desc: strings.Join(fields[1:], " ")})
+	}
+	return cmds
+}
+
+// completionData is what the script templates are filled from. Lists are
+// space-separated words; maps are keyed by command.
+type completionData struct {
+	Commands    []completionCommand
+	Names       string
+	Globals     string
+	Flags       map[string]string
+	Subcommands map[string]string
+	IDCommands  string
+	Statuses    string
+	Types       string
+}
+
+func newCompletionData() completionData {
+	d := completionData{
+		Commands:    completionCommands(),
+		Globals:     "--host --repo -r --pretty --format",
+		Flags:       make(map[string]string),
+		Subcommands: make(map[string]string),
+		IDCommands:  strings.Join(completionIDCommands, " "),
+	}
+	names := make([]string, 0, len(d.Commands))
+	for _, c := range d.Commands {
+		names = append(names, c.Name)
+	}
+	d.Names = strings.Join(names, " ")
+	for cmd, flags := range completionFlags {
+		d.Flags[cmd] = strings.Join(flags, " ")
+	}
+	for cmd, subs := range completionSubcommands {
+		d.Subcommands[cmd] = strings.Join(subs, " ")
+	}
+	statuses := make([]string, 0, len(model.Statuses))
+	for _, s := range model.Statuses {
+		statuses = append(statuses, string(s))
+	}
+	d.Statuses = strings.Join(statuses, " ")
+	types := make([]string, 0, len(model.DefaultIssueTypes))
+	for _, t := range model.DefaultIssueTypes {
+		types = append(types, string(t))
+	}
+	d.Types = strings.Join(types, " ")
+	return d
+}
+
+var completionFuncs = template.FuncMap{
+	// quote single-quotes s for bash, zsh and fish alike.
+	"quote": func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	},
+	// bar joins space-separated words into a case pattern.
+	"bar": func(s string) string {
+		return strings.ReplaceAll(s, " ", "|")
+	},
+	// keys returns a map's keys in order, so the scripts are stable.
+	"keys": func(m map[string]string) []string {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	},
+	// fishFlags turns flags into complete's -s and -l options.
+	"fishFlags": func(flags string) string {
+		var opts []string
+		for _, f := range strings.Fields(flags) {
+			if strings.HasPrefix(f, "--") {
+				opts = append(opts, "-l "+strings.TrimPrefix(f, "--"))
+			} else {
+				opts = append(opts, "-s "+strings.TrimPrefix(f, "-"))
+			}
+		}
+		return strings.Join(opts, " ")
+	},
+	// zshDescribe formats a command for _describe, which splits on ":".
+	"zshDescribe": func(c completionCommand) string {
+		return strconv.Quote(c.Name + ":" + strings.ReplaceAll(c.Desc, ":", `\:`))
+	},
+}
+
+var completionTemplates = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Funcs(completionFuncs).Parse(bashCompletion)),
+	"zsh":  template.Must(template.New("zsh").Funcs(completionFuncs).Parse(zshCompletion)),
+	"fish": template.Must(template.New("fish").Funcs(completionFuncs).Parse(fishCompletion)),
+}
+
+func writeCompletion(shell string) error {
+	return completionTemplates[shell].Execute(os.Stdout, newCompletionData())
+}
+
+const bashCompletion = `# bash completion for bor
+# Load with: source <(bor completion bash)
+
+_bor() {
+    local cur prev cmd sub i
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+            --host|--repo|-r|--format) ((i++)) ;;
+            -*) ;;
+            *)
+                if [[ -z "$cmd" ]]; then
+                    cmd="${COMP_WORDS[i]}"
+                elif [[ -z "$sub" ]]; then
+                    sub="${COMP_WORDS[i]}"
+                fi
+                ;;
+        esac
+    done
+
+    case "$prev" in
+        --status|-status) COMPREPLY=($(compgen -W {{quote .Statuses}} -- "$cur")); return ;;
+        -t) COMPREPLY=($(compgen -W {{quote .Types}} -- "$cur")); return ;;
+        --format) COMPREPLY=($(compgen -W "json table" -- "$cur")); return ;;
+        --sort|-sort) COMPREPLY=($(compgen -W "priority created updated" -- "$cur")); return ;;
+        --host|--repo|-r) return ;;
+    esac
+
+    if [[ -z "$cmd" ]]; then
+        if [[ "$cur" == -* ]]; then
+            COMPREPLY=($(compgen -W {{quote .Globals}} -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W {{quote .Names}} -- "$cur"))
+        fi
+        return
+    fi
+
+    if [[ "$cur" == -* ]]; then
+        case "$cmd" in
+{{- range $cmd := keys .Flags}}
+            {{$cmd}}) COMPREPLY=($(compgen -W {{quote (index $.Flags $cmd)}} -- "$cur")) ;;
+{{- end}}
+        esac
+        return
+    fi
+
+    case "$cmd" in
+{{- range $cmd := keys .Subcommands}}
+        {{$cmd}})
+            if [[ -z "$sub" ]]; then
+                COMPREPLY=($(compgen -W {{quote (index $.Subcommands $cmd)}} -- "$cur"))
+                return
+            fi
+            ;;
+{{- end}}
+    esac
+
+    case "$cmd" in
+        {{bar .IDCommands}})
+            COMPREPLY=($(compgen -W "$(bor completion ids 2>/dev/null)" -- "$cur"))
+            ;;
+    esac
+}
+
+complete -F _bor bor
+`
+
+const zshCompletion = `#compdef bor
+# zsh completion for bor
+# Load with: source <(bor completion zsh), after compinit
+
+_bor() {
+    local cmd sub i
+    local -a commands
+    commands=(
+{{- range .Commands}}
+        {{zshDescribe .}}
+{{- end}}
+    )
+
+    for ((i = 2; i < CURRENT; i++)); do
+        case "${words[i]}" in
+            --host|--repo|-r|--format) ((i++)) ;;
+            -*) ;;
+            *)
+                if [[ -z "$cmd" ]]; then
+                    cmd="${words[i]}"
+                elif [[ -z "$sub" ]]; then
+                    sub="${words[i]}"
+                fi
+                ;;
+        esac
+    done
+
+    case "${words[CURRENT-1]}" in
+        --status|-status) compadd -- {{.Statuses}}; return ;;
+        -t) compadd -- {{.Types}}; return ;;
+        --format) compadd -- json table; return ;;
+        --sort|-sort) compadd -- priority created updated; return ;;
+        --host|--repo|-r) return ;;
+    esac
+
+    if [[ -z "$cmd" ]]; then
+        if [[ "${words[CURRENT]}" == -* ]]; then
+            compadd -- {{.Globals}}
+        else
+            _describe 'command' commands
+        fi
+        return
+    fi
+
+    if [[ "${words[CURRENT]}" == -* ]]; then
+        case "$cmd" in
+{{- range $cmd := keys .Flags}}
+            {{$cmd}}) compadd -- {{index $.Flags $cmd}} ;;
+{{- end}}
+        esac
+        return
+    fi
+
+    case "$cmd" in
+{{- range $cmd := keys .Subcommands}}
+        {{$cmd}})
+            if [[ -z "$sub" ]]; then
+                compadd -- {{index $.Subcommands $cmd}}
+                return
+            fi
+            ;;
+{{- end}}
+    esac
+
+    case "$cmd" in
+        {{bar .IDCommands}})
+            compadd -- ${(f)"$(bor completion ids 2>/dev/null)"}
+            ;;
+    esac
+}
+
+compdef _bor bor
+`
+
+const fishCompletion = `# fish completion for bor
+# Install with: bor completion fish > ~/.config/fish/completions/bor.fish
+
+complete -c bor -f
+
+complete -c bor -l host -x -d 'Daemon URL'
+complete -c bor -s r -l repo -x -d 'Repository owner/name'
+complete -c bor -l pretty -d 'Use pretty-printed output'
+complete -c bor -l format -x -a 'json table' -d 'Output format'
+{{range .Commands}}
+complete -c bor -n __fish_use_subcommand -a {{.Name}} -d {{quote .Desc}}
+{{- end}}
+{{range $cmd := keys .Subcommands}}
+complete -c bor -n '__fish_seen_subcommand_from {{$cmd}}' -a {{quote (index $.Subcommands $cmd)}}
+{{- end}}
+{{range $cmd := keys .Flags}}
+complete -c bor -n '__fish_seen_subcommand_from {{$cmd}}' {{fishFlags (index $.Flags $cmd)}}
+{{- end}}
+
+complete -c bor -n '__fish_seen_subcommand_from list update' -l status -x -a {{quote .Statuses}}
+complete -c bor -n '__fish_seen_subcommand_from create' -s t -x -a {{quote .Types}}
+complete -c bor -n '__fish_seen_subcommand_from list' -l sort -x -a 'priority created updated'
+complete -c bor -n '__fish_seen_subcommand_from {{.IDCommands}}' -a '(bor completion ids 2>/dev/null)'
+`
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCompletionScripts(t *testing.T) {
+	tests := []struct {
+		shell  string
+		marker string
+	}{
+		{"bash", "complete -F _bor bor"},
+		{"zsh", "#compdef bor"},
+		{"fish", "complete -c bor -n __fish_use_subcommand -a list"},
+	}
+	for _, tt := range tests {
+		var err error
+		out := captureStdout(t, func() {
+			err = runCompletion([]string{tt.shell}, globalFlags{})
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.shell, err)
+		}
+		if !strings.Contains(out, tt.marker) {
+			t.Errorf("%s: expected %q in the script", tt.shell, tt.marker)
+		}
+		for _, want := range []string{"in_progress", "completion ids", "validate-assignee"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: expected %q in the script", tt.shell, want)
+			}
+		}
+		// Check the syntax when the shell is installed.
+		if path, err := exec.LookPath(tt.shell); err == nil {
+			flag := "-n"
+			if tt.shell == "fish" {
+				flag = "--no-execute"
+			}
+			cmd := exec.Command(path, flag)
+			cmd.Stdin = strings.NewReader(out)
+			if msg, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("%s: script does not parse: %v\n%s", tt.shell, err, msg)
+			}
+		}
+	}
+
+	if err := runCompletion([]string{"powershell"}, globalFlags{}); err == nil {
+		t.Error("expected an error for an unknown shell")
+	}
+}
+
+func TestCompletionCommandsMatchUsage(t *testing.T) {
+	cmds := completionCommands()
+	names := make(map[string]bool)
+	for _, c := range cmds {
+		if c.Desc == "" {
+			t.Errorf("command %s has no description", c.Name)
+		}
+		names[c.Name] = true
+	}
+	for _, want := range []string{"list", "show", "completion", "version"} {
+		if !names[want] {
+			t.Errorf("expected %s among the completed commands, got %v", want, cmds)
+		}
+	}
+	for _, cmd := range completionIDCommands {
+		if !names[cmd] {
+			t.Errorf("ID command %s is not a command", cmd)
+		}
+	}
+}
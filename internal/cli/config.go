@@ -2,18 +2,46 @@ package cli
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
 )
 
 func runConfig(args []string, gf globalFlags) error {
 	if len(args) == 0 {
-		return fmt.Errorf("usage: bor config <setting> <value>\n\nSettings:\n  trusted-authors-only true|false   Enable/disable trusted author filtering")
+		return fmt.Errorf("usage: bor config <setting> <value>\n\nSettings:\n  trusted-authors-only true|false   Enable/disable trusted author filtering\n  trusted-authors none|login,login  Trust these GitHub logins besides the repo owner\n  allowed-inbound-actions all|a,b   Restrict which actions are applied from GitHub comments\n  issue-types default|a,b           Set the issue types the repo accepts\n  epic-rollup true|false            Post child issues as checklist items on their parent's GitHub issue\n  next-strategy priority|fifo|weighted  Choose how next and plan order open issues\n  sync-direction both|pull|push     Sync both ways, only mirror GitHub, or only publish to it\n  ingest-human-comments true|false  Record plain GitHub comments as local comments\n  label <name>                      Set the GitHub label that marks tracked issues\n  assignee-logins none|owner=login,...  Assign issues on GitHub to the login mapped from their owner\n  wip-limit <n>                     Cap each owner's open and in_progress issues; 0 means no limit\n  validate-assignee true|false      Only allow assigning GitHub collaborators\n  key-prefix none|<prefix>          Let issues be referred to as PREFIX-<id>")
 	}
 
 	setting := args[0]
 	switch setting {
 	case "trusted-authors-only":
 		return runConfigTrustedAuthors(args[1:], gf)
+	case "trusted-authors":
+		return runConfigTrustedAuthorLogins(args[1:], gf)
+	case "allowed-inbound-actions":
+		return runConfigAllowedInboundActions(args[1:], gf)
+	case "issue-types":
+		return runConfigIssueTypes(args[1:], gf)
+	case "epic-rollup":
+		return runConfigEpicRollup(args[1:], gf)
+	case "next-strategy":
+		return runConfigNextStrategy(args[1:], gf)
+	case "sync-direction":
+		return runConfigSyncDirection(args[1:], gf)
+	case "ingest-human-comments":
+		return runConfigIngestHumanComments(args[1:], gf)
+	case "label":
+		return runConfigLabel(args[1:], gf)
+	case "assignee-logins":
+		return runConfigAssigneeLogins(args[1:], gf)
+	case "wip-limit":
+		return runConfigWIPLimit(args[1:], gf)
+	case "validate-assignee":
+		return runConfigValidateAssignee(args[1:], gf)
+	case "key-prefix":
+		return runConfigKeyPrefix(args[1:], gf)
 	default:
 		return fmt.Errorf("unknown config setting: %s", setting)
 	}
@@ -24,15 +52,9 @@ func runConfigTrustedAuthors(args []string, gf globalFlags) error {
 		return fmt.Errorf("usage: bor config trusted-authors-only <true|false>")
 	}
 
-	val := strings.ToLower(args[0])
-	var enabled bool
-	switch val {
-	case "true", "1", "on", "yes":
-		enabled = true
-	case "false", "0", "off", "no":
-		enabled = false
-	default:
-		return fmt.Errorf("invalid value %q: use true or false", args[0])
+	enabled, err := parseBoolSetting(args[0])
+	if err != nil {
+		return err
 	}
 
 	client := newClient(gf)
@@ -49,3 +71,353 @@ func runConfigTrustedAuthors(args []string, gf globalFlags) error {
 	fmt.Printf("trusted_authors_only = %v (repo: %s/%s)\n", updated.TrustedAuthorsOnly, updated.Owner, updated.Name)
 	return nil
 }
+
+func runConfigTrustedAuthorLogins(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config trusted-authors <none|login,login,...>")
+	}
+
+	logins := []string{}
+	if strings.ToLower(args[0]) != "none" {
+		for _, l := range strings.Split(args[0], ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				logins = append(logins, l)
+			}
+		}
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"trusted_authors": logins,
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	trusted := "none"
+	if len(updated.TrustedAuthors) > 0 {
+		trusted = strings.Join(updated.TrustedAuthors, ",")
+	}
+	fmt.Printf("trusted_authors = %s (repo: %s/%s)\n", trusted, updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigAllowedInboundActions(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config allowed-inbound-actions <all|action,action,...>")
+	}
+
+	actions := []string{}
+	if strings.ToLower(args[0]) != "all" {
+		for _, a := range strings.Split(args[0], ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				actions = append(actions, a)
+			}
+		}
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"allowed_inbound_actions": actions,
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	allowed := "all"
+	if len(updated.AllowedInboundActions) > 0 {
+		allowed = strings.Join(updated.AllowedInboundActions, ",")
+	}
+	fmt.Printf("allowed_inbound_actions = %s (repo: %s/%s)\n", allowed, updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigIssueTypes(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config issue-types <default|type,type,...>")
+	}
+
+	types := []string{}
+	if strings.ToLower(args[0]) != "default" {
+		for _, t := range strings.Split(args[0], ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"issue_types": types,
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	valid := make([]string, 0, len(updated.ValidIssueTypes()))
+	for _, t := range updated.ValidIssueTypes() {
+		valid = append(valid, string(t))
+	}
+	fmt.Printf("issue_types = %s (repo: %s/%s)\n", strings.Join(valid, ","), updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigEpicRollup(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config epic-rollup <true|false>")
+	}
+
+	enabled, err := parseBoolSetting(args[0])
+	if err != nil {
+		return err
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"epic_rollup": enabled,
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("epic_rollup = %v (repo: %s/%s)\n", updated.EpicRollup, updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigNextStrategy(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config next-strategy <priority|fifo|weighted>")
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"next_strategy": strings.ToLower(args[0]),
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	strategy := string(updated.NextStrategy)
+	if strategy == "" {
+		strategy = string(model.NextStrategyPriority)
+	}
+	fmt.Printf("next_strategy = %s (repo: %s/%s)\n", strategy, updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigSyncDirection(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config sync-direction <both|pull|push>")
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"sync_direction": strings.ToLower(args[0]),
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	direction := string(updated.SyncDirection)
+	if direction == "" {
+		direction = string(model.SyncBoth)
+	}
+	fmt.Printf("sync_direction = %s (repo: %s/%s)\n", direction, updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigIngestHumanComments(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config ingest-human-comments <true|false>")
+	}
+
+	enabled, err := parseBoolSetting(args[0])
+	if err != nil {
+		return err
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"ingest_human_comments": enabled,
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("ingest_human_comments = %v (repo: %s/%s)\n", updated.IngestHumanComments, updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigLabel(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config label <name>")
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"label": args[0],
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("label = %s (repo: %s/%s)\n", updated.TrackingLabel(), updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigAssigneeLogins(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config assignee-logins <none|owner=login,owner=login,...>")
+	}
+
+	logins := map[string]string{}
+	if strings.ToLower(args[0]) != "none" {
+		for _, pair := range strings.Split(args[0], ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			owner, login, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid mapping %q: use owner=login", pair)
+			}
+			logins[owner] = login
+		}
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"assignee_logins": logins,
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	mapped := "none"
+	if len(updated.AssigneeLogins) > 0 {
+		pairs := make([]string, 0, len(updated.AssigneeLogins))
+		for owner, login := range updated.AssigneeLogins {
+			pairs = append(pairs, owner+"="+login)
+		}
+		sort.Strings(pairs)
+		mapped = strings.Join(pairs, ",")
+	}
+	fmt.Printf("assignee_logins = %s (repo: %s/%s)\n", mapped, updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigWIPLimit(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config wip-limit <n>")
+	}
+
+	limit, err := strconv.Atoi(args[0])
+	if err != nil || limit < 0 {
+		return fmt.Errorf("invalid wip limit %q: use a non-negative integer", args[0])
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"wip_limit": limit,
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("wip_limit = %d (repo: %s/%s)\n", updated.WIPLimit, updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigValidateAssignee(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config validate-assignee <true|false>")
+	}
+
+	enabled, err := parseBoolSetting(args[0])
+	if err != nil {
+		return err
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"validate_assignee": enabled,
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("validate_assignee = %v (repo: %s/%s)\n", updated.ValidateAssignee, updated.Owner, updated.Name)
+	return nil
+}
+
+func runConfigKeyPrefix(args []string, gf globalFlags) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bor config key-prefix <none|prefix>")
+	}
+
+	prefix := args[0]
+	if strings.ToLower(prefix) == "none" {
+		prefix = ""
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	fields := map[string]interface{}{
+		"key_prefix": prefix,
+	}
+	updated, err := client.UpdateRepo(repo, fields)
+	if err != nil {
+		return err
+	}
+
+	shown := updated.KeyPrefix
+	if shown == "" {
+		shown = "none"
+	}
+	fmt.Printf("key_prefix = %s (repo: %s/%s)\n", shown, updated.Owner, updated.Name)
+	return nil
+}
+
+// parseBoolSetting accepts true/false and the usual on/off spellings.
+func parseBoolSetting(val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case "true", "1", "on", "yes":
+		return true, nil
+	case "false", "0", "off", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid value %q: use true or false", val)
+}
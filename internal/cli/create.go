@@ -3,21 +3,30 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"io"
+	"os"
 )
 
 func runCreate(args []string, gf globalFlags) error {
 	fs := flag.NewFlagSet("create", flag.ContinueOnError)
 	priority := fs.Int("p", 0, "Priority (lower is higher priority)")
-	issueType := fs.String("t", "task", "Issue type (task, bug, feature, epic)")
+	issueType := fs.String("t", "", "Issue type (default: the repo's first issue type, normally task)")
 	description := fs.String("d", "", "Description")
+	estimate := fs.Int("e", 0, "Estimate (points or hours)")
+	parent := fs.Int("parent", 0, "Parent (epic) issue ID")
+	fromFile := fs.String("from-file", "", "Create every issue in a JSON array of create requests (- for stdin)")
 
 	if err := fs.Parse(reorderArgs(args)); err != nil {
 		return err
 	}
 
+	if *fromFile != "" {
+		return runCreateFromFile(*fromFile, gf)
+	}
+
 	remaining := fs.Args()
 	if len(remaining) == 0 {
-		return fmt.Errorf("usage: bor create \"title\" [-p priority] [-t type] [-d description]")
+		return fmt.Errorf("usage: bor create \"title\" [-p priority] [-t type] [-d description] [-e estimate] [--parent id]\n       bor create --from-file <issues.json|->")
 	}
 	title := remaining[0]
 
@@ -32,6 +41,12 @@ func runCreate(args []string, gf globalFlags) error {
 	if *priority != 0 {
 		req.Priority = priority
 	}
+	if *estimate != 0 {
+		req.Estimate = estimate
+	}
+	if *parent != 0 {
+		req.ParentID = parent
+	}
 
 	issue, err := client.CreateIssue(repo, req)
 	if err != nil {
@@ -41,3 +56,41 @@ func runCreate(args []string, gf globalFlags) error {
 	printIssue(issue, gf.pretty)
 	return nil
 }
+
+// runCreateFromFile creates the issues in a JSON array file, or stdin given
+// "-", in one request. Entries the daemon rejects are reported, and make
+// the command fail once the rest are created.
+func runCreateFromFile(path string, gf globalFlags) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("create issues: %w", err)
+	}
+
+	result, err := newClient(gf).BulkCreateIssues(resolveRepo(gf), data)
+	if err != nil {
+		return fmt.Errorf("create issues: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(result)
+	} else {
+		fmt.Printf("Created %d issues, %d failed.\n", result.Created, result.Failed)
+		for _, item := range result.Results {
+			if item.Issue != nil {
+				fmt.Printf("  #%d %s\n", item.Issue.ID, item.Issue.Title)
+			} else {
+				fmt.Printf("  entry %d: %s\n", item.Index, item.Error)
+			}
+		}
+	}
+	if result.Failed > 0 {
+		return fmt.Errorf("create issues: %d of %d entries failed", result.Failed, len(result.Results))
+	}
+	return nil
+}
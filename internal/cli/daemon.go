@@ -39,18 +39,22 @@ func runDaemon(args []string, gf globalFlags) error {
 func runDaemonStart(args []string, gf globalFlags) error {
 	fs := flag.NewFlagSet("daemon start", flag.ContinueOnError)
 	foreground := fs.Bool("foreground", false, "Run in foreground (default: background)")
+	offline := fs.Bool("offline", false, "Run without GitHub: local tracking only, sync disabled")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
 	if *foreground {
-		return runDaemonForeground(gf)
+		return runDaemonForeground(gf, *offline)
 	}
-	return runDaemonBackground(gf)
+	return runDaemonBackground(gf, *offline)
 }
 
-func runDaemonForeground(gf globalFlags) error {
+func runDaemonForeground(gf globalFlags, offline bool) error {
+	// Log lines written while serving a request carry its request_id.
+	slog.SetDefault(slog.New(daemon.LogHandler{Handler: slog.NewTextHandler(os.Stderr, nil)}))
+
 	// 1. Load config.
 	cfg, err := config.Load()
 	if err != nil {
@@ -61,17 +65,34 @@ func runDaemonForeground(gf globalFlags) error {
 	}
 
 	// 2. Open SQLite store.
-	st, err := store.NewSQLiteStore(cfg.DBPath)
+	st, err := store.NewSQLiteStoreWithOptions(cfg.DBPath, store.SQLiteOptions{
+		BusyTimeoutMs:         cfg.BusyTimeoutMs,
+		Synchronous:           cfg.Synchronous,
+		MaxInlineCommentBytes: cfg.MaxInlineCommentBytes,
+	})
 	if err != nil {
 		return fmt.Errorf("open store: %w", err)
 	}
 	defer st.Close()
 
-	// 3. Resolve GitHub token (optional - warn if not found).
-	token, tokenErr := github.ResolveToken()
+	// 3. Resolve GitHub token (optional - warn if not found), unless running
+	// offline by flag or config. GitHub App credentials, when configured,
+	// take the place of a token.
 	var ghClient github.Client
-	if tokenErr == nil {
-		ghClient = github.NewClient(token)
+	retry := github.DefaultRetryPolicy
+	retry.MaxRetries = cfg.GitHubMaxRetries
+	if offline || cfg.Offline {
+		slog.Info("offline mode requested, GitHub sync disabled")
+	} else if cfg.UsesGitHubApp() {
+		key, keyErr := github.LoadPrivateKey(cfg.GitHubAppPrivateKeyPath)
+		if keyErr != nil {
+			return fmt.Errorf("load GitHub App key: %w", keyErr)
+		}
+		tokens := github.NewAppTokenProvider(cfg.GitHubAppID, cfg.GitHubAppInstallationID, key, cfg.GitHubBaseURL())
+		ghClient = github.NewClientWithProvider(tokens, cfg.GitHubBaseURL(), retry)
+		slog.Info("authenticating as GitHub App", "app_id", cfg.GitHubAppID, "installation_id", cfg.GitHubAppInstallationID)
+	} else if token, tokenErr := github.ResolveToken(); tokenErr == nil {
+		ghClient = github.NewClientWithRetry(token, cfg.GitHubBaseURL(), retry)
 	} else {
 		slog.Info("GitHub token not found, sync disabled", "error", tokenErr)
 	}
@@ -79,7 +100,10 @@ func runDaemonForeground(gf globalFlags) error {
 	// 4. Create SyncManager (if we have a GitHub client).
 	var syncMgr *sync.SyncManager
 	if ghClient != nil {
+		st.SetCommentFetcher(sync.CommentFetcher(st, ghClient))
 		syncMgr = sync.NewSyncManager(st, ghClient)
+		syncMgr.SetMaxPollInterval(time.Duration(cfg.MaxPollIntervalMs) * time.Millisecond)
+		syncMgr.SetPriorityRange(cfg.PriorityRange())
 		// Start syncers for all registered repos.
 		repos, listErr := st.ListRepos(context.Background())
 		if listErr != nil {
@@ -99,7 +123,7 @@ func runDaemonForeground(gf globalFlags) error {
 	return d.Run(context.Background())
 }
 
-func runDaemonBackground(gf globalFlags) error {
+func runDaemonBackground(gf globalFlags, offline bool) error {
 	// Check if already running by hitting health endpoint.
 	client := newClient(gf)
 	if _, err := client.Health(); err == nil {
@@ -126,7 +150,11 @@ func runDaemonBackground(gf globalFlags) error {
 		return fmt.Errorf("open log file: %w", err)
 	}
 
-	cmd := exec.Command(executable, "daemon", "start", "--foreground")
+	childArgs := []string{"daemon", "start", "--foreground"}
+	if offline {
+		childArgs = append(childArgs, "--offline")
+	}
+	cmd := exec.Command(executable, childArgs...)
 	cmd.Stdout = logFile
 	cmd.Stderr = logFile
 	setSysProcAttr(cmd)
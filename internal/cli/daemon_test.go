@@ -120,7 +120,7 @@ func TestRunDaemonBackground_AlreadyRunning(t *testing.T) {
 	t.Cleanup(ts.Close)
 
 	gf := globalFlags{host: ts.URL}
-	err := runDaemonBackground(gf)
+	err := runDaemonBackground(gf, false)
 	if err == nil {
 		t.Fatal("expected error when daemon already running")
 	}
@@ -128,3 +128,58 @@ func TestRunDaemonBackground_AlreadyRunning(t *testing.T) {
 		t.Errorf("expected 'already running' in error, got: %v", err)
 	}
 }
+
+func TestRunStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		w.Write([]byte(`{
+			"status": "ok", "mode": "online", "uptime": "1h2m3s",
+			"repos": ["acme/api", "acme/web"],
+			"sync_status": {
+				"acme/web": {"pending_events": 0, "syncing": true, "poll_interval_ms": 60000},
+				"acme/api": {"last_sync": "2026-03-01T12:00:00Z", "pending_events": 7, "syncing": false,
+					"poll_interval_ms": 5000, "last_error": "list issues: 502 Bad Gateway"}
+			}
+		}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	out := captureStdout(t, func() {
+		if err := runStatus(globalFlags{host: ts.URL, pretty: true}); err != nil {
+			t.Errorf("runStatus: %v", err)
+		}
+	})
+
+	lines := strings.Split(out, "\n")
+	var api, web string
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, "acme/api"):
+			api = line
+		case strings.Contains(line, "acme/web"):
+			web = line
+		}
+	}
+	lastSync := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC).Local().Format("2006-01-02 15:04:05")
+	for _, want := range []string{"! acme/api", lastSync, " 7 ", " no ", "5s", "list issues: 502 Bad Gateway"} {
+		if !strings.Contains(api, want) {
+			t.Errorf("acme/api line %q lacks %q", api, want)
+		}
+	}
+	for _, want := range []string{"never", " 0 ", " yes ", "1m0s", " -"} {
+		if !strings.Contains(web, want) {
+			t.Errorf("acme/web line %q lacks %q", web, want)
+		}
+	}
+	if strings.HasPrefix(web, "!") {
+		t.Errorf("acme/web has no error but is marked: %q", web)
+	}
+	if !strings.Contains(out, "1 of 2 repos failed their last sync") {
+		t.Errorf("output lacks the failure summary:\n%s", out)
+	}
+	if strings.Index(out, "acme/api") > strings.Index(out, "acme/web") {
+		t.Errorf("repos should be sorted by name:\n%s", out)
+	}
+}
@@ -1,10 +1,14 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
+	"github.com/jmaddaus/boxofrocks/internal/config"
+	"github.com/jmaddaus/boxofrocks/internal/engine"
 	"github.com/jmaddaus/boxofrocks/internal/store"
 	_ "modernc.org/sqlite"
 )
@@ -15,14 +19,54 @@ const dbUsage = `Usage:
 Commands:
   version   <db-path>             Show current DB schema version
   check     <db-path>             Check if DB is compatible with this binary
+  upgrade   <db-path> [version]   Upgrade DB to target version (default: latest)
   downgrade <db-path> <version>   Downgrade DB to target version
+  vacuum    <db-path>             Reclaim space left by deleted rows
+  compact   <db-path>             Collapse old events of closed issues into snapshots
+
+Flags:
+  --no-backup   Skip the backup downgrade takes before changing the DB
+  --purge       With vacuum, first delete rows of repos that no longer exist
+  --keep N      With compact, keep each issue's newest N events (default: 20)
 
 Examples:
   bor db version ~/.boxofrocks/bor.db
+  bor db upgrade ~/.boxofrocks/bor.db 12
   bor db downgrade ~/.boxofrocks/bor.db 1
-  bor db check ~/.boxofrocks/bor.db`
+  bor db check ~/.boxofrocks/bor.db
+  bor db vacuum ~/.boxofrocks/bor.db --purge
+  bor db compact ~/.boxofrocks/bor.db --keep 5`
+
+// defaultCompactKeep is how many of each issue's newest events compact
+// leaves alone unless --keep says otherwise.
+const defaultCompactKeep = 20
 
 func runDB(args []string, _ globalFlags) error {
+	noBackup, purge := false, false
+	keep := defaultCompactKeep
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--no-backup", "-no-backup":
+			noBackup = true
+		case "--purge", "-purge":
+			purge = true
+		case "--keep", "-keep":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--keep requires a number")
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid --keep value: %s", args[i])
+			}
+			keep = n
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	args = rest
+
 	if len(args) < 2 {
 		fmt.Fprintln(os.Stderr, dbUsage)
 		return fmt.Errorf("usage: bor db <command> <db-path>")
@@ -36,6 +80,16 @@ func runDB(args []string, _ globalFlags) error {
 		return runDBVersion(dbPath)
 	case "check":
 		return runDBCheck(dbPath)
+	case "upgrade":
+		target := store.DBSchemaVersion
+		if len(args) >= 3 {
+			v, err := strconv.Atoi(args[2])
+			if err != nil {
+				return fmt.Errorf("invalid version number: %s", args[2])
+			}
+			target = v
+		}
+		return runDBUpgrade(dbPath, target)
 	case "downgrade":
 		if len(args) < 3 {
 			return fmt.Errorf("downgrade requires a target version\n%s", dbUsage)
@@ -44,7 +98,11 @@ func runDB(args []string, _ globalFlags) error {
 		if err != nil {
 			return fmt.Errorf("invalid version number: %s", args[2])
 		}
-		return runDBDowngrade(dbPath, target)
+		return runDBDowngrade(dbPath, target, !noBackup)
+	case "vacuum":
+		return runDBVacuum(dbPath, purge)
+	case "compact":
+		return runDBCompact(dbPath, keep)
 	default:
 		return fmt.Errorf("unknown db subcommand: %s\n%s", command, dbUsage)
 	}
@@ -92,7 +150,38 @@ func runDBCheck(dbPath string) error {
 	return nil
 }
 
-func runDBDowngrade(dbPath string, target int) error {
+func runDBUpgrade(dbPath string, target int) error {
+	db, err := store.OpenRawDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	current, err := store.ReadDBVersion(db)
+	if err != nil {
+		return fmt.Errorf("read version: %w", err)
+	}
+
+	fmt.Printf("database: %s\n", dbPath)
+	fmt.Printf("current version: %d\n", current)
+	fmt.Printf("target version: %d\n", target)
+
+	if target <= current {
+		return fmt.Errorf("target version %d must be greater than current version %d", target, current)
+	}
+
+	err = store.UpgradeDB(db, current, target, func(version int, desc string) {
+		fmt.Printf("  applied v%d: %s\n", version, desc)
+	})
+	if err != nil {
+		return fmt.Errorf("upgrade: %w", err)
+	}
+
+	fmt.Printf("upgraded: %d → %d\n", current, target)
+	return nil
+}
+
+func runDBDowngrade(dbPath string, target int, backup bool) error {
 	db, err := store.OpenRawDB(dbPath)
 	if err != nil {
 		return fmt.Errorf("open database: %w", err)
@@ -112,6 +201,15 @@ func runDBDowngrade(dbPath string, target int) error {
 		return fmt.Errorf("target version %d must be less than current version %d", target, current)
 	}
 
+	// Down migrations may drop data, so keep a copy of file-backed DBs.
+	if backup && dbPath != ":memory:" {
+		backupPath := fmt.Sprintf("%s.pre-downgrade-%s.bak", dbPath, time.Now().UTC().Format("20060102T150405Z"))
+		if err := store.BackupDB(db, backupPath); err != nil {
+			return err
+		}
+		fmt.Printf("backup: %s\n", backupPath)
+	}
+
 	if err := store.DowngradeDB(db, current, target); err != nil {
 		return fmt.Errorf("downgrade: %w", err)
 	}
@@ -119,3 +217,67 @@ func runDBDowngrade(dbPath string, target int) error {
 	fmt.Printf("downgraded: %d → %d\n", current, target)
 	return nil
 }
+
+func runDBVacuum(dbPath string, purge bool) error {
+	db, err := store.OpenRawDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	fmt.Printf("database: %s\n", dbPath)
+	before := dbFileSize(dbPath)
+
+	result, err := store.VacuumDB(context.Background(), db, purge)
+	if err != nil {
+		return err
+	}
+
+	if purge {
+		fmt.Printf("purged rows: %d\n", result.PurgedRows)
+	}
+	if dbPath != ":memory:" {
+		after := dbFileSize(dbPath)
+		fmt.Printf("size: %d → %d bytes (freed %d)\n", before, after, before-after)
+	}
+	return nil
+}
+
+func runDBCompact(dbPath string, keep int) error {
+	db, err := store.OpenRawDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	// Replay as the daemon does, so the snapshots keep priorities in its
+	// configured range.
+	var opts []engine.Option
+	if cfg, err := config.Load(); err == nil {
+		opts = append(opts, engine.WithPriorityRange(cfg.PriorityRange()))
+	}
+
+	fmt.Printf("database: %s\n", dbPath)
+	result, err := store.CompactEventsDB(context.Background(), db, 0, keep, opts...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("compacted issues: %d\n", result.Issues)
+	fmt.Printf("events removed: %d\n", result.EventsRemoved)
+	if result.EventsRemoved > 0 {
+		fmt.Printf("run bor db vacuum %s to return the space to the filesystem\n", dbPath)
+	}
+	return nil
+}
+
+// dbFileSize returns the size of a SQLite database file and its WAL, or 0
+// for files that cannot be read.
+func dbFileSize(dbPath string) int64 {
+	var size int64
+	for _, path := range []string{dbPath, dbPath + "-wal"} {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
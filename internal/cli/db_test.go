@@ -1,8 +1,10 @@
 package cli
 
 import (
+	"path/filepath"
 	"testing"
 
+	"github.com/jmaddaus/boxofrocks/internal/store"
 	_ "modernc.org/sqlite"
 )
 
@@ -62,3 +64,118 @@ func TestRunDBDowngradeTargetNotLess(t *testing.T) {
 		t.Fatal("expected error when target >= current")
 	}
 }
+
+func TestRunDBUpgradeInvalidVersion(t *testing.T) {
+	err := runDB([]string{"upgrade", ":memory:", "abc"}, globalFlags{})
+	if err == nil {
+		t.Fatal("expected error for invalid version number")
+	}
+}
+
+func TestRunDBUpgrade(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "bor.db")
+	if err := runDB([]string{"upgrade", dbPath, "1"}, globalFlags{}); err != nil {
+		t.Fatalf("upgrade to 1: %v", err)
+	}
+	if err := runDB([]string{"upgrade", dbPath, "8"}, globalFlags{}); err != nil {
+		t.Fatalf("upgrade to 8: %v", err)
+	}
+
+	db, err := store.OpenRawDB(dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	if version, _ := store.ReadDBVersion(db); version != 8 {
+		t.Errorf("expected version 8, got %d", version)
+	}
+
+	// Upgrading to the current version is an error, as with downgrade.
+	if err := runDB([]string{"upgrade", dbPath, "8"}, globalFlags{}); err == nil {
+		t.Error("expected error when target <= current")
+	}
+	if err := runDB([]string{"upgrade", dbPath}, globalFlags{}); err != nil {
+		t.Fatalf("upgrade to latest: %v", err)
+	}
+	if version, _ := store.ReadDBVersion(db); version != store.DBSchemaVersion {
+		t.Errorf("expected version %d, got %d", store.DBSchemaVersion, version)
+	}
+}
+
+func TestRunDBDowngradeBackup(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "bor.db")
+	if err := runDB([]string{"upgrade", dbPath}, globalFlags{}); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	if err := runDB([]string{"downgrade", dbPath, "5"}, globalFlags{}); err != nil {
+		t.Fatalf("downgrade: %v", err)
+	}
+
+	backups, _ := filepath.Glob(dbPath + ".pre-downgrade-*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup file, got %v", backups)
+	}
+	db, err := store.OpenRawDB(backups[0])
+	if err != nil {
+		t.Fatalf("open backup: %v", err)
+	}
+	defer db.Close()
+	var check string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&check); err != nil || check != "ok" {
+		t.Fatalf("backup integrity_check = %q, %v", check, err)
+	}
+	if version, _ := store.ReadDBVersion(db); version != store.DBSchemaVersion {
+		t.Errorf("backup should keep the pre-downgrade version %d, got %d", store.DBSchemaVersion, version)
+	}
+
+	// --no-backup skips the copy.
+	if err := runDB([]string{"downgrade", "--no-backup", dbPath, "1"}, globalFlags{}); err != nil {
+		t.Fatalf("downgrade --no-backup: %v", err)
+	}
+	if backups, _ := filepath.Glob(dbPath + ".pre-downgrade-*.bak"); len(backups) != 1 {
+		t.Errorf("expected --no-backup to add no backup, got %v", backups)
+	}
+}
+
+func TestRunDBVacuum(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "bor.db")
+	if err := runDB([]string{"upgrade", dbPath}, globalFlags{}); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	if err := runDB([]string{"vacuum", dbPath}, globalFlags{}); err != nil {
+		t.Fatalf("vacuum: %v", err)
+	}
+	if err := runDB([]string{"vacuum", "--purge", dbPath}, globalFlags{}); err != nil {
+		t.Fatalf("vacuum --purge: %v", err)
+	}
+	// Purging needs the current schema.
+	if err := runDB([]string{"downgrade", "--no-backup", dbPath, "1"}, globalFlags{}); err != nil {
+		t.Fatalf("downgrade: %v", err)
+	}
+	if err := runDB([]string{"vacuum", "--purge", dbPath}, globalFlags{}); err == nil {
+		t.Error("expected vacuum --purge to refuse an old schema")
+	}
+}
+
+func TestRunDBCompact(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "bor.db")
+	if err := runDB([]string{"upgrade", dbPath}, globalFlags{}); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	if err := runDB([]string{"compact", dbPath, "--keep", "5"}, globalFlags{}); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if err := runDB([]string{"compact", dbPath, "--keep", "-1"}, globalFlags{}); err == nil {
+		t.Error("expected error for a negative --keep")
+	}
+	if err := runDB([]string{"compact", dbPath, "--keep"}, globalFlags{}); err == nil {
+		t.Error("expected error for --keep without a value")
+	}
+	// Compaction needs the current schema.
+	if err := runDB([]string{"downgrade", "--no-backup", dbPath, "1"}, globalFlags{}); err != nil {
+		t.Fatalf("downgrade: %v", err)
+	}
+	if err := runDB([]string{"compact", dbPath}, globalFlags{}); err == nil {
+		t.Error("expected compact to refuse an old schema")
+	}
+}
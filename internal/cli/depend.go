@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+)
+
+func runDepend(args []string, gf globalFlags) error {
+	if len(args) < 3 || (args[0] != "add" && args[0] != "remove") {
+		return fmt.Errorf("usage: bor depend <add|remove> <id> <blocker-id>")
+	}
+
+	id, err := issueID(gf, args[1])
+	if err != nil {
+		return fmt.Errorf("invalid issue id %q: %w", args[1], err)
+	}
+	blockerID, err := strconv.Atoi(args[2])
+	if err != nil {
+		return fmt.Errorf("invalid blocker id %q: %w", args[2], err)
+	}
+
+	client := newClient(gf)
+
+	change := client.AddDependency
+	if args[0] == "remove" {
+		change = client.RemoveDependency
+	}
+	issue, err := change(id, blockerID)
+	if err != nil {
+		return fmt.Errorf("%s dependency: %w", args[0], err)
+	}
+
+	printIssue(issue, gf.pretty)
+	return nil
+}
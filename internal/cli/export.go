@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+)
+
+// runExport writes the daemon's export document to stdout. Without --repo
+// (or the global -r) it exports every repo; the repo is not detected from
+// the working directory.
+func runExport(args []string, gf globalFlags) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	repo := fs.String("repo", gf.repo, "Export only this repository (owner/name)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: bor export [--repo owner/name] > dump.json")
+	}
+
+	if err := newClient(gf).Export(*repo, os.Stdout); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+// runImport loads an export document, from a file or from stdin given
+// "-", into the daemon's store.
+func runImport(args []string, gf globalFlags) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: bor import <dump.json|->")
+	}
+
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	result, err := newClient(gf).Import(data)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(result)
+		return nil
+	}
+	fmt.Printf("Imported %d repos, %d issues, %d events.\n", result.Repos, result.Issues, result.Events)
+	for _, renumbered := range []struct {
+		kind string
+		ids  map[int]int
+	}{{"repo", result.RenumberedRepos}, {"issue", result.RenumberedIssues}, {"event", result.RenumberedEvents}} {
+		for _, old := range slices.Sorted(maps.Keys(renumbered.ids)) {
+			fmt.Printf("  %s %d is now %d\n", renumbered.kind, old, renumbered.ids[old])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func runHistory(args []string, gf globalFlags) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: bor history <id> <status|owner|priority|issue_type|title>")
+	}
+
+	id, err := issueID(gf, args[0])
+	if err != nil {
+		return fmt.Errorf("invalid issue id %q: %w", args[0], err)
+	}
+	field := args[1]
+
+	client := newClient(gf)
+
+	history, err := client.FieldHistory(id, field)
+	if err != nil {
+		return fmt.Errorf("field history: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(history)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tVALUE\tAGENT")
+	for _, c := range history {
+		agent := c.Agent
+		if agent == "" {
+			agent = "-"
+		}
+		fmt.Fprintf(w, "%s\t%v\t%s\n", c.Timestamp.Local().Format("2006-01-02 15:04:05"), c.Value, agent)
+	}
+	return w.Flush()
+}
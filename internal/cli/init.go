@@ -71,13 +71,25 @@ func runInit(args []string, gf globalFlags) error {
 	jsonFlag := fs.Bool("json", false, "Enable file-based queue for sandbox agents")
 	updateArbiter := fs.Bool("update-arbiter", false, "Update arbiter workflow to current version")
 	importAll := fs.Bool("import-all", false, "Label all existing open GitHub issues with 'boxofrocks' for sync")
+	pathFlag := fs.String("path", "", "Local directory to register (default: current directory)")
 
+	// The repo may be given positionally, before or after the flags.
+	var positional string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		positional, args = args[0], args[1:]
+	}
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if positional == "" {
+		positional = fs.Arg(0)
+	}
 
 	// Determine the repo.
 	repo := *repoFlag
+	if repo == "" {
+		repo = positional
+	}
 	if repo == "" {
 		repo = resolveRepo(gf)
 	}
@@ -95,7 +107,7 @@ func runInit(args []string, gf globalFlags) error {
 	// Step 1: Ensure daemon is running. Auto-start in background if not.
 	if _, err := client.Health(); err != nil {
 		fmt.Println("Daemon not running. Starting in background...")
-		if startErr := runDaemonBackground(gf); startErr != nil {
+		if startErr := runDaemonBackground(gf, false); startErr != nil {
 			return fmt.Errorf("auto-start daemon: %w\nStart it manually with: bor daemon start", startErr)
 		}
 		// Wait for daemon to be fully ready.
@@ -119,9 +131,16 @@ func runInit(args []string, gf globalFlags) error {
 		"owner": parts[0],
 		"name":  parts[1],
 	}
-	localPath, err := os.Getwd()
+	localPath := *pathFlag
+	if localPath == "" {
+		localPath = "."
+	}
+	localPath, err := filepath.Abs(localPath)
 	if err != nil {
-		return fmt.Errorf("get working directory: %w", err)
+		return fmt.Errorf("resolve local path: %w", err)
+	}
+	if info, err := os.Stat(localPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("local path %s is not a directory", localPath)
 	}
 	repoBody["local_path"] = localPath
 	if *socketFlag {
@@ -233,7 +252,18 @@ func runInit(args []string, gf globalFlags) error {
 		}
 	}
 
-	// Step 6: Import all existing issues if --import-all.
+	// Step 6: Ensure the tracking label exists on GitHub.
+	if !*offline {
+		if _, err := client.EnsureLabels(repo); err != nil {
+			if gf.pretty {
+				fmt.Printf("Warning: could not ensure labels: %v\n", err)
+			}
+		} else if gf.pretty {
+			fmt.Println("Tracking label present on GitHub.")
+		}
+	}
+
+	// Step 7: Import all existing issues if --import-all.
 	if *importAll && !*offline {
 		result, err := client.ImportIssues(repo)
 		if err != nil {
@@ -247,7 +277,7 @@ func runInit(args []string, gf globalFlags) error {
 		}
 	}
 
-	// Step 7: Trigger initial sync unless --offline.
+	// Step 8: Trigger initial sync unless --offline.
 	if !*offline {
 		if err := client.ForceSync(repo); err != nil {
 			// Non-fatal: sync might not be available.
@@ -261,7 +291,7 @@ func runInit(args []string, gf globalFlags) error {
 		}
 	}
 
-	// Step 8: Print result.
+	// Step 9: Print result.
 	if gf.pretty {
 		fmt.Println()
 		fmt.Println("Ready! Run 'bor list' to see issues.")
@@ -29,7 +29,7 @@ func TestRunInit_DaemonAlreadyRunning(t *testing.T) {
 	t.Cleanup(ts.Close)
 
 	gf := globalFlags{host: ts.URL}
-	err := runInit([]string{"--repo", "owner/name"}, gf)
+	err := runInit([]string{"--repo", "owner/name", "--path", t.TempDir()}, gf)
 	if err != nil {
 		t.Fatalf("runInit: %v", err)
 	}
@@ -59,12 +59,58 @@ func TestRunInit_AlreadyRegistered(t *testing.T) {
 	t.Cleanup(ts.Close)
 
 	gf := globalFlags{host: ts.URL}
-	err := runInit([]string{"--repo", "owner/name"}, gf)
+	err := runInit([]string{"--repo", "owner/name", "--path", t.TempDir()}, gf)
 	if err != nil {
 		t.Fatalf("runInit should succeed for already-registered repo: %v", err)
 	}
 }
 
+func TestRunInit_PositionalRepoAndPath(t *testing.T) {
+	dir := t.TempDir()
+	var registered map[string]interface{}
+	labelsEnsured := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/health":
+			json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+		case r.URL.Path == "/repos" && r.Method == "POST":
+			json.NewDecoder(r.Body).Decode(&registered)
+			json.NewEncoder(w).Encode(map[string]string{"message": "created"})
+		case r.URL.Path == "/repos/ensure-labels" && r.Method == "POST":
+			labelsEnsured = true
+			if got := r.URL.Query().Get("repo"); got != "owner/name" {
+				t.Errorf("ensure-labels repo: want owner/name, got %q", got)
+			}
+			json.NewEncoder(w).Encode(EnsureLabelsResult{Repo: "owner/name", Created: []string{"boxofrocks"}})
+		case r.URL.Path == "/sync" && r.Method == "POST":
+			json.NewEncoder(w).Encode(map[string]string{"message": "synced"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	gf := globalFlags{host: ts.URL}
+	if err := runInit([]string{"owner/name", "--path", dir, "--socket"}, gf); err != nil {
+		t.Fatalf("runInit: %v", err)
+	}
+	if registered["owner"] != "owner" || registered["name"] != "name" {
+		t.Errorf("registered wrong repo: %v", registered)
+	}
+	if registered["local_path"] != dir {
+		t.Errorf("local_path: want %s, got %v", dir, registered["local_path"])
+	}
+	if registered["socket"] != true {
+		t.Errorf("expected socket enabled, got %v", registered["socket"])
+	}
+	if !labelsEnsured {
+		t.Error("expected labels to be ensured")
+	}
+	if _, err := os.Stat(dir + "/.github/workflows/arbiter.yml"); err != nil {
+		t.Errorf("expected arbiter workflow under --path: %v", err)
+	}
+}
+
 func TestRunInit_InvalidRepoFormat(t *testing.T) {
 	gf := globalFlags{host: "http://localhost:9999"}
 	tests := []string{"noslash", "", "/", "a/", "/b"}
@@ -100,7 +146,7 @@ func TestRunInit_Offline(t *testing.T) {
 	t.Cleanup(ts.Close)
 
 	gf := globalFlags{host: ts.URL}
-	err := runInit([]string{"--repo", "owner/name", "--offline"}, gf)
+	err := runInit([]string{"--repo", "owner/name", "--path", t.TempDir(), "--offline"}, gf)
 	if err != nil {
 		t.Fatalf("runInit --offline: %v", err)
 	}
@@ -133,7 +179,7 @@ func TestRunInit_JSONOutput(t *testing.T) {
 	os.Stdout = w
 
 	gf := globalFlags{host: ts.URL, pretty: false}
-	err := runInit([]string{"--repo", "owner/name"}, gf)
+	err := runInit([]string{"--repo", "owner/name", "--path", t.TempDir()}, gf)
 
 	w.Close()
 	os.Stdout = old
@@ -205,7 +251,7 @@ func TestRunInit_AuthGuidance(t *testing.T) {
 	os.Stdout = w
 
 	gf := globalFlags{host: ts.URL, pretty: true}
-	err := runInit([]string{"--repo", "owner/name"}, gf)
+	err := runInit([]string{"--repo", "owner/name", "--path", t.TempDir()}, gf)
 
 	w.Close()
 	os.Stdout = old
@@ -0,0 +1,28 @@
+package cli
+
+import "fmt"
+
+func runLabel(args []string, gf globalFlags) error {
+	if len(args) < 3 || (args[0] != "add" && args[0] != "remove") {
+		return fmt.Errorf("usage: bor label <add|remove> <id> <label>")
+	}
+
+	id, err := issueID(gf, args[1])
+	if err != nil {
+		return fmt.Errorf("invalid issue id %q: %w", args[1], err)
+	}
+
+	client := newClient(gf)
+
+	change := client.AddLabel
+	if args[0] == "remove" {
+		change = client.RemoveLabel
+	}
+	issue, err := change(id, args[2])
+	if err != nil {
+		return fmt.Errorf("%s label: %w", args[0], err)
+	}
+
+	printIssue(issue, gf.pretty)
+	return nil
+}
@@ -8,8 +8,16 @@ import (
 func runList(args []string, gf globalFlags) error {
 	fs := flag.NewFlagSet("list", flag.ContinueOnError)
 	all := fs.Bool("all", false, "Include deleted issues")
-	status := fs.String("status", "", "Filter by status (open, in_progress, blocked, in_review, closed, deleted)")
+	status := fs.String("status", "", "Filter by status (open, in_progress, blocked, in_review, closed, deleted, or actionable); separate several with commas")
 	priority := fs.String("priority", "", "Filter by priority")
+	includeSnoozed := fs.Bool("include-snoozed", false, "Include snoozed issues")
+	owner := fs.String("owner", "", "Filter by owner (@me for $BOR_AGENT)")
+	var labels stringsFlag
+	fs.Var(&labels, "label", "Filter by label (repeat to require several)")
+	sort := fs.String("sort", "", "Sort by priority (default), created or updated")
+	desc := fs.Bool("desc", false, "Reverse the sort order")
+	limit := fs.Int("limit", 0, "Maximum issues to show (daemon default 100, at most 1000)")
+	offset := fs.Int("offset", 0, "Skip this many issues")
 
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -19,9 +27,16 @@ func runList(args []string, gf globalFlags) error {
 	repo := resolveRepo(gf)
 
 	issues, err := client.ListIssues(repo, ListOpts{
-		Status:   *status,
-		Priority: *priority,
-		All:      *all,
+		Status:         *status,
+		Priority:       *priority,
+		All:            *all,
+		IncludeSnoozed: *includeSnoozed,
+		Owner:          *owner,
+		Labels:         labels,
+		Sort:           *sort,
+		Desc:           *desc,
+		Limit:          *limit,
+		Offset:         *offset,
 	})
 	if err != nil {
 		return fmt.Errorf("list issues: %w", err)
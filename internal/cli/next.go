@@ -1,12 +1,70 @@
 package cli
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
 
 func runNext(args []string, gf globalFlags) error {
+	fs := flag.NewFlagSet("next", flag.ContinueOnError)
+	budget := fs.Int("budget", -1, "Only consider issues whose estimate fits this budget")
+	owner := fs.String("owner", "", "Resume this owner's unfinished work instead (@me for $BOR_AGENT)")
+	explain := fs.Bool("explain", false, "When no issue is eligible, report why instead of failing")
+	count := fs.Int("count", 0, "List up to this many eligible issues, for handing out work in a batch")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
 	client := newClient(gf)
 	repo := resolveRepo(gf)
 
-	issue, err := client.NextIssue(repo)
+	if *count > 0 {
+		if *owner != "" || *budget >= 0 || *explain {
+			return fmt.Errorf("--count cannot be combined with --owner, --budget or --explain")
+		}
+		issues, err := client.NextIssues(repo, *count)
+		if err != nil {
+			return fmt.Errorf("next issues: %w", err)
+		}
+		printIssueList(issues, gf.pretty)
+		return nil
+	}
+
+	if *explain {
+		if *owner != "" {
+			return fmt.Errorf("--explain cannot be combined with --owner")
+		}
+		result, err := client.NextIssueExplained(repo, *budget)
+		if err != nil {
+			return fmt.Errorf("next issue: %w", err)
+		}
+		if !gf.pretty {
+			printJSON(result)
+			return nil
+		}
+		if result.Next != nil {
+			printIssue(result.Next, gf.pretty)
+			return nil
+		}
+		fmt.Printf("No issue available: %s.\n", result.Reason)
+		return nil
+	}
+
+	var issue *model.Issue
+	var err error
+	if *owner != "" {
+		issue, err = client.NextIssueForOwner(repo, *owner)
+	} else if *budget >= 0 {
+		issue, err = client.NextIssueWithinBudget(repo, *budget)
+	} else {
+		issue, err = client.NextIssue(repo)
+	}
 	if err != nil {
 		return fmt.Errorf("next issue: %w", err)
 	}
@@ -14,3 +72,128 @@ func runNext(args []string, gf globalFlags) error {
 	printIssue(issue, gf.pretty)
 	return nil
 }
+
+func runClaim(args []string, gf globalFlags) error {
+	fs := flag.NewFlagSet("claim", flag.ContinueOnError)
+	owner := fs.String("owner", "@me", "Owner to assign the issue to (@me for $BOR_AGENT)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client := newClient(gf)
+	issue, err := client.ClaimIssue(resolveRepo(gf), *owner)
+	if err != nil {
+		return fmt.Errorf("claim issue: %w", err)
+	}
+
+	printIssue(issue, gf.pretty)
+	return nil
+}
+
+func runPlan(args []string, gf globalFlags) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	budget := fs.Int("budget", -1, "Total estimate budget for the work session (required)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *budget < 0 {
+		return fmt.Errorf("usage: bor plan --budget N")
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	plan, err := client.PlanIssues(repo, *budget)
+	if err != nil {
+		return fmt.Errorf("plan issues: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(plan)
+		return nil
+	}
+
+	if len(plan.Issues) == 0 {
+		fmt.Println("No issues fit the budget.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPRI\tEST\tTITLE")
+	for _, iss := range plan.Issues {
+		fmt.Fprintf(w, "#%d\t%d\t%d\t%s\n", iss.ID, iss.Priority, iss.Estimate, iss.Title)
+	}
+	w.Flush()
+	fmt.Printf("Total estimate: %d of %d\n", plan.Total, plan.Budget)
+	return nil
+}
+
+func runTrending(args []string, gf globalFlags) error {
+	fs := flag.NewFlagSet("trending", flag.ContinueOnError)
+	days := fs.Int("days", 7, "Count activity over this many days")
+	limit := fs.Int("limit", 10, "Show at most this many issues")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	result, err := client.TrendingIssues(repo, *days, *limit)
+	if err != nil {
+		return fmt.Errorf("trending issues: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(result)
+		return nil
+	}
+
+	if len(result.Issues) == 0 {
+		fmt.Printf("No activity in the last %d days.\n", result.Days)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSCORE\tEVENTS\tCOMMENTS\tAGENTS\tTITLE")
+	for _, a := range result.Issues {
+		fmt.Fprintf(w, "#%d\t%d\t%d\t%d\t%d\t%s\n", a.Issue.ID, a.Score, a.Events, a.Comments, a.Agents, a.Issue.Title)
+	}
+	return w.Flush()
+}
+
+func runStale(args []string, gf globalFlags) error {
+	fs := flag.NewFlagSet("stale", flag.ContinueOnError)
+	days := fs.Int("days", 14, "Show issues not updated in this many days")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	result, err := client.StaleIssues(repo, *days)
+	if err != nil {
+		return fmt.Errorf("stale issues: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(result)
+		return nil
+	}
+
+	if len(result.Issues) == 0 {
+		fmt.Printf("No open or blocked issues idle for %d days.\n", result.Days)
+		return nil
+	}
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tIDLE\tSTATUS\tOWNER\tTITLE")
+	for _, iss := range result.Issues {
+		idle := int(now.Sub(iss.UpdatedAt).Hours() / 24)
+		fmt.Fprintf(w, "#%d\t%dd\t%s\t%s\t%s\n", iss.ID, idle, iss.Status, iss.Owner, iss.Title)
+	}
+	return w.Flush()
+}
@@ -67,6 +67,9 @@ func printPrettyIssue(issue *model.Issue) {
 	fmt.Printf("  Status:      %s\n", issue.Status)
 	fmt.Printf("  Priority:    %d\n", issue.Priority)
 	fmt.Printf("  Type:        %s\n", issue.IssueType)
+	if issue.Estimate != 0 {
+		fmt.Printf("  Estimate:    %d\n", issue.Estimate)
+	}
 	if issue.Owner != "" {
 		fmt.Printf("  Owner:       %s\n", issue.Owner)
 	}
@@ -76,11 +79,17 @@ func printPrettyIssue(issue *model.Issue) {
 	if len(issue.Labels) > 0 {
 		fmt.Printf("  Labels:      %v\n", issue.Labels)
 	}
+	if len(issue.Watchers) > 0 {
+		fmt.Printf("  Watchers:    %v\n", issue.Watchers)
+	}
 	fmt.Printf("  Created:     %s\n", issue.CreatedAt.Format("2006-01-02 15:04:05"))
 	fmt.Printf("  Updated:     %s\n", issue.UpdatedAt.Format("2006-01-02 15:04:05"))
 	if issue.ClosedAt != nil {
 		fmt.Printf("  Closed:      %s\n", issue.ClosedAt.Format("2006-01-02 15:04:05"))
 	}
+	if issue.SnoozedUntil != nil {
+		fmt.Printf("  Snoozed:     until %s\n", issue.SnoozedUntil.Local().Format("2006-01-02 15:04:05"))
+	}
 	if len(issue.Comments) > 0 {
 		fmt.Println("  Comments:")
 		for _, c := range issue.Comments {
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func runPending(gf globalFlags) error {
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	result, err := client.PendingEvents(repo)
+	if err != nil {
+		return fmt.Errorf("pending: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(result)
+		return nil
+	}
+
+	if len(result.Events) == 0 {
+		fmt.Println("No pending events.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EVENT\tISSUE\tACTION\tAGE\tTITLE")
+	for _, ev := range result.Events {
+		age := (time.Duration(ev.AgeSeconds) * time.Second).String()
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", ev.ID, ev.IssueID, ev.Action, age, ev.IssueTitle)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,27 @@
+package cli
+
+import "fmt"
+
+func runRepair(gf globalFlags) error {
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	result, err := client.RepairRepo(repo)
+	if err != nil {
+		return fmt.Errorf("repair: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(result)
+		return nil
+	}
+
+	fmt.Printf("Checked %d issues in %s, repaired %d.\n", result.Checked, result.Repo, len(result.Repaired))
+	for _, id := range result.Repaired {
+		fmt.Printf("  repaired #%d\n", id)
+	}
+	for _, s := range result.Skipped {
+		fmt.Printf("  skipped #%d: %s\n", s.IssueID, s.Reason)
+	}
+	return nil
+}
@@ -8,6 +8,13 @@ import (
 )
 
 func runRepos(args []string, gf globalFlags) error {
+	if len(args) > 0 && args[0] == "ensure-labels" {
+		return runReposEnsureLabels(args[1:], gf)
+	}
+	if len(args) > 0 && args[0] == "remove" {
+		return runReposRemove(args[1:], gf)
+	}
+
 	client := newClient(gf)
 
 	repos, err := client.ListRepos()
@@ -55,3 +62,49 @@ func runRepos(args []string, gf globalFlags) error {
 	w.Flush()
 	return nil
 }
+
+func runReposEnsureLabels(args []string, gf globalFlags) error {
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	result, err := client.EnsureLabels(repo)
+	if err != nil {
+		return fmt.Errorf("ensure labels: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(result)
+		return nil
+	}
+
+	for _, name := range result.Created {
+		fmt.Printf("Created label %q in %s\n", name, result.Repo)
+	}
+	for _, name := range result.Existing {
+		fmt.Printf("Label %q already exists in %s\n", name, result.Repo)
+	}
+	return nil
+}
+
+// runReposRemove deletes a registered repo and all of its local data. The
+// repo must be named explicitly rather than resolved from the working
+// directory, since the deletion cannot be undone.
+func runReposRemove(args []string, gf globalFlags) error {
+	if len(args) != 1 || !strings.Contains(args[0], "/") {
+		return fmt.Errorf("usage: bor repos remove <owner/name>")
+	}
+
+	client := newClient(gf)
+
+	repo, err := client.DeleteRepo(args[0])
+	if err != nil {
+		return fmt.Errorf("remove repo: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(repo)
+		return nil
+	}
+	fmt.Printf("Removed %s and its local issues and events\n", repo.FullName())
+	return nil
+}
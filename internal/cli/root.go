@@ -19,16 +19,39 @@ Commands:
   login      Authenticate with GitHub
   logout     Remove stored GitHub token
   list       List issues
-  create     Create an issue
+  create     Create an issue, or many with --from-file (alias: add)
   close      Close an issue
+  reopen     Reopen a closed issue
+  restore    Bring back a deleted issue
   comment    Add a comment to an issue
+  label      Add or remove one label (label add|remove)
+  depend     Add or remove a blocking issue (depend add|remove)
+  watch      Add or remove an issue watcher (watch add|remove)
+  notifications  List changes to issues you watch
   update     Update an issue
   next       Get the next issue to work on
+  claim      Assign yourself the next issue in one step
+  plan       Pick issues that fit an estimate budget
+  trending   Rank open issues by recent activity
+  stale      List open and blocked issues nobody has updated lately
+  search     Find issues by words in the title or description
   assign     Assign an issue
-  sync       Trigger a sync with GitHub
-  repos      List registered repositories
-  config     Configure repo settings (trusted-authors-only)
-  db         Database migration tools (version, check, downgrade)
+  abandon    Unassign an issue and say why
+  snooze     Hide an issue from next/list until a time
+  show       Show an issue and its event timeline
+  history    Show how an issue field changed over time
+  sync       Trigger a sync with GitHub (sync log|active|cancel)
+  pending    Show events waiting to be pushed to GitHub
+  stats      Count issues by status and type
+  status     Show each repo's sync status: last sync, pending events, errors
+  repair     Rebuild issues that drifted from their events
+  repos      List registered repositories (repos ensure-labels: create GitHub label; repos remove owner/name: unregister)
+  config     Configure repo settings (trusted-authors-only, trusted-authors, allowed-inbound-actions, issue-types, epic-rollup, next-strategy, sync-direction, ingest-human-comments, label)
+  template   Show or set issue templates per type (template show|set|remove <type>)
+  db         Database maintenance tools (version, check, upgrade, downgrade, vacuum)
+  export     Write all repos, issues and events as JSON to stdout
+  import     Load a JSON dump written by export
+  completion Print a shell completion script (bash, zsh, fish)
   help       Show this help
   version    Show version
 
@@ -36,6 +59,10 @@ Global Flags:
   --host URL     Daemon URL (default: $TRACKER_HOST or http://127.0.0.1:8042)
   -r, --repo NAME  Repository owner/name (default: auto-detect from git remote)
   --pretty       Use pretty-printed output instead of JSON
+  --format json|table  Output format; table is the same as --pretty (default: json)
+
+Environment:
+  BOR_AGENT      Agent name sent as X-Agent; --owner @me resolves to it
 
 Run 'bor <command> --help' for more information on a command.`
 
@@ -44,6 +71,7 @@ type globalFlags struct {
 	host    string
 	repo    string
 	pretty  bool
+	format  string // --format as given; checked and applied to pretty by Run
 	version string
 }
 
@@ -63,6 +91,12 @@ func parseGlobalFlags(args []string) (globalFlags, []string) {
 		case remaining[0] == "--pretty":
 			gf.pretty = true
 			remaining = remaining[1:]
+		case remaining[0] == "--format" && len(remaining) > 1:
+			gf.format = remaining[1]
+			remaining = remaining[2:]
+		case strings.HasPrefix(remaining[0], "--format="):
+			gf.format = strings.TrimPrefix(remaining[0], "--format=")
+			remaining = remaining[1:]
 		case remaining[0] == "--host" && len(remaining) > 1:
 			gf.host = remaining[1]
 			remaining = remaining[2:]
@@ -83,6 +117,21 @@ func parseGlobalFlags(args []string) (globalFlags, []string) {
 	return gf, remaining
 }
 
+// applyFormat sets gf.pretty from --format, which takes precedence over
+// --pretty. Without --format, --pretty alone decides.
+func applyFormat(gf *globalFlags) error {
+	switch gf.format {
+	case "":
+	case "json":
+		gf.pretty = false
+	case "table":
+		gf.pretty = true
+	default:
+		return fmt.Errorf("invalid --format %q: use json or table", gf.format)
+	}
+	return nil
+}
+
 // resolveRepo returns the repo from the global flag, or tries auto-detection.
 // If neither works, it returns "" (the daemon will try to resolve it).
 func resolveRepo(gf globalFlags) string {
@@ -104,6 +153,9 @@ func newClient(gf globalFlags) *Client {
 func Run(args []string, version string) error {
 	gf, remaining := parseGlobalFlags(args)
 	gf.version = version
+	if err := applyFormat(&gf); err != nil {
+		return err
+	}
 
 	if len(remaining) == 0 {
 		fmt.Println(usage)
@@ -118,8 +170,7 @@ func Run(args []string, version string) error {
 		fmt.Println(usage)
 		return nil
 	case "version", "--version", "-v":
-		fmt.Printf("bor version %s\n", version)
-		return nil
+		return runVersion(version, gf)
 	case "daemon":
 		return runDaemon(subArgs, gf)
 	case "init":
@@ -130,26 +181,72 @@ func Run(args []string, version string) error {
 		return runLogout(subArgs, gf)
 	case "list":
 		return runList(subArgs, gf)
-	case "create":
+	case "create", "add":
 		return runCreate(subArgs, gf)
 	case "close":
 		return runClose(subArgs, gf)
+	case "reopen":
+		return runReopen(subArgs, gf)
+	case "restore":
+		return runRestore(subArgs, gf)
 	case "comment":
 		return runComment(subArgs, gf)
+	case "label":
+		return runLabel(subArgs, gf)
+	case "depend":
+		return runDepend(subArgs, gf)
 	case "update":
 		return runUpdate(subArgs, gf)
 	case "next":
 		return runNext(subArgs, gf)
+	case "claim":
+		return runClaim(subArgs, gf)
+	case "plan":
+		return runPlan(subArgs, gf)
+	case "trending":
+		return runTrending(subArgs, gf)
+	case "stale":
+		return runStale(subArgs, gf)
+	case "search":
+		return runSearch(subArgs, gf)
+	case "watch":
+		return runWatch(subArgs, gf)
+	case "notifications":
+		return runNotifications(subArgs, gf)
 	case "assign":
 		return runAssign(subArgs, gf)
+	case "abandon":
+		return runAbandon(subArgs, gf)
+	case "snooze":
+		return runSnooze(subArgs, gf)
+	case "show":
+		return runShow(subArgs, gf)
+	case "history":
+		return runHistory(subArgs, gf)
 	case "sync":
 		return runSync(subArgs, gf)
-	case "repos":
+	case "pending":
+		return runPending(gf)
+	case "stats":
+		return runStats(gf)
+	case "status":
+		return runStatus(gf)
+	case "repair":
+		return runRepair(gf)
+	case "repos", "repo":
 		return runRepos(subArgs, gf)
 	case "config":
 		return runConfig(subArgs, gf)
+	case "template":
+		return runTemplate(subArgs, gf)
 	case "db":
 		return runDB(subArgs, gf)
+	case "export":
+		return runExport(subArgs, gf)
+	case "import":
+		return runImport(subArgs, gf)
+	case "completion":
+		return runCompletion(subArgs, gf)
 	default:
 		return fmt.Errorf("unknown command: %s\nRun 'bor help' for usage", strings.TrimSpace(cmd))
 	}
@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"encoding/json"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -71,3 +74,94 @@ func TestParseGlobalFlagsNone(t *testing.T) {
 		t.Errorf("remaining: want [list], got %v", remaining)
 	}
 }
+
+func TestParseGlobalFlagsFormat(t *testing.T) {
+	os.Unsetenv("TRACKER_HOST")
+	for _, args := range [][]string{{"--format", "table", "list"}, {"--format=table", "list"}} {
+		gf, remaining := parseGlobalFlags(args)
+		if gf.format != "table" {
+			t.Errorf("%v: format: want table, got %q", args, gf.format)
+		}
+		if len(remaining) != 1 || remaining[0] != "list" {
+			t.Errorf("%v: remaining: want [list], got %v", args, remaining)
+		}
+	}
+}
+
+func TestApplyFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		pretty  bool
+		want    bool
+		wantErr bool
+	}{
+		{"", false, false, false},
+		{"", true, true, false},
+		{"table", false, true, false},
+		{"json", true, false, false},
+		{"yaml", false, false, true},
+	}
+	for _, tt := range tests {
+		gf := globalFlags{format: tt.format, pretty: tt.pretty}
+		err := applyFormat(&gf)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("format %q: error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			continue
+		}
+		if err == nil && gf.pretty != tt.want {
+			t.Errorf("format %q pretty %v: want pretty=%v, got %v", tt.format, tt.pretty, tt.want, gf.pretty)
+		}
+	}
+}
+
+func TestRunFormat(t *testing.T) {
+	ts, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/issues":
+			w.Write([]byte(`[{"id":7,"title":"Fix it","status":"open","issue_type":"task"}]`))
+		case "/stats":
+			w.Write([]byte(`{"repo":"o/r","open":1,"by_status":{"open":1}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+		}
+	})
+	run := func(args ...string) (string, error) {
+		var err error
+		out := captureStdout(t, func() {
+			err = Run(append([]string{"--host", ts.URL, "--repo", "o/r"}, args...), "test")
+		})
+		return out, err
+	}
+
+	for _, cmd := range []string{"list", "stats"} {
+		out, err := run("--format", "json", cmd)
+		if err != nil {
+			t.Fatalf("%s: %v", cmd, err)
+		}
+		if !json.Valid([]byte(out)) {
+			t.Errorf("%s --format json: expected valid JSON, got %s", cmd, out)
+		}
+	}
+
+	out, err := run("--format", "table", "list")
+	if err != nil {
+		t.Fatalf("list --format table: %v", err)
+	}
+	if json.Valid([]byte(out)) || !strings.Contains(out, "Fix it") {
+		t.Errorf("list --format table: expected a table, got %s", out)
+	}
+
+	// Errors are returned for main to print on stderr, not written to stdout.
+	out, err = run("--format", "json", "show", "99")
+	if err == nil {
+		t.Error("show of a missing issue: expected an error")
+	}
+	if out != "" {
+		t.Errorf("show of a missing issue: expected nothing on stdout, got %s", out)
+	}
+
+	if _, err := run("--format", "yaml", "list"); err == nil || !strings.Contains(err.Error(), "--format") {
+		t.Errorf("unknown format: expected a --format error, got %v", err)
+	}
+}
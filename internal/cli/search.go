@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+func runSearch(args []string, gf globalFlags) error {
+	query := strings.TrimSpace(strings.Join(args, " "))
+	if query == "" {
+		return fmt.Errorf("usage: bor search <query>")
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	issues, err := client.SearchIssues(repo, query)
+	if err != nil {
+		return fmt.Errorf("search issues: %w", err)
+	}
+
+	printIssueList(issues, gf.pretty)
+	return nil
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+func runShow(args []string, gf globalFlags) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: bor show <id>")
+	}
+
+	id, err := issueID(gf, args[0])
+	if err != nil {
+		return fmt.Errorf("invalid issue id %q: %w", args[0], err)
+	}
+
+	client := newClient(gf)
+
+	issue, err := client.GetIssue(id)
+	if err != nil {
+		return fmt.Errorf("get issue: %w", err)
+	}
+	events, err := client.IssueEvents(id)
+	if err != nil {
+		return fmt.Errorf("issue events: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(struct {
+			Issue  *model.Issue    `json:"issue"`
+			Events []TimelineEvent `json:"events"`
+		}{issue, events})
+		return nil
+	}
+
+	printPrettyIssue(issue)
+	fmt.Println()
+	fmt.Println("Timeline:")
+	for _, ev := range events {
+		agent := ev.Agent
+		if agent == "" {
+			agent = "-"
+		}
+		synced := "pending"
+		if ev.Synced != 0 {
+			synced = "synced"
+		}
+		fmt.Printf("  %s  #%d %s by %s (%s)\n",
+			ev.Timestamp.Local().Format("2006-01-02 15:04:05"), ev.ID, ev.Action, agent, synced)
+		// The text is GitHub markdown; bold markers only add noise here.
+		text := strings.ReplaceAll(ev.Text, "**", "")
+		for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+			fmt.Printf("      %s\n", line)
+		}
+	}
+	return nil
+}
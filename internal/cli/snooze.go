@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+)
+
+func runSnooze(args []string, gf globalFlags) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: bor snooze <id> <duration|RFC3339 time|off>")
+	}
+
+	id, err := issueID(gf, args[0])
+	if err != nil {
+		return fmt.Errorf("invalid issue id %q: %w", args[0], err)
+	}
+
+	until, err := parseSnoozeUntil(args[1], time.Now())
+	if err != nil {
+		return err
+	}
+
+	client := newClient(gf)
+
+	issue, err := client.SnoozeIssue(id, until)
+	if err != nil {
+		return fmt.Errorf("snooze issue: %w", err)
+	}
+
+	printIssue(issue, gf.pretty)
+	return nil
+}
+
+// parseSnoozeUntil accepts a Go duration ("4h", "30m"), an RFC3339
+// timestamp, or "off" to clear the snooze (returns nil).
+func parseSnoozeUntil(s string, now time.Time) (*time.Time, error) {
+	if s == "off" {
+		return nil, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return nil, fmt.Errorf("snooze duration must be positive")
+		}
+		t := now.Add(d).UTC()
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snooze time %q: use a duration (e.g. 4h) or RFC3339 timestamp", s)
+	}
+	return &t, nil
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+func runStats(gf globalFlags) error {
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	result, err := client.Stats(repo)
+	if err != nil {
+		return fmt.Errorf("stats: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(result)
+		return nil
+	}
+
+	fmt.Printf("Repo: %s\n", result.Repo)
+	fmt.Printf("Open: %d  Closed: %d  Pending events: %d\n", result.Open, result.Closed, result.PendingEvents)
+	if result.OldestOpenID != 0 {
+		age := (time.Duration(result.OldestOpenAgeSeconds) * time.Second).String()
+		fmt.Printf("Oldest open: #%d (%s)\n", result.OldestOpenID, age)
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tCOUNT")
+	for _, status := range model.Statuses {
+		if n, ok := result.ByStatus[status]; ok {
+			fmt.Fprintf(w, "%s\t%d\n", status, n)
+		}
+	}
+	fmt.Fprintln(w, "\t")
+	fmt.Fprintln(w, "TYPE\tCOUNT")
+	types := make([]string, 0, len(result.ByType))
+	for t := range result.ByType {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(w, "%s\t%d\n", t, result.ByType[model.IssueType(t)])
+	}
+	return w.Flush()
+}
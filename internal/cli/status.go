@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// runStatus prints each repo's sync status from GET /health: when it last
+// synced, how many events wait to be pushed, whether a cycle is running,
+// the interval it polls at, and its last error. Repos whose last cycle
+// failed are marked with "!" and counted at the end.
+func runStatus(gf globalFlags) error {
+	client := newClient(gf)
+	health, err := client.SyncHealth()
+	if err != nil {
+		return fmt.Errorf("daemon not running at %s; start with: bor daemon start", gf.host)
+	}
+
+	if !gf.pretty {
+		printJSON(health)
+		return nil
+	}
+
+	fmt.Printf("Daemon: %s (%s)", health.Status, health.Mode)
+	if health.Uptime != "" {
+		fmt.Printf(", up %s", health.Uptime)
+	}
+	fmt.Println()
+	if health.SyncStatus == nil {
+		fmt.Println("Sync is off; no repo is syncing with GitHub.")
+		return nil
+	}
+	if len(health.SyncStatus) == 0 {
+		fmt.Println("No repos are syncing.")
+		return nil
+	}
+	fmt.Println()
+
+	names := make([]string, 0, len(health.SyncStatus))
+	for name := range health.SyncStatus {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failing := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  REPO\tLAST SYNC\tPENDING\tSYNCING\tPOLL\tLAST ERROR")
+	for _, name := range names {
+		st := health.SyncStatus[name]
+		mark, lastErr := " ", "-"
+		if st.LastError != "" {
+			mark, lastErr = "!", st.LastError
+			failing++
+		}
+		lastSync := "never"
+		if st.LastSync != "" {
+			lastSync = st.LastSync
+			if t, err := time.Parse(time.RFC3339, st.LastSync); err == nil {
+				lastSync = t.Local().Format("2006-01-02 15:04:05")
+			}
+		}
+		syncing := "no"
+		if st.Syncing {
+			syncing = "yes"
+		}
+		poll := (time.Duration(st.PollIntervalMs) * time.Millisecond).String()
+		fmt.Fprintf(w, "%s %s\t%s\t%d\t%s\t%s\t%s\n", mark, name, lastSync, st.PendingEvents, syncing, poll, lastErr)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failing > 0 {
+		fmt.Printf("\n%d of %d repos failed their last sync.\n", failing, len(names))
+	}
+	return nil
+}
@@ -3,9 +3,23 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
 )
 
 func runSync(args []string, gf globalFlags) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "log":
+			return runSyncLog(args[1:], gf)
+		case "active":
+			return runSyncActive(gf)
+		case "cancel":
+			return runSyncCancel(gf)
+		}
+	}
+
 	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
 	full := fs.Bool("full", false, "Perform a full replay sync instead of incremental")
 
@@ -42,3 +56,138 @@ func runSync(args []string, gf globalFlags) error {
 
 	return nil
 }
+
+// syncLogPollInterval is how often `bor sync log -f` polls the daemon.
+const syncLogPollInterval = 2 * time.Second
+
+func runSyncLog(args []string, gf globalFlags) error {
+	fs := flag.NewFlagSet("sync log", flag.ContinueOnError)
+	follow := fs.Bool("f", false, "Follow new sync cycles as they complete")
+	lines := fs.Int("n", 20, "Number of recent cycles to show")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	result, err := client.SyncLog(repo)
+	if err != nil {
+		return err
+	}
+
+	cycles := result.Cycles
+	if *lines >= 0 && len(cycles) > *lines {
+		cycles = cycles[len(cycles)-*lines:]
+	}
+	printSyncCycles(cycles, gf.pretty, true)
+
+	if !*follow {
+		return nil
+	}
+
+	var last time.Time
+	if n := len(result.Cycles); n > 0 {
+		last = result.Cycles[n-1].StartedAt
+	}
+	for {
+		time.Sleep(syncLogPollInterval)
+		result, err := client.SyncLog(repo)
+		if err != nil {
+			return err
+		}
+		var fresh []SyncCycle
+		for _, c := range result.Cycles {
+			if c.StartedAt.After(last) {
+				fresh = append(fresh, c)
+			}
+		}
+		if len(fresh) == 0 {
+			continue
+		}
+		last = fresh[len(fresh)-1].StartedAt
+		printSyncCycles(fresh, gf.pretty, false)
+	}
+}
+
+// printSyncCycles prints sync cycle outcomes as a table (pretty) or as one
+// JSON object per cycle.
+func printSyncCycles(cycles []SyncCycle, pretty, header bool) {
+	if !pretty {
+		for _, c := range cycles {
+			printJSON(c)
+		}
+		return
+	}
+	if header && len(cycles) == 0 {
+		fmt.Println("No sync cycles recorded.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if header {
+		fmt.Fprintln(w, "STARTED\tMODE\tPUSHED\tPULLED\tIGNORED\tERROR")
+	}
+	for _, c := range cycles {
+		mode := "incremental"
+		if c.Full {
+			mode = "full"
+		}
+		errStr := "-"
+		if c.Error != "" {
+			errStr = c.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\n",
+			c.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			mode,
+			c.Pushed,
+			c.Pulled,
+			c.Ignored,
+			errStr,
+		)
+	}
+	w.Flush()
+}
+
+func runSyncActive(gf globalFlags) error {
+	client := newClient(gf)
+
+	active, err := client.SyncActive()
+	if err != nil {
+		return fmt.Errorf("sync active: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(active)
+		return nil
+	}
+
+	if len(active) == 0 {
+		fmt.Println("No syncs in progress.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tSTARTED\tELAPSED")
+	for _, a := range active {
+		elapsed := time.Duration(a.ElapsedSeconds * float64(time.Second)).Round(time.Second)
+		fmt.Fprintf(w, "%s\t%s\t%s\n", a.RepoName, a.StartedAt.Local().Format("15:04:05"), elapsed)
+	}
+	return w.Flush()
+}
+
+func runSyncCancel(gf globalFlags) error {
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	if err := client.CancelSync(repo); err != nil {
+		return fmt.Errorf("sync cancel: %w", err)
+	}
+
+	if gf.pretty {
+		fmt.Println("Sync cycle cancelled.")
+	} else {
+		printJSON(map[string]string{"status": "sync cancelled"})
+	}
+	return nil
+}
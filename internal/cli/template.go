@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+const templateUsage = "usage: bor template [show <type> | set <type> <file|-> | remove <type>]"
+
+// runTemplate lists the repo's issue templates, or shows, sets or removes
+// the template for one issue type. Setting sends the whole map back, since
+// PATCH /repos replaces issue_templates as a unit.
+func runTemplate(args []string, gf globalFlags) error {
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	templates, err := client.IssueTemplates(repo)
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+
+	if len(args) == 0 || args[0] == "list" {
+		if !gf.pretty {
+			printJSON(templates)
+			return nil
+		}
+		if len(templates) == 0 {
+			fmt.Println("No issue templates.")
+			return nil
+		}
+		types := make([]string, 0, len(templates))
+		for t := range templates {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		for _, t := range types {
+			fmt.Printf("== %s ==\n%s\n", t, templates[t])
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "show":
+		if len(args) < 2 {
+			return fmt.Errorf(templateUsage)
+		}
+		tmpl, ok := templates[args[1]]
+		if !ok {
+			return fmt.Errorf("no template for issue type %q", args[1])
+		}
+		fmt.Print(tmpl)
+		return nil
+	case "set":
+		if len(args) < 3 {
+			return fmt.Errorf(templateUsage)
+		}
+		var data []byte
+		if args[2] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[2])
+		}
+		if err != nil {
+			return fmt.Errorf("template: %w", err)
+		}
+		templates[args[1]] = string(data)
+	case "remove", "rm":
+		if len(args) < 2 {
+			return fmt.Errorf(templateUsage)
+		}
+		if _, ok := templates[args[1]]; !ok {
+			return fmt.Errorf("no template for issue type %q", args[1])
+		}
+		delete(templates, args[1])
+	default:
+		return fmt.Errorf(templateUsage)
+	}
+
+	updated, err := client.UpdateRepo(repo, map[string]interface{}{
+		"issue_templates": templates,
+	})
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	if !gf.pretty {
+		printJSON(updated.IssueTemplates)
+		return nil
+	}
+	verb := "removed"
+	if args[0] == "set" {
+		verb = "set"
+	}
+	fmt.Printf("%s template for %s (repo: %s/%s)\n", verb, args[1], updated.Owner, updated.Name)
+	return nil
+}
@@ -3,16 +3,18 @@ package cli
 import (
 	"flag"
 	"fmt"
-	"strconv"
 )
 
 func runUpdate(args []string, gf globalFlags) error {
 	fs := flag.NewFlagSet("update", flag.ContinueOnError)
 	status := fs.String("status", "", "New status (open, in_progress, blocked, in_review, closed)")
 	priority := fs.Int("priority", -1, "New priority")
+	estimate := fs.Int("estimate", -1, "New estimate (points or hours)")
 	title := fs.String("title", "", "New title")
 	description := fs.String("description", "", "New description")
 	comment := fs.String("comment", "", "Add a comment")
+	parent := fs.Int("parent", -1, "Parent (epic) issue ID; 0 clears it")
+	ifVersion := fs.Int("if-version", 0, "Refuse the update if the issue's version is no longer this")
 
 	if err := fs.Parse(reorderArgs(args)); err != nil {
 		return err
@@ -20,10 +22,10 @@ func runUpdate(args []string, gf globalFlags) error {
 
 	remaining := fs.Args()
 	if len(remaining) == 0 {
-		return fmt.Errorf("usage: bor update <id> [--status S] [--priority N] [--title T] [--description D] [--comment C]")
+		return fmt.Errorf("usage: bor update <id> [--status S] [--priority N] [--estimate N] [--title T] [--description D] [--comment C] [--parent id] [--if-version N]")
 	}
 
-	id, err := strconv.Atoi(remaining[0])
+	id, err := issueID(gf, remaining[0])
 	if err != nil {
 		return fmt.Errorf("invalid issue id %q: %w", remaining[0], err)
 	}
@@ -35,6 +37,9 @@ func runUpdate(args []string, gf globalFlags) error {
 	if *priority >= 0 {
 		fields["priority"] = *priority
 	}
+	if *estimate >= 0 {
+		fields["estimate"] = *estimate
+	}
 	if *title != "" {
 		fields["title"] = *title
 	}
@@ -44,9 +49,15 @@ func runUpdate(args []string, gf globalFlags) error {
 	if *comment != "" {
 		fields["comment"] = *comment
 	}
+	if *parent >= 0 {
+		fields["parent_id"] = *parent
+	}
 
 	if len(fields) == 0 {
-		return fmt.Errorf("no fields to update; use --status, --priority, --title, --description, or --comment")
+		return fmt.Errorf("no fields to update; use --status, --priority, --estimate, --title, --description, or --comment")
+	}
+	if *ifVersion > 0 {
+		fields["version"] = *ifVersion
 	}
 
 	client := newClient(gf)
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/jmaddaus/boxofrocks/internal/daemon"
+	"github.com/jmaddaus/boxofrocks/internal/store"
+)
+
+// runVersion prints the CLI's versions and, when a daemon is reachable, the
+// daemon's versions alongside them.
+func runVersion(version string, gf globalFlags) error {
+	fmt.Printf("bor version %s (api %d, schema %d)\n", version, daemon.APIVersion, store.DBSchemaVersion)
+
+	client := newClient(gf)
+	client.warnOut = nil // the comparison below already reports skew
+	info, err := client.Version()
+	if err != nil {
+		return nil
+	}
+	fmt.Printf("daemon version %s (api %d, schema %d)\n", info.Version, info.APIVersion, info.DBSchemaVersion)
+	if info.APIVersion != daemon.APIVersion {
+		fmt.Println("warning: CLI and daemon API versions differ; restart the daemon")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func runWatch(args []string, gf globalFlags) error {
+	if len(args) < 2 || (args[0] != "add" && args[0] != "remove") {
+		return fmt.Errorf("usage: bor watch <add|remove> <id> [watcher]")
+	}
+
+	id, err := issueID(gf, args[1])
+	if err != nil {
+		return fmt.Errorf("invalid issue id %q: %w", args[1], err)
+	}
+	watcher := "@me"
+	if len(args) > 2 {
+		watcher = args[2]
+	}
+
+	client := newClient(gf)
+
+	change := client.WatchIssue
+	if args[0] == "remove" {
+		change = client.UnwatchIssue
+	}
+	issue, err := change(id, watcher)
+	if err != nil {
+		return fmt.Errorf("%s watcher: %w", args[0], err)
+	}
+
+	printIssue(issue, gf.pretty)
+	return nil
+}
+
+func runNotifications(args []string, gf globalFlags) error {
+	owner := "@me"
+	if len(args) > 0 {
+		owner = args[0]
+	}
+
+	client := newClient(gf)
+	repo := resolveRepo(gf)
+
+	notifications, err := client.Notifications(repo, owner)
+	if err != nil {
+		return fmt.Errorf("notifications: %w", err)
+	}
+
+	if !gf.pretty {
+		printJSON(notifications)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ISSUE\tACTION\tEVENT\tAT")
+	for _, n := range notifications {
+		fmt.Fprintf(w, "#%d\t%s\t%d\t%s\n", n.IssueID, n.Action, n.EventID, n.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -15,6 +16,70 @@ type Config struct {
 	ListenAddr string `json:"listen_addr"` // default ":8042"
 	DataDir    string `json:"data_dir"`    // default "~/.boxofrocks"
 	DBPath     string `json:"db_path"`     // default "{data_dir}/bor.db"
+
+	// SQLite tuning. BusyTimeoutMs is how long a connection waits on a locked
+	// database before failing; Synchronous is the PRAGMA synchronous level
+	// (OFF, NORMAL, FULL, EXTRA). NORMAL is safe under WAL: a power loss can
+	// roll back the most recent commits but cannot corrupt the database.
+	BusyTimeoutMs int    `json:"busy_timeout_ms,omitempty"` // default 5000
+	Synchronous   string `json:"synchronous,omitempty"`     // default "NORMAL"
+
+	// MaxInlineCommentBytes, if > 0, keeps only a hash reference in the local
+	// DB for synced comments longer than this; the text is re-fetched from
+	// GitHub when the event log is read. 0 (default) keeps everything inline.
+	MaxInlineCommentBytes int `json:"max_inline_comment_bytes,omitempty"`
+
+	// Identity is the owner name that "@me" resolves to when a request has no
+	// X-Agent header.
+	Identity string `json:"identity,omitempty"`
+
+	// Inclusive range of valid issue priorities (lower is more urgent).
+	MinPriority int `json:"min_priority"`           // default 0
+	MaxPriority int `json:"max_priority,omitempty"` // default 5
+
+	// GitHubMaxRetries is how many times a GitHub request is retried after a
+	// 5xx response or a secondary rate limit. 0 disables retrying.
+	GitHubMaxRetries int `json:"github_max_retries"` // default 3
+
+	// MaxPollIntervalMs, if > 0, turns on adaptive polling: each sync cycle
+	// that changes nothing doubles a repo's poll interval, up to this cap.
+	// 0 (default) polls at fixed intervals.
+	MaxPollIntervalMs int `json:"max_poll_interval_ms,omitempty"`
+
+	// GitHubAPIURL is the REST API base URL, for GitHub Enterprise Server
+	// (e.g. "https://github.example.com/api/v3"). The GITHUB_API_URL
+	// environment variable overrides it. Empty means api.github.com.
+	GitHubAPIURL string `json:"github_api_url,omitempty"`
+
+	// GitHub App credentials. When GitHubAppID is set the daemon
+	// authenticates as the app installation instead of resolving a token,
+	// refreshing the installation token before it expires. All three must
+	// be set together.
+	GitHubAppID             int64  `json:"github_app_id,omitempty"`
+	GitHubAppInstallationID int64  `json:"github_app_installation_id,omitempty"`
+	GitHubAppPrivateKeyPath string `json:"github_app_private_key_path,omitempty"`
+
+	// AuthToken, if set, must be sent as "Authorization: Bearer <token>" on
+	// every TCP request except GET /health. Unix sockets and file queues are
+	// guarded by file permissions instead and do not need it.
+	AuthToken string `json:"auth_token,omitempty"`
+
+	// WebhookSecret, if set, turns on POST /webhooks/github: deliveries
+	// signed with it pull the changed issue at once instead of waiting for
+	// the next poll. Empty means polling only.
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+
+	// SocketMode is the permission mode, in octal (e.g. "0660"), given to
+	// each repo's Unix socket; anyone who can connect to it can act on the
+	// repo without AuthToken. SocketGroup, a group name or numeric GID,
+	// becomes the socket's group so its members can share a daemon. Empty
+	// means owner-only, "0700", and the daemon user's primary group.
+	SocketMode  string `json:"socket_mode,omitempty"`
+	SocketGroup string `json:"socket_group,omitempty"`
+
+	// Offline runs the daemon without GitHub even when a token is available:
+	// issues are tracked locally only and sync endpoints return 503.
+	Offline bool `json:"offline,omitempty"`
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -25,7 +90,24 @@ func DefaultConfig() *Config {
 		ListenAddr: ":8042",
 		DataDir:    dataDir,
 		DBPath:     filepath.Join(dataDir, "bor.db"),
+
+		BusyTimeoutMs: 5000,
+		Synchronous:   "NORMAL",
+
+		MinPriority: 0,
+		MaxPriority: 5,
+
+		GitHubMaxRetries: 3,
+	}
+}
+
+// PriorityRange returns the configured inclusive priority bounds. A config
+// that sets neither bound gets the default 0-5.
+func (c *Config) PriorityRange() (min, max int) {
+	if c.MinPriority == 0 && c.MaxPriority == 0 {
+		return 0, 5
 	}
+	return c.MinPriority, c.MaxPriority
 }
 
 // configPath returns the path to the config file.
@@ -66,6 +148,7 @@ func Load() (*Config, error) {
 	// Expand home directory references.
 	cfg.DataDir = expandHome(cfg.DataDir)
 	cfg.DBPath = expandHome(cfg.DBPath)
+	cfg.GitHubAppPrivateKeyPath = expandHome(cfg.GitHubAppPrivateKeyPath)
 
 	// If DBPath is empty after loading, set the default relative to DataDir.
 	if cfg.DBPath == "" {
@@ -79,6 +162,33 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// GitHubBaseURL returns the GitHub API base URL to use: GITHUB_API_URL if
+// set, else GitHubAPIURL. Empty means the client default, api.github.com.
+func (c *Config) GitHubBaseURL() string {
+	if v := os.Getenv("GITHUB_API_URL"); v != "" {
+		return v
+	}
+	return c.GitHubAPIURL
+}
+
+// SocketFileMode returns SocketMode parsed, or 0700 if it is not set.
+// Validate rejects a SocketMode that does not parse.
+func (c *Config) SocketFileMode() os.FileMode {
+	if c.SocketMode == "" {
+		return 0700
+	}
+	mode, err := strconv.ParseUint(c.SocketMode, 8, 32)
+	if err != nil {
+		return 0700
+	}
+	return os.FileMode(mode)
+}
+
+// UsesGitHubApp reports whether GitHub App credentials are configured.
+func (c *Config) UsesGitHubApp() bool {
+	return c.GitHubAppID != 0 || c.GitHubAppInstallationID != 0 || c.GitHubAppPrivateKeyPath != ""
+}
+
 // Validate checks that the Config contains valid values.
 func (c *Config) Validate() error {
 	if c.ListenAddr == "" {
@@ -102,6 +212,47 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("data_dir must not be empty")
 	}
 
+	if min, max := c.PriorityRange(); min < 0 || max < min {
+		return fmt.Errorf("invalid priority range %d-%d: need 0 <= min_priority <= max_priority", min, max)
+	}
+	if c.MaxInlineCommentBytes < 0 {
+		return fmt.Errorf("max_inline_comment_bytes must not be negative")
+	}
+	if c.GitHubMaxRetries < 0 {
+		return fmt.Errorf("github_max_retries must not be negative")
+	}
+	if c.MaxPollIntervalMs < 0 {
+		return fmt.Errorf("max_poll_interval_ms must not be negative")
+	}
+	if c.GitHubAPIURL != "" {
+		u, err := url.Parse(c.GitHubAPIURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid github_api_url %q: must be an http(s) URL", c.GitHubAPIURL)
+		}
+	}
+	if c.UsesGitHubApp() {
+		if c.GitHubAppID <= 0 || c.GitHubAppInstallationID <= 0 || c.GitHubAppPrivateKeyPath == "" {
+			return fmt.Errorf("github_app_id, github_app_installation_id, and github_app_private_key_path must be set together")
+		}
+	}
+	if c.BusyTimeoutMs < 0 {
+		return fmt.Errorf("busy_timeout_ms must not be negative")
+	}
+	if c.SocketMode != "" {
+		mode, err := strconv.ParseUint(c.SocketMode, 8, 32)
+		if err != nil || mode > 0777 {
+			return fmt.Errorf("invalid socket_mode %q: use octal permission bits such as \"0660\"", c.SocketMode)
+		}
+		if mode&0200 == 0 {
+			return fmt.Errorf("invalid socket_mode %q: the owner needs write permission to connect", c.SocketMode)
+		}
+	}
+	switch strings.ToUpper(c.Synchronous) {
+	case "", "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		return fmt.Errorf("invalid synchronous %q: use OFF, NORMAL, FULL, or EXTRA", c.Synchronous)
+	}
+
 	return nil
 }
 
@@ -21,6 +21,15 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.DBPath != wantDB {
 		t.Errorf("DBPath: want %s, got %s", wantDB, cfg.DBPath)
 	}
+	if cfg.BusyTimeoutMs != 5000 {
+		t.Errorf("BusyTimeoutMs: want 5000, got %d", cfg.BusyTimeoutMs)
+	}
+	if cfg.Synchronous != "NORMAL" {
+		t.Errorf("Synchronous: want NORMAL, got %s", cfg.Synchronous)
+	}
+	if cfg.GitHubMaxRetries != 3 {
+		t.Errorf("GitHubMaxRetries: want 3, got %d", cfg.GitHubMaxRetries)
+	}
 }
 
 func TestExpandHomeWithTilde(t *testing.T) {
@@ -88,6 +97,121 @@ func TestValidateInvalidPortNonNumeric(t *testing.T) {
 	}
 }
 
+func TestValidateSQLiteTuning(t *testing.T) {
+	cfg := &Config{ListenAddr: ":8042", DataDir: "/tmp/bor", Synchronous: "normal", BusyTimeoutMs: 100}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got error: %v", err)
+	}
+	cfg.Synchronous = "sometimes"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid synchronous")
+	}
+	cfg.Synchronous = "FULL"
+	cfg.BusyTimeoutMs = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for negative busy_timeout_ms")
+	}
+	cfg.BusyTimeoutMs = 100
+	cfg.GitHubMaxRetries = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for negative github_max_retries")
+	}
+	cfg.GitHubMaxRetries = 3
+	cfg.MaxPollIntervalMs = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for negative max_poll_interval_ms")
+	}
+}
+
+func TestSocketMode(t *testing.T) {
+	cfg := &Config{ListenAddr: ":8042", DataDir: "/tmp/bor"}
+	if got := cfg.SocketFileMode(); got != 0700 {
+		t.Errorf("default socket mode = %o, want 700", got)
+	}
+	cfg.SocketMode = "0660"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid socket_mode, got %v", err)
+	}
+	if got := cfg.SocketFileMode(); got != 0660 {
+		t.Errorf("socket mode = %o, want 660", got)
+	}
+	for _, bad := range []string{"rw-rw----", "0999", "01777", "0460"} {
+		cfg.SocketMode = bad
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected error for socket_mode %q", bad)
+		}
+	}
+}
+
+func TestGitHubAPIURL(t *testing.T) {
+	t.Setenv("GITHUB_API_URL", "")
+	cfg := &Config{ListenAddr: ":8042", DataDir: "/tmp/bor", GitHubAPIURL: "https://ghe.example.com/api/v3"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid github_api_url, got %v", err)
+	}
+	if got := cfg.GitHubBaseURL(); got != "https://ghe.example.com/api/v3" {
+		t.Errorf("GitHubBaseURL = %q, want config value", got)
+	}
+
+	t.Setenv("GITHUB_API_URL", "https://other.example.com/api/v3")
+	if got := cfg.GitHubBaseURL(); got != "https://other.example.com/api/v3" {
+		t.Errorf("GitHubBaseURL = %q, want environment override", got)
+	}
+
+	for _, bad := range []string{"ghe.example.com", "ftp://ghe.example.com", "https://"} {
+		cfg.GitHubAPIURL = bad
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected error for github_api_url %q", bad)
+		}
+	}
+}
+
+func TestValidateGitHubApp(t *testing.T) {
+	cfg := &Config{ListenAddr: ":8042", DataDir: "/tmp/bor"}
+	if cfg.UsesGitHubApp() {
+		t.Error("UsesGitHubApp = true with no app credentials")
+	}
+
+	cfg.GitHubAppID = 7
+	cfg.GitHubAppInstallationID = 42
+	cfg.GitHubAppPrivateKeyPath = "/tmp/bor/app.pem"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid app credentials, got %v", err)
+	}
+	if !cfg.UsesGitHubApp() {
+		t.Error("UsesGitHubApp = false with app credentials")
+	}
+
+	cfg.GitHubAppInstallationID = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for app credentials without an installation id")
+	}
+	cfg.GitHubAppInstallationID = 42
+	cfg.GitHubAppPrivateKeyPath = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for app credentials without a private key path")
+	}
+}
+
+func TestValidatePriorityRange(t *testing.T) {
+	cfg := &Config{ListenAddr: ":8042", DataDir: "/tmp/bor"}
+	if min, max := cfg.PriorityRange(); min != 0 || max != 5 {
+		t.Errorf("expected default range 0-5, got %d-%d", min, max)
+	}
+	cfg.MinPriority, cfg.MaxPriority = 1, 9
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got error: %v", err)
+	}
+	cfg.MinPriority, cfg.MaxPriority = 5, 2
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for min_priority > max_priority")
+	}
+	cfg.MinPriority, cfg.MaxPriority = -1, 5
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for negative min_priority")
+	}
+}
+
 func TestValidateEmptyDataDir(t *testing.T) {
 	cfg := &Config{ListenAddr: ":8042", DataDir: ""}
 	if err := cfg.Validate(); err == nil {
@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	stdsync "sync"
+	"time"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+// collaboratorTTL is how long a repo's collaborator list is trusted before
+// it is fetched again. A collaborator added on GitHub may be rejected as an
+// assignee for up to this long.
+const collaboratorTTL = 5 * time.Minute
+
+// collaboratorCache holds each repo's GitHub collaborators, lowercased, so
+// that validating an assignee does not cost an API call per assignment.
+type collaboratorCache struct {
+	mu      stdsync.Mutex
+	entries map[int]collaboratorEntry // repoID → collaborators
+	now     func() time.Time          // nil means time.Now
+}
+
+type collaboratorEntry struct {
+	logins    map[string]bool
+	fetchedAt time.Time
+}
+
+func (c *collaboratorCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// forget drops the repo's cached list, so the next check fetches it.
+func (c *collaboratorCache) forget(repoID int) {
+	c.mu.Lock()
+	delete(c.entries, repoID)
+	c.mu.Unlock()
+}
+
+// collaborators returns the repo's collaborators, lowercased, fetching them
+// from GitHub when none are cached or the cached list is older than
+// collaboratorTTL.
+func (d *Daemon) collaborators(ctx context.Context, repo *model.RepoConfig) (map[string]bool, error) {
+	c := &d.collaboratorCache
+	c.mu.Lock()
+	entry, ok := c.entries[repo.ID]
+	c.mu.Unlock()
+	if ok && c.clock().Sub(entry.fetchedAt) < collaboratorTTL {
+		return entry.logins, nil
+	}
+
+	list, err := d.ghClient.ListCollaborators(ctx, repo.Owner, repo.Name)
+	if err != nil {
+		return nil, err
+	}
+	logins := make(map[string]bool, len(list))
+	for _, login := range list {
+		logins[strings.ToLower(login)] = true
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[int]collaboratorEntry)
+	}
+	c.entries[repo.ID] = collaboratorEntry{logins: logins, fetchedAt: c.clock()}
+	c.mu.Unlock()
+	return logins, nil
+}
+
+// checkAssignee returns why issue may not be assigned to owner, or "" if it
+// may. Only repos with ValidateAssignee are checked, and only when the daemon
+// can reach GitHub. Unassigning, and assigning an issue to the owner it
+// already has, are always allowed.
+func (d *Daemon) checkAssignee(ctx context.Context, issue *model.Issue, owner string) (string, error) {
+	if owner == "" || strings.EqualFold(owner, issue.Owner) || d.ghClient == nil {
+		return "", nil
+	}
+	repo, err := d.store.GetRepo(ctx, issue.RepoID)
+	if err != nil {
+		return "", fmt.Errorf("get repo: %w", err)
+	}
+	if !repo.ValidateAssignee {
+		return "", nil
+	}
+	login := repo.GitHubLogin(owner)
+	if login == "" {
+		login = owner
+	}
+	logins, err := d.collaborators(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	if logins[strings.ToLower(login)] {
+		return "", nil
+	}
+	if login != owner {
+		return fmt.Sprintf("%s (GitHub login %s) is not a collaborator on %s", owner, login, repo.FullName()), nil
+	}
+	return fmt.Sprintf("%s is not a collaborator on %s; map it to a login with assignee_logins", owner, repo.FullName()), nil
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"os/user"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -30,6 +31,21 @@ type contextKey string
 // socketRepoIDKey is the context key for the repo ID resolved from a Unix socket connection.
 const socketRepoIDKey contextKey = "socketRepoID"
 
+// localConnKey marks requests that arrived over a Unix socket or a file
+// queue. Both are gated by file permissions, so they skip token auth.
+const localConnKey contextKey = "localConn"
+
+// APIVersion identifies the shape of the daemon's REST API. Bump it whenever
+// endpoints, request fields, or response fields change so that a CLI talking
+// to an older (or newer) daemon can warn about the skew.
+const APIVersion = 63
+
+// APIVersionHeader is the response header carrying APIVersion.
+const APIVersionHeader = "X-Bor-API-Version"
+
+// AgentHeader names the calling agent. It is what owner=@me resolves to.
+const AgentHeader = "X-Agent"
+
 // Daemon manages the HTTP server and its dependencies.
 type Daemon struct {
 	cfg       *config.Config
@@ -47,6 +63,13 @@ type Daemon struct {
 	queueMu    stdsync.Mutex
 	queueStops map[string]chan struct{} // queueDir → stop channel
 	queueRepos map[string]int           // queueDir → repoID
+	queueWG    stdsync.WaitGroup        // running file queue pollers
+
+	pathIdx pathIndex // cached local path → repoID for X-Working-Dir resolution
+
+	issueFeed issueHub // live issue changes for GET /issues/events
+
+	collaboratorCache collaboratorCache // GitHub collaborators for assignee validation
 }
 
 // New creates a new Daemon, opening the SQLite store and setting up the HTTP server.
@@ -55,7 +78,11 @@ func New(cfg *config.Config) (*Daemon, error) {
 		return nil, fmt.Errorf("ensure data dir: %w", err)
 	}
 
-	s, err := store.NewSQLiteStore(cfg.DBPath)
+	s, err := store.NewSQLiteStoreWithOptions(cfg.DBPath, store.SQLiteOptions{
+		BusyTimeoutMs:         cfg.BusyTimeoutMs,
+		Synchronous:           cfg.Synchronous,
+		MaxInlineCommentBytes: cfg.MaxInlineCommentBytes,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("open store: %w", err)
 	}
@@ -170,10 +197,12 @@ func removePIDFile(cfg *config.Config) {
 }
 
 // connContext is the http.Server.ConnContext hook. For Unix socket connections,
-// it injects the associated repo ID into the request context so that
-// resolveRepo can use it without an explicit ?repo= or X-Repo header.
+// it marks the connection as local and injects the associated repo ID into the
+// request context so that resolveRepo can use it without an explicit ?repo= or
+// X-Repo header.
 func (d *Daemon) connContext(ctx context.Context, c net.Conn) context.Context {
 	if addr, ok := c.LocalAddr().(*net.UnixAddr); ok {
+		ctx = context.WithValue(ctx, localConnKey, true)
 		d.socketMu.Lock()
 		repoID, exists := d.socketRepos[addr.Name]
 		d.socketMu.Unlock()
@@ -208,11 +237,25 @@ func (d *Daemon) createSocketAtPath(repoID int, sockPath string) error {
 		return nil // already listening
 	}
 
-	// Ensure the .boxofrocks/ directory exists.
+	// Ensure the .boxofrocks/ directory exists. Whoever may connect to the
+	// socket must also be able to reach it, so the directory is made
+	// searchable by the same classes of user as the socket. One left at
+	// 0700 by an earlier daemon, or narrowed by the umask, is widened.
+	mode := d.cfg.SocketFileMode()
 	sockDir := filepath.Dir(sockPath)
-	if err := os.MkdirAll(sockDir, 0700); err != nil {
+	search := socketDirSearchBits(mode)
+	if err := os.MkdirAll(sockDir, 0700|search); err != nil {
 		return fmt.Errorf("create socket dir: %w", err)
 	}
+	if info, err := os.Stat(sockDir); err != nil {
+		return fmt.Errorf("stat socket dir: %w", err)
+	} else if dirMode := info.Mode().Perm(); dirMode&search != search {
+		// A directory owned by someone else cannot be changed; the socket
+		// then stays reachable by its owner only, so say so.
+		if err := os.Chmod(sockDir, dirMode|search); err != nil {
+			slog.Warn("socket dir not searchable by socket_mode's users", "path", sockDir, "mode", fmt.Sprintf("%04o", dirMode), "error", err)
+		}
+	}
 
 	// Remove stale socket file if present.
 	os.Remove(sockPath)
@@ -222,12 +265,23 @@ func (d *Daemon) createSocketAtPath(repoID int, sockPath string) error {
 		return fmt.Errorf("listen unix %s: %w", sockPath, err)
 	}
 
-	// Set socket permissions to owner-only.
-	if err := os.Chmod(sockPath, 0700); err != nil {
+	// Connecting needs write permission on the socket, and a connection is
+	// trusted like the daemon's own user (no auth_token), so the default
+	// is owner-only. socket_mode and socket_group widen it deliberately.
+	if err := os.Chmod(sockPath, mode); err != nil {
 		ln.Close()
 		os.Remove(sockPath)
 		return fmt.Errorf("chmod socket: %w", err)
 	}
+	if d.cfg.SocketGroup != "" {
+		// A group the daemon's user is not in, or a platform without
+		// chown, leaves the socket usable by its owner; warn and go on.
+		if gid, err := lookupGroupID(d.cfg.SocketGroup); err != nil {
+			slog.Warn("could not resolve socket group", "path", sockPath, "group", d.cfg.SocketGroup, "error", err)
+		} else if err := os.Chown(sockPath, -1, gid); err != nil {
+			slog.Warn("could not set socket group", "path", sockPath, "group", d.cfg.SocketGroup, "error", err)
+		}
+	}
 
 	d.socketLns[sockPath] = ln
 	d.socketRepos[sockPath] = repoID
@@ -242,6 +296,31 @@ func (d *Daemon) createSocketAtPath(repoID int, sockPath string) error {
 	return nil
 }
 
+// socketDirSearchBits returns the search (x) bits a directory needs so that
+// each class of user granted any access by the socket mode can reach it.
+func socketDirSearchBits(mode os.FileMode) os.FileMode {
+	var bits os.FileMode
+	if mode&0070 != 0 {
+		bits |= 0010
+	}
+	if mode&0007 != 0 {
+		bits |= 0001
+	}
+	return bits
+}
+
+// lookupGroupID resolves a group name, or a numeric GID, to a GID.
+func lookupGroupID(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
 // removeSocket closes and removes a single Unix domain socket.
 func (d *Daemon) removeSocket(sockPath string) {
 	d.socketMu.Lock()
@@ -255,6 +334,22 @@ func (d *Daemon) removeSocket(sockPath string) {
 	os.Remove(sockPath)
 }
 
+// removeRepoSockets closes and removes every socket associated with repoID.
+func (d *Daemon) removeRepoSockets(repoID int) {
+	d.socketMu.Lock()
+	var paths []string
+	for sockPath, id := range d.socketRepos {
+		if id == repoID {
+			paths = append(paths, sockPath)
+		}
+	}
+	d.socketMu.Unlock()
+
+	for _, sockPath := range paths {
+		d.removeSocket(sockPath)
+	}
+}
+
 // cleanupSockets removes all socket files from disk.
 func (d *Daemon) cleanupSockets() {
 	d.socketMu.Lock()
@@ -396,6 +491,12 @@ func (d *Daemon) Run(ctx context.Context) error {
 	// Check arbiter workflow versions (advisory only).
 	d.checkArbiterVersions()
 
+	if d.offline() {
+		slog.Info("running in offline mode: issues are tracked locally, GitHub sync endpoints return 503")
+	} else {
+		slog.Info("running in online mode: syncing with GitHub")
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -425,13 +526,24 @@ func (d *Daemon) Run(ctx context.Context) error {
 	return d.Shutdown(context.Background())
 }
 
-// Shutdown gracefully shuts down the HTTP server and closes the store.
+// Shutdown stops the daemon in order: it stops taking requests on TCP,
+// Unix sockets and file queues, waits for the requests already running,
+// stops the syncers, and closes the store. Waiting is bounded by ctx and by
+// a 10 second cap; past that the remaining work is cut off.
 func (d *Daemon) Shutdown(ctx context.Context) error {
 	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	var firstErr error
 
+	// End event streams first; server.Shutdown waits for open requests.
+	d.issueFeed.close()
+
+	// Stop polling file queues so no new queue request starts.
+	d.cleanupFileQueues()
+
+	// Close every listener, TCP and Unix socket alike, and wait for the
+	// handlers they started.
 	if err := d.server.Shutdown(shutdownCtx); err != nil {
 		firstErr = fmt.Errorf("server shutdown: %w", err)
 	}
@@ -439,8 +551,15 @@ func (d *Daemon) Shutdown(ctx context.Context) error {
 	// Remove socket files from disk (listeners already closed by server.Shutdown).
 	d.cleanupSockets()
 
-	// Stop file queue goroutines.
-	d.cleanupFileQueues()
+	// Let queue requests already being handled write their response files.
+	if err := d.drainFileQueues(shutdownCtx); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	// Syncers write to the store, so they stop before it closes.
+	if d.syncMgr != nil {
+		d.syncMgr.StopContext(shutdownCtx)
+	}
 
 	if err := d.store.Close(); err != nil {
 		if firstErr == nil {
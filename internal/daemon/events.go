@@ -0,0 +1,185 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	stdsync "sync"
+	"time"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+// ---------------------------------------------------------------------------
+// Issue event stream
+// ---------------------------------------------------------------------------
+
+// issueEvent is one message on the GET /issues/events stream: the action
+// that changed the issue, and the issue as stored afterwards.
+type issueEvent struct {
+	Action model.Action `json:"action"`
+	Issue  *model.Issue `json:"issue"`
+}
+
+// issueSubBuffer is how many events a stream may fall behind before it is
+// dropped. A dropped client reconnects and re-lists instead of silently
+// missing changes.
+const issueSubBuffer = 64
+
+// issueStreamPing is how often an idle stream sends a comment line, so
+// proxies and clients can tell a quiet stream from a dead one.
+const issueStreamPing = 30 * time.Second
+
+type issueSub struct {
+	repoID int
+	ch     chan issueEvent
+}
+
+// issueHub fans issue events out to the streams watching each repo. The
+// zero value is ready to use.
+type issueHub struct {
+	mu     stdsync.Mutex
+	subs   map[*issueSub]struct{}
+	closed bool
+}
+
+// subscribe registers a stream for repoID. It returns nil once the hub is
+// closed.
+func (h *issueHub) subscribe(repoID int) *issueSub {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	if h.subs == nil {
+		h.subs = make(map[*issueSub]struct{})
+	}
+	sub := &issueSub{repoID: repoID, ch: make(chan issueEvent, issueSubBuffer)}
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe removes sub and closes its channel. It is safe to call after
+// the hub has already dropped sub.
+func (h *issueHub) unsubscribe(sub *issueSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// publish sends ev to every stream watching repoID without blocking. A
+// stream whose buffer is full is dropped.
+func (h *issueHub) publish(repoID int, ev issueEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if sub.repoID != repoID {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			slog.Warn("dropping slow issue event stream", "repo_id", repoID)
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// close ends every stream and refuses new ones. Open streams would
+// otherwise hold up a graceful server shutdown.
+func (h *issueHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// publishIssue tells the issue's watchers that action changed it. Handlers
+// call it after the change is stored.
+func (d *Daemon) publishIssue(action model.Action, issue *model.Issue) {
+	d.issueFeed.publish(issue.RepoID, issueEvent{Action: action, Issue: issue})
+}
+
+// canFlush reports whether w, or a writer it wraps, can flush a partial
+// response. File queue requests cannot, so they cannot stream.
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		if _, ok := w.(http.Flusher); ok {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// issueEvents streams changes to the resolved repo's issues as Server-Sent
+// Events until the client disconnects. Each message is an issueEvent.
+func (d *Daemon) issueEvents(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !canFlush(w) {
+		writeError(w, http.StatusNotImplemented, "event stream not supported on this connection")
+		return
+	}
+
+	sub := d.issueFeed.subscribe(repo.ID)
+	if sub == nil {
+		writeError(w, http.StatusServiceUnavailable, "daemon is shutting down")
+		return
+	}
+	defer d.issueFeed.unsubscribe(sub)
+
+	// The stream outlives the server's WriteTimeout, so lift the deadline
+	// for this response.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("clear event stream write deadline", "error", err)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	// The opening comment tells the client the subscription is live.
+	fmt.Fprint(w, ": connected\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(issueStreamPing)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev, ok := <-sub.ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				slog.Warn("marshal issue event", "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
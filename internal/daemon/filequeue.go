@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -112,7 +113,11 @@ func (d *Daemon) startFileQueueAtPath(repoID int, queueDir string) error {
 	d.queueStops[queueDir] = stop
 	d.queueRepos[queueDir] = repoID
 
-	go d.pollFileQueue(queueDir, repoID, stop)
+	d.queueWG.Add(1)
+	go func() {
+		defer d.queueWG.Done()
+		d.pollFileQueue(queueDir, repoID, stop)
+	}()
 
 	slog.Info("file queue started", "dir", queueDir)
 	return nil
@@ -130,6 +135,22 @@ func (d *Daemon) stopFileQueue(queueDir string) {
 	delete(d.queueRepos, queueDir)
 }
 
+// stopRepoFileQueues stops every file queue associated with repoID.
+func (d *Daemon) stopRepoFileQueues(repoID int) {
+	d.queueMu.Lock()
+	var dirs []string
+	for queueDir, id := range d.queueRepos {
+		if id == repoID {
+			dirs = append(dirs, queueDir)
+		}
+	}
+	d.queueMu.Unlock()
+
+	for _, queueDir := range dirs {
+		d.stopFileQueue(queueDir)
+	}
+}
+
 // cleanupFileQueues stops all file queue goroutines.
 func (d *Daemon) cleanupFileQueues() {
 	d.queueMu.Lock()
@@ -142,6 +163,22 @@ func (d *Daemon) cleanupFileQueues() {
 	d.queueRepos = make(map[string]int)
 }
 
+// drainFileQueues waits for stopped pollers to finish the scan they are in,
+// so no request file is left half answered. It gives up when ctx is done.
+func (d *Daemon) drainFileQueues(ctx context.Context) error {
+	drained := make(chan struct{})
+	go func() {
+		d.queueWG.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("drain file queues: %w", ctx.Err())
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Polling
 // ---------------------------------------------------------------------------
@@ -222,9 +259,13 @@ func (d *Daemon) processQueueFile(reqPath string, repoID int) {
 	if freq.Body != nil && string(freq.Body) != "null" {
 		httpReq.Header.Set("Content-Type", "application/json")
 	}
+	// Name the request after its file, so its log lines can be matched
+	// to the request and response files.
+	httpReq.Header.Set(RequestIDHeader, "queue-"+strings.TrimSuffix(filepath.Base(reqPath), ".req"))
 
 	// Inject repo ID via context, same key used by Unix socket connections.
 	ctx := context.WithValue(httpReq.Context(), socketRepoIDKey, repoID)
+	ctx = context.WithValue(ctx, localConnKey, true)
 	httpReq = httpReq.WithContext(ctx)
 
 	// Dispatch through the existing handler chain.
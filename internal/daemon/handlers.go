@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -50,12 +51,29 @@ func writeError(w http.ResponseWriter, status int, msg string) {
 	json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
 
-func parseIssueID(r *http.Request) (int, error) {
-	id, err := strconv.Atoi(r.PathValue("id"))
+// parseIssueID returns the issue ID named by the {id} path value: a bare
+// ID, or the ID prefixed with its repo's key prefix, e.g. BOR-42. The prefix
+// is checked against the repo of the issue the ID names; an ID naming no
+// issue is returned as is, for the handler to report as not found.
+func (d *Daemon) parseIssueID(r *http.Request) (int, error) {
+	ref := r.PathValue("id")
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, nil
+	}
+	_, num, _ := strings.Cut(ref, "-")
+	id, err := strconv.Atoi(num)
 	if err != nil {
 		return 0, fmt.Errorf("invalid issue id")
 	}
-	return id, nil
+	issue, err := d.store.GetIssue(r.Context(), id)
+	if err != nil {
+		return id, nil
+	}
+	repo, err := d.store.GetRepo(r.Context(), issue.RepoID)
+	if err != nil {
+		return id, nil
+	}
+	return model.ParseIssueRef(repo.KeyPrefix, ref)
 }
 
 // lookupRepo parses an "owner/name" string and looks up the repo.
@@ -119,27 +137,18 @@ func (d *Daemon) resolveRepo(r *http.Request) (*model.RepoConfig, error) {
 	// 4. Working directory: if the CLI sent X-Working-Dir, match it against
 	// registered repos' local paths (exact match or subdirectory).
 	if workDir := r.Header.Get("X-Working-Dir"); workDir != "" {
-		repos, err := d.store.ListRepos(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("list repos: %w", err)
-		}
-		var best *model.RepoConfig
-		bestLen := 0
-		for _, repo := range repos {
-			for _, lp := range repo.LocalPaths {
-				if lp.LocalPath == "" {
-					continue
-				}
-				if workDir == lp.LocalPath || strings.HasPrefix(workDir, lp.LocalPath+"/") {
-					if len(lp.LocalPath) > bestLen {
-						best = repo
-						bestLen = len(lp.LocalPath)
-					}
-				}
+		if repoID, ok := d.lookupPathIndex(ctx, workDir); ok {
+			if repo, err := d.store.GetRepo(ctx, repoID); err == nil {
+				return repo, nil
 			}
 		}
-		if best != nil {
-			return best, nil
+		// Cache miss (or stale entry): fall back to the authoritative query.
+		repo, err := d.store.ResolveRepoByPath(ctx, workDir)
+		if err == nil {
+			return repo, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("resolve repo by path: %w", err)
 		}
 	}
 
@@ -174,8 +183,13 @@ func (d *Daemon) health(w http.ResponseWriter, r *http.Request) {
 		repoNames[i] = repo.FullName()
 	}
 
+	mode := "online"
+	if d.offline() {
+		mode = "offline"
+	}
 	resp := map[string]interface{}{
 		"status": "ok",
+		"mode":   mode,
 		"repos":  repoNames,
 	}
 
@@ -190,8 +204,9 @@ func (d *Daemon) health(w http.ResponseWriter, r *http.Request) {
 		syncInfo := make(map[string]interface{}, len(syncStatuses))
 		for _, st := range syncStatuses {
 			entry := map[string]interface{}{
-				"pending_events": st.PendingEvents,
-				"syncing":        st.Syncing,
+				"pending_events":   st.PendingEvents,
+				"syncing":          st.Syncing,
+				"poll_interval_ms": st.PollIntervalMs,
 			}
 			if st.LastSyncAt != nil {
 				entry["last_sync"] = st.LastSyncAt.Format(time.RFC3339)
@@ -199,6 +214,9 @@ func (d *Daemon) health(w http.ResponseWriter, r *http.Request) {
 			if st.LastError != "" {
 				entry["last_error"] = st.LastError
 			}
+			if st.RateBudget != nil {
+				entry["rate_budget"] = st.RateBudget
+			}
 			syncInfo[st.RepoName] = entry
 		}
 		resp["sync_status"] = syncInfo
@@ -207,15 +225,98 @@ func (d *Daemon) health(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// ready is the readiness probe. Unlike /health, which answers whenever the
+// daemon is up, it returns 503 until the database answers and, when
+// syncing, every registered repo has finished a sync cycle without error.
+// The body names the repos still waiting.
+func (d *Daemon) ready(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := d.store.Ping(ctx); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status": "not_ready",
+			"error":  "database unavailable: " + err.Error(),
+		})
+		return
+	}
+
+	waiting := []string{}
+	if d.syncMgr != nil {
+		repos, err := d.store.ListRepos(ctx)
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+				"status": "not_ready",
+				"error":  "list repos: " + err.Error(),
+			})
+			return
+		}
+		statuses := d.syncMgr.Status()
+		for _, repo := range repos {
+			if st, ok := statuses[repo.ID]; !ok || st.FirstSuccessAt == nil {
+				waiting = append(waiting, repo.FullName())
+			}
+		}
+	}
+	if len(waiting) > 0 {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status":  "not_ready",
+			"error":   fmt.Sprintf("%d repo(s) have not finished a sync cycle", len(waiting)),
+			"waiting": waiting,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ready"})
+}
+
 // ---------------------------------------------------------------------------
-// Force sync (stub)
+// Version
 // ---------------------------------------------------------------------------
 
-func (d *Daemon) forceSync(w http.ResponseWriter, r *http.Request) {
+func (d *Daemon) versionInfo(w http.ResponseWriter, r *http.Request) {
+	version := d.version
+	if version == "" {
+		version = "dev"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"version":           version,
+		"api_version":       APIVersion,
+		"db_schema_version": store.DBSchemaVersion,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Sync
+// ---------------------------------------------------------------------------
+
+// githubUnavailable is the 503 message from every endpoint that needs GitHub
+// or the sync manager, so local-only users get the same answer everywhere.
+const githubUnavailable = "github unavailable: daemon is running offline (run bor login and restart the daemon to sync)"
+
+// offline reports whether the daemon is running without GitHub sync, either
+// by request or because no token was found.
+func (d *Daemon) offline() bool {
+	return d.syncMgr == nil
+}
+
+// requireSync writes a 503 and returns false if there is no sync manager.
+func (d *Daemon) requireSync(w http.ResponseWriter) bool {
 	if d.syncMgr == nil {
-		writeJSON(w, http.StatusOK, map[string]string{
-			"status": "sync not yet implemented",
-		})
+		writeError(w, http.StatusServiceUnavailable, githubUnavailable)
+		return false
+	}
+	return true
+}
+
+// requireGitHub writes a 503 and returns false if there is no GitHub client.
+func (d *Daemon) requireGitHub(w http.ResponseWriter) bool {
+	if d.ghClient == nil {
+		writeError(w, http.StatusServiceUnavailable, githubUnavailable)
+		return false
+	}
+	return true
+}
+
+func (d *Daemon) forceSync(w http.ResponseWriter, r *http.Request) {
+	if !d.requireSync(w) {
 		return
 	}
 
@@ -225,6 +326,16 @@ func (d *Daemon) forceSync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.URL.Query().Get("dry_run") == "true" {
+		report, err := d.syncMgr.ForceSyncDryRun(r.Context(), repo.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+		return
+	}
+
 	full := r.URL.Query().Get("full") == "true"
 	if full {
 		err = d.syncMgr.ForceSyncFull(repo.ID)
@@ -242,13 +353,173 @@ func (d *Daemon) forceSync(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (d *Daemon) syncLog(w http.ResponseWriter, r *http.Request) {
+	if !d.requireSync(w) {
+		return
+	}
+
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cycles, err := d.syncMgr.CycleLog(repo.ID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"repo":   repo.FullName(),
+		"cycles": cycles,
+	})
+}
+
+// pendingEvent is an unsynced event as reported by GET /events/pending.
+type pendingEvent struct {
+	ID         int          `json:"id"`
+	IssueID    int          `json:"issue_id"`
+	IssueTitle string       `json:"issue_title"`
+	Action     model.Action `json:"action"`
+	Agent      string       `json:"agent,omitempty"`
+	Timestamp  time.Time    `json:"timestamp"`
+	AgeSeconds int64        `json:"age_seconds"`
+}
+
+// pendingEvents handles GET /events/pending, listing events not yet pushed to
+// GitHub, oldest first so whatever is blocking the queue is at the top.
+func (d *Daemon) pendingEvents(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, err := d.store.PendingEventsWithTitles(r.Context(), repo.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	pending := make([]pendingEvent, 0, len(events))
+	for _, p := range events {
+		ev := p.Event
+		pending = append(pending, pendingEvent{
+			ID:         ev.ID,
+			IssueID:    ev.IssueID,
+			IssueTitle: p.IssueTitle,
+			Action:     ev.Action,
+			Agent:      ev.Agent,
+			Timestamp:  ev.Timestamp,
+			AgeSeconds: int64(now.Sub(ev.Timestamp).Seconds()),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"repo":   repo.FullName(),
+		"events": pending,
+	})
+}
+
+// eventsSince handles GET /events?since=<id>, returning the repo's events
+// with an ID above since, across all its issues, oldest first and at most
+// ?limit= (default 100, at most 1000) at a time. cursor is the highest ID
+// returned, or since itself when nothing is new, and goes in the next
+// request's since; more is set when events beyond this page remain.
+func (d *Daemon) eventsSince(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	since := 0
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		since, err = strconv.Atoi(raw)
+		if err != nil || since < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid since %q: must be a non-negative event ID", raw))
+			return
+		}
+	}
+	limit, err := positiveIntParam(r, "limit", defaultListLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit = min(limit, maxListLimit)
+
+	// One extra event tells whether another page follows.
+	events, err := d.store.EventsSince(r.Context(), repo.ID, since, limit+1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	more := len(events) > limit
+	if more {
+		events = events[:limit]
+	}
+	d.store.HydrateComments(r.Context(), events)
+	if events == nil {
+		events = []*model.Event{}
+	}
+	cursor := since
+	if len(events) > 0 {
+		cursor = events[len(events)-1].ID
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"repo":   repo.FullName(),
+		"events": events,
+		"cursor": cursor,
+		"more":   more,
+	})
+}
+
+func (d *Daemon) syncActive(w http.ResponseWriter, r *http.Request) {
+	if !d.requireSync(w) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"active": d.syncMgr.Active(),
+	})
+}
+
+func (d *Daemon) syncCancel(w http.ResponseWriter, r *http.Request) {
+	if !d.requireSync(w) {
+		return
+	}
+
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cancelled, err := d.syncMgr.CancelCycle(repo.ID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if !cancelled {
+		writeError(w, http.StatusConflict, "repo "+repo.FullName()+" is not currently syncing")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status": "sync cancelled",
+		"repo":   repo.FullName(),
+	})
+}
+
 // ---------------------------------------------------------------------------
 // Import all issues
 // ---------------------------------------------------------------------------
 
 func (d *Daemon) importIssues(w http.ResponseWriter, r *http.Request) {
-	if d.ghClient == nil {
-		writeError(w, http.StatusServiceUnavailable, "GitHub client not configured; authenticate first")
+	if !d.requireGitHub(w) {
 		return
 	}
 
@@ -269,18 +540,19 @@ func (d *Daemon) importIssues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	label := repo.TrackingLabel()
 	labeled := 0
 	for _, issue := range ghIssues {
 		hasLabel := false
 		for _, lbl := range issue.Labels {
-			if lbl.Name == "boxofrocks" {
+			if lbl.Name == label {
 				hasLabel = true
 				break
 			}
 		}
 		if !hasLabel {
-			if err := d.ghClient.AddLabelsToIssue(ctx, repo.Owner, repo.Name, issue.Number, []string{"boxofrocks"}); err != nil {
-				slog.Warn("could not label issue", "number", issue.Number, "error", err)
+			if err := d.ghClient.AddLabelsToIssue(ctx, repo.Owner, repo.Name, issue.Number, []string{label}); err != nil {
+				slog.WarnContext(r.Context(), "could not label issue", "number", issue.Number, "error", err)
 				continue
 			}
 			labeled++
@@ -290,7 +562,7 @@ func (d *Daemon) importIssues(w http.ResponseWriter, r *http.Request) {
 	// Trigger sync so the newly-labeled issues get pulled in.
 	if d.syncMgr != nil {
 		if err := d.syncMgr.ForceSync(repo.ID); err != nil {
-			slog.Warn("could not trigger sync after import", "repo", repo.FullName(), "error", err)
+			slog.WarnContext(r.Context(), "could not trigger sync after import", "repo", repo.FullName(), "error", err)
 		}
 	}
 
@@ -303,306 +575,2676 @@ func (d *Daemon) importIssues(w http.ResponseWriter, r *http.Request) {
 }
 
 // ---------------------------------------------------------------------------
-// Repos
+// Replay integrity check
 // ---------------------------------------------------------------------------
 
-type addRepoRequest struct {
-	Owner     string `json:"owner"`
-	Name      string `json:"name"`
-	LocalPath string `json:"local_path,omitempty"`
-	Socket    bool   `json:"socket,omitempty"`
-	Queue     bool   `json:"queue,omitempty"`
+// integrityMismatch is one field where an issue row disagrees with the
+// replay of its events.
+type integrityMismatch struct {
+	IssueID  int         `json:"issue_id"`
+	Field    string      `json:"field"`
+	Stored   interface{} `json:"stored"`
+	Replayed interface{} `json:"replayed"`
 }
 
-func (d *Daemon) addRepo(w http.ResponseWriter, r *http.Request) {
-	var req addRepoRequest
-	if err := readJSON(r, &req); err != nil {
+// repoIntegrity replays each issue's stored events and reports where the
+// result differs from the persisted issue row. It is read-only.
+func (d *Daemon) repoIntegrity(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if req.Owner == "" || req.Name == "" {
-		writeError(w, http.StatusBadRequest, "owner and name are required")
+
+	issues, err := d.store.ListIssues(r.Context(), store.IssueFilter{RepoID: repo.ID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	repo, err := d.store.AddRepo(r.Context(), req.Owner, req.Name)
-	if err != nil {
-		if strings.Contains(err.Error(), "already exists") {
-			writeError(w, http.StatusConflict, err.Error())
+	mismatches := []integrityMismatch{}
+	for _, stored := range issues {
+		replayed, _, problem, err := d.replayIssue(r.Context(), stored)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		if problem != "" {
+			mismatches = append(mismatches, integrityMismatch{IssueID: stored.ID, Field: "replay", Stored: "issue row", Replayed: problem})
+			continue
+		}
+		mismatches = append(mismatches, compareReplayed(stored, replayed)...)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"repo":       repo.FullName(),
+		"checked":    len(issues),
+		"ok":         len(mismatches) == 0,
+		"mismatches": mismatches,
+	})
+}
+
+// repairRepo overwrites each issue row that drifted from its events with
+// the replayed state. Issues that cannot be replayed, or that gained events
+// while being repaired, are skipped and reported.
+func (d *Daemon) repairRepo(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	issues, err := d.store.ListIssues(r.Context(), store.IssueFilter{RepoID: repo.ID})
+	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// Auto-detect repo visibility and enable trusted-author filtering for public repos.
-	if d.ghClient != nil {
-		ghRepo, err := d.ghClient.GetRepo(r.Context(), req.Owner, req.Name)
+	type skipped struct {
+		IssueID int    `json:"issue_id"`
+		Reason  string `json:"reason"`
+	}
+	repaired := []int{}
+	skips := []skipped{}
+	for _, stored := range issues {
+		replayed, lastEventID, problem, err := d.replayIssue(r.Context(), stored)
 		if err != nil {
-			slog.Warn("could not check repo visibility", "repo", repo.FullName(), "error", err)
-		} else if !ghRepo.Private {
-			repo.TrustedAuthorsOnly = true
-			if err := d.store.UpdateRepo(r.Context(), repo); err != nil {
-				slog.Warn("could not save trusted_authors_only setting", "repo", repo.FullName(), "error", err)
-			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if problem != "" {
+			skips = append(skips, skipped{stored.ID, problem})
+			continue
+		}
+		if len(compareReplayed(stored, replayed)) == 0 {
+			continue
 		}
-	}
 
-	// Register local path with socket/queue if requested.
-	if req.LocalPath != "" {
-		lp, err := d.store.AddLocalPath(r.Context(), repo.ID, req.LocalPath, req.Socket, req.Queue)
+		replayed.ID = stored.ID
+		replayed.RepoID = stored.RepoID
+		replayed.GitHubID = stored.GitHubID
+		ok, err := d.store.RebuildIssue(r.Context(), replayed, lastEventID)
 		if err != nil {
-			slog.Warn("could not save local path", "repo", repo.FullName(), "error", err)
-		} else {
-			if sp := lp.SocketPath(); sp != "" {
-				if err := d.createSocketAtPath(repo.ID, sp); err != nil {
-					slog.Warn("could not create socket for repo", "repo", repo.FullName(), "error", err)
-				}
-			}
-			if qd := lp.QueueDir(); qd != "" {
-				if err := d.startFileQueueAtPath(repo.ID, qd); err != nil {
-					slog.Warn("could not start file queue", "repo", repo.FullName(), "error", err)
-				}
-			}
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("rebuild issue %d: %v", stored.ID, err))
+			return
 		}
-		// Re-fetch repo to get updated local paths.
-		repo, _ = d.store.GetRepo(r.Context(), repo.ID)
-	}
-
-	if d.syncMgr != nil {
-		if err := d.syncMgr.AddRepo(repo); err != nil {
-			slog.Warn("failed to start syncer for new repo", "repo", repo.FullName(), "error", err)
+		if !ok {
+			skips = append(skips, skipped{stored.ID, "changed during repair; run again"})
+			continue
 		}
+		repaired = append(repaired, stored.ID)
 	}
 
-	writeJSON(w, http.StatusCreated, repo)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"repo":     repo.FullName(),
+		"checked":  len(issues),
+		"repaired": repaired,
+		"skipped":  skips,
+	})
 }
 
-func (d *Daemon) listRepos(w http.ResponseWriter, r *http.Request) {
-	repos, err := d.store.ListRepos(r.Context())
+// replayIssue replays stored's events. It returns the replayed issue and the
+// ID of the last event applied, or a reason the issue cannot be replayed.
+func (d *Daemon) replayIssue(ctx context.Context, stored *model.Issue) (*model.Issue, int, string, error) {
+	events, err := d.store.ListEvents(ctx, stored.RepoID, stored.ID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
+		return nil, 0, "", err
 	}
-	if repos == nil {
-		repos = []*model.RepoConfig{}
+	if len(events) == 0 {
+		return nil, 0, "no events", nil
 	}
-	writeJSON(w, http.StatusOK, repos)
+	replayed, err := engine.Replay(events, d.priorityRange())
+	if err != nil {
+		return nil, 0, err.Error(), nil
+	}
+	issue := replayed[stored.ID]
+	if issue == nil {
+		return nil, 0, "no create event", nil
+	}
+	return issue, events[len(events)-1].ID, "", nil
 }
 
-// ---------------------------------------------------------------------------
-// Issues
-// ---------------------------------------------------------------------------
+// compareReplayed lists the fields where stored and replayed differ.
+// Timestamps are compared at the store's one-second resolution; updated_at,
+// comments and sync bookkeeping are not compared.
+func compareReplayed(stored, replayed *model.Issue) []integrityMismatch {
 
-func (d *Daemon) listIssues(w http.ResponseWriter, r *http.Request) {
-	repo, err := d.resolveRepo(r)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
-		return
+	var out []integrityMismatch
+	check := func(field string, equal bool, s, r interface{}) {
+		if !equal {
+			out = append(out, integrityMismatch{IssueID: stored.ID, Field: field, Stored: s, Replayed: r})
+		}
 	}
+	check("title", stored.Title == replayed.Title, stored.Title, replayed.Title)
+	check("status", stored.Status == replayed.Status, stored.Status, replayed.Status)
+	check("priority", stored.Priority == replayed.Priority, stored.Priority, replayed.Priority)
+	check("issue_type", stored.IssueType == replayed.IssueType, stored.IssueType, replayed.IssueType)
+	check("description", stored.Description == replayed.Description, stored.Description, replayed.Description)
+	check("owner", stored.Owner == replayed.Owner, stored.Owner, replayed.Owner)
+	check("labels", strings.Join(stored.Labels, "\x00") == strings.Join(replayed.Labels, "\x00"), stored.Labels, replayed.Labels)
+	check("estimate", stored.Estimate == replayed.Estimate, stored.Estimate, replayed.Estimate)
+	check("parent_id", equalIntPtr(stored.ParentID, replayed.ParentID), stored.ParentID, replayed.ParentID)
+	check("blocked_by", fmt.Sprint(stored.BlockedBy) == fmt.Sprint(replayed.BlockedBy), stored.BlockedBy, replayed.BlockedBy)
+	check("watchers", strings.Join(stored.Watchers, "\x00") == strings.Join(replayed.Watchers, "\x00"), stored.Watchers, replayed.Watchers)
+	check("closed_at", equalTimePtr(stored.ClosedAt, replayed.ClosedAt), stored.ClosedAt, replayed.ClosedAt)
+	check("snoozed_until", equalTimePtr(stored.SnoozedUntil, replayed.SnoozedUntil), stored.SnoozedUntil, replayed.SnoozedUntil)
+	return out
+}
 
-	filter := store.IssueFilter{
-		RepoID: repo.ID,
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
+	return *a == *b
+}
 
-	if s := r.URL.Query().Get("status"); s != "" {
-		filter.Status = model.Status(s)
-	}
-	if p := r.URL.Query().Get("priority"); p != "" {
-		pv, err := strconv.Atoi(p)
-		if err == nil {
-			filter.Priority = &pv
-		}
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
+
+// ---------------------------------------------------------------------------
+// Export and import
+// ---------------------------------------------------------------------------
+
+// exportData streams a store.Dump of the repo named by ?repo=, or of every
+// repo without one. Unlike most endpoints it does not fall back to the
+// caller's working directory, so a bare request exports everything.
+func (d *Daemon) exportData(w http.ResponseWriter, r *http.Request) {
+	var repoID int
+	if r.URL.Query().Get("repo") != "" {
+		repo, err := d.resolveRepo(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		repoID = repo.ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := store.WriteDump(r.Context(), d.store, w, repoID); err != nil {
+		// The response has likely started, so the client sees a truncated
+		// document that fails to parse rather than an error body.
+		slog.WarnContext(r.Context(), "export failed", "repo_id", repoID, "error", err)
+	}
+}
+
+// maxImportBytes caps a POST /import body, well above readJSON's limit
+// since a dump holds every event.
+const maxImportBytes = 256 << 20
+
+// importDump loads a store.Dump from the request body, as written by GET
+// /export, and starts syncing the repos it adds.
+func (d *Daemon) importDump(w http.ResponseWriter, r *http.Request) {
+	var dump store.Dump
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if err := json.NewDecoder(r.Body).Decode(&dump); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid dump: "+err.Error())
+		return
+	}
+	if err := dump.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid dump: "+err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	result, err := d.store.ImportDump(ctx, &dump)
+	if errors.Is(err, store.ErrRepoExists) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "import: "+err.Error())
+		return
+	}
+
+	if d.syncMgr != nil {
+		for _, dumped := range dump.Repos {
+			id := dumped.ID
+			if renumbered, ok := result.RenumberedRepos[id]; ok {
+				id = renumbered
+			}
+			repo, err := d.store.GetRepo(ctx, id)
+			if err == nil {
+				err = d.syncMgr.AddRepo(repo)
+			}
+			if err != nil {
+				slog.WarnContext(r.Context(), "could not start sync for imported repo", "repo", dumped.FullName(), "error", err)
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ---------------------------------------------------------------------------
+// Ensure labels
+// ---------------------------------------------------------------------------
+
+// ensureLabels creates the repo's tracking label on GitHub if it is missing,
+// so a repo can be prepared before the first sync push.
+func (d *Daemon) ensureLabels(w http.ResponseWriter, r *http.Request) {
+	if !d.requireGitHub(w) {
+		return
+	}
+
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	label := repo.TrackingLabel()
+	created := []string{}
+	existing := []string{}
+	ok, err := d.ghClient.CreateLabel(r.Context(), repo.Owner, repo.Name,
+		label, github.TrackingLabelColor, github.TrackingLabelDescription)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "create label: "+err.Error())
+		return
+	}
+	if ok {
+		created = append(created, label)
+	} else {
+		existing = append(existing, label)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"repo":     repo.FullName(),
+		"created":  created,
+		"existing": existing,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Repos
+// ---------------------------------------------------------------------------
+
+type addRepoRequest struct {
+	Owner     string `json:"owner"`
+	Name      string `json:"name"`
+	LocalPath string `json:"local_path,omitempty"`
+	Socket    bool   `json:"socket,omitempty"`
+	Queue     bool   `json:"queue,omitempty"`
+}
+
+func (d *Daemon) addRepo(w http.ResponseWriter, r *http.Request) {
+	var req addRepoRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Owner == "" || req.Name == "" {
+		writeError(w, http.StatusBadRequest, "owner and name are required")
+		return
+	}
+
+	repo, err := d.store.AddRepo(r.Context(), req.Owner, req.Name)
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Auto-detect repo visibility and enable trusted-author filtering for public repos.
+	if d.ghClient != nil {
+		ghRepo, err := d.ghClient.GetRepo(r.Context(), req.Owner, req.Name)
+		if err != nil {
+			slog.WarnContext(r.Context(), "could not check repo visibility", "repo", repo.FullName(), "error", err)
+		} else if !ghRepo.Private {
+			repo.TrustedAuthorsOnly = true
+			if err := d.store.UpdateRepo(r.Context(), repo); err != nil {
+				slog.WarnContext(r.Context(), "could not save trusted_authors_only setting", "repo", repo.FullName(), "error", err)
+			}
+		}
+	} else {
+		slog.InfoContext(r.Context(), "offline: skipped repo visibility check; trusted_authors_only left off", "repo", repo.FullName())
+	}
+
+	// Register local path with socket/queue if requested.
+	if req.LocalPath != "" {
+		lp, err := d.store.AddLocalPath(r.Context(), repo.ID, req.LocalPath, req.Socket, req.Queue)
+		if err != nil {
+			slog.WarnContext(r.Context(), "could not save local path", "repo", repo.FullName(), "error", err)
+		} else {
+			d.invalidatePathIndex()
+			if sp := lp.SocketPath(); sp != "" {
+				if err := d.createSocketAtPath(repo.ID, sp); err != nil {
+					slog.WarnContext(r.Context(), "could not create socket for repo", "repo", repo.FullName(), "error", err)
+				}
+			}
+			if qd := lp.QueueDir(); qd != "" {
+				if err := d.startFileQueueAtPath(repo.ID, qd); err != nil {
+					slog.WarnContext(r.Context(), "could not start file queue", "repo", repo.FullName(), "error", err)
+				}
+			}
+		}
+		// Re-fetch repo to get updated local paths.
+		repo, _ = d.store.GetRepo(r.Context(), repo.ID)
+	}
+
+	if d.syncMgr != nil {
+		if err := d.syncMgr.AddRepo(repo); err != nil {
+			slog.WarnContext(r.Context(), "failed to start syncer for new repo", "repo", repo.FullName(), "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, repo)
+}
+
+// repoTemplates handles GET /repos/templates, returning the resolved repo's
+// issue templates keyed by issue type.
+func (d *Daemon) repoTemplates(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	templates := repo.IssueTemplates
+	if templates == nil {
+		templates = map[string]string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"repo":      repo.FullName(),
+		"templates": templates,
+	})
+}
+
+func (d *Daemon) listRepos(w http.ResponseWriter, r *http.Request) {
+	repos, err := d.store.ListRepos(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if repos == nil {
+		repos = []*model.RepoConfig{}
+	}
+	writeJSON(w, http.StatusOK, repos)
+}
+
+// deleteRepo handles DELETE /repos?repo=owner/name. It stops the repo's
+// syncer, sockets and file queues, then deletes the repo and everything
+// stored for it. The repo must be named explicitly; GitHub is not touched.
+func (d *Daemon) deleteRepo(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("repo")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "repo is required")
+		return
+	}
+	ctx := r.Context()
+
+	repo, err := d.lookupRepo(ctx, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("repo %s not found", name))
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Stop writers before the rows go away.
+	if d.syncMgr != nil {
+		if err := d.syncMgr.RemoveRepo(repo.ID); err != nil {
+			slog.DebugContext(r.Context(), "no syncer to stop for deleted repo", "repo", repo.FullName(), "error", err)
+		}
+	}
+	d.removeRepoSockets(repo.ID)
+	d.stopRepoFileQueues(repo.ID)
+
+	if err := d.store.DeleteRepo(ctx, repo.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "delete repo: "+err.Error())
+		return
+	}
+	d.invalidatePathIndex()
+
+	slog.InfoContext(r.Context(), "repo deleted", "repo", repo.FullName())
+	writeJSON(w, http.StatusOK, repo)
+}
+
+// ---------------------------------------------------------------------------
+// Issues
+// ---------------------------------------------------------------------------
+
+// GET /issues returns one page of issues: ?limit= (default 100, at most
+// 1000) starting at ?offset=. The X-Total-Count header carries the number of
+// matching issues across all pages. HEAD /issues and ?count_only=true skip
+// the listing and return only the count.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+	totalCountHeader = "X-Total-Count"
+)
+
+func (d *Daemon) listIssues(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := store.IssueFilter{
+		RepoID: repo.ID,
+	}
+
+	statuses, err := parseStatuses(r.URL.Query()["status"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Statuses = statuses
+	if p := r.URL.Query().Get("priority"); p != "" {
+		pv, err := strconv.Atoi(p)
+		if err == nil {
+			filter.Priority = &pv
+		}
 	}
 	if t := r.URL.Query().Get("type"); t != "" {
 		filter.Type = model.IssueType(t)
 	}
 	if o := r.URL.Query().Get("owner"); o != "" {
-		filter.Owner = o
+		owner, err := d.resolveOwner(r, o)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.Owner = owner
+	}
+	// ?label= may repeat; an issue must carry every label given.
+	filter.Labels = r.URL.Query()["label"]
+	if raw := r.URL.Query().Get("updated_since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "updated_since must be an RFC 3339 timestamp")
+			return
+		}
+		filter.UpdatedSince = since
+	}
+
+	// Unless ?all=true or a status is given, exclude closed and deleted
+	// issues.
+	showAll := r.URL.Query().Get("all") == "true"
+	filter.ExcludeClosed = !showAll && len(filter.Statuses) == 0
+
+	// Snoozed issues are hidden unless explicitly requested.
+	filter.ExcludeSnoozed = !showAll && r.URL.Query().Get("include_snoozed") != "true"
+
+	filter.Sort = store.IssueSort(r.URL.Query().Get("sort"))
+	if !store.IsValidIssueSort(filter.Sort) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown sort %q: use priority, created or updated", filter.Sort))
+		return
+	}
+	switch order := r.URL.Query().Get("order"); order {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown order %q: use asc or desc", order))
+		return
+	}
+
+	limit, err := positiveIntParam(r, "limit", defaultListLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Limit = min(limit, maxListLimit)
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid offset %q: must be a non-negative integer", raw))
+			return
+		}
+		filter.Offset = offset
+	}
+
+	total, err := d.store.CountIssues(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.URL.Query().Get("count_only") == "true" {
+		writeJSON(w, http.StatusOK, map[string]int{"count": total})
+		return
+	}
+
+	issues, err := d.store.ListIssues(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if issues == nil {
+		issues = []*model.Issue{}
+	}
+	if err := d.store.FillEventStats(r.Context(), issues); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, issues)
+}
+
+// parseStatuses reads ?status= values, which may repeat and may each list
+// several statuses separated by commas. "actionable" stands for every status
+// short of closed.
+func parseStatuses(values []string) ([]model.Status, error) {
+	var statuses []model.Status
+	for _, value := range values {
+		for _, s := range strings.Split(value, ",") {
+			s = strings.TrimSpace(s)
+			switch {
+			case s == "":
+			case s == "actionable":
+				statuses = append(statuses, model.ActionableStatuses...)
+			case model.Status(s).Valid():
+				statuses = append(statuses, model.Status(s))
+			default:
+				return nil, fmt.Errorf("unknown status %q", s)
+			}
+		}
 	}
+	return statuses, nil
+}
+
+// changedIssues handles GET /issues/changed?since=<rfc3339>. It returns every
+// issue updated at or after since, deleted ones included, so a client can
+// keep a local cache current and evict deleted issues.
+func (d *Daemon) changedIssues(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	raw := r.URL.Query().Get("since")
+	if raw == "" {
+		writeError(w, http.StatusBadRequest, "since is required")
+		return
+	}
+	since, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+		return
+	}
+
+	issues, err := d.store.IssuesUpdatedSince(r.Context(), repo.ID, since)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if issues == nil {
+		issues = []*model.Issue{}
+	}
+
+	writeJSON(w, http.StatusOK, issues)
+}
+
+// searchIssues handles GET /issues/search?q=, returning the repo's issues
+// whose title or description contains a word starting with each term.
+func (d *Daemon) searchIssues(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	issues, err := d.store.SearchIssues(r.Context(), repo.ID, q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if issues == nil {
+		issues = []*model.Issue{}
+	}
+
+	writeJSON(w, http.StatusOK, issues)
+}
+
+// trendingIssues ranks open issues by their events over the last ?days=
+// (default 7), returning at most ?limit= (default 10) with the counts behind
+// each score.
+func (d *Daemon) trendingIssues(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	days, err := positiveIntParam(r, "days", 7)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := positiveIntParam(r, "limit", 10)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	trending, err := d.store.TrendingIssues(r.Context(), repo.ID, since, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if trending == nil {
+		trending = []*store.IssueActivity{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"days":   days,
+		"since":  since.Format(time.RFC3339),
+		"issues": trending,
+	})
+}
+
+// staleIssues lists open and blocked issues not updated in the last ?days=
+// (default 14), least recently updated first.
+func (d *Daemon) staleIssues(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	days, err := positiveIntParam(r, "days", 14)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	olderThan := time.Duration(days) * 24 * time.Hour
+	stale, err := d.store.StaleIssues(r.Context(), repo.ID, olderThan)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if stale == nil {
+		stale = []*model.Issue{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"days":   days,
+		"before": time.Now().UTC().Add(-olderThan).Format(time.RFC3339),
+		"issues": stale,
+	})
+}
+
+// positiveIntParam parses query parameter name as a positive integer,
+// returning def when it is absent.
+func positiveIntParam(r *http.Request, name string, def int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", name, raw)
+	}
+	return n, nil
+}
+
+// statsResponse is the body of GET /stats.
+type statsResponse struct {
+	Repo string `json:"repo"`
+	store.IssueStats
+	// OldestOpenAgeSeconds is how long the oldest open issue has existed.
+	OldestOpenAgeSeconds int64 `json:"oldest_open_age_seconds,omitempty"`
+}
+
+// issueStats handles GET /stats, summarizing the resolved repo's issues for
+// a dashboard.
+func (d *Daemon) issueStats(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := d.store.IssueStats(r.Context(), repo.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := statsResponse{Repo: repo.FullName(), IssueStats: *stats}
+	if stats.OldestOpenAt != nil {
+		resp.OldestOpenAgeSeconds = int64(time.Since(*stats.OldestOpenAt).Seconds())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type reorderIssuesRequest struct {
+	Order []int `json:"order"`
+}
+
+// reorderPriorities spreads n issues across the priority range min..max in
+// order, leaving gaps between them when the range allows. When n exceeds the
+// range, neighbouring issues share a priority but the order stays monotonic.
+func reorderPriorities(n, min, max int) []int {
+	priorities := make([]int, n)
+	for i := range priorities {
+		if n == 1 {
+			priorities[i] = min
+			continue
+		}
+		priorities[i] = min + i*(max-min)/(n-1)
+	}
+	return priorities
+}
+
+// reorderIssues handles POST /issues/reorder. It assigns priorities to the
+// listed issues in the given order, emitting an update event only for issues
+// whose priority actually changes, and writes all of them in one transaction.
+func (d *Daemon) reorderIssues(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req reorderIssuesRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Order) == 0 {
+		writeError(w, http.StatusBadRequest, "order is required")
+		return
+	}
+
+	ctx := r.Context()
+	issues := make([]*model.Issue, 0, len(req.Order))
+	seen := make(map[int]bool, len(req.Order))
+	for _, id := range req.Order {
+		if seen[id] {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("issue %d listed more than once", id))
+			return
+		}
+		seen[id] = true
+
+		issue, err := d.store.GetIssue(ctx, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("issue %d not found", id))
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if issue.RepoID != repo.ID {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("issue %d is not in %s", id, repo.FullName()))
+			return
+		}
+		issues = append(issues, issue)
+	}
+
+	min, max := d.cfg.PriorityRange()
+	priorities := reorderPriorities(len(issues), min, max)
+	now := time.Now().UTC()
+
+	var changes []store.IssueChange
+	for i, issue := range issues {
+		if issue.Priority == priorities[i] {
+			continue
+		}
+		from := issue.Priority
+		payloadJSON, err := json.Marshal(model.EventPayload{Priority: &priorities[i], FromPriority: &from})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+			return
+		}
+		event := &model.Event{
+			RepoID:    issue.RepoID,
+			IssueID:   issue.ID,
+			Timestamp: now,
+			Action:    model.ActionPriorityChange,
+			Payload:   string(payloadJSON),
+			Synced:    0,
+		}
+		updated, err := engine.Apply(issue, event, d.priorityRange())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+			return
+		}
+		issues[i] = updated
+		changes = append(changes, store.IssueChange{Issue: updated, Event: event})
+	}
+
+	if len(changes) > 0 {
+		if err := d.store.UpdateIssuesWithEvents(ctx, changes); err != nil {
+			writeError(w, http.StatusInternalServerError, "reorder: "+err.Error())
+			return
+		}
+		d.triggerSync(repo.ID)
+		for _, c := range changes {
+			d.publishIssue(model.ActionPriorityChange, c.Issue)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, issues)
+}
+
+// meOwner is the owner filter value that stands for the calling agent.
+const meOwner = "@me"
+
+// resolveOwner substitutes the caller's identity for "@me": the X-Agent
+// header if present, otherwise the configured identity. Other owner values
+// are returned unchanged.
+func (d *Daemon) resolveOwner(r *http.Request, owner string) (string, error) {
+	if owner != meOwner {
+		return owner, nil
+	}
+	if agent := r.Header.Get(AgentHeader); agent != "" {
+		return agent, nil
+	}
+	if d.cfg.Identity != "" {
+		return d.cfg.Identity, nil
+	}
+	return "", fmt.Errorf("owner=%s needs an %s header or identity in config", meOwner, AgentHeader)
+}
+
+// validatePriority checks an optional requested priority against the
+// configured range.
+func (d *Daemon) validatePriority(p *int) error {
+	if p == nil {
+		return nil
+	}
+	min, max := d.cfg.PriorityRange()
+	if *p < min || *p > max {
+		return fmt.Errorf("priority %d out of range (%d-%d)", *p, min, max)
+	}
+	return nil
+}
+
+// priorityRange is the engine option that clamps priorities set by the
+// events the handlers apply to the configured range.
+func (d *Daemon) priorityRange() engine.Option {
+	return engine.WithPriorityRange(d.cfg.PriorityRange())
+}
+
+// validateEstimate checks an optional requested estimate is not negative.
+func validateEstimate(e *int) error {
+	if e != nil && *e < 0 {
+		return fmt.Errorf("estimate %d must not be negative", *e)
+	}
+	return nil
+}
+
+// validateStatus checks an optional requested status against the known
+// statuses.
+func validateStatus(s string) error {
+	if s == "" || model.Status(s).Valid() {
+		return nil
+	}
+	valid := make([]string, len(model.Statuses))
+	for i, v := range model.Statuses {
+		valid[i] = string(v)
+	}
+	return fmt.Errorf("unknown status %q (valid: %s)", s, strings.Join(valid, ", "))
+}
+
+// validateIssueType checks an optional requested issue type against the
+// repo's issue types.
+func validateIssueType(repo *model.RepoConfig, t string) error {
+	if t == "" || repo.AllowsIssueType(model.IssueType(t)) {
+		return nil
+	}
+	types := repo.ValidIssueTypes()
+	valid := make([]string, len(types))
+	for i, v := range types {
+		valid[i] = string(v)
+	}
+	return fmt.Errorf("unknown issue_type %q (valid: %s)", t, strings.Join(valid, ", "))
+}
+
+// maxParentDepth bounds the ancestor walk in validateParent.
+const maxParentDepth = 100
+
+// validateParent checks that an optional requested parent exists in the same
+// repo and would not make issueID its own ancestor. issueID is 0 for a new
+// issue, and a parentID of 0 (clear the parent) is always valid. On failure it
+// returns the HTTP status to report.
+func (d *Daemon) validateParent(ctx context.Context, repoID, issueID int, parentID *int) (int, error) {
+	if parentID == nil || *parentID == 0 {
+		return 0, nil
+	}
+	id := *parentID
+	for depth := 0; id != 0 && depth < maxParentDepth; depth++ {
+		if id == issueID {
+			return http.StatusBadRequest, fmt.Errorf("issue %d cannot be its own ancestor", issueID)
+		}
+		ancestor, err := d.store.GetIssue(ctx, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return http.StatusBadRequest, fmt.Errorf("parent issue %d not found", id)
+			}
+			return http.StatusInternalServerError, err
+		}
+		if ancestor.RepoID != repoID {
+			return http.StatusBadRequest, fmt.Errorf("parent issue %d is in another repo", id)
+		}
+		id = 0
+		if ancestor.ParentID != nil {
+			id = *ancestor.ParentID
+		}
+	}
+	return 0, nil
+}
+
+// parseBudget reads the optional ?budget= query parameter.
+// It returns ok=false when the parameter is absent.
+func parseBudget(r *http.Request) (budget int, ok bool, err error) {
+	b := r.URL.Query().Get("budget")
+	if b == "" {
+		return 0, false, nil
+	}
+	budget, err = strconv.Atoi(b)
+	if err != nil || budget < 0 {
+		return 0, false, fmt.Errorf("invalid budget %q: must be a non-negative integer", b)
+	}
+	return budget, true, nil
+}
+
+func (d *Daemon) nextIssue(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	budget, hasBudget, err := parseBudget(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	explain := r.URL.Query().Get("explain") == "true"
+
+	if r.URL.Query().Has("count") {
+		d.nextIssues(w, r, repo, hasBudget, explain)
+		return
+	}
+
+	var issue *model.Issue
+	if o := r.URL.Query().Get("owner"); o != "" {
+		// With an owner, next means "what should this owner resume".
+		if hasBudget {
+			writeError(w, http.StatusBadRequest, "budget cannot be combined with owner")
+			return
+		}
+		if explain {
+			writeError(w, http.StatusBadRequest, "explain cannot be combined with owner")
+			return
+		}
+		owner, err := d.resolveOwner(r, o)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		issue, err = d.store.NextIssueForOwner(r.Context(), repo.ID, owner)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				writeError(w, http.StatusNotFound, "no unfinished issues assigned to "+owner)
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, issue)
+		return
+	}
+	if hasBudget {
+		issue, err = d.store.NextIssueWithinBudget(r.Context(), repo.ID, budget)
+	} else {
+		issue, err = d.store.NextIssue(r.Context(), repo.ID)
+	}
+	if err == sql.ErrNoRows && explain {
+		issue, err = nil, nil
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "no issues available")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !explain {
+		writeJSON(w, http.StatusOK, issue)
+		return
+	}
+
+	if !hasBudget {
+		budget = -1
+	}
+	ex, err := d.store.NextIssueExclusions(r.Context(), repo.ID, budget)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := map[string]interface{}{
+		"next":     issue,
+		"excluded": ex,
+	}
+	if issue == nil {
+		resp["reason"] = explainNoNext(ex)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// nextIssues handles GET /issues/next?count=N, which returns up to N issues
+// in next order as an array, so a scheduler can hand work to a pool of
+// agents in one call. An empty array means nothing is eligible.
+func (d *Daemon) nextIssues(w http.ResponseWriter, r *http.Request, repo *model.RepoConfig, hasBudget, explain bool) {
+	for _, other := range []struct {
+		set  bool
+		name string
+	}{
+		{r.URL.Query().Get("owner") != "", "owner"},
+		{hasBudget, "budget"},
+		{explain, "explain"},
+	} {
+		if other.set {
+			writeError(w, http.StatusBadRequest, "count cannot be combined with "+other.name)
+			return
+		}
+	}
+	count, err := positiveIntParam(r, "count", 1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	issues, err := d.store.NextIssues(r.Context(), repo.ID, min(count, maxListLimit))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if issues == nil {
+		issues = []*model.Issue{}
+	}
+	writeJSON(w, http.StatusOK, issues)
+}
+
+// explainNoNext summarises why no issue was eligible, e.g.
+// "all 5 open issues are assigned".
+func explainNoNext(ex *store.NextExclusions) string {
+	if ex.Candidates == 0 {
+		return "no open issues"
+	}
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		name string
+	}{
+		{ex.Blocked, "blocked"},
+		{ex.Assigned, "assigned"},
+		{ex.Snoozed, "snoozed"},
+		{ex.OverBudget, "over budget"},
+	} {
+		if c.n == ex.Candidates {
+			if c.n == 1 {
+				return "the only open issue is " + c.name
+			}
+			return fmt.Sprintf("all %d open issues are %s", ex.Candidates, c.name)
+		}
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.name))
+		}
+	}
+	return fmt.Sprintf("all %d open issues are excluded: %s", ex.Candidates, strings.Join(parts, ", "))
+}
+
+// planIssues returns a greedy set of next-eligible issues whose estimates
+// sum to at most ?budget=.
+func (d *Daemon) planIssues(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	budget, hasBudget, err := parseBudget(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !hasBudget {
+		writeError(w, http.StatusBadRequest, "budget is required")
+		return
+	}
+
+	issues, err := d.store.PlanIssues(r.Context(), repo.ID, budget)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if issues == nil {
+		issues = []*model.Issue{}
+	}
+
+	total := 0
+	for _, iss := range issues {
+		total += iss.Estimate
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"budget": budget,
+		"total":  total,
+		"issues": issues,
+	})
+}
+
+func (d *Daemon) getIssue(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	issue, err := d.store.GetIssue(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if issue.ChildCounts, err = d.childCounts(r.Context(), issue); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := d.store.FillEventStats(r.Context(), []*model.Issue{issue}); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// fieldHistory returns how a single issue field changed over time, derived by
+// replaying the issue's event log.
+func (d *Daemon) fieldHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	field := r.URL.Query().Get("field")
+	supported := false
+	for _, f := range engine.HistoryFields {
+		if f == field {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		writeError(w, http.StatusBadRequest, "field must be one of: "+strings.Join(engine.HistoryFields, ", "))
+		return
+	}
+
+	ctx := r.Context()
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	events, err := d.store.ListEvents(ctx, issue.RepoID, issue.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "list events: "+err.Error())
+		return
+	}
+
+	history, err := engine.FieldHistory(events, field, d.priorityRange())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "replay events: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issue_id": issue.ID,
+		"field":    field,
+		"history":  history,
+	})
+}
+
+// timelineEvent is one entry of GET /issues/{id}/events: a stored event and
+// the human-readable text its GitHub comment carries.
+type timelineEvent struct {
+	*model.Event
+	Text string `json:"text"`
+}
+
+// issueTimeline returns the issue's events in the order they were recorded,
+// each with its human-readable text.
+func (d *Daemon) issueTimeline(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	events, err := d.store.ListEvents(ctx, issue.RepoID, issue.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "list events: "+err.Error())
+		return
+	}
+
+	d.store.HydrateComments(ctx, events)
+	timeline := make([]timelineEvent, 0, len(events))
+	for _, ev := range events {
+		timeline = append(timeline, timelineEvent{Event: ev, Text: github.FormatHumanText(ev)})
+	}
+	writeJSON(w, http.StatusOK, timeline)
+}
+
+type createIssueRequest struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Priority    *int     `json:"priority"`
+	Estimate    *int     `json:"estimate"`
+	IssueType   string   `json:"issue_type"`
+	Labels      []string `json:"labels"`
+	Comment     string   `json:"comment"`
+	ParentID    *int     `json:"parent_id"`
+}
+
+func (d *Daemon) createIssue(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req createIssueRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := r.Context()
+	if status, err := d.validateCreateIssue(ctx, repo, &req); err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+
+	change, err := newIssueChange(repo, &req, time.Now().UTC())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Persist the issue first to get its ID.
+	created, err := d.store.CreateIssue(ctx, change.Issue)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "create issue: "+err.Error())
+		return
+	}
+
+	change.Event.IssueID = created.ID
+	if _, err := d.store.AppendEvent(ctx, change.Event); err != nil {
+		writeError(w, http.StatusInternalServerError, "append event: "+err.Error())
+		return
+	}
+
+	d.triggerSync(repo.ID)
+	d.publishIssue(model.ActionCreate, created)
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// validateCreateIssue checks req as a new issue in repo, clearing a zero
+// parent_id. It returns the status to report with the error.
+func (d *Daemon) validateCreateIssue(ctx context.Context, repo *model.RepoConfig, req *createIssueRequest) (int, error) {
+	if req.Title == "" {
+		return http.StatusBadRequest, fmt.Errorf("title is required")
+	}
+	if err := d.validatePriority(req.Priority); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err := validateEstimate(req.Estimate); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err := validateIssueType(repo, req.IssueType); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if req.ParentID != nil && *req.ParentID == 0 {
+		req.ParentID = nil
+	}
+	return d.validateParent(ctx, repo.ID, 0, req.ParentID)
+}
+
+// newIssueChange builds the issue req creates and its create event. The
+// event's IssueID is left for the caller to set once the issue is stored.
+func newIssueChange(repo *model.RepoConfig, req *createIssueRequest, now time.Time) (store.IssueChange, error) {
+	issue := &model.Issue{
+		RepoID:    repo.ID,
+		Title:     req.Title,
+		Status:    model.StatusOpen,
+		IssueType: repo.ValidIssueTypes()[0],
+		Labels:    req.Labels,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Priority != nil {
+		issue.Priority = *req.Priority
+	}
+	if req.Estimate != nil {
+		issue.Estimate = *req.Estimate
+	}
+	if req.IssueType != "" {
+		issue.IssueType = model.IssueType(req.IssueType)
+	}
+	issue.Description = repo.ApplyTemplate(issue.IssueType, req.Description)
+	if issue.Labels == nil {
+		issue.Labels = []string{}
+	}
+	issue.ParentID = req.ParentID
+
+	payload := model.EventPayload{
+		Title:       req.Title,
+		Description: issue.Description,
+		Priority:    req.Priority,
+		Estimate:    req.Estimate,
+		IssueType:   string(issue.IssueType),
+		Labels:      req.Labels,
+		Comment:     req.Comment,
+		ParentID:    req.ParentID,
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return store.IssueChange{}, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	event := &model.Event{
+		RepoID:    repo.ID,
+		Timestamp: now,
+		Action:    model.ActionCreate,
+		Payload:   string(payloadJSON),
+		Synced:    0,
+	}
+	return store.IssueChange{Issue: issue, Event: event}, nil
+}
+
+// maxBulkIssues caps POST /issues/bulk, whose single transaction holds the
+// write lock for the whole batch.
+const maxBulkIssues = 1000
+
+// bulkCreateResult reports one entry of a POST /issues/bulk request: the
+// issue it created, or why it was rejected.
+type bulkCreateResult struct {
+	Index int          `json:"index"`
+	Issue *model.Issue `json:"issue,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+type bulkCreateResponse struct {
+	Created int                `json:"created"`
+	Failed  int                `json:"failed"`
+	Results []bulkCreateResult `json:"results"`
+	// Error is set when no entry was created.
+	Error string `json:"error,omitempty"`
+}
+
+// bulkCreateIssues handles POST /issues/bulk. It validates each entry of a
+// JSON array of createIssueRequest, then creates the valid ones and their
+// create events in one transaction. Results follow the request order. It
+// answers 201 if anything was created and 400 if every entry was rejected.
+func (d *Daemon) bulkCreateIssues(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var reqs []createIssueRequest
+	if err := readJSON(r, &reqs); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(reqs) == 0 {
+		writeError(w, http.StatusBadRequest, "at least one issue is required")
+		return
+	}
+	if len(reqs) > maxBulkIssues {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d issues per request", maxBulkIssues))
+		return
+	}
+
+	ctx := r.Context()
+	now := time.Now().UTC()
+	resp := bulkCreateResponse{Results: make([]bulkCreateResult, len(reqs))}
+	var changes []store.IssueChange
+	var indexes []int
+	for i := range reqs {
+		resp.Results[i].Index = i
+		if _, err := d.validateCreateIssue(ctx, repo, &reqs[i]); err != nil {
+			resp.Results[i].Error = err.Error()
+			resp.Failed++
+			continue
+		}
+		change, err := newIssueChange(repo, &reqs[i], now)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		changes = append(changes, change)
+		indexes = append(indexes, i)
+	}
+
+	if len(changes) == 0 {
+		resp.Error = fmt.Sprintf("no issues created; entry 0: %s", resp.Results[0].Error)
+		if resp.Failed > 1 {
+			resp.Error += fmt.Sprintf(" (and %d more)", resp.Failed-1)
+		}
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	created, err := d.store.CreateIssuesWithEvents(ctx, changes)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "create issues: "+err.Error())
+		return
+	}
+	for j, issue := range created {
+		resp.Results[indexes[j]].Issue = issue
+		resp.Created++
+	}
+
+	d.triggerSync(repo.ID)
+	for _, issue := range created {
+		d.publishIssue(model.ActionCreate, issue)
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+type updateIssueRequest struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Status      string   `json:"status,omitempty"`
+	Priority    *int     `json:"priority,omitempty"`
+	Estimate    *int     `json:"estimate,omitempty"`
+	IssueType   string   `json:"issue_type,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Comment     string   `json:"comment,omitempty"`
+	// ParentID links the issue to an epic; 0 clears the link.
+	ParentID *int `json:"parent_id,omitempty"`
+	// Version, if set, is the issue's version as the client last read it.
+	// The update is refused with 409 if the issue has changed since.
+	Version *int `json:"version,omitempty"`
+}
+
+func (d *Daemon) updateIssue(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req updateIssueRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := d.validatePriority(req.Priority); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateEstimate(req.Estimate); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	now := time.Now().UTC()
+
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := validateStatus(req.Status); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.IssueType != "" {
+		repo, err := d.store.GetRepo(ctx, issue.RepoID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := validateIssueType(repo, req.IssueType); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if status, err := d.validateParent(ctx, issue.RepoID, issue.ID, req.ParentID); err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+
+	// streamAction is the most significant action applied, for the event
+	// stream; it stays empty when the request changed nothing.
+	var streamAction model.Action
+
+	// With a version, the first event is appended only if the issue is
+	// still at that version; the events after it belong to the same change.
+	expected := req.Version
+	appendEvent := func(event *model.Event) (*model.Event, error) {
+		if expected == nil {
+			return d.store.AppendEvent(ctx, event)
+		}
+		version := *expected
+		expected = nil
+		return d.store.AppendEventAtVersion(ctx, event, version)
+	}
+
+	// If status is changing, use a status_change or close event.
+	statusChanged := false
+	if req.Status != "" && model.Status(req.Status) != issue.Status {
+		statusChanged = true
+		newStatus := model.Status(req.Status)
+
+		var action model.Action
+		if newStatus == model.StatusClosed {
+			action = model.ActionClose
+		} else {
+			action = model.ActionStatusChange
+		}
+		streamAction = action
+
+		payload := model.EventPayload{
+			Status:     newStatus,
+			FromStatus: issue.Status,
+			Comment:    req.Comment,
+		}
+		payloadJSON, err := json.Marshal(payload)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+			return
+		}
+
+		event := &model.Event{
+			RepoID:    issue.RepoID,
+			IssueID:   issue.ID,
+			Timestamp: now,
+			Action:    action,
+			Payload:   string(payloadJSON),
+			Synced:    0,
+		}
+
+		savedEvent, err := appendEvent(event)
+		if err != nil {
+			d.writeAppendError(w, r, issue, req.Version, event, err)
+			return
+		}
+
+		issue, err = engine.Apply(issue, savedEvent, d.priorityRange())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+			return
+		}
+	}
+
+	// If there are non-status field changes, generate an update event, or a
+	// priority_change event when the priority is the only field changed.
+	otherFieldChange := req.Title != "" || req.Description != "" ||
+		req.Estimate != nil || req.IssueType != "" || req.Labels != nil || req.ParentID != nil
+	hasFieldChange := otherFieldChange || req.Priority != nil
+	if hasFieldChange {
+		fieldAction := model.ActionUpdate
+		if !otherFieldChange {
+			fieldAction = model.ActionPriorityChange
+		}
+		// If the comment was already attached to a status_change event, don't duplicate it.
+		comment := req.Comment
+		if statusChanged {
+			comment = ""
+		} else {
+			streamAction = fieldAction
+		}
+		payload := model.EventPayload{
+			Title:       req.Title,
+			Description: req.Description,
+			Priority:    req.Priority,
+			Estimate:    req.Estimate,
+			IssueType:   req.IssueType,
+			Labels:      req.Labels,
+			Comment:     comment,
+			ParentID:    req.ParentID,
+		}
+		if fieldAction == model.ActionPriorityChange {
+			from := issue.Priority
+			payload.FromPriority = &from
+		}
+		payloadJSON, err := json.Marshal(payload)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+			return
+		}
+
+		event := &model.Event{
+			RepoID:    issue.RepoID,
+			IssueID:   issue.ID,
+			Timestamp: now,
+			Action:    fieldAction,
+			Payload:   string(payloadJSON),
+			Synced:    0,
+		}
+
+		savedEvent, err := appendEvent(event)
+		if err != nil {
+			d.writeAppendError(w, r, issue, req.Version, event, err)
+			return
+		}
+
+		issue, err = engine.Apply(issue, savedEvent, d.priorityRange())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+			return
+		}
+	}
+
+	// If there's a comment but no other changes carried it, generate a standalone comment event.
+	if req.Comment != "" && !hasFieldChange && !statusChanged {
+		streamAction = model.ActionComment
+		payload := model.EventPayload{
+			Comment: req.Comment,
+		}
+		payloadJSON, err := json.Marshal(payload)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+			return
+		}
+
+		event := &model.Event{
+			RepoID:    issue.RepoID,
+			IssueID:   issue.ID,
+			Timestamp: now,
+			Action:    model.ActionComment,
+			Payload:   string(payloadJSON),
+			Synced:    0,
+		}
+
+		savedEvent, err := appendEvent(event)
+		if err != nil {
+			d.writeAppendError(w, r, issue, req.Version, event, err)
+			return
+		}
+
+		issue, err = engine.Apply(issue, savedEvent, d.priorityRange())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+			return
+		}
+	}
+
+	if err := d.store.UpdateIssue(ctx, issue); err != nil {
+		writeError(w, http.StatusInternalServerError, "update issue: "+err.Error())
+		return
+	}
+
+	// Re-fetch to get the canonical stored state, with its new version.
+	issue, err = d.store.GetIssue(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := d.store.FillEventStats(ctx, []*model.Issue{issue}); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// A request that changed nothing appended no event to check its
+	// version against, so compare it here.
+	if expected != nil && *expected != issue.Version {
+		d.writeAppendError(w, r, issue, expected, nil, store.ErrVersionConflict)
+		return
+	}
+
+	d.triggerSync(issue.RepoID)
+	if streamAction != "" {
+		d.publishIssue(streamAction, issue)
+	}
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// writeAppendError reports a failed append of one of an update's events:
+// 409 if the issue changed since the client's version, else 500. A refused
+// event is recorded in the issue's log as an update_rejected event, which
+// replay does not apply, so both racing attempts are kept for audit.
+func (d *Daemon) writeAppendError(w http.ResponseWriter, r *http.Request, issue *model.Issue, version *int, refused *model.Event, err error) {
+	if !errors.Is(err, store.ErrVersionConflict) {
+		writeError(w, http.StatusInternalServerError, "append event: "+err.Error())
+		return
+	}
+	agent := r.Header.Get(AgentHeader)
+	slog.InfoContext(r.Context(), "update refused: version conflict", "issue", issue.ID, "version", *version, "agent", agent)
+	if refused != nil {
+		payload := model.EventPayload{RejectedAction: refused.Action, Version: *version}
+		if refused.Payload != "" {
+			payload.Rejected = json.RawMessage(refused.Payload)
+		}
+		payloadJSON, err := json.Marshal(payload)
+		if err == nil {
+			// Never pushed: the refused change did not happen.
+			_, err = d.store.AppendEvent(r.Context(), &model.Event{
+				RepoID:    issue.RepoID,
+				IssueID:   issue.ID,
+				Timestamp: refused.Timestamp,
+				Action:    model.ActionUpdateRejected,
+				Payload:   string(payloadJSON),
+				Agent:     agent,
+				Synced:    1,
+			})
+		}
+		if err != nil {
+			slog.WarnContext(r.Context(), "record refused update", "issue", issue.ID, "error", err)
+		}
+	}
+	writeError(w, http.StatusConflict, fmt.Sprintf("issue #%d has changed since version %d; fetch it again and retry", issue.ID, *version))
+}
+
+func (d *Daemon) deleteIssue(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	now := time.Now().UTC()
+
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Append a delete event.
+	deletePayload := model.EventPayload{
+		FromStatus: issue.Status,
+	}
+	deletePayloadJSON, err := json.Marshal(deletePayload)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+		return
+	}
+
+	event := &model.Event{
+		RepoID:    issue.RepoID,
+		IssueID:   issue.ID,
+		Timestamp: now,
+		Action:    model.ActionDelete,
+		Payload:   string(deletePayloadJSON),
+		Synced:    0,
+	}
+
+	if _, err := d.store.AppendEvent(ctx, event); err != nil {
+		writeError(w, http.StatusInternalServerError, "append event: "+err.Error())
+		return
+	}
+
+	// Soft-delete in store.
+	if err := d.store.DeleteIssue(ctx, id); err != nil {
+		writeError(w, http.StatusInternalServerError, "delete issue: "+err.Error())
+		return
+	}
+
+	// Re-fetch to return current state.
+	issue, err = d.store.GetIssue(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	d.triggerSync(issue.RepoID)
+	d.publishIssue(model.ActionDelete, issue)
+	writeJSON(w, http.StatusOK, issue)
+}
+
+type assignIssueRequest struct {
+	Owner string `json:"owner"`
+}
+
+func (d *Daemon) assignIssue(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req assignIssueRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	now := time.Now().UTC()
+
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if msg, err := d.checkAssignee(ctx, issue, req.Owner); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	} else if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if msg, err := d.checkWIPLimit(ctx, issue, req.Owner); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	} else if msg != "" {
+		writeError(w, http.StatusConflict, msg)
+		return
+	}
+
+	// Append an assign event.
+	payload := model.EventPayload{
+		Owner: req.Owner,
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+		return
+	}
+
+	event := &model.Event{
+		RepoID:    issue.RepoID,
+		IssueID:   issue.ID,
+		Timestamp: now,
+		Action:    model.ActionAssign,
+		Payload:   string(payloadJSON),
+		Synced:    0,
+	}
+
+	savedEvent, err := d.store.AppendEvent(ctx, event)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "append event: "+err.Error())
+		return
+	}
+
+	issue, err = engine.Apply(issue, savedEvent, d.priorityRange())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+		return
+	}
+
+	if err := d.store.UpdateIssue(ctx, issue); err != nil {
+		writeError(w, http.StatusInternalServerError, "update issue: "+err.Error())
+		return
+	}
+
+	// Re-fetch.
+	issue, err = d.store.GetIssue(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	d.triggerSync(issue.RepoID)
+	d.publishIssue(model.ActionAssign, issue)
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// claimIssue handles POST /issues/claim?owner=X. It assigns the issue
+// GET /issues/next would return to owner in one store transaction, so two
+// agents claiming at once never get the same issue, and returns it.
+func (d *Daemon) claimIssue(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	o := r.URL.Query().Get("owner")
+	if o == "" {
+		writeError(w, http.StatusBadRequest, "owner is required")
+		return
+	}
+	owner, err := d.resolveOwner(r, o)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	unassigned := &model.Issue{RepoID: repo.ID}
+	if msg, err := d.checkAssignee(ctx, unassigned, owner); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	} else if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	payloadJSON, err := json.Marshal(model.EventPayload{Owner: owner})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+		return
+	}
+	// The store checks the wip_limit in the claim's transaction.
+	issue, err := d.store.ClaimNextIssue(ctx, repo.ID, owner, func(issue *model.Issue) (store.IssueChange, error) {
+		event := &model.Event{
+			RepoID:    issue.RepoID,
+			IssueID:   issue.ID,
+			Timestamp: time.Now().UTC(),
+			Action:    model.ActionAssign,
+			Payload:   string(payloadJSON),
+			Synced:    0,
+		}
+		issue, err := engine.Apply(issue, event, d.priorityRange())
+		return store.IssueChange{Issue: issue, Event: event}, err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "no issues available")
+			return
+		}
+		var wipErr *store.WIPLimitError
+		if errors.As(err, &wipErr) {
+			writeError(w, http.StatusConflict, wipErr.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "claim issue: "+err.Error())
+		return
+	}
+
+	d.triggerSync(issue.RepoID)
+	d.publishIssue(model.ActionAssign, issue)
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// checkWIPLimit returns why assigning issue to owner would exceed the repo's
+// WIPLimit, or "" if it would not. Unassigning, and assigning an issue to the
+// owner it already has, never count against the limit.
+func (d *Daemon) checkWIPLimit(ctx context.Context, issue *model.Issue, owner string) (string, error) {
+	if owner == "" || strings.EqualFold(owner, issue.Owner) {
+		return "", nil
+	}
+	repo, err := d.store.GetRepo(ctx, issue.RepoID)
+	if err != nil {
+		return "", fmt.Errorf("get repo: %w", err)
+	}
+	if repo.WIPLimit == 0 {
+		return "", nil
+	}
+
+	held := 0
+	for _, status := range []model.Status{model.StatusOpen, model.StatusInProgress} {
+		n, err := d.store.CountIssues(ctx, store.IssueFilter{RepoID: repo.ID, Status: status, Owner: owner})
+		if err != nil {
+			return "", fmt.Errorf("count issues: %w", err)
+		}
+		held += n
+	}
+	if held < repo.WIPLimit {
+		return "", nil
+	}
+	return fmt.Sprintf("%s already has %d open or in_progress issues, the repo's wip_limit of %d", owner, held, repo.WIPLimit), nil
+}
+
+// ---------------------------------------------------------------------------
+// Abandon issue
+// ---------------------------------------------------------------------------
+
+type abandonIssueRequest struct {
+	Reason string `json:"reason"`
+	// KeepStatus leaves an in_progress issue in_progress instead of moving
+	// it back to open.
+	KeepStatus bool `json:"keep_status,omitempty"`
+}
+
+// abandonIssue returns an issue to the pool: it clears the owner, moves an
+// in_progress issue back to open, and records the reason as a comment. The
+// assign, status_change and comment events are written in one transaction.
+func (d *Daemon) abandonIssue(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req abandonIssueRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		writeError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+
+	ctx := r.Context()
+	now := time.Now().UTC()
+
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	type step struct {
+		action  model.Action
+		payload model.EventPayload
+	}
+	steps := []step{{model.ActionAssign, model.EventPayload{Owner: ""}}}
+	if issue.Status == model.StatusInProgress && !req.KeepStatus {
+		steps = append(steps, step{model.ActionStatusChange, model.EventPayload{Status: model.StatusOpen, FromStatus: model.StatusInProgress}})
+	}
+	steps = append(steps, step{model.ActionComment, model.EventPayload{Comment: "Abandoned: " + req.Reason}})
+
+	var changes []store.IssueChange
+	for _, p := range steps {
+		payloadJSON, err := json.Marshal(p.payload)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+			return
+		}
+		event := &model.Event{
+			RepoID:    issue.RepoID,
+			IssueID:   issue.ID,
+			Timestamp: now,
+			Action:    p.action,
+			Payload:   string(payloadJSON),
+			Synced:    0,
+		}
+		issue, err = engine.Apply(issue, event, d.priorityRange())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+			return
+		}
+		changes = append(changes, store.IssueChange{Issue: issue, Event: event})
+	}
+
+	if err := d.store.UpdateIssuesWithEvents(ctx, changes); err != nil {
+		writeError(w, http.StatusInternalServerError, "abandon: "+err.Error())
+		return
+	}
+
+	issue, err = d.store.GetIssue(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	d.triggerSync(issue.RepoID)
+	d.publishIssue(model.ActionAssign, issue)
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// ---------------------------------------------------------------------------
+// Reopen issue
+// ---------------------------------------------------------------------------
+
+// reopenIssue moves a closed issue back to open with a reopen event, which
+// also clears closed_at. Only closed issues can be reopened.
+func (d *Daemon) reopenIssue(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if issue.Status != model.StatusClosed {
+		writeError(w, http.StatusConflict, fmt.Sprintf("issue %d is %s, not closed", issue.ID, issue.Status))
+		return
+	}
+
+	event := &model.Event{
+		RepoID:    issue.RepoID,
+		IssueID:   issue.ID,
+		Timestamp: time.Now().UTC(),
+		Action:    model.ActionReopen,
+		Payload:   "{}",
+		Synced:    0,
+	}
+	issue, err = engine.Apply(issue, event, d.priorityRange())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+		return
+	}
+
+	if err := d.store.UpdateIssuesWithEvents(ctx, []store.IssueChange{{Issue: issue, Event: event}}); err != nil {
+		writeError(w, http.StatusInternalServerError, "reopen: "+err.Error())
+		return
+	}
+
+	issue, err = d.store.GetIssue(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	d.triggerSync(issue.RepoID)
+	d.publishIssue(model.ActionReopen, issue)
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// ---------------------------------------------------------------------------
+// Restore issue
+// ---------------------------------------------------------------------------
+
+// restoreIssue brings a deleted issue back as open with a restore event,
+// which also clears closed_at. It is the only way out of the deleted
+// status; any other issue is rejected.
+func (d *Daemon) restoreIssue(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if issue.Status != model.StatusDeleted {
+		writeError(w, http.StatusConflict, fmt.Sprintf("issue %d is %s, not deleted", issue.ID, issue.Status))
+		return
+	}
+
+	event := &model.Event{
+		RepoID:    issue.RepoID,
+		IssueID:   issue.ID,
+		Timestamp: time.Now().UTC(),
+		Action:    model.ActionRestore,
+		Payload:   "{}",
+		Synced:    0,
+	}
+	issue, err = engine.Apply(issue, event, d.priorityRange())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+		return
+	}
+
+	if err := d.store.UpdateIssuesWithEvents(ctx, []store.IssueChange{{Issue: issue, Event: event}}); err != nil {
+		writeError(w, http.StatusInternalServerError, "restore: "+err.Error())
+		return
+	}
+
+	issue, err = d.store.GetIssue(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	d.triggerSync(issue.RepoID)
+	d.publishIssue(model.ActionRestore, issue)
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// ---------------------------------------------------------------------------
+// Issue labels
+// ---------------------------------------------------------------------------
+
+type addLabelRequest struct {
+	Label string `json:"label"`
+}
+
+// addIssueLabel adds one label with a label_add event. Unlike replacing the
+// labels through PATCH, concurrent adds from different agents all survive.
+func (d *Daemon) addIssueLabel(w http.ResponseWriter, r *http.Request) {
+	var req addLabelRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	d.changeIssueLabel(w, r, model.ActionLabelAdd, req.Label)
+}
+
+// removeIssueLabel removes the label named by the "label" query parameter
+// with a label_remove event.
+func (d *Daemon) removeIssueLabel(w http.ResponseWriter, r *http.Request) {
+	d.changeIssueLabel(w, r, model.ActionLabelRemove, r.URL.Query().Get("label"))
+}
+
+// changeIssueLabel records a label_add or label_remove event and returns the
+// updated issue. A change that would not alter the labels records nothing.
+func (d *Daemon) changeIssueLabel(w http.ResponseWriter, r *http.Request, action model.Action, label string) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	label = strings.TrimSpace(label)
+	if label == "" {
+		writeError(w, http.StatusBadRequest, "label is required")
+		return
+	}
+
+	ctx := r.Context()
+
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	has := false
+	for _, l := range issue.Labels {
+		if strings.EqualFold(l, label) {
+			has = true
+			break
+		}
+	}
+	if has == (action == model.ActionLabelAdd) {
+		writeJSON(w, http.StatusOK, issue)
+		return
+	}
+
+	payloadJSON, err := json.Marshal(model.EventPayload{Label: label})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+		return
+	}
+	event := &model.Event{
+		RepoID:    issue.RepoID,
+		IssueID:   issue.ID,
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Payload:   string(payloadJSON),
+		Synced:    0,
+	}
+	issue, err = engine.Apply(issue, event, d.priorityRange())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+		return
+	}
+
+	if err := d.store.UpdateIssuesWithEvents(ctx, []store.IssueChange{{Issue: issue, Event: event}}); err != nil {
+		writeError(w, http.StatusInternalServerError, "update labels: "+err.Error())
+		return
+	}
+
+	issue, err = d.store.GetIssue(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	d.triggerSync(issue.RepoID)
+	d.publishIssue(action, issue)
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// ---------------------------------------------------------------------------
+// Issue dependencies
+// ---------------------------------------------------------------------------
+
+type addDependencyRequest struct {
+	BlockerID int `json:"blocker_id"`
+}
+
+// addIssueDependency records that the issue is blocked by another issue in
+// the same repo. NextIssue skips the issue until the blocker closes.
+func (d *Daemon) addIssueDependency(w http.ResponseWriter, r *http.Request) {
+	var req addDependencyRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	d.changeIssueDependency(w, r, model.ActionAddDependency, req.BlockerID)
+}
+
+// removeIssueDependency drops the blocker named by the "blocker_id" query
+// parameter.
+func (d *Daemon) removeIssueDependency(w http.ResponseWriter, r *http.Request) {
+	blockerID, err := strconv.Atoi(r.URL.Query().Get("blocker_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid blocker_id")
+		return
+	}
+	d.changeIssueDependency(w, r, model.ActionRemoveDependency, blockerID)
+}
+
+// changeIssueDependency records an add_dependency or remove_dependency event
+// and returns the updated issue. A change that would not alter the issue's
+// blockers records nothing.
+func (d *Daemon) changeIssueDependency(w http.ResponseWriter, r *http.Request, action model.Action, blockerID int) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if blockerID <= 0 {
+		writeError(w, http.StatusBadRequest, "blocker_id is required")
+		return
+	}
+	if blockerID == id {
+		writeError(w, http.StatusBadRequest, "an issue cannot block itself")
+		return
+	}
+
+	ctx := r.Context()
+
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	has := false
+	for _, b := range issue.BlockedBy {
+		if b == blockerID {
+			has = true
+			break
+		}
+	}
+	if has == (action == model.ActionAddDependency) {
+		writeJSON(w, http.StatusOK, issue)
+		return
+	}
+
+	if action == model.ActionAddDependency {
+		blocker, err := d.store.GetIssue(ctx, blockerID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("blocker issue %d not found", blockerID))
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if blocker.RepoID != issue.RepoID {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("blocker issue %d belongs to a different repo", blockerID))
+			return
+		}
+	}
+
+	payloadJSON, err := json.Marshal(model.EventPayload{BlockerID: blockerID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+		return
+	}
+	event := &model.Event{
+		RepoID:    issue.RepoID,
+		IssueID:   issue.ID,
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Payload:   string(payloadJSON),
+		Synced:    0,
+	}
+	issue, err = engine.Apply(issue, event, d.priorityRange())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+		return
+	}
+
+	if err := d.store.UpdateIssuesWithEvents(ctx, []store.IssueChange{{Issue: issue, Event: event}}); err != nil {
+		writeError(w, http.StatusInternalServerError, "update dependencies: "+err.Error())
+		return
+	}
+
+	issue, err = d.store.GetIssue(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	d.triggerSync(issue.RepoID)
+	d.publishIssue(action, issue)
+	writeJSON(w, http.StatusOK, issue)
+}
+
+// ---------------------------------------------------------------------------
+// Issue watchers and notifications
+// ---------------------------------------------------------------------------
 
-	// Unless ?all=true, exclude deleted issues. If no explicit status filter
-	// is set, we need to filter out deleted in the result set.
-	showAll := r.URL.Query().Get("all") == "true"
+type watchRequest struct {
+	Watcher string `json:"watcher"`
+}
 
-	issues, err := d.store.ListIssues(r.Context(), filter)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+// watchIssue adds a watcher with a watch event. From then on every change to
+// the issue is recorded as a notification for the watcher.
+func (d *Daemon) watchIssue(w http.ResponseWriter, r *http.Request) {
+	var req watchRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	d.changeIssueWatcher(w, r, model.ActionWatch, req.Watcher)
+}
 
-	if !showAll && filter.Status == "" {
-		filtered := make([]*model.Issue, 0, len(issues))
-		for _, iss := range issues {
-			if iss.Status != model.StatusDeleted && iss.Status != model.StatusClosed {
-				filtered = append(filtered, iss)
-			}
-		}
-		issues = filtered
-	}
-
-	if issues == nil {
-		issues = []*model.Issue{}
-	}
-
-	writeJSON(w, http.StatusOK, issues)
+// unwatchIssue removes the watcher named by the "watcher" query parameter
+// with an unwatch event.
+func (d *Daemon) unwatchIssue(w http.ResponseWriter, r *http.Request) {
+	d.changeIssueWatcher(w, r, model.ActionUnwatch, r.URL.Query().Get("watcher"))
 }
 
-func (d *Daemon) nextIssue(w http.ResponseWriter, r *http.Request) {
-	repo, err := d.resolveRepo(r)
+// changeIssueWatcher records a watch or unwatch event and returns the updated
+// issue. "@me" names the caller, as in the owner filter. A change that would
+// not alter the watchers records nothing.
+func (d *Daemon) changeIssueWatcher(w http.ResponseWriter, r *http.Request, action model.Action, watcher string) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	watcher, err = d.resolveOwner(r, strings.TrimSpace(watcher))
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if watcher == "" {
+		writeError(w, http.StatusBadRequest, "watcher is required")
+		return
+	}
+
+	ctx := r.Context()
 
-	issue, err := d.store.NextIssue(r.Context(), repo.ID)
+	issue, err := d.store.GetIssue(ctx, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			writeError(w, http.StatusNotFound, "no issues available")
+			writeError(w, http.StatusNotFound, "issue not found")
 			return
 		}
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	writeJSON(w, http.StatusOK, issue)
-}
+	has := false
+	for _, existing := range issue.Watchers {
+		if strings.EqualFold(existing, watcher) {
+			has = true
+			break
+		}
+	}
+	if has == (action == model.ActionWatch) {
+		writeJSON(w, http.StatusOK, issue)
+		return
+	}
 
-func (d *Daemon) getIssue(w http.ResponseWriter, r *http.Request) {
-	id, err := parseIssueID(r)
+	payloadJSON, err := json.Marshal(model.EventPayload{Watcher: watcher})
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+		return
+	}
+	event := &model.Event{
+		RepoID:    issue.RepoID,
+		IssueID:   issue.ID,
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Payload:   string(payloadJSON),
+		Synced:    0,
+	}
+	issue, err = engine.Apply(issue, event, d.priorityRange())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
 		return
 	}
 
-	issue, err := d.store.GetIssue(r.Context(), id)
+	if err := d.store.UpdateIssuesWithEvents(ctx, []store.IssueChange{{Issue: issue, Event: event}}); err != nil {
+		writeError(w, http.StatusInternalServerError, "update watchers: "+err.Error())
+		return
+	}
+
+	issue, err = d.store.GetIssue(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			writeError(w, http.StatusNotFound, "issue not found")
-			return
-		}
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	d.triggerSync(issue.RepoID)
+	d.publishIssue(action, issue)
 	writeJSON(w, http.StatusOK, issue)
 }
 
-type createIssueRequest struct {
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Priority    *int     `json:"priority"`
-	IssueType   string   `json:"issue_type"`
-	Labels      []string `json:"labels"`
-	Comment     string   `json:"comment"`
-}
-
-func (d *Daemon) createIssue(w http.ResponseWriter, r *http.Request) {
+// listNotifications handles GET /notifications?owner=, returning the changes
+// to issues the owner watches in the resolved repo, newest first, at most
+// ?limit= of them.
+func (d *Daemon) listNotifications(w http.ResponseWriter, r *http.Request) {
 	repo, err := d.resolveRepo(r)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	var req createIssueRequest
-	if err := readJSON(r, &req); err != nil {
+	owner, err := d.resolveOwner(r, strings.TrimSpace(r.URL.Query().Get("owner")))
+	if err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if req.Title == "" {
-		writeError(w, http.StatusBadRequest, "title is required")
+	if owner == "" {
+		writeError(w, http.StatusBadRequest, "owner is required")
 		return
 	}
-
-	ctx := r.Context()
-	now := time.Now().UTC()
-
-	// Build the issue.
-	issue := &model.Issue{
-		RepoID:      repo.ID,
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      model.StatusOpen,
-		IssueType:   model.IssueTypeTask,
-		Labels:      req.Labels,
-		CreatedAt:   now,
-		UpdatedAt:   now,
-	}
-	if req.Priority != nil {
-		issue.Priority = *req.Priority
-	}
-	if req.IssueType != "" {
-		issue.IssueType = model.IssueType(req.IssueType)
-	}
-	if issue.Labels == nil {
-		issue.Labels = []string{}
-	}
-
-	// Persist the issue first to get its ID.
-	created, err := d.store.CreateIssue(ctx, issue)
+	limit, err := positiveIntParam(r, "limit", defaultListLimit)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "create issue: "+err.Error())
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Build and append the create event.
-	payload := model.EventPayload{
-		Title:       req.Title,
-		Description: req.Description,
-		Priority:    req.Priority,
-		IssueType:   req.IssueType,
-		Labels:      req.Labels,
-		Comment:     req.Comment,
-	}
-	payloadJSON, err := json.Marshal(payload)
+	notifications, err := d.store.ListNotifications(r.Context(), repo.ID, owner, limit)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	writeJSON(w, http.StatusOK, notifications)
+}
 
-	event := &model.Event{
-		RepoID:    repo.ID,
-		IssueID:   created.ID,
-		Timestamp: now,
-		Action:    model.ActionCreate,
-		Payload:   string(payloadJSON),
-		Synced:    0,
-	}
+// ---------------------------------------------------------------------------
+// Epic parents and children
+// ---------------------------------------------------------------------------
 
-	if _, err := d.store.AppendEvent(ctx, event); err != nil {
-		writeError(w, http.StatusInternalServerError, "append event: "+err.Error())
+type setParentRequest struct {
+	ParentID int `json:"parent_id"`
+}
+
+// setIssueParent links the issue to an epic in the same repo with a
+// set_parent event. A link that would make the issue its own ancestor is
+// rejected.
+func (d *Daemon) setIssueParent(w http.ResponseWriter, r *http.Request) {
+	var req setParentRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	d.triggerSync(repo.ID)
-	writeJSON(w, http.StatusCreated, created)
+	if req.ParentID <= 0 {
+		writeError(w, http.StatusBadRequest, "parent_id is required")
+		return
+	}
+	d.changeIssueParent(w, r, model.ActionSetParent, req.ParentID)
 }
 
-type updateIssueRequest struct {
-	Title       string   `json:"title,omitempty"`
-	Description string   `json:"description,omitempty"`
-	Status      string   `json:"status,omitempty"`
-	Priority    *int     `json:"priority,omitempty"`
-	IssueType   string   `json:"issue_type,omitempty"`
-	Labels      []string `json:"labels,omitempty"`
-	Comment     string   `json:"comment,omitempty"`
+// clearIssueParent unlinks the issue from its epic with a clear_parent event.
+func (d *Daemon) clearIssueParent(w http.ResponseWriter, r *http.Request) {
+	d.changeIssueParent(w, r, model.ActionClearParent, 0)
 }
 
-func (d *Daemon) updateIssue(w http.ResponseWriter, r *http.Request) {
-	id, err := parseIssueID(r)
+// changeIssueParent records a set_parent or clear_parent event and returns
+// the updated issue. A change that would not alter the parent records
+// nothing.
+func (d *Daemon) changeIssueParent(w http.ResponseWriter, r *http.Request, action model.Action, parentID int) {
+	id, err := d.parseIssueID(r)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	var req updateIssueRequest
-	if err := readJSON(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
 	ctx := r.Context()
-	now := time.Now().UTC()
 
 	issue, err := d.store.GetIssue(ctx, id)
 	if err != nil {
@@ -614,153 +3256,139 @@ func (d *Daemon) updateIssue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If status is changing, use a status_change or close event.
-	statusChanged := false
-	if req.Status != "" && model.Status(req.Status) != issue.Status {
-		statusChanged = true
-		newStatus := model.Status(req.Status)
-
-		var action model.Action
-		if newStatus == model.StatusClosed {
-			action = model.ActionClose
-		} else {
-			action = model.ActionStatusChange
-		}
-
-		payload := model.EventPayload{
-			Status:     newStatus,
-			FromStatus: issue.Status,
-			Comment:    req.Comment,
-		}
-		payloadJSON, err := json.Marshal(payload)
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+	if action == model.ActionClearParent {
+		if issue.ParentID == nil {
+			writeJSON(w, http.StatusOK, issue)
 			return
 		}
-
-		event := &model.Event{
-			RepoID:    issue.RepoID,
-			IssueID:   issue.ID,
-			Timestamp: now,
-			Action:    action,
-			Payload:   string(payloadJSON),
-			Synced:    0,
-		}
-
-		savedEvent, err := d.store.AppendEvent(ctx, event)
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "append event: "+err.Error())
+		// Name the parent being cleared, so the event only undoes this link.
+		parentID = *issue.ParentID
+	} else {
+		if issue.ParentID != nil && *issue.ParentID == parentID {
+			writeJSON(w, http.StatusOK, issue)
 			return
 		}
-
-		issue, err = engine.Apply(issue, savedEvent)
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+		if status, err := d.validateParent(ctx, issue.RepoID, issue.ID, &parentID); err != nil {
+			writeError(w, status, err.Error())
 			return
 		}
 	}
 
-	// If there are non-status field changes, generate an update event.
-	hasFieldChange := req.Title != "" || req.Description != "" ||
-		req.Priority != nil || req.IssueType != "" || req.Labels != nil
-	if hasFieldChange {
-		// If the comment was already attached to a status_change event, don't duplicate it.
-		comment := req.Comment
-		if statusChanged {
-			comment = ""
-		}
-		payload := model.EventPayload{
-			Title:       req.Title,
-			Description: req.Description,
-			Priority:    req.Priority,
-			IssueType:   req.IssueType,
-			Labels:      req.Labels,
-			Comment:     comment,
-		}
-		payloadJSON, err := json.Marshal(payload)
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
-			return
-		}
-
-		event := &model.Event{
-			RepoID:    issue.RepoID,
-			IssueID:   issue.ID,
-			Timestamp: now,
-			Action:    model.ActionUpdate,
-			Payload:   string(payloadJSON),
-			Synced:    0,
-		}
-
-		savedEvent, err := d.store.AppendEvent(ctx, event)
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "append event: "+err.Error())
-			return
-		}
-
-		issue, err = engine.Apply(issue, savedEvent)
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
-			return
-		}
+	payloadJSON, err := json.Marshal(model.EventPayload{ParentID: &parentID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
+		return
+	}
+	event := &model.Event{
+		RepoID:    issue.RepoID,
+		IssueID:   issue.ID,
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Payload:   string(payloadJSON),
+		Synced:    0,
+	}
+	issue, err = engine.Apply(issue, event, d.priorityRange())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+		return
 	}
 
-	// If there's a comment but no other changes carried it, generate a standalone comment event.
-	if req.Comment != "" && !hasFieldChange && !statusChanged {
-		payload := model.EventPayload{
-			Comment: req.Comment,
-		}
-		payloadJSON, err := json.Marshal(payload)
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
-			return
-		}
+	if err := d.store.UpdateIssuesWithEvents(ctx, []store.IssueChange{{Issue: issue, Event: event}}); err != nil {
+		writeError(w, http.StatusInternalServerError, "update parent: "+err.Error())
+		return
+	}
 
-		event := &model.Event{
-			RepoID:    issue.RepoID,
-			IssueID:   issue.ID,
-			Timestamp: now,
-			Action:    model.ActionComment,
-			Payload:   string(payloadJSON),
-			Synced:    0,
-		}
+	issue, err = d.store.GetIssue(ctx, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-		savedEvent, err := d.store.AppendEvent(ctx, event)
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "append event: "+err.Error())
-			return
-		}
+	d.triggerSync(issue.RepoID)
+	d.publishIssue(action, issue)
+	writeJSON(w, http.StatusOK, issue)
+}
 
-		issue, err = engine.Apply(issue, savedEvent)
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
-			return
-		}
+// listChildren handles GET /issues/{id}/children, returning the issue's
+// direct children. Deleted children are left out unless ?all=true.
+func (d *Daemon) listChildren(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	if err := d.store.UpdateIssue(ctx, issue); err != nil {
-		writeError(w, http.StatusInternalServerError, "update issue: "+err.Error())
+	ctx := r.Context()
+	issue, err := d.store.GetIssue(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// Re-fetch to get the canonical stored state.
-	issue, err = d.store.GetIssue(ctx, id)
+	children, err := d.store.ListIssues(ctx, store.IssueFilter{RepoID: issue.RepoID, ParentID: issue.ID})
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	showAll := r.URL.Query().Get("all") == "true"
+	out := []*model.Issue{}
+	for _, child := range children {
+		if showAll || child.Status != model.StatusDeleted {
+			out = append(out, child)
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
 
-	d.triggerSync(issue.RepoID)
-	writeJSON(w, http.StatusOK, issue)
+// childCounts counts the issue's children by status, deleted ones excluded.
+// It returns nil when the issue has no children.
+func (d *Daemon) childCounts(ctx context.Context, issue *model.Issue) (map[model.Status]int, error) {
+	children, err := d.store.ListIssues(ctx, store.IssueFilter{RepoID: issue.RepoID, ParentID: issue.ID})
+	if err != nil {
+		return nil, err
+	}
+	var counts map[model.Status]int
+	for _, child := range children {
+		if child.Status == model.StatusDeleted {
+			continue
+		}
+		if counts == nil {
+			counts = make(map[model.Status]int)
+		}
+		counts[child.Status]++
+	}
+	return counts, nil
 }
 
-func (d *Daemon) deleteIssue(w http.ResponseWriter, r *http.Request) {
-	id, err := parseIssueID(r)
+// ---------------------------------------------------------------------------
+// Comment on issue
+// ---------------------------------------------------------------------------
+
+type commentIssueRequest struct {
+	Comment string `json:"comment"`
+}
+
+func (d *Daemon) commentIssue(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	var req commentIssueRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Comment == "" {
+		writeError(w, http.StatusBadRequest, "comment is required")
+		return
+	}
+
 	ctx := r.Context()
 	now := time.Now().UTC()
 
@@ -774,11 +3402,10 @@ func (d *Daemon) deleteIssue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Append a delete event.
-	deletePayload := model.EventPayload{
-		FromStatus: issue.Status,
+	payload := model.EventPayload{
+		Comment: req.Comment,
 	}
-	deletePayloadJSON, err := json.Marshal(deletePayload)
+	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
 		return
@@ -788,23 +3415,28 @@ func (d *Daemon) deleteIssue(w http.ResponseWriter, r *http.Request) {
 		RepoID:    issue.RepoID,
 		IssueID:   issue.ID,
 		Timestamp: now,
-		Action:    model.ActionDelete,
-		Payload:   string(deletePayloadJSON),
+		Action:    model.ActionComment,
+		Payload:   string(payloadJSON),
 		Synced:    0,
 	}
 
-	if _, err := d.store.AppendEvent(ctx, event); err != nil {
+	savedEvent, err := d.store.AppendEvent(ctx, event)
+	if err != nil {
 		writeError(w, http.StatusInternalServerError, "append event: "+err.Error())
 		return
 	}
 
-	// Soft-delete in store.
-	if err := d.store.DeleteIssue(ctx, id); err != nil {
-		writeError(w, http.StatusInternalServerError, "delete issue: "+err.Error())
+	issue, err = engine.Apply(issue, savedEvent, d.priorityRange())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
+		return
+	}
+
+	if err := d.store.UpdateIssue(ctx, issue); err != nil {
+		writeError(w, http.StatusInternalServerError, "update issue: "+err.Error())
 		return
 	}
 
-	// Re-fetch to return current state.
 	issue, err = d.store.GetIssue(ctx, id)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
@@ -812,28 +3444,47 @@ func (d *Daemon) deleteIssue(w http.ResponseWriter, r *http.Request) {
 	}
 
 	d.triggerSync(issue.RepoID)
-	writeJSON(w, http.StatusOK, issue)
+	d.publishIssue(model.ActionComment, issue)
+	writeJSON(w, http.StatusCreated, issue)
 }
 
-type assignIssueRequest struct {
-	Owner string `json:"owner"`
+// editComment handles PATCH /issues/{id}/comments/{index}, replacing the
+// text of the comment at that index with a comment_edit event.
+func (d *Daemon) editComment(w http.ResponseWriter, r *http.Request) {
+	var req commentIssueRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Comment == "" {
+		writeError(w, http.StatusBadRequest, "comment is required")
+		return
+	}
+	d.changeComment(w, r, model.ActionCommentEdit, req.Comment)
 }
 
-func (d *Daemon) assignIssue(w http.ResponseWriter, r *http.Request) {
-	id, err := parseIssueID(r)
+// deleteComment handles DELETE /issues/{id}/comments/{index}, removing the
+// comment at that index with a comment_delete event.
+func (d *Daemon) deleteComment(w http.ResponseWriter, r *http.Request) {
+	d.changeComment(w, r, model.ActionCommentDelete, "")
+}
+
+// changeComment records a comment_edit or comment_delete event for the
+// comment at the path's index and returns the updated issue. Indexes count
+// from 0 in the issue's comments list.
+func (d *Daemon) changeComment(w http.ResponseWriter, r *http.Request, action model.Action, text string) {
+	id, err := d.parseIssueID(r)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	var req assignIssueRequest
-	if err := readJSON(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || index < 0 {
+		writeError(w, http.StatusBadRequest, "invalid comment index")
 		return
 	}
 
 	ctx := r.Context()
-	now := time.Now().UTC()
 
 	issue, err := d.store.GetIssue(ctx, id)
 	if err != nil {
@@ -844,44 +3495,35 @@ func (d *Daemon) assignIssue(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-
-	// Append an assign event.
-	payload := model.EventPayload{
-		Owner: req.Owner,
+	if index >= len(issue.Comments) {
+		writeError(w, http.StatusNotFound, "comment not found")
+		return
 	}
-	payloadJSON, err := json.Marshal(payload)
+
+	payloadJSON, err := json.Marshal(model.EventPayload{CommentIndex: &index, CommentText: text})
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "marshal payload: "+err.Error())
 		return
 	}
-
 	event := &model.Event{
 		RepoID:    issue.RepoID,
 		IssueID:   issue.ID,
-		Timestamp: now,
-		Action:    model.ActionAssign,
+		Timestamp: time.Now().UTC(),
+		Action:    action,
 		Payload:   string(payloadJSON),
 		Synced:    0,
 	}
-
-	savedEvent, err := d.store.AppendEvent(ctx, event)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "append event: "+err.Error())
-		return
-	}
-
-	issue, err = engine.Apply(issue, savedEvent)
+	issue, err = engine.Apply(issue, event, d.priorityRange())
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
 		return
 	}
 
-	if err := d.store.UpdateIssue(ctx, issue); err != nil {
-		writeError(w, http.StatusInternalServerError, "update issue: "+err.Error())
+	if err := d.store.UpdateIssuesWithEvents(ctx, []store.IssueChange{{Issue: issue, Event: event}}); err != nil {
+		writeError(w, http.StatusInternalServerError, "update comment: "+err.Error())
 		return
 	}
 
-	// Re-fetch.
 	issue, err = d.store.GetIssue(ctx, id)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
@@ -889,37 +3531,42 @@ func (d *Daemon) assignIssue(w http.ResponseWriter, r *http.Request) {
 	}
 
 	d.triggerSync(issue.RepoID)
+	d.publishIssue(action, issue)
 	writeJSON(w, http.StatusOK, issue)
 }
 
 // ---------------------------------------------------------------------------
-// Comment on issue
+// Snooze issue
 // ---------------------------------------------------------------------------
 
-type commentIssueRequest struct {
-	Comment string `json:"comment"`
+type snoozeIssueRequest struct {
+	Until   *time.Time `json:"until"`
+	Comment string     `json:"comment"`
 }
 
-func (d *Daemon) commentIssue(w http.ResponseWriter, r *http.Request) {
-	id, err := parseIssueID(r)
+// snoozeIssue hides an issue from next/list until the given time.
+// A null or missing "until" clears an existing snooze.
+func (d *Daemon) snoozeIssue(w http.ResponseWriter, r *http.Request) {
+	id, err := d.parseIssueID(r)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	var req commentIssueRequest
+	var req snoozeIssueRequest
 	if err := readJSON(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if req.Comment == "" {
-		writeError(w, http.StatusBadRequest, "comment is required")
-		return
-	}
 
 	ctx := r.Context()
 	now := time.Now().UTC()
 
+	if req.Until != nil && !req.Until.After(now) {
+		writeError(w, http.StatusBadRequest, "until must be in the future")
+		return
+	}
+
 	issue, err := d.store.GetIssue(ctx, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -931,7 +3578,12 @@ func (d *Daemon) commentIssue(w http.ResponseWriter, r *http.Request) {
 	}
 
 	payload := model.EventPayload{
-		Comment: req.Comment,
+		SnoozedUntil: req.Until,
+		Comment:      req.Comment,
+	}
+	if payload.SnoozedUntil != nil {
+		until := payload.SnoozedUntil.UTC()
+		payload.SnoozedUntil = &until
 	}
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
@@ -943,7 +3595,7 @@ func (d *Daemon) commentIssue(w http.ResponseWriter, r *http.Request) {
 		RepoID:    issue.RepoID,
 		IssueID:   issue.ID,
 		Timestamp: now,
-		Action:    model.ActionComment,
+		Action:    model.ActionSnooze,
 		Payload:   string(payloadJSON),
 		Synced:    0,
 	}
@@ -954,7 +3606,7 @@ func (d *Daemon) commentIssue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	issue, err = engine.Apply(issue, savedEvent)
+	issue, err = engine.Apply(issue, savedEvent, d.priorityRange())
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "apply event: "+err.Error())
 		return
@@ -972,18 +3624,71 @@ func (d *Daemon) commentIssue(w http.ResponseWriter, r *http.Request) {
 	}
 
 	d.triggerSync(issue.RepoID)
-	writeJSON(w, http.StatusCreated, issue)
+	d.publishIssue(model.ActionSnooze, issue)
+	writeJSON(w, http.StatusOK, issue)
 }
 
 // ---------------------------------------------------------------------------
 // Repo config update
 // ---------------------------------------------------------------------------
 
+// maxLabelLength is GitHub's limit on label names. Commas are rejected too,
+// since the issues API takes the label filter as a comma-separated list.
+const maxLabelLength = 50
+
 type updateRepoRequest struct {
 	TrustedAuthorsOnly *bool   `json:"trusted_authors_only"`
 	LocalPath          *string `json:"local_path"`
 	SocketEnabled      *bool   `json:"socket_enabled"`
 	QueueEnabled       *bool   `json:"queue_enabled"`
+
+	// TrustedAuthors replaces the repo's trusted GitHub logins; an empty
+	// list leaves only the repo owner and author associations.
+	TrustedAuthors *[]string `json:"trusted_authors"`
+
+	// AllowedInboundActions replaces the repo's inbound action whitelist;
+	// an empty list allows every action.
+	AllowedInboundActions *[]string `json:"allowed_inbound_actions"`
+
+	// IssueTypes replaces the repo's issue types; an empty list restores
+	// the defaults.
+	IssueTypes *[]string `json:"issue_types"`
+
+	EpicRollup *bool `json:"epic_rollup"`
+
+	// NextStrategy sets how next/plan order candidates; "" restores the
+	// default priority order.
+	NextStrategy *string `json:"next_strategy"`
+
+	// SyncDirection limits syncing to "pull" or "push"; "both" or ""
+	// restores two-way sync.
+	SyncDirection *string `json:"sync_direction"`
+
+	IngestHumanComments *bool `json:"ingest_human_comments"`
+
+	// Label sets the GitHub label that marks tracked issues; "" restores
+	// the default.
+	Label *string `json:"label"`
+
+	// AssigneeLogins replaces the repo's map of local owners to GitHub
+	// logins; an empty map stops assigning issues on GitHub.
+	AssigneeLogins *map[string]string `json:"assignee_logins"`
+
+	// WIPLimit caps each owner's open and in_progress issues; 0 removes
+	// the limit.
+	WIPLimit *int `json:"wip_limit"`
+
+	// ValidateAssignee turns checking assignees against the GitHub repo's
+	// collaborators on or off.
+	ValidateAssignee *bool `json:"validate_assignee"`
+
+	// IssueTemplates replaces the repo's map of issue types to description
+	// templates; an empty map removes them all.
+	IssueTemplates *map[string]string `json:"issue_templates"`
+
+	// KeyPrefix sets the prefix issues can be referred to by, as in
+	// BOR-42; "" removes it.
+	KeyPrefix *string `json:"key_prefix"`
 }
 
 func (d *Daemon) updateRepo(w http.ResponseWriter, r *http.Request) {
@@ -999,9 +3704,152 @@ func (d *Daemon) updateRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Handle trusted_authors_only via the repos table.
-	if req.TrustedAuthorsOnly != nil {
-		repo.TrustedAuthorsOnly = *req.TrustedAuthorsOnly
+	if req.AllowedInboundActions != nil {
+		for _, a := range *req.AllowedInboundActions {
+			if !model.IsValidAction(model.Action(a)) {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown action %q in allowed_inbound_actions", a))
+				return
+			}
+		}
+	}
+
+	if req.IssueTypes != nil {
+		for _, t := range *req.IssueTypes {
+			if !model.IssueType(t).Valid() {
+				writeError(w, http.StatusBadRequest, "issue_types must not contain empty names")
+				return
+			}
+		}
+	}
+
+	if req.NextStrategy != nil && !model.IsValidNextStrategy(model.NextStrategy(*req.NextStrategy)) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown next_strategy %q: use priority, fifo or weighted", *req.NextStrategy))
+		return
+	}
+
+	if req.SyncDirection != nil && !model.IsValidSyncDirection(model.SyncDirection(*req.SyncDirection)) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown sync_direction %q: use both, pull or push", *req.SyncDirection))
+		return
+	}
+
+	if req.TrustedAuthors != nil {
+		logins := make([]string, 0, len(*req.TrustedAuthors))
+		for _, login := range *req.TrustedAuthors {
+			login = strings.TrimPrefix(strings.TrimSpace(login), "@")
+			if login == "" {
+				writeError(w, http.StatusBadRequest, "trusted_authors must not contain empty logins")
+				return
+			}
+			logins = append(logins, login)
+		}
+		*req.TrustedAuthors = logins
+	}
+
+	if req.AssigneeLogins != nil {
+		logins := make(map[string]string, len(*req.AssigneeLogins))
+		for owner, login := range *req.AssigneeLogins {
+			owner = strings.TrimSpace(owner)
+			login = strings.TrimPrefix(strings.TrimSpace(login), "@")
+			if owner == "" || login == "" {
+				writeError(w, http.StatusBadRequest, "assignee_logins must not contain empty owners or logins")
+				return
+			}
+			logins[owner] = login
+		}
+		*req.AssigneeLogins = logins
+	}
+
+	if req.IssueTemplates != nil {
+		typed := *repo
+		if req.IssueTypes != nil {
+			typed.IssueTypes = *req.IssueTypes
+		}
+		templates := make(map[string]string, len(*req.IssueTemplates))
+		for issueType, tmpl := range *req.IssueTemplates {
+			if err := validateIssueType(&typed, issueType); err != nil || issueType == "" {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("issue_templates: unknown issue_type %q", issueType))
+				return
+			}
+			if strings.TrimSpace(tmpl) != "" {
+				templates[issueType] = tmpl
+			}
+		}
+		*req.IssueTemplates = templates
+	}
+
+	if req.KeyPrefix != nil {
+		prefix := strings.TrimSpace(*req.KeyPrefix)
+		if !model.IsValidKeyPrefix(prefix) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid key_prefix %q: use up to 10 letters and digits, starting with a letter", prefix))
+			return
+		}
+		*req.KeyPrefix = prefix
+	}
+
+	if req.WIPLimit != nil && *req.WIPLimit < 0 {
+		writeError(w, http.StatusBadRequest, "wip_limit must not be negative")
+		return
+	}
+
+	if req.Label != nil {
+		label := strings.TrimSpace(*req.Label)
+		if len(label) > maxLabelLength || strings.Contains(label, ",") {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid label %q: must be at most %d characters with no commas", label, maxLabelLength))
+			return
+		}
+		*req.Label = label
+	}
+
+	// Handle trusted_authors_only, trusted_authors, allowed_inbound_actions,
+	// issue_types, epic_rollup, next_strategy, sync_direction,
+	// ingest_human_comments, label, assignee_logins, wip_limit,
+	// validate_assignee, issue_templates and key_prefix via the repos table.
+	if req.TrustedAuthorsOnly != nil || req.TrustedAuthors != nil || req.AllowedInboundActions != nil || req.IssueTypes != nil || req.EpicRollup != nil ||
+		req.NextStrategy != nil || req.SyncDirection != nil || req.IngestHumanComments != nil || req.Label != nil || req.AssigneeLogins != nil ||
+		req.WIPLimit != nil || req.ValidateAssignee != nil || req.IssueTemplates != nil || req.KeyPrefix != nil {
+		if req.TrustedAuthorsOnly != nil {
+			repo.TrustedAuthorsOnly = *req.TrustedAuthorsOnly
+		}
+		if req.TrustedAuthors != nil {
+			repo.TrustedAuthors = *req.TrustedAuthors
+		}
+		if req.AllowedInboundActions != nil {
+			repo.AllowedInboundActions = *req.AllowedInboundActions
+		}
+		if req.IssueTypes != nil {
+			repo.IssueTypes = *req.IssueTypes
+		}
+		if req.EpicRollup != nil {
+			repo.EpicRollup = *req.EpicRollup
+		}
+		if req.NextStrategy != nil {
+			repo.NextStrategy = model.NextStrategy(*req.NextStrategy)
+		}
+		if req.SyncDirection != nil {
+			repo.SyncDirection = model.SyncDirection(*req.SyncDirection)
+		}
+		if req.IngestHumanComments != nil {
+			repo.IngestHumanComments = *req.IngestHumanComments
+		}
+		if req.Label != nil {
+			repo.Label = *req.Label
+		}
+		if req.AssigneeLogins != nil {
+			repo.AssigneeLogins = *req.AssigneeLogins
+		}
+		if req.WIPLimit != nil {
+			repo.WIPLimit = *req.WIPLimit
+		}
+		if req.ValidateAssignee != nil {
+			repo.ValidateAssignee = *req.ValidateAssignee
+			d.collaboratorCache.forget(repo.ID)
+		}
+		if req.IssueTemplates != nil {
+			repo.IssueTemplates = *req.IssueTemplates
+		}
+		if req.KeyPrefix != nil {
+			repo.KeyPrefix = *req.KeyPrefix
+		}
 		if err := d.store.UpdateRepo(r.Context(), repo); err != nil {
 			writeError(w, http.StatusInternalServerError, "update repo: "+err.Error())
 			return
@@ -1040,24 +3888,8 @@ func (d *Daemon) updateRepo(w http.ResponseWriter, r *http.Request) {
 				writeError(w, http.StatusInternalServerError, "add local path: "+err.Error())
 				return
 			}
-			// Toggle socket on/off.
-			sockPath := filepath.Join(targetPath, ".boxofrocks", "bor.sock")
-			if lp.SocketEnabled {
-				if err := d.createSocketAtPath(repo.ID, sockPath); err != nil {
-					slog.Warn("could not create socket for repo", "repo", repo.FullName(), "error", err)
-				}
-			} else {
-				d.removeSocket(sockPath)
-			}
-			// Toggle queue on/off.
-			queueDir := filepath.Join(targetPath, ".boxofrocks", "queue")
-			if lp.QueueEnabled {
-				if err := d.startFileQueueAtPath(repo.ID, queueDir); err != nil {
-					slog.Warn("could not start file queue", "repo", repo.FullName(), "error", err)
-				}
-			} else {
-				d.stopFileQueue(queueDir)
-			}
+			d.invalidatePathIndex()
+			d.applyLocalPathFlags(repo, lp)
 		}
 	}
 
@@ -1103,17 +3935,81 @@ func (d *Daemon) addRepoPath(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "add local path: "+err.Error())
 		return
 	}
+	d.invalidatePathIndex()
 
 	if sp := lp.SocketPath(); sp != "" {
 		if err := d.createSocketAtPath(repo.ID, sp); err != nil {
-			slog.Warn("could not create socket", "path", sp, "error", err)
+			slog.WarnContext(r.Context(), "could not create socket", "path", sp, "error", err)
 		}
 	}
 	if qd := lp.QueueDir(); qd != "" {
 		if err := d.startFileQueueAtPath(repo.ID, qd); err != nil {
-			slog.Warn("could not start file queue", "dir", qd, "error", err)
+			slog.WarnContext(r.Context(), "could not start file queue", "dir", qd, "error", err)
+		}
+	}
+
+	// Re-fetch repo to return updated state.
+	repo, err = d.store.GetRepo(r.Context(), repo.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, repo)
+}
+
+type updateRepoPathRequest struct {
+	LocalPath     string `json:"local_path"`
+	SocketEnabled *bool  `json:"socket_enabled"`
+	QueueEnabled  *bool  `json:"queue_enabled"`
+}
+
+// updateRepoPath changes the socket/queue flags of one registered local path,
+// leaving the repo's other worktrees untouched. Omitted flags keep their
+// current value.
+func (d *Daemon) updateRepoPath(w http.ResponseWriter, r *http.Request) {
+	repo, err := d.resolveRepo(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req updateRepoPathRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.LocalPath == "" {
+		writeError(w, http.StatusBadRequest, "local_path is required")
+		return
+	}
+
+	var current *model.LocalPathConfig
+	for i := range repo.LocalPaths {
+		if repo.LocalPaths[i].LocalPath == req.LocalPath {
+			current = &repo.LocalPaths[i]
+			break
 		}
 	}
+	if current == nil {
+		writeError(w, http.StatusNotFound, "local path not registered for repo: "+req.LocalPath)
+		return
+	}
+
+	socket, queue := current.SocketEnabled, current.QueueEnabled
+	if req.SocketEnabled != nil {
+		socket = *req.SocketEnabled
+	}
+	if req.QueueEnabled != nil {
+		queue = *req.QueueEnabled
+	}
+
+	lp, err := d.store.AddLocalPath(r.Context(), repo.ID, req.LocalPath, socket, queue)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "update local path: "+err.Error())
+		return
+	}
+	d.applyLocalPathFlags(repo, lp)
 
 	// Re-fetch repo to return updated state.
 	repo, err = d.store.GetRepo(r.Context(), repo.ID)
@@ -1125,6 +4021,28 @@ func (d *Daemon) addRepoPath(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, repo)
 }
 
+// applyLocalPathFlags starts or stops the socket listener and file queue for
+// a local path so they match its stored flags.
+func (d *Daemon) applyLocalPathFlags(repo *model.RepoConfig, lp *model.LocalPathConfig) {
+	sockPath := filepath.Join(lp.LocalPath, ".boxofrocks", "bor.sock")
+	if lp.SocketEnabled {
+		if err := d.createSocketAtPath(repo.ID, sockPath); err != nil {
+			slog.Warn("could not create socket for repo", "repo", repo.FullName(), "error", err)
+		}
+	} else {
+		d.removeSocket(sockPath)
+	}
+
+	queueDir := filepath.Join(lp.LocalPath, ".boxofrocks", "queue")
+	if lp.QueueEnabled {
+		if err := d.startFileQueueAtPath(repo.ID, queueDir); err != nil {
+			slog.Warn("could not start file queue", "repo", repo.FullName(), "error", err)
+		}
+	} else {
+		d.stopFileQueue(queueDir)
+	}
+}
+
 func (d *Daemon) removeRepoPath(w http.ResponseWriter, r *http.Request) {
 	repo, err := d.resolveRepo(r)
 	if err != nil {
@@ -1154,6 +4072,7 @@ func (d *Daemon) removeRepoPath(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "remove local path: "+err.Error())
 		return
 	}
+	d.invalidatePathIndex()
 
 	// Re-fetch repo to return updated state.
 	repo, err = d.store.GetRepo(r.Context(), repo.ID)
@@ -1,17 +1,30 @@
 package daemon
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/jmaddaus/boxofrocks/internal/config"
+	"github.com/jmaddaus/boxofrocks/internal/engine"
 	"github.com/jmaddaus/boxofrocks/internal/github"
 	"github.com/jmaddaus/boxofrocks/internal/model"
 	"github.com/jmaddaus/boxofrocks/internal/store"
@@ -19,7 +32,7 @@ import (
 )
 
 // testDaemon creates a Daemon backed by an in-memory SQLite store for testing.
-func testDaemon(t *testing.T) *Daemon {
+func testDaemon(t testing.TB) *Daemon {
 	t.Helper()
 	s, err := store.NewSQLiteStore(":memory:")
 	if err != nil {
@@ -99,6 +112,224 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestVersionEndpoint(t *testing.T) {
+	d := testDaemon(t)
+
+	rr := doRequest(t, d, "GET", "/version", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get(APIVersionHeader); got != itoa(APIVersion) {
+		t.Errorf("%s header: want %d, got %q", APIVersionHeader, APIVersion, got)
+	}
+
+	var resp map[string]interface{}
+	decodeJSON(t, rr, &resp)
+	if resp["version"] != "dev" {
+		t.Errorf("version: want dev, got %v", resp["version"])
+	}
+	if resp["api_version"] != float64(APIVersion) {
+		t.Errorf("api_version: want %d, got %v", APIVersion, resp["api_version"])
+	}
+	if resp["db_schema_version"] != float64(store.DBSchemaVersion) {
+		t.Errorf("db_schema_version: want %d, got %v", store.DBSchemaVersion, resp["db_schema_version"])
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(LogHandler{slog.NewTextHandler(&logs, nil)}))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	d := testDaemon(t)
+
+	rr := doRequest(t, d, "GET", "/health", nil)
+	id := rr.Header().Get(RequestIDHeader)
+	if len(id) != 16 {
+		t.Fatalf("%s header: want a generated 16-character ID, got %q", RequestIDHeader, id)
+	}
+	if line := logs.String(); !strings.Contains(line, "msg=\"http request\"") ||
+		!strings.Contains(line, "request_id="+id) || !strings.Contains(line, "path=/health") || !strings.Contains(line, "status=200") {
+		t.Errorf("request log line missing fields: %s", line)
+	}
+
+	// A caller's ID is kept; an unusable one is replaced.
+	rr = doRequestWithHeader(t, d, "GET", "/health", RequestIDHeader, "agent-7-call-3", nil)
+	if got := rr.Header().Get(RequestIDHeader); got != "agent-7-call-3" {
+		t.Errorf("%s header: want the caller's ID echoed, got %q", RequestIDHeader, got)
+	}
+	rr = doRequestWithHeader(t, d, "GET", "/health", RequestIDHeader, "bad id\n", nil)
+	if got := rr.Header().Get(RequestIDHeader); got == "bad id\n" || len(got) != 16 {
+		t.Errorf("%s header: want a generated ID in place of an unusable one, got %q", RequestIDHeader, got)
+	}
+}
+
+// authDaemon creates a test daemon that requires the given auth token.
+func authDaemon(t *testing.T, token string) *Daemon {
+	t.Helper()
+	s, err := store.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("create in-memory store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return NewWithStore(&config.Config{
+		ListenAddr: ":0",
+		DataDir:    t.TempDir(),
+		DBPath:     ":memory:",
+		AuthToken:  token,
+	}, s)
+}
+
+func TestAuthToken(t *testing.T) {
+	d := authDaemon(t, "s3cret")
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"missing token", "/repos", "", http.StatusUnauthorized},
+		{"wrong token", "/repos", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "/repos", "Basic s3cret", http.StatusUnauthorized},
+		{"correct token", "/repos", "Bearer s3cret", http.StatusOK},
+		{"health is open", "/health", "", http.StatusOK},
+		{"ready is open", "/ready", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rr := httptest.NewRecorder()
+		d.Handler().ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.want, rr.Code, rr.Body.String())
+		}
+		if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s: expected WWW-Authenticate: Bearer", tt.name)
+		}
+	}
+
+	// Without a configured token nothing is required.
+	if rr := doRequest(t, testDaemon(t), "GET", "/repos", nil); rr.Code != http.StatusOK {
+		t.Errorf("no auth_token: expected 200, got %d", rr.Code)
+	}
+}
+
+func TestAuthTokenSocketBypass(t *testing.T) {
+	d := authDaemon(t, "s3cret")
+	repo, err := d.store.AddRepo(context.Background(), "o", "r")
+	if err != nil {
+		t.Fatalf("add repo: %v", err)
+	}
+
+	sockPath := filepath.Join(t.TempDir(), "bor.sock")
+	if err := d.createSocketAtPath(repo.ID, sockPath); err != nil {
+		t.Fatalf("create socket: %v", err)
+	}
+	t.Cleanup(func() { d.removeRepoSockets(repo.ID) })
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sockPath)
+		},
+	}}
+	resp, err := client.Get("http://unix/issues")
+	if err != nil {
+		t.Fatalf("GET over socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("socket request without token: expected 200, got %d", resp.StatusCode)
+	}
+
+	// The file queue is trusted the same way.
+	queueDir := filepath.Join(t.TempDir(), "queue")
+	reqPath := writeReqFile(t, queueDir, "q1", fileQueueRequest{Method: "GET", Path: "/issues"})
+	d.processQueueFile(reqPath, repo.ID)
+	if qr := readRespFile(t, queueDir, "q1"); qr.Status != http.StatusOK {
+		t.Errorf("queue request without token: expected 200, got %d", qr.Status)
+	}
+}
+
+func TestSocketModeAndGroup(t *testing.T) {
+	s, err := store.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("create in-memory store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	d := NewWithStore(&config.Config{
+		ListenAddr:  ":0",
+		DataDir:     t.TempDir(),
+		DBPath:      ":memory:",
+		SocketMode:  "0660",
+		SocketGroup: strconv.Itoa(os.Getgid()),
+	}, s)
+
+	sockPath := filepath.Join(t.TempDir(), ".boxofrocks", "bor.sock")
+	if err := d.createSocketAtPath(1, sockPath); err != nil {
+		t.Fatalf("create socket: %v", err)
+	}
+	t.Cleanup(func() { d.removeSocket(sockPath) })
+
+	info, err := os.Stat(sockPath)
+	if err != nil {
+		t.Fatalf("stat socket: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0660 {
+		t.Errorf("expected socket mode 0660, got %o", perm)
+	}
+	dir, err := os.Stat(filepath.Dir(sockPath))
+	if err != nil {
+		t.Fatalf("stat socket dir: %v", err)
+	}
+	if dir.Mode().Perm()&0010 == 0 {
+		t.Errorf("expected the socket dir to be group-searchable, got %o", dir.Mode().Perm())
+	}
+
+	// An unknown group only logs a warning.
+	d.cfg.SocketGroup = "no-such-group-for-bor-tests"
+	otherPath := filepath.Join(filepath.Dir(sockPath), "other.sock")
+	if err := d.createSocketAtPath(1, otherPath); err != nil {
+		t.Fatalf("create socket with unknown group: %v", err)
+	}
+	t.Cleanup(func() { d.removeSocket(otherPath) })
+}
+
+func TestSocketModeWidensExistingDir(t *testing.T) {
+	s, err := store.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("create in-memory store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	d := NewWithStore(&config.Config{
+		ListenAddr: ":0",
+		DataDir:    t.TempDir(),
+		DBPath:     ":memory:",
+		SocketMode: "0660",
+	}, s)
+
+	// An earlier daemon left the directory owner-only.
+	sockDir := filepath.Join(t.TempDir(), ".boxofrocks")
+	if err := os.Mkdir(sockDir, 0700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	sockPath := filepath.Join(sockDir, "bor.sock")
+	if err := d.createSocketAtPath(1, sockPath); err != nil {
+		t.Fatalf("create socket: %v", err)
+	}
+	t.Cleanup(func() { d.removeSocket(sockPath) })
+
+	dir, err := os.Stat(sockDir)
+	if err != nil {
+		t.Fatalf("stat socket dir: %v", err)
+	}
+	if perm := dir.Mode().Perm(); perm != 0710 {
+		t.Errorf("expected the existing socket dir widened to 0710, got %o", perm)
+	}
+}
+
 func TestCreateAndListRepos(t *testing.T) {
 	d := testDaemon(t)
 
@@ -215,6 +446,79 @@ func TestUpdateIssueTitleChange(t *testing.T) {
 	}
 }
 
+func TestUpdateIssueVersion(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Contested"})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	path := "/issues/" + itoa(iss.ID)
+
+	rr = doRequest(t, d, "GET", path, nil)
+	var read model.Issue
+	decodeJSON(t, rr, &read)
+	if read.Version == 0 {
+		t.Fatalf("expected a version on read, got %s", rr.Body.String())
+	}
+
+	// The first agent's update matches the version it read.
+	rr = doRequest(t, d, "PATCH", path, map[string]interface{}{"title": "First", "version": read.Version})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("matching version: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var first model.Issue
+	decodeJSON(t, rr, &first)
+	if first.Version <= read.Version {
+		t.Errorf("expected the version to move past %d, got %d", read.Version, first.Version)
+	}
+
+	// The second agent read the same version and loses.
+	rr = doRequest(t, d, "PATCH", path, map[string]interface{}{"title": "Second", "version": read.Version})
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("stale version: expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = doRequest(t, d, "GET", path, nil)
+	var after model.Issue
+	decodeJSON(t, rr, &after)
+	if after.Title != "First" || after.Version != first.Version {
+		t.Errorf("expected the refused update to change nothing, got %q at version %d", after.Title, after.Version)
+	}
+
+	// The refused attempt is in the log for audit, but replay skips it and
+	// it is never pushed.
+	events, err := d.store.ListEvents(context.Background(), iss.RepoID, iss.ID)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	last := events[len(events)-1]
+	var payload model.EventPayload
+	json.Unmarshal([]byte(last.Payload), &payload)
+	if last.Action != model.ActionUpdateRejected || payload.RejectedAction != model.ActionUpdate ||
+		payload.Version != read.Version || !strings.Contains(string(payload.Rejected), "Second") {
+		t.Errorf("expected the refused update recorded, got %s %s", last.Action, last.Payload)
+	}
+	if last.Synced != 1 {
+		t.Errorf("expected the refused update never to be pushed, got synced %d", last.Synced)
+	}
+	replayed, err := engine.Replay(events)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if got := replayed[iss.ID].Title; got != "First" {
+		t.Errorf("expected replay to skip the refused update, got title %q", got)
+	}
+
+	// A stale version is refused even when the request changes nothing.
+	if rr := doRequest(t, d, "PATCH", path, map[string]interface{}{"version": read.Version}); rr.Code != http.StatusConflict {
+		t.Errorf("stale version without changes: expected 409, got %d", rr.Code)
+	}
+	// Without a version the last write wins, as before.
+	if rr := doRequest(t, d, "PATCH", path, map[string]interface{}{"title": "Third"}); rr.Code != http.StatusOK {
+		t.Errorf("no version: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
 func TestUpdateIssueStatusChange(t *testing.T) {
 	d := testDaemon(t)
 
@@ -294,350 +598,3065 @@ func TestDeleteIssueSoftDelete(t *testing.T) {
 	}
 }
 
-func TestAssignIssue(t *testing.T) {
+func TestEventsSinceCursor(t *testing.T) {
 	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	for i := 0; i < 3; i++ {
+		doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Issue " + itoa(i)})
+	}
+
+	type eventsPage struct {
+		Repo   string         `json:"repo"`
+		Events []*model.Event `json:"events"`
+		Cursor int            `json:"cursor"`
+		More   bool           `json:"more"`
+	}
+	get := func(path string) eventsPage {
+		t.Helper()
+		rr := doRequest(t, d, "GET", path, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", path, rr.Code, rr.Body.String())
+		}
+		var page eventsPage
+		decodeJSON(t, rr, &page)
+		return page
+	}
+
+	first := get("/events?limit=2")
+	if len(first.Events) != 2 || !first.More || first.Cursor != first.Events[1].ID {
+		t.Fatalf("first page: %d events, more=%v, cursor=%d", len(first.Events), first.More, first.Cursor)
+	}
+	second := get("/events?limit=2&since=" + itoa(first.Cursor))
+	if len(second.Events) != 1 || second.More || second.Events[0].ID <= first.Cursor {
+		t.Fatalf("second page: %d events, more=%v", len(second.Events), second.More)
+	}
+	if second.Events[0].IssueID == first.Events[0].IssueID {
+		t.Errorf("events should span the repo's issues, got issue %d twice", second.Events[0].IssueID)
+	}
+
+	// Nothing new keeps the cursor where it was.
+	idle := get("/events?since=" + itoa(second.Cursor))
+	if len(idle.Events) != 0 || idle.Cursor != second.Cursor || idle.More {
+		t.Errorf("idle page: %d events, cursor=%d (want %d), more=%v", len(idle.Events), idle.Cursor, second.Cursor, idle.More)
+	}
+
+	if rr := doRequest(t, d, "GET", "/events?since=-1", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("negative since: expected 400, got %d", rr.Code)
+	}
+}
 
+func TestListIssuesCountOnly(t *testing.T) {
+	d := testDaemon(t)
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title": "Assign Me",
-	})
-	var iss model.Issue
-	decodeJSON(t, rr, &iss)
+	for i := 0; i < 3; i++ {
+		doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Issue " + itoa(i), "labels": []string{"ui"}})
+	}
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Unlabelled"})
 
-	// Assign.
-	rr = doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/assign", map[string]string{
-		"owner": "alice",
-	})
+	rr := doRequest(t, d, "HEAD", "/issues?label=ui&limit=1", nil)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("assign: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("HEAD /issues: expected 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("X-Total-Count"); got != "3" {
+		t.Errorf("HEAD X-Total-Count = %q, want 3", got)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("HEAD should have no body, got %q", rr.Body.String())
 	}
 
-	var assigned model.Issue
-	decodeJSON(t, rr, &assigned)
-	if assigned.Owner != "alice" {
-		t.Errorf("expected owner 'alice', got %q", assigned.Owner)
+	rr = doRequest(t, d, "GET", "/issues?count_only=true", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("count_only: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body struct {
+		Count int `json:"count"`
+	}
+	decodeJSON(t, rr, &body)
+	if body.Count != 4 || rr.Header().Get("X-Total-Count") != "4" {
+		t.Errorf("count_only = %d (header %s), want 4", body.Count, rr.Header().Get("X-Total-Count"))
 	}
 }
 
-func TestNextIssueReturnsHighestPriority(t *testing.T) {
+func TestListIssuesPagination(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	for i := 0; i < 5; i++ {
+		doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Issue " + itoa(i), "priority": i})
+	}
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Closed"})
+	var closed model.Issue
+	decodeJSON(t, rr, &closed)
+	doRequest(t, d, "PATCH", "/issues/"+itoa(closed.ID), map[string]string{"status": "closed"})
 
-	// Create two issues with different priorities.
-	doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title":    "Low priority",
-		"priority": 10,
-	})
-	doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title":    "High priority",
-		"priority": 1,
-	})
-
-	rr := doRequest(t, d, "GET", "/issues/next", nil)
+	rr = doRequest(t, d, "GET", "/issues?limit=2&offset=2", nil)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("next: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("X-Total-Count"); got != "5" {
+		t.Errorf("X-Total-Count = %q, want 5 (closed issue excluded)", got)
+	}
+	var page []*model.Issue
+	decodeJSON(t, rr, &page)
+	if len(page) != 2 || page[0].Title != "Issue 2" || page[1].Title != "Issue 3" {
+		t.Errorf("page = %v, want Issue 2 and Issue 3", page)
 	}
 
-	var next model.Issue
-	decodeJSON(t, rr, &next)
-	if next.Title != "High priority" {
-		t.Errorf("expected 'High priority', got %q", next.Title)
+	rr = doRequest(t, d, "GET", "/issues?sort=priority&order=desc&limit=1", nil)
+	decodeJSON(t, rr, &page)
+	if len(page) != 1 || page[0].Title != "Issue 4" {
+		t.Errorf("sort=priority&order=desc: first page = %v, want Issue 4", page)
+	}
+
+	for _, q := range []string{"limit=0", "limit=x", "offset=-1", "sort=title", "order=sideways"} {
+		if rr := doRequest(t, d, "GET", "/issues?"+q, nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, rr.Code)
+		}
 	}
 }
 
-func TestNextIssueReturns404WhenNoneAvailable(t *testing.T) {
+func TestListIssuesByLabels(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Both", "labels": []string{"api", "bug"}})
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Bug only", "labels": []string{"bug"}})
 
-	rr := doRequest(t, d, "GET", "/issues/next", nil)
-	if rr.Code != http.StatusNotFound {
-		t.Fatalf("next (empty): expected 404, got %d: %s", rr.Code, rr.Body.String())
+	rr := doRequest(t, d, "GET", "/issues?label=bug&label=api", nil)
+	var issues []*model.Issue
+	decodeJSON(t, rr, &issues)
+	if len(issues) != 1 || issues[0].Title != "Both" {
+		t.Errorf("label=bug&label=api: expected only Both, got %v", issues)
+	}
+
+	rr = doRequest(t, d, "GET", "/issues?label=bug", nil)
+	decodeJSON(t, rr, &issues)
+	if len(issues) != 2 {
+		t.Errorf("label=bug: expected 2 issues, got %d", len(issues))
 	}
 }
 
-func TestNextIssueSkipsAssigned(t *testing.T) {
+func TestListIssuesByStatuses(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	for _, st := range append(slices.Clone(model.ActionableStatuses), model.StatusClosed) {
+		rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": string(st)})
+		var iss model.Issue
+		decodeJSON(t, rr, &iss)
+		if st != model.StatusOpen {
+			doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{"status": st})
+		}
+	}
 
-	// Create an issue and assign it.
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title":    "Assigned issue",
-		"priority": 1,
-	})
-	var iss model.Issue
-	decodeJSON(t, rr, &iss)
-	doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/assign", map[string]string{
-		"owner": "bob",
-	})
-
-	// Create another unassigned issue.
-	doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title":    "Unassigned issue",
-		"priority": 5,
-	})
+	titles := func(path string) []string {
+		t.Helper()
+		rr := doRequest(t, d, "GET", path, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, rr.Code, rr.Body.String())
+		}
+		var issues []*model.Issue
+		decodeJSON(t, rr, &issues)
+		var got []string
+		for _, iss := range issues {
+			got = append(got, iss.Title)
+		}
+		sort.Strings(got)
+		return got
+	}
 
-	rr = doRequest(t, d, "GET", "/issues/next", nil)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("next: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	for path, want := range map[string][]string{
+		"/issues?status=closed":                      {"closed"},
+		"/issues?status=open,in_progress":            {"in_progress", "open"},
+		"/issues?status=open&status=blocked":         {"blocked", "open"},
+		"/issues?status=actionable":                  {"blocked", "in_progress", "in_review", "open"},
+		"/issues?status=actionable,closed&all=false": {"blocked", "closed", "in_progress", "in_review", "open"},
+	} {
+		if got := titles(path); !slices.Equal(got, want) {
+			t.Errorf("%s: got %v, want %v", path, got, want)
+		}
 	}
 
-	var next model.Issue
-	decodeJSON(t, rr, &next)
-	if next.Title != "Unassigned issue" {
-		t.Errorf("expected 'Unassigned issue', got %q", next.Title)
+	if rr := doRequest(t, d, "GET", "/issues?status=open,done", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown status: expected 400, got %d", rr.Code)
 	}
 }
 
-func TestRepoResolutionQueryParam(t *testing.T) {
+func TestListIssuesUpdatedSince(t *testing.T) {
 	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Recent"})
 
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org1", "name": "repo1"})
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org2", "name": "repo2"})
+	past := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+	rr := doRequest(t, d, "GET", "/issues?updated_since="+past, nil)
+	var issues []*model.Issue
+	decodeJSON(t, rr, &issues)
+	if len(issues) != 1 {
+		t.Errorf("updated_since in the past: expected 1 issue, got %d", len(issues))
+	}
 
-	// Create issue with explicit repo query param.
-	rr := doRequest(t, d, "POST", "/issues?repo=org1/repo1", map[string]interface{}{
-		"title": "Issue in repo1",
-	})
-	if rr.Code != http.StatusCreated {
-		t.Fatalf("create with repo param: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	future := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	rr = doRequest(t, d, "GET", "/issues?updated_since="+future, nil)
+	decodeJSON(t, rr, &issues)
+	if len(issues) != 0 || rr.Header().Get("X-Total-Count") != "0" {
+		t.Errorf("updated_since in the future: expected no issues, got %d (total %s)", len(issues), rr.Header().Get("X-Total-Count"))
 	}
 
-	var iss model.Issue
-	decodeJSON(t, rr, &iss)
+	if rr := doRequest(t, d, "GET", "/issues?updated_since=yesterday", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("malformed updated_since: expected 400, got %d", rr.Code)
+	}
+}
 
-	// List issues for repo1.
-	rr = doRequest(t, d, "GET", "/issues?repo=org1/repo1", nil)
+func TestSearchIssues(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Flaky integration tests"})
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Docs", "description": "Explain integrity checks"})
+
+	rr := doRequest(t, d, "GET", "/issues/search?q=integr", nil)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("list with repo param: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
 	}
-
 	var issues []*model.Issue
 	decodeJSON(t, rr, &issues)
-	if len(issues) != 1 {
-		t.Fatalf("expected 1 issue in repo1, got %d", len(issues))
+	if len(issues) != 2 {
+		t.Errorf("expected 2 matches, got %d", len(issues))
+	}
+
+	rr = doRequest(t, d, "GET", "/issues/search?q=nothing+matches", nil)
+	if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != "[]" {
+		t.Errorf("no match: expected 200 with [], got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := doRequest(t, d, "GET", "/issues/search?q=+", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("blank query: expected 400, got %d", rr.Code)
 	}
 }
 
-func TestRepoResolutionXRepoHeader(t *testing.T) {
+func TestDeleteRepo(t *testing.T) {
 	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "gone"})
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "kept"})
+	doRequest(t, d, "POST", "/issues?repo=o/gone", map[string]interface{}{"title": "Doomed"})
+	doRequest(t, d, "POST", "/issues?repo=o/kept", map[string]interface{}{"title": "Safe"})
 
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org1", "name": "repo1"})
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org2", "name": "repo2"})
+	if rr := doRequest(t, d, "DELETE", "/repos", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("no repo: expected 400, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "DELETE", "/repos?repo=o/missing", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown repo: expected 404, got %d", rr.Code)
+	}
+
+	rr := doRequest(t, d, "DELETE", "/repos?repo=o/gone", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var repos []*model.RepoConfig
+	decodeJSON(t, doRequest(t, d, "GET", "/repos", nil), &repos)
+	if len(repos) != 1 || repos[0].Name != "kept" {
+		t.Errorf("expected only o/kept left, got %v", repos)
+	}
+	issues, err := d.store.ListIssues(context.Background(), store.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Title != "Safe" {
+		t.Errorf("expected only the kept repo's issue, got %d issues", len(issues))
+	}
+}
+
+func TestAssignIssue(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Assign Me",
+	})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+
+	// Assign.
+	rr = doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/assign", map[string]string{
+		"owner": "alice",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("assign: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var assigned model.Issue
+	decodeJSON(t, rr, &assigned)
+	if assigned.Owner != "alice" {
+		t.Errorf("expected owner 'alice', got %q", assigned.Owner)
+	}
+}
+
+func TestAssignIssueWIPLimit(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	if rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"wip_limit": -1}); rr.Code != http.StatusBadRequest {
+		t.Errorf("negative wip_limit: expected 400, got %d", rr.Code)
+	}
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"wip_limit": 2})
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if repo.WIPLimit != 2 {
+		t.Fatalf("expected wip_limit 2, got %d", repo.WIPLimit)
+	}
+
+	var ids []int
+	for i := 0; i < 4; i++ {
+		rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Task " + itoa(i)})
+		var iss model.Issue
+		decodeJSON(t, rr, &iss)
+		ids = append(ids, iss.ID)
+	}
+	assign := func(id int, owner string) int {
+		return doRequest(t, d, "POST", "/issues/"+itoa(id)+"/assign", map[string]string{"owner": owner}).Code
+	}
+
+	// Below the limit.
+	if code := assign(ids[0], "alice"); code != http.StatusOK {
+		t.Fatalf("first assign: expected 200, got %d", code)
+	}
+	doRequest(t, d, "PATCH", "/issues/"+itoa(ids[0]), map[string]string{"status": "in_progress"})
+	// Reaching the limit.
+	if code := assign(ids[1], "alice"); code != http.StatusOK {
+		t.Fatalf("second assign: expected 200, got %d", code)
+	}
+
+	// Above the limit.
+	rr = doRequest(t, d, "POST", "/issues/"+itoa(ids[2])+"/assign", map[string]string{"owner": "Alice"})
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("third assign: expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "wip_limit of 2") {
+		t.Errorf("expected the limit in the error, got %s", rr.Body.String())
+	}
+
+	// Reassigning an issue alice holds, and assigning other owners, is fine.
+	if code := assign(ids[1], "alice"); code != http.StatusOK {
+		t.Errorf("reassign to same owner: expected 200, got %d", code)
+	}
+	if code := assign(ids[2], "bob"); code != http.StatusOK {
+		t.Errorf("assign to bob: expected 200, got %d", code)
+	}
+
+	// Closing one of alice's issues frees a slot.
+	doRequest(t, d, "PATCH", "/issues/"+itoa(ids[1]), map[string]string{"status": "closed"})
+	if code := assign(ids[3], "alice"); code != http.StatusOK {
+		t.Errorf("assign after close: expected 200, got %d", code)
+	}
+}
+
+// collaboratorsGitHubClient reports a fixed collaborator list and counts
+// how often it is asked for it.
+type collaboratorsGitHubClient struct {
+	noopGitHubClient
+	logins []string
+	calls  *int
+}
+
+func (c collaboratorsGitHubClient) ListCollaborators(ctx context.Context, owner, repo string) ([]string, error) {
+	*c.calls++
+	return c.logins, nil
+}
+
+func TestAssignIssueValidateAssignee(t *testing.T) {
+	s, err := store.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	calls := 0
+	gh := collaboratorsGitHubClient{logins: []string{"Alice", "carol-gh"}, calls: &calls}
+	d := NewWithStoreAndSync(&config.Config{ListenAddr: ":0", DataDir: t.TempDir(), DBPath: ":memory:"}, s, nil, gh)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Task"})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	assign := func(owner string) *httptest.ResponseRecorder {
+		return doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/assign", map[string]string{"owner": owner})
+	}
+
+	// Off by default: anyone may be assigned.
+	if rr := assign("mallory"); rr.Code != http.StatusOK {
+		t.Fatalf("assign with validation off: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if calls != 0 {
+		t.Errorf("expected no collaborator lookups with validation off, got %d", calls)
+	}
+
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"validate_assignee": true,
+		"assignee_logins":   map[string]string{"carol": "carol-gh"},
+	})
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if !repo.ValidateAssignee {
+		t.Fatalf("expected validate_assignee on, got %s", rr.Body.String())
+	}
+
+	rr = assign("bob")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("assign non-collaborator: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "not a collaborator") {
+		t.Errorf("expected a collaborator error, got %s", rr.Body.String())
+	}
+	if rr := assign("alice"); rr.Code != http.StatusOK {
+		t.Errorf("assign collaborator: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := assign("carol"); rr.Code != http.StatusOK {
+		t.Errorf("assign mapped collaborator: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := assign(""); rr.Code != http.StatusOK {
+		t.Errorf("unassign: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if calls != 1 {
+		t.Errorf("expected the collaborator list to be fetched once, got %d", calls)
+	}
+}
+
+func TestAbandonIssue(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Too hard"})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	path := "/issues/" + itoa(iss.ID)
+	doRequest(t, d, "POST", path+"/assign", map[string]string{"owner": "alice"})
+	doRequest(t, d, "PATCH", path, map[string]string{"status": "in_progress"})
+
+	if rr := doRequest(t, d, "POST", path+"/abandon", map[string]string{}); rr.Code != http.StatusBadRequest {
+		t.Errorf("abandon without reason: expected 400, got %d", rr.Code)
+	}
+
+	rr = doRequest(t, d, "POST", path+"/abandon", map[string]string{"reason": "needs DB access"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("abandon: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var abandoned model.Issue
+	decodeJSON(t, rr, &abandoned)
+	if abandoned.Owner != "" {
+		t.Errorf("expected owner cleared, got %q", abandoned.Owner)
+	}
+	if abandoned.Status != model.StatusOpen {
+		t.Errorf("expected status open, got %s", abandoned.Status)
+	}
+
+	events, err := d.store.ListEvents(context.Background(), iss.RepoID, iss.ID)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	var tail []model.Action
+	for _, ev := range events[len(events)-3:] {
+		tail = append(tail, ev.Action)
+	}
+	want := []model.Action{model.ActionAssign, model.ActionStatusChange, model.ActionComment}
+	for i := range want {
+		if tail[i] != want[i] {
+			t.Fatalf("expected trailing events %v, got %v", want, tail)
+		}
+	}
+	if !strings.Contains(events[len(events)-1].Payload, "needs DB access") {
+		t.Errorf("comment payload missing reason: %s", events[len(events)-1].Payload)
+	}
+
+	// keep_status leaves in_progress alone.
+	doRequest(t, d, "POST", path+"/assign", map[string]string{"owner": "bob"})
+	doRequest(t, d, "PATCH", path, map[string]string{"status": "in_progress"})
+	rr = doRequest(t, d, "POST", path+"/abandon", map[string]interface{}{"reason": "handing off", "keep_status": true})
+	var kept model.Issue
+	decodeJSON(t, rr, &kept)
+	if kept.Owner != "" || kept.Status != model.StatusInProgress {
+		t.Errorf("keep_status: expected unowned in_progress, got owner=%q status=%s", kept.Owner, kept.Status)
+	}
+
+	if rr := doRequest(t, d, "POST", "/issues/99999/abandon", map[string]string{"reason": "x"}); rr.Code != http.StatusNotFound {
+		t.Errorf("abandon unknown issue: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestReopenIssue(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Flaky"})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	path := "/issues/" + itoa(iss.ID)
+
+	if rr := doRequest(t, d, "POST", path+"/reopen", nil); rr.Code != http.StatusConflict {
+		t.Errorf("reopen open issue: expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	doRequest(t, d, "PATCH", path, map[string]string{"status": "closed"})
+	rr = doRequest(t, d, "POST", path+"/reopen", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("reopen: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var reopened model.Issue
+	decodeJSON(t, rr, &reopened)
+	if reopened.Status != model.StatusOpen || reopened.ClosedAt != nil {
+		t.Errorf("expected open with closed_at cleared, got status=%s closed_at=%v", reopened.Status, reopened.ClosedAt)
+	}
+
+	events, err := d.store.ListEvents(context.Background(), iss.RepoID, iss.ID)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if last := events[len(events)-1]; last.Action != model.ActionReopen {
+		t.Errorf("expected trailing reopen event, got %s", last.Action)
+	}
+
+	if rr := doRequest(t, d, "POST", "/issues/99999/reopen", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("reopen unknown issue: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestRestoreIssue(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Removed by mistake"})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	path := "/issues/" + itoa(iss.ID)
+
+	if rr := doRequest(t, d, "POST", path+"/restore", nil); rr.Code != http.StatusConflict {
+		t.Errorf("restore open issue: expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	doRequest(t, d, "PATCH", path, map[string]string{"status": "closed"})
+	if rr := doRequest(t, d, "DELETE", path, nil); rr.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := doRequest(t, d, "POST", path+"/reopen", nil); rr.Code != http.StatusConflict {
+		t.Errorf("reopen deleted issue: expected 409, got %d", rr.Code)
+	}
+
+	rr = doRequest(t, d, "POST", path+"/restore", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("restore: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var restored model.Issue
+	decodeJSON(t, rr, &restored)
+	if restored.Status != model.StatusOpen || restored.ClosedAt != nil {
+		t.Errorf("expected open with closed_at cleared, got status=%s closed_at=%v", restored.Status, restored.ClosedAt)
+	}
+
+	events, err := d.store.ListEvents(context.Background(), iss.RepoID, iss.ID)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if last := events[len(events)-1]; last.Action != model.ActionRestore {
+		t.Errorf("expected trailing restore event, got %s", last.Action)
+	}
+
+	if rr := doRequest(t, d, "POST", "/issues/99999/restore", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("restore unknown issue: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestIssueLabelEvents(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Labelled", "labels": []string{"ui"}})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	path := "/issues/" + itoa(iss.ID) + "/labels"
+
+	rr = doRequest(t, d, "POST", path, map[string]string{"label": "bug"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("add label: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var got model.Issue
+	decodeJSON(t, rr, &got)
+	if len(got.Labels) != 2 || got.Labels[0] != "bug" || got.Labels[1] != "ui" {
+		t.Errorf("after add: labels = %v, want [bug ui]", got.Labels)
+	}
+
+	// Adding a label that is already there records nothing.
+	doRequest(t, d, "POST", path, map[string]string{"label": "BUG"})
+
+	rr = doRequest(t, d, "DELETE", path+"?label=UI", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("remove label: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &got)
+	if len(got.Labels) != 1 || got.Labels[0] != "bug" {
+		t.Errorf("after remove: labels = %v, want [bug]", got.Labels)
+	}
+
+	events, err := d.store.ListEvents(context.Background(), iss.RepoID, iss.ID)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	var actions []model.Action
+	for _, ev := range events {
+		actions = append(actions, ev.Action)
+	}
+	if len(actions) != 3 || actions[1] != model.ActionLabelAdd || actions[2] != model.ActionLabelRemove {
+		t.Errorf("expected create, label_add, label_remove; got %v", actions)
+	}
+
+	if rr := doRequest(t, d, "POST", path, map[string]string{"label": " "}); rr.Code != http.StatusBadRequest {
+		t.Errorf("blank label: expected 400, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "DELETE", "/issues/99999/labels?label=bug", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown issue: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestIssueDependencies(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Dependent", "priority": 0})
+	var dependent model.Issue
+	decodeJSON(t, rr, &dependent)
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Blocker", "priority": 1})
+	var blocker model.Issue
+	decodeJSON(t, rr, &blocker)
+	path := "/issues/" + itoa(dependent.ID) + "/dependencies"
+
+	rr = doRequest(t, d, "POST", path, map[string]int{"blocker_id": blocker.ID})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("add dependency: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var got model.Issue
+	decodeJSON(t, rr, &got)
+	if len(got.BlockedBy) != 1 || got.BlockedBy[0] != blocker.ID {
+		t.Fatalf("blocked_by = %v, want [%d]", got.BlockedBy, blocker.ID)
+	}
+
+	var next model.Issue
+	decodeJSON(t, doRequest(t, d, "GET", "/issues/next", nil), &next)
+	if next.ID != blocker.ID {
+		t.Errorf("while blocked: next = %d, want blocker %d", next.ID, blocker.ID)
+	}
+
+	doRequest(t, d, "PATCH", "/issues/"+itoa(blocker.ID), map[string]string{"status": "closed"})
+	decodeJSON(t, doRequest(t, d, "GET", "/issues/next", nil), &next)
+	if next.ID != dependent.ID {
+		t.Errorf("after blocker closed: next = %d, want dependent %d", next.ID, dependent.ID)
+	}
+
+	rr = doRequest(t, d, "DELETE", path+"?blocker_id="+itoa(blocker.ID), nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("remove dependency: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var removed model.Issue
+	decodeJSON(t, rr, &removed)
+	if len(removed.BlockedBy) != 0 {
+		t.Errorf("after remove: blocked_by = %v, want none", removed.BlockedBy)
+	}
+
+	if rr := doRequest(t, d, "POST", path, map[string]int{"blocker_id": dependent.ID}); rr.Code != http.StatusBadRequest {
+		t.Errorf("self dependency: expected 400, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "POST", path, map[string]int{"blocker_id": 99999}); rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown blocker: expected 400, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "DELETE", "/issues/99999/dependencies?blocker_id=1", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown issue: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestIssueParent(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Epic", "issue_type": "epic"})
+	var epic model.Issue
+	decodeJSON(t, rr, &epic)
+
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Child", "parent_id": epic.ID})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create child: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var child model.Issue
+	decodeJSON(t, rr, &child)
+	if child.ParentID == nil || *child.ParentID != epic.ID {
+		t.Fatalf("expected parent %d, got %v", epic.ID, child.ParentID)
+	}
+
+	if rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Orphan", "parent_id": 99999}); rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown parent: expected 400, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "PATCH", "/issues/"+itoa(epic.ID), map[string]interface{}{"parent_id": child.ID}); rr.Code != http.StatusBadRequest {
+		t.Errorf("cycle: expected 400, got %d", rr.Code)
+	}
+
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(child.ID), map[string]interface{}{"parent_id": 0})
+	var cleared model.Issue
+	decodeJSON(t, rr, &cleared)
+	if cleared.ParentID != nil {
+		t.Errorf("expected parent cleared, got %v", *cleared.ParentID)
+	}
+}
+
+func TestIssueParentEndpoints(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	create := func(title string) model.Issue {
+		t.Helper()
+		rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": title})
+		var iss model.Issue
+		decodeJSON(t, rr, &iss)
+		return iss
+	}
+	epic := create("Epic")
+	a, b, c := create("A"), create("B"), create("C")
+
+	for _, child := range []model.Issue{a, b, c} {
+		rr := doRequest(t, d, "POST", "/issues/"+itoa(child.ID)+"/parent", map[string]int{"parent_id": epic.ID})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("set parent of %d: expected 200, got %d: %s", child.ID, rr.Code, rr.Body.String())
+		}
+	}
+	doRequest(t, d, "PATCH", "/issues/"+itoa(b.ID), map[string]string{"status": "closed"})
+	doRequest(t, d, "DELETE", "/issues/"+itoa(c.ID), nil)
+
+	events, _ := d.store.ListEvents(context.Background(), a.RepoID, a.ID)
+	if last := events[len(events)-1]; last.Action != model.ActionSetParent {
+		t.Errorf("expected set_parent event, got %s", last.Action)
+	}
+
+	// A cycle through a child, or a self link, is rejected.
+	if rr := doRequest(t, d, "POST", "/issues/"+itoa(epic.ID)+"/parent", map[string]int{"parent_id": a.ID}); rr.Code != http.StatusBadRequest {
+		t.Errorf("cycle: expected 400, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "POST", "/issues/"+itoa(a.ID)+"/parent", map[string]int{"parent_id": a.ID}); rr.Code != http.StatusBadRequest {
+		t.Errorf("self parent: expected 400, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "POST", "/issues/"+itoa(a.ID)+"/parent", map[string]int{}); rr.Code != http.StatusBadRequest {
+		t.Errorf("missing parent_id: expected 400, got %d", rr.Code)
+	}
+
+	rr := doRequest(t, d, "GET", "/issues/"+itoa(epic.ID)+"/children", nil)
+	var children []model.Issue
+	decodeJSON(t, rr, &children)
+	if len(children) != 2 || children[0].ID != a.ID || children[1].ID != b.ID {
+		t.Errorf("children: expected A and B, got %v", children)
+	}
+	rr = doRequest(t, d, "GET", "/issues/"+itoa(epic.ID)+"/children?all=true", nil)
+	decodeJSON(t, rr, &children)
+	if len(children) != 3 {
+		t.Errorf("children?all=true: expected 3, got %d", len(children))
+	}
+
+	rr = doRequest(t, d, "GET", "/issues/"+itoa(epic.ID), nil)
+	var got model.Issue
+	decodeJSON(t, rr, &got)
+	if len(got.ChildCounts) != 2 || got.ChildCounts[model.StatusOpen] != 1 || got.ChildCounts[model.StatusClosed] != 1 {
+		t.Errorf("child_counts = %v, want 1 open and 1 closed", got.ChildCounts)
+	}
+	if got.EventCount != 1 || got.LastEventAt == nil {
+		t.Errorf("event_count = %d, last_event_at = %v; want the create event", got.EventCount, got.LastEventAt)
+	}
+
+	rr = doRequest(t, d, "DELETE", "/issues/"+itoa(a.ID)+"/parent", nil)
+	decodeJSON(t, rr, &got)
+	if got.ParentID != nil {
+		t.Errorf("expected parent cleared, got %d", *got.ParentID)
+	}
+	events, _ = d.store.ListEvents(context.Background(), a.RepoID, a.ID)
+	if last := events[len(events)-1]; last.Action != model.ActionClearParent {
+		t.Errorf("expected clear_parent event, got %s", last.Action)
+	}
+	// Clearing again records nothing.
+	doRequest(t, d, "DELETE", "/issues/"+itoa(a.ID)+"/parent", nil)
+	if again, _ := d.store.ListEvents(context.Background(), a.RepoID, a.ID); len(again) != len(events) {
+		t.Errorf("repeated clear added %d events", len(again)-len(events))
+	}
+
+	if rr := doRequest(t, d, "GET", "/issues/99999/children", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("children of missing issue: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestNextIssueReturnsHighestPriority(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	// Create two issues with different priorities.
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title":    "Low priority",
+		"priority": 10,
+	})
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title":    "High priority",
+		"priority": 1,
+	})
+
+	rr := doRequest(t, d, "GET", "/issues/next", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("next: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var next model.Issue
+	decodeJSON(t, rr, &next)
+	if next.Title != "High priority" {
+		t.Errorf("expected 'High priority', got %q", next.Title)
+	}
+}
+
+func TestNextIssueReturns404WhenNoneAvailable(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "GET", "/issues/next", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("next (empty): expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestNextIssueSkipsAssigned(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	// Create an issue and assign it.
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title":    "Assigned issue",
+		"priority": 1,
+	})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/assign", map[string]string{
+		"owner": "bob",
+	})
+
+	// Create another unassigned issue.
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title":    "Unassigned issue",
+		"priority": 5,
+	})
+
+	rr = doRequest(t, d, "GET", "/issues/next", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("next: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var next model.Issue
+	decodeJSON(t, rr, &next)
+	if next.Title != "Unassigned issue" {
+		t.Errorf("expected 'Unassigned issue', got %q", next.Title)
+	}
+}
+
+func TestNextIssueCount(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	if rr := doRequest(t, d, "GET", "/issues/next?count=3", nil); rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != "[]" {
+		t.Errorf("no issues: expected 200 with [], got %d %s", rr.Code, rr.Body.String())
+	}
+
+	for _, p := range []int{3, 1, 2, 0, 4} {
+		rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "p" + itoa(p), "priority": p})
+		var iss model.Issue
+		decodeJSON(t, rr, &iss)
+		switch p {
+		case 0:
+			doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/assign", map[string]string{"owner": "bob"})
+		case 4:
+			doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{"status": "closed"})
+		}
+	}
+
+	rr := doRequest(t, d, "GET", "/issues/next?count=2", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("count=2: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var issues []*model.Issue
+	decodeJSON(t, rr, &issues)
+	if len(issues) != 2 || issues[0].Title != "p1" || issues[1].Title != "p2" {
+		t.Errorf("count=2: expected [p1 p2], got %v", issues)
+	}
+
+	rr = doRequest(t, d, "GET", "/issues/next?count=10", nil)
+	decodeJSON(t, rr, &issues)
+	if len(issues) != 3 {
+		t.Errorf("count=10: expected the 3 unassigned open issues, got %d", len(issues))
+	}
+
+	for _, path := range []string{
+		"/issues/next?count=0",
+		"/issues/next?count=two",
+		"/issues/next?count=2&budget=5",
+		"/issues/next?count=2&owner=bob",
+		"/issues/next?count=2&explain=true",
+	} {
+		if rr := doRequest(t, d, "GET", path, nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, rr.Code)
+		}
+	}
+}
+
+func TestClaimIssue(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Later", "priority": 3})
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "First", "priority": 1})
+
+	if rr := doRequest(t, d, "POST", "/issues/claim", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("no owner: expected 400, got %d", rr.Code)
+	}
+
+	for _, want := range []string{"First", "Later"} {
+		rr := doRequestWithHeader(t, d, "POST", "/issues/claim?owner=@me", AgentHeader, "agent-1", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("claim: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var iss model.Issue
+		decodeJSON(t, rr, &iss)
+		if iss.Title != want || iss.Owner != "agent-1" {
+			t.Errorf("claim: expected %s owned by agent-1, got %s owned by %q", want, iss.Title, iss.Owner)
+		}
+
+		rr = doRequest(t, d, "GET", "/issues/"+itoa(iss.ID)+"/events", nil)
+		if !strings.Contains(rr.Body.String(), string(model.ActionAssign)) {
+			t.Errorf("claim of %s recorded no assign event: %s", want, rr.Body.String())
+		}
+	}
+
+	if rr := doRequest(t, d, "POST", "/issues/claim?owner=agent-2", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("nothing left: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestNextIssueExplain(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	type explained struct {
+		Next     *model.Issue         `json:"next"`
+		Reason   string               `json:"reason"`
+		Excluded store.NextExclusions `json:"excluded"`
+	}
+	explain := func(query string) explained {
+		t.Helper()
+		rr := doRequest(t, d, "GET", "/issues/next?explain=true"+query, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("next explain: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var got explained
+		decodeJSON(t, rr, &got)
+		return got
+	}
+
+	if got := explain(""); got.Next != nil || got.Reason != "no open issues" {
+		t.Errorf("empty repo: got next=%v reason=%q", got.Next, got.Reason)
+	}
+
+	for _, title := range []string{"A", "B"} {
+		rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": title})
+		var iss model.Issue
+		decodeJSON(t, rr, &iss)
+		doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/assign", map[string]string{"owner": "bob"})
+	}
+	if got := explain(""); got.Reason != "all 2 open issues are assigned" || got.Excluded.Assigned != 2 {
+		t.Errorf("all assigned: got reason=%q excluded=%+v", got.Reason, got.Excluded)
+	}
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Big", "estimate": 8})
+	var big model.Issue
+	decodeJSON(t, rr, &big)
+
+	got := explain("&budget=3")
+	if got.Reason != "all 3 open issues are excluded: 2 assigned, 1 over budget" {
+		t.Errorf("mixed: got reason %q", got.Reason)
+	}
+	if got.Excluded.Candidates != 3 || got.Excluded.OverBudget != 1 {
+		t.Errorf("mixed: got excluded %+v", got.Excluded)
+	}
+
+	// With an eligible issue, next is returned and there is no reason.
+	got = explain("")
+	if got.Next == nil || got.Next.ID != big.ID || got.Reason != "" {
+		t.Errorf("eligible: got next=%v reason=%q", got.Next, got.Reason)
+	}
+
+	// Without explain, the 404 is unchanged.
+	rr = doRequest(t, d, "GET", "/issues/next?budget=3", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("next without explain: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestRepoResolutionQueryParam(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org1", "name": "repo1"})
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org2", "name": "repo2"})
+
+	// Create issue with explicit repo query param.
+	rr := doRequest(t, d, "POST", "/issues?repo=org1/repo1", map[string]interface{}{
+		"title": "Issue in repo1",
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create with repo param: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+
+	// List issues for repo1.
+	rr = doRequest(t, d, "GET", "/issues?repo=org1/repo1", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list with repo param: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var issues []*model.Issue
+	decodeJSON(t, rr, &issues)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue in repo1, got %d", len(issues))
+	}
+}
+
+func TestRepoResolutionXRepoHeader(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org1", "name": "repo1"})
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org2", "name": "repo2"})
 
 	// Create issue via X-Repo header.
 	rr := doRequestWithHeader(t, d, "POST", "/issues", "X-Repo", "org2/repo2", map[string]interface{}{
 		"title": "Issue in repo2",
 	})
 	if rr.Code != http.StatusCreated {
-		t.Fatalf("create with X-Repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("create with X-Repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// List issues for repo2.
+	rr = doRequestWithHeader(t, d, "GET", "/issues", "X-Repo", "org2/repo2", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list with X-Repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var issues []*model.Issue
+	decodeJSON(t, rr, &issues)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue in repo2, got %d", len(issues))
+	}
+}
+
+func TestRepoResolutionSingleRepoImplicit(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "only", "name": "repo"})
+
+	// No repo specified; should implicitly use the single registered repo.
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Implicit repo",
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create (implicit repo): expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestRepoResolutionMultiRepoAmbiguous(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "a", "name": "1"})
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "b", "name": "2"})
+
+	// No repo specified; should return 400.
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Ambiguous",
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("ambiguous repo: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestInvalidJSONBody(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	// Send malformed JSON.
+	req := httptest.NewRequest("POST", "/issues", bytes.NewBufferString("{invalid"))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid JSON: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestIssueNotFound(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "GET", "/issues/99999", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("not found: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestOfflineModeReturns503(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	for _, ep := range []struct{ method, path string }{
+		{"POST", "/sync?repo=o/r"},
+		{"GET", "/sync/log?repo=o/r"},
+		{"GET", "/sync/active"},
+		{"POST", "/sync/cancel?repo=o/r"},
+		{"POST", "/repos/import?repo=o/r"},
+		{"POST", "/repos/ensure-labels?repo=o/r"},
+	} {
+		rr := doRequest(t, d, ep.method, ep.path, nil)
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s %s: expected 503, got %d: %s", ep.method, ep.path, rr.Code, rr.Body.String())
+			continue
+		}
+		var resp map[string]string
+		decodeJSON(t, rr, &resp)
+		if !strings.HasPrefix(resp["error"], "github unavailable") {
+			t.Errorf("%s %s: unexpected error %q", ep.method, ep.path, resp["error"])
+		}
+	}
+
+	rr := doRequest(t, d, "GET", "/health", nil)
+	var health map[string]interface{}
+	decodeJSON(t, rr, &health)
+	if health["mode"] != "offline" {
+		t.Errorf("health mode = %v, want offline", health["mode"])
+	}
+}
+
+func TestHealthEndpointWithRepos(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org", "name": "repo1"})
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org", "name": "repo2"})
+
+	rr := doRequest(t, d, "GET", "/health", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("health: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp map[string]interface{}
+	decodeJSON(t, rr, &resp)
+
+	repos, ok := resp["repos"].([]interface{})
+	if !ok {
+		t.Fatalf("expected repos array, got %T", resp["repos"])
+	}
+	if len(repos) != 2 {
+		t.Errorf("expected 2 repos in health, got %d", len(repos))
+	}
+}
+
+func TestCreateIssueRequiresTitle(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"description": "no title",
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("missing title: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestBulkCreateIssues(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues/bulk", []map[string]interface{}{
+		{"title": "first", "priority": 2, "labels": []string{"backlog"}},
+		{"title": "second", "issue_type": "bug", "comment": "from the old tracker"},
+		{"title": "third"},
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("bulk create: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp bulkCreateResponse
+	decodeJSON(t, rr, &resp)
+	if resp.Created != 3 || resp.Failed != 0 || len(resp.Results) != 3 {
+		t.Fatalf("created/failed/results = %d/%d/%d, want 3/0/3", resp.Created, resp.Failed, len(resp.Results))
+	}
+	for i, want := range []string{"first", "second", "third"} {
+		res := resp.Results[i]
+		if res.Index != i || res.Issue == nil || res.Issue.Title != want || res.Error != "" {
+			t.Fatalf("result %d = %+v, want issue %q", i, res, want)
+		}
+
+		// Each issue gets its own create event.
+		events, err := d.store.ListEvents(context.Background(), res.Issue.RepoID, res.Issue.ID)
+		if err != nil {
+			t.Fatalf("ListEvents: %v", err)
+		}
+		if len(events) != 1 || events[0].Action != model.ActionCreate {
+			t.Errorf("issue %q events = %+v, want one create event", want, events)
+		}
+	}
+	if got := resp.Results[0].Issue; got.Priority != 2 || len(got.Labels) != 1 || got.Labels[0] != "backlog" {
+		t.Errorf("first issue = %+v, want priority 2 and label backlog", got)
+	}
+	if got := resp.Results[1].Issue.IssueType; got != model.IssueTypeBug {
+		t.Errorf("second issue type = %q, want bug", got)
+	}
+}
+
+func TestBulkCreateIssuesPartialFailure(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues/bulk", []map[string]interface{}{
+		{"title": "kept"},
+		{"description": "no title"},
+		{"title": "also kept"},
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("bulk create: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp bulkCreateResponse
+	decodeJSON(t, rr, &resp)
+	if resp.Created != 2 || resp.Failed != 1 {
+		t.Fatalf("created/failed = %d/%d, want 2/1", resp.Created, resp.Failed)
+	}
+	if bad := resp.Results[1]; bad.Index != 1 || bad.Issue != nil || bad.Error != "title is required" {
+		t.Errorf("result 1 = %+v, want a title error", bad)
+	}
+	if resp.Results[0].Issue == nil || resp.Results[2].Issue == nil || resp.Results[2].Issue.Title != "also kept" {
+		t.Errorf("valid entries not created: %+v", resp.Results)
+	}
+
+	var listed []*model.Issue
+	decodeJSON(t, doRequest(t, d, "GET", "/issues", nil), &listed)
+	if len(listed) != 2 {
+		t.Errorf("listed %d issues, want 2", len(listed))
+	}
+
+	// A batch with nothing valid creates nothing and fails.
+	rr = doRequest(t, d, "POST", "/issues/bulk", []map[string]interface{}{{"description": "no title"}})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("all invalid: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &resp)
+	if resp.Created != 0 || resp.Failed != 1 || !strings.Contains(resp.Error, "title is required") {
+		t.Errorf("all invalid response = %+v", resp)
+	}
+
+	rr = doRequest(t, d, "POST", "/issues/bulk", []map[string]interface{}{})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty batch: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDeleteIssueNotFound(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "DELETE", "/issues/99999", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("delete not found: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAssignIssueNotFound(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues/99999/assign", map[string]string{
+		"owner": "alice",
+	})
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("assign not found: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestUpdateIssueRejectsUnknownStatus(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Enum test"})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{
+		"status": "frobnicate",
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("unknown status: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "unknown status") {
+		t.Errorf("expected unknown status error, got %s", rr.Body.String())
+	}
+
+	got, err := d.store.GetIssue(context.Background(), iss.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.Status != model.StatusOpen {
+		t.Errorf("status = %q, want open", got.Status)
+	}
+	events, _ := d.store.ListEvents(context.Background(), got.RepoID, got.ID)
+	if len(events) != 1 {
+		t.Errorf("expected only the create event, got %d events", len(events))
+	}
+}
+
+func TestUnknownIssueTypeRejected(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Widget", "issue_type": "widget"})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("create with unknown type: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Task"})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{"issue_type": "widget"})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("update to unknown type: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got, _ := d.store.GetIssue(context.Background(), iss.ID); got.IssueType != model.IssueTypeTask {
+		t.Errorf("issue_type = %q, want task", got.IssueType)
+	}
+}
+
+func TestUpdateIssueNotFound(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "PATCH", "/issues/99999", map[string]interface{}{
+		"title": "nope",
+	})
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("update not found: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+// itoa is a convenience for tests.
+func itoa(i int) string {
+	return fmt.Sprintf("%d", i)
+}
+
+// ---------------------------------------------------------------------------
+// noopGitHubClient implements github.Client for wiring tests.
+// ---------------------------------------------------------------------------
+
+type noopGitHubClient struct{}
+
+func (noopGitHubClient) ListIssues(ctx context.Context, owner, repo string, opts github.ListOpts) ([]*github.GitHubIssue, string, error) {
+	return nil, "", nil
+}
+func (noopGitHubClient) GetIssue(ctx context.Context, owner, repo string, number int) (*github.GitHubIssue, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (noopGitHubClient) CreateIssue(ctx context.Context, owner, repo, title, body string, labels []string) (*github.GitHubIssue, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (noopGitHubClient) UpdateIssueBody(ctx context.Context, owner, repo string, number int, body string) error {
+	return fmt.Errorf("not implemented")
+}
+func (noopGitHubClient) ListComments(ctx context.Context, owner, repo string, number int, opts github.ListOpts) ([]*github.GitHubComment, string, error) {
+	return nil, "", nil
+}
+func (noopGitHubClient) CreateComment(ctx context.Context, owner, repo string, number int, body string) (*github.GitHubComment, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (noopGitHubClient) GetComment(ctx context.Context, owner, repo string, commentID int) (*github.GitHubComment, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (noopGitHubClient) AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) error {
+	return nil
+}
+func (noopGitHubClient) AddAssignees(ctx context.Context, owner, repo string, number int, logins []string) error {
+	return nil
+}
+func (noopGitHubClient) RemoveAssignees(ctx context.Context, owner, repo string, number int, logins []string) error {
+	return nil
+}
+func (noopGitHubClient) RemoveLabelFromIssue(ctx context.Context, owner, repo string, number int, label string) error {
+	return nil
+}
+func (noopGitHubClient) CreateLabel(ctx context.Context, owner, repo, name, color, description string) (bool, error) {
+	return true, nil
+}
+func (noopGitHubClient) UpdateLabel(ctx context.Context, owner, repo, name, color, description string) error {
+	return nil
+}
+func (noopGitHubClient) UpdateIssueState(ctx context.Context, owner, repo string, number int, state string) error {
+	return nil
+}
+func (noopGitHubClient) GetRepo(ctx context.Context, owner, repo string) (*github.GitHubRepo, error) {
+	return &github.GitHubRepo{Private: true}, nil
+}
+func (noopGitHubClient) ListCollaborators(ctx context.Context, owner, repo string) ([]string, error) {
+	return nil, nil
+}
+func (noopGitHubClient) GetRateLimit() github.RateLimit {
+	return github.RateLimit{Remaining: 5000, Reset: time.Now().Add(time.Hour)}
+}
+
+// labelGitHubClient records labels created via CreateLabel and reports
+// subsequent creations of the same label as already existing.
+type labelGitHubClient struct {
+	noopGitHubClient
+	labels map[string]bool
+}
+
+func (c *labelGitHubClient) CreateLabel(ctx context.Context, owner, repo, name, color, description string) (bool, error) {
+	if c.labels[name] {
+		return false, nil
+	}
+	c.labels[name] = true
+	return true, nil
+}
+
+func TestEnsureLabels(t *testing.T) {
+	s, err := store.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	cfg := &config.Config{
+		ListenAddr: ":0",
+		DataDir:    t.TempDir(),
+		DBPath:     ":memory:",
+	}
+	gh := &labelGitHubClient{labels: map[string]bool{}}
+	d := NewWithStoreAndSync(cfg, s, nil, gh)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/repos/ensure-labels?repo=o/r", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("ensure labels: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Created  []string `json:"created"`
+		Existing []string `json:"existing"`
+	}
+	decodeJSON(t, rr, &resp)
+	if len(resp.Created) != 1 || resp.Created[0] != github.TrackingLabel || len(resp.Existing) != 0 {
+		t.Errorf("first call: expected created=[%s] existing=[], got %+v", github.TrackingLabel, resp)
+	}
+
+	// Second call is idempotent and reports the label as already present.
+	rr = doRequest(t, d, "POST", "/repos/ensure-labels?repo=o/r", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("ensure labels again: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &resp)
+	if len(resp.Created) != 0 || len(resp.Existing) != 1 || resp.Existing[0] != github.TrackingLabel {
+		t.Errorf("second call: expected created=[] existing=[%s], got %+v", github.TrackingLabel, resp)
+	}
+}
+
+func TestEnsureLabelsWithoutGitHubClient(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/repos/ensure-labels?repo=o/r", nil)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAddRepoStartsSyncer(t *testing.T) {
+	s, err := store.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+
+	cfg := &config.Config{
+		ListenAddr: ":0",
+		DataDir:    t.TempDir(),
+		DBPath:     ":memory:",
+	}
+
+	sm := borSync.NewSyncManager(s, noopGitHubClient{})
+
+	d := NewWithStoreAndSync(cfg, s, sm)
+
+	rr := doRequest(t, d, "POST", "/repos", map[string]string{
+		"owner": "testorg",
+		"name":  "testrepo",
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// AddRepo adds the syncer to the map synchronously (under lock) before
+	// spawning the goroutine, so Status() can see it immediately.
+	status := sm.Status()
+	if len(status) != 1 {
+		t.Fatalf("expected 1 repo in sync status, got %d", len(status))
+	}
+	for _, st := range status {
+		if st.RepoName != "testorg/testrepo" {
+			t.Errorf("expected repo name 'testorg/testrepo', got %q", st.RepoName)
+		}
+	}
+
+	// Stop the sync manager before closing the store to ensure the syncer
+	// goroutine has exited and won't race with store.Close().
+	sm.Stop()
+	s.Close()
+}
+
+func TestUpdateIssueStatusToBlocked(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Blocked Test",
+	})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{
+		"status": "blocked",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update to blocked: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var updated model.Issue
+	decodeJSON(t, rr, &updated)
+	if updated.Status != model.StatusBlocked {
+		t.Errorf("expected blocked, got %q", updated.Status)
+	}
+}
+
+func TestUpdateIssueStatusToInReview(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "InReview Test",
+	})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{
+		"status": "in_review",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update to in_review: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var updated model.Issue
+	decodeJSON(t, rr, &updated)
+	if updated.Status != model.StatusInReview {
+		t.Errorf("expected in_review, got %q", updated.Status)
+	}
+}
+
+func TestCreateIssueWithEpicType(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title":      "Epic Test",
+		"issue_type": "epic",
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create epic: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	if iss.IssueType != model.IssueTypeEpic {
+		t.Errorf("expected epic, got %q", iss.IssueType)
+	}
+}
+
+func TestCommentIssue(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Comment Test",
+	})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+
+	// Add a comment.
+	rr = doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/comment", map[string]string{
+		"comment": "This is a test comment",
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("comment: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var commented model.Issue
+	decodeJSON(t, rr, &commented)
+	if len(commented.Comments) != 1 {
+		t.Fatalf("expected 1 comment, got %d", len(commented.Comments))
+	}
+	if commented.Comments[0].Text != "This is a test comment" {
+		t.Errorf("expected comment text 'This is a test comment', got %q", commented.Comments[0].Text)
+	}
+}
+
+func TestRepeatedChangesWithinASecond(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Busy"})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	path := "/issues/" + itoa(iss.ID)
+
+	// Changes repeated back to back are each recorded, not merged.
+	for range 2 {
+		doRequest(t, d, "POST", path+"/comment", map[string]string{"comment": "same"})
+	}
+	doRequest(t, d, "POST", path+"/labels", map[string]string{"label": "bug"})
+	doRequest(t, d, "DELETE", path+"/labels?label=bug", nil)
+	rr = doRequest(t, d, "POST", path+"/labels", map[string]string{"label": "bug"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("label add again: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	got, err := d.store.GetIssue(context.Background(), iss.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if len(got.Comments) != 2 || !slices.Equal(got.Labels, []string{"bug"}) {
+		t.Errorf("expected 2 comments and label bug, got %d comments and %v", len(got.Comments), got.Labels)
+	}
+	events, _ := d.store.ListEvents(context.Background(), iss.RepoID, iss.ID)
+	if len(events) != 6 {
+		t.Errorf("expected 6 events, got %d", len(events))
+	}
+}
+
+func TestWatchIssueNotifications(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Watch me"})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	path := "/issues/" + itoa(iss.ID)
+
+	if rr := doRequest(t, d, "POST", path+"/watch", map[string]string{"watcher": " "}); rr.Code != http.StatusBadRequest {
+		t.Errorf("empty watcher: expected 400, got %d", rr.Code)
+	}
+	rr = doRequest(t, d, "POST", path+"/watch", map[string]string{"watcher": "alice"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("watch: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var watched model.Issue
+	decodeJSON(t, rr, &watched)
+	if len(watched.Watchers) != 1 || watched.Watchers[0] != "alice" {
+		t.Fatalf("expected watchers [alice], got %v", watched.Watchers)
+	}
+
+	doRequest(t, d, "PATCH", path, map[string]string{"status": "in_progress"})
+	doRequest(t, d, "POST", path+"/assign", map[string]string{"owner": "bob"})
+
+	rr = doRequest(t, d, "GET", "/notifications?owner=alice", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("notifications: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var notes []store.Notification
+	decodeJSON(t, rr, &notes)
+	if len(notes) != 2 {
+		t.Fatalf("expected 2 notifications, got %+v", notes)
+	}
+	if notes[0].Action != model.ActionAssign || notes[1].Action != model.ActionStatusChange || notes[0].IssueID != iss.ID {
+		t.Errorf("expected assign then status_change on issue %d, got %+v", iss.ID, notes)
+	}
+
+	rr = doRequest(t, d, "GET", "/notifications?owner=bob", nil)
+	decodeJSON(t, rr, &notes)
+	if len(notes) != 0 {
+		t.Errorf("bob watches nothing, got %+v", notes)
+	}
+	if rr := doRequest(t, d, "GET", "/notifications", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("missing owner: expected 400, got %d", rr.Code)
+	}
+
+	rr = doRequest(t, d, "DELETE", path+"/watch?watcher=Alice", nil)
+	var unwatched model.Issue
+	decodeJSON(t, rr, &unwatched)
+	if len(unwatched.Watchers) != 0 {
+		t.Errorf("expected no watchers, got %v", unwatched.Watchers)
+	}
+	doRequest(t, d, "POST", path+"/comment", map[string]string{"comment": "done yet?"})
+	rr = doRequest(t, d, "GET", "/notifications?owner=alice", nil)
+	decodeJSON(t, rr, &notes)
+	if len(notes) != 2 {
+		t.Errorf("after unwatch: expected still 2 notifications, got %d", len(notes))
+	}
+}
+
+func TestEditAndDeleteComment(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Comment Edit Test",
+	})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	for _, text := range []string{"one", "twoo", "three"} {
+		doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/comment", map[string]string{"comment": text})
+	}
+
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID)+"/comments/1", map[string]string{"comment": "two"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("edit: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var edited model.Issue
+	decodeJSON(t, rr, &edited)
+	if c := edited.Comments[1]; c.Text != "two" || c.EditedAt == "" {
+		t.Errorf("expected edited comment 'two' with edited_at, got %+v", c)
+	}
+
+	rr = doRequest(t, d, "DELETE", "/issues/"+itoa(iss.ID)+"/comments/0", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var deleted model.Issue
+	decodeJSON(t, rr, &deleted)
+	if len(deleted.Comments) != 2 || deleted.Comments[0].Text != "two" || deleted.Comments[1].Text != "three" {
+		t.Errorf("expected comments [two three], got %+v", deleted.Comments)
+	}
+
+	// The stored issue matches, and both changes were recorded as events.
+	rr = doRequest(t, d, "GET", "/issues/"+itoa(iss.ID), nil)
+	var got model.Issue
+	decodeJSON(t, rr, &got)
+	if len(got.Comments) != 2 || got.Comments[0].EditedAt == "" {
+		t.Errorf("expected stored comments to match, got %+v", got.Comments)
+	}
+	events, _ := d.store.ListEvents(context.Background(), iss.RepoID, iss.ID)
+	if n := len(events); n < 2 || events[n-2].Action != model.ActionCommentEdit || events[n-1].Action != model.ActionCommentDelete {
+		t.Errorf("expected comment_edit and comment_delete as the latest events, got %d events", n)
+	}
+
+	if rr := doRequest(t, d, "DELETE", "/issues/"+itoa(iss.ID)+"/comments/2", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("out of range: expected 404, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "DELETE", "/issues/"+itoa(iss.ID)+"/comments/x", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("bad index: expected 400, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID)+"/comments/0", map[string]string{"comment": ""}); rr.Code != http.StatusBadRequest {
+		t.Errorf("empty edit: expected 400, got %d", rr.Code)
+	}
+}
+
+func TestCommentIssueNotFound(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues/99999/comment", map[string]string{
+		"comment": "test",
+	})
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("comment not found: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestCommentIssueEmptyComment(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Empty Comment Test",
+	})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+
+	rr = doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/comment", map[string]string{
+		"comment": "",
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty comment: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestUpdateIssueWithComment(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Update Comment Test",
+	})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+
+	// Update with a comment.
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{
+		"title":   "New Title",
+		"comment": "Changed the title",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update with comment: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var updated model.Issue
+	decodeJSON(t, rr, &updated)
+	if updated.Title != "New Title" {
+		t.Errorf("expected title 'New Title', got %q", updated.Title)
+	}
+	if len(updated.Comments) != 1 {
+		t.Fatalf("expected 1 comment, got %d", len(updated.Comments))
+	}
+	if updated.Comments[0].Text != "Changed the title" {
+		t.Errorf("expected comment text 'Changed the title', got %q", updated.Comments[0].Text)
+	}
+}
+
+func TestUpdateIssueCommentOnly(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Comment Only Update",
+	})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+
+	// Update with only a comment (no field changes).
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{
+		"comment": "Just a note",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("comment-only update: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var updated model.Issue
+	decodeJSON(t, rr, &updated)
+	if len(updated.Comments) != 1 {
+		t.Fatalf("expected 1 comment, got %d", len(updated.Comments))
+	}
+	if updated.Comments[0].Text != "Just a note" {
+		t.Errorf("expected comment text 'Just a note', got %q", updated.Comments[0].Text)
+	}
+}
+
+func TestServeUI(t *testing.T) {
+	d := testDaemon(t)
+
+	rr := doRequest(t, d, "GET", "/", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET /: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	ct := rr.Header().Get("Content-Type")
+	if ct != "text/html; charset=utf-8" {
+		t.Errorf("expected Content-Type text/html; charset=utf-8, got %q", ct)
+	}
+
+	body := rr.Body.String()
+	if !strings.Contains(body, "<title>Box of Rocks</title>") {
+		t.Error("expected response to contain <title>Box of Rocks</title>")
+	}
+}
+
+func TestAddRepoWithoutSyncManager(t *testing.T) {
+	d := testDaemon(t) // syncMgr is nil
+
+	rr := doRequest(t, d, "POST", "/repos", map[string]string{
+		"owner": "testorg",
+		"name":  "testrepo",
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if repo.Owner != "testorg" || repo.Name != "testrepo" {
+		t.Errorf("unexpected repo: %+v", repo)
+	}
+}
+
+func TestAddRepoWithSocketFields(t *testing.T) {
+	d := testDaemon(t)
+
+	rr := doRequest(t, d, "POST", "/repos", map[string]interface{}{
+		"owner":      "testorg",
+		"name":       "testrepo",
+		"local_path": "/tmp/testrepo",
+		"socket":     true,
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if repo.LocalPath != "/tmp/testrepo" {
+		t.Errorf("expected local_path '/tmp/testrepo', got %q", repo.LocalPath)
+	}
+	if !repo.SocketEnabled {
+		t.Error("expected socket_enabled=true")
+	}
+}
+
+func TestRepoResolutionWorkingDir(t *testing.T) {
+	d := testDaemon(t)
+
+	// Register two repos with different local paths.
+	doRequest(t, d, "POST", "/repos", map[string]interface{}{
+		"owner": "org1", "name": "repo1",
+		"local_path": "/home/user/projects/repo1", "socket": false,
+	})
+	doRequest(t, d, "POST", "/repos", map[string]interface{}{
+		"owner": "org2", "name": "repo2",
+		"local_path": "/home/user/projects/repo2", "socket": false,
+	})
+
+	// Create an issue with explicit repo param so we have something to list.
+	doRequest(t, d, "POST", "/issues?repo=org1/repo1", map[string]interface{}{
+		"title": "issue in repo1",
+	})
+	doRequest(t, d, "POST", "/issues?repo=org2/repo2", map[string]interface{}{
+		"title": "issue in repo2",
+	})
+
+	// List issues using X-Working-Dir matching repo1's local path.
+	rr := doRequestWithHeader(t, d, "GET", "/issues", "X-Working-Dir", "/home/user/projects/repo1", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list with working dir: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var issues []*model.Issue
+	decodeJSON(t, rr, &issues)
+	if len(issues) != 1 || issues[0].Title != "issue in repo1" {
+		t.Errorf("expected 1 issue in repo1, got %d", len(issues))
+	}
+
+	// Subdirectory should also resolve to repo2.
+	rr = doRequestWithHeader(t, d, "GET", "/issues", "X-Working-Dir", "/home/user/projects/repo2/src/pkg", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list with subdir: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &issues)
+	if len(issues) != 1 || issues[0].Title != "issue in repo2" {
+		t.Errorf("expected 1 issue in repo2, got %d", len(issues))
+	}
+}
+
+func TestUpdateRepoSocketEnabled(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	// Enable socket.
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"local_path":     "/tmp/repo",
+		"socket_enabled": true,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if !repo.SocketEnabled {
+		t.Error("expected socket_enabled=true")
+	}
+	if repo.LocalPath != "/tmp/repo" {
+		t.Errorf("expected local_path '/tmp/repo', got %q", repo.LocalPath)
+	}
+
+	// Disable socket.
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"socket_enabled": false,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	decodeJSON(t, rr, &repo)
+	if repo.SocketEnabled {
+		t.Error("expected socket_enabled=false")
+	}
+}
+
+func TestAddRepoWithQueueField(t *testing.T) {
+	d := testDaemon(t)
+
+	rr := doRequest(t, d, "POST", "/repos", map[string]interface{}{
+		"owner":      "testorg",
+		"name":       "testrepo",
+		"local_path": "/tmp/testrepo",
+		"queue":      true,
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if repo.LocalPath != "/tmp/testrepo" {
+		t.Errorf("expected local_path '/tmp/testrepo', got %q", repo.LocalPath)
+	}
+	if !repo.QueueEnabled {
+		t.Error("expected queue_enabled=true")
+	}
+	if repo.SocketEnabled {
+		t.Error("expected socket_enabled=false when only queue was requested")
+	}
+}
+
+func TestUpdateRepoQueueEnabled(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	// Enable queue.
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"local_path":    "/tmp/repo",
+		"queue_enabled": true,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if !repo.QueueEnabled {
+		t.Error("expected queue_enabled=true")
+	}
+	if repo.LocalPath != "/tmp/repo" {
+		t.Errorf("expected local_path '/tmp/repo', got %q", repo.LocalPath)
+	}
+
+	// Disable queue.
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"queue_enabled": false,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	decodeJSON(t, rr, &repo)
+	if repo.QueueEnabled {
+		t.Error("expected queue_enabled=false")
+	}
+}
+
+func TestUpdateRepoTrustedAuthorsOnly(t *testing.T) {
+	d := testDaemon(t)
+
+	// Create a repo first.
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	// Enable trusted_authors_only.
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"trusted_authors_only": true,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if !repo.TrustedAuthorsOnly {
+		t.Error("expected trusted_authors_only=true")
+	}
+
+	// Disable it.
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"trusted_authors_only": false,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	decodeJSON(t, rr, &repo)
+	if repo.TrustedAuthorsOnly {
+		t.Error("expected trusted_authors_only=false")
+	}
+}
+
+func TestUpdateRepoAllowedInboundActions(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"allowed_inbound_actions": []string{"comment", "status_change"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if len(repo.AllowedInboundActions) != 2 {
+		t.Errorf("expected 2 allowed actions, got %v", repo.AllowedInboundActions)
+	}
+
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"allowed_inbound_actions": []string{"comment", "explode"},
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown action: expected 400, got %d", rr.Code)
+	}
+
+	// An empty list lifts the restriction.
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"allowed_inbound_actions": []string{},
+	})
+	var cleared model.RepoConfig
+	decodeJSON(t, rr, &cleared)
+	if len(cleared.AllowedInboundActions) != 0 {
+		t.Errorf("expected restriction cleared, got %v", cleared.AllowedInboundActions)
+	}
+}
+
+func TestUpdateRepoNextStrategy(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"next_strategy": "fifo"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if repo.NextStrategy != model.NextStrategyFIFO {
+		t.Errorf("expected next_strategy fifo, got %q", repo.NextStrategy)
+	}
+
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"next_strategy": "random"})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown strategy: expected 400, got %d", rr.Code)
+	}
+}
+
+func TestUpdateRepoSyncDirection(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"sync_direction": "pull"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if repo.SyncDirection != model.SyncPull || repo.PushesToGitHub() {
+		t.Errorf("expected pull-only, got %q", repo.SyncDirection)
+	}
+
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"sync_direction": "sideways"})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown direction: expected 400, got %d", rr.Code)
+	}
+}
+
+func TestUpdateRepoTrustedAuthors(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"trusted_authors": []string{"@alice", " bob "}})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if len(repo.TrustedAuthors) != 2 || repo.TrustedAuthors[0] != "alice" || repo.TrustedAuthors[1] != "bob" {
+		t.Errorf("expected trusted authors [alice bob], got %v", repo.TrustedAuthors)
+	}
+
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"trusted_authors": []string{" "}})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("empty login: expected 400, got %d", rr.Code)
+	}
+}
+
+func TestUpdateRepoAssigneeLogins(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"assignee_logins": map[string]string{"agent-1": "@octocat"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if repo.GitHubLogin("agent-1") != "octocat" {
+		t.Errorf("expected agent-1 mapped to octocat, got %v", repo.AssigneeLogins)
+	}
+
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"assignee_logins": map[string]string{"agent-1": " "},
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("empty login: expected 400, got %d", rr.Code)
+	}
+
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"assignee_logins": map[string]string{},
+	})
+	var cleared model.RepoConfig
+	decodeJSON(t, rr, &cleared)
+	if len(cleared.AssigneeLogins) != 0 {
+		t.Errorf("expected mapping cleared, got %v", cleared.AssigneeLogins)
+	}
+}
+
+func TestUpdateRepoLabel(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"label": " tracked "})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if repo.Label != "tracked" {
+		t.Errorf("expected label tracked, got %q", repo.Label)
+	}
+
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"label": "a,b"})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("label with comma: expected 400, got %d", rr.Code)
+	}
+
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"label": ""})
+	decodeJSON(t, rr, &repo)
+	if repo.Label != model.DefaultTrackingLabel {
+		t.Errorf("empty label: expected default %q, got %q", model.DefaultTrackingLabel, repo.Label)
+	}
+}
+
+func TestRepoIntegrity(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Epic", "issue_type": "epic"})
+	var epic model.Issue
+	decodeJSON(t, rr, &epic)
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Child", "labels": []string{"api", " API"}, "parent_id": epic.ID,
+	})
+	var child model.Issue
+	decodeJSON(t, rr, &child)
+	id := itoa(child.ID)
+	doRequest(t, d, "PATCH", "/issues/"+id, map[string]interface{}{"priority": 2, "estimate": 3})
+	doRequest(t, d, "POST", "/issues/"+id+"/assign", map[string]string{"owner": "bob"})
+	doRequest(t, d, "POST", "/issues/"+id+"/comment", map[string]string{"comment": "halfway"})
+	doRequest(t, d, "PATCH", "/issues/"+itoa(epic.ID), map[string]interface{}{"status": "closed"})
+
+	type report struct {
+		Checked    int                 `json:"checked"`
+		OK         bool                `json:"ok"`
+		Mismatches []integrityMismatch `json:"mismatches"`
+	}
+	rr = doRequest(t, d, "GET", "/repos/integrity", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("integrity: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var got report
+	decodeJSON(t, rr, &got)
+	if got.Checked != 2 || !got.OK {
+		t.Fatalf("expected 2 clean issues, got checked=%d mismatches=%+v", got.Checked, got.Mismatches)
+	}
+
+	// Edit the row directly, bypassing the event log.
+	row, _ := d.store.GetIssue(context.Background(), child.ID)
+	row.Title = "Edited by hand"
+	d.store.UpdateIssue(context.Background(), row)
+
+	rr = doRequest(t, d, "GET", "/repos/integrity", nil)
+	decodeJSON(t, rr, &got)
+	if got.OK || len(got.Mismatches) != 1 {
+		t.Fatalf("expected one mismatch, got %+v", got.Mismatches)
+	}
+	if m := got.Mismatches[0]; m.IssueID != child.ID || m.Field != "title" || m.Stored != "Edited by hand" || m.Replayed != "Child" {
+		t.Errorf("unexpected mismatch %+v", m)
+	}
+}
+
+func TestRepoIntegrityAfterNormalizedLabels(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	// The row stores the labels trimmed; a later label event must apply to
+	// the replayed state the same way it applied to the row.
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title": "Padded", "labels": []string{"b", " a"},
+	})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	doRequest(t, d, "DELETE", "/issues/"+itoa(iss.ID)+"/labels?label=a", nil)
+
+	rr = doRequest(t, d, "GET", "/repos/integrity", nil)
+	var got struct {
+		OK         bool                `json:"ok"`
+		Mismatches []integrityMismatch `json:"mismatches"`
+	}
+	decodeJSON(t, rr, &got)
+	if !got.OK {
+		t.Errorf("expected no drift, got %+v", got.Mismatches)
+	}
+}
+
+func TestRepoRepair(t *testing.T) {
+	d := testDaemon(t)
+	ctx := context.Background()
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Original", "priority": 1})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/assign", map[string]string{"owner": "bob"})
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Untouched"})
+
+	// Corrupt the row behind the event log's back.
+	row, _ := d.store.GetIssue(ctx, iss.ID)
+	row.Title = "Corrupted"
+	row.Owner = ""
+	row.Status = model.StatusClosed
+	d.store.UpdateIssue(ctx, row)
+
+	rr = doRequest(t, d, "POST", "/repos/repair", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("repair: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var result struct {
+		Checked  int   `json:"checked"`
+		Repaired []int `json:"repaired"`
+	}
+	decodeJSON(t, rr, &result)
+	if result.Checked != 2 || len(result.Repaired) != 1 || result.Repaired[0] != iss.ID {
+		t.Fatalf("expected only issue %d repaired, got %+v", iss.ID, result)
+	}
+
+	got, _ := d.store.GetIssue(ctx, iss.ID)
+	if got.Title != "Original" || got.Owner != "bob" || got.Status != model.StatusOpen || got.ID != iss.ID {
+		t.Errorf("expected replayed state, got title=%q owner=%q status=%q", got.Title, got.Owner, got.Status)
+	}
+
+	rr = doRequest(t, d, "GET", "/repos/integrity", nil)
+	var report struct {
+		OK bool `json:"ok"`
+	}
+	decodeJSON(t, rr, &report)
+	if !report.OK {
+		t.Errorf("expected integrity check to pass after repair: %s", rr.Body.String())
+	}
+}
+
+func TestTrendingIssues(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Busy"})
+	var busy model.Issue
+	decodeJSON(t, rr, &busy)
+	doRequest(t, d, "POST", "/issues/"+itoa(busy.ID)+"/comment", map[string]string{"comment": "ping"})
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Quiet"})
+
+	rr = doRequest(t, d, "GET", "/issues/trending?days=3", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("trending: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var result struct {
+		Days   int                    `json:"days"`
+		Issues []*store.IssueActivity `json:"issues"`
+	}
+	decodeJSON(t, rr, &result)
+	if result.Days != 3 || len(result.Issues) != 2 || result.Issues[0].Issue.ID != busy.ID {
+		t.Fatalf("expected Busy first of 2 over 3 days, got %s", rr.Body.String())
+	}
+	if result.Issues[0].Comments != 1 {
+		t.Errorf("expected 1 comment signal, got %d", result.Issues[0].Comments)
+	}
+
+	if rr := doRequest(t, d, "GET", "/issues/trending?days=0", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("days=0: expected 400, got %d", rr.Code)
+	}
+}
+
+func TestStaleIssues(t *testing.T) {
+	d := testDaemon(t)
+	repo, err := d.store.AddRepo(context.Background(), "o", "r")
+	if err != nil {
+		t.Fatalf("add repo: %v", err)
+	}
+	old := time.Now().UTC().AddDate(0, 0, -20)
+	idle, err := d.store.CreateIssue(context.Background(), &model.Issue{RepoID: repo.ID, Title: "Idle", CreatedAt: old, UpdatedAt: old})
+	if err != nil {
+		t.Fatalf("create issue: %v", err)
+	}
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Fresh"})
+
+	rr := doRequest(t, d, "GET", "/issues/stale?days=10", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("stale: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var result struct {
+		Days   int            `json:"days"`
+		Issues []*model.Issue `json:"issues"`
+	}
+	decodeJSON(t, rr, &result)
+	if result.Days != 10 || len(result.Issues) != 1 || result.Issues[0].ID != idle.ID {
+		t.Errorf("expected only Idle over 10 days, got %s", rr.Body.String())
+	}
+
+	rr = doRequest(t, d, "GET", "/issues/stale?days=30", nil)
+	decodeJSON(t, rr, &result)
+	if result.Issues == nil || len(result.Issues) != 0 {
+		t.Errorf("expected an empty list over 30 days, got %s", rr.Body.String())
+	}
+	if rr := doRequest(t, d, "GET", "/issues/stale?days=-1", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("days=-1: expected 400, got %d", rr.Code)
+	}
+}
+
+func TestRepoIssueTypes(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	if rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "s", "issue_type": "spike"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("spike before configuring: expected 400, got %d", rr.Code)
+	}
+
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"issue_types": []string{"chore", "spike"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "s", "issue_type": "spike"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create spike: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var spike model.Issue
+	decodeJSON(t, rr, &spike)
+	if spike.IssueType != "spike" {
+		t.Errorf("expected issue_type spike, got %s", spike.IssueType)
+	}
+
+	// Without issue_type, the repo's first type is used.
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "untyped"})
+	var untyped model.Issue
+	decodeJSON(t, rr, &untyped)
+	if untyped.IssueType != "chore" {
+		t.Errorf("expected default issue_type chore, got %s", untyped.IssueType)
+	}
+	events, err := d.store.ListEvents(context.Background(), untyped.RepoID, untyped.ID)
+	if err != nil || len(events) == 0 {
+		t.Fatalf("ListEvents: %v (%d events)", err, len(events))
+	}
+	if !strings.Contains(events[0].Payload, `"issue_type":"chore"`) {
+		t.Errorf("create event should record the defaulted type for replay: %s", events[0].Payload)
+	}
+
+	if rr := doRequest(t, d, "PATCH", "/issues/"+itoa(spike.ID), map[string]string{"issue_type": "bug"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("update to bug: expected 400, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "PATCH", "/issues/"+itoa(spike.ID), map[string]string{"issue_type": "chore"}); rr.Code != http.StatusOK {
+		t.Errorf("update to chore: expected 200, got %d", rr.Code)
+	}
+
+	if rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"issue_types": []string{" "}}); rr.Code != http.StatusBadRequest {
+		t.Errorf("blank issue type: expected 400, got %d", rr.Code)
+	}
+}
+
+func TestRepoIssueTemplates(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	const bugTemplate = "## Steps to reproduce\n\n## Expected\n\n## Actual\n"
+	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"issue_templates": map[string]string{"bug": bugTemplate},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = doRequest(t, d, "GET", "/repos/templates?repo=o/r", nil)
+	var listed struct {
+		Repo      string            `json:"repo"`
+		Templates map[string]string `json:"templates"`
+	}
+	decodeJSON(t, rr, &listed)
+	if listed.Repo != "o/r" || listed.Templates["bug"] != bugTemplate {
+		t.Errorf("GET /repos/templates = %+v", listed)
+	}
+
+	// A bug created without a description gets the whole skeleton.
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Crash", "issue_type": "bug"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create bug: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var bug model.Issue
+	decodeJSON(t, rr, &bug)
+	if bug.Description != bugTemplate {
+		t.Errorf("description = %q, want the template", bug.Description)
+	}
+	events, err := d.store.ListEvents(context.Background(), bug.RepoID, bug.ID)
+	if err != nil || len(events) == 0 {
+		t.Fatalf("ListEvents: %v (%d events)", err, len(events))
+	}
+	if !strings.Contains(events[0].Payload, "Steps to reproduce") {
+		t.Errorf("create event should carry the filled description for replay: %s", events[0].Payload)
+	}
+
+	// Sections the client wrote are kept; only the missing ones are added.
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{
+		"title":       "Hang",
+		"issue_type":  "bug",
+		"description": "## Steps to reproduce\nOpen the app.",
+	})
+	var partial model.Issue
+	decodeJSON(t, rr, &partial)
+	want := "## Steps to reproduce\nOpen the app.\n\n## Expected\n\n## Actual"
+	if partial.Description != want {
+		t.Errorf("description = %q, want %q", partial.Description, want)
+	}
+
+	// Types without a template are left alone.
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Chore", "issue_type": "task"})
+	var task model.Issue
+	decodeJSON(t, rr, &task)
+	if task.Description != "" {
+		t.Errorf("task description = %q, want empty", task.Description)
+	}
+
+	if rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
+		"issue_templates": map[string]string{"widget": "## Parts\n"},
+	}); rr.Code != http.StatusBadRequest {
+		t.Errorf("template for unknown type: expected 400, got %d", rr.Code)
+	}
+}
+
+func TestIssueKeyPrefix(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Prefixed"})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	ref := "BOR-" + itoa(iss.ID)
+
+	// Without a prefix only bare IDs resolve.
+	if rr := doRequest(t, d, "GET", "/issues/"+ref, nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("prefixed ref before configuring: expected 400, got %d", rr.Code)
+	}
+
+	if rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"key_prefix": "B-R"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid key_prefix: expected 400, got %d", rr.Code)
+	}
+	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{"key_prefix": "BOR"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if repo.KeyPrefix != "BOR" {
+		t.Errorf("key_prefix = %q, want BOR", repo.KeyPrefix)
+	}
+
+	for _, path := range []string{itoa(iss.ID), ref, "bor-" + itoa(iss.ID)} {
+		rr := doRequest(t, d, "GET", "/issues/"+path, nil)
+		if rr.Code != http.StatusOK {
+			t.Errorf("GET /issues/%s: expected 200, got %d: %s", path, rr.Code, rr.Body.String())
+			continue
+		}
+		var got model.Issue
+		decodeJSON(t, rr, &got)
+		if got.ID != iss.ID {
+			t.Errorf("GET /issues/%s returned issue %d, want %d", path, got.ID, iss.ID)
+		}
+	}
+
+	if rr := doRequest(t, d, "GET", "/issues/XYZ-"+itoa(iss.ID), nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("wrong prefix: expected 400, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "GET", "/issues/BOR-99999", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown prefixed issue: expected 404, got %d", rr.Code)
+	}
+
+	// Every issue route takes the prefixed form.
+	if rr := doRequest(t, d, "POST", "/issues/"+ref+"/comment", map[string]string{"comment": "by key"}); rr.Code != http.StatusCreated {
+		t.Errorf("comment by key: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Repo local paths (worktree support)
+// ---------------------------------------------------------------------------
+
+func TestAddRepoPath(t *testing.T) {
+	d := testDaemon(t)
+
+	// Register a repo.
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	// Add a local path.
+	rr := doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{
+		"local_path":     "/tmp/worktree-a",
+		"socket_enabled": true,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("add repo path: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if len(repo.LocalPaths) != 1 {
+		t.Fatalf("expected 1 local path, got %d", len(repo.LocalPaths))
+	}
+	if repo.LocalPaths[0].LocalPath != "/tmp/worktree-a" {
+		t.Errorf("expected '/tmp/worktree-a', got %q", repo.LocalPaths[0].LocalPath)
+	}
+	if !repo.LocalPaths[0].SocketEnabled {
+		t.Error("expected socket_enabled=true on local path")
+	}
+
+	// Add a second local path.
+	rr = doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{
+		"local_path":    "/tmp/worktree-b",
+		"queue_enabled": true,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("add second path: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	decodeJSON(t, rr, &repo)
+	if len(repo.LocalPaths) != 2 {
+		t.Fatalf("expected 2 local paths, got %d", len(repo.LocalPaths))
+	}
+}
+
+func TestRemoveRepoPath(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	// Add two paths.
+	doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{
+		"local_path": "/tmp/worktree-a",
+	})
+	doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{
+		"local_path": "/tmp/worktree-b",
+	})
+
+	// Remove one.
+	rr := doRequest(t, d, "DELETE", "/repos/paths?repo=o/r", map[string]interface{}{
+		"local_path": "/tmp/worktree-a",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("remove repo path: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if len(repo.LocalPaths) != 1 {
+		t.Fatalf("expected 1 local path after removal, got %d", len(repo.LocalPaths))
+	}
+	if repo.LocalPaths[0].LocalPath != "/tmp/worktree-b" {
+		t.Errorf("expected '/tmp/worktree-b', got %q", repo.LocalPaths[0].LocalPath)
+	}
+}
+
+func TestUpdateRepoPathTogglesEachPathIndependently(t *testing.T) {
+	d := testDaemon(t)
+	pathA := t.TempDir()
+	pathB := t.TempDir()
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{"local_path": pathA})
+	doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{"local_path": pathB})
+
+	flags := func(repo model.RepoConfig, path string) (socket, queue bool) {
+		t.Helper()
+		for _, lp := range repo.LocalPaths {
+			if lp.LocalPath == path {
+				return lp.SocketEnabled, lp.QueueEnabled
+			}
+		}
+		t.Fatalf("path %s missing from repo", path)
+		return false, false
+	}
+
+	// Enable the socket on the second path only.
+	rr := doRequest(t, d, "PATCH", "/repos/paths?repo=o/r", map[string]interface{}{
+		"local_path":     pathB,
+		"socket_enabled": true,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("patch path B: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var repo model.RepoConfig
+	decodeJSON(t, rr, &repo)
+	if s, q := flags(repo, pathA); s || q {
+		t.Errorf("path A: expected socket=false queue=false, got socket=%v queue=%v", s, q)
+	}
+	if s, q := flags(repo, pathB); !s || q {
+		t.Errorf("path B: expected socket=true queue=false, got socket=%v queue=%v", s, q)
+	}
+	d.socketMu.Lock()
+	_, sockB := d.socketLns[filepath.Join(pathB, ".boxofrocks", "bor.sock")]
+	d.socketMu.Unlock()
+	if !sockB {
+		t.Error("expected socket listener for path B")
+	}
+
+	// Enable the queue on the first path; path B's socket must be preserved.
+	rr = doRequest(t, d, "PATCH", "/repos/paths?repo=o/r", map[string]interface{}{
+		"local_path":    pathA,
+		"queue_enabled": true,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("patch path A: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &repo)
+	if s, q := flags(repo, pathA); s || !q {
+		t.Errorf("path A: expected socket=false queue=true, got socket=%v queue=%v", s, q)
+	}
+	if s, q := flags(repo, pathB); !s || q {
+		t.Errorf("path B: expected socket=true queue=false, got socket=%v queue=%v", s, q)
+	}
+
+	// Disable path B's socket; path A's queue must be preserved.
+	rr = doRequest(t, d, "PATCH", "/repos/paths?repo=o/r", map[string]interface{}{
+		"local_path":     pathB,
+		"socket_enabled": false,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("patch path B off: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &repo)
+	if s, q := flags(repo, pathA); s || !q {
+		t.Errorf("path A: expected socket=false queue=true, got socket=%v queue=%v", s, q)
+	}
+	if s, _ := flags(repo, pathB); s {
+		t.Error("path B: expected socket=false")
+	}
+	d.socketMu.Lock()
+	_, sockB = d.socketLns[filepath.Join(pathB, ".boxofrocks", "bor.sock")]
+	d.socketMu.Unlock()
+	if sockB {
+		t.Error("expected path B socket listener to be removed")
+	}
+}
+
+func TestUpdateRepoPathUnknownPath(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+
+	rr := doRequest(t, d, "PATCH", "/repos/paths?repo=o/r", map[string]interface{}{
+		"local_path":     "/tmp/not-registered",
+		"socket_enabled": true,
+	})
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = doRequest(t, d, "PATCH", "/repos/paths?repo=o/r", map[string]interface{}{})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without local_path, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestResolveRepoMultiplePaths(t *testing.T) {
+	d := testDaemon(t)
+
+	// Register two repos.
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "repo1"})
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "repo2"})
+
+	// Add local paths for each.
+	doRequest(t, d, "POST", "/repos/paths?repo=o/repo1", map[string]interface{}{
+		"local_path": "/home/user/repo1/main",
+	})
+	doRequest(t, d, "POST", "/repos/paths?repo=o/repo1", map[string]interface{}{
+		"local_path": "/home/user/repo1/feature-branch",
+	})
+	doRequest(t, d, "POST", "/repos/paths?repo=o/repo2", map[string]interface{}{
+		"local_path": "/home/user/repo2",
+	})
+
+	// Request from repo1 main worktree.
+	rr := doRequestWithHeader(t, d, "GET", "/issues", "X-Working-Dir", "/home/user/repo1/main", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list issues: expected 200, got %d: %s", rr.Code, rr.Body.String())
 	}
 
-	// List issues for repo2.
-	rr = doRequestWithHeader(t, d, "GET", "/issues", "X-Repo", "org2/repo2", nil)
+	// Request from repo1 feature-branch worktree subdirectory.
+	rr = doRequestWithHeader(t, d, "GET", "/issues", "X-Working-Dir", "/home/user/repo1/feature-branch/src", nil)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("list with X-Repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("list issues from subdir: expected 200, got %d: %s", rr.Code, rr.Body.String())
 	}
 
-	var issues []*model.Issue
-	decodeJSON(t, rr, &issues)
-	if len(issues) != 1 {
-		t.Fatalf("expected 1 issue in repo2, got %d", len(issues))
+	// Request from repo2.
+	rr = doRequestWithHeader(t, d, "GET", "/issues", "X-Working-Dir", "/home/user/repo2", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list issues repo2: expected 200, got %d: %s", rr.Code, rr.Body.String())
 	}
 }
 
-func TestRepoResolutionSingleRepoImplicit(t *testing.T) {
+func TestAddRepoPathCreatesSocket(t *testing.T) {
 	d := testDaemon(t)
+	tmpDir := t.TempDir()
 
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "only", "name": "repo"})
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	// No repo specified; should implicitly use the single registered repo.
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title": "Implicit repo",
+	rr := doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{
+		"local_path":     tmpDir,
+		"socket_enabled": true,
 	})
-	if rr.Code != http.StatusCreated {
-		t.Fatalf("create (implicit repo): expected 201, got %d: %s", rr.Code, rr.Body.String())
+	if rr.Code != http.StatusOK {
+		t.Fatalf("add repo path: expected 200, got %d: %s", rr.Code, rr.Body.String())
 	}
-}
-
-func TestRepoResolutionMultiRepoAmbiguous(t *testing.T) {
-	d := testDaemon(t)
 
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "a", "name": "1"})
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "b", "name": "2"})
+	// Check that a socket was created.
+	d.socketMu.Lock()
+	sockCount := len(d.socketLns)
+	d.socketMu.Unlock()
 
-	// No repo specified; should return 400.
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title": "Ambiguous",
-	})
-	if rr.Code != http.StatusBadRequest {
-		t.Fatalf("ambiguous repo: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	if sockCount == 0 {
+		t.Error("expected at least one socket listener to be created")
 	}
 }
 
-func TestInvalidJSONBody(t *testing.T) {
+func TestFieldHistoryEndpoint(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	// Send malformed JSON.
-	req := httptest.NewRequest("POST", "/issues", bytes.NewBufferString("{invalid"))
-	req.Header.Set("Content-Type", "application/json")
-	rr := httptest.NewRecorder()
-	d.Handler().ServeHTTP(rr, req)
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Prioritize me", "priority": 2})
+	var issue model.Issue
+	decodeJSON(t, rr, &issue)
+	id := itoa(issue.ID)
+
+	doRequest(t, d, "PATCH", "/issues/"+id, map[string]interface{}{"priority": 0})
+	doRequest(t, d, "PATCH", "/issues/"+id, map[string]interface{}{"title": "Renamed"})
+
+	rr = doRequest(t, d, "GET", "/issues/"+id+"/field-history?field=priority", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("field history: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Field   string `json:"field"`
+		History []struct {
+			Value float64 `json:"value"`
+		} `json:"history"`
+	}
+	decodeJSON(t, rr, &resp)
+	if resp.Field != "priority" || len(resp.History) != 2 {
+		t.Fatalf("expected 2 priority entries, got %+v", resp)
+	}
+	if resp.History[0].Value != 2 || resp.History[1].Value != 0 {
+		t.Errorf("expected priority 2 then 0, got %+v", resp.History)
+	}
 
+	rr = doRequest(t, d, "GET", "/issues/"+id+"/field-history?field=description", nil)
 	if rr.Code != http.StatusBadRequest {
-		t.Fatalf("invalid JSON: expected 400, got %d: %s", rr.Code, rr.Body.String())
+		t.Errorf("unsupported field: expected 400, got %d", rr.Code)
+	}
+	rr = doRequest(t, d, "GET", "/issues/9999/field-history?field=status", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("missing issue: expected 404, got %d", rr.Code)
 	}
 }
 
-func TestIssueNotFound(t *testing.T) {
+func TestIssueTimeline(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	rr := doRequest(t, d, "GET", "/issues/99999", nil)
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Show me"})
+	var issue model.Issue
+	decodeJSON(t, rr, &issue)
+	id := itoa(issue.ID)
+
+	doRequest(t, d, "POST", "/issues/"+id+"/assign", map[string]string{"owner": "alice"})
+	doRequest(t, d, "POST", "/issues/"+id+"/comment", map[string]string{"comment": "on it"})
+	doRequest(t, d, "POST", "/issues/"+id+"/labels", map[string]string{"label": "bug"})
+
+	rr = doRequest(t, d, "GET", "/issues/"+id+"/events", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("events: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var events []struct {
+		ID     int          `json:"id"`
+		Action model.Action `json:"action"`
+		Synced int          `json:"synced"`
+		Text   string       `json:"text"`
+	}
+	decodeJSON(t, rr, &events)
+	wantActions := []model.Action{model.ActionCreate, model.ActionAssign, model.ActionComment, model.ActionLabelAdd}
+	if len(events) != len(wantActions) {
+		t.Fatalf("expected %d events, got %+v", len(wantActions), events)
+	}
+	for i, ev := range events {
+		if ev.Action != wantActions[i] {
+			t.Errorf("event %d: expected %s, got %s", i, wantActions[i], ev.Action)
+		}
+		if i > 0 && ev.ID <= events[i-1].ID {
+			t.Errorf("events not in id order: %d after %d", ev.ID, events[i-1].ID)
+		}
+	}
+	if !strings.HasPrefix(events[0].Text, "**Created**: Show me") || !strings.HasPrefix(events[1].Text, "**Assigned** to alice") {
+		t.Errorf("unexpected text: %q, %q", events[0].Text, events[1].Text)
+	}
+
+	rr = doRequest(t, d, "GET", "/issues/9999/events", nil)
 	if rr.Code != http.StatusNotFound {
-		t.Fatalf("not found: expected 404, got %d: %s", rr.Code, rr.Body.String())
+		t.Errorf("missing issue: expected 404, got %d", rr.Code)
 	}
 }
 
-func TestForceSyncStub(t *testing.T) {
+func TestUpdatePriorityOnlyEmitsPriorityChange(t *testing.T) {
 	d := testDaemon(t)
-
-	rr := doRequest(t, d, "POST", "/sync", nil)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("sync: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Triage", "priority": 3})
+	var issue model.Issue
+	decodeJSON(t, rr, &issue)
+	path := "/issues/" + itoa(issue.ID)
+
+	rr = doRequest(t, d, "PATCH", path, map[string]interface{}{"priority": 1, "comment": "customer escalation"})
+	decodeJSON(t, rr, &issue)
+	if issue.Priority != 1 {
+		t.Fatalf("expected priority 1, got %d", issue.Priority)
+	}
+	events, _ := d.store.ListEvents(context.Background(), issue.RepoID, issue.ID)
+	last := events[len(events)-1]
+	var payload model.EventPayload
+	json.Unmarshal([]byte(last.Payload), &payload)
+	if last.Action != model.ActionPriorityChange || payload.FromPriority == nil || *payload.FromPriority != 3 ||
+		payload.Comment != "customer escalation" {
+		t.Errorf("expected priority_change from 3 with comment, got %s %s", last.Action, last.Payload)
 	}
 
-	var resp map[string]string
-	decodeJSON(t, rr, &resp)
-	if resp["status"] != "sync not yet implemented" {
-		t.Errorf("unexpected sync response: %v", resp)
+	// Changing priority with another field stays a single update event.
+	doRequest(t, d, "PATCH", path, map[string]interface{}{"priority": 2, "title": "Triaged"})
+	events, _ = d.store.ListEvents(context.Background(), issue.RepoID, issue.ID)
+	if last := events[len(events)-1]; last.Action != model.ActionUpdate {
+		t.Errorf("priority with title: expected update event, got %s", last.Action)
 	}
 }
 
-func TestHealthEndpointWithRepos(t *testing.T) {
+func TestPendingEventsOldestFirstWithTitles(t *testing.T) {
 	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org", "name": "repo1"})
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "org", "name": "repo2"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "First"})
+	var first model.Issue
+	decodeJSON(t, rr, &first)
+	doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Second"})
+	doRequest(t, d, "PATCH", "/issues/"+itoa(first.ID), map[string]interface{}{"priority": 1})
 
-	rr := doRequest(t, d, "GET", "/health", nil)
+	rr = doRequest(t, d, "GET", "/events/pending?repo=o/r", nil)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("health: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("pending: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Repo   string `json:"repo"`
+		Events []struct {
+			ID         int    `json:"id"`
+			IssueID    int    `json:"issue_id"`
+			IssueTitle string `json:"issue_title"`
+			Action     string `json:"action"`
+		} `json:"events"`
 	}
-
-	var resp map[string]interface{}
 	decodeJSON(t, rr, &resp)
 
-	repos, ok := resp["repos"].([]interface{})
-	if !ok {
-		t.Fatalf("expected repos array, got %T", resp["repos"])
+	if resp.Repo != "o/r" {
+		t.Errorf("repo: want o/r, got %s", resp.Repo)
 	}
-	if len(repos) != 2 {
-		t.Errorf("expected 2 repos in health, got %d", len(repos))
+	if len(resp.Events) != 3 {
+		t.Fatalf("expected 3 pending events, got %d", len(resp.Events))
 	}
-}
-
-func TestCreateIssueRequiresTitle(t *testing.T) {
-	d := testDaemon(t)
-
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
-
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"description": "no title",
-	})
-	if rr.Code != http.StatusBadRequest {
-		t.Fatalf("missing title: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	var got []string
+	for i, ev := range resp.Events {
+		got = append(got, ev.IssueTitle+":"+ev.Action)
+		if i > 0 && ev.ID < resp.Events[i-1].ID {
+			t.Errorf("events not oldest first: %d after %d", ev.ID, resp.Events[i-1].ID)
+		}
+	}
+	if strings.Join(got, ",") != "First:create,Second:create,First:priority_change" {
+		t.Errorf("unexpected pending events: %v", got)
 	}
 }
 
-func TestDeleteIssueNotFound(t *testing.T) {
+func TestIssueStats(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	rr := doRequest(t, d, "DELETE", "/issues/99999", nil)
-	if rr.Code != http.StatusNotFound {
-		t.Fatalf("delete not found: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Bug", "issue_type": "bug"})
+	var bug model.Issue
+	decodeJSON(t, rr, &bug)
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Task"})
+	var task model.Issue
+	decodeJSON(t, rr, &task)
+	doRequest(t, d, "POST", "/issues/"+itoa(task.ID)+"/close", nil)
+	doRequest(t, d, "DELETE", "/issues/"+itoa(task.ID), nil)
+
+	rr = doRequest(t, d, "GET", "/stats?repo=o/r", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("stats: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Repo          string         `json:"repo"`
+		ByStatus      map[string]int `json:"by_status"`
+		ByType        map[string]int `json:"by_type"`
+		Open          int            `json:"open"`
+		Closed        int            `json:"closed"`
+		PendingEvents int            `json:"pending_events"`
+		OldestOpenID  int            `json:"oldest_open_id"`
+	}
+	decodeJSON(t, rr, &resp)
+	if resp.Repo != "o/r" || resp.Open != 1 || resp.OldestOpenID != bug.ID {
+		t.Errorf("unexpected stats: %+v", resp)
+	}
+	if resp.ByType["bug"] != 1 || resp.ByType["task"] != 0 {
+		t.Errorf("by_type should skip the deleted task: %v", resp.ByType)
+	}
+	if resp.PendingEvents == 0 {
+		t.Error("expected pending events from the unsynced creates")
 	}
 }
 
-func TestAssignIssueNotFound(t *testing.T) {
+func TestSyncLogWithoutSyncManager(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	rr := doRequest(t, d, "POST", "/issues/99999/assign", map[string]string{
-		"owner": "alice",
-	})
-	if rr.Code != http.StatusNotFound {
-		t.Fatalf("assign not found: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	rr := doRequest(t, d, "GET", "/sync/log", nil)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", rr.Code, rr.Body.String())
 	}
 }
 
-func TestUpdateIssueNotFound(t *testing.T) {
+func TestSyncActiveAndCancelWithoutSyncManager(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	rr := doRequest(t, d, "PATCH", "/issues/99999", map[string]interface{}{
-		"title": "nope",
-	})
-	if rr.Code != http.StatusNotFound {
-		t.Fatalf("update not found: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	if rr := doRequest(t, d, "GET", "/sync/active", nil); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("sync active: expected 503, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := doRequest(t, d, "POST", "/sync/cancel", nil); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("sync cancel: expected 503, got %d: %s", rr.Code, rr.Body.String())
 	}
 }
 
-// itoa is a convenience for tests.
-func itoa(i int) string {
-	return fmt.Sprintf("%d", i)
-}
-
-// ---------------------------------------------------------------------------
-// noopGitHubClient implements github.Client for wiring tests.
-// ---------------------------------------------------------------------------
-
-type noopGitHubClient struct{}
-
-func (noopGitHubClient) ListIssues(ctx context.Context, owner, repo string, opts github.ListOpts) ([]*github.GitHubIssue, string, error) {
-	return nil, "", nil
-}
-func (noopGitHubClient) GetIssue(ctx context.Context, owner, repo string, number int) (*github.GitHubIssue, error) {
-	return nil, fmt.Errorf("not implemented")
-}
-func (noopGitHubClient) CreateIssue(ctx context.Context, owner, repo, title, body string, labels []string) (*github.GitHubIssue, error) {
-	return nil, fmt.Errorf("not implemented")
-}
-func (noopGitHubClient) UpdateIssueBody(ctx context.Context, owner, repo string, number int, body string) error {
-	return fmt.Errorf("not implemented")
-}
-func (noopGitHubClient) ListComments(ctx context.Context, owner, repo string, number int, opts github.ListOpts) ([]*github.GitHubComment, string, error) {
-	return nil, "", nil
-}
-func (noopGitHubClient) CreateComment(ctx context.Context, owner, repo string, number int, body string) (*github.GitHubComment, error) {
-	return nil, fmt.Errorf("not implemented")
-}
-func (noopGitHubClient) AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) error {
-	return nil
-}
-func (noopGitHubClient) CreateLabel(ctx context.Context, owner, repo, name, color, description string) error {
-	return nil
-}
-func (noopGitHubClient) UpdateIssueState(ctx context.Context, owner, repo string, number int, state string) error {
-	return nil
-}
-func (noopGitHubClient) GetRepo(ctx context.Context, owner, repo string) (*github.GitHubRepo, error) {
-	return &github.GitHubRepo{Private: true}, nil
-}
-func (noopGitHubClient) GetRateLimit() github.RateLimit {
-	return github.RateLimit{Remaining: 5000, Reset: time.Now().Add(time.Hour)}
-}
-
-func TestAddRepoStartsSyncer(t *testing.T) {
+func TestSyncActiveListsNoneWhenIdle(t *testing.T) {
 	s, err := store.NewSQLiteStore(":memory:")
 	if err != nil {
 		t.Fatalf("create store: %v", err)
@@ -650,592 +3669,844 @@ func TestAddRepoStartsSyncer(t *testing.T) {
 	}
 
 	sm := borSync.NewSyncManager(s, noopGitHubClient{})
-
 	d := NewWithStoreAndSync(cfg, s, sm)
-
-	rr := doRequest(t, d, "POST", "/repos", map[string]string{
-		"owner": "testorg",
-		"name":  "testrepo",
-	})
-	if rr.Code != http.StatusCreated {
-		t.Fatalf("create repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
-	}
-
-	// AddRepo adds the syncer to the map synchronously (under lock) before
-	// spawning the goroutine, so Status() can see it immediately.
-	status := sm.Status()
-	if len(status) != 1 {
-		t.Fatalf("expected 1 repo in sync status, got %d", len(status))
-	}
-	for _, st := range status {
-		if st.RepoName != "testorg/testrepo" {
-			t.Errorf("expected repo name 'testorg/testrepo', got %q", st.RepoName)
-		}
-	}
-
-	// Stop the sync manager before closing the store to ensure the syncer
-	// goroutine has exited and won't race with store.Close().
-	sm.Stop()
-	s.Close()
-}
-
-func TestUpdateIssueStatusToBlocked(t *testing.T) {
-	d := testDaemon(t)
-
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title": "Blocked Test",
+	t.Cleanup(func() {
+		sm.Stop()
+		s.Close()
 	})
-	var iss model.Issue
-	decodeJSON(t, rr, &iss)
 
-	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{
-		"status": "blocked",
-	})
+	rr := doRequest(t, d, "GET", "/sync/active", nil)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("update to blocked: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("sync active: expected 200, got %d: %s", rr.Code, rr.Body.String())
 	}
-
-	var updated model.Issue
-	decodeJSON(t, rr, &updated)
-	if updated.Status != model.StatusBlocked {
-		t.Errorf("expected blocked, got %q", updated.Status)
+	var resp struct {
+		Active []borSync.ActiveSync `json:"active"`
 	}
-}
-
-func TestUpdateIssueStatusToInReview(t *testing.T) {
-	d := testDaemon(t)
-
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title": "InReview Test",
-	})
-	var iss model.Issue
-	decodeJSON(t, rr, &iss)
-
-	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{
-		"status": "in_review",
-	})
-	if rr.Code != http.StatusOK {
-		t.Fatalf("update to in_review: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	decodeJSON(t, rr, &resp)
+	if resp.Active == nil || len(resp.Active) != 0 {
+		t.Errorf("expected empty active list, got %+v", resp.Active)
 	}
 
-	var updated model.Issue
-	decodeJSON(t, rr, &updated)
-	if updated.Status != model.StatusInReview {
-		t.Errorf("expected in_review, got %q", updated.Status)
+	// Cancelling a repo the sync manager doesn't know about is a 404.
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	sm.RemoveRepo(1)
+	rr = doRequest(t, d, "POST", "/sync/cancel?repo=o/r", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("sync cancel: expected 404, got %d: %s", rr.Code, rr.Body.String())
 	}
 }
 
-func TestCreateIssueWithEpicType(t *testing.T) {
-	d := testDaemon(t)
+// gatedGitHubClient holds every ListIssues call until release is closed,
+// so a sync cycle cannot finish before the test allows it.
+type gatedGitHubClient struct {
+	noopGitHubClient
+	release chan struct{}
+}
 
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title":      "Epic Test",
-		"issue_type": "epic",
-	})
-	if rr.Code != http.StatusCreated {
-		t.Fatalf("create epic: expected 201, got %d: %s", rr.Code, rr.Body.String())
+func (c gatedGitHubClient) ListIssues(ctx context.Context, owner, repo string, opts github.ListOpts) ([]*github.GitHubIssue, string, error) {
+	select {
+	case <-c.release:
+		return nil, "", nil
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
 	}
+}
 
-	var iss model.Issue
-	decodeJSON(t, rr, &iss)
-	if iss.IssueType != model.IssueTypeEpic {
-		t.Errorf("expected epic, got %q", iss.IssueType)
+func TestReady(t *testing.T) {
+	// The syncer and the handlers query concurrently, and every connection
+	// to ":memory:" opens its own empty database, so use a file.
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "bor.db")
+	s, err := store.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("create store: %v", err)
 	}
-}
+	gh := gatedGitHubClient{release: make(chan struct{})}
+	sm := borSync.NewSyncManager(s, gh)
+	d := NewWithStoreAndSync(&config.Config{ListenAddr: ":0", DataDir: dir, DBPath: dbPath}, s, sm)
+	t.Cleanup(func() {
+		sm.Stop()
+		s.Close()
+	})
 
-func TestCommentIssue(t *testing.T) {
-	d := testDaemon(t)
+	// With no repos there is nothing to wait for.
+	if rr := doRequest(t, d, "GET", "/ready", nil); rr.Code != http.StatusOK {
+		t.Fatalf("ready with no repos: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
 
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title": "Comment Test",
-	})
-	var iss model.Issue
-	decodeJSON(t, rr, &iss)
-
-	// Add a comment.
-	rr = doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/comment", map[string]string{
-		"comment": "This is a test comment",
-	})
-	if rr.Code != http.StatusCreated {
-		t.Fatalf("comment: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	rr := doRequest(t, d, "GET", "/ready", nil)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("ready before first sync: expected 503, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Status  string   `json:"status"`
+		Waiting []string `json:"waiting"`
+	}
+	decodeJSON(t, rr, &resp)
+	if resp.Status != "not_ready" || len(resp.Waiting) != 1 || resp.Waiting[0] != "o/r" {
+		t.Errorf("unexpected not-ready body: %s", rr.Body.String())
+	}
+	// Liveness does not wait for sync.
+	if rr := doRequest(t, d, "GET", "/health", nil); rr.Code != http.StatusOK {
+		t.Errorf("health before first sync: expected 200, got %d", rr.Code)
 	}
 
-	var commented model.Issue
-	decodeJSON(t, rr, &commented)
-	if len(commented.Comments) != 1 {
-		t.Fatalf("expected 1 comment, got %d", len(commented.Comments))
+	close(gh.release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rr = doRequest(t, d, "GET", "/ready", nil)
+		if rr.Code == http.StatusOK || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
 	}
-	if commented.Comments[0].Text != "This is a test comment" {
-		t.Errorf("expected comment text 'This is a test comment', got %q", commented.Comments[0].Text)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("ready after first sync: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if st := sm.Status()[1]; st == nil || st.FirstSuccessAt == nil {
+		t.Errorf("expected first_success_at in sync status, got %+v", st)
+	}
+
+	s.Close()
+	if rr := doRequest(t, d, "GET", "/ready", nil); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("ready with a closed database: expected 503, got %d", rr.Code)
 	}
 }
 
-func TestCommentIssueNotFound(t *testing.T) {
-	d := testDaemon(t)
+func TestSyncLogReportsCycles(t *testing.T) {
+	s, err := store.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
 
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	cfg := &config.Config{
+		ListenAddr: ":0",
+		DataDir:    t.TempDir(),
+		DBPath:     ":memory:",
+	}
 
-	rr := doRequest(t, d, "POST", "/issues/99999/comment", map[string]string{
-		"comment": "test",
+	sm := borSync.NewSyncManager(s, noopGitHubClient{})
+	d := NewWithStoreAndSync(cfg, s, sm)
+	t.Cleanup(func() {
+		sm.Stop()
+		s.Close()
 	})
-	if rr.Code != http.StatusNotFound {
-		t.Fatalf("comment not found: expected 404, got %d: %s", rr.Code, rr.Body.String())
-	}
-}
 
-func TestCommentIssueEmptyComment(t *testing.T) {
-	d := testDaemon(t)
+	rr := doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
 
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title": "Empty Comment Test",
-	})
-	var iss model.Issue
-	decodeJSON(t, rr, &iss)
+	// The syncer runs an initial cycle immediately after starting.
+	var resp struct {
+		Repo   string                `json:"repo"`
+		Cycles []borSync.CycleResult `json:"cycles"`
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		rr = doRequest(t, d, "GET", "/sync/log?repo=o/r", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("sync log: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		decodeJSON(t, rr, &resp)
+		if len(resp.Cycles) > 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
 
-	rr = doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/comment", map[string]string{
-		"comment": "",
-	})
-	if rr.Code != http.StatusBadRequest {
-		t.Fatalf("empty comment: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	if resp.Repo != "o/r" {
+		t.Errorf("expected repo o/r, got %q", resp.Repo)
+	}
+	if len(resp.Cycles) == 0 {
+		t.Fatal("expected at least one recorded cycle")
+	}
+	if resp.Cycles[0].StartedAt.IsZero() {
+		t.Error("expected cycle start time to be set")
 	}
 }
 
-func TestUpdateIssueWithComment(t *testing.T) {
+func TestSnoozeIssue(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title": "Update Comment Test",
-	})
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Later"})
 	var iss model.Issue
 	decodeJSON(t, rr, &iss)
 
-	// Update with a comment.
-	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{
-		"title":   "New Title",
-		"comment": "Changed the title",
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	rr = doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/snooze", map[string]interface{}{
+		"until": until,
 	})
 	if rr.Code != http.StatusOK {
-		t.Fatalf("update with comment: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("snooze: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &iss)
+	if iss.SnoozedUntil == nil || !iss.SnoozedUntil.Equal(until) {
+		t.Fatalf("expected snoozed_until %v, got %v", until, iss.SnoozedUntil)
 	}
 
-	var updated model.Issue
-	decodeJSON(t, rr, &updated)
-	if updated.Title != "New Title" {
-		t.Errorf("expected title 'New Title', got %q", updated.Title)
+	rr = doRequest(t, d, "GET", "/issues/next", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("next: expected 404 while snoozed, got %d", rr.Code)
 	}
-	if len(updated.Comments) != 1 {
-		t.Fatalf("expected 1 comment, got %d", len(updated.Comments))
+
+	var list []model.Issue
+	decodeJSON(t, doRequest(t, d, "GET", "/issues", nil), &list)
+	if len(list) != 0 {
+		t.Errorf("expected snoozed issue hidden from list, got %d", len(list))
 	}
-	if updated.Comments[0].Text != "Changed the title" {
-		t.Errorf("expected comment text 'Changed the title', got %q", updated.Comments[0].Text)
+	decodeJSON(t, doRequest(t, d, "GET", "/issues?include_snoozed=true", nil), &list)
+	if len(list) != 1 {
+		t.Errorf("expected snoozed issue with include_snoozed, got %d", len(list))
+	}
+
+	// Clearing the snooze makes it available again.
+	rr = doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/snooze", map[string]interface{}{"until": nil})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unsnooze: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = doRequest(t, d, "GET", "/issues/next", nil)
+	if rr.Code != http.StatusOK {
+		t.Errorf("next: expected 200 after unsnooze, got %d", rr.Code)
 	}
 }
 
-func TestUpdateIssueCommentOnly(t *testing.T) {
+func TestSnoozeIssueRejectsPastTime(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
-	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{
-		"title": "Comment Only Update",
-	})
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Past"})
 	var iss model.Issue
 	decodeJSON(t, rr, &iss)
 
-	// Update with only a comment (no field changes).
-	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{
-		"comment": "Just a note",
+	rr = doRequest(t, d, "POST", "/issues/"+itoa(iss.ID)+"/snooze", map[string]interface{}{
+		"until": time.Now().Add(-time.Hour),
 	})
-	if rr.Code != http.StatusOK {
-		t.Fatalf("comment-only update: expected 200, got %d: %s", rr.Code, rr.Body.String())
-	}
-
-	var updated model.Issue
-	decodeJSON(t, rr, &updated)
-	if len(updated.Comments) != 1 {
-		t.Fatalf("expected 1 comment, got %d", len(updated.Comments))
-	}
-	if updated.Comments[0].Text != "Just a note" {
-		t.Errorf("expected comment text 'Just a note', got %q", updated.Comments[0].Text)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", rr.Code, rr.Body.String())
 	}
 }
 
-func TestServeUI(t *testing.T) {
+func TestChangedIssuesIncludesDeleted(t *testing.T) {
 	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	rr := doRequest(t, d, "GET", "/", nil)
+	since := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Kept"})
+	var kept model.Issue
+	decodeJSON(t, rr, &kept)
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Removed"})
+	var removed model.Issue
+	decodeJSON(t, rr, &removed)
+	doRequest(t, d, "DELETE", "/issues/"+itoa(removed.ID), nil)
+
+	rr = doRequest(t, d, "GET", "/issues/changed?since="+since, nil)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("GET /: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("changed: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var changed []model.Issue
+	decodeJSON(t, rr, &changed)
+	if len(changed) != 2 {
+		t.Fatalf("expected 2 changed issues, got %d", len(changed))
+	}
+	for _, iss := range changed {
+		if iss.ID == removed.ID && iss.Status != model.StatusDeleted {
+			t.Errorf("expected removed issue to be reported as deleted, got %q", iss.Status)
+		}
 	}
 
-	ct := rr.Header().Get("Content-Type")
-	if ct != "text/html; charset=utf-8" {
-		t.Errorf("expected Content-Type text/html; charset=utf-8, got %q", ct)
+	future := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	rr = doRequest(t, d, "GET", "/issues/changed?since="+future, nil)
+	decodeJSON(t, rr, &changed)
+	if len(changed) != 0 {
+		t.Errorf("expected no issues changed after %s, got %d", future, len(changed))
 	}
 
-	body := rr.Body.String()
-	if !strings.Contains(body, "<title>Box of Rocks</title>") {
-		t.Error("expected response to contain <title>Box of Rocks</title>")
+	for _, q := range []string{"", "?since=yesterday"} {
+		rr = doRequest(t, d, "GET", "/issues/changed"+q, nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("GET /issues/changed%s: expected 400, got %d", q, rr.Code)
+		}
 	}
 }
 
-func TestAddRepoWithoutSyncManager(t *testing.T) {
-	d := testDaemon(t) // syncMgr is nil
+func TestIssueEvents(t *testing.T) {
+	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Watched"})
+	var issue model.Issue
+	decodeJSON(t, rr, &issue)
 
-	rr := doRequest(t, d, "POST", "/repos", map[string]string{
-		"owner": "testorg",
-		"name":  "testrepo",
-	})
-	if rr.Code != http.StatusCreated {
-		t.Fatalf("create repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
-	}
+	ts := httptest.NewServer(d.Handler())
+	defer ts.Close()
 
-	var repo model.RepoConfig
-	decodeJSON(t, rr, &repo)
-	if repo.Owner != "testorg" || repo.Name != "testrepo" {
-		t.Errorf("unexpected repo: %+v", repo)
+	resp, err := http.Get(ts.URL + "/issues/events?repo=o/r")
+	if err != nil {
+		t.Fatalf("GET /issues/events: %v", err)
 	}
-}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream, got %q", ct)
+	}
+	br := bufio.NewReader(resp.Body)
+	readLine := func() string {
+		t.Helper()
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		return strings.TrimSpace(line)
+	}
+	if line := readLine(); line != ": connected" {
+		t.Fatalf("expected connected comment, got %q", line)
+	}
+	readLine()
 
-func TestAddRepoWithSocketFields(t *testing.T) {
-	d := testDaemon(t)
+	doRequest(t, d, "PATCH", "/issues/"+itoa(issue.ID), map[string]interface{}{"status": "in_progress"})
 
-	rr := doRequest(t, d, "POST", "/repos", map[string]interface{}{
-		"owner":      "testorg",
-		"name":       "testrepo",
-		"local_path": "/tmp/testrepo",
-		"socket":     true,
-	})
-	if rr.Code != http.StatusCreated {
-		t.Fatalf("create repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	line := readLine()
+	data, ok := strings.CutPrefix(line, "data: ")
+	if !ok {
+		t.Fatalf("expected data line, got %q", line)
+	}
+	var ev issueEvent
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+	if ev.Action != model.ActionStatusChange || ev.Issue.ID != issue.ID || ev.Issue.Status != model.StatusInProgress {
+		t.Errorf("unexpected event: action=%q issue=%d status=%q", ev.Action, ev.Issue.ID, ev.Issue.Status)
 	}
 
-	var repo model.RepoConfig
-	decodeJSON(t, rr, &repo)
-	if repo.LocalPath != "/tmp/testrepo" {
-		t.Errorf("expected local_path '/tmp/testrepo', got %q", repo.LocalPath)
+	// Closing the feed, as Shutdown does, ends the stream.
+	d.issueFeed.close()
+	readLine()
+	if _, err := br.ReadString('\n'); err == nil {
+		t.Error("expected stream to end after the feed closed")
 	}
-	if !repo.SocketEnabled {
-		t.Error("expected socket_enabled=true")
+
+	rr = doRequest(t, d, "GET", "/issues/events?repo=o/r", nil)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 after close, got %d", rr.Code)
 	}
 }
 
-func TestRepoResolutionWorkingDir(t *testing.T) {
+func TestIssueEventsOutlivesWriteTimeout(t *testing.T) {
 	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Watched"})
+	var issue model.Issue
+	decodeJSON(t, rr, &issue)
 
-	// Register two repos with different local paths.
-	doRequest(t, d, "POST", "/repos", map[string]interface{}{
-		"owner": "org1", "name": "repo1",
-		"local_path": "/home/user/projects/repo1", "socket": false,
-	})
-	doRequest(t, d, "POST", "/repos", map[string]interface{}{
-		"owner": "org2", "name": "repo2",
-		"local_path": "/home/user/projects/repo2", "socket": false,
-	})
-
-	// Create an issue with explicit repo param so we have something to list.
-	doRequest(t, d, "POST", "/issues?repo=org1/repo1", map[string]interface{}{
-		"title": "issue in repo1",
-	})
-	doRequest(t, d, "POST", "/issues?repo=org2/repo2", map[string]interface{}{
-		"title": "issue in repo2",
-	})
+	ts := httptest.NewUnstartedServer(d.Handler())
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
 
-	// List issues using X-Working-Dir matching repo1's local path.
-	rr := doRequestWithHeader(t, d, "GET", "/issues", "X-Working-Dir", "/home/user/projects/repo1", nil)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("list with working dir: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	resp, err := http.Get(ts.URL + "/issues/events?repo=o/r")
+	if err != nil {
+		t.Fatalf("GET /issues/events: %v", err)
 	}
-	var issues []*model.Issue
-	decodeJSON(t, rr, &issues)
-	if len(issues) != 1 || issues[0].Title != "issue in repo1" {
-		t.Errorf("expected 1 issue in repo1, got %d", len(issues))
+	defer resp.Body.Close()
+	br := bufio.NewReader(resp.Body)
+	for range 2 {
+		if _, err := br.ReadString('\n'); err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
 	}
 
-	// Subdirectory should also resolve to repo2.
-	rr = doRequestWithHeader(t, d, "GET", "/issues", "X-Working-Dir", "/home/user/projects/repo2/src/pkg", nil)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("list with subdir: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	time.Sleep(3 * ts.Config.WriteTimeout)
+	doRequest(t, d, "PATCH", "/issues/"+itoa(issue.ID), map[string]interface{}{"status": "in_progress"})
+
+	line, err := br.ReadString('\n')
+	if err != nil {
+		t.Fatalf("stream ended after the write timeout: %v", err)
 	}
-	decodeJSON(t, rr, &issues)
-	if len(issues) != 1 || issues[0].Title != "issue in repo2" {
-		t.Errorf("expected 1 issue in repo2, got %d", len(issues))
+	if !strings.HasPrefix(line, "data: ") {
+		t.Errorf("expected data line, got %q", line)
 	}
+	d.issueFeed.close()
 }
 
-func TestUpdateRepoSocketEnabled(t *testing.T) {
-	d := testDaemon(t)
+// slowListStore delays ListIssues, so GET /issues stands in for a slow
+// request.
+type slowListStore struct {
+	store.Store
+	entered chan struct{}
+	delay   time.Duration
+}
 
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+func (s *slowListStore) ListIssues(ctx context.Context, filter store.IssueFilter) ([]*model.Issue, error) {
+	select {
+	case s.entered <- struct{}{}:
+	default:
+	}
+	time.Sleep(s.delay)
+	return s.Store.ListIssues(ctx, filter)
+}
 
-	// Enable socket.
-	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
-		"local_path":     "/tmp/repo",
-		"socket_enabled": true,
-	})
-	if rr.Code != http.StatusOK {
-		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+func TestShutdownDrainsInFlightRequest(t *testing.T) {
+	inner, err := store.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("create in-memory store: %v", err)
+	}
+	slow := &slowListStore{Store: inner, entered: make(chan struct{}, 1), delay: 300 * time.Millisecond}
+	if _, err := slow.AddRepo(context.Background(), "o", "r"); err != nil {
+		t.Fatalf("add repo: %v", err)
 	}
 
-	var repo model.RepoConfig
-	decodeJSON(t, rr, &repo)
-	if !repo.SocketEnabled {
-		t.Error("expected socket_enabled=true")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("pick port: %v", err)
 	}
-	if repo.LocalPath != "/tmp/repo" {
-		t.Errorf("expected local_path '/tmp/repo', got %q", repo.LocalPath)
+	addr := ln.Addr().String()
+	ln.Close()
+	d := NewWithStore(&config.Config{ListenAddr: addr, DataDir: t.TempDir(), DBPath: ":memory:"}, slow)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- d.Run(ctx) }()
+
+	// Without keep-alives no spare connection sits unused, which
+	// http.Server.Shutdown would wait five seconds for.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	base := "http://" + addr
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := client.Get(base + "/health")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("daemon never came up: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
 	}
 
-	// Disable socket.
-	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
-		"socket_enabled": false,
-	})
-	if rr.Code != http.StatusOK {
-		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	type result struct {
+		status int
+		body   []byte
+		err    error
 	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := client.Get(base + "/issues?repo=o/r")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		done <- result{status: resp.StatusCode, body: body, err: err}
+	}()
 
-	decodeJSON(t, rr, &repo)
-	if repo.SocketEnabled {
-		t.Error("expected socket_enabled=false")
+	<-slow.entered
+	cancel()
+
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("in-flight request failed during shutdown: %v", res.err)
+	}
+	var issues []*model.Issue
+	if res.status != http.StatusOK || json.Unmarshal(res.body, &issues) != nil {
+		t.Errorf("expected the in-flight request to complete, got %d: %s", res.status, res.body)
+	}
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after shutdown")
+	}
+	if resp, err := client.Get(base + "/health"); err == nil {
+		resp.Body.Close()
+		t.Error("expected no new connections after shutdown")
 	}
 }
 
-func TestAddRepoWithQueueField(t *testing.T) {
+func TestPriorityBounds(t *testing.T) {
 	d := testDaemon(t)
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	rr := doRequest(t, d, "POST", "/repos", map[string]interface{}{
-		"owner":      "testorg",
-		"name":       "testrepo",
-		"local_path": "/tmp/testrepo",
-		"queue":      true,
-	})
-	if rr.Code != http.StatusCreated {
-		t.Fatalf("create repo: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	for _, p := range []int{0, 5} {
+		rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "ok", "priority": p})
+		if rr.Code != http.StatusCreated {
+			t.Errorf("create priority %d: expected 201, got %d: %s", p, rr.Code, rr.Body.String())
+		}
 	}
-
-	var repo model.RepoConfig
-	decodeJSON(t, rr, &repo)
-	if repo.LocalPath != "/tmp/testrepo" {
-		t.Errorf("expected local_path '/tmp/testrepo', got %q", repo.LocalPath)
+	for _, p := range []int{-1, 6} {
+		rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "bad", "priority": p})
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("create priority %d: expected 400, got %d", p, rr.Code)
+		}
 	}
-	if !repo.QueueEnabled {
-		t.Error("expected queue_enabled=true")
+
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "patch me", "priority": 2})
+	var iss model.Issue
+	decodeJSON(t, rr, &iss)
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{"priority": 6})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("update priority 6: expected 400, got %d", rr.Code)
 	}
-	if repo.SocketEnabled {
-		t.Error("expected socket_enabled=false when only queue was requested")
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(iss.ID), map[string]interface{}{"priority": 5})
+	if rr.Code != http.StatusOK {
+		t.Errorf("update priority 5: expected 200, got %d: %s", rr.Code, rr.Body.String())
 	}
 }
 
-func TestUpdateRepoQueueEnabled(t *testing.T) {
+func TestReorderIssues(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	// Enable queue.
-	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
-		"local_path":    "/tmp/repo",
-		"queue_enabled": true,
-	})
+	var ids []int
+	for _, p := range []int{0, 3, 5} {
+		rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "issue", "priority": p})
+		var iss model.Issue
+		decodeJSON(t, rr, &iss)
+		ids = append(ids, iss.ID)
+	}
+
+	// Reverse the order. Three issues spread over 0-5 get 0, 2 and 5.
+	order := []int{ids[2], ids[1], ids[0]}
+	rr := doRequest(t, d, "POST", "/issues/reorder", map[string]interface{}{"order": order})
 	if rr.Code != http.StatusOK {
-		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("reorder: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var issues []model.Issue
+	decodeJSON(t, rr, &issues)
+	want := map[int]int{ids[2]: 0, ids[1]: 2, ids[0]: 5}
+	for _, iss := range issues {
+		if iss.Priority != want[iss.ID] {
+			t.Errorf("issue %d: priority %d, want %d", iss.ID, iss.Priority, want[iss.ID])
+		}
 	}
 
-	var repo model.RepoConfig
-	decodeJSON(t, rr, &repo)
-	if !repo.QueueEnabled {
-		t.Error("expected queue_enabled=true")
+	// Every issue changed, so each has one priority_change event on top of
+	// its create.
+	for _, id := range ids {
+		events, _ := d.store.ListEvents(context.Background(), issues[0].RepoID, id)
+		if len(events) != 2 || events[1].Action != model.ActionPriorityChange {
+			t.Errorf("issue %d: expected create+priority_change events, got %d", id, len(events))
+		}
 	}
-	if repo.LocalPath != "/tmp/repo" {
-		t.Errorf("expected local_path '/tmp/repo', got %q", repo.LocalPath)
+
+	// Repeating the same order changes nothing and emits no events.
+	doRequest(t, d, "POST", "/issues/reorder", map[string]interface{}{"order": order})
+	events, _ := d.store.ListEvents(context.Background(), issues[0].RepoID, ids[1])
+	if len(events) != 2 {
+		t.Errorf("expected no new events for unchanged priority, got %d events", len(events))
 	}
 
-	// Disable queue.
-	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
-		"queue_enabled": false,
-	})
-	if rr.Code != http.StatusOK {
-		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	for _, body := range []map[string]interface{}{
+		{"order": []int{}},
+		{"order": []int{ids[0], ids[0]}},
+	} {
+		if rr := doRequest(t, d, "POST", "/issues/reorder", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("reorder %v: expected 400, got %d", body, rr.Code)
+		}
+	}
+	if rr := doRequest(t, d, "POST", "/issues/reorder", map[string]interface{}{"order": []int{9999}}); rr.Code != http.StatusNotFound {
+		t.Errorf("reorder unknown issue: expected 404, got %d", rr.Code)
 	}
+}
 
-	decodeJSON(t, rr, &repo)
-	if repo.QueueEnabled {
-		t.Error("expected queue_enabled=false")
+func TestReorderPriorities(t *testing.T) {
+	cases := []struct {
+		n    int
+		want string
+	}{
+		{1, "[0]"},
+		{2, "[0 5]"},
+		{3, "[0 2 5]"},
+		{6, "[0 1 2 3 4 5]"},
+		{8, "[0 0 1 2 2 3 4 5]"},
+	}
+	for _, tc := range cases {
+		if got := fmt.Sprint(reorderPriorities(tc.n, 0, 5)); got != tc.want {
+			t.Errorf("reorderPriorities(%d, 0, 5) = %s, want %s", tc.n, got, tc.want)
+		}
 	}
 }
 
-func TestUpdateRepoTrustedAuthorsOnly(t *testing.T) {
+func TestOwnerMeResolution(t *testing.T) {
 	d := testDaemon(t)
-
-	// Create a repo first.
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	// Enable trusted_authors_only.
-	rr := doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
-		"trusted_authors_only": true,
-	})
+	var ids []int
+	for _, title := range []string{"mine", "theirs", "mine too"} {
+		rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": title})
+		var iss model.Issue
+		decodeJSON(t, rr, &iss)
+		ids = append(ids, iss.ID)
+	}
+	doRequest(t, d, "POST", "/issues/"+itoa(ids[0])+"/assign", map[string]string{"owner": "agent-a"})
+	doRequest(t, d, "POST", "/issues/"+itoa(ids[1])+"/assign", map[string]string{"owner": "agent-b"})
+	doRequest(t, d, "POST", "/issues/"+itoa(ids[2])+"/assign", map[string]string{"owner": "agent-a"})
+	doRequest(t, d, "PATCH", "/issues/"+itoa(ids[2]), map[string]string{"status": "in_progress"})
+
+	rr := doRequestWithHeader(t, d, "GET", "/issues?owner=@me", AgentHeader, "agent-a", nil)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("list @me: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var mine []model.Issue
+	decodeJSON(t, rr, &mine)
+	if len(mine) != 2 {
+		t.Errorf("expected 2 issues for agent-a, got %d", len(mine))
 	}
 
-	var repo model.RepoConfig
-	decodeJSON(t, rr, &repo)
-	if !repo.TrustedAuthorsOnly {
-		t.Error("expected trusted_authors_only=true")
+	// next?owner=@me resumes in-progress work first.
+	rr = doRequestWithHeader(t, d, "GET", "/issues/next?owner=@me", AgentHeader, "agent-a", nil)
+	var next model.Issue
+	decodeJSON(t, rr, &next)
+	if next.ID != ids[2] {
+		t.Errorf("expected in-progress issue %d, got %d", ids[2], next.ID)
 	}
 
-	// Disable it.
-	rr = doRequest(t, d, "PATCH", "/repos?repo=o/r", map[string]interface{}{
-		"trusted_authors_only": false,
-	})
-	if rr.Code != http.StatusOK {
-		t.Fatalf("update repo: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	// Without a header or configured identity, @me cannot be resolved.
+	if rr := doRequest(t, d, "GET", "/issues?owner=@me", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("@me without identity: expected 400, got %d", rr.Code)
 	}
 
-	decodeJSON(t, rr, &repo)
-	if repo.TrustedAuthorsOnly {
-		t.Error("expected trusted_authors_only=false")
+	d.cfg.Identity = "agent-b"
+	rr = doRequest(t, d, "GET", "/issues?owner=@me", nil)
+	decodeJSON(t, rr, &mine)
+	if len(mine) != 1 || mine[0].ID != ids[1] {
+		t.Errorf("expected configured identity to resolve to agent-b's issue, got %+v", mine)
 	}
-}
 
-// ---------------------------------------------------------------------------
-// Repo local paths (worktree support)
-// ---------------------------------------------------------------------------
+	if rr := doRequest(t, d, "GET", "/issues/next?owner=nobody", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("next for owner with no work: expected 404, got %d", rr.Code)
+	}
+}
 
-func TestAddRepoPath(t *testing.T) {
+func TestEstimateAndPlan(t *testing.T) {
 	d := testDaemon(t)
-
-	// Register a repo.
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
 
-	// Add a local path.
-	rr := doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{
-		"local_path":     "/tmp/worktree-a",
-		"socket_enabled": true,
-	})
+	rr := doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Large", "priority": 0, "estimate": 20})
+	var large model.Issue
+	decodeJSON(t, rr, &large)
+	if large.Estimate != 20 {
+		t.Fatalf("expected estimate 20 on create, got %d", large.Estimate)
+	}
+
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Small", "priority": 1})
+	var small model.Issue
+	decodeJSON(t, rr, &small)
+
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(small.ID), map[string]interface{}{"estimate": 5})
 	if rr.Code != http.StatusOK {
-		t.Fatalf("add repo path: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("patch estimate: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &small)
+	if small.Estimate != 5 {
+		t.Fatalf("expected estimate 5 after patch, got %d", small.Estimate)
 	}
 
-	var repo model.RepoConfig
-	decodeJSON(t, rr, &repo)
-	if len(repo.LocalPaths) != 1 {
-		t.Fatalf("expected 1 local path, got %d", len(repo.LocalPaths))
+	rr = doRequest(t, d, "POST", "/issues", map[string]interface{}{"title": "Negative", "estimate": -5})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("create with negative estimate: expected 400, got %d", rr.Code)
 	}
-	if repo.LocalPaths[0].LocalPath != "/tmp/worktree-a" {
-		t.Errorf("expected '/tmp/worktree-a', got %q", repo.LocalPaths[0].LocalPath)
+	rr = doRequest(t, d, "PATCH", "/issues/"+itoa(small.ID), map[string]interface{}{"estimate": -5})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("patch negative estimate: expected 400, got %d", rr.Code)
 	}
-	if !repo.LocalPaths[0].SocketEnabled {
-		t.Error("expected socket_enabled=true on local path")
+
+	rr = doRequest(t, d, "GET", "/issues/next?budget=8", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("next with budget: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var next model.Issue
+	decodeJSON(t, rr, &next)
+	if next.ID != small.ID {
+		t.Errorf("expected issue %d to fit budget, got %d", small.ID, next.ID)
 	}
 
-	// Add a second local path.
-	rr = doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{
-		"local_path":    "/tmp/worktree-b",
-		"queue_enabled": true,
-	})
+	rr = doRequest(t, d, "GET", "/issues/plan?budget=40", nil)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("add second path: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("plan: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var plan struct {
+		Budget int           `json:"budget"`
+		Total  int           `json:"total"`
+		Issues []model.Issue `json:"issues"`
+	}
+	decodeJSON(t, rr, &plan)
+	if len(plan.Issues) != 2 || plan.Total != 25 {
+		t.Errorf("expected 2 issues totalling 25, got %d totalling %d", len(plan.Issues), plan.Total)
 	}
 
-	decodeJSON(t, rr, &repo)
-	if len(repo.LocalPaths) != 2 {
-		t.Fatalf("expected 2 local paths, got %d", len(repo.LocalPaths))
+	rr = doRequest(t, d, "GET", "/issues/plan", nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("plan without budget: expected 400, got %d", rr.Code)
+	}
+	rr = doRequest(t, d, "GET", "/issues/next?budget=abc", nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("next with invalid budget: expected 400, got %d", rr.Code)
 	}
 }
 
-func TestRemoveRepoPath(t *testing.T) {
+func TestExport(t *testing.T) {
 	d := testDaemon(t)
-
 	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "s"})
+	doRequest(t, d, "POST", "/issues?repo=o/r", map[string]interface{}{"title": "First"})
+	doRequest(t, d, "POST", "/issues?repo=o/s", map[string]interface{}{"title": "Second"})
 
-	// Add two paths.
-	doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{
-		"local_path": "/tmp/worktree-a",
-	})
-	doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{
-		"local_path": "/tmp/worktree-b",
-	})
-
-	// Remove one.
-	rr := doRequest(t, d, "DELETE", "/repos/paths?repo=o/r", map[string]interface{}{
-		"local_path": "/tmp/worktree-a",
-	})
+	rr := doRequest(t, d, "GET", "/export", nil)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("remove repo path: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var dump store.Dump
+	decodeJSON(t, rr, &dump)
+	if dump.SchemaVersion != store.DumpVersion || len(dump.Repos) != 2 || len(dump.Issues) != 2 || len(dump.Events) != 2 {
+		t.Errorf("expected a v%d dump of 2 repos, 2 issues and 2 events, got %+v", store.DumpVersion, dump)
 	}
 
-	var repo model.RepoConfig
-	decodeJSON(t, rr, &repo)
-	if len(repo.LocalPaths) != 1 {
-		t.Fatalf("expected 1 local path after removal, got %d", len(repo.LocalPaths))
+	rr = doRequest(t, d, "GET", "/export?repo=o/s", nil)
+	dump = store.Dump{}
+	decodeJSON(t, rr, &dump)
+	if len(dump.Repos) != 1 || len(dump.Issues) != 1 || dump.Issues[0].Title != "Second" {
+		t.Errorf("expected only o/s in the dump, got %+v", dump)
 	}
-	if repo.LocalPaths[0].LocalPath != "/tmp/worktree-b" {
-		t.Errorf("expected '/tmp/worktree-b', got %q", repo.LocalPaths[0].LocalPath)
+
+	if rr := doRequest(t, d, "GET", "/export?repo=o/missing", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown repo, got %d", rr.Code)
 	}
 }
 
-func TestResolveRepoMultiplePaths(t *testing.T) {
-	d := testDaemon(t)
-
-	// Register two repos.
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "repo1"})
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "repo2"})
-
-	// Add local paths for each.
-	doRequest(t, d, "POST", "/repos/paths?repo=o/repo1", map[string]interface{}{
-		"local_path": "/home/user/repo1/main",
-	})
-	doRequest(t, d, "POST", "/repos/paths?repo=o/repo1", map[string]interface{}{
-		"local_path": "/home/user/repo1/feature-branch",
-	})
-	doRequest(t, d, "POST", "/repos/paths?repo=o/repo2", map[string]interface{}{
-		"local_path": "/home/user/repo2",
-	})
+func TestImportDump(t *testing.T) {
+	src := testDaemon(t)
+	doRequest(t, src, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+	doRequest(t, src, "POST", "/issues", map[string]interface{}{"title": "Moved"})
+	var dump store.Dump
+	decodeJSON(t, doRequest(t, src, "GET", "/export", nil), &dump)
 
-	// Request from repo1 main worktree.
-	rr := doRequestWithHeader(t, d, "GET", "/issues", "X-Working-Dir", "/home/user/repo1/main", nil)
+	d := testDaemon(t)
+	rr := doRequest(t, d, "POST", "/import", dump)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("list issues: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
 	}
-
-	// Request from repo1 feature-branch worktree subdirectory.
-	rr = doRequestWithHeader(t, d, "GET", "/issues", "X-Working-Dir", "/home/user/repo1/feature-branch/src", nil)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("list issues from subdir: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	var result store.ImportResult
+	decodeJSON(t, rr, &result)
+	if result.Repos != 1 || result.Issues != 1 || result.Events != 1 {
+		t.Errorf("expected 1 repo, 1 issue and 1 event imported, got %+v", result)
+	}
+	var iss model.Issue
+	decodeJSON(t, doRequest(t, d, "GET", "/issues/"+itoa(dump.Issues[0].ID)+"?repo=o/r", nil), &iss)
+	if iss.Title != "Moved" {
+		t.Errorf("expected the imported issue, got %+v", iss)
 	}
 
-	// Request from repo2.
-	rr = doRequestWithHeader(t, d, "GET", "/issues", "X-Working-Dir", "/home/user/repo2", nil)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("list issues repo2: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	if rr := doRequest(t, d, "POST", "/import", dump); rr.Code != http.StatusConflict {
+		t.Errorf("expected 409 importing a registered repo again, got %d", rr.Code)
+	}
+	dump.SchemaVersion = store.DumpVersion + 1
+	if rr := doRequest(t, d, "POST", "/import", dump); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a newer schema_version, got %d", rr.Code)
+	}
+	if rr := doRequest(t, d, "POST", "/import", `{"schema_version":1,"repos":[`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed dump, got %d", rr.Code)
 	}
 }
 
-func TestAddRepoPathCreatesSocket(t *testing.T) {
-	d := testDaemon(t)
-	tmpDir := t.TempDir()
+// webhookGitHubClient reports each GetIssue call, which the syncer makes when
+// a webhook queues a single-issue pull.
+type webhookGitHubClient struct {
+	noopGitHubClient
+	fetched chan string
+}
 
-	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "r"})
+func (c *webhookGitHubClient) GetIssue(ctx context.Context, owner, repo string, number int) (*github.GitHubIssue, error) {
+	c.fetched <- fmt.Sprintf("%s/%s#%d", owner, repo, number)
+	return &github.GitHubIssue{Number: number, State: "open"}, nil
+}
 
-	rr := doRequest(t, d, "POST", "/repos/paths?repo=o/r", map[string]interface{}{
-		"local_path":     tmpDir,
-		"socket_enabled": true,
+func TestGitHubWebhook(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "bor.db")
+	s, err := store.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	gh := &webhookGitHubClient{fetched: make(chan string, 10)}
+	sm := borSync.NewSyncManager(s, gh)
+	t.Cleanup(func() {
+		sm.Stop()
+		s.Close()
 	})
-	if rr.Code != http.StatusOK {
-		t.Fatalf("add repo path: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	cfg := &config.Config{ListenAddr: ":0", DataDir: dir, DBPath: dbPath, AuthToken: "tok", WebhookSecret: "s3cret"}
+	d := NewWithStoreAndSync(cfg, s, sm)
+	doRequestWithHeader(t, d, "POST", "/repos", "Authorization", "Bearer tok", map[string]string{"owner": "o", "name": "r"})
+	doRequestWithHeader(t, d, "POST", "/repos", "Authorization", "Bearer tok", map[string]string{"owner": "o", "name": "other"})
+
+	deliver := func(event, signature, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/webhooks/github", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		if signature != "" {
+			req.Header.Set("X-Hub-Signature-256", signature)
+		}
+		rr := httptest.NewRecorder()
+		d.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+	sign := func(secret, body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	payload := func(repo string, number int) string {
+		return fmt.Sprintf(`{"action":"created","issue":{"number":%d},"repository":{"name":%q,"owner":{"login":"o"}}}`, number, repo)
 	}
 
-	// Check that a socket was created.
-	d.socketMu.Lock()
-	sockCount := len(d.socketLns)
-	d.socketMu.Unlock()
+	body := payload("r", 5)
+	if rr := deliver("issue_comment", "", body); rr.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned: expected 401, got %d", rr.Code)
+	}
+	if rr := deliver("issue_comment", sign("wrong", body), body); rr.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: expected 401, got %d", rr.Code)
+	}
+	select {
+	case got := <-gh.fetched:
+		t.Fatalf("rejected delivery fetched %s", got)
+	case <-time.After(50 * time.Millisecond):
+	}
 
-	if sockCount == 0 {
-		t.Error("expected at least one socket listener to be created")
+	// The bearer token is not needed: the signature authenticates GitHub.
+	if rr := deliver("ping", sign("s3cret", "{}"), "{}"); rr.Code != http.StatusOK {
+		t.Errorf("ping: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := deliver("issue_comment", sign("s3cret", body), body); rr.Code != http.StatusAccepted {
+		t.Fatalf("signed: expected 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+	select {
+	case got := <-gh.fetched:
+		if got != "o/r#5" {
+			t.Errorf("expected a sync of o/r#5, got %s", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("issue sync never ran")
+	}
+
+	body = payload("untracked", 1)
+	rr := deliver("issues", sign("s3cret", body), body)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "ignored") {
+		t.Errorf("untracked repo: expected 200 ignored, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Without a secret the endpoint is off and polling is all there is.
+	if rr := doRequest(t, testDaemon(t), "POST", "/webhooks/github", map[string]string{}); rr.Code != http.StatusNotFound {
+		t.Errorf("no secret: expected 404, got %d", rr.Code)
 	}
 }
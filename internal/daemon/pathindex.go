@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	stdsync "sync"
+)
+
+// pathIndex caches the local path → repo ID mapping used to resolve
+// X-Working-Dir, so that resolveRepo does not hit the store on every request.
+// It is rebuilt lazily from the store after invalidate is called; handlers
+// that add or remove local paths must call Daemon.invalidatePathIndex.
+type pathIndex struct {
+	mu    stdsync.RWMutex
+	paths map[string]int // local path → repoID
+	valid bool
+}
+
+// invalidatePathIndex marks the path index stale so the next lookup reloads it.
+func (d *Daemon) invalidatePathIndex() {
+	d.pathIdx.mu.Lock()
+	d.pathIdx.valid = false
+	d.pathIdx.paths = nil
+	d.pathIdx.mu.Unlock()
+}
+
+// lookupPathIndex returns the repo ID whose local path is the longest prefix
+// of dir. It walks dir's ancestors from deepest to shallowest, so the cost is
+// proportional to path depth rather than the number of registered paths.
+func (d *Daemon) lookupPathIndex(ctx context.Context, dir string) (int, bool) {
+	paths := d.loadPathIndex(ctx)
+	if len(paths) == 0 {
+		return 0, false
+	}
+	for p := dir; p != ""; {
+		if id, ok := paths[p]; ok {
+			return id, true
+		}
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	return 0, false
+}
+
+// loadPathIndex returns the cached path map, rebuilding it if stale.
+// On store errors it returns nil so callers fall back to the store query.
+func (d *Daemon) loadPathIndex(ctx context.Context) map[string]int {
+	d.pathIdx.mu.RLock()
+	if d.pathIdx.valid {
+		paths := d.pathIdx.paths
+		d.pathIdx.mu.RUnlock()
+		return paths
+	}
+	d.pathIdx.mu.RUnlock()
+
+	d.pathIdx.mu.Lock()
+	defer d.pathIdx.mu.Unlock()
+	if d.pathIdx.valid {
+		return d.pathIdx.paths
+	}
+	lps, err := d.store.ListAllLocalPaths(ctx)
+	if err != nil {
+		slog.Warn("could not load local path index", "error", err)
+		return nil
+	}
+	paths := make(map[string]int, len(lps))
+	for _, lp := range lps {
+		if lp.LocalPath != "" {
+			paths[lp.LocalPath] = lp.RepoID
+		}
+	}
+	d.pathIdx.paths = paths
+	d.pathIdx.valid = true
+	return paths
+}
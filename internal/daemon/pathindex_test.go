@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPathIndexInvalidatedOnPathChanges(t *testing.T) {
+	d := testDaemon(t)
+
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "outer"})
+	doRequest(t, d, "POST", "/repos", map[string]string{"owner": "o", "name": "inner"})
+	doRequest(t, d, "POST", "/repos/paths?repo=o/outer", map[string]interface{}{"local_path": "/work"})
+
+	resolve := func() string {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/issues", nil)
+		req.Header.Set("X-Working-Dir", "/work/nested/src")
+		repo, err := d.resolveRepo(req)
+		if err != nil {
+			t.Fatalf("resolveRepo: %v", err)
+		}
+		return repo.Name
+	}
+
+	// Warm the index.
+	if got := resolve(); got != "outer" {
+		t.Fatalf("expected outer, got %s", got)
+	}
+
+	// Adding a deeper path mid-run must take effect immediately.
+	doRequest(t, d, "POST", "/repos/paths?repo=o/inner", map[string]interface{}{"local_path": "/work/nested"})
+	if got := resolve(); got != "inner" {
+		t.Fatalf("after add: expected inner, got %s", got)
+	}
+
+	// Removing it must fall back to the enclosing path.
+	doRequest(t, d, "DELETE", "/repos/paths?repo=o/inner", map[string]interface{}{"local_path": "/work/nested"})
+	if got := resolve(); got != "outer" {
+		t.Fatalf("after remove: expected outer, got %s", got)
+	}
+}
+
+func TestLookupPathIndexLongestPrefix(t *testing.T) {
+	d := testDaemon(t)
+	ctx := context.Background()
+
+	outer, _ := d.store.AddRepo(ctx, "o", "outer")
+	inner, _ := d.store.AddRepo(ctx, "o", "inner")
+	d.store.AddLocalPath(ctx, outer.ID, "/a", false, false)
+	d.store.AddLocalPath(ctx, inner.ID, "/a/b", false, false)
+
+	tests := []struct {
+		dir    string
+		wantID int
+		wantOK bool
+	}{
+		{"/a", outer.ID, true},
+		{"/a/bc", outer.ID, true},
+		{"/a/b", inner.ID, true},
+		{"/a/b/c/d", inner.ID, true},
+		{"/ab", 0, false},
+		{"/", 0, false},
+	}
+	for _, tt := range tests {
+		id, ok := d.lookupPathIndex(ctx, tt.dir)
+		if ok != tt.wantOK || id != tt.wantID {
+			t.Errorf("lookupPathIndex(%q) = (%d, %v), want (%d, %v)", tt.dir, id, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+// BenchmarkResolveRepoByWorkingDir compares X-Working-Dir resolution through
+// the in-memory index against the store's longest-prefix query with 500
+// registered worktrees.
+func BenchmarkResolveRepoByWorkingDir(b *testing.B) {
+	d := testDaemon(b)
+	ctx := context.Background()
+
+	const worktrees = 500
+	for i := 0; i < worktrees; i++ {
+		repo, err := d.store.AddRepo(ctx, "o", fmt.Sprintf("repo%d", i))
+		if err != nil {
+			b.Fatalf("AddRepo: %v", err)
+		}
+		if _, err := d.store.AddLocalPath(ctx, repo.ID, fmt.Sprintf("/home/user/src/repo%d", i), false, false); err != nil {
+			b.Fatalf("AddLocalPath: %v", err)
+		}
+	}
+	workDir := fmt.Sprintf("/home/user/src/repo%d/internal/pkg", worktrees-1)
+
+	b.Run("store", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := d.store.ResolveRepoByPath(ctx, workDir); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("index", func(b *testing.B) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Working-Dir", workDir)
+		for i := 0; i < b.N; i++ {
+			if _, err := d.resolveRepo(req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
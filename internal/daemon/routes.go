@@ -8,26 +8,74 @@ func (d *Daemon) registerRoutes() *http.ServeMux {
 
 	// Health and sync.
 	mux.HandleFunc("GET /health", d.health)
+	mux.HandleFunc("GET /ready", d.ready)
+	mux.HandleFunc("GET /version", d.versionInfo)
 	mux.HandleFunc("POST /sync", d.forceSync)
+	mux.HandleFunc("GET /sync/log", d.syncLog)
+	mux.HandleFunc("GET /sync/active", d.syncActive)
+	mux.HandleFunc("POST /sync/cancel", d.syncCancel)
+	mux.HandleFunc("GET /events", d.eventsSince)
+	mux.HandleFunc("GET /events/pending", d.pendingEvents)
+	mux.HandleFunc("GET /stats", d.issueStats)
+	mux.HandleFunc("GET /notifications", d.listNotifications)
+	mux.HandleFunc("POST /webhooks/github", d.githubWebhook)
 
 	// Repos.
 	mux.HandleFunc("POST /repos", d.addRepo)
 	mux.HandleFunc("GET /repos", d.listRepos)
 	mux.HandleFunc("PATCH /repos", d.updateRepo)
+	mux.HandleFunc("DELETE /repos", d.deleteRepo)
 	mux.HandleFunc("POST /repos/paths", d.addRepoPath)
+	mux.HandleFunc("PATCH /repos/paths", d.updateRepoPath)
 	mux.HandleFunc("DELETE /repos/paths", d.removeRepoPath)
+	mux.HandleFunc("POST /repos/ensure-labels", d.ensureLabels)
 	mux.HandleFunc("POST /repos/import", d.importIssues)
+	mux.HandleFunc("GET /repos/integrity", d.repoIntegrity)
+	mux.HandleFunc("POST /repos/repair", d.repairRepo)
+	mux.HandleFunc("GET /repos/templates", d.repoTemplates)
 
-	// Issues: register /issues/next BEFORE /issues/{id} so the literal
-	// route matches first.
+	// Export and import.
+	mux.HandleFunc("GET /export", d.exportData)
+	mux.HandleFunc("POST /import", d.importDump)
+
+	// Issues: register /issues/next, /issues/plan, /issues/changed,
+	// /issues/events, /issues/trending, /issues/stale and /issues/search
+	// BEFORE /issues/{id} so the literal routes match first.
 	mux.HandleFunc("GET /issues/next", d.nextIssue)
+	mux.HandleFunc("GET /issues/plan", d.planIssues)
+	mux.HandleFunc("GET /issues/changed", d.changedIssues)
+	mux.HandleFunc("GET /issues/events", d.issueEvents)
+	mux.HandleFunc("GET /issues/trending", d.trendingIssues)
+	mux.HandleFunc("GET /issues/stale", d.staleIssues)
+	mux.HandleFunc("GET /issues/search", d.searchIssues)
 	mux.HandleFunc("GET /issues/{id}", d.getIssue)
 	mux.HandleFunc("GET /issues", d.listIssues)
+	mux.HandleFunc("HEAD /issues", d.listIssues)
 	mux.HandleFunc("POST /issues", d.createIssue)
+	mux.HandleFunc("POST /issues/reorder", d.reorderIssues)
+	mux.HandleFunc("POST /issues/claim", d.claimIssue)
+	mux.HandleFunc("POST /issues/bulk", d.bulkCreateIssues)
 	mux.HandleFunc("PATCH /issues/{id}", d.updateIssue)
 	mux.HandleFunc("DELETE /issues/{id}", d.deleteIssue)
 	mux.HandleFunc("POST /issues/{id}/assign", d.assignIssue)
+	mux.HandleFunc("POST /issues/{id}/abandon", d.abandonIssue)
+	mux.HandleFunc("POST /issues/{id}/reopen", d.reopenIssue)
+	mux.HandleFunc("POST /issues/{id}/restore", d.restoreIssue)
+	mux.HandleFunc("POST /issues/{id}/labels", d.addIssueLabel)
+	mux.HandleFunc("DELETE /issues/{id}/labels", d.removeIssueLabel)
+	mux.HandleFunc("POST /issues/{id}/dependencies", d.addIssueDependency)
+	mux.HandleFunc("DELETE /issues/{id}/dependencies", d.removeIssueDependency)
+	mux.HandleFunc("POST /issues/{id}/watch", d.watchIssue)
+	mux.HandleFunc("DELETE /issues/{id}/watch", d.unwatchIssue)
+	mux.HandleFunc("POST /issues/{id}/parent", d.setIssueParent)
+	mux.HandleFunc("DELETE /issues/{id}/parent", d.clearIssueParent)
+	mux.HandleFunc("GET /issues/{id}/children", d.listChildren)
 	mux.HandleFunc("POST /issues/{id}/comment", d.commentIssue)
+	mux.HandleFunc("PATCH /issues/{id}/comments/{index}", d.editComment)
+	mux.HandleFunc("DELETE /issues/{id}/comments/{index}", d.deleteComment)
+	mux.HandleFunc("POST /issues/{id}/snooze", d.snoozeIssue)
+	mux.HandleFunc("GET /issues/{id}/field-history", d.fieldHistory)
+	mux.HandleFunc("GET /issues/{id}/events", d.issueTimeline)
 
 	// Web UI (served at root; more-specific API routes take precedence).
 	mux.HandleFunc("GET /", d.serveUI)
@@ -1,11 +1,29 @@
 package daemon
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// RequestIDHeader carries the ID that ties a request to its log lines. A
+// caller may send its own; otherwise the daemon assigns one. Either way the
+// response echoes it.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey holds the request's ID in its context.
+const requestIDKey contextKey = "requestID"
+
+// maxRequestIDLen bounds a caller-supplied request ID, which is logged
+// verbatim.
+const maxRequestIDLen = 128
+
 // responseRecorder wraps http.ResponseWriter to capture the status code.
 type responseRecorder struct {
 	http.ResponseWriter
@@ -17,22 +35,74 @@ func (rr *responseRecorder) WriteHeader(code int) {
 	rr.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer so http.ResponseController can flush
+// streaming responses through the logger.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
 // applyMiddleware wraps the mux with the middleware chain.
 func (d *Daemon) applyMiddleware(mux http.Handler) http.Handler {
 	// Apply middleware in reverse order (outermost first).
-	handler := jsonContentType(mux)
+	handler := d.requireAuth(mux)
+	handler = jsonContentType(handler)
+	handler = apiVersionHeader(handler)
 	handler = requestLogger(handler)
+	handler = withRequestID(handler)
 	return handler
 }
 
-// requestLogger logs method, path, status code, and duration for each request.
+// withRequestID takes the request's ID from X-Request-ID, or assigns one
+// if it is missing or unusable, and sets it on the response and in the
+// request context.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// validRequestID reports whether a caller-supplied ID is short printable
+// ASCII, and so safe to log.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 16-character hex ID.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "" outside a
+// request.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// requestLogger logs request ID, method, path, status code, and duration for
+// each request.
 func requestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		rr := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
 		next.ServeHTTP(rr, r)
 		duration := time.Since(start)
-		slog.Info("http request",
+		slog.InfoContext(r.Context(), "http request",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", rr.statusCode,
@@ -41,6 +111,31 @@ func requestLogger(next http.Handler) http.Handler {
 	})
 }
 
+// LogHandler wraps a slog.Handler so that records logged with the context
+// of a request carry its request_id.
+type LogHandler struct {
+	slog.Handler
+}
+
+// Handle adds the request ID, if ctx has one, and passes the record on.
+func (h LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r = r.Clone()
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a LogHandler over the inner handler's WithAttrs.
+func (h LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return LogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a LogHandler over the inner handler's WithGroup.
+func (h LogHandler) WithGroup(name string) slog.Handler {
+	return LogHandler{h.Handler.WithGroup(name)}
+}
+
 // jsonContentType sets the Content-Type header to application/json for all responses.
 func jsonContentType(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -48,3 +143,36 @@ func jsonContentType(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// requireAuth rejects TCP requests without the configured bearer token. It is
+// a no-op when no auth_token is set. GET /health and GET /ready stay open so
+// supervisors can probe the daemon, POST /webhooks/github checks its own
+// signature instead, and local connections are trusted.
+func (d *Daemon) requireAuth(next http.Handler) http.Handler {
+	token := d.cfg.AuthToken
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" || r.URL.Path == "/ready" || r.URL.Path == "/webhooks/github" || r.Context().Value(localConnKey) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or invalid auth token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiVersionHeader advertises the daemon's API version on every response so
+// clients can detect version skew without an extra round trip.
+func apiVersionHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(APIVersionHeader, strconv.Itoa(APIVersion))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// maxWebhookBytes caps a webhook delivery; GitHub sends at most 25 MB.
+const maxWebhookBytes = 25 << 20
+
+// webhookPayload holds the fields of an issues or issue_comment delivery
+// that name the changed issue.
+type webhookPayload struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number int `json:"number"`
+	} `json:"issue"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+}
+
+// githubWebhook handles POST /webhooks/github. A delivery signed with the
+// configured webhook_secret for an issues or issue_comment event on a
+// tracked repo queues a pull of just that issue, so changes made on GitHub
+// arrive without waiting for the next poll. Polling carries on regardless,
+// and is all there is when no secret is configured.
+func (d *Daemon) githubWebhook(w http.ResponseWriter, r *http.Request) {
+	secret := d.cfg.WebhookSecret
+	if secret == "" {
+		writeError(w, http.StatusNotFound, "webhooks are off: no webhook_secret is configured")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "read body: "+err.Error())
+		return
+	}
+	if !validWebhookSignature(secret, body, r.Header.Get("X-Hub-Signature-256")) {
+		writeError(w, http.StatusUnauthorized, "missing or invalid X-Hub-Signature-256")
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	switch event {
+	case "ping":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "pong"})
+		return
+	case "issues", "issue_comment":
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored", "event": event})
+		return
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if payload.Issue.Number <= 0 || payload.Repository.Owner.Login == "" || payload.Repository.Name == "" {
+		writeError(w, http.StatusBadRequest, "payload names no issue and repository")
+		return
+	}
+
+	repo, err := d.store.GetRepoByName(r.Context(), payload.Repository.Owner.Login, payload.Repository.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored", "event": event})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !d.requireSync(w) {
+		return
+	}
+	if err := d.syncMgr.SyncIssue(repo.ID, payload.Issue.Number); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	slog.InfoContext(r.Context(), "webhook queued issue sync",
+		"repo", repo.FullName(), "issue", payload.Issue.Number, "event", event, "action", payload.Action)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"status": "sync triggered",
+		"repo":   repo.FullName(),
+		"issue":  payload.Issue.Number,
+	})
+}
+
+// validWebhookSignature reports whether header, an X-Hub-Signature-256
+// value, is the HMAC-SHA256 of body under secret.
+func validWebhookSignature(secret string, body []byte, header string) bool {
+	got, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	sig, err := hex.DecodeString(got)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
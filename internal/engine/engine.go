@@ -3,21 +3,42 @@ package engine
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/jmaddaus/boxofrocks/internal/model"
 )
 
 // Replay takes a list of events and produces a map of issueID to derived Issue state.
-// Events must be sorted by timestamp. This is the full replay path.
-func Replay(events []*model.Event) (map[int]*model.Issue, error) {
+// Events must be sorted by timestamp. This is the full replay path. An event
+// whose idempotency key matches one already applied to its issue is skipped.
+// An event without a stored key is keyed by its GitHub comment, if any.
+// Each event is applied with opts.
+func Replay(events []*model.Event, opts ...Option) (map[int]*model.Issue, error) {
 	issues := make(map[int]*model.Issue)
+	applied := make(map[int]map[string]bool)
 	for _, ev := range events {
+		// A comment posted twice by racing syncers parses to two copies.
+		key := ev.IdempotencyKey
+		if key == "" && ev.GitHubCommentID != nil {
+			key = model.CommentIdempotencyKey(*ev.GitHubCommentID)
+		}
+		if key != "" {
+			if applied[ev.IssueID][key] {
+				continue
+			}
+			if applied[ev.IssueID] == nil {
+				applied[ev.IssueID] = make(map[string]bool)
+			}
+			applied[ev.IssueID][key] = true
+		}
+
 		existing := issues[ev.IssueID]
 		if ev.Action == model.ActionCreate && existing != nil {
 			return nil, fmt.Errorf("duplicate create for issue %d", ev.IssueID)
 		}
-		updated, err := Apply(existing, ev)
+		updated, err := Apply(existing, ev, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("applying event %d (action=%s, issue=%d): %w",
 				ev.ID, ev.Action, ev.IssueID, err)
@@ -28,8 +49,9 @@ func Replay(events []*model.Event) (map[int]*model.Issue, error) {
 }
 
 // Apply takes an existing issue (can be nil for "create") and a single event,
-// returns the updated issue. Used for incremental processing.
-func Apply(issue *model.Issue, event *model.Event) (*model.Issue, error) {
+// returns the updated issue. Used for incremental processing. Options such as
+// WithPriorityRange adjust how the payload applies.
+func Apply(issue *model.Issue, event *model.Event, opts ...Option) (*model.Issue, error) {
 	var payload model.EventPayload
 	if event.Payload != "" {
 		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
@@ -42,7 +64,7 @@ func Apply(issue *model.Issue, event *model.Event) (*model.Issue, error) {
 
 	switch event.Action {
 	case model.ActionCreate:
-		result, err = applyCreate(event, &payload)
+		result, err = applyCreate(event, &payload, newOptions(opts))
 	case model.ActionStatusChange:
 		result, err = applyStatusChange(issue, event, &payload)
 	case model.ActionAssign:
@@ -50,13 +72,43 @@ func Apply(issue *model.Issue, event *model.Event) (*model.Issue, error) {
 	case model.ActionClose:
 		result, err = applyClose(issue, event)
 	case model.ActionUpdate:
-		result, err = applyUpdate(issue, event, &payload)
+		result, err = applyUpdate(issue, event, &payload, newOptions(opts))
 	case model.ActionDelete:
 		result, err = applyDelete(issue, event)
 	case model.ActionReopen:
 		result, err = applyReopen(issue, event)
+	case model.ActionRestore:
+		result, err = applyRestore(issue, event)
 	case model.ActionComment:
 		result, err = applyComment(issue, event)
+	case model.ActionSnooze:
+		result, err = applySnooze(issue, event, &payload)
+	case model.ActionLabelAdd:
+		result, err = applyLabelAdd(issue, event, &payload)
+	case model.ActionLabelRemove:
+		result, err = applyLabelRemove(issue, event, &payload)
+	case model.ActionAddDependency:
+		result, err = applyAddDependency(issue, event, &payload)
+	case model.ActionRemoveDependency:
+		result, err = applyRemoveDependency(issue, event, &payload)
+	case model.ActionPriorityChange:
+		result, err = applyPriorityChange(issue, event, &payload, newOptions(opts))
+	case model.ActionSetParent:
+		result, err = applySetParent(issue, event, &payload)
+	case model.ActionClearParent:
+		result, err = applyClearParent(issue, event, &payload)
+	case model.ActionCommentEdit:
+		result, err = applyCommentEdit(issue, event, &payload)
+	case model.ActionCommentDelete:
+		result, err = applyCommentDelete(issue, event, &payload)
+	case model.ActionWatch:
+		result, err = applyWatch(issue, event, &payload)
+	case model.ActionUnwatch:
+		result, err = applyUnwatch(issue, event, &payload)
+	case model.ActionSnapshot:
+		result, err = applySnapshot(event, &payload, newOptions(opts))
+	case model.ActionUpdateRejected:
+		result, err = applyUpdateRejected(issue, event)
 	default:
 		return nil, fmt.Errorf("unknown action: %s", event.Action)
 	}
@@ -76,11 +128,14 @@ func Apply(issue *model.Issue, event *model.Event) (*model.Issue, error) {
 			Timestamp: event.Timestamp.UTC().Format(time.RFC3339),
 		})
 	}
+	if result != nil {
+		NormalizeIssue(result)
+	}
 
 	return result, nil
 }
 
-func applyCreate(event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+func applyCreate(event *model.Event, payload *model.EventPayload, o *options) (*model.Issue, error) {
 	issue := &model.Issue{
 		ID:          event.IssueID,
 		RepoID:      event.RepoID,
@@ -94,7 +149,10 @@ func applyCreate(event *model.Event, payload *model.EventPayload) (*model.Issue,
 		UpdatedAt:   event.Timestamp,
 	}
 	if payload.Priority != nil {
-		issue.Priority = *payload.Priority
+		issue.Priority = o.priority(*payload.Priority)
+	}
+	if payload.Estimate != nil {
+		issue.Estimate = *payload.Estimate
 	}
 	if payload.IssueType != "" {
 		issue.IssueType = model.IssueType(payload.IssueType)
@@ -102,6 +160,7 @@ func applyCreate(event *model.Event, payload *model.EventPayload) (*model.Issue,
 	if issue.Labels == nil {
 		issue.Labels = []string{}
 	}
+	setParent(issue, payload.ParentID)
 	return issue, nil
 }
 
@@ -112,6 +171,9 @@ func applyStatusChange(issue *model.Issue, event *model.Event, payload *model.Ev
 	if payload.Status == "" {
 		return issue, nil
 	}
+	if !payload.Status.Valid() {
+		return nil, fmt.Errorf("status_change to unknown status %q", payload.Status)
+	}
 	if IsTerminal(issue.Status) {
 		return issue, nil
 	}
@@ -146,7 +208,7 @@ func applyClose(issue *model.Issue, event *model.Event) (*model.Issue, error) {
 	return issue, nil
 }
 
-func applyUpdate(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+func applyUpdate(issue *model.Issue, event *model.Event, payload *model.EventPayload, o *options) (*model.Issue, error) {
 	if issue == nil {
 		return nil, fmt.Errorf("update on non-existent issue %d", event.IssueID)
 	}
@@ -157,7 +219,10 @@ func applyUpdate(issue *model.Issue, event *model.Event, payload *model.EventPay
 		issue.Description = payload.Description
 	}
 	if payload.Priority != nil {
-		issue.Priority = *payload.Priority
+		issue.Priority = o.priority(*payload.Priority)
+	}
+	if payload.Estimate != nil {
+		issue.Estimate = *payload.Estimate
 	}
 	if payload.IssueType != "" {
 		issue.IssueType = model.IssueType(payload.IssueType)
@@ -165,6 +230,238 @@ func applyUpdate(issue *model.Issue, event *model.Event, payload *model.EventPay
 	if payload.Labels != nil {
 		issue.Labels = payload.Labels
 	}
+	setParent(issue, payload.ParentID)
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
+
+// applyPriorityChange sets the priority. from_priority is not checked:
+// unlike a status, any priority may follow any other, so the last change
+// wins.
+func applyPriorityChange(issue *model.Issue, event *model.Event, payload *model.EventPayload, o *options) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("priority_change on non-existent issue %d", event.IssueID)
+	}
+	if payload.Priority == nil {
+		return issue, nil
+	}
+	issue.Priority = o.priority(*payload.Priority)
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
+
+// applyLabelAdd adds one label, leaving the rest alone so concurrent label
+// events from different agents merge rather than overwrite each other.
+// Labels stay sorted, making the result independent of the order in which
+// different labels were added. Labels match case-insensitively; of two
+// spellings of one label, the byte-wise smaller is kept.
+func applyLabelAdd(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("label_add on non-existent issue %d", event.IssueID)
+	}
+	label := strings.TrimSpace(payload.Label)
+	if label == "" {
+		return issue, nil
+	}
+	labels := append([]string{}, issue.Labels...)
+	found := false
+	for i, l := range labels {
+		if strings.EqualFold(l, label) {
+			// Keep one spelling whichever agent added it first.
+			if label < l {
+				labels[i] = label
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		labels = append(labels, label)
+	}
+	sortLabels(labels)
+	issue.Labels = labels
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
+
+// applyLabelRemove removes one label, if present.
+func applyLabelRemove(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("label_remove on non-existent issue %d", event.IssueID)
+	}
+	label := strings.TrimSpace(payload.Label)
+	if label == "" {
+		return issue, nil
+	}
+	labels := make([]string, 0, len(issue.Labels))
+	for _, l := range issue.Labels {
+		if !strings.EqualFold(l, label) {
+			labels = append(labels, l)
+		}
+	}
+	sortLabels(labels)
+	issue.Labels = labels
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
+
+// applyAddDependency records that the issue is blocked by another issue.
+// BlockedBy is kept sorted and free of duplicates, so concurrent events
+// merge regardless of order. A missing or self-referencing blocker is
+// ignored rather than failing replay.
+func applyAddDependency(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("add_dependency on non-existent issue %d", event.IssueID)
+	}
+	blocker := payload.BlockerID
+	if blocker <= 0 || blocker == issue.ID {
+		return issue, nil
+	}
+	for _, id := range issue.BlockedBy {
+		if id == blocker {
+			return issue, nil
+		}
+	}
+	blockedBy := append(append([]int{}, issue.BlockedBy...), blocker)
+	sort.Ints(blockedBy)
+	issue.BlockedBy = blockedBy
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
+
+// applyRemoveDependency drops a blocker, if present.
+func applyRemoveDependency(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("remove_dependency on non-existent issue %d", event.IssueID)
+	}
+	if payload.BlockerID <= 0 {
+		return issue, nil
+	}
+	var blockedBy []int
+	for _, id := range issue.BlockedBy {
+		if id != payload.BlockerID {
+			blockedBy = append(blockedBy, id)
+		}
+	}
+	issue.BlockedBy = blockedBy
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
+
+// applyWatch adds a watcher. Watchers are matched case-insensitively and
+// kept sorted, like labels; an empty watcher is ignored.
+func applyWatch(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("watch on non-existent issue %d", event.IssueID)
+	}
+	watcher := strings.TrimSpace(payload.Watcher)
+	if watcher == "" {
+		return issue, nil
+	}
+	for _, w := range issue.Watchers {
+		if strings.EqualFold(w, watcher) {
+			return issue, nil
+		}
+	}
+	watchers := append(append([]string{}, issue.Watchers...), watcher)
+	sortLabels(watchers)
+	issue.Watchers = watchers
+	return issue, nil
+}
+
+// applyUnwatch removes a watcher, if present.
+func applyUnwatch(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("unwatch on non-existent issue %d", event.IssueID)
+	}
+	watcher := strings.TrimSpace(payload.Watcher)
+	var watchers []string
+	for _, w := range issue.Watchers {
+		if !strings.EqualFold(w, watcher) {
+			watchers = append(watchers, w)
+		}
+	}
+	issue.Watchers = watchers
+	return issue, nil
+}
+
+// applySnapshot replaces the issue, existing or not, with the snapshot's
+// state. The snapshot stands in for the events compaction removed,
+// create included, so its priority is clamped as theirs would have been.
+func applySnapshot(event *model.Event, payload *model.EventPayload, o *options) (*model.Issue, error) {
+	if payload.Snapshot == nil {
+		return nil, fmt.Errorf("snapshot event for issue %d has no snapshot", event.IssueID)
+	}
+	issue := *payload.Snapshot
+	issue.ID = event.IssueID
+	issue.RepoID = event.RepoID
+	issue.Priority = o.priority(issue.Priority)
+	if issue.Labels == nil {
+		issue.Labels = []string{}
+	}
+	if issue.Comments == nil {
+		issue.Comments = []model.Comment{}
+	}
+	return &issue, nil
+}
+
+// applyUpdateRejected leaves the issue as it was: the event only records
+// a refused update for audit.
+func applyUpdateRejected(issue *model.Issue, event *model.Event) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("update_rejected on non-existent issue %d", event.IssueID)
+	}
+	return issue, nil
+}
+
+// sortLabels orders labels case-insensitively.
+func sortLabels(labels []string) {
+	sort.SliceStable(labels, func(i, j int) bool {
+		return strings.ToLower(labels[i]) < strings.ToLower(labels[j])
+	})
+}
+
+// setParent applies a payload's parent_id: nil leaves the parent unchanged,
+// 0 clears it.
+func setParent(issue *model.Issue, parentID *int) {
+	if parentID == nil {
+		return
+	}
+	if *parentID == 0 {
+		issue.ParentID = nil
+		return
+	}
+	p := *parentID
+	issue.ParentID = &p
+}
+
+// applySetParent links the issue to the parent named by parent_id. An event
+// naming the issue itself, or no parent, changes nothing. Longer cycles span
+// several issues, which Apply cannot see; the daemon rejects them before the
+// event is written.
+func applySetParent(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("set_parent on non-existent issue %d", event.IssueID)
+	}
+	if payload.ParentID == nil || *payload.ParentID <= 0 || *payload.ParentID == issue.ID {
+		return issue, nil
+	}
+	setParent(issue, payload.ParentID)
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
+
+// applyClearParent unlinks the issue only if its parent is still the one
+// named by parent_id, so a clear that races with a newer set_parent does not
+// undo it.
+func applyClearParent(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("clear_parent on non-existent issue %d", event.IssueID)
+	}
+	if payload.ParentID == nil || issue.ParentID == nil || *issue.ParentID != *payload.ParentID {
+		return issue, nil
+	}
+	issue.ParentID = nil
 	issue.UpdatedAt = event.Timestamp
 	return issue, nil
 }
@@ -195,6 +492,21 @@ func applyReopen(issue *model.Issue, event *model.Event) (*model.Issue, error) {
 	return issue, nil
 }
 
+// applyRestore brings a deleted issue back as open, clearing closed_at in
+// case it was closed before it was deleted. Any other issue is unchanged.
+func applyRestore(issue *model.Issue, event *model.Event) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("restore on non-existent issue %d", event.IssueID)
+	}
+	if issue.Status != model.StatusDeleted {
+		return issue, nil
+	}
+	issue.Status = model.StatusOpen
+	issue.ClosedAt = nil
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
+
 func applyComment(issue *model.Issue, event *model.Event) (*model.Issue, error) {
 	if issue == nil {
 		return nil, fmt.Errorf("comment on non-existent issue %d", event.IssueID)
@@ -202,3 +514,62 @@ func applyComment(issue *model.Issue, event *model.Event) (*model.Issue, error)
 	issue.UpdatedAt = event.Timestamp
 	return issue, nil
 }
+
+// commentAt returns the index of the comment a comment_edit or
+// comment_delete event targets, or false if the event names no comment the
+// issue has. Such an event changes nothing rather than failing replay.
+func commentAt(issue *model.Issue, payload *model.EventPayload) (int, bool) {
+	if payload.CommentIndex == nil {
+		return 0, false
+	}
+	i := *payload.CommentIndex
+	return i, i >= 0 && i < len(issue.Comments)
+}
+
+// applyCommentEdit replaces a comment's text and marks it edited. The
+// author and original timestamp are kept.
+func applyCommentEdit(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("comment_edit on non-existent issue %d", event.IssueID)
+	}
+	i, ok := commentAt(issue, payload)
+	if !ok || payload.CommentText == "" {
+		return issue, nil
+	}
+	comments := append([]model.Comment{}, issue.Comments...)
+	comments[i].Text = payload.CommentText
+	comments[i].EditedAt = event.Timestamp.UTC().Format(time.RFC3339)
+	issue.Comments = comments
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
+
+// applyCommentDelete removes a comment. Later comments move down one
+// place, so an index always refers to the list as it stood when the event
+// was written.
+func applyCommentDelete(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("comment_delete on non-existent issue %d", event.IssueID)
+	}
+	i, ok := commentAt(issue, payload)
+	if !ok {
+		return issue, nil
+	}
+	comments := make([]model.Comment, 0, len(issue.Comments)-1)
+	comments = append(comments, issue.Comments[:i]...)
+	issue.Comments = append(comments, issue.Comments[i+1:]...)
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
+
+func applySnooze(issue *model.Issue, event *model.Event, payload *model.EventPayload) (*model.Issue, error) {
+	if issue == nil {
+		return nil, fmt.Errorf("snooze on non-existent issue %d", event.IssueID)
+	}
+	if IsTerminal(issue.Status) {
+		return issue, nil
+	}
+	issue.SnoozedUntil = payload.SnoozedUntil
+	issue.UpdatedAt = event.Timestamp
+	return issue, nil
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -258,6 +260,66 @@ func TestReplay_DuplicateCreate(t *testing.T) {
 	}
 }
 
+func TestReplay_Snapshot(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []*model.Event{
+		{
+			ID: 5, RepoID: 1, IssueID: 1, Timestamp: ts,
+			Action:  model.ActionSnapshot,
+			Payload: `{"snapshot":{"title":"Compacted","status":"closed","owner":"alice","labels":["a"],"comments":[{"text":"old","timestamp":"2025-01-01T00:00:00Z"}]}}`,
+		},
+		{
+			ID: 6, RepoID: 1, IssueID: 1, Timestamp: ts.Add(time.Hour),
+			Action:  model.ActionReopen,
+			Payload: `{"comment":"again"}`,
+		},
+	}
+	issues, err := Replay(events)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	got := issues[1]
+	if got.ID != 1 || got.RepoID != 1 || got.Title != "Compacted" || got.Owner != "alice" {
+		t.Errorf("snapshot state not restored: %+v", got)
+	}
+	if got.Status != model.StatusOpen || len(got.Comments) != 2 {
+		t.Errorf("status %s with %d comments, want open with 2", got.Status, len(got.Comments))
+	}
+
+	// A snapshot stands in for the create, so a later create is a duplicate.
+	events = append(events, &model.Event{ID: 7, RepoID: 1, IssueID: 1, Timestamp: ts.Add(2 * time.Hour), Action: model.ActionCreate, Payload: `{"title":"again"}`})
+	if _, err := Replay(events); err == nil {
+		t.Error("expected error for create after snapshot")
+	}
+	if _, err := Apply(nil, &model.Event{IssueID: 2, Action: model.ActionSnapshot, Payload: `{}`}); err == nil {
+		t.Error("expected error for snapshot without state")
+	}
+}
+
+func TestReplay_SnapshotPriorityClamped(t *testing.T) {
+	// A snapshot made by a replay without a range can carry a priority the
+	// range would have clamped.
+	events := []*model.Event{{
+		ID: 5, RepoID: 1, IssueID: 1, Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Action:  model.ActionSnapshot,
+		Payload: `{"snapshot":{"title":"Compacted","status":"closed","priority":999}}`,
+	}}
+	issues, err := Replay(events, WithPriorityRange(0, 5))
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if got := issues[1].Priority; got != 5 {
+		t.Errorf("Priority = %d, want 5", got)
+	}
+	issues, err = Replay(events)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if got := issues[1].Priority; got != 999 {
+		t.Errorf("Priority without a range = %d, want 999", got)
+	}
+}
+
 // --- Rules tests ---
 
 func TestFromStatusMatch(t *testing.T) {
@@ -385,6 +447,75 @@ func TestApply_ReopenFromOpenIgnored(t *testing.T) {
 	}
 }
 
+// --- Restore is the only way out of deleted ---
+
+func TestApply_RestoreFromDeleted(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events := []*model.Event{
+		{ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts, Action: model.ActionCreate, Payload: `{"title":"Restore test"}`},
+		{ID: 2, RepoID: 1, IssueID: 1, Timestamp: ts.Add(time.Hour), Action: model.ActionClose, Payload: `{}`},
+		{ID: 3, RepoID: 1, IssueID: 1, Timestamp: ts.Add(2 * time.Hour), Action: model.ActionDelete, Payload: `{}`},
+		// Neither reopen nor a status change leaves deleted.
+		{ID: 4, RepoID: 1, IssueID: 1, Timestamp: ts.Add(3 * time.Hour), Action: model.ActionReopen, Payload: `{}`},
+		{ID: 5, RepoID: 1, IssueID: 1, Timestamp: ts.Add(4 * time.Hour), Action: model.ActionStatusChange, Payload: `{"status":"open"}`},
+	}
+	issues, err := Replay(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := issues[1]
+	if issue.Status != model.StatusDeleted {
+		t.Fatalf("status = %q before restore, want %q", issue.Status, model.StatusDeleted)
+	}
+
+	issue, err = Apply(issue, &model.Event{
+		ID: 6, RepoID: 1, IssueID: 1, Timestamp: ts.Add(5 * time.Hour),
+		Action:  model.ActionRestore,
+		Payload: `{}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Status != model.StatusOpen {
+		t.Errorf("status = %q after restore, want %q", issue.Status, model.StatusOpen)
+	}
+	if issue.ClosedAt != nil {
+		t.Errorf("ClosedAt should be nil after restore, got %v", issue.ClosedAt)
+	}
+	if !issue.UpdatedAt.Equal(ts.Add(5 * time.Hour)) {
+		t.Errorf("UpdatedAt = %v, want %v", issue.UpdatedAt, ts.Add(5*time.Hour))
+	}
+}
+
+func TestApply_RestoreFromOpenIgnored(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	issue, err := Apply(nil, &model.Event{
+		ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts,
+		Action:  model.ActionCreate,
+		Payload: `{"title":"Restore ignored test"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue, err = Apply(issue, &model.Event{
+		ID: 2, RepoID: 1, IssueID: 1, Timestamp: ts.Add(time.Hour),
+		Action:  model.ActionRestore,
+		Payload: `{}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Status != model.StatusOpen {
+		t.Errorf("status = %q, want %q (unchanged)", issue.Status, model.StatusOpen)
+	}
+	if !issue.UpdatedAt.Equal(ts) {
+		t.Errorf("UpdatedAt = %v, want %v (unchanged)", issue.UpdatedAt, ts)
+	}
+}
+
 // --- Delete from various states ---
 
 func TestApply_DeleteFromOpen(t *testing.T) {
@@ -558,6 +689,69 @@ func TestApply_UpdatePartialPatch(t *testing.T) {
 	}
 }
 
+// --- Priority bounds ---
+
+func TestApply_ClampsPriority(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		payload string
+		want    int
+	}{
+		{`{"title":"t","priority":0}`, 0},
+		{`{"title":"t","priority":5}`, 5},
+		{`{"title":"t","priority":-1}`, 0},
+		{`{"title":"t","priority":999999}`, 5},
+	}
+	for _, tc := range cases {
+		issue, err := Apply(nil, &model.Event{
+			ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts,
+			Action: model.ActionCreate, Payload: tc.payload,
+		}, WithPriorityRange(0, 5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if issue.Priority != tc.want {
+			t.Errorf("create %s: Priority = %d, want %d", tc.payload, issue.Priority, tc.want)
+		}
+	}
+
+	issue, _ := Apply(nil, &model.Event{
+		ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts,
+		Action: model.ActionCreate, Payload: `{"title":"t","priority":2}`,
+	}, WithPriorityRange(0, 5))
+	issue, err := Apply(issue, &model.Event{
+		ID: 2, RepoID: 1, IssueID: 1, Timestamp: ts.Add(time.Hour),
+		Action: model.ActionUpdate, Payload: `{"priority":6}`,
+	}, WithPriorityRange(0, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Priority != 5 {
+		t.Errorf("update to 6: Priority = %d, want 5", issue.Priority)
+	}
+}
+
+func TestApply_PriorityUnclampedWithoutRange(t *testing.T) {
+	issue, err := Apply(nil, &model.Event{
+		ID: 1, RepoID: 1, IssueID: 1, Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Action: model.ActionCreate, Payload: `{"title":"t","priority":9}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Priority != 9 {
+		t.Errorf("Priority = %d, want 9 kept without a priority range", issue.Priority)
+	}
+}
+
+func TestClampPriority(t *testing.T) {
+	for in, want := range map[int]int{0: 1, 1: 1, 7: 7, 10: 10, 11: 10} {
+		if got := ClampPriority(in, 1, 10); got != want {
+			t.Errorf("ClampPriority(%d, 1, 10) = %d, want %d", in, got, want)
+		}
+	}
+}
+
 // --- Unknown action error ---
 
 func TestApply_UnknownAction(t *testing.T) {
@@ -684,6 +878,30 @@ func TestApply_StatusChangeWithFromStatus(t *testing.T) {
 	}
 }
 
+func TestApply_StatusChangeToUnknownStatusErrors(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue, err := Apply(nil, &model.Event{
+		ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts,
+		Action:  model.ActionCreate,
+		Payload: `{"title":"Enum test"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Apply(issue, &model.Event{
+		ID: 2, RepoID: 1, IssueID: 1, Timestamp: ts.Add(time.Hour),
+		Action:  model.ActionStatusChange,
+		Payload: `{"status":"frobnicate","from_status":"open"}`,
+	})
+	if err == nil || !strings.Contains(err.Error(), "frobnicate") {
+		t.Fatalf("expected error for unknown status, got %v", err)
+	}
+	if issue.Status != model.StatusOpen {
+		t.Errorf("status = %q after rejected event, want open", issue.Status)
+	}
+}
+
 func TestApply_StatusChangeWithoutFromStatus_LegacyAccepted(t *testing.T) {
 	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
 
@@ -893,6 +1111,129 @@ func TestApply_UpdateWithComment(t *testing.T) {
 	}
 }
 
+func TestReplay_CommentEditAndDelete(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []*model.Event{
+		{ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts, Action: model.ActionCreate, Payload: `{"title":"Comments"}`, Agent: "alice"},
+		{ID: 2, RepoID: 1, IssueID: 1, Timestamp: ts.Add(1 * time.Hour), Action: model.ActionComment, Payload: `{"comment":"first"}`, Agent: "alice"},
+		{ID: 3, RepoID: 1, IssueID: 1, Timestamp: ts.Add(2 * time.Hour), Action: model.ActionComment, Payload: `{"comment":"secnod"}`, Agent: "bob"},
+		{ID: 4, RepoID: 1, IssueID: 1, Timestamp: ts.Add(3 * time.Hour), Action: model.ActionComment, Payload: `{"comment":"third"}`, Agent: "carol"},
+		{ID: 5, RepoID: 1, IssueID: 1, Timestamp: ts.Add(4 * time.Hour), Action: model.ActionCommentEdit, Payload: `{"comment_index":1,"comment_text":"second"}`, Agent: "bob"},
+		{ID: 6, RepoID: 1, IssueID: 1, Timestamp: ts.Add(5 * time.Hour), Action: model.ActionCommentDelete, Payload: `{"comment_index":0}`, Agent: "alice"},
+		// Out of range: changes nothing.
+		{ID: 7, RepoID: 1, IssueID: 1, Timestamp: ts.Add(6 * time.Hour), Action: model.ActionCommentDelete, Payload: `{"comment_index":5}`},
+		{ID: 8, RepoID: 1, IssueID: 1, Timestamp: ts.Add(7 * time.Hour), Action: model.ActionCommentEdit, Payload: `{"comment_text":"no index"}`},
+	}
+
+	issues, err := Replay(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := issues[1]
+	want := []model.Comment{
+		{Text: "second", Author: "bob", Timestamp: "2025-01-01T02:00:00Z", EditedAt: "2025-01-01T04:00:00Z"},
+		{Text: "third", Author: "carol", Timestamp: "2025-01-01T03:00:00Z"},
+	}
+	if len(issue.Comments) != len(want) {
+		t.Fatalf("expected %d comments, got %+v", len(want), issue.Comments)
+	}
+	for i := range want {
+		if issue.Comments[i] != want[i] {
+			t.Errorf("comment %d = %+v, want %+v", i, issue.Comments[i], want[i])
+		}
+	}
+	if !issue.UpdatedAt.Equal(ts.Add(5 * time.Hour)) {
+		t.Errorf("updated_at = %v, want the delete's timestamp", issue.UpdatedAt)
+	}
+}
+
+func TestReplay_WatchAndUnwatch(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []*model.Event{
+		{ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts, Action: model.ActionCreate, Payload: `{"title":"Watched"}`},
+		{ID: 2, RepoID: 1, IssueID: 1, Timestamp: ts.Add(1 * time.Hour), Action: model.ActionWatch, Payload: `{"watcher":"carol"}`},
+		{ID: 3, RepoID: 1, IssueID: 1, Timestamp: ts.Add(2 * time.Hour), Action: model.ActionWatch, Payload: `{"watcher":"alice"}`},
+		// Already watching, in another case: changes nothing.
+		{ID: 4, RepoID: 1, IssueID: 1, Timestamp: ts.Add(3 * time.Hour), Action: model.ActionWatch, Payload: `{"watcher":"Carol"}`},
+		{ID: 5, RepoID: 1, IssueID: 1, Timestamp: ts.Add(4 * time.Hour), Action: model.ActionWatch, Payload: `{"watcher":"bob"}`},
+		{ID: 6, RepoID: 1, IssueID: 1, Timestamp: ts.Add(5 * time.Hour), Action: model.ActionUnwatch, Payload: `{"watcher":"ALICE"}`},
+		{ID: 7, RepoID: 1, IssueID: 1, Timestamp: ts.Add(6 * time.Hour), Action: model.ActionWatch, Payload: `{"watcher":" "}`},
+	}
+
+	issues, err := Replay(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := issues[1].Watchers; len(got) != 2 || got[0] != "bob" || got[1] != "carol" {
+		t.Errorf("watchers = %v, want [bob carol]", got)
+	}
+}
+
+func TestReplay_NormalizesLikeTheStore(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []*model.Event{
+		{ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts, Action: model.ActionCreate, Payload: `{"title":"t","labels":["b"," a","B"],"owner":" alice "}`},
+		{ID: 2, RepoID: 1, IssueID: 1, Timestamp: ts.Add(time.Minute), Action: model.ActionLabelRemove, Payload: `{"label":"a"}`},
+	}
+	issues, err := Replay(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := issues[1]
+	if got.Owner != "alice" || !slices.Equal(got.Labels, []string{"b"}) {
+		t.Errorf("got owner %q labels %q, want alice and [b]", got.Owner, got.Labels)
+	}
+}
+
+func TestReplay_SkipsDuplicateEvents(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	commentID := 42
+	create := &model.Event{ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts, Action: model.ActionCreate, Payload: `{"title":"Dup"}`, IdempotencyKey: "create"}
+	comment := &model.Event{ID: 2, RepoID: 1, IssueID: 1, Timestamp: ts.Add(time.Minute), Action: model.ActionComment, Payload: `{"comment":"looking"}`, GitHubCommentID: &commentID}
+	start := &model.Event{ID: 3, RepoID: 1, IssueID: 1, Timestamp: ts.Add(2 * time.Minute), Action: model.ActionStatusChange, Payload: `{"status":"in_progress","from_status":"open"}`, IdempotencyKey: "start"}
+
+	once, err := Replay([]*model.Event{create, comment, start})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Copies with new IDs, as when the same comment is parsed twice. The
+	// keyless comment copy is keyed by its GitHub comment.
+	copyOf := func(ev *model.Event, id int) *model.Event {
+		dup := *ev
+		dup.ID = id
+		return &dup
+	}
+	events := []*model.Event{
+		create, copyOf(create, 4), comment, copyOf(comment, 5),
+		start, copyOf(start, 6),
+	}
+	twice, err := Replay(events)
+	if err != nil {
+		t.Fatalf("Replay with duplicates: %v", err)
+	}
+
+	got, want := twice[1], once[1]
+	if len(got.Comments) != 1 || len(want.Comments) != 1 {
+		t.Fatalf("comments = %d, want 1 as after a single application", len(got.Comments))
+	}
+	if got.Status != want.Status || got.Title != want.Title || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("replayed with duplicates = %+v, want %+v", got, want)
+	}
+
+	// The same content at the same second under another key is a
+	// separate change, as is a keyless local event.
+	again := copyOf(comment, 7)
+	again.GitHubCommentID = nil
+	issues, err := Replay([]*model.Event{create, comment, again, copyOf(again, 8)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(issues[1].Comments); n != 3 {
+		t.Errorf("comments = %d, want 3 for separately keyed events", n)
+	}
+}
+
 func TestApply_CommentOnNilIssueErrors(t *testing.T) {
 	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
 	_, err := Apply(nil, &model.Event{
@@ -910,3 +1251,277 @@ func TestApply_CommentOnNilIssueErrors(t *testing.T) {
 func TestReplay_LegacyNoFromStatus(t *testing.T) {
 	runFixture(t, "legacy_no_from_status.json")
 }
+
+func TestApply_SnoozeAndClear(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	issue, err := Apply(nil, &model.Event{
+		ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts,
+		Action:  model.ActionCreate,
+		Payload: `{"title":"Snooze test"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue, err = Apply(issue, &model.Event{
+		ID: 2, RepoID: 1, IssueID: 1, Timestamp: ts.Add(time.Hour),
+		Action:  model.ActionSnooze,
+		Payload: `{"snoozed_until":"2025-01-02T00:00:00Z"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	if issue.SnoozedUntil == nil || !issue.SnoozedUntil.Equal(want) {
+		t.Fatalf("SnoozedUntil = %v, want %v", issue.SnoozedUntil, want)
+	}
+	if !issue.IsSnoozed(ts.Add(2 * time.Hour)) {
+		t.Error("expected issue to be snoozed before the deadline")
+	}
+	if issue.IsSnoozed(want.Add(time.Second)) {
+		t.Error("expected issue to wake after the deadline")
+	}
+
+	// A snooze event without a deadline clears it.
+	issue, err = Apply(issue, &model.Event{
+		ID: 3, RepoID: 1, IssueID: 1, Timestamp: ts.Add(2 * time.Hour),
+		Action:  model.ActionSnooze,
+		Payload: `{}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.SnoozedUntil != nil {
+		t.Errorf("expected snooze cleared, got %v", issue.SnoozedUntil)
+	}
+}
+
+func TestApply_LabelEventsMergeAcrossAgents(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	label := func(agent string, action model.Action, l string) *model.Event {
+		return &model.Event{RepoID: 1, IssueID: 1, Action: action, Agent: agent, Payload: `{"label":"` + l + `"}`}
+	}
+	alice := []*model.Event{
+		label("alice", model.ActionLabelAdd, "api"),
+		label("alice", model.ActionLabelRemove, "base"),
+	}
+	bob := []*model.Event{
+		label("bob", model.ActionLabelAdd, "Bug"),
+		label("bob", model.ActionLabelAdd, "ui"),
+		label("bob", model.ActionLabelRemove, "UI"),
+		label("bob", model.ActionLabelAdd, "API"), // already added by alice
+	}
+
+	// Every interleaving that keeps each agent's own order yields the same
+	// labels in the same order.
+	var interleave func(a, b []*model.Event) [][]*model.Event
+	interleave = func(a, b []*model.Event) [][]*model.Event {
+		if len(a) == 0 || len(b) == 0 {
+			return [][]*model.Event{append(append([]*model.Event{}, a...), b...)}
+		}
+		var out [][]*model.Event
+		for _, rest := range interleave(a[1:], b) {
+			out = append(out, append([]*model.Event{a[0]}, rest...))
+		}
+		for _, rest := range interleave(a, b[1:]) {
+			out = append(out, append([]*model.Event{b[0]}, rest...))
+		}
+		return out
+	}
+
+	orders := interleave(alice, bob)
+	if len(orders) != 15 {
+		t.Fatalf("expected 15 interleavings, got %d", len(orders))
+	}
+	want := []string{"API", "Bug"}
+	for n, order := range orders {
+		issue, err := Apply(nil, &model.Event{
+			RepoID: 1, IssueID: 1, Timestamp: ts,
+			Action:  model.ActionCreate,
+			Payload: `{"title":"Labels","labels":["base"]}`,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, ev := range order {
+			ev := *ev
+			ev.Timestamp = ts.Add(time.Duration(i+1) * time.Minute)
+			if issue, err = Apply(issue, &ev); err != nil {
+				t.Fatalf("order %d: apply %s: %v", n, ev.Action, err)
+			}
+		}
+		if len(issue.Labels) != len(want) || issue.Labels[0] != want[0] || issue.Labels[1] != want[1] {
+			t.Errorf("order %d: labels = %v, want %v", n, issue.Labels, want)
+		}
+	}
+}
+
+func TestApply_LabelEventWithoutLabelIgnored(t *testing.T) {
+	issue := &model.Issue{ID: 1, Labels: []string{"keep"}}
+	for _, action := range []model.Action{model.ActionLabelAdd, model.ActionLabelRemove} {
+		got, err := Apply(issue, &model.Event{IssueID: 1, Action: action, Payload: `{}`})
+		if err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+		if len(got.Labels) != 1 || got.Labels[0] != "keep" {
+			t.Errorf("%s without a label changed labels to %v", action, got.Labels)
+		}
+	}
+}
+
+func TestApply_PriorityChange(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue := &model.Issue{ID: 1, Status: model.StatusOpen, Priority: 3, Title: "keep"}
+
+	// from_priority is recorded but not checked, so a stale value still
+	// applies.
+	got, err := Apply(issue, &model.Event{IssueID: 1, Timestamp: ts, Action: model.ActionPriorityChange,
+		Payload: `{"priority":1,"from_priority":2}`})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if got.Priority != 1 || got.Title != "keep" || !got.UpdatedAt.Equal(ts) {
+		t.Errorf("got priority %d title %q updated %v, want 1, keep, %v", got.Priority, got.Title, got.UpdatedAt, ts)
+	}
+
+	got, _ = Apply(got, &model.Event{IssueID: 1, Timestamp: ts, Action: model.ActionPriorityChange, Payload: `{"priority":99}`}, WithPriorityRange(0, 5))
+	if got.Priority != 5 {
+		t.Errorf("out-of-range priority: got %d, want clamped to 5", got.Priority)
+	}
+
+	got, _ = Apply(got, &model.Event{IssueID: 1, Timestamp: ts, Action: model.ActionPriorityChange, Payload: `{}`})
+	if got.Priority != 5 {
+		t.Errorf("priority_change without a priority changed it to %d", got.Priority)
+	}
+
+	if _, err := Apply(nil, &model.Event{IssueID: 2, Action: model.ActionPriorityChange, Payload: `{"priority":1}`}); err == nil {
+		t.Error("expected error for priority_change on a missing issue")
+	}
+}
+
+func TestApply_ParentEvents(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue := &model.Issue{ID: 2, Status: model.StatusOpen}
+	apply := func(action model.Action, payload string) {
+		t.Helper()
+		var err error
+		issue, err = Apply(issue, &model.Event{IssueID: 2, Timestamp: ts, Action: action, Payload: payload})
+		if err != nil {
+			t.Fatalf("%s %s: %v", action, payload, err)
+		}
+	}
+	parent := func() int {
+		if issue.ParentID == nil {
+			return 0
+		}
+		return *issue.ParentID
+	}
+
+	apply(model.ActionSetParent, `{"parent_id":7}`)
+	if parent() != 7 {
+		t.Fatalf("set_parent: parent = %d, want 7", parent())
+	}
+	apply(model.ActionSetParent, `{"parent_id":2}`) // self: a cycle
+	apply(model.ActionSetParent, `{}`)              // parent stripped on pull
+	if parent() != 7 {
+		t.Errorf("self or empty set_parent changed parent to %d", parent())
+	}
+
+	// A clear naming a parent the issue no longer has is stale.
+	apply(model.ActionClearParent, `{"parent_id":5}`)
+	if parent() != 7 {
+		t.Errorf("stale clear_parent changed parent to %d", parent())
+	}
+	apply(model.ActionClearParent, `{"parent_id":7}`)
+	if issue.ParentID != nil {
+		t.Errorf("clear_parent left parent %d", parent())
+	}
+
+	if _, err := Apply(nil, &model.Event{IssueID: 3, Action: model.ActionSetParent, Payload: `{"parent_id":1}`}); err == nil {
+		t.Error("expected error for set_parent on a missing issue")
+	}
+}
+
+func TestApply_Dependencies(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue := &model.Issue{ID: 1, Status: model.StatusOpen}
+	dep := func(action model.Action, payload string) {
+		t.Helper()
+		var err error
+		issue, err = Apply(issue, &model.Event{IssueID: 1, Timestamp: ts, Action: action, Payload: payload})
+		if err != nil {
+			t.Fatalf("%s %s: %v", action, payload, err)
+		}
+	}
+
+	dep(model.ActionAddDependency, `{"blocker_id":5}`)
+	dep(model.ActionAddDependency, `{"blocker_id":3}`)
+	dep(model.ActionAddDependency, `{"blocker_id":5}`) // duplicate
+	dep(model.ActionAddDependency, `{"blocker_id":1}`) // self
+	dep(model.ActionAddDependency, `{}`)               // missing blocker
+	if len(issue.BlockedBy) != 2 || issue.BlockedBy[0] != 3 || issue.BlockedBy[1] != 5 {
+		t.Fatalf("after adds: blocked_by = %v, want [3 5]", issue.BlockedBy)
+	}
+
+	dep(model.ActionRemoveDependency, `{"blocker_id":3}`)
+	dep(model.ActionRemoveDependency, `{"blocker_id":9}`) // not a blocker
+	if len(issue.BlockedBy) != 1 || issue.BlockedBy[0] != 5 {
+		t.Errorf("after removes: blocked_by = %v, want [5]", issue.BlockedBy)
+	}
+
+	if _, err := Apply(nil, &model.Event{IssueID: 2, Action: model.ActionAddDependency, Payload: `{"blocker_id":1}`}); err == nil {
+		t.Error("expected error adding a dependency to a non-existent issue")
+	}
+}
+
+func TestFieldHistory(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []*model.Event{
+		{ID: 1, RepoID: 1, IssueID: 1, Timestamp: ts, Agent: "alice",
+			Action: model.ActionCreate, Payload: `{"title":"History","priority":2}`},
+		{ID: 2, RepoID: 1, IssueID: 1, Timestamp: ts.Add(time.Hour), Agent: "bob",
+			Action: model.ActionUpdate, Payload: `{"title":"History v2"}`},
+		{ID: 3, RepoID: 1, IssueID: 1, Timestamp: ts.Add(2 * time.Hour), Agent: "carol",
+			Action: model.ActionUpdate, Payload: `{"priority":0}`},
+		{ID: 4, RepoID: 1, IssueID: 1, Timestamp: ts.Add(3 * time.Hour), Agent: "dave",
+			Action: model.ActionClose, Payload: `{}`},
+		// Ignored by the engine: status_change on a closed issue.
+		{ID: 5, RepoID: 1, IssueID: 1, Timestamp: ts.Add(4 * time.Hour), Agent: "eve",
+			Action: model.ActionStatusChange, Payload: `{"status":"in_progress","from_status":"open"}`},
+	}
+
+	priority, err := FieldHistory(events, "priority")
+	if err != nil {
+		t.Fatalf("FieldHistory(priority): %v", err)
+	}
+	if len(priority) != 2 {
+		t.Fatalf("expected 2 priority entries, got %d: %+v", len(priority), priority)
+	}
+	if priority[0].Value != 2 || priority[0].Agent != "alice" {
+		t.Errorf("priority[0] = %+v, want value 2 by alice", priority[0])
+	}
+	if priority[1].Value != 0 || priority[1].Agent != "carol" || !priority[1].Timestamp.Equal(ts.Add(2*time.Hour)) {
+		t.Errorf("priority[1] = %+v, want value 0 by carol", priority[1])
+	}
+
+	status, err := FieldHistory(events, "status")
+	if err != nil {
+		t.Fatalf("FieldHistory(status): %v", err)
+	}
+	if len(status) != 2 || status[0].Value != "open" || status[1].Value != "closed" || status[1].EventID != 4 {
+		t.Errorf("unexpected status history: %+v", status)
+	}
+
+	title, err := FieldHistory(events, "title")
+	if err != nil {
+		t.Fatalf("FieldHistory(title): %v", err)
+	}
+	if len(title) != 2 || title[1].Value != "History v2" {
+		t.Errorf("unexpected title history: %+v", title)
+	}
+
+	if _, err := FieldHistory(events, "description"); err == nil {
+		t.Error("expected error for unsupported field")
+	}
+}
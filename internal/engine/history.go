@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+// FieldChange is a value a single issue field took on, and who set it.
+type FieldChange struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Value     interface{} `json:"value"`
+	Agent     string      `json:"agent"`
+	EventID   int         `json:"event_id"`
+}
+
+// HistoryFields lists the issue fields FieldHistory can track.
+var HistoryFields = []string{"status", "owner", "priority", "issue_type", "title"}
+
+// FieldHistory replays one issue's events (sorted oldest first) and returns
+// each value the named field took on: the initial value from the create
+// event, then one entry per event that changed it. Events that leave the
+// field unchanged (including ones the engine ignores, such as a status change
+// on a closed issue) are skipped. Each event is applied with opts.
+func FieldHistory(events []*model.Event, field string, opts ...Option) ([]FieldChange, error) {
+	if _, ok := fieldValue(&model.Issue{}, field); !ok {
+		return nil, fmt.Errorf("unsupported field %q", field)
+	}
+
+	history := []FieldChange{}
+	var issue *model.Issue
+	for _, ev := range events {
+		var before interface{}
+		if issue != nil {
+			before, _ = fieldValue(issue, field)
+		}
+		updated, err := Apply(issue, ev, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("applying event %d (action=%s): %w", ev.ID, ev.Action, err)
+		}
+		issue = updated
+
+		after, _ := fieldValue(issue, field)
+		if ev.Action != model.ActionCreate && after == before {
+			continue
+		}
+		history = append(history, FieldChange{
+			Timestamp: ev.Timestamp,
+			Value:     after,
+			Agent:     ev.Agent,
+			EventID:   ev.ID,
+		})
+	}
+	return history, nil
+}
+
+// fieldValue returns the current value of a tracked field.
+func fieldValue(issue *model.Issue, field string) (interface{}, bool) {
+	switch field {
+	case "status":
+		return string(issue.Status), true
+	case "owner":
+		return issue.Owner, true
+	case "priority":
+		return issue.Priority, true
+	case "issue_type":
+		return string(issue.IssueType), true
+	case "title":
+		return issue.Title, true
+	default:
+		return nil, false
+	}
+}
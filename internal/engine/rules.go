@@ -1,8 +1,14 @@
 package engine
 
-import "github.com/jmaddaus/boxofrocks/internal/model"
+import (
+	"strings"
 
-// IsTerminal returns true if the status is a terminal state (no further transitions allowed).
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+// IsTerminal returns true if the status is a terminal state: status changes,
+// closes, reopens and deletes leave it alone. A restore event is the only
+// transition out of it.
 func IsTerminal(s model.Status) bool {
 	return s == model.StatusDeleted
 }
@@ -12,3 +18,71 @@ func IsTerminal(s model.Status) bool {
 func FromStatusMatch(current, from model.Status) bool {
 	return from == "" || from == current
 }
+
+// NormalizeIssue trims the owner and labels and drops empty labels and
+// labels that differ only in case from an earlier one, so that "Alice" and
+// " alice" are not stored as distinct values by different agents. Case is
+// preserved; filters match owner and labels with COLLATE NOCASE. Apply
+// normalizes every issue it returns, and the store every row it writes, so
+// a stored row and the replay of its events agree.
+func NormalizeIssue(issue *model.Issue) {
+	issue.Owner = strings.TrimSpace(issue.Owner)
+	labels := make([]string, 0, len(issue.Labels))
+	seen := make(map[string]bool, len(issue.Labels))
+	for _, l := range issue.Labels {
+		l = strings.TrimSpace(l)
+		key := strings.ToLower(l)
+		if l == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		labels = append(labels, l)
+	}
+	issue.Labels = labels
+}
+
+// Option adjusts how Apply, Replay and FieldHistory treat events.
+type Option func(*options)
+
+type options struct {
+	limitPriority bool
+	minPriority   int
+	maxPriority   int
+}
+
+// WithPriorityRange clamps every priority an event sets to the inclusive
+// range min to max, so that a corrupt or hostile event comment cannot push
+// an issue's priority out of range; the API rejects out-of-range values
+// outright instead. Without it, priorities apply as the events give them.
+func WithPriorityRange(min, max int) Option {
+	return func(o *options) {
+		o.limitPriority, o.minPriority, o.maxPriority = true, min, max
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// priority returns p, clamped if a priority range was given.
+func (o *options) priority(p int) int {
+	if !o.limitPriority {
+		return p
+	}
+	return ClampPriority(p, o.minPriority, o.maxPriority)
+}
+
+// ClampPriority limits p to the inclusive range min to max.
+func ClampPriority(p, min, max int) int {
+	if p < min {
+		return min
+	}
+	if p > max {
+		return max
+	}
+	return p
+}
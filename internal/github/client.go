@@ -6,12 +6,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
 )
 
 const (
@@ -27,9 +31,22 @@ type GitHubIssue struct {
 	Body              string        `json:"body"`
 	State             string        `json:"state"`
 	Labels            []GitHubLabel `json:"labels"`
+	Assignees         []GitHubUser  `json:"assignees"`
 	AuthorAssociation string        `json:"author_association"`
 	CreatedAt         time.Time     `json:"created_at"`
 	UpdatedAt         time.Time     `json:"updated_at"`
+	ClosedAt          *time.Time    `json:"closed_at"`
+}
+
+// HasLabel reports whether the issue carries the label. GitHub label names
+// ignore case.
+func (i *GitHubIssue) HasLabel(name string) bool {
+	for _, l := range i.Labels {
+		if strings.EqualFold(l.Name, name) {
+			return true
+		}
+	}
+	return false
 }
 
 // GitHubLabel represents a label on a GitHub issue.
@@ -39,10 +56,16 @@ type GitHubLabel struct {
 
 // GitHubComment represents a comment on a GitHub issue.
 type GitHubComment struct {
-	ID                int       `json:"id"`
-	Body              string    `json:"body"`
-	AuthorAssociation string    `json:"author_association"`
-	CreatedAt         time.Time `json:"created_at"`
+	ID                int        `json:"id"`
+	Body              string     `json:"body"`
+	User              GitHubUser `json:"user"`
+	AuthorAssociation string     `json:"author_association"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// GitHubUser is the author of a GitHub comment or an issue assignee.
+type GitHubUser struct {
+	Login string `json:"login"`
 }
 
 // GitHubRepo represents a GitHub repository from the REST API.
@@ -65,55 +88,98 @@ type RateLimit struct {
 	Reset     time.Time
 }
 
+// The label that marks a GitHub issue as tracked by boxofrocks, and the
+// color/description used when creating it.
+const (
+	TrackingLabel            = model.DefaultTrackingLabel
+	TrackingLabelColor       = "6f42c1"
+	TrackingLabelDescription = "Tracked by boxofrocks"
+)
+
 // Client defines the interface for interacting with the GitHub REST API.
 type Client interface {
 	ListIssues(ctx context.Context, owner, repo string, opts ListOpts) ([]*GitHubIssue, string, error)
 	GetIssue(ctx context.Context, owner, repo string, number int) (*GitHubIssue, error)
 	GetRepo(ctx context.Context, owner, repo string) (*GitHubRepo, error)
+	ListCollaborators(ctx context.Context, owner, repo string) ([]string, error)
 	CreateIssue(ctx context.Context, owner, repo, title, body string, labels []string) (*GitHubIssue, error)
 	UpdateIssueBody(ctx context.Context, owner, repo string, number int, body string) error
 	UpdateIssueState(ctx context.Context, owner, repo string, number int, state string) error
 	ListComments(ctx context.Context, owner, repo string, number int, opts ListOpts) ([]*GitHubComment, string, error)
 	CreateComment(ctx context.Context, owner, repo string, number int, body string) (*GitHubComment, error)
+	GetComment(ctx context.Context, owner, repo string, commentID int) (*GitHubComment, error)
 	AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) error
-	CreateLabel(ctx context.Context, owner, repo, name, color, description string) error
+	RemoveLabelFromIssue(ctx context.Context, owner, repo string, number int, label string) error
+	AddAssignees(ctx context.Context, owner, repo string, number int, logins []string) error
+	RemoveAssignees(ctx context.Context, owner, repo string, number int, logins []string) error
+	CreateLabel(ctx context.Context, owner, repo, name, color, description string) (bool, error)
+	UpdateLabel(ctx context.Context, owner, repo, name, color, description string) error
 	GetRateLimit() RateLimit
 }
 
 // clientImpl is the concrete implementation of Client.
 type clientImpl struct {
-	token      string
+	tokens     TokenProvider
 	httpClient *http.Client
 	baseURL    string
 
+	retry RetryPolicy
+
 	mu        sync.RWMutex
 	rateLimit RateLimit
 }
 
 // NewClient creates a new GitHub API client with the given token.
 func NewClient(token string) Client {
+	return NewClientWithBaseURL(token, "")
+}
+
+// NewClientWithBaseURL creates a client for another API host, such as
+// GitHub Enterprise Server ("https://github.example.com/api/v3"). An empty
+// baseURL means api.github.com.
+func NewClientWithBaseURL(token, baseURL string) Client {
+	return NewClientWithRetry(token, baseURL, DefaultRetryPolicy)
+}
+
+// NewClientWithRetry is like NewClientWithBaseURL but with an explicit retry
+// policy.
+func NewClientWithRetry(token, baseURL string, retry RetryPolicy) Client {
+	return NewClientWithProvider(StaticToken(token), baseURL, retry)
+}
+
+// NewClientWithProvider is like NewClientWithRetry but asks tokens for the
+// token on each request, for credentials that expire, such as GitHub App
+// installation tokens.
+func NewClientWithProvider(tokens TokenProvider, baseURL string, retry RetryPolicy) Client {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
 	return &clientImpl{
-		token:      token,
+		tokens:     tokens,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
-		baseURL:    defaultBaseURL,
+		baseURL:    baseURL,
+		retry:      retry,
 	}
 }
 
 // NewClientWithHTTP creates a new GitHub API client with a custom http.Client (useful for testing).
 func NewClientWithHTTP(token string, httpClient *http.Client) Client {
 	return &clientImpl{
-		token:      token,
+		tokens:     StaticToken(token),
 		httpClient: httpClient,
 		baseURL:    defaultBaseURL,
+		retry:      DefaultRetryPolicy,
 	}
 }
 
 // newClientWithBaseURL is an internal constructor for testing with httptest servers.
 func newClientWithBaseURL(token string, httpClient *http.Client, baseURL string) *clientImpl {
 	return &clientImpl{
-		token:      token,
+		tokens:     StaticToken(token),
 		httpClient: httpClient,
 		baseURL:    baseURL,
+		retry:      DefaultRetryPolicy,
 	}
 }
 
@@ -132,7 +198,11 @@ func (c *clientImpl) newRequest(ctx context.Context, method, url string, body in
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get GitHub token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Accept", acceptHeader)
 	req.Header.Set("User-Agent", userAgent)
 	if body != nil {
@@ -142,13 +212,43 @@ func (c *clientImpl) newRequest(ctx context.Context, method, url string, body in
 	return req, nil
 }
 
+// do sends req, retrying transient failures per the client's RetryPolicy.
+// The request's context aborts the wait between attempts.
 func (c *clientImpl) do(req *http.Request) (*http.Response, error) {
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	for attempt := 0; ; attempt++ {
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		c.updateRateLimit(resp)
+
+		wait, retry := c.retry.retryDelay(req, resp, attempt)
+		if !retry {
+			return resp, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		slog.Debug("retrying github request",
+			"method", req.Method, "url", req.URL.String(),
+			"status", resp.StatusCode, "attempt", attempt+1, "wait", wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
 	}
-	c.updateRateLimit(resp)
-	return resp, nil
 }
 
 func (c *clientImpl) updateRateLimit(resp *http.Response) {
@@ -288,6 +388,34 @@ func (c *clientImpl) GetIssue(ctx context.Context, owner, repo string, number in
 	return &issue, nil
 }
 
+// GetComment fetches a single issue comment by its ID.
+func (c *clientImpl) GetComment(ctx context.Context, owner, repo string, commentID int) (*GitHubComment, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d", c.baseURL, owner, repo, commentID)
+
+	req, err := c.newRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get comment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get comment: unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var comment GitHubComment
+	if err := json.NewDecoder(resp.Body).Decode(&comment); err != nil {
+		return nil, fmt.Errorf("get comment: decode response: %w", err)
+	}
+
+	return &comment, nil
+}
+
 // GetRepo fetches repository metadata (including visibility).
 func (c *clientImpl) GetRepo(ctx context.Context, owner, repo string) (*GitHubRepo, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s", c.baseURL, owner, repo)
@@ -316,6 +444,45 @@ func (c *clientImpl) GetRepo(ctx context.Context, owner, repo string) (*GitHubRe
 	return &ghRepo, nil
 }
 
+// ListCollaborators returns the logins of everyone who can be assigned the
+// repo's issues: its collaborators, including those with access through an
+// organization or team. Listing them needs push access to the repo.
+func (c *clientImpl) ListCollaborators(ctx context.Context, owner, repo string) ([]string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/collaborators?per_page=100", c.baseURL, owner, repo)
+
+	var logins []string
+	for url != "" {
+		req, err := c.newRequest(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("list collaborators: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("list collaborators: unexpected status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var users []GitHubUser
+		if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("list collaborators: decode response: %w", err)
+		}
+		resp.Body.Close()
+
+		for _, u := range users {
+			logins = append(logins, u.Login)
+		}
+		url = parseLinkNext(resp.Header.Get("Link"))
+	}
+	return logins, nil
+}
+
 // IsTrustedAuthor returns true if the given GitHub author_association value
 // indicates a trusted contributor (OWNER, MEMBER, COLLABORATOR, or CONTRIBUTOR).
 func IsTrustedAuthor(association string) bool {
@@ -545,9 +712,74 @@ func (c *clientImpl) AddLabelsToIssue(ctx context.Context, owner, repo string, n
 	return nil
 }
 
-// CreateLabel creates a label in the specified repository.
-// If the label already exists (422), it is not treated as an error.
-func (c *clientImpl) CreateLabel(ctx context.Context, owner, repo, name, color, description string) error {
+// RemoveLabelFromIssue removes a label from an issue. A 404, meaning the
+// issue does not carry the label, is not an error.
+func (c *clientImpl) RemoveLabelFromIssue(ctx context.Context, owner, repo string, number int, label string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/labels/%s", c.baseURL, owner, repo, number, url.PathEscape(label))
+
+	req, err := c.newRequest(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("remove label from issue: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("remove label from issue: unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// AddAssignees adds GitHub logins to an issue's assignees. Logins that
+// cannot be assigned are silently ignored by GitHub.
+func (c *clientImpl) AddAssignees(ctx context.Context, owner, repo string, number int, logins []string) error {
+	return c.changeAssignees(ctx, http.MethodPost, owner, repo, number, logins)
+}
+
+// RemoveAssignees removes GitHub logins from an issue's assignees. Logins
+// that are not assigned are ignored.
+func (c *clientImpl) RemoveAssignees(ctx context.Context, owner, repo string, number int, logins []string) error {
+	return c.changeAssignees(ctx, http.MethodDelete, owner, repo, number, logins)
+}
+
+func (c *clientImpl) changeAssignees(ctx context.Context, method, owner, repo string, number int, logins []string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/assignees", c.baseURL, owner, repo, number)
+
+	payload := map[string][]string{
+		"assignees": logins,
+	}
+
+	req, err := c.newRequest(ctx, method, url, payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("update assignees: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("update assignees: unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// CreateLabel creates a label in the specified repository and reports whether
+// it was newly created. If the label already exists (422), it returns false
+// without an error.
+func (c *clientImpl) CreateLabel(ctx context.Context, owner, repo, name, color, description string) (bool, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/labels", c.baseURL, owner, repo)
 
 	// Strip leading '#' from color if present
@@ -561,21 +793,55 @@ func (c *clientImpl) CreateLabel(ctx context.Context, owner, repo, name, color,
 
 	req, err := c.newRequest(ctx, http.MethodPost, url, payload)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	resp, err := c.do(req)
 	if err != nil {
-		return fmt.Errorf("create label: %w", err)
+		return false, fmt.Errorf("create label: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 201 Created or 422 Unprocessable Entity (already exists) are both OK
-	if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusUnprocessableEntity {
+	switch resp.StatusCode {
+	case http.StatusCreated:
 		io.Copy(io.Discard, resp.Body)
-		return nil
+		return true, nil
+	case http.StatusUnprocessableEntity:
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
 	}
 
 	respBody, _ := io.ReadAll(resp.Body)
-	return fmt.Errorf("create label: unexpected status %d: %s", resp.StatusCode, string(respBody))
+	return false, fmt.Errorf("create label: unexpected status %d: %s", resp.StatusCode, string(respBody))
+}
+
+// UpdateLabel sets the color and description of an existing label in the
+// specified repository.
+func (c *clientImpl) UpdateLabel(ctx context.Context, owner, repo, name, color, description string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/labels/%s", c.baseURL, owner, repo, url.PathEscape(name))
+
+	payload := map[string]string{
+		"color":       strings.TrimPrefix(color, "#"),
+		"description": description,
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPatch, url, payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("update label: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("update label: unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	return nil
 }
@@ -3,10 +3,13 @@ package github
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -321,10 +324,13 @@ func TestCreateLabel_Success(t *testing.T) {
 	})
 	defer ts.Close()
 
-	err := client.CreateLabel(context.Background(), "owner", "repo", "boxofrocks", "#0e8a16", "Managed by boxofrocks")
+	created, err := client.CreateLabel(context.Background(), "owner", "repo", "boxofrocks", "#0e8a16", "Managed by boxofrocks")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if !created {
+		t.Error("expected created=true on 201")
+	}
 }
 
 func TestCreateLabel_AlreadyExists(t *testing.T) {
@@ -335,10 +341,86 @@ func TestCreateLabel_AlreadyExists(t *testing.T) {
 	})
 	defer ts.Close()
 
-	err := client.CreateLabel(context.Background(), "owner", "repo", "boxofrocks", "0e8a16", "desc")
+	created, err := client.CreateLabel(context.Background(), "owner", "repo", "boxofrocks", "0e8a16", "desc")
 	if err != nil {
 		t.Fatalf("expected no error on 422 (already exists), got: %v", err)
 	}
+	if created {
+		t.Error("expected created=false on 422")
+	}
+}
+
+func TestUpdateLabel(t *testing.T) {
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH, got %s", r.Method)
+		}
+		if r.URL.EscapedPath() != "/repos/owner/repo/labels/good%20first%20issue" {
+			t.Errorf("unexpected path: %s", r.URL.EscapedPath())
+		}
+
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload["color"] != "7057ff" {
+			t.Errorf("expected color '7057ff', got %v", payload["color"])
+		}
+		if payload["description"] != "Good for newcomers" {
+			t.Errorf("expected description 'Good for newcomers', got %v", payload["description"])
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"name":"good first issue","color":"7057ff"}`))
+	})
+	defer ts.Close()
+
+	if err := client.UpdateLabel(context.Background(), "owner", "repo", "good first issue", "#7057ff", "Good for newcomers"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestUpdateLabel_NotFound(t *testing.T) {
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	})
+	defer ts.Close()
+
+	if err := client.UpdateLabel(context.Background(), "owner", "repo", "missing", "000000", ""); err == nil {
+		t.Fatal("expected error updating a missing label")
+	}
+}
+
+func TestRemoveLabelFromIssue(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusNotFound} {
+		ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodDelete {
+				t.Errorf("expected DELETE, got %s", r.Method)
+			}
+			if r.URL.EscapedPath() != "/repos/owner/repo/issues/5/labels/status%2Fblocked" {
+				t.Errorf("unexpected path: %s", r.URL.EscapedPath())
+			}
+			w.WriteHeader(status)
+			w.Write([]byte("[]"))
+		})
+
+		if err := client.RemoveLabelFromIssue(context.Background(), "owner", "repo", 5, "status/blocked"); err != nil {
+			t.Errorf("status %d: unexpected error: %v", status, err)
+		}
+		ts.Close()
+	}
+}
+
+func TestRemoveLabelFromIssue_Error(t *testing.T) {
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+	})
+	defer ts.Close()
+
+	err := client.RemoveLabelFromIssue(context.Background(), "owner", "repo", 5, "bug")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected 403 error, got %v", err)
+	}
 }
 
 func TestRateLimitTracking(t *testing.T) {
@@ -461,6 +543,48 @@ func TestUpdateIssueState_Success(t *testing.T) {
 	}
 }
 
+func TestAssignees(t *testing.T) {
+	var gotMethods []string
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/issues/42/assignees" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var payload map[string][]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		if len(payload["assignees"]) != 1 || payload["assignees"][0] != "octocat" {
+			t.Errorf("expected assignees [octocat], got %v", payload["assignees"])
+		}
+		gotMethods = append(gotMethods, r.Method)
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{}`))
+	})
+	defer ts.Close()
+
+	if err := client.AddAssignees(context.Background(), "owner", "repo", 42, []string{"octocat"}); err != nil {
+		t.Fatalf("AddAssignees: %v", err)
+	}
+	if err := client.RemoveAssignees(context.Background(), "owner", "repo", 42, []string{"octocat"}); err != nil {
+		t.Fatalf("RemoveAssignees: %v", err)
+	}
+	if len(gotMethods) != 2 || gotMethods[0] != http.MethodPost || gotMethods[1] != http.MethodDelete {
+		t.Errorf("expected POST then DELETE, got %v", gotMethods)
+	}
+}
+
+func TestAssignees_Error(t *testing.T) {
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	})
+	defer ts.Close()
+
+	if err := client.AddAssignees(context.Background(), "owner", "repo", 999, []string{"octocat"}); err == nil {
+		t.Fatal("expected error for 404 response")
+	}
+}
+
 func TestUpdateIssueState_Error(t *testing.T) {
 	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -519,6 +643,53 @@ func TestGetRepo_Error(t *testing.T) {
 	}
 }
 
+func TestListCollaborators_Pagination(t *testing.T) {
+	callCount := 0
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		if r.URL.Path != "/repos/owner/repo/collaborators" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if callCount == 1 {
+			nextURL := fmt.Sprintf("http://%s%s?page=2&per_page=100", r.Host, r.URL.Path)
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextURL))
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode([]GitHubUser{{Login: "alice"}, {Login: "bob"}})
+		} else {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode([]GitHubUser{{Login: "carol"}})
+		}
+	})
+	defer ts.Close()
+
+	logins, err := client.ListCollaborators(context.Background(), "owner", "repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(logins, ","); got != "alice,bob,carol" {
+		t.Errorf("expected alice,bob,carol across 2 pages, got %s", got)
+	}
+	if callCount != 2 {
+		t.Errorf("expected 2 HTTP requests, got %d", callCount)
+	}
+}
+
+func TestListCollaborators_Error(t *testing.T) {
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Must have push access to view repository collaborators."}`))
+	})
+	defer ts.Close()
+
+	_, err := client.ListCollaborators(context.Background(), "owner", "repo")
+	if err == nil {
+		t.Fatal("expected error for 403 response")
+	}
+	if !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected status in error, got %v", err)
+	}
+}
+
 func TestIsTrustedAuthor(t *testing.T) {
 	tests := []struct {
 		association string
@@ -545,3 +716,177 @@ func TestIsTrustedAuthor(t *testing.T) {
 		})
 	}
 }
+
+// fastRetry keeps retry tests quick while exercising the real policy.
+var fastRetry = RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond, MaxDelay: time.Second}
+
+func TestRetry_ServerErrorThenSuccess(t *testing.T) {
+	calls := 0
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(GitHubIssue{Number: 7, Title: "Recovered"})
+	})
+	defer ts.Close()
+	client.retry = fastRetry
+
+	issue, err := client.GetIssue(context.Background(), "owner", "repo", 7)
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if issue.Title != "Recovered" || calls != 3 {
+		t.Errorf("got %q after %d calls, want Recovered after 3", issue.Title, calls)
+	}
+}
+
+func TestRetry_ResendsBody(t *testing.T) {
+	var bodies []string
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer ts.Close()
+	client.retry = fastRetry
+
+	if err := client.UpdateIssueBody(context.Background(), "owner", "repo", 1, "new body"); err != nil {
+		t.Fatalf("UpdateIssueBody: %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || !strings.Contains(bodies[1], "new body") {
+		t.Errorf("expected the same body on both attempts, got %q", bodies)
+	}
+}
+
+func TestRetry_SecondaryRateLimit(t *testing.T) {
+	calls := 0
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"You have exceeded a secondary rate limit"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(GitHubComment{ID: 1, Body: "hi"})
+	})
+	defer ts.Close()
+	client.retry = fastRetry
+
+	if _, err := client.CreateComment(context.Background(), "owner", "repo", 1, "hi"); err != nil {
+		t.Fatalf("expected success after rate-limit retry, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestRetry_PostNotRetriedOnServerError(t *testing.T) {
+	calls := 0
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	defer ts.Close()
+	client.retry = fastRetry
+
+	if _, err := client.CreateComment(context.Background(), "owner", "repo", 1, "hi"); err == nil {
+		t.Fatal("expected error for 502 response")
+	}
+	if calls != 1 {
+		t.Errorf("a POST that may have succeeded must not be repeated; got %d calls", calls)
+	}
+}
+
+func TestRetry_GivesUp(t *testing.T) {
+	calls := 0
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer ts.Close()
+	client.retry = fastRetry
+
+	if _, err := client.GetIssue(context.Background(), "owner", "repo", 1); err == nil {
+		t.Fatal("expected error once retries are exhausted")
+	}
+	if calls != fastRetry.MaxRetries+1 {
+		t.Errorf("expected %d calls, got %d", fastRetry.MaxRetries+1, calls)
+	}
+}
+
+func TestRetry_ContextCancelAbortsWait(t *testing.T) {
+	ts, client := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer ts.Close()
+	client.retry = RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond, MaxDelay: time.Minute}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.GetIssue(ctx, "owner", "repo", 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancellation did not abort the wait (took %v)", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewClientWithBaseURL(t *testing.T) {
+	var gotPath, gotAuth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(GitHubIssue{Number: 3, Title: "Enterprise"})
+	}))
+	defer ts.Close()
+
+	// Enterprise Server serves the REST API under /api/v3; a trailing slash
+	// must not produce a double slash in request paths.
+	client := NewClientWithBaseURL("ghe-token", ts.URL+"/api/v3/")
+	issue, err := client.GetIssue(context.Background(), "owner", "repo", 3)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if issue.Title != "Enterprise" {
+		t.Errorf("expected title Enterprise, got %q", issue.Title)
+	}
+	if gotPath != "/api/v3/repos/owner/repo/issues/3" {
+		t.Errorf("expected request under the custom base URL, got path %q", gotPath)
+	}
+	if gotAuth != "Bearer ghe-token" {
+		t.Errorf("expected token sent to the custom host, got %q", gotAuth)
+	}
+}
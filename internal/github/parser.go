@@ -1,6 +1,8 @@
 package github
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"regexp"
@@ -58,6 +60,21 @@ func ParseMetadata(body string) (*MetadataBlock, string, error) {
 	return &meta, humanText, nil
 }
 
+// IssueMetadata builds the metadata block describing an issue's current state.
+func IssueMetadata(issue *model.Issue) *MetadataBlock {
+	meta := &MetadataBlock{
+		Status:    string(issue.Status),
+		Priority:  issue.Priority,
+		IssueType: string(issue.IssueType),
+		Owner:     issue.Owner,
+		Labels:    issue.Labels,
+	}
+	if meta.Labels == nil {
+		meta.Labels = []string{}
+	}
+	return meta
+}
+
 // RenderBody combines human text with boxofrocks metadata into a full issue body.
 func RenderBody(humanText string, meta *MetadataBlock) string {
 	jsonData, err := json.Marshal(meta)
@@ -81,6 +98,9 @@ type eventJSON struct {
 	Action    string `json:"action"`
 	Payload   string `json:"payload"`
 	Agent     string `json:"agent"`
+	// Key is the event's idempotency key, so copies of one comment pulled
+	// back are recognised as one change.
+	Key string `json:"key,omitempty"`
 }
 
 // FormatEventComment formats an event for posting as a GitHub comment.
@@ -91,6 +111,7 @@ func FormatEventComment(event *model.Event) string {
 		Action:    string(event.Action),
 		Payload:   event.Payload,
 		Agent:     event.Agent,
+		Key:       event.IdempotencyKey,
 	}
 	data, err := json.Marshal(ej)
 	if err != nil {
@@ -103,6 +124,28 @@ func FormatEventComment(event *model.Event) string {
 	return humanText + "\n\n" + jsonTag
 }
 
+// rollupRe matches a checklist comment posted by the epic rollup.
+var rollupRe = regexp.MustCompile(`^- \[[ xX]\] #\d+ [^\n]*$`)
+
+// FormatRollupComment renders the checklist line the epic rollup posts on a
+// parent's GitHub issue for a child issue.
+func FormatRollupComment(childNumber int, title string) string {
+	return fmt.Sprintf("- [ ] #%d %s", childNumber, title)
+}
+
+// IsRollupComment reports whether body is a checklist line posted by the
+// epic rollup, from this daemon or another.
+func IsRollupComment(body string) bool {
+	return rollupRe.MatchString(strings.TrimSpace(body))
+}
+
+// CommentHash returns a stable hash of a comment body, ignoring surrounding
+// whitespace, for recognising a comment that was already posted.
+func CommentHash(body string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(body)))
+	return hex.EncodeToString(sum[:])
+}
+
 // FormatHumanText generates the human-readable portion of a v2 event comment.
 func FormatHumanText(event *model.Event) string {
 	var payload model.EventPayload
@@ -146,6 +189,9 @@ func FormatHumanText(event *model.Event) string {
 		if payload.Priority != nil {
 			changed = append(changed, "priority")
 		}
+		if payload.Estimate != nil {
+			changed = append(changed, "estimate")
+		}
 		if payload.IssueType != "" {
 			changed = append(changed, "type")
 		}
@@ -157,8 +203,64 @@ func FormatHumanText(event *model.Event) string {
 		} else {
 			parts = append(parts, "**Updated**")
 		}
+	case model.ActionSetParent, model.ActionClearParent:
+		verb := "Parent set"
+		if event.Action == model.ActionClearParent {
+			verb = "Parent cleared"
+		}
+		if payload.ParentID != nil {
+			parts = append(parts, fmt.Sprintf("**%s**: local issue %d", verb, *payload.ParentID))
+		} else {
+			parts = append(parts, fmt.Sprintf("**%s**", verb))
+		}
+	case model.ActionPriorityChange:
+		switch {
+		case payload.Priority == nil:
+			parts = append(parts, "**Priority changed**")
+		case payload.FromPriority != nil:
+			parts = append(parts, fmt.Sprintf("**Priority changed**: %d \u2192 %d", *payload.FromPriority, *payload.Priority))
+		default:
+			parts = append(parts, fmt.Sprintf("**Priority changed**: \u2192 %d", *payload.Priority))
+		}
 	case model.ActionDelete:
 		parts = append(parts, "**Deleted**")
+	case model.ActionRestore:
+		parts = append(parts, "**Restored**")
+	case model.ActionSnapshot:
+		parts = append(parts, "**Compacted**: earlier history replaced by a snapshot")
+	case model.ActionUpdateRejected:
+		parts = append(parts, fmt.Sprintf("**Update refused**: %s at stale version %d", payload.RejectedAction, payload.Version))
+	case model.ActionSnooze:
+		if payload.SnoozedUntil != nil {
+			parts = append(parts, fmt.Sprintf("**Snoozed** until %s", payload.SnoozedUntil.UTC().Format("2006-01-02 15:04 UTC")))
+		} else {
+			parts = append(parts, "**Unsnoozed**")
+		}
+	case model.ActionLabelAdd:
+		parts = append(parts, fmt.Sprintf("**Label added**: %s", payload.Label))
+	case model.ActionLabelRemove:
+		parts = append(parts, fmt.Sprintf("**Label removed**: %s", payload.Label))
+	case model.ActionAddDependency:
+		parts = append(parts, fmt.Sprintf("**Blocked by**: local issue %d", payload.BlockerID))
+	case model.ActionRemoveDependency:
+		parts = append(parts, fmt.Sprintf("**No longer blocked by**: local issue %d", payload.BlockerID))
+	case model.ActionWatch:
+		parts = append(parts, fmt.Sprintf("**Watching**: %s", payload.Watcher))
+	case model.ActionUnwatch:
+		parts = append(parts, fmt.Sprintf("**No longer watching**: %s", payload.Watcher))
+	case model.ActionCommentEdit, model.ActionCommentDelete:
+		verb := "Comment edited"
+		if event.Action == model.ActionCommentDelete {
+			verb = "Comment deleted"
+		}
+		if payload.CommentIndex != nil {
+			parts = append(parts, fmt.Sprintf("**%s**: comment %d", verb, *payload.CommentIndex))
+		} else {
+			parts = append(parts, fmt.Sprintf("**%s**", verb))
+		}
+		if payload.CommentText != "" {
+			parts = append(parts, "\n> "+payload.CommentText)
+		}
 	case model.ActionComment:
 		if payload.Comment != "" {
 			parts = append(parts, fmt.Sprintf("**Comment**: %s", payload.Comment))
@@ -229,10 +331,11 @@ func parseEventFromMatches(versionStr, jsonStr string) (*model.Event, error) {
 	}
 
 	event := &model.Event{
-		Timestamp: ts,
-		Action:    model.Action(ej.Action),
-		Payload:   ej.Payload,
-		Agent:     ej.Agent,
+		Timestamp:      ts,
+		Action:         model.Action(ej.Action),
+		Payload:        ej.Payload,
+		Agent:          ej.Agent,
+		IdempotencyKey: ej.Key,
 	}
 
 	return event, nil
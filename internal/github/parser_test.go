@@ -357,6 +357,35 @@ func TestFormatHumanText_AllActions(t *testing.T) {
 			},
 			contains: []string{"**Updated**: title, priority", "grace"},
 		},
+		{
+			name: "priority_change",
+			event: &model.Event{
+				Timestamp: ts, Action: model.ActionPriorityChange,
+				Payload: `{"priority":1,"from_priority":3}`, Agent: "grace",
+			},
+			contains: []string{"**Priority changed**: 3 \u2192 1", "grace"},
+		},
+		{
+			name: "set_parent",
+			event: &model.Event{
+				Timestamp: ts, Action: model.ActionSetParent, Payload: `{"parent_id":4}`,
+			},
+			contains: []string{"**Parent set**: local issue 4"},
+		},
+		{
+			name: "clear_parent",
+			event: &model.Event{
+				Timestamp: ts, Action: model.ActionClearParent, Payload: `{"parent_id":4}`,
+			},
+			contains: []string{"**Parent cleared**: local issue 4"},
+		},
+		{
+			name: "priority_change without from",
+			event: &model.Event{
+				Timestamp: ts, Action: model.ActionPriorityChange, Payload: `{"priority":0}`,
+			},
+			contains: []string{"**Priority changed**: \u2192 0"},
+		},
 		{
 			name: "delete",
 			event: &model.Event{
@@ -465,3 +494,21 @@ func TestFormatEventComment_EmptyPayload(t *testing.T) {
 		t.Errorf("expected empty payload, got %q", parsed.Payload)
 	}
 }
+
+func TestIsRollupComment(t *testing.T) {
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{FormatRollupComment(12, "Write the parser"), true},
+		{"- [x] #12 Write the parser\n", true},
+		{"- [ ] see #12", false},
+		{"Looks good to me", false},
+		{"- [ ] #12 first\n- [ ] #13 second", false},
+	}
+	for _, tt := range tests {
+		if got := IsRollupComment(tt.body); got != tt.want {
+			t.Errorf("IsRollupComment(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package github
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how requests are retried when GitHub answers with a
+// transient server error or a secondary rate limit, which would otherwise
+// abort the whole sync cycle.
+type RetryPolicy struct {
+	MaxRetries int           // retries after the first attempt; 0 disables retrying
+	Backoff    time.Duration // base delay before the first retry, doubled after each
+	MaxDelay   time.Duration // longest single wait; a longer Retry-After is not retried
+}
+
+// DefaultRetryPolicy is the retry policy used by NewClient.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, Backoff: time.Second, MaxDelay: time.Minute}
+
+// retryDelay reports whether resp, the response to attempt (0-based), should
+// be retried and how long to wait first.
+//
+// 403 and 429 responses are retried only when they carry Retry-After, which
+// marks GitHub's secondary rate limits; other 403s are permission errors.
+// 5xx responses are retried only for methods that are safe to repeat: a POST
+// that failed with a 502 may still have created its comment.
+func (p RetryPolicy) retryDelay(req *http.Request, resp *http.Response, attempt int) (time.Duration, bool) {
+	if attempt >= p.MaxRetries {
+		return 0, false
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok || wait > p.MaxDelay {
+			return 0, false
+		}
+		return wait, true
+	case resp.StatusCode >= 500 && req.Method != http.MethodPost:
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return min(wait, p.MaxDelay), true
+		}
+		return p.backoff(attempt), true
+	}
+	return 0, false
+}
+
+// backoff returns the exponential delay for attempt with equal jitter: a
+// random duration between half and all of Backoff * 2^attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff << attempt
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
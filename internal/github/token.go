@@ -0,0 +1,184 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenProvider supplies the token sent with each API request. The client
+// asks for it on every request, so a provider may refresh it as it expires.
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenProvider for a token that never changes, such as a
+// personal access token.
+type StaticToken string
+
+// Token returns the token itself.
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+const (
+	// appJWTLifetime is how long the JWT signed for an installation token
+	// request stays valid. GitHub rejects JWTs that live over 10 minutes.
+	appJWTLifetime = 9 * time.Minute
+	// appJWTBackdate covers clock drift between this host and GitHub.
+	appJWTBackdate = time.Minute
+	// appTokenRefreshMargin is how long before expiry an installation token
+	// is replaced, so a request never goes out with a token about to lapse.
+	appTokenRefreshMargin = 5 * time.Minute
+)
+
+// AppTokenProvider authenticates as a GitHub App installation. It signs a
+// JWT with the app's private key, exchanges it for an installation token,
+// and reuses that token until it is near expiry. Installation tokens last
+// an hour.
+type AppTokenProvider struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	baseURL        string
+	httpClient     *http.Client
+	now            func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewAppTokenProvider creates a provider for the given app installation. An
+// empty baseURL means api.github.com.
+func NewAppTokenProvider(appID, installationID int64, key *rsa.PrivateKey, baseURL string) *AppTokenProvider {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return &AppTokenProvider{
+		appID:          appID,
+		installationID: installationID,
+		key:            key,
+		baseURL:        baseURL,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		now:            time.Now,
+	}
+}
+
+// Token returns the cached installation token, fetching a new one when none
+// is cached or the cached one expires within appTokenRefreshMargin.
+func (p *AppTokenProvider) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && p.now().Add(appTokenRefreshMargin).Before(p.expires) {
+		return p.token, nil
+	}
+	token, expires, err := p.fetchToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.token, p.expires = token, expires
+	return token, nil
+}
+
+// fetchToken exchanges a freshly signed JWT for an installation token.
+func (p *AppTokenProvider) fetchToken(ctx context.Context) (string, time.Time, error) {
+	jwt, err := p.signJWT()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", p.baseURL, p.installationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", acceptHeader)
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("request installation token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return "", time.Time{}, fmt.Errorf("request installation token: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", time.Time{}, fmt.Errorf("decode installation token: %w", err)
+	}
+	if result.Token == "" {
+		return "", time.Time{}, fmt.Errorf("GitHub returned an empty installation token")
+	}
+	return result.Token, result.ExpiresAt, nil
+}
+
+// signJWT returns an RS256 JWT identifying the app, as GitHub requires for
+// app-level endpoints.
+func (p *AppTokenProvider) signJWT() (string, error) {
+	now := p.now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iat": now.Add(-appJWTBackdate).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(p.appID, 10),
+	})
+
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign app JWT: %w", err)
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// LoadPrivateKey reads a GitHub App private key from a PEM file. GitHub
+// issues PKCS#1 keys; PKCS#8 is accepted too.
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key %s: no PEM block found", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("private key %s: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s: not an RSA key", path)
+	}
+	return key, nil
+}
@@ -0,0 +1,200 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTokenServer issues installation tokens that expire an hour after the
+// provider's clock, after checking the JWT it was sent.
+type fakeTokenServer struct {
+	t     *testing.T
+	key   *rsa.PublicKey
+	now   func() time.Time
+	calls int
+}
+
+func (f *fakeTokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+		http.NotFound(w, r)
+		return
+	}
+	jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		http.Error(w, "missing JWT", http.StatusUnauthorized)
+		return
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		http.Error(w, "malformed JWT", http.StatusUnauthorized)
+		return
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(f.key, crypto.SHA256, digest[:], sig); err != nil {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+		return
+	}
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	json.Unmarshal(claimsJSON, &claims)
+	if claims.Iss != "7" {
+		f.t.Errorf("iss = %q, want 7", claims.Iss)
+	}
+	if now := f.now().Unix(); claims.Iat > now || claims.Exp <= now || claims.Exp-claims.Iat > 600 {
+		f.t.Errorf("JWT valid %d-%d, want a window of at most 10 minutes around %d", claims.Iat, claims.Exp, now)
+	}
+
+	f.calls++
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"token":      fmt.Sprintf("ghs_%d", f.calls),
+		"expires_at": f.now().Add(time.Hour).UTC().Format(time.RFC3339),
+	})
+}
+
+func TestAppTokenProviderRefresh(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := func() time.Time { return clock }
+
+	srv := &fakeTokenServer{t: t, key: &key.PublicKey, now: now}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	p := NewAppTokenProvider(7, 42, key, ts.URL)
+	p.now = now
+	ctx := context.Background()
+
+	steps := []struct {
+		advance time.Duration
+		want    string
+	}{
+		{0, "ghs_1"},                // nothing cached: fetch
+		{30 * time.Minute, "ghs_1"}, // 30 minutes left: reuse
+		{24 * time.Minute, "ghs_1"}, // 6 minutes left: reuse
+		{2 * time.Minute, "ghs_2"},  // 4 minutes left: refresh
+		{time.Minute, "ghs_2"},      // new token has 59 minutes left
+		{2 * time.Hour, "ghs_3"},    // long expired: refresh
+	}
+	for i, step := range steps {
+		clock = clock.Add(step.advance)
+		got, err := p.Token(ctx)
+		if err != nil {
+			t.Fatalf("step %d: Token: %v", i, err)
+		}
+		if got != step.want {
+			t.Errorf("step %d: token = %q, want %q", i, got, step.want)
+		}
+	}
+	if srv.calls != 3 {
+		t.Errorf("token endpoint called %d times, want 3", srv.calls)
+	}
+}
+
+func TestAppTokenProviderError(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"A JSON web token could not be decoded"}`, http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	p := NewAppTokenProvider(7, 42, key, ts.URL)
+	if _, err := p.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("Token error = %v, want status 401", err)
+	}
+
+	// A client built on the provider fails the request instead of sending
+	// it unauthenticated.
+	client := NewClientWithProvider(p, ts.URL, RetryPolicy{})
+	if _, err := client.GetIssue(context.Background(), "o", "r", 1); err == nil || !strings.Contains(err.Error(), "get GitHub token") {
+		t.Fatalf("GetIssue error = %v, want token error", err)
+	}
+}
+
+func TestClientUsesTokenProvider(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &fakeTokenServer{t: t, key: &key.PublicKey, now: time.Now}
+	var auth []string
+	mux := http.NewServeMux()
+	mux.Handle("/app/", srv)
+	mux.HandleFunc("/repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"private": true}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := NewClientWithProvider(NewAppTokenProvider(7, 42, key, ts.URL), ts.URL, RetryPolicy{})
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetRepo(context.Background(), "o", "r"); err != nil {
+			t.Fatalf("GetRepo: %v", err)
+		}
+	}
+	if len(auth) != 2 || auth[0] != "Bearer ghs_1" || auth[1] != "Bearer ghs_1" {
+		t.Errorf("Authorization headers = %v, want the cached installation token twice", auth)
+	}
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, block := range map[string]*pem.Block{
+		"pkcs1.pem": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		"pkcs8.pem": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadPrivateKey(path)
+		if err != nil {
+			t.Fatalf("%s: LoadPrivateKey: %v", name, err)
+		}
+		if !got.Equal(key) {
+			t.Errorf("%s: loaded key differs", name)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.pem")
+	os.WriteFile(bad, []byte("not a key"), 0600)
+	if _, err := LoadPrivateKey(bad); err == nil {
+		t.Error("expected error for a file without a PEM block")
+	}
+}
@@ -1,6 +1,13 @@
 package model
 
-import "time"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
 
 type Action string
 
@@ -13,8 +20,64 @@ const (
 	ActionDelete       Action = "delete"
 	ActionReopen       Action = "reopen"
 	ActionComment      Action = "comment"
+	ActionSnooze       Action = "snooze"
+	ActionLabelAdd     Action = "label_add"
+	ActionLabelRemove  Action = "label_remove"
+	// ActionAddDependency and ActionRemoveDependency record that the issue
+	// is, or is no longer, blocked by another issue.
+	ActionAddDependency    Action = "add_dependency"
+	ActionRemoveDependency Action = "remove_dependency"
+	// ActionPriorityChange sets the priority alone, recording the previous
+	// value in from_priority.
+	ActionPriorityChange Action = "priority_change"
+	// ActionSetParent and ActionClearParent link the issue to, or unlink it
+	// from, the epic named by parent_id.
+	ActionSetParent   Action = "set_parent"
+	ActionClearParent Action = "clear_parent"
+	// ActionCommentEdit and ActionCommentDelete rewrite or remove the
+	// comment at comment_index in the issue's comment list.
+	ActionCommentEdit   Action = "comment_edit"
+	ActionCommentDelete Action = "comment_delete"
+	// ActionWatch and ActionUnwatch add or remove the watcher who is
+	// notified when the issue changes.
+	ActionWatch   Action = "watch"
+	ActionUnwatch Action = "unwatch"
+	// ActionRestore brings a deleted issue back as open. It is the only
+	// way out of the deleted status.
+	ActionRestore Action = "restore"
+	// ActionSnapshot replaces an issue's state with the issue in snapshot.
+	// Only event compaction writes it, in place of the events it collapses;
+	// it is never pushed to or pulled from GitHub.
+	ActionSnapshot Action = "snapshot"
+	// ActionUpdateRejected records an event the daemon refused because the
+	// issue had changed since the version the client sent. It is kept for
+	// audit only: replay leaves the issue as it was, and it is never pushed
+	// to or pulled from GitHub.
+	ActionUpdateRejected Action = "update_rejected"
 )
 
+// Actions lists every event action, in declaration order.
+var Actions = []Action{
+	ActionCreate, ActionStatusChange, ActionAssign, ActionClose, ActionUpdate,
+	ActionDelete, ActionReopen, ActionComment, ActionSnooze,
+	ActionLabelAdd, ActionLabelRemove,
+	ActionAddDependency, ActionRemoveDependency,
+	ActionPriorityChange, ActionSetParent, ActionClearParent,
+	ActionCommentEdit, ActionCommentDelete,
+	ActionWatch, ActionUnwatch,
+	ActionSnapshot, ActionRestore, ActionUpdateRejected,
+}
+
+// IsValidAction reports whether a is a known event action.
+func IsValidAction(a Action) bool {
+	for _, known := range Actions {
+		if a == known {
+			return true
+		}
+	}
+	return false
+}
+
 type Event struct {
 	ID                int       `json:"id,omitempty"`
 	RepoID            int       `json:"repo_id"`
@@ -26,17 +89,82 @@ type Event struct {
 	Payload           string    `json:"payload"`
 	Agent             string    `json:"agent,omitempty"`
 	Synced            int       `json:"synced"`
+	// IdempotencyKey identifies the change the event records, so the same
+	// change arriving twice is applied once. The store sets it on append;
+	// events from before it was introduced have none. Event comments carry
+	// it, so every copy of a pushed event pulled back shares it.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// NewIdempotencyKey returns a random key for a locally created event, so two
+// changes are never taken for one however alike and close together they are.
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("read random key: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// CommentIdempotencyKey keys an event ingested from the GitHub comment with
+// ID commentID that carried no key of its own, such as a human comment or
+// one posted before keys were added.
+func CommentIdempotencyKey(commentID int) string {
+	return "github-comment:" + strconv.Itoa(commentID)
 }
 
 // EventPayload is the structured data within an event's payload JSON.
 type EventPayload struct {
-	Title       string   `json:"title,omitempty"`
-	Description string   `json:"description,omitempty"`
-	Status      Status   `json:"status,omitempty"`
-	FromStatus  Status   `json:"from_status,omitempty"`
-	Priority    *int     `json:"priority,omitempty"`
-	IssueType   string   `json:"issue_type,omitempty"`
-	Owner       string   `json:"owner,omitempty"`
-	Labels      []string `json:"labels,omitempty"`
-	Comment     string   `json:"comment,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Status      Status `json:"status,omitempty"`
+	FromStatus  Status `json:"from_status,omitempty"`
+	Priority    *int   `json:"priority,omitempty"`
+	// FromPriority is the priority a priority_change event replaced. It is
+	// recorded for history only; unlike from_status it is not checked.
+	FromPriority *int     `json:"from_priority,omitempty"`
+	Estimate     *int     `json:"estimate,omitempty"`
+	IssueType    string   `json:"issue_type,omitempty"`
+	Owner        string   `json:"owner,omitempty"`
+	Labels       []string `json:"labels,omitempty"`
+	// Label is the single label added or removed by label_add and
+	// label_remove events.
+	Label   string `json:"label,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	// SnoozedUntil is carried by snooze events; nil clears the snooze.
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	// ParentID sets the issue's parent by local issue ID; 0 clears it.
+	ParentID *int `json:"parent_id,omitempty"`
+	// BlockerID is the local ID of the blocking issue in add_dependency and
+	// remove_dependency events.
+	BlockerID int `json:"blocker_id,omitempty"`
+	// CommentRef replaces Comment in the local DB when an oversized comment
+	// was stored by reference; the full text lives on GitHub.
+	CommentRef *CommentRef `json:"comment_ref,omitempty"`
+	// CommentIndex is the position, counting from 0, of the comment that a
+	// comment_edit or comment_delete event targets.
+	CommentIndex *int `json:"comment_index,omitempty"`
+	// CommentText is the replacement text in comment_edit events. It is kept
+	// apart from Comment, which would append a new comment instead.
+	CommentText string `json:"comment_text,omitempty"`
+	// Watcher is the name that watch and unwatch events add or remove.
+	Watcher string `json:"watcher,omitempty"`
+	// Snapshot is the issue state a snapshot event sets.
+	Snapshot *Issue `json:"snapshot,omitempty"`
+	// CompactedCommentIDs lists the GitHub comments of the events a
+	// snapshot replaced, so a full sync does not pull them in again.
+	CompactedCommentIDs []int `json:"compacted_comment_ids,omitempty"`
+	// RejectedAction, Version and Rejected describe the refused event in
+	// update_rejected events: its action, the stale version the client
+	// sent, and its payload as written.
+	RejectedAction Action          `json:"rejected_action,omitempty"`
+	Version        int             `json:"version,omitempty"`
+	Rejected       json.RawMessage `json:"rejected,omitempty"`
+}
+
+// CommentRef points at comment text kept on GitHub rather than inline.
+// The GitHub comment is identified by the event's GitHubCommentID.
+type CommentRef struct {
+	SHA256 string `json:"sha256"`
+	Length int    `json:"length"`
 }
@@ -1,6 +1,11 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Status string
 
@@ -13,6 +18,28 @@ const (
 	StatusDeleted    Status = "deleted"
 )
 
+// Statuses lists every status in the order work moves through them.
+var Statuses = []Status{
+	StatusOpen, StatusInProgress, StatusBlocked,
+	StatusInReview, StatusClosed, StatusDeleted,
+}
+
+// ActionableStatuses lists the statuses of work still to be done or
+// finished.
+var ActionableStatuses = []Status{
+	StatusOpen, StatusInProgress, StatusBlocked, StatusInReview,
+}
+
+// Valid reports whether s is a known status.
+func (s Status) Valid() bool {
+	for _, known := range Statuses {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
 type IssueType string
 
 const (
@@ -22,11 +49,25 @@ const (
 	IssueTypeEpic    IssueType = "epic"
 )
 
+// DefaultIssueTypes are the issue types a repo accepts unless it configures
+// its own set.
+var DefaultIssueTypes = []IssueType{IssueTypeTask, IssueTypeBug, IssueTypeFeature, IssueTypeEpic}
+
+// Valid reports whether t can name an issue type, that is, whether it is
+// not blank. Repos may configure their own types, so whether a repo
+// accepts t is up to RepoConfig.AllowsIssueType.
+func (t IssueType) Valid() bool {
+	return strings.TrimSpace(string(t)) != ""
+}
+
 // Comment represents a narrative comment attached to an issue.
 type Comment struct {
 	Text      string `json:"text"`
 	Author    string `json:"author,omitempty"`
 	Timestamp string `json:"timestamp"`
+	// EditedAt is when the comment text was last replaced by a comment_edit
+	// event, empty if it never was.
+	EditedAt string `json:"edited_at,omitempty"`
 }
 
 type Issue struct {
@@ -44,4 +85,56 @@ type Issue struct {
 	UpdatedAt   time.Time  `json:"updated_at"`
 	ClosedAt    *time.Time `json:"closed_at,omitempty"`
 	Comments    []Comment  `json:"comments"`
+	// Estimate is the expected effort in points or hours; 0 means unestimated.
+	Estimate int `json:"estimate"`
+	// SnoozedUntil hides the issue from next/list until the given time.
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	// ParentID is the local ID of the epic this issue belongs to.
+	ParentID *int `json:"parent_id,omitempty"`
+	// BlockedBy lists the local IDs of issues that must close before this
+	// one is offered by next, in ascending order.
+	BlockedBy []int `json:"blocked_by,omitempty"`
+	// Watchers are notified when the issue changes, sorted
+	// case-insensitively.
+	Watchers []string `json:"watchers,omitempty"`
+	// ChildCounts counts the issue's children by status, deleted ones
+	// excluded. GET /issues/{id} fills it in; it is not stored.
+	ChildCounts map[Status]int `json:"child_counts,omitempty"`
+	// EventCount and LastEventAt describe the issue's event log: how many
+	// events it holds and when the newest was recorded. GET /issues and
+	// GET /issues/{id} fill them in; they are not stored.
+	EventCount  int        `json:"event_count,omitempty"`
+	LastEventAt *time.Time `json:"last_event_at,omitempty"`
+	// Version is the ID of the issue's newest event, so it changes with
+	// every change to the issue. A PATCH that sends it back is refused if
+	// the issue has changed since. Filled in with EventCount.
+	Version int `json:"version,omitempty"`
+}
+
+// IsSnoozed reports whether the issue is snoozed at the given time.
+func (i *Issue) IsSnoozed(now time.Time) bool {
+	return i.SnoozedUntil != nil && i.SnoozedUntil.After(now)
+}
+
+// ParseIssueRef parses a reference to an issue in a repo whose key prefix
+// is prefix: either the bare ID ("42") or the prefixed form ("BOR-42"). The
+// prefix matches regardless of case. A prefixed reference is rejected when
+// its prefix is not the repo's, or the repo has none.
+func ParseIssueRef(prefix, ref string) (int, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.Atoi(ref); err == nil && id > 0 {
+		return id, nil
+	}
+	p, num, ok := strings.Cut(ref, "-")
+	id, err := strconv.Atoi(num)
+	if !ok || err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid issue reference %q: use an ID or PREFIX-ID", ref)
+	}
+	if prefix == "" {
+		return 0, fmt.Errorf("invalid issue reference %q: the repo has no key prefix", ref)
+	}
+	if !strings.EqualFold(p, prefix) {
+		return 0, fmt.Errorf("invalid issue reference %q: the repo's key prefix is %s", ref, prefix)
+	}
+	return id, nil
 }
@@ -0,0 +1,41 @@
+package model
+
+import "testing"
+
+func TestParseIssueRef(t *testing.T) {
+	cases := []struct {
+		prefix, ref string
+		want        int
+		wantErr     bool
+	}{
+		{"BOR", "42", 42, false},
+		{"BOR", "BOR-42", 42, false},
+		{"BOR", "bor-42", 42, false},
+		{"", "42", 42, false},
+		{"BOR", "XYZ-42", 0, true},
+		{"", "BOR-42", 0, true},
+		{"BOR", "BOR-", 0, true},
+		{"BOR", "BOR-x", 0, true},
+		{"BOR", "BOR-0", 0, true},
+		{"BOR", "", 0, true},
+	}
+	for _, tc := range cases {
+		got, err := ParseIssueRef(tc.prefix, tc.ref)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseIssueRef(%q, %q) = %d, %v; want %d, error %v", tc.prefix, tc.ref, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestIsValidKeyPrefix(t *testing.T) {
+	for _, p := range []string{"", "BOR", "bor", "B2", "ABCDEFGHIJ"} {
+		if !IsValidKeyPrefix(p) {
+			t.Errorf("IsValidKeyPrefix(%q) = false, want true", p)
+		}
+	}
+	for _, p := range []string{"2B", "BO-R", "BOR ", "ABCDEFGHIJK", "ÄBC"} {
+		if IsValidKeyPrefix(p) {
+			t.Errorf("IsValidKeyPrefix(%q) = true, want false", p)
+		}
+	}
+}
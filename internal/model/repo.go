@@ -2,6 +2,7 @@ package model
 
 import (
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -33,20 +34,137 @@ func (lp *LocalPathConfig) QueueDir() string {
 	return filepath.Join(lp.LocalPath, ".boxofrocks", "queue")
 }
 
+// NextStrategy selects how NextIssue orders eligible issues.
+type NextStrategy string
+
+const (
+	// NextStrategyPriority picks the highest priority (lowest number),
+	// oldest first among equals. It is the default.
+	NextStrategyPriority NextStrategy = "priority"
+	// NextStrategyFIFO picks the oldest issue regardless of priority.
+	NextStrategyFIFO NextStrategy = "fifo"
+	// NextStrategyWeighted divides (priority+1) by (1 + age in days), so
+	// older issues gradually overtake newer, more urgent ones.
+	NextStrategyWeighted NextStrategy = "weighted"
+)
+
+// IsValidNextStrategy reports whether s is a known strategy. The empty
+// string is valid and means NextStrategyPriority.
+func IsValidNextStrategy(s NextStrategy) bool {
+	switch s {
+	case "", NextStrategyPriority, NextStrategyFIFO, NextStrategyWeighted:
+		return true
+	}
+	return false
+}
+
+// maxKeyPrefixLength caps a repo's issue key prefix.
+const maxKeyPrefixLength = 10
+
+// IsValidKeyPrefix reports whether p can be used as a repo's issue key
+// prefix: up to 10 ASCII letters and digits, starting with a letter. The
+// empty string is valid and means issues are referred to by bare ID.
+func IsValidKeyPrefix(p string) bool {
+	if len(p) > maxKeyPrefixLength {
+		return false
+	}
+	for i, c := range p {
+		letter := c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// SyncDirection limits which way a repo syncs with GitHub.
+type SyncDirection string
+
+const (
+	SyncBoth SyncDirection = "both" // the default
+	SyncPull SyncDirection = "pull" // mirror GitHub; never write to it
+	SyncPush SyncDirection = "push" // publish local events; never read GitHub
+)
+
+// IsValidSyncDirection reports whether d is a known direction. The empty
+// string is valid and means SyncBoth.
+func IsValidSyncDirection(d SyncDirection) bool {
+	switch d {
+	case "", SyncBoth, SyncPull, SyncPush:
+		return true
+	}
+	return false
+}
+
+// DefaultTrackingLabel is the GitHub label that marks an issue as tracked by
+// boxofrocks when a repo has not chosen its own.
+const DefaultTrackingLabel = "boxofrocks"
+
 type RepoConfig struct {
-	ID                 int               `json:"id"`
-	Owner              string            `json:"owner"`
-	Name               string            `json:"name"`
-	PollIntervalMs     int               `json:"poll_interval_ms"`
-	LastSyncAt         *time.Time        `json:"last_sync_at,omitempty"`
-	IssuesETag         string            `json:"issues_etag"`
-	IssuesSince        string            `json:"issues_since"`
-	TrustedAuthorsOnly bool              `json:"trusted_authors_only"`
-	LocalPath          string            `json:"local_path,omitempty"`
-	SocketEnabled      bool              `json:"socket_enabled"`
-	QueueEnabled       bool              `json:"queue_enabled"`
-	CreatedAt          time.Time         `json:"created_at"`
-	LocalPaths         []LocalPathConfig `json:"local_paths,omitempty"`
+	ID                 int        `json:"id"`
+	Owner              string     `json:"owner"`
+	Name               string     `json:"name"`
+	PollIntervalMs     int        `json:"poll_interval_ms"`
+	LastSyncAt         *time.Time `json:"last_sync_at,omitempty"`
+	IssuesETag         string     `json:"issues_etag"`
+	IssuesSince        string     `json:"issues_since"`
+	TrustedAuthorsOnly bool       `json:"trusted_authors_only"`
+	// TrustedAuthors lists GitHub logins trusted under TrustedAuthorsOnly in
+	// addition to the repo owner and trusted author associations.
+	TrustedAuthors []string          `json:"trusted_authors,omitempty"`
+	LocalPath      string            `json:"local_path,omitempty"`
+	SocketEnabled  bool              `json:"socket_enabled"`
+	QueueEnabled   bool              `json:"queue_enabled"`
+	CreatedAt      time.Time         `json:"created_at"`
+	LocalPaths     []LocalPathConfig `json:"local_paths,omitempty"`
+
+	// AllowedInboundActions restricts which actions parsed from GitHub
+	// comments are applied. Empty means every action is allowed.
+	AllowedInboundActions []string `json:"allowed_inbound_actions,omitempty"`
+
+	// IssueTypes is the set of issue types the repo accepts. Empty means
+	// DefaultIssueTypes.
+	IssueTypes []string `json:"issue_types,omitempty"`
+
+	// EpicRollup posts a checklist comment on the parent's GitHub issue
+	// when a child issue is first pushed.
+	EpicRollup bool `json:"epic_rollup"`
+
+	// NextStrategy orders next/plan candidates. Empty means
+	// NextStrategyPriority.
+	NextStrategy NextStrategy `json:"next_strategy,omitempty"`
+
+	// SyncDirection limits syncing to one direction. Empty means SyncBoth.
+	SyncDirection SyncDirection `json:"sync_direction,omitempty"`
+
+	// IngestHumanComments records plain GitHub comments (not boxofrocks
+	// events) as local comment events attributed to their author.
+	IngestHumanComments bool `json:"ingest_human_comments"`
+
+	// Label is the GitHub label that marks an issue as tracked. Empty means
+	// DefaultTrackingLabel.
+	Label string `json:"label"`
+
+	// AssigneeLogins maps local owners to the GitHub logins they are
+	// assigned as. Owners without an entry are not assigned on GitHub.
+	AssigneeLogins map[string]string `json:"assignee_logins,omitempty"`
+
+	// WIPLimit caps how many open or in_progress issues one owner may be
+	// assigned. 0 means no limit.
+	WIPLimit int `json:"wip_limit"`
+
+	// ValidateAssignee rejects assigning an issue to an owner who is not a
+	// collaborator on the GitHub repo. The owner is checked as the login
+	// AssigneeLogins maps it to, or as itself if it has no entry.
+	ValidateAssignee bool `json:"validate_assignee"`
+
+	// IssueTemplates maps an issue type to the Markdown skeleton new issues
+	// of that type are filled in from; see ApplyTemplate.
+	IssueTemplates map[string]string `json:"issue_templates,omitempty"`
+
+	// KeyPrefix, if set, lets the repo's issues be referred to as
+	// KeyPrefix-<id>, e.g. BOR-42, as well as by bare ID.
+	KeyPrefix string `json:"key_prefix,omitempty"`
 }
 
 // FullName returns "owner/name".
@@ -54,6 +172,141 @@ func (r *RepoConfig) FullName() string {
 	return r.Owner + "/" + r.Name
 }
 
+// TrackingLabel returns the GitHub label the repo's issues are tracked by.
+func (r *RepoConfig) TrackingLabel() string {
+	if r.Label == "" {
+		return DefaultTrackingLabel
+	}
+	return r.Label
+}
+
+// TrustsLogin reports whether a GitHub login is the repo owner or one of its
+// TrustedAuthors. Logins are compared case-insensitively, as GitHub does.
+func (r *RepoConfig) TrustsLogin(login string) bool {
+	if login == "" {
+		return false
+	}
+	if strings.EqualFold(login, r.Owner) {
+		return true
+	}
+	for _, trusted := range r.TrustedAuthors {
+		if strings.EqualFold(login, trusted) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyTemplate completes the description of a new issue of the given type
+// from the repo's template for it. An empty description becomes the whole
+// template. Otherwise each template section, a Markdown heading and the
+// lines under it, whose heading the description lacks is appended, so the
+// issue keeps the template's structure. Without a template the description
+// is returned unchanged.
+func (r *RepoConfig) ApplyTemplate(issueType IssueType, description string) string {
+	tmpl := r.IssueTemplates[string(issueType)]
+	if tmpl == "" {
+		return description
+	}
+	if strings.TrimSpace(description) == "" {
+		return tmpl
+	}
+
+	present := make(map[string]bool)
+	for _, line := range strings.Split(description, "\n") {
+		if isHeading(line) {
+			present[strings.ToLower(strings.TrimSpace(line))] = true
+		}
+	}
+	var missing []string
+	var section []string
+	flush := func() {
+		if len(section) > 0 && !present[strings.ToLower(strings.TrimSpace(section[0]))] {
+			missing = append(missing, strings.TrimRight(strings.Join(section, "\n"), "\n"))
+		}
+		section = nil
+	}
+	for _, line := range strings.Split(tmpl, "\n") {
+		if isHeading(line) {
+			flush()
+		}
+		if section != nil || isHeading(line) {
+			section = append(section, line)
+		}
+	}
+	flush()
+	if len(missing) == 0 {
+		return description
+	}
+	return strings.TrimRight(description, "\n") + "\n\n" + strings.Join(missing, "\n\n")
+}
+
+// isHeading reports whether line is a Markdown ATX heading.
+func isHeading(line string) bool {
+	line = strings.TrimSpace(line)
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	return level >= 1 && level <= 6 && (len(line) == level || line[level] == ' ')
+}
+
+// GitHubLogin returns the GitHub login that a local owner is assigned as, or
+// "" if the owner is empty or has no entry in AssigneeLogins.
+func (r *RepoConfig) GitHubLogin(owner string) string {
+	if owner == "" {
+		return ""
+	}
+	return r.AssigneeLogins[owner]
+}
+
+// PullsFromGitHub reports whether the syncer should read from GitHub.
+func (r *RepoConfig) PullsFromGitHub() bool {
+	return r.SyncDirection != SyncPush
+}
+
+// PushesToGitHub reports whether the syncer may write to GitHub.
+func (r *RepoConfig) PushesToGitHub() bool {
+	return r.SyncDirection != SyncPull
+}
+
+// AllowsInboundAction reports whether an event with this action, pulled from
+// a GitHub comment, may be applied. Snapshots and refused updates are
+// written only locally, so one arriving from GitHub never is.
+func (r *RepoConfig) AllowsInboundAction(a Action) bool {
+	if a == ActionSnapshot || a == ActionUpdateRejected {
+		return false
+	}
+	if len(r.AllowedInboundActions) == 0 {
+		return true
+	}
+	for _, allowed := range r.AllowedInboundActions {
+		if Action(allowed) == a {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidIssueTypes returns the issue types the repo accepts.
+func (r *RepoConfig) ValidIssueTypes() []IssueType {
+	if len(r.IssueTypes) == 0 {
+		return DefaultIssueTypes
+	}
+	types := make([]IssueType, len(r.IssueTypes))
+	for i, t := range r.IssueTypes {
+		types[i] = IssueType(t)
+	}
+	return types
+}
+
+// AllowsIssueType reports whether t is one of the repo's issue types.
+func (r *RepoConfig) AllowsIssueType(t IssueType) bool {
+	for _, valid := range r.ValidIssueTypes() {
+		if valid == t {
+			return true
+		}
+	}
+	return false
+}
+
 // SocketPath returns the path to the Unix domain socket for this repo,
 // or "" if socket is not enabled or local path is not set.
 // Uses the first local path entry for backward compatibility.
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+// CommentFetcher returns the full comment text of the event that was
+// synced as the given GitHub comment.
+type CommentFetcher func(ctx context.Context, repoID, githubCommentID int) (string, error)
+
+// SetCommentFetcher installs the function HydrateComments uses to restore
+// comments that AppendEvent stored by reference.
+func (s *SQLiteStore) SetCommentFetcher(f CommentFetcher) {
+	s.fetchComment = f
+}
+
+// compactPayload replaces an oversized "comment" with a CommentRef when the
+// store has a MaxInlineCommentBytes threshold. Callers only compact events
+// that already exist as GitHub comments (inbound events in AppendEvent,
+// outbound ones in MarkEventSynced), since GitHub then holds the full text.
+// Payloads that are small or unparsable are returned unchanged.
+func (s *SQLiteStore) compactPayload(payload string) string {
+	if s.maxInlineComment <= 0 || len(payload) <= s.maxInlineComment {
+		return payload
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(payload), &fields); err != nil {
+		return payload
+	}
+	var comment string
+	if raw, ok := fields["comment"]; !ok || json.Unmarshal(raw, &comment) != nil || len(comment) <= s.maxInlineComment {
+		return payload
+	}
+
+	sum := sha256.Sum256([]byte(comment))
+	ref, err := json.Marshal(model.CommentRef{SHA256: hex.EncodeToString(sum[:]), Length: len(comment)})
+	if err != nil {
+		return payload
+	}
+	delete(fields, "comment")
+	fields["comment_ref"] = ref
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return payload
+	}
+	return string(out)
+}
+
+// HydrateComments restores the comment text of events stored by reference.
+// It may call GitHub once per such event, so only the API's event reads use
+// it; replay and sync work from the references.
+func (s *SQLiteStore) HydrateComments(ctx context.Context, events []*model.Event) {
+	for _, e := range events {
+		s.hydrateComment(ctx, e)
+	}
+}
+
+// hydrateComment restores the comment text of an event stored by reference.
+// If no fetcher is installed or the fetch fails, the event is left with its
+// reference and no comment text; replay still succeeds without it.
+func (s *SQLiteStore) hydrateComment(ctx context.Context, e *model.Event) {
+	if e.GitHubCommentID == nil || s.fetchComment == nil {
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(e.Payload), &fields); err != nil {
+		return
+	}
+	raw, ok := fields["comment_ref"]
+	if !ok {
+		return
+	}
+	var ref model.CommentRef
+	if err := json.Unmarshal(raw, &ref); err != nil {
+		return
+	}
+
+	text, err := s.fetchComment(ctx, e.RepoID, *e.GitHubCommentID)
+	if err != nil {
+		slog.Warn("could not hydrate comment", "event", e.ID, "github_comment_id", *e.GitHubCommentID, "error", err)
+		return
+	}
+	sum := sha256.Sum256([]byte(text))
+	if hex.EncodeToString(sum[:]) != ref.SHA256 {
+		slog.Warn("hydrated comment does not match stored hash", "event", e.ID, "github_comment_id", *e.GitHubCommentID)
+		return
+	}
+
+	comment, err := json.Marshal(text)
+	if err != nil {
+		return
+	}
+	delete(fields, "comment_ref")
+	fields["comment"] = comment
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+	e.Payload = string(out)
+}
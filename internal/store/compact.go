@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmaddaus/boxofrocks/internal/engine"
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+// CompactResult reports what CompactEventsDB collapsed.
+type CompactResult struct {
+	// Issues counts the issues that were given a snapshot event.
+	Issues int `json:"issues"`
+	// EventsRemoved counts the events deleted, not counting the ones that
+	// became snapshots.
+	EventsRemoved int64 `json:"events_removed"`
+}
+
+// CompactEventsDB collapses the old history of closed and deleted issues.
+// For each such issue in the repo, or in every repo if repoID is 0, the
+// events before the newest keepPerIssue are replayed into one snapshot
+// event. Compaction stops at the first of those events that is not yet
+// synced, or whose comment is stored by reference and so cannot be
+// replayed from the database alone; an issue left with fewer than two
+// events to collapse is skipped.
+//
+// The snapshot keeps the ID and timestamp of the newest event it replaces,
+// so it still sorts before the events kept, and records the GitHub comment
+// IDs of the events it replaces. Notifications of removed events are moved
+// to the snapshot. Each issue is compacted in its own transaction. opts
+// are passed to the replay, so a priority range the daemon applies is baked
+// into the snapshot too.
+func CompactEventsDB(ctx context.Context, db *sql.DB, repoID, keepPerIssue int, opts ...engine.Option) (*CompactResult, error) {
+	if keepPerIssue < 0 {
+		return nil, fmt.Errorf("keep per issue must not be negative, got %d", keepPerIssue)
+	}
+	version, err := ReadDBVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if version != DBSchemaVersion {
+		return nil, fmt.Errorf("compaction needs schema version %d, database is at %d", DBSchemaVersion, version)
+	}
+
+	query := `SELECT id FROM issues WHERE status IN (?, ?)`
+	args := []interface{}{string(model.StatusClosed), string(model.StatusDeleted)}
+	if repoID != 0 {
+		query += ` AND repo_id = ?`
+		args = append(args, repoID)
+	}
+	rows, err := db.QueryContext(ctx, query+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list closed issues: %w", err)
+	}
+	var issueIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		issueIDs = append(issueIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &CompactResult{}
+	for _, issueID := range issueIDs {
+		removed, err := compactIssueEvents(ctx, db, issueID, keepPerIssue, opts)
+		if err != nil {
+			return result, fmt.Errorf("compact issue %d: %w", issueID, err)
+		}
+		if removed > 0 {
+			result.Issues++
+			result.EventsRemoved += removed
+		}
+	}
+	return result, nil
+}
+
+// compactIssueEvents collapses one issue's compactable events into a
+// snapshot and returns how many events it deleted.
+func compactIssueEvents(ctx context.Context, db *sql.DB, issueID, keep int, opts []engine.Option) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT `+eventColumns+`
+		 FROM events WHERE issue_id = ? ORDER BY id`, issueID)
+	if err != nil {
+		return 0, err
+	}
+	var events []*model.Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for n < len(events)-keep && compactable(events[n]) {
+		n++
+	}
+	if n < 2 {
+		return 0, nil
+	}
+	prefix := events[:n]
+
+	replayed, err := engine.Replay(prefix, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("replay: %w", err)
+	}
+	state := replayed[issueID]
+	if state == nil {
+		return 0, fmt.Errorf("replay produced no state")
+	}
+	// The GitHub link lives on the issue row, not in its events.
+	state.GitHubID = nil
+
+	var commentIDs []int
+	for _, e := range prefix {
+		if e.GitHubCommentID != nil {
+			commentIDs = append(commentIDs, *e.GitHubCommentID)
+		}
+		if e.Action == model.ActionSnapshot {
+			var p model.EventPayload
+			if err := json.Unmarshal([]byte(e.Payload), &p); err == nil {
+				commentIDs = append(commentIDs, p.CompactedCommentIDs...)
+			}
+		}
+	}
+	payload, err := json.Marshal(model.EventPayload{Snapshot: state, CompactedCommentIDs: commentIDs})
+	if err != nil {
+		return 0, err
+	}
+
+	last := prefix[n-1]
+	snapshot := &model.Event{
+		RepoID:    last.RepoID,
+		IssueID:   issueID,
+		Timestamp: last.Timestamp,
+		Action:    model.ActionSnapshot,
+		Payload:   string(payload),
+		Synced:    1,
+		// The snapshot replaces the events it folds, keys and all.
+		IdempotencyKey: model.NewIdempotencyKey(),
+	}
+
+	ids := make([]interface{}, 0, n-1)
+	for _, e := range prefix[:n-1] {
+		ids = append(ids, e.ID)
+	}
+	in := "(" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")"
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE notifications SET event_id = ? WHERE event_id IN `+in,
+		append([]interface{}{last.ID}, ids...)...); err != nil {
+		return 0, fmt.Errorf("move notifications: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM events WHERE id IN `+in, ids...)
+	if err != nil {
+		return 0, fmt.Errorf("delete events: %w", err)
+	}
+	removed, _ := res.RowsAffected()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE events SET github_comment_id = NULL, action = ?, payload = ?, agent = '', synced = 1, idempotency_key = ?
+		 WHERE id = ?`,
+		string(snapshot.Action), snapshot.Payload, snapshot.IdempotencyKey, last.ID); err != nil {
+		return 0, fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// compactable reports whether e may be folded into a snapshot: it must be
+// synced, and replayable without fetching its comment from GitHub.
+func compactable(e *model.Event) bool {
+	if e.Synced == 0 {
+		return false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(e.Payload), &fields); err != nil {
+		return e.Payload == ""
+	}
+	_, byRef := fields["comment_ref"]
+	return !byRef
+}
+
+// CompactEvents runs CompactEventsDB on the store's database for one repo.
+func (s *SQLiteStore) CompactEvents(ctx context.Context, repoID, keepPerIssue int, opts ...engine.Option) (*CompactResult, error) {
+	return CompactEventsDB(ctx, s.db, repoID, keepPerIssue, opts...)
+}
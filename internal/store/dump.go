@@ -0,0 +1,534 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+// DumpVersion is the schema_version of the export documents WriteDump
+// produces. Bump it when a field is added, removed or changes meaning.
+const DumpVersion = 3
+
+// ErrDumpTooNew is returned for a dump whose schema_version is newer than
+// DumpVersion.
+var ErrDumpTooNew = errors.New("dump schema_version is newer than this build supports")
+
+// ErrRepoExists is returned by ImportDump when a dumped repo is already
+// registered.
+var ErrRepoExists = errors.New("repo already registered")
+
+// Dump is an export document: every repo, issue and event in a store, or
+// in one of its repos. Issues and events are in ID order, and events keep
+// their stored payloads, so comments stored by reference stay references.
+type Dump struct {
+	SchemaVersion int                 `json:"schema_version"`
+	ExportedAt    time.Time           `json:"exported_at"`
+	Repos         []*model.RepoConfig `json:"repos"`
+	Issues        []*model.Issue      `json:"issues"`
+	Events        []*model.Event      `json:"events"`
+}
+
+// WriteDump writes a Dump of repoID, or of every repo if repoID is 0, to w.
+// Issues and events are streamed from the store rather than loaded at once.
+func WriteDump(ctx context.Context, s Store, w io.Writer, repoID int) error {
+	var repos []*model.RepoConfig
+	if repoID != 0 {
+		repo, err := s.GetRepo(ctx, repoID)
+		if err != nil {
+			return fmt.Errorf("get repo %d: %w", repoID, err)
+		}
+		repos = []*model.RepoConfig{repo}
+	} else {
+		var err error
+		if repos, err = s.ListRepos(ctx); err != nil {
+			return fmt.Errorf("list repos: %w", err)
+		}
+	}
+	if repos == nil {
+		repos = []*model.RepoConfig{}
+	}
+
+	bw := bufio.NewWriter(w)
+	reposJSON, err := json.Marshal(repos)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(bw, `{"schema_version":%d,"exported_at":"%s","repos":%s,"issues":[`,
+		DumpVersion, time.Now().UTC().Format(time.RFC3339), reposJSON)
+
+	elem := arrayElements(bw)
+	if err := s.StreamIssues(ctx, repoID, func(issue *model.Issue) error {
+		return elem(issue)
+	}); err != nil {
+		return fmt.Errorf("export issues: %w", err)
+	}
+	bw.WriteString(`],"events":[`)
+
+	elem = arrayElements(bw)
+	if err := s.StreamEvents(ctx, repoID, func(event *model.Event) error {
+		return elem(event)
+	}); err != nil {
+		return fmt.Errorf("export events: %w", err)
+	}
+	bw.WriteString("]}\n")
+	return bw.Flush()
+}
+
+// arrayElements returns a function that writes each value it is given to
+// w as the next element of a JSON array.
+func arrayElements(w *bufio.Writer) func(v any) error {
+	first := true
+	return func(v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if !first {
+			w.WriteByte(',')
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	}
+}
+
+// StreamIssues calls fn with each issue of repoID, or of every repo if
+// repoID is 0, in ID order. Deleted issues are included. An error from fn
+// stops the walk and is returned.
+func (s *SQLiteStore) StreamIssues(ctx context.Context, repoID int, fn func(*model.Issue) error) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+issueColumns+`
+		 FROM issues WHERE ? = 0 OR repo_id = ? ORDER BY id`, repoID, repoID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		iss, err := scanIssue(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(iss); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamEvents calls fn with each event of repoID, or of every repo if
+// repoID is 0, in ID order. Payloads are passed as stored, without
+// hydrating comments stored by reference. An error from fn stops the walk
+// and is returned.
+func (s *SQLiteStore) StreamEvents(ctx context.Context, repoID int, fn func(*model.Event) error) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+eventColumns+`
+		 FROM events WHERE ? = 0 OR repo_id = ? ORDER BY id`, repoID, repoID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Validate checks that d is an export document this build can read and
+// that every issue and event refers only to rows within it.
+func (d *Dump) Validate() error {
+	if d.SchemaVersion < 1 {
+		return fmt.Errorf("missing schema_version; not an export document")
+	}
+	if d.SchemaVersion > DumpVersion {
+		return fmt.Errorf("%w: got %d, supported up to %d", ErrDumpTooNew, d.SchemaVersion, DumpVersion)
+	}
+
+	repos := make(map[int]bool, len(d.Repos))
+	for _, r := range d.Repos {
+		if r.Owner == "" || r.Name == "" {
+			return fmt.Errorf("repo %d: owner and name are required", r.ID)
+		}
+		if repos[r.ID] {
+			return fmt.Errorf("repo %d appears twice", r.ID)
+		}
+		repos[r.ID] = true
+	}
+	issueRepo := make(map[int]int, len(d.Issues))
+	for _, iss := range d.Issues {
+		if !repos[iss.RepoID] {
+			return fmt.Errorf("issue %d: repo %d is not in the dump", iss.ID, iss.RepoID)
+		}
+		if _, dup := issueRepo[iss.ID]; dup {
+			return fmt.Errorf("issue %d appears twice", iss.ID)
+		}
+		issueRepo[iss.ID] = iss.RepoID
+	}
+	for _, iss := range d.Issues {
+		if iss.ParentID != nil && issueRepo[*iss.ParentID] != iss.RepoID {
+			return fmt.Errorf("issue %d: parent %d is not in the same repo in the dump", iss.ID, *iss.ParentID)
+		}
+		for _, b := range iss.BlockedBy {
+			if issueRepo[b] != iss.RepoID {
+				return fmt.Errorf("issue %d: blocker %d is not in the same repo in the dump", iss.ID, b)
+			}
+		}
+	}
+	events := make(map[int]bool, len(d.Events))
+	for _, e := range d.Events {
+		if repoID, ok := issueRepo[e.IssueID]; !ok || repoID != e.RepoID {
+			return fmt.Errorf("event %d: issue %d is not in repo %d in the dump", e.ID, e.IssueID, e.RepoID)
+		}
+		if events[e.ID] {
+			return fmt.Errorf("event %d appears twice", e.ID)
+		}
+		events[e.ID] = true
+	}
+	return nil
+}
+
+// ImportResult counts what ImportDump loaded. The renumbered maps list the
+// dumped IDs that were already taken, and the IDs the rows got instead.
+type ImportResult struct {
+	Repos            int         `json:"repos"`
+	Issues           int         `json:"issues"`
+	Events           int         `json:"events"`
+	RenumberedRepos  map[int]int `json:"renumbered_repos,omitempty"`
+	RenumberedIssues map[int]int `json:"renumbered_issues,omitempty"`
+	RenumberedEvents map[int]int `json:"renumbered_events,omitempty"`
+}
+
+// ImportDump loads a validated dump in one transaction, so a failure part
+// way leaves the store as it was. Rows keep their dumped IDs unless an ID
+// is taken, and references are rewritten to match. Local paths are not
+// part of a dump, so imported repos have none. A repo that is already
+// registered fails the import with ErrRepoExists.
+func (s *SQLiteStore) ImportDump(ctx context.Context, d *Dump) (*ImportResult, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	var maxRepoID, maxIssueID, maxEventID int
+	for _, r := range d.Repos {
+		maxRepoID = max(maxRepoID, r.ID)
+	}
+	for _, iss := range d.Issues {
+		maxIssueID = max(maxIssueID, iss.ID)
+	}
+	for _, e := range d.Events {
+		maxEventID = max(maxEventID, e.ID)
+	}
+
+	var result *ImportResult
+	err := s.writeTx(ctx, func(tx *sql.Tx) error {
+		result = &ImportResult{
+			RenumberedRepos:  make(map[int]int),
+			RenumberedIssues: make(map[int]int),
+			RenumberedEvents: make(map[int]int),
+		}
+		repoAlloc, err := newIDAllocator(ctx, tx, "repos", maxRepoID)
+		if err != nil {
+			return err
+		}
+		issueAlloc, err := newIDAllocator(ctx, tx, "issues", maxIssueID)
+		if err != nil {
+			return err
+		}
+		eventAlloc, err := newIDAllocator(ctx, tx, "events", maxEventID)
+		if err != nil {
+			return err
+		}
+
+		repoIDs := make(map[int]int, len(d.Repos))
+		for _, r := range d.Repos {
+			id, err := s.importRepo(ctx, tx, repoAlloc, r)
+			if err != nil {
+				return err
+			}
+			repoIDs[r.ID] = id
+			if id != r.ID {
+				result.RenumberedRepos[r.ID] = id
+			}
+			result.Repos++
+		}
+
+		// Insert every issue before linking parents and blockers, which
+		// may come later in ID order.
+		issueIDs := make(map[int]int, len(d.Issues))
+		for _, iss := range d.Issues {
+			id, err := importIssue(ctx, tx, issueAlloc, iss, repoIDs[iss.RepoID])
+			if err != nil {
+				return err
+			}
+			issueIDs[iss.ID] = id
+			if id != iss.ID {
+				result.RenumberedIssues[iss.ID] = id
+			}
+			result.Issues++
+		}
+		for _, iss := range d.Issues {
+			id := issueIDs[iss.ID]
+			if iss.ParentID != nil {
+				if _, err := tx.ExecContext(ctx,
+					`UPDATE issues SET parent_id = ? WHERE id = ?`, issueIDs[*iss.ParentID], id); err != nil {
+					return fmt.Errorf("link parent of issue %d: %w", iss.ID, err)
+				}
+			}
+			blockers := make([]int, 0, len(iss.BlockedBy))
+			for _, b := range iss.BlockedBy {
+				blockers = append(blockers, issueIDs[b])
+			}
+			if err := saveDependencies(ctx, tx, id, blockers); err != nil {
+				return err
+			}
+			if err := saveWatchers(ctx, tx, id, iss.Watchers); err != nil {
+				return err
+			}
+		}
+
+		for _, e := range d.Events {
+			ev := *e
+			ev.RepoID = repoIDs[e.RepoID]
+			ev.IssueID = issueIDs[e.IssueID]
+			payload, err := remapPayloadIssueIDs(ev.Payload, issueIDs)
+			if err != nil {
+				return fmt.Errorf("import event %d: %w", e.ID, err)
+			}
+			ev.Payload = payload
+			id, err := eventAlloc.id(ctx, tx, e.ID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, importEventSQL, append([]interface{}{id}, s.insertEventArgs(&ev)...)...); err != nil {
+				return fmt.Errorf("import event %d: %w", e.ID, err)
+			}
+			if id != e.ID {
+				result.RenumberedEvents[e.ID] = id
+			}
+			result.Events++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// remapPayloadIssueIDs rewrites the parent_id and blocker_id an event
+// payload names, and the parent_id and blocked_by of a snapshot event's
+// issue, to the issues' imported IDs, so replaying the imported events
+// links the same issues. Other fields are left as they were.
+func remapPayloadIssueIDs(payload string, issueIDs map[int]int) (string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(payload), &fields); err != nil || fields == nil {
+		// Not a JSON object, so it names no issues.
+		return payload, nil
+	}
+	changed, err := remapIssueIDFields(fields, issueIDs, "parent_id", "blocker_id")
+	if err != nil {
+		return "", fmt.Errorf("payload %w", err)
+	}
+	if raw, ok := fields["snapshot"]; ok {
+		var snapshot map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &snapshot); err != nil {
+			return "", fmt.Errorf("payload snapshot: %w", err)
+		}
+		snapshotChanged, err := remapIssueIDFields(snapshot, issueIDs, "parent_id")
+		if err != nil {
+			return "", fmt.Errorf("payload snapshot %w", err)
+		}
+		if raw, ok := snapshot["blocked_by"]; ok {
+			var blockers []int
+			if err := json.Unmarshal(raw, &blockers); err != nil {
+				return "", fmt.Errorf("payload snapshot blocked_by: %w", err)
+			}
+			blockersChanged := false
+			for i, id := range blockers {
+				if newID, ok := issueIDs[id]; ok && newID != id {
+					blockers[i] = newID
+					blockersChanged = true
+				}
+			}
+			if blockersChanged {
+				data, err := json.Marshal(blockers)
+				if err != nil {
+					return "", fmt.Errorf("marshal snapshot blocked_by: %w", err)
+				}
+				snapshot["blocked_by"] = data
+				snapshotChanged = true
+			}
+		}
+		if snapshotChanged {
+			data, err := json.Marshal(snapshot)
+			if err != nil {
+				return "", fmt.Errorf("marshal snapshot: %w", err)
+			}
+			fields["snapshot"] = data
+			changed = true
+		}
+	}
+	if !changed {
+		return payload, nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("marshal payload: %w", err)
+	}
+	return string(data), nil
+}
+
+// remapIssueIDFields rewrites each of keys in fields that holds an issue ID
+// to that issue's imported ID, reporting whether any changed.
+func remapIssueIDFields(fields map[string]json.RawMessage, issueIDs map[int]int, keys ...string) (bool, error) {
+	changed := false
+	for _, key := range keys {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+		var id int
+		if err := json.Unmarshal(raw, &id); err != nil {
+			return false, fmt.Errorf("%s: %w", key, err)
+		}
+		newID, ok := issueIDs[id]
+		if !ok || newID == id {
+			continue
+		}
+		fields[key] = json.RawMessage(strconv.Itoa(newID))
+		changed = true
+	}
+	return changed, nil
+}
+
+const importEventSQL = `INSERT INTO events (id, repo_id, github_comment_id, issue_id, github_issue_number, timestamp, action, payload, agent, synced, idempotency_key)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// idAllocator keeps the dumped IDs of one table that are free. A taken ID
+// is replaced by one above every existing and dumped ID, so a renumbered
+// row never takes the ID a later dumped row needs.
+type idAllocator struct {
+	table string
+	next  int
+}
+
+// newIDAllocator allocates IDs for table, whose highest dumped ID is
+// maxDumped.
+func newIDAllocator(ctx context.Context, tx *sql.Tx, table string, maxDumped int) (*idAllocator, error) {
+	var maxID sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT MAX(id) FROM `+table).Scan(&maxID); err != nil {
+		return nil, fmt.Errorf("find highest %s id: %w", table, err)
+	}
+	return &idAllocator{table: table, next: max(int(maxID.Int64), maxDumped) + 1}, nil
+}
+
+// id returns dumped if no row has it yet, or else a new ID.
+func (a *idAllocator) id(ctx context.Context, tx *sql.Tx, dumped int) (int, error) {
+	var taken bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM `+a.table+` WHERE id = ?)`, dumped).Scan(&taken); err != nil {
+		return 0, fmt.Errorf("check %s id %d: %w", a.table, dumped, err)
+	}
+	if dumped > 0 && !taken {
+		return dumped, nil
+	}
+	id := a.next
+	a.next++
+	return id, nil
+}
+
+// importRepo inserts a dumped repo with its settings and sync cursors, and
+// returns its ID. The legacy local path fields are cleared with the paths.
+func (s *SQLiteStore) importRepo(ctx context.Context, tx *sql.Tx, alloc *idAllocator, r *model.RepoConfig) (int, error) {
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM repos WHERE owner = ? AND name = ?)`, r.Owner, r.Name).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("check repo %s: %w", r.FullName(), err)
+	}
+	if exists {
+		return 0, fmt.Errorf("%w: %s", ErrRepoExists, r.FullName())
+	}
+
+	id, err := alloc.id(ctx, tx, r.ID)
+	if err != nil {
+		return 0, err
+	}
+	createdAt := r.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO repos (id, owner, name, created_at) VALUES (?, ?, ?, ?)`,
+		id, r.Owner, r.Name, createdAt.UTC().Format(time.RFC3339)); err != nil {
+		return 0, fmt.Errorf("import repo %s: %w", r.FullName(), err)
+	}
+
+	repo := *r
+	repo.ID = id
+	repo.LocalPath, repo.SocketEnabled, repo.QueueEnabled = "", false, false
+	args, err := updateRepoArgs(&repo)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, updateRepoSQL, args...); err != nil {
+		return 0, fmt.Errorf("import settings of repo %s: %w", r.FullName(), err)
+	}
+	return repo.ID, nil
+}
+
+// importIssue inserts a dumped issue into repoID, keeping its timestamps,
+// and returns its ID. Parent and blockers are linked by the caller.
+func importIssue(ctx context.Context, tx *sql.Tx, alloc *idAllocator, iss *model.Issue, repoID int) (int, error) {
+	id, err := alloc.id(ctx, tx, iss.ID)
+	if err != nil {
+		return 0, err
+	}
+	labels := iss.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return 0, fmt.Errorf("marshal labels: %w", err)
+	}
+	comments := iss.Comments
+	if comments == nil {
+		comments = []model.Comment{}
+	}
+	commentsJSON, err := json.Marshal(comments)
+	if err != nil {
+		return 0, fmt.Errorf("marshal comments: %w", err)
+	}
+	var closedAt *string
+	if iss.ClosedAt != nil {
+		t := iss.ClosedAt.UTC().Format(time.RFC3339)
+		closedAt = &t
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO issues (id, repo_id, github_id, title, status, priority, issue_type, description, owner, labels, created_at, updated_at, closed_at, comments, snoozed_until, estimate)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, repoID, iss.GitHubID, iss.Title, string(iss.Status), iss.Priority,
+		string(iss.IssueType), iss.Description, iss.Owner, string(labelsJSON),
+		iss.CreatedAt.UTC().Format(time.RFC3339), iss.UpdatedAt.UTC().Format(time.RFC3339),
+		closedAt, string(commentsJSON), formatSnoozedUntil(iss.SnoozedUntil), iss.Estimate); err != nil {
+		return 0, fmt.Errorf("import issue %d: %w", iss.ID, err)
+	}
+	return id, nil
+}
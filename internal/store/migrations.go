@@ -8,20 +8,7 @@ import (
 
 // DBSchemaVersion is the current database schema version.
 // Bump this when adding migrations that change the schema.
-const DBSchemaVersion = 5
-
-// downMigrations maps a version to the SQL needed to reverse it.
-// Version N's entry contains statements that undo the changes introduced
-// when migrating from N-1 to N. For additive-only changes (ADD COLUMN,
-// CREATE TABLE IF NOT EXISTS), no reverse SQL is needed — just the
-// version number reset.
-//
-// Example for a future breaking migration:
-//
-//	2: []string{"ALTER TABLE issues DROP COLUMN new_col"},
-var downMigrations = map[int][]string{
-	// Version 1 is the baseline schema; nothing to reverse.
-}
+const DBSchemaVersion = 27
 
 // alterColumn runs an ALTER TABLE ADD COLUMN and silently ignores
 // "duplicate column name" errors, making the migration idempotent.
@@ -33,75 +20,252 @@ func alterColumn(db *sql.DB, stmt string) error {
 	return err
 }
 
-// migrations is an ordered list of SQL statements applied to the database.
-// Each statement is idempotent (uses IF NOT EXISTS where possible).
-var migrations = []string{
-	`CREATE TABLE IF NOT EXISTS repos (
-		id               INTEGER PRIMARY KEY AUTOINCREMENT,
-		owner            TEXT NOT NULL,
-		name             TEXT NOT NULL,
-		poll_interval_ms INTEGER DEFAULT 5000,
-		last_sync_at     TEXT,
-		issues_etag      TEXT DEFAULT '',
-		created_at       TEXT NOT NULL DEFAULT (datetime('now')),
-		UNIQUE(owner, name)
-	)`,
-
-	`CREATE TABLE IF NOT EXISTS issues (
-		id          INTEGER PRIMARY KEY AUTOINCREMENT,
-		repo_id     INTEGER NOT NULL REFERENCES repos(id),
-		github_id   INTEGER,
-		title       TEXT NOT NULL,
-		status      TEXT NOT NULL DEFAULT 'open',
-		priority    INTEGER NOT NULL DEFAULT 2,
-		issue_type  TEXT NOT NULL DEFAULT 'task',
-		description TEXT DEFAULT '',
-		owner       TEXT DEFAULT '',
-		labels      TEXT DEFAULT '[]',
-		created_at  TEXT NOT NULL,
-		updated_at  TEXT NOT NULL,
-		closed_at   TEXT
-	)`,
+// migrationStep is the schema change that brings a database from
+// version-1 up to version. up must be idempotent: runMigrations re-applies
+// every step on each start. from is the version the database had before
+// the migration began, for steps that move existing data.
+//
+// down holds the statements that reverse the step. Additive changes
+// (ADD COLUMN, CREATE TABLE IF NOT EXISTS) need none; downgrading past them
+// only resets the version number. For a future breaking migration:
+//
+//	down: []string{"ALTER TABLE issues DROP COLUMN new_col"},
+type migrationStep struct {
+	version int
+	desc    string
+	up      func(db *sql.DB, from int) error
+	down    []string
+}
 
-	`CREATE INDEX IF NOT EXISTS idx_issues_repo_status ON issues(repo_id, status)`,
-	`CREATE INDEX IF NOT EXISTS idx_issues_repo_priority ON issues(repo_id, priority)`,
-	`CREATE INDEX IF NOT EXISTS idx_issues_github_id ON issues(repo_id, github_id)`,
-
-	`CREATE TABLE IF NOT EXISTS events (
-		id                  INTEGER PRIMARY KEY AUTOINCREMENT,
-		repo_id             INTEGER NOT NULL REFERENCES repos(id),
-		github_comment_id   INTEGER,
-		issue_id            INTEGER NOT NULL,
-		github_issue_number INTEGER,
-		timestamp           TEXT NOT NULL,
-		action              TEXT NOT NULL,
-		payload             TEXT NOT NULL,
-		agent               TEXT DEFAULT '',
-		synced              INTEGER DEFAULT 0,
-		created_at          TEXT NOT NULL DEFAULT (datetime('now'))
-	)`,
+// execAll returns a step body that runs stmts in order.
+func execAll(stmts ...string) func(*sql.DB, int) error {
+	return func(db *sql.DB, _ int) error {
+		for _, stmt := range stmts {
+			if _, err := db.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
 
-	`CREATE INDEX IF NOT EXISTS idx_events_repo_issue ON events(repo_id, issue_id)`,
-	`CREATE INDEX IF NOT EXISTS idx_events_synced ON events(synced)`,
+// addColumns returns a step body that runs ALTER TABLE ADD COLUMN stmts
+// through alterColumn.
+func addColumns(stmts ...string) func(*sql.DB, int) error {
+	return func(db *sql.DB, _ int) error {
+		for _, stmt := range stmts {
+			if err := alterColumn(db, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
 
-	`CREATE TABLE IF NOT EXISTS issue_sync_state (
-		repo_id              INTEGER NOT NULL,
-		github_issue_number  INTEGER NOT NULL,
-		last_comment_id      INTEGER NOT NULL DEFAULT 0,
-		last_comment_at      TEXT,
-		PRIMARY KEY (repo_id, github_issue_number)
-	)`,
+// upMigrations is the ordered registry of schema versions. Versions 2-4
+// predate per-version steps; their columns are added by step 5, so a
+// database at any of them still converges. The last entry must be
+// DBSchemaVersion.
+var upMigrations = []migrationStep{
+	{version: 1, desc: "baseline repos, issues, events and sync state tables", up: execAll(
+		`CREATE TABLE IF NOT EXISTS repos (
+			id               INTEGER PRIMARY KEY AUTOINCREMENT,
+			owner            TEXT NOT NULL,
+			name             TEXT NOT NULL,
+			poll_interval_ms INTEGER DEFAULT 5000,
+			last_sync_at     TEXT,
+			issues_etag      TEXT DEFAULT '',
+			created_at       TEXT NOT NULL DEFAULT (datetime('now')),
+			UNIQUE(owner, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS issues (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			repo_id     INTEGER NOT NULL REFERENCES repos(id),
+			github_id   INTEGER,
+			title       TEXT NOT NULL,
+			status      TEXT NOT NULL DEFAULT 'open',
+			priority    INTEGER NOT NULL DEFAULT 2,
+			issue_type  TEXT NOT NULL DEFAULT 'task',
+			description TEXT DEFAULT '',
+			owner       TEXT DEFAULT '',
+			labels      TEXT DEFAULT '[]',
+			created_at  TEXT NOT NULL,
+			updated_at  TEXT NOT NULL,
+			closed_at   TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_issues_repo_status ON issues(repo_id, status)`,
+		`CREATE INDEX IF NOT EXISTS idx_issues_repo_priority ON issues(repo_id, priority)`,
+		`CREATE INDEX IF NOT EXISTS idx_issues_github_id ON issues(repo_id, github_id)`,
+		`CREATE TABLE IF NOT EXISTS events (
+			id                  INTEGER PRIMARY KEY AUTOINCREMENT,
+			repo_id             INTEGER NOT NULL REFERENCES repos(id),
+			github_comment_id   INTEGER,
+			issue_id            INTEGER NOT NULL,
+			github_issue_number INTEGER,
+			timestamp           TEXT NOT NULL,
+			action              TEXT NOT NULL,
+			payload             TEXT NOT NULL,
+			agent               TEXT DEFAULT '',
+			synced              INTEGER DEFAULT 0,
+			created_at          TEXT NOT NULL DEFAULT (datetime('now'))
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_events_repo_issue ON events(repo_id, issue_id)`,
+		`CREATE TABLE IF NOT EXISTS issue_sync_state (
+			repo_id              INTEGER NOT NULL,
+			github_issue_number  INTEGER NOT NULL,
+			last_comment_id      INTEGER NOT NULL DEFAULT 0,
+			last_comment_at      TEXT,
+			PRIMARY KEY (repo_id, github_issue_number)
+		)`,
+	)},
+	{version: 5, desc: "local paths, inline comments and trusted-author filtering", up: migrateLocalPaths},
+	{version: 6, desc: "issue snooze", up: addColumns(
+		`ALTER TABLE issues ADD COLUMN snoozed_until TEXT`,
+	)},
+	{version: 7, desc: "issue estimates", up: addColumns(
+		`ALTER TABLE issues ADD COLUMN estimate INTEGER NOT NULL DEFAULT 0`,
+	)},
+	{version: 8, desc: "per-repo allowed inbound actions", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN allowed_inbound_actions TEXT NOT NULL DEFAULT '[]'`,
+	)},
+	{version: 9, desc: "per-repo issue types", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN issue_types TEXT NOT NULL DEFAULT '[]'`,
+	)},
+	{version: 10, desc: "epic parent links and rollup", up: addColumns(
+		`ALTER TABLE issues ADD COLUMN parent_id INTEGER`,
+		`ALTER TABLE repos ADD COLUMN epic_rollup INTEGER NOT NULL DEFAULT 0`,
+	)},
+	// Hashes of event comments pushed to GitHub, so a retried push can
+	// recognise a comment it already posted.
+	{version: 11, desc: "posted comment hashes", up: execAll(
+		`CREATE TABLE IF NOT EXISTS posted_comments (
+			repo_id              INTEGER NOT NULL,
+			github_issue_number  INTEGER NOT NULL,
+			body_hash            TEXT NOT NULL,
+			event_id             INTEGER NOT NULL,
+			github_comment_id    INTEGER NOT NULL,
+			PRIMARY KEY (repo_id, github_issue_number, body_hash)
+		)`,
+	)},
+	{version: 12, desc: "per-repo next strategy", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN next_strategy TEXT NOT NULL DEFAULT ''`,
+	)},
+	{version: 13, desc: "per-repo sync direction", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN sync_direction TEXT NOT NULL DEFAULT ''`,
+	)},
+	{version: 14, desc: "per-repo human comment ingestion", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN ingest_human_comments INTEGER NOT NULL DEFAULT 0`,
+	)},
+	{version: 15, desc: "recent-activity index for trending issues", up: execAll(
+		`CREATE INDEX IF NOT EXISTS idx_events_repo_timestamp ON events(repo_id, timestamp)`,
+	)},
+	// The default backfills existing repos with the label they have always
+	// used.
+	{version: 16, desc: "per-repo tracking label", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN label TEXT NOT NULL DEFAULT 'boxofrocks'`,
+	)},
+	{version: 17, desc: "per-repo trusted GitHub logins", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN trusted_authors TEXT NOT NULL DEFAULT '[]'`,
+	)},
+	// blocker_id must close before issue_id is offered by next.
+	{version: 18, desc: "issue dependencies", up: execAll(
+		`CREATE TABLE IF NOT EXISTS issue_dependencies (
+			issue_id    INTEGER NOT NULL,
+			blocker_id  INTEGER NOT NULL,
+			PRIMARY KEY (issue_id, blocker_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_issue_dependencies_blocker ON issue_dependencies(blocker_id)`,
+	)},
+	{version: 19, desc: "full-text issue search", up: func(db *sql.DB, _ int) error { return migrateFTS(db) }},
+	{version: 20, desc: "comment listing ETags", up: addColumns(
+		`ALTER TABLE issue_sync_state ADD COLUMN comments_etag TEXT NOT NULL DEFAULT ''`,
+	)},
+	{version: 21, desc: "per-repo assignee login map", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN assignee_logins TEXT NOT NULL DEFAULT '{}'`,
+	)},
+	{version: 22, desc: "per-repo work-in-progress limit", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN wip_limit INTEGER NOT NULL DEFAULT 0`,
+	)},
+	// A notification row records that event_id changed an issue watcher
+	// was watching when it was appended.
+	{version: 23, desc: "issue watchers and notifications", up: execAll(
+		`CREATE TABLE IF NOT EXISTS issue_watchers (
+			issue_id  INTEGER NOT NULL,
+			watcher   TEXT NOT NULL COLLATE NOCASE,
+			PRIMARY KEY (issue_id, watcher)
+		)`,
+		`CREATE TABLE IF NOT EXISTS notifications (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			repo_id     INTEGER NOT NULL,
+			watcher     TEXT NOT NULL COLLATE NOCASE,
+			issue_id    INTEGER NOT NULL,
+			event_id    INTEGER NOT NULL,
+			created_at  TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_notifications_watcher ON notifications(repo_id, watcher, id)`,
+	)},
+	// Existing events keep a NULL key, which the unique index never matches,
+	// so rows that would collide cannot fail the migration.
+	{version: 24, desc: "event idempotency keys", up: func(db *sql.DB, _ int) error {
+		if err := alterColumn(db, `ALTER TABLE events ADD COLUMN idempotency_key TEXT`); err != nil {
+			return err
+		}
+		_, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_events_idempotency ON events(issue_id, idempotency_key)`)
+		return err
+	}},
+	{version: 25, desc: "per-repo assignee validation", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN validate_assignee INTEGER NOT NULL DEFAULT 0`,
+	)},
+	{version: 26, desc: "per-repo issue templates", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN issue_templates TEXT NOT NULL DEFAULT '{}'`,
+	)},
+	{version: 27, desc: "per-repo issue key prefix", up: addColumns(
+		`ALTER TABLE repos ADD COLUMN key_prefix TEXT NOT NULL DEFAULT ''`,
+	)},
 }
 
-// alterMigrations are ALTER TABLE statements that are run after the main
-// CREATE TABLE migrations. They use alterColumn to be idempotent.
-var alterMigrations = []string{
-	`ALTER TABLE repos ADD COLUMN issues_since TEXT DEFAULT ''`,
-	`ALTER TABLE issues ADD COLUMN comments TEXT DEFAULT '[]'`,
-	`ALTER TABLE repos ADD COLUMN trusted_authors_only INTEGER DEFAULT 0`,
-	`ALTER TABLE repos ADD COLUMN local_path TEXT DEFAULT ''`,
-	`ALTER TABLE repos ADD COLUMN socket_enabled INTEGER DEFAULT 0`,
-	`ALTER TABLE repos ADD COLUMN queue_enabled INTEGER DEFAULT 0`,
+// migrateLocalPaths is step 5. It also carries the columns added by
+// versions 2-4, and moves repos.local_path into the repo_local_paths
+// junction table (multiple worktrees per repo) when upgrading from below 5.
+func migrateLocalPaths(db *sql.DB, from int) error {
+	if err := addColumns(
+		`ALTER TABLE repos ADD COLUMN issues_since TEXT DEFAULT ''`,
+		`ALTER TABLE issues ADD COLUMN comments TEXT DEFAULT '[]'`,
+		`ALTER TABLE repos ADD COLUMN trusted_authors_only INTEGER DEFAULT 0`,
+		`ALTER TABLE repos ADD COLUMN local_path TEXT DEFAULT ''`,
+		`ALTER TABLE repos ADD COLUMN socket_enabled INTEGER DEFAULT 0`,
+		`ALTER TABLE repos ADD COLUMN queue_enabled INTEGER DEFAULT 0`,
+	)(db, from); err != nil {
+		return err
+	}
+
+	// Replace single-column index with composite index for the actual query pattern.
+	if _, err := db.Exec(`DROP INDEX IF EXISTS idx_events_synced`); err != nil {
+		return fmt.Errorf("drop idx_events_synced: %w", err)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_events_repo_synced ON events(repo_id, synced)`); err != nil {
+		return fmt.Errorf("create idx_events_repo_synced: %w", err)
+	}
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS repo_local_paths (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		repo_id INTEGER NOT NULL REFERENCES repos(id) ON DELETE CASCADE,
+		local_path TEXT NOT NULL,
+		socket_enabled INTEGER DEFAULT 0,
+		queue_enabled INTEGER DEFAULT 0,
+		UNIQUE(local_path)
+	)`); err != nil {
+		return fmt.Errorf("create repo_local_paths: %w", err)
+	}
+
+	// Migrate existing local_path data from repos table (only on upgrade from v4).
+	if from < 5 {
+		if _, err := db.Exec(`INSERT OR IGNORE INTO repo_local_paths (repo_id, local_path, socket_enabled, queue_enabled)
+			SELECT id, local_path, socket_enabled, queue_enabled FROM repos WHERE local_path != ''`); err != nil {
+			return fmt.Errorf("migrate repo local paths: %w", err)
+		}
+	}
+	return nil
 }
 
 // OpenRawDB opens a SQLite database without running migrations or
@@ -127,6 +291,44 @@ func ReadDBVersion(db *sql.DB) (int, error) {
 	return version, nil
 }
 
+// BackupDB writes a consistent copy of db to dest, which must not exist.
+// Unlike copying the file, the copy includes changes still in the WAL.
+func BackupDB(db *sql.DB, dest string) error {
+	if _, err := db.Exec(`VACUUM INTO ?`, dest); err != nil {
+		return fmt.Errorf("backup to %s: %w", dest, err)
+	}
+	return nil
+}
+
+// UpgradeDB upgrades the database from its current version to the target
+// version, applying the registered steps in between. onStep, if non-nil,
+// is called after each applied step.
+func UpgradeDB(db *sql.DB, current, target int, onStep func(version int, desc string)) error {
+	if target <= current {
+		return fmt.Errorf("target version %d must be greater than current version %d", target, current)
+	}
+	if target > DBSchemaVersion {
+		return fmt.Errorf("target version %d is newer than this binary supports (max %d)", target, DBSchemaVersion)
+	}
+
+	for _, step := range upMigrations {
+		if step.version <= current || step.version > target {
+			continue
+		}
+		if err := step.up(db, current); err != nil {
+			return fmt.Errorf("up migration v%d: %w", step.version, err)
+		}
+		if onStep != nil {
+			onStep(step.version, step.desc)
+		}
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", target)); err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+	return nil
+}
+
 // DowngradeDB downgrades the database from its current version to the
 // target version, running any reverse migrations along the way.
 // For additive-only schema changes, this just resets user_version.
@@ -140,12 +342,14 @@ func DowngradeDB(db *sql.DB, current, target int) error {
 	}
 
 	// Run reverse migrations from current down to target+1.
-	for v := current; v > target; v-- {
-		if stmts, ok := downMigrations[v]; ok {
-			for _, stmt := range stmts {
-				if _, err := db.Exec(stmt); err != nil {
-					return fmt.Errorf("down migration v%d: %w", v, err)
-				}
+	for i := len(upMigrations) - 1; i >= 0; i-- {
+		step := upMigrations[i]
+		if step.version > current || step.version <= target {
+			continue
+		}
+		for _, stmt := range step.down {
+			if _, err := db.Exec(stmt); err != nil {
+				return fmt.Errorf("down migration v%d: %w", step.version, err)
 			}
 		}
 	}
@@ -156,10 +360,11 @@ func DowngradeDB(db *sql.DB, current, target int) error {
 	return nil
 }
 
-// runMigrations applies all migration statements in order.
+// runMigrations applies every migration step in order.
 // It checks the database schema version and refuses to proceed if the
 // database was created by a newer binary (to prevent data corruption
-// on rollback).
+// on rollback). Steps at or below the stored version are re-applied too,
+// which repairs a database whose version was reset by a downgrade.
 func runMigrations(db *sql.DB) error {
 	var dbVersion int
 	if err := db.QueryRow("PRAGMA user_version").Scan(&dbVersion); err != nil {
@@ -171,50 +376,67 @@ func runMigrations(db *sql.DB) error {
 			dbVersion, DBSchemaVersion)
 	}
 
-	for _, m := range migrations {
-		if _, err := db.Exec(m); err != nil {
+	for _, step := range upMigrations {
+		if err := step.up(db, dbVersion); err != nil {
 			return err
 		}
 	}
-	for _, m := range alterMigrations {
-		if err := alterColumn(db, m); err != nil {
-			return err
+
+	if dbVersion < DBSchemaVersion {
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", DBSchemaVersion)); err != nil {
+			return fmt.Errorf("set schema version: %w", err)
 		}
 	}
 
-	// Replace single-column index with composite index for the actual query pattern.
-	if _, err := db.Exec(`DROP INDEX IF EXISTS idx_events_synced`); err != nil {
-		return fmt.Errorf("drop idx_events_synced: %w", err)
-	}
-	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_events_repo_synced ON events(repo_id, synced)`); err != nil {
-		return fmt.Errorf("create idx_events_repo_synced: %w", err)
-	}
+	return nil
+}
 
-	// Version 5: repo_local_paths junction table for multiple worktrees per repo.
-	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS repo_local_paths (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		repo_id INTEGER NOT NULL REFERENCES repos(id) ON DELETE CASCADE,
-		local_path TEXT NOT NULL,
-		socket_enabled INTEGER DEFAULT 0,
-		queue_enabled INTEGER DEFAULT 0,
-		UNIQUE(local_path)
-	)`); err != nil {
-		return fmt.Errorf("create repo_local_paths: %w", err)
-	}
+// ftsMigrations create the issues_fts full-text index over issue titles and
+// descriptions, and the triggers that keep it in step with the issues table.
+var ftsMigrations = []string{
+	`CREATE VIRTUAL TABLE issues_fts USING fts5(
+		title, description, content='issues', content_rowid='id'
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS issues_fts_insert AFTER INSERT ON issues BEGIN
+		INSERT INTO issues_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS issues_fts_delete AFTER DELETE ON issues BEGIN
+		INSERT INTO issues_fts(issues_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS issues_fts_update AFTER UPDATE OF title, description ON issues BEGIN
+		INSERT INTO issues_fts(issues_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+		INSERT INTO issues_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	// Backfill rows that predate the index.
+	`INSERT INTO issues_fts(issues_fts) VALUES ('rebuild')`,
+}
 
-	// Migrate existing local_path data from repos table (only on upgrade from v4).
-	if dbVersion < 5 {
-		if _, err := db.Exec(`INSERT OR IGNORE INTO repo_local_paths (repo_id, local_path, socket_enabled, queue_enabled)
-			SELECT id, local_path, socket_enabled, queue_enabled FROM repos WHERE local_path != ''`); err != nil {
-			return fmt.Errorf("migrate repo local paths: %w", err)
-		}
+// migrateFTS creates the full-text index (version 19) if it does not exist
+// yet. A SQLite build without FTS5 is not an error: the index is skipped and
+// SearchIssues falls back to LIKE.
+func migrateFTS(db *sql.DB) error {
+	if hasFTS(db) {
+		return nil
 	}
-
-	if dbVersion < DBSchemaVersion {
-		if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", DBSchemaVersion)); err != nil {
-			return fmt.Errorf("set schema version: %w", err)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, m := range ftsMigrations {
+		if _, err := tx.Exec(m); err != nil {
+			if strings.Contains(err.Error(), "no such module") {
+				return nil
+			}
+			return fmt.Errorf("create issues_fts: %w", err)
 		}
 	}
+	return tx.Commit()
+}
 
-	return nil
+// hasFTS reports whether the issues_fts full-text index exists.
+func hasFTS(db *sql.DB) bool {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'issues_fts'`).Scan(&n)
+	return err == nil && n > 0
 }
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// WriteRetry controls how store writes are retried when SQLite reports that
+// the database is locked. busy_timeout handles most contention inside SQLite;
+// this covers the cases it cannot (e.g. lock upgrades in WAL mode), which
+// would otherwise surface to clients as a 500.
+type WriteRetry struct {
+	Attempts int           // total attempts, including the first; < 1 means 1
+	Backoff  time.Duration // delay before the first retry, doubled after each
+}
+
+// DefaultWriteRetry is the retry policy used by NewSQLiteStore.
+var DefaultWriteRetry = WriteRetry{Attempts: 5, Backoff: 10 * time.Millisecond}
+
+// SetWriteRetry replaces the store's write retry policy.
+func (s *SQLiteStore) SetWriteRetry(r WriteRetry) {
+	s.retry = r
+}
+
+// isBusy reports whether err is SQLite's "database is locked" (SQLITE_BUSY)
+// or "database table is locked" (SQLITE_LOCKED) error.
+func isBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "SQLITE_BUSY") ||
+		strings.Contains(msg, "SQLITE_LOCKED") ||
+		strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked")
+}
+
+// execWrite runs a write statement, retrying with exponential backoff while
+// the database is locked. It gives up early if ctx is done.
+func (s *SQLiteStore) execWrite(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	attempts := s.retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := s.retry.Backoff
+
+	var res sql.Result
+	var err error
+	for i := 0; i < attempts; i++ {
+		res, err = s.db.ExecContext(ctx, query, args...)
+		if !isBusy(err) || i == attempts-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return res, err
+}
+
+// writeTx runs fn in a transaction and commits it. The whole transaction is
+// retried, like execWrite, if the database is locked; fn must therefore be
+// safe to run more than once.
+func (s *SQLiteStore) writeTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	attempts := s.retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := s.retry.Backoff
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		err = s.runTx(ctx, fn)
+		if !isBusy(err) || i == attempts-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return err
+}
+
+func (s *SQLiteStore) runTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
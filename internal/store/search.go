@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+// SearchIssues matches each whitespace-separated term of query as a word
+// prefix against issue titles and descriptions, so "auth" finds
+// "authentication". Results are ranked by relevance. Without FTS5 it falls
+// back to a substring LIKE match ordered like ListIssues.
+func (s *SQLiteStore) SearchIssues(ctx context.Context, repoID int, query string) ([]*model.Issue, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var rows *sql.Rows
+	var err error
+	if s.fts {
+		rows, err = s.db.QueryContext(ctx,
+			`SELECT `+issueColumns+`
+			 FROM issues
+			 JOIN (SELECT rowid AS fts_id, rank AS fts_rank
+			       FROM issues_fts WHERE issues_fts MATCH ?) f ON f.fts_id = issues.id
+			 WHERE repo_id = ? AND status != 'deleted'
+			 ORDER BY f.fts_rank, priority ASC, id ASC`,
+			ftsQuery(terms), repoID)
+	} else {
+		where := "repo_id = ? AND status != 'deleted'"
+		args := []interface{}{repoID}
+		for _, term := range terms {
+			where += ` AND (title LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`
+			pattern := "%" + escapeLike(term) + "%"
+			args = append(args, pattern, pattern)
+		}
+		rows, err = s.db.QueryContext(ctx,
+			`SELECT `+issueColumns+` FROM issues WHERE `+where+`
+			 ORDER BY priority ASC, created_at ASC, id ASC`, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []*model.Issue
+	for rows.Next() {
+		iss, err := scanIssue(rows)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, iss)
+	}
+	return issues, rows.Err()
+}
+
+// ftsQuery turns search terms into an FTS5 query requiring every term as a
+// word prefix. Each term is quoted so FTS5 operators in user input are
+// matched literally.
+func ftsQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(parts, " ")
+}
+
+// escapeLike escapes LIKE wildcards in s for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
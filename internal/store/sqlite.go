@@ -4,23 +4,66 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/jmaddaus/boxofrocks/internal/engine"
 	"github.com/jmaddaus/boxofrocks/internal/model"
 	_ "modernc.org/sqlite"
 )
 
 // SQLiteStore implements Store backed by a SQLite database.
 type SQLiteStore struct {
-	db *sql.DB
+	db    *sql.DB
+	retry WriteRetry
+
+	maxInlineComment int            // 0 keeps every comment inline
+	fetchComment     CommentFetcher // hydrates comments stored by reference
+	fts              bool           // issues_fts exists; SearchIssues uses it
+
+	// claimMu serializes ClaimNextIssue calls, so concurrent claims queue
+	// up instead of failing each other's transactions as SQLite locks.
+	claimMu sync.Mutex
+}
+
+// SQLiteOptions tunes per-connection SQLite pragmas.
+type SQLiteOptions struct {
+	BusyTimeoutMs int    // wait this long on a locked database; 0 uses the default
+	Synchronous   string // PRAGMA synchronous level; "" uses the default
+
+	// MaxInlineCommentBytes, if > 0, stores comments longer than this by
+	// reference for events that came from GitHub. See AppendEvent.
+	MaxInlineCommentBytes int
 }
 
+// DefaultSQLiteOptions waits up to 5s on locks and uses synchronous=NORMAL,
+// which is durable against application crashes and cannot corrupt a WAL
+// database on power loss (only the latest commits may be lost).
+var DefaultSQLiteOptions = SQLiteOptions{BusyTimeoutMs: 5000, Synchronous: "NORMAL"}
+
 // NewSQLiteStore opens (or creates) a SQLite database at dbPath and runs
 // migrations. Use ":memory:" for an in-memory database.
 func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	return NewSQLiteStoreWithOptions(dbPath, DefaultSQLiteOptions)
+}
+
+// NewSQLiteStoreWithOptions is like NewSQLiteStore but with explicit pragma
+// settings.
+func NewSQLiteStoreWithOptions(dbPath string, opts SQLiteOptions) (*SQLiteStore, error) {
+	switch strings.ToUpper(opts.Synchronous) {
+	case "", "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		return nil, fmt.Errorf("invalid synchronous mode %q", opts.Synchronous)
+	}
+
+	// busy_timeout and synchronous are per-connection, so they go in the DSN
+	// where the driver applies them to every pooled connection rather than
+	// via a one-off Exec.
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath, opts))
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite: %w", err)
 	}
@@ -41,7 +84,23 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("run migrations: %w", err)
 	}
 
-	return &SQLiteStore{db: db}, nil
+	return &SQLiteStore{db: db, retry: DefaultWriteRetry, maxInlineComment: opts.MaxInlineCommentBytes, fts: hasFTS(db)}, nil
+}
+
+// sqliteDSN appends the per-connection pragmas from opts to dbPath.
+func sqliteDSN(dbPath string, opts SQLiteOptions) string {
+	if opts.BusyTimeoutMs <= 0 {
+		opts.BusyTimeoutMs = DefaultSQLiteOptions.BusyTimeoutMs
+	}
+	if opts.Synchronous == "" {
+		opts.Synchronous = DefaultSQLiteOptions.Synchronous
+	}
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_pragma=busy_timeout(%d)&_pragma=synchronous(%s)",
+		dbPath, sep, opts.BusyTimeoutMs, strings.ToUpper(opts.Synchronous))
 }
 
 // Close closes the underlying database connection.
@@ -49,12 +108,17 @@ func (s *SQLiteStore) Close() error {
 	return s.db.Close()
 }
 
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	var one int
+	return s.db.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
+
 // ---------------------------------------------------------------------------
 // Repos
 // ---------------------------------------------------------------------------
 
 func (s *SQLiteStore) AddRepo(ctx context.Context, owner, name string) (*model.RepoConfig, error) {
-	res, err := s.db.ExecContext(ctx,
+	res, err := s.execWrite(ctx,
 		`INSERT INTO repos (owner, name) VALUES (?, ?)`, owner, name)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
@@ -66,9 +130,12 @@ func (s *SQLiteStore) AddRepo(ctx context.Context, owner, name string) (*model.R
 	return s.GetRepo(ctx, int(id))
 }
 
+// repoColumns is the column list scanned by scanRepo, in order.
+const repoColumns = `id, owner, name, poll_interval_ms, last_sync_at, issues_etag, issues_since, trusted_authors_only, local_path, socket_enabled, queue_enabled, created_at, allowed_inbound_actions, issue_types, epic_rollup, next_strategy, sync_direction, ingest_human_comments, label, trusted_authors, assignee_logins, wip_limit, validate_assignee, issue_templates, key_prefix`
+
 func (s *SQLiteStore) GetRepo(ctx context.Context, id int) (*model.RepoConfig, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, owner, name, poll_interval_ms, last_sync_at, issues_etag, issues_since, trusted_authors_only, local_path, socket_enabled, queue_enabled, created_at
+		`SELECT `+repoColumns+`
 		 FROM repos WHERE id = ?`, id)
 	repo, err := scanRepo(row)
 	if err != nil {
@@ -82,7 +149,7 @@ func (s *SQLiteStore) GetRepo(ctx context.Context, id int) (*model.RepoConfig, e
 
 func (s *SQLiteStore) GetRepoByName(ctx context.Context, owner, name string) (*model.RepoConfig, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, owner, name, poll_interval_ms, last_sync_at, issues_etag, issues_since, trusted_authors_only, local_path, socket_enabled, queue_enabled, created_at
+		`SELECT `+repoColumns+`
 		 FROM repos WHERE owner = ? AND name = ?`, owner, name)
 	repo, err := scanRepo(row)
 	if err != nil {
@@ -96,7 +163,7 @@ func (s *SQLiteStore) GetRepoByName(ctx context.Context, owner, name string) (*m
 
 func (s *SQLiteStore) ListRepos(ctx context.Context) ([]*model.RepoConfig, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, owner, name, poll_interval_ms, last_sync_at, issues_etag, issues_since, trusted_authors_only, local_path, socket_enabled, queue_enabled, created_at
+		`SELECT `+repoColumns+`
 		 FROM repos ORDER BY id`)
 	if err != nil {
 		return nil, err
@@ -158,7 +225,7 @@ func (s *SQLiteStore) loadLocalPaths(ctx context.Context, repo *model.RepoConfig
 }
 
 func (s *SQLiteStore) AddLocalPath(ctx context.Context, repoID int, localPath string, socket, queue bool) (*model.LocalPathConfig, error) {
-	_, err := s.db.ExecContext(ctx,
+	_, err := s.execWrite(ctx,
 		`INSERT INTO repo_local_paths (repo_id, local_path, socket_enabled, queue_enabled)
 		 VALUES (?, ?, ?, ?)
 		 ON CONFLICT(local_path) DO UPDATE SET socket_enabled=excluded.socket_enabled, queue_enabled=excluded.queue_enabled`,
@@ -181,8 +248,41 @@ func (s *SQLiteStore) AddLocalPath(ctx context.Context, repoID int, localPath st
 	return &lp, nil
 }
 
+// DeleteRepo removes a repo together with its issues, events, sync state,
+// posted-comment hashes, issue dependencies and local paths, in one
+// transaction. Returns sql.ErrNoRows if the repo does not exist.
+func (s *SQLiteStore) DeleteRepo(ctx context.Context, id int) error {
+	return s.writeTx(ctx, func(tx *sql.Tx) error {
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM repos WHERE id = ?`, id).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		for _, stmt := range []string{
+			`DELETE FROM issue_dependencies
+			 WHERE issue_id IN (SELECT id FROM issues WHERE repo_id = ?1)
+			    OR blocker_id IN (SELECT id FROM issues WHERE repo_id = ?1)`,
+			`DELETE FROM issue_watchers WHERE issue_id IN (SELECT id FROM issues WHERE repo_id = ?)`,
+			`DELETE FROM notifications WHERE repo_id = ?`,
+			`DELETE FROM events WHERE repo_id = ?`,
+			`DELETE FROM issue_sync_state WHERE repo_id = ?`,
+			`DELETE FROM posted_comments WHERE repo_id = ?`,
+			`DELETE FROM issues WHERE repo_id = ?`,
+			`DELETE FROM repo_local_paths WHERE repo_id = ?`,
+			`DELETE FROM repos WHERE id = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, stmt, id); err != nil {
+				return fmt.Errorf("delete repo %d: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
 func (s *SQLiteStore) RemoveLocalPath(ctx context.Context, repoID int, localPath string) error {
-	_, err := s.db.ExecContext(ctx,
+	_, err := s.execWrite(ctx,
 		`DELETE FROM repo_local_paths WHERE repo_id = ? AND local_path = ?`,
 		repoID, localPath)
 	return err
@@ -194,6 +294,19 @@ func (s *SQLiteStore) ListLocalPaths(ctx context.Context, repoID int) ([]model.L
 	if err != nil {
 		return nil, err
 	}
+	return scanLocalPaths(rows)
+}
+
+func (s *SQLiteStore) ListAllLocalPaths(ctx context.Context) ([]model.LocalPathConfig, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, repo_id, local_path, socket_enabled, queue_enabled FROM repo_local_paths ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	return scanLocalPaths(rows)
+}
+
+func scanLocalPaths(rows *sql.Rows) ([]model.LocalPathConfig, error) {
 	defer rows.Close()
 
 	var paths []model.LocalPathConfig
@@ -210,17 +323,83 @@ func (s *SQLiteStore) ListLocalPaths(ctx context.Context, repoID int) ([]model.L
 	return paths, rows.Err()
 }
 
+func (s *SQLiteStore) ResolveRepoByPath(ctx context.Context, dir string) (*model.RepoConfig, error) {
+	// substr rather than LIKE so that '%' and '_' in paths are not wildcards.
+	var repoID int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT repo_id FROM repo_local_paths
+		 WHERE local_path != ''
+		   AND (local_path = ? OR substr(?, 1, length(local_path) + 1) = local_path || '/')
+		 ORDER BY length(local_path) DESC
+		 LIMIT 1`, dir, dir).Scan(&repoID)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRepo(ctx, repoID)
+}
+
 func (s *SQLiteStore) UpdateRepo(ctx context.Context, repo *model.RepoConfig) error {
+	args, err := updateRepoArgs(repo)
+	if err != nil {
+		return err
+	}
+	_, err = s.execWrite(ctx, updateRepoSQL, args...)
+	return err
+}
+
+const updateRepoSQL = `UPDATE repos SET owner=?, name=?, poll_interval_ms=?, last_sync_at=?, issues_etag=?, issues_since=?, trusted_authors_only=?, local_path=?, socket_enabled=?, queue_enabled=?, allowed_inbound_actions=?, issue_types=?, epic_rollup=?, next_strategy=?, sync_direction=?, ingest_human_comments=?, label=?, trusted_authors=?, assignee_logins=?, wip_limit=?, validate_assignee=?, issue_templates=?, key_prefix=?
+		 WHERE id=?`
+
+// updateRepoArgs returns the arguments for updateRepoSQL.
+func updateRepoArgs(repo *model.RepoConfig) ([]interface{}, error) {
 	var lastSync *string
 	if repo.LastSyncAt != nil {
 		t := repo.LastSyncAt.Format(time.RFC3339)
 		lastSync = &t
 	}
-	_, err := s.db.ExecContext(ctx,
-		`UPDATE repos SET owner=?, name=?, poll_interval_ms=?, last_sync_at=?, issues_etag=?, issues_since=?, trusted_authors_only=?, local_path=?, socket_enabled=?, queue_enabled=?
-		 WHERE id=?`,
-		repo.Owner, repo.Name, repo.PollIntervalMs, lastSync, repo.IssuesETag, repo.IssuesSince, boolToInt(repo.TrustedAuthorsOnly), repo.LocalPath, boolToInt(repo.SocketEnabled), boolToInt(repo.QueueEnabled), repo.ID)
-	return err
+	allowed := repo.AllowedInboundActions
+	if allowed == nil {
+		allowed = []string{}
+	}
+	allowedJSON, err := json.Marshal(allowed)
+	if err != nil {
+		return nil, fmt.Errorf("marshal allowed_inbound_actions: %w", err)
+	}
+	issueTypes := repo.IssueTypes
+	if issueTypes == nil {
+		issueTypes = []string{}
+	}
+	issueTypesJSON, err := json.Marshal(issueTypes)
+	if err != nil {
+		return nil, fmt.Errorf("marshal issue_types: %w", err)
+	}
+	trustedAuthors := repo.TrustedAuthors
+	if trustedAuthors == nil {
+		trustedAuthors = []string{}
+	}
+	trustedAuthorsJSON, err := json.Marshal(trustedAuthors)
+	if err != nil {
+		return nil, fmt.Errorf("marshal trusted_authors: %w", err)
+	}
+	assigneeLogins := repo.AssigneeLogins
+	if assigneeLogins == nil {
+		assigneeLogins = map[string]string{}
+	}
+	assigneeLoginsJSON, err := json.Marshal(assigneeLogins)
+	if err != nil {
+		return nil, fmt.Errorf("marshal assignee_logins: %w", err)
+	}
+	issueTemplates := repo.IssueTemplates
+	if issueTemplates == nil {
+		issueTemplates = map[string]string{}
+	}
+	issueTemplatesJSON, err := json.Marshal(issueTemplates)
+	if err != nil {
+		return nil, fmt.Errorf("marshal issue_templates: %w", err)
+	}
+	return []interface{}{
+		repo.Owner, repo.Name, repo.PollIntervalMs, lastSync, repo.IssuesETag, repo.IssuesSince, boolToInt(repo.TrustedAuthorsOnly), repo.LocalPath, boolToInt(repo.SocketEnabled), boolToInt(repo.QueueEnabled), string(allowedJSON), string(issueTypesJSON), boolToInt(repo.EpicRollup), string(repo.NextStrategy), string(repo.SyncDirection), boolToInt(repo.IngestHumanComments), repo.TrackingLabel(), string(trustedAuthorsJSON), string(assigneeLoginsJSON), repo.WIPLimit, boolToInt(repo.ValidateAssignee), string(issueTemplatesJSON), repo.KeyPrefix, repo.ID,
+	}, nil
 }
 
 // ---------------------------------------------------------------------------
@@ -228,6 +407,59 @@ func (s *SQLiteStore) UpdateRepo(ctx context.Context, repo *model.RepoConfig) er
 // ---------------------------------------------------------------------------
 
 func (s *SQLiteStore) CreateIssue(ctx context.Context, issue *model.Issue) (*model.Issue, error) {
+	var id int
+	err := s.writeTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		id, err = insertIssue(ctx, tx, issue)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetIssue(ctx, id)
+}
+
+// CreateIssuesWithEvents stores each change's issue and then its create
+// event, with the event's IssueID set to the new issue, in one transaction.
+// It returns the created issues in the order given.
+func (s *SQLiteStore) CreateIssuesWithEvents(ctx context.Context, changes []IssueChange) ([]*model.Issue, error) {
+	ids := make([]int, len(changes))
+	err := s.writeTx(ctx, func(tx *sql.Tx) error {
+		for i, c := range changes {
+			id, err := insertIssue(ctx, tx, c.Issue)
+			if err != nil {
+				return fmt.Errorf("create issue %q: %w", c.Issue.Title, err)
+			}
+			ids[i] = id
+
+			c.Event.IssueID = id
+			eventID, err := s.insertNewEvent(ctx, tx, c.Event)
+			if err != nil {
+				return fmt.Errorf("append event for issue %d: %w", id, err)
+			}
+			c.Event.ID = eventID
+			if err := notifyWatchers(ctx, tx, c.Event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created := make([]*model.Issue, len(ids))
+	for i, id := range ids {
+		if created[i], err = s.GetIssue(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+// insertIssue fills in issue's defaults and inserts it with its blockers
+// and watchers, returning the new ID.
+func insertIssue(ctx context.Context, tx *sql.Tx, issue *model.Issue) (int, error) {
 	now := time.Now().UTC()
 	if issue.CreatedAt.IsZero() {
 		issue.CreatedAt = now
@@ -241,20 +473,18 @@ func (s *SQLiteStore) CreateIssue(ctx context.Context, issue *model.Issue) (*mod
 	if issue.IssueType == "" {
 		issue.IssueType = model.IssueTypeTask
 	}
-	if issue.Labels == nil {
-		issue.Labels = []string{}
-	}
+	engine.NormalizeIssue(issue)
 	if issue.Comments == nil {
 		issue.Comments = []model.Comment{}
 	}
 
 	labelsJSON, err := json.Marshal(issue.Labels)
 	if err != nil {
-		return nil, fmt.Errorf("marshal labels: %w", err)
+		return 0, fmt.Errorf("marshal labels: %w", err)
 	}
 	commentsJSON, err := json.Marshal(issue.Comments)
 	if err != nil {
-		return nil, fmt.Errorf("marshal comments: %w", err)
+		return 0, fmt.Errorf("marshal comments: %w", err)
 	}
 
 	var githubID *int
@@ -267,87 +497,266 @@ func (s *SQLiteStore) CreateIssue(ctx context.Context, issue *model.Issue) (*mod
 		closedAt = &t
 	}
 
-	res, err := s.db.ExecContext(ctx,
-		`INSERT INTO issues (repo_id, github_id, title, status, priority, issue_type, description, owner, labels, created_at, updated_at, closed_at, comments)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO issues (repo_id, github_id, title, status, priority, issue_type, description, owner, labels, created_at, updated_at, closed_at, comments, snoozed_until, estimate, parent_id)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		issue.RepoID, githubID, issue.Title, string(issue.Status), issue.Priority,
 		string(issue.IssueType), issue.Description, issue.Owner,
 		string(labelsJSON),
 		issue.CreatedAt.Format(time.RFC3339), issue.UpdatedAt.Format(time.RFC3339),
-		closedAt, string(commentsJSON))
+		closedAt, string(commentsJSON), formatSnoozedUntil(issue.SnoozedUntil), issue.Estimate, issue.ParentID)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	id, _ := res.LastInsertId()
-	return s.GetIssue(ctx, int(id))
+	if err := saveDependencies(ctx, tx, int(id), issue.BlockedBy); err != nil {
+		return 0, err
+	}
+	if err := saveWatchers(ctx, tx, int(id), issue.Watchers); err != nil {
+		return 0, err
+	}
+	return int(id), nil
 }
 
+// issueColumns is the column list scanned by scanIssue, in order. The
+// issue's blockers and watchers come from issue_dependencies and
+// issue_watchers as JSON arrays.
+const issueColumns = `id, repo_id, github_id, title, status, priority, issue_type, description, owner, labels, created_at, updated_at, closed_at, comments, snoozed_until, estimate, parent_id,
+	(SELECT json_group_array(blocker_id) FROM issue_dependencies WHERE issue_id = issues.id),
+	(SELECT json_group_array(watcher) FROM issue_watchers WHERE issue_id = issues.id)`
+
 func (s *SQLiteStore) GetIssue(ctx context.Context, id int) (*model.Issue, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, repo_id, github_id, title, status, priority, issue_type, description, owner, labels, created_at, updated_at, closed_at, comments
+		`SELECT `+issueColumns+`
 		 FROM issues WHERE id = ?`, id)
 	return scanIssue(row)
 }
 
 func (s *SQLiteStore) ListIssues(ctx context.Context, filter IssueFilter) ([]*model.Issue, error) {
-	query := `SELECT id, repo_id, github_id, title, status, priority, issue_type, description, owner, labels, created_at, updated_at, closed_at, comments FROM issues WHERE 1=1`
+	orderBy, err := issueOrderBy(filter)
+	if err != nil {
+		return nil, err
+	}
+	where, args := issueFilterWhere(filter)
+	query := `SELECT ` + issueColumns + ` FROM issues WHERE ` + where + " ORDER BY " + orderBy
+	if filter.Limit > 0 || filter.Offset > 0 {
+		limit := filter.Limit
+		if limit <= 0 {
+			limit = -1 // SQLite: no limit
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, filter.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []*model.Issue
+	for rows.Next() {
+		iss, err := scanIssue(rows)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, iss)
+	}
+	return issues, rows.Err()
+}
+
+func (s *SQLiteStore) CountIssues(ctx context.Context, filter IssueFilter) (int, error) {
+	where, args := issueFilterWhere(filter)
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM issues WHERE `+where, args...).Scan(&n)
+	return n, err
+}
+
+func (s *SQLiteStore) FillEventStats(ctx context.Context, issues []*model.Issue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	byID := make(map[int]*model.Issue, len(issues))
+	ids := make([]int, 0, len(issues))
+	for _, iss := range issues {
+		iss.EventCount, iss.LastEventAt, iss.Version = 0, nil, 0
+		byID[iss.ID] = iss
+		ids = append(ids, iss.ID)
+	}
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT issue_id, COUNT(*), MAX(timestamp), `+versionColumn+` FROM events
+		 WHERE issue_id IN (SELECT value FROM json_each(?))
+		 GROUP BY issue_id`, string(idsJSON))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var issueID, count, version int
+		var last string
+		if err := rows.Scan(&issueID, &count, &last, &version); err != nil {
+			return err
+		}
+		iss := byID[issueID]
+		if iss == nil {
+			continue
+		}
+		iss.EventCount = count
+		iss.Version = version
+		if t, err := time.Parse(time.RFC3339, last); err == nil {
+			iss.LastEventAt = &t
+		}
+	}
+	return rows.Err()
+}
+
+// issueOrderBy returns the ORDER BY clause for filter's sort. Only known
+// keys are accepted, so the clause is never built from caller input. id
+// breaks ties, which keeps pages stable.
+func issueOrderBy(filter IssueFilter) (string, error) {
+	dir := "ASC"
+	if filter.Desc {
+		dir = "DESC"
+	}
+	switch filter.Sort {
+	case "", SortPriority:
+		return "priority " + dir + ", created_at ASC, id ASC", nil
+	case SortCreated:
+		return "created_at " + dir + ", id " + dir, nil
+	case SortUpdated:
+		return "updated_at " + dir + ", id " + dir, nil
+	}
+	return "", fmt.Errorf("unknown sort %q: use priority, created or updated", filter.Sort)
+}
+
+// issueFilterWhere returns the WHERE clause and arguments selecting the
+// issues that match filter. Limit and Offset are left to the caller.
+func issueFilterWhere(filter IssueFilter) (string, []interface{}) {
+	where := "1=1"
 	var args []interface{}
 
 	if filter.RepoID != 0 {
-		query += " AND repo_id = ?"
+		where += " AND repo_id = ?"
 		args = append(args, filter.RepoID)
 	}
 	if filter.Status != "" {
-		query += " AND status = ?"
+		where += " AND status = ?"
 		args = append(args, string(filter.Status))
 	}
+	if len(filter.Statuses) > 0 {
+		statuses, _ := json.Marshal(filter.Statuses)
+		where += " AND status IN (SELECT value FROM json_each(?))"
+		args = append(args, string(statuses))
+	}
 	if filter.Priority != nil {
-		query += " AND priority = ?"
+		where += " AND priority = ?"
 		args = append(args, *filter.Priority)
 	}
 	if filter.Type != "" {
-		query += " AND issue_type = ?"
+		where += " AND issue_type = ?"
 		args = append(args, string(filter.Type))
 	}
 	if filter.Owner != "" {
-		query += " AND owner = ?"
-		args = append(args, filter.Owner)
+		where += " AND owner = ? COLLATE NOCASE"
+		args = append(args, strings.TrimSpace(filter.Owner))
 	}
+	if filter.ParentID != 0 {
+		where += " AND parent_id = ?"
+		args = append(args, filter.ParentID)
+	}
+	for _, label := range filter.Labels {
+		if label = strings.TrimSpace(label); label == "" {
+			continue
+		}
+		where += " AND EXISTS (SELECT 1 FROM json_each(issues.labels) WHERE json_each.value = ? COLLATE NOCASE)"
+		args = append(args, label)
+	}
+	if filter.ExcludeSnoozed {
+		where += " AND (snoozed_until IS NULL OR snoozed_until <= ?)"
+		args = append(args, time.Now().UTC().Format(time.RFC3339))
+	}
+	if filter.ExcludeClosed {
+		where += " AND status NOT IN ('closed', 'deleted')"
+	}
+	if !filter.UpdatedSince.IsZero() {
+		where += " AND updated_at >= ?"
+		args = append(args, filter.UpdatedSince.UTC().Format(time.RFC3339))
+	}
+	return where, args
+}
 
-	query += " ORDER BY priority ASC, created_at ASC"
-
-	rows, err := s.db.QueryContext(ctx, query, args...)
+func (s *SQLiteStore) UpdateIssue(ctx context.Context, issue *model.Issue) error {
+	args, err := updateIssueArgs(issue)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	return s.writeTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, updateIssueSQL, args...); err != nil {
+			return err
+		}
+		if err := saveDependencies(ctx, tx, issue.ID, issue.BlockedBy); err != nil {
+			return err
+		}
+		return saveWatchers(ctx, tx, issue.ID, issue.Watchers)
+	})
+}
 
-	var issues []*model.Issue
-	for rows.Next() {
-		iss, err := scanIssue(rows)
-		if err != nil {
-			return nil, err
+// saveDependencies replaces the issue's rows in issue_dependencies with
+// blockedBy.
+func saveDependencies(ctx context.Context, tx *sql.Tx, issueID int, blockedBy []int) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM issue_dependencies WHERE issue_id = ?`, issueID); err != nil {
+		return fmt.Errorf("clear dependencies of issue %d: %w", issueID, err)
+	}
+	for _, blocker := range blockedBy {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO issue_dependencies (issue_id, blocker_id) VALUES (?, ?)`,
+			issueID, blocker); err != nil {
+			return fmt.Errorf("save dependency of issue %d: %w", issueID, err)
 		}
-		issues = append(issues, iss)
 	}
-	return issues, rows.Err()
+	return nil
 }
 
-func (s *SQLiteStore) UpdateIssue(ctx context.Context, issue *model.Issue) error {
-	issue.UpdatedAt = time.Now().UTC()
-	if issue.Labels == nil {
-		issue.Labels = []string{}
+// saveWatchers replaces the issue's rows in issue_watchers with watchers.
+func saveWatchers(ctx context.Context, tx *sql.Tx, issueID int, watchers []string) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM issue_watchers WHERE issue_id = ?`, issueID); err != nil {
+		return fmt.Errorf("clear watchers of issue %d: %w", issueID, err)
+	}
+	for _, w := range watchers {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO issue_watchers (issue_id, watcher) VALUES (?, ?)`,
+			issueID, w); err != nil {
+			return fmt.Errorf("save watcher of issue %d: %w", issueID, err)
+		}
 	}
+	return nil
+}
+
+const updateIssueSQL = `UPDATE issues SET repo_id=?, github_id=?, title=?, status=?, priority=?, issue_type=?, description=?, owner=?, labels=?, updated_at=?, closed_at=?, comments=?, snoozed_until=?, estimate=?, parent_id=?
+		 WHERE id=?`
+
+// updateIssueArgs stamps issue.UpdatedAt and returns the arguments for
+// updateIssueSQL.
+func updateIssueArgs(issue *model.Issue) ([]interface{}, error) {
+	issue.UpdatedAt = time.Now().UTC()
+	engine.NormalizeIssue(issue)
 	if issue.Comments == nil {
 		issue.Comments = []model.Comment{}
 	}
 	labelsJSON, err := json.Marshal(issue.Labels)
 	if err != nil {
-		return fmt.Errorf("marshal labels: %w", err)
+		return nil, fmt.Errorf("marshal labels: %w", err)
 	}
 	commentsJSON, err := json.Marshal(issue.Comments)
 	if err != nil {
-		return fmt.Errorf("marshal comments: %w", err)
+		return nil, fmt.Errorf("marshal comments: %w", err)
 	}
 	var closedAt *string
 	if issue.ClosedAt != nil {
@@ -359,40 +768,589 @@ func (s *SQLiteStore) UpdateIssue(ctx context.Context, issue *model.Issue) error
 		githubID = issue.GitHubID
 	}
 
-	_, err = s.db.ExecContext(ctx,
-		`UPDATE issues SET repo_id=?, github_id=?, title=?, status=?, priority=?, issue_type=?, description=?, owner=?, labels=?, updated_at=?, closed_at=?, comments=?
-		 WHERE id=?`,
+	return []interface{}{
 		issue.RepoID, githubID, issue.Title, string(issue.Status), issue.Priority,
 		string(issue.IssueType), issue.Description, issue.Owner,
 		string(labelsJSON),
 		issue.UpdatedAt.Format(time.RFC3339), closedAt,
-		string(commentsJSON),
-		issue.ID)
-	return err
+		string(commentsJSON), formatSnoozedUntil(issue.SnoozedUntil), issue.Estimate,
+		issue.ParentID, issue.ID,
+	}, nil
+}
+
+// UpdateIssuesWithEvents appends each change's event and saves its issue in a
+// single transaction, so either every change lands or none does.
+func (s *SQLiteStore) UpdateIssuesWithEvents(ctx context.Context, changes []IssueChange) error {
+	return s.writeTx(ctx, func(tx *sql.Tx) error {
+		for _, c := range changes {
+			if err := s.writeChange(ctx, tx, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeChange appends c's event and saves its issue within tx.
+func (s *SQLiteStore) writeChange(ctx context.Context, tx *sql.Tx, c IssueChange) error {
+	id, err := s.insertNewEvent(ctx, tx, c.Event)
+	if err != nil {
+		return fmt.Errorf("append event for issue %d: %w", c.Issue.ID, err)
+	}
+	c.Event.ID = id
+	if err := notifyWatchers(ctx, tx, c.Event); err != nil {
+		return err
+	}
+
+	args, err := updateIssueArgs(c.Issue)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, updateIssueSQL, args...); err != nil {
+		return fmt.Errorf("update issue %d: %w", c.Issue.ID, err)
+	}
+	if err := saveDependencies(ctx, tx, c.Issue.ID, c.Issue.BlockedBy); err != nil {
+		return err
+	}
+	return saveWatchers(ctx, tx, c.Issue.ID, c.Issue.Watchers)
+}
+
+// RebuildIssue overwrites issue's row in one transaction, unless an event
+// newer than lastEventID has been appended for it since it was replayed.
+func (s *SQLiteStore) RebuildIssue(ctx context.Context, issue *model.Issue, lastEventID int) (bool, error) {
+	rebuilt := false
+	err := s.writeTx(ctx, func(tx *sql.Tx) error {
+		var latest sql.NullInt64
+		if err := tx.QueryRowContext(ctx,
+			`SELECT MAX(id) FROM events WHERE issue_id = ?`, issue.ID).Scan(&latest); err != nil {
+			return err
+		}
+		if int(latest.Int64) != lastEventID {
+			return nil
+		}
+		args, err := updateIssueArgs(issue)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, updateIssueSQL, args...); err != nil {
+			return fmt.Errorf("update issue %d: %w", issue.ID, err)
+		}
+		if err := saveDependencies(ctx, tx, issue.ID, issue.BlockedBy); err != nil {
+			return err
+		}
+		if err := saveWatchers(ctx, tx, issue.ID, issue.Watchers); err != nil {
+			return err
+		}
+		rebuilt = true
+		return nil
+	})
+	return rebuilt, err
 }
 
 func (s *SQLiteStore) DeleteIssue(ctx context.Context, id int) error {
-	_, err := s.db.ExecContext(ctx,
+	_, err := s.execWrite(ctx,
 		`UPDATE issues SET status = ?, updated_at = ? WHERE id = ?`,
 		string(model.StatusDeleted), time.Now().UTC().Format(time.RFC3339), id)
 	return err
 }
 
+// nextIssueWhere selects issues eligible to be picked up next: open,
+// unassigned, not snoozed, and not waiting on an unfinished blocker. Its
+// arguments are repo ID and the current time.
+const nextIssueWhere = `repo_id = ? AND status = 'open' AND owner = ''
+		   AND (snoozed_until IS NULL OR snoozed_until <= ?)
+		   AND NOT ` + hasOpenBlocker
+
+// hasOpenBlocker matches issues with a blocker that is neither closed nor
+// deleted. A blocker whose row is missing does not block.
+const hasOpenBlocker = `EXISTS (SELECT 1 FROM issue_dependencies d
+		   JOIN issues b ON b.id = d.blocker_id
+		   WHERE d.issue_id = issues.id AND b.status NOT IN ('closed', 'deleted'))`
+
+// nextIssueOrder returns the pick order for eligible issues under the repo's
+// next_strategy. An unknown repo gets the default order.
+func (s *SQLiteStore) nextIssueOrder(ctx context.Context, repoID int) (string, error) {
+	var strategy string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT next_strategy FROM repos WHERE id = ?`, repoID).Scan(&strategy)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	switch model.NextStrategy(strategy) {
+	case model.NextStrategyFIFO:
+		return `ORDER BY created_at ASC, priority ASC`, nil
+	case model.NextStrategyWeighted:
+		return `ORDER BY (priority + 1) / (julianday('now') - julianday(created_at) + 1) ASC, created_at ASC`, nil
+	default:
+		return `ORDER BY priority ASC, created_at ASC`, nil
+	}
+}
+
 func (s *SQLiteStore) NextIssue(ctx context.Context, repoID int) (*model.Issue, error) {
+	issues, err := s.NextIssues(ctx, repoID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return issues[0], nil
+}
+
+func (s *SQLiteStore) NextIssues(ctx context.Context, repoID, n int) ([]*model.Issue, error) {
+	order, err := s.nextIssueOrder(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+issueColumns+`
+		 FROM issues
+		 WHERE `+nextIssueWhere+`
+		 `+order+`
+		 LIMIT ?`, repoID, time.Now().UTC().Format(time.RFC3339), n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []*model.Issue
+	for rows.Next() {
+		iss, err := scanIssue(rows)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, iss)
+	}
+	return issues, rows.Err()
+}
+
+// WIPLimitError is returned by ClaimNextIssue when the claiming owner
+// already holds the repo's wip_limit of open and in_progress issues.
+type WIPLimitError struct {
+	Owner string
+	Held  int
+	Limit int
+}
+
+func (e *WIPLimitError) Error() string {
+	return fmt.Sprintf("%s already has %d open or in_progress issues, the repo's wip_limit of %d", e.Owner, e.Held, e.Limit)
+}
+
+// ClaimNextIssue picks the issue NextIssue would return and, in the same
+// transaction, stores the change claim makes to it. The owner's open and
+// in_progress issues are counted against the repo's wip_limit in that
+// transaction too, so concurrent claims cannot together exceed it. claim may
+// run more than once if the transaction is retried.
+func (s *SQLiteStore) ClaimNextIssue(ctx context.Context, repoID int, owner string, claim func(*model.Issue) (IssueChange, error)) (*model.Issue, error) {
+	s.claimMu.Lock()
+	defer s.claimMu.Unlock()
+
+	order, err := s.nextIssueOrder(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+	var claimedID int
+	err = s.writeTx(ctx, func(tx *sql.Tx) error {
+		var limit int
+		if err := tx.QueryRowContext(ctx,
+			`SELECT wip_limit FROM repos WHERE id = ?`, repoID).Scan(&limit); err != nil {
+			return fmt.Errorf("get wip_limit of repo %d: %w", repoID, err)
+		}
+		if limit > 0 {
+			var held int
+			if err := tx.QueryRowContext(ctx,
+				`SELECT COUNT(*) FROM issues
+				 WHERE repo_id = ? AND owner = ? COLLATE NOCASE AND status IN ('open', 'in_progress')`,
+				repoID, owner).Scan(&held); err != nil {
+				return fmt.Errorf("count issues of %s: %w", owner, err)
+			}
+			if held >= limit {
+				return &WIPLimitError{Owner: owner, Held: held, Limit: limit}
+			}
+		}
+
+		issue, err := scanIssue(tx.QueryRowContext(ctx,
+			`SELECT `+issueColumns+`
+			 FROM issues
+			 WHERE `+nextIssueWhere+`
+			 `+order+`
+			 LIMIT 1`, repoID, time.Now().UTC().Format(time.RFC3339)))
+		if err != nil {
+			return err
+		}
+		c, err := claim(issue)
+		if err != nil {
+			return err
+		}
+		claimedID = c.Issue.ID
+		return s.writeChange(ctx, tx, c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetIssue(ctx, claimedID)
+}
+
+// NextIssueForOwner returns the issue an owner should resume: their
+// highest-priority in-progress issue, or failing that their highest-priority
+// open or blocked one. Snoozed issues are skipped. Returns sql.ErrNoRows if
+// the owner has nothing unfinished.
+func (s *SQLiteStore) NextIssueForOwner(ctx context.Context, repoID int, owner string) (*model.Issue, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+issueColumns+`
+		 FROM issues
+		 WHERE repo_id = ? AND owner = ? COLLATE NOCASE AND status IN ('in_progress', 'open', 'blocked')
+		   AND (snoozed_until IS NULL OR snoozed_until <= ?)
+		 ORDER BY CASE status WHEN 'in_progress' THEN 0 ELSE 1 END, priority ASC, created_at ASC
+		 LIMIT 1`, repoID, owner, time.Now().UTC().Format(time.RFC3339))
+	return scanIssue(row)
+}
+
+// NextIssueWithinBudget is like NextIssue but skips issues whose estimate
+// exceeds budget. Unestimated issues (estimate 0) always fit.
+func (s *SQLiteStore) NextIssueWithinBudget(ctx context.Context, repoID, budget int) (*model.Issue, error) {
+	order, err := s.nextIssueOrder(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, repo_id, github_id, title, status, priority, issue_type, description, owner, labels, created_at, updated_at, closed_at, comments
+		`SELECT `+issueColumns+`
 		 FROM issues
-		 WHERE repo_id = ? AND status = 'open' AND owner = ''
-		 ORDER BY priority ASC, created_at ASC
-		 LIMIT 1`, repoID)
+		 WHERE `+nextIssueWhere+` AND estimate <= ?
+		 `+order+`
+		 LIMIT 1`, repoID, time.Now().UTC().Format(time.RFC3339), budget)
 	return scanIssue(row)
 }
 
+// NextIssueExclusions counts the open and blocked issues in a repo by the
+// first reason NextIssue (or NextIssueWithinBudget, when budget >= 0) would
+// skip them. An open issue waiting on an unfinished blocker counts as
+// blocked.
+func (s *SQLiteStore) NextIssueExclusions(ctx context.Context, repoID, budget int) (*NextExclusions, error) {
+	var ex NextExclusions
+	now := time.Now().UTC().Format(time.RFC3339)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*),
+		        COALESCE(SUM(CASE WHEN status = 'blocked' OR `+hasOpenBlocker+` THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN status = 'open' AND NOT `+hasOpenBlocker+`
+		                           AND owner != '' THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN status = 'open' AND NOT `+hasOpenBlocker+` AND owner = ''
+		                           AND snoozed_until IS NOT NULL AND snoozed_until > ? THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN status = 'open' AND NOT `+hasOpenBlocker+` AND owner = ''
+		                           AND (snoozed_until IS NULL OR snoozed_until <= ?)
+		                           AND ? >= 0 AND estimate > ? THEN 1 ELSE 0 END), 0)
+		 FROM issues
+		 WHERE repo_id = ? AND status IN ('open', 'blocked')`,
+		now, now, budget, budget, repoID).
+		Scan(&ex.Candidates, &ex.Blocked, &ex.Assigned, &ex.Snoozed, &ex.OverBudget)
+	if err != nil {
+		return nil, err
+	}
+	return &ex, nil
+}
+
+// PlanIssues greedily selects eligible issues in next-issue order whose
+// estimates sum to at most budget. An issue that does not fit the remaining
+// budget is skipped rather than ending the plan, so smaller lower-priority
+// issues can still fill the gap.
+func (s *SQLiteStore) PlanIssues(ctx context.Context, repoID, budget int) ([]*model.Issue, error) {
+	order, err := s.nextIssueOrder(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+issueColumns+`
+		 FROM issues
+		 WHERE `+nextIssueWhere+` AND estimate <= ?
+		 `+order, repoID, time.Now().UTC().Format(time.RFC3339), budget)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	remaining := budget
+	var plan []*model.Issue
+	for rows.Next() {
+		iss, err := scanIssue(rows)
+		if err != nil {
+			return nil, err
+		}
+		if iss.Estimate > remaining {
+			continue
+		}
+		remaining -= iss.Estimate
+		plan = append(plan, iss)
+	}
+	return plan, rows.Err()
+}
+
+// IssuesUpdatedSince returns every issue in the repo, deleted and closed
+// ones included, whose updated_at is at or after since, oldest change first.
+// updated_at has one-second resolution, so the bound is inclusive: a caller
+// polling with its last-seen timestamp may see an issue twice but never
+// misses one.
+func (s *SQLiteStore) IssuesUpdatedSince(ctx context.Context, repoID int, since time.Time) ([]*model.Issue, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+issueColumns+`
+		 FROM issues
+		 WHERE repo_id = ? AND updated_at >= ?
+		 ORDER BY updated_at ASC, id ASC`,
+		repoID, since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []*model.Issue
+	for rows.Next() {
+		iss, err := scanIssue(rows)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, iss)
+	}
+	return issues, rows.Err()
+}
+
+// StaleIssues returns the repo's open and blocked issues whose updated_at is
+// more than olderThan ago, least recently updated first.
+func (s *SQLiteStore) StaleIssues(ctx context.Context, repoID int, olderThan time.Duration) ([]*model.Issue, error) {
+	before := time.Now().UTC().Add(-olderThan)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+issueColumns+`
+		 FROM issues
+		 WHERE repo_id = ? AND status IN (?, ?) AND updated_at < ?
+		 ORDER BY updated_at ASC, id ASC`,
+		repoID, string(model.StatusOpen), string(model.StatusBlocked), before.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []*model.Issue
+	for rows.Next() {
+		iss, err := scanIssue(rows)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, iss)
+	}
+	return issues, rows.Err()
+}
+
+// TrendingIssues counts each unclosed issue's events since the given time
+// and ranks issues by the sum of events, comments and distinct agents. Ties
+// go to the higher priority. Issues with no recent events are omitted.
+func (s *SQLiteStore) TrendingIssues(ctx context.Context, repoID int, since time.Time, limit int) ([]*IssueActivity, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+issueColumns+`, a.n_events, a.n_comments, a.n_agents
+		 FROM issues
+		 JOIN (SELECT issue_id,
+		              COUNT(*) AS n_events,
+		              SUM(CASE WHEN COALESCE(json_extract(payload, '$.comment'), '') != '' THEN 1 ELSE 0 END) AS n_comments,
+		              COUNT(DISTINCT NULLIF(agent, '')) AS n_agents
+		       FROM events
+		       WHERE repo_id = ? AND timestamp >= ?
+		       GROUP BY issue_id) a ON a.issue_id = issues.id
+		 WHERE status NOT IN ('closed', 'deleted')
+		 ORDER BY a.n_events + a.n_comments + a.n_agents DESC, priority ASC, id ASC
+		 LIMIT ?`,
+		repoID, since.UTC().Format(time.RFC3339), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trending []*IssueActivity
+	for rows.Next() {
+		var a IssueActivity
+		iss, err := scanIssue(extraScanner{rows, []interface{}{&a.Events, &a.Comments, &a.Agents}})
+		if err != nil {
+			return nil, err
+		}
+		a.Issue = iss
+		a.Score = a.Events + a.Comments + a.Agents
+		trending = append(trending, &a)
+	}
+	return trending, rows.Err()
+}
+
+// IssueStats aggregates the repo's issues in SQL. An empty issue type
+// counts as a task, CreateIssue's default.
+func (s *SQLiteStore) IssueStats(ctx context.Context, repoID int) (*IssueStats, error) {
+	stats := &IssueStats{
+		ByStatus: make(map[model.Status]int),
+		ByType:   make(map[model.IssueType]int),
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT status, COALESCE(NULLIF(issue_type, ''), 'task'), COUNT(*)
+		 FROM issues WHERE repo_id = ?
+		 GROUP BY 1, 2`, repoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status model.Status
+		var issueType model.IssueType
+		var n int
+		if err := rows.Scan(&status, &issueType, &n); err != nil {
+			return nil, err
+		}
+		stats.ByStatus[status] += n
+		switch status {
+		case model.StatusDeleted:
+			continue
+		case model.StatusClosed:
+			stats.Closed += n
+		default:
+			stats.Open += n
+		}
+		stats.ByType[issueType] += n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM events WHERE repo_id = ? AND synced = 0`,
+		repoID).Scan(&stats.PendingEvents); err != nil {
+		return nil, err
+	}
+
+	var createdAt string
+	err = s.db.QueryRowContext(ctx,
+		`SELECT id, created_at FROM issues
+		 WHERE repo_id = ? AND status NOT IN ('closed', 'deleted')
+		 ORDER BY created_at ASC, id ASC LIMIT 1`,
+		repoID).Scan(&stats.OldestOpenID, &createdAt)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, err
+	default:
+		t, _ := time.Parse(time.RFC3339, createdAt)
+		stats.OldestOpenAt = &t
+	}
+	return stats, nil
+}
+
 // ---------------------------------------------------------------------------
 // Events
 // ---------------------------------------------------------------------------
 
 func (s *SQLiteStore) AppendEvent(ctx context.Context, event *model.Event) (*model.Event, error) {
+	return s.appendEvent(ctx, event, nil)
+}
+
+// ErrVersionConflict is returned by AppendEventAtVersion when the issue has
+// changed since the version the caller read.
+var ErrVersionConflict = errors.New("issue has changed since the given version")
+
+// versionColumn computes an issue's version over its events: the ID of the
+// newest one that is not a refused update, which changes nothing.
+const versionColumn = `COALESCE(MAX(CASE WHEN action != '` + string(model.ActionUpdateRejected) + `' THEN id END), 0)`
+
+// AppendEventAtVersion appends event as AppendEvent does, but only if the
+// newest event of its issue is still the one with ID version. The check and
+// the insert share a transaction, so of two writers holding the same
+// version only the first succeeds.
+func (s *SQLiteStore) AppendEventAtVersion(ctx context.Context, event *model.Event, version int) (*model.Event, error) {
+	return s.appendEvent(ctx, event, &version)
+}
+
+func (s *SQLiteStore) appendEvent(ctx context.Context, event *model.Event, version *int) (*model.Event, error) {
+	var id int64
+	err := s.writeTx(ctx, func(tx *sql.Tx) error {
+		if version != nil {
+			var current int
+			if err := tx.QueryRowContext(ctx,
+				`SELECT `+versionColumn+` FROM events WHERE issue_id = ?`, event.IssueID).Scan(&current); err != nil {
+				return err
+			}
+			if current != *version {
+				return ErrVersionConflict
+			}
+		}
+		eventID, err := s.insertNewEvent(ctx, tx, event)
+		if err != nil {
+			return err
+		}
+		id = int64(eventID)
+		ev := *event
+		ev.ID = eventID
+		return notifyWatchers(ctx, tx, &ev)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getEvent(ctx, int(id))
+}
+
+// notifyWatchers records a notification of event for each watcher of its
+// issue, other than the agent that made the change. Watching, unwatching
+// and refused updates notify no one.
+func notifyWatchers(ctx context.Context, tx *sql.Tx, event *model.Event) error {
+	switch event.Action {
+	case model.ActionWatch, model.ActionUnwatch, model.ActionUpdateRejected:
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO notifications (repo_id, watcher, issue_id, event_id, created_at)
+		 SELECT ?, watcher, issue_id, ?, ? FROM issue_watchers
+		 WHERE issue_id = ? AND watcher != ?`,
+		event.RepoID, event.ID, event.Timestamp.UTC().Format(time.RFC3339), event.IssueID, event.Agent); err != nil {
+		return fmt.Errorf("notify watchers of issue %d: %w", event.IssueID, err)
+	}
+	return nil
+}
+
+// ListNotifications returns the watcher's notifications in the repo, newest
+// first, with the action of the event each records. A limit of 0 returns
+// all of them.
+func (s *SQLiteStore) ListNotifications(ctx context.Context, repoID int, watcher string, limit int) ([]*Notification, error) {
+	query := `SELECT n.id, n.repo_id, n.watcher, n.issue_id, n.event_id, e.action, n.created_at
+		 FROM notifications n JOIN events e ON e.id = n.event_id
+		 WHERE n.repo_id = ? AND n.watcher = ?
+		 ORDER BY n.id DESC`
+	args := []interface{}{repoID, watcher}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		var n Notification
+		var createdAt string
+		if err := rows.Scan(&n.ID, &n.RepoID, &n.Watcher, &n.IssueID, &n.EventID, &n.Action, &createdAt); err != nil {
+			return nil, err
+		}
+		n.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		notifications = append(notifications, &n)
+	}
+	return notifications, rows.Err()
+}
+
+// eventColumns are the events columns scanEvent reads, in order.
+const eventColumns = `id, repo_id, github_comment_id, issue_id, github_issue_number, timestamp, action, payload, agent, synced, idempotency_key`
+
+// insertEventSQL inserts nothing when the issue already has an event with
+// the same idempotency key; RowsAffected tells the caller which happened.
+const insertEventSQL = `INSERT INTO events (repo_id, github_comment_id, issue_id, github_issue_number, timestamp, action, payload, agent, synced, idempotency_key)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (issue_id, idempotency_key) DO NOTHING`
+
+// insertEventArgs defaults event.Timestamp to now and returns the arguments
+// for insertEventSQL. An event without an idempotency key is stored with
+// none.
+func (s *SQLiteStore) insertEventArgs(event *model.Event) []interface{} {
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now().UTC()
 	}
@@ -406,29 +1364,69 @@ func (s *SQLiteStore) AppendEvent(ctx context.Context, event *model.Event) (*mod
 		githubIssueNumber = event.GitHubIssueNumber
 	}
 
-	res, err := s.db.ExecContext(ctx,
-		`INSERT INTO events (repo_id, github_comment_id, issue_id, github_issue_number, timestamp, action, payload, agent, synced)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	payload := event.Payload
+	if githubCommentID != nil {
+		payload = s.compactPayload(payload)
+	}
+	var idempotencyKey *string
+	if event.IdempotencyKey != "" {
+		idempotencyKey = &event.IdempotencyKey
+	}
+
+	return []interface{}{
 		event.RepoID, githubCommentID, event.IssueID, githubIssueNumber,
-		event.Timestamp.Format(time.RFC3339), string(event.Action), event.Payload,
-		event.Agent, event.Synced)
+		event.Timestamp.Format(time.RFC3339), string(event.Action), payload,
+		event.Agent, event.Synced, idempotencyKey,
+	}
+}
+
+// ErrDuplicateEvent is returned when an appended event's idempotency key is
+// already stored for its issue. Nothing is written: the change is already
+// recorded by the existing event.
+var ErrDuplicateEvent = errors.New("event already recorded")
+
+// insertNewEvent keys event and inserts it, reporting its ID. An event
+// without a key gets one from its GitHub comment, or a random one if it was
+// created locally. If the key is already stored for the issue, nothing is
+// inserted and ErrDuplicateEvent names the existing event.
+func (s *SQLiteStore) insertNewEvent(ctx context.Context, tx *sql.Tx, event *model.Event) (int, error) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	if event.IdempotencyKey == "" {
+		if event.GitHubCommentID != nil {
+			event.IdempotencyKey = model.CommentIdempotencyKey(*event.GitHubCommentID)
+		} else {
+			event.IdempotencyKey = model.NewIdempotencyKey()
+		}
+	}
+	res, err := tx.ExecContext(ctx, insertEventSQL, s.insertEventArgs(event)...)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var id int
+		if err := tx.QueryRowContext(ctx,
+			`SELECT id FROM events WHERE issue_id = ? AND idempotency_key = ?`,
+			event.IssueID, event.IdempotencyKey).Scan(&id); err != nil {
+			return 0, fmt.Errorf("find duplicate event: %w", err)
+		}
+		return 0, fmt.Errorf("%w as event %d of issue %d", ErrDuplicateEvent, id, event.IssueID)
 	}
 	id, _ := res.LastInsertId()
-	return s.getEvent(ctx, int(id))
+	return int(id), nil
 }
 
 func (s *SQLiteStore) getEvent(ctx context.Context, id int) (*model.Event, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, repo_id, github_comment_id, issue_id, github_issue_number, timestamp, action, payload, agent, synced
+		`SELECT `+eventColumns+`
 		 FROM events WHERE id = ?`, id)
 	return scanEvent(row)
 }
 
 func (s *SQLiteStore) ListEvents(ctx context.Context, repoID, issueID int) ([]*model.Event, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, repo_id, github_comment_id, issue_id, github_issue_number, timestamp, action, payload, agent, synced
+		`SELECT `+eventColumns+`
 		 FROM events WHERE repo_id = ? AND issue_id = ? ORDER BY id`,
 		repoID, issueID)
 	if err != nil {
@@ -449,7 +1447,7 @@ func (s *SQLiteStore) ListEvents(ctx context.Context, repoID, issueID int) ([]*m
 
 func (s *SQLiteStore) PendingEvents(ctx context.Context, repoID int) ([]*model.Event, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, repo_id, github_comment_id, issue_id, github_issue_number, timestamp, action, payload, agent, synced
+		`SELECT `+eventColumns+`
 		 FROM events WHERE repo_id = ? AND synced = 0 ORDER BY id`,
 		repoID)
 	if err != nil {
@@ -468,8 +1466,76 @@ func (s *SQLiteStore) PendingEvents(ctx context.Context, repoID int) ([]*model.E
 	return events, rows.Err()
 }
 
+// PendingEventsWithTitles joins each pending event to its issue's title so
+// listing the queue takes one query however many issues it spans.
+func (s *SQLiteStore) PendingEventsWithTitles(ctx context.Context, repoID int) ([]*PendingEvent, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+eventColumns+`, COALESCE(i.issue_title, '')
+		 FROM events
+		 LEFT JOIN (SELECT id AS issue_pk, title AS issue_title FROM issues) i ON i.issue_pk = events.issue_id
+		 WHERE repo_id = ? AND synced = 0 ORDER BY id`,
+		repoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []*PendingEvent
+	for rows.Next() {
+		var p PendingEvent
+		e, err := scanEvent(extraScanner{rows, []interface{}{&p.IssueTitle}})
+		if err != nil {
+			return nil, err
+		}
+		p.Event = e
+		pending = append(pending, &p)
+	}
+	return pending, rows.Err()
+}
+
+// EventsSince returns up to limit of the repo's events with an ID above
+// afterID, oldest first, so a client that remembers the last ID it saw can
+// pull only what is new. Event IDs only grow, so nothing is skipped.
+func (s *SQLiteStore) EventsSince(ctx context.Context, repoID, afterID, limit int) ([]*model.Event, error) {
+	query := `SELECT ` + eventColumns + `
+		 FROM events WHERE repo_id = ? AND id > ? ORDER BY id`
+	args := []interface{}{repoID, afterID}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
 func (s *SQLiteStore) MarkEventSynced(ctx context.Context, eventID int, githubCommentID int) error {
-	_, err := s.db.ExecContext(ctx,
+	if s.maxInlineComment > 0 {
+		// Now that GitHub holds the comment, an oversized one can be compacted.
+		ev, err := s.getEvent(ctx, eventID)
+		if err != nil {
+			return err
+		}
+		if compacted := s.compactPayload(ev.Payload); compacted != ev.Payload {
+			_, err := s.execWrite(ctx,
+				`UPDATE events SET synced = 1, github_comment_id = ?, payload = ? WHERE id = ?`,
+				githubCommentID, compacted, eventID)
+			return err
+		}
+	}
+	_, err := s.execWrite(ctx,
 		`UPDATE events SET synced = 1, github_comment_id = ? WHERE id = ?`,
 		githubCommentID, eventID)
 	return err
@@ -496,7 +1562,7 @@ func (s *SQLiteStore) GetIssueSyncState(ctx context.Context, repoID, githubIssue
 }
 
 func (s *SQLiteStore) SetIssueSyncState(ctx context.Context, repoID, githubIssueNumber, lastCommentID int, lastCommentAt string) error {
-	_, err := s.db.ExecContext(ctx,
+	_, err := s.execWrite(ctx,
 		`INSERT INTO issue_sync_state (repo_id, github_issue_number, last_comment_id, last_comment_at)
 		 VALUES (?, ?, ?, ?)
 		 ON CONFLICT(repo_id, github_issue_number)
@@ -505,6 +1571,55 @@ func (s *SQLiteStore) SetIssueSyncState(ctx context.Context, repoID, githubIssue
 	return err
 }
 
+func (s *SQLiteStore) GetCommentsETag(ctx context.Context, repoID, githubIssueNumber int) (string, error) {
+	var etag string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT comments_etag FROM issue_sync_state
+		 WHERE repo_id = ? AND github_issue_number = ?`,
+		repoID, githubIssueNumber).Scan(&etag)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return etag, err
+}
+
+// SetCommentsETag stores the comment listing ETag without touching the
+// last-seen comment, which only advances when new comments are processed.
+func (s *SQLiteStore) SetCommentsETag(ctx context.Context, repoID, githubIssueNumber int, etag string) error {
+	_, err := s.execWrite(ctx,
+		`INSERT INTO issue_sync_state (repo_id, github_issue_number, comments_etag)
+		 VALUES (?, ?, ?)
+		 ON CONFLICT(repo_id, github_issue_number)
+		 DO UPDATE SET comments_etag = excluded.comments_etag`,
+		repoID, githubIssueNumber, etag)
+	return err
+}
+
+func (s *SQLiteStore) PostedCommentID(ctx context.Context, repoID, githubIssueNumber int, bodyHash string, eventID int) (int, error) {
+	var commentID int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT github_comment_id FROM posted_comments
+		 WHERE repo_id = ? AND github_issue_number = ? AND body_hash = ? AND event_id = ?`,
+		repoID, githubIssueNumber, bodyHash, eventID).Scan(&commentID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return commentID, err
+}
+
+// RecordPostedComment notes that eventID was posted as githubCommentID. A
+// later event with an identical body replaces the row, so only the latest
+// poster of a given body is remembered.
+func (s *SQLiteStore) RecordPostedComment(ctx context.Context, repoID, githubIssueNumber int, bodyHash string, eventID, githubCommentID int) error {
+	_, err := s.execWrite(ctx,
+		`INSERT INTO posted_comments (repo_id, github_issue_number, body_hash, event_id, github_comment_id)
+		 VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(repo_id, github_issue_number, body_hash)
+		 DO UPDATE SET event_id = excluded.event_id, github_comment_id = excluded.github_comment_id`,
+		repoID, githubIssueNumber, bodyHash, eventID, githubCommentID)
+	return err
+}
+
 // ---------------------------------------------------------------------------
 // Helpers
 // ---------------------------------------------------------------------------
@@ -516,6 +1631,16 @@ func boolToInt(b bool) int {
 	return 0
 }
 
+// formatSnoozedUntil renders a snooze deadline in UTC RFC3339 so that the
+// string comparison used by the snooze filter orders correctly.
+func formatSnoozedUntil(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC().Format(time.RFC3339)
+	return &v
+}
+
 // ---------------------------------------------------------------------------
 // Scan helpers
 // ---------------------------------------------------------------------------
@@ -524,6 +1649,16 @@ type scanner interface {
 	Scan(dest ...interface{}) error
 }
 
+// extraScanner scans columns selected after a known column list into extra.
+type extraScanner struct {
+	scanner
+	extra []interface{}
+}
+
+func (e extraScanner) Scan(dest ...interface{}) error {
+	return e.scanner.Scan(append(dest, e.extra...)...)
+}
+
 func scanRepo(row scanner) (*model.RepoConfig, error) {
 	var r model.RepoConfig
 	var lastSync sql.NullString
@@ -531,13 +1666,49 @@ func scanRepo(row scanner) (*model.RepoConfig, error) {
 	var socketInt int
 	var queueInt int
 	var createdAt string
-	err := row.Scan(&r.ID, &r.Owner, &r.Name, &r.PollIntervalMs, &lastSync, &r.IssuesETag, &r.IssuesSince, &trustedInt, &r.LocalPath, &socketInt, &queueInt, &createdAt)
+	var allowedJSON string
+	var issueTypesJSON string
+	var epicRollupInt int
+	var ingestHumanInt int
+	var trustedAuthorsJSON string
+	var assigneeLoginsJSON string
+	var validateAssigneeInt int
+	var issueTemplatesJSON string
+	err := row.Scan(&r.ID, &r.Owner, &r.Name, &r.PollIntervalMs, &lastSync, &r.IssuesETag, &r.IssuesSince, &trustedInt, &r.LocalPath, &socketInt, &queueInt, &createdAt, &allowedJSON, &issueTypesJSON, &epicRollupInt, &r.NextStrategy, &r.SyncDirection, &ingestHumanInt, &r.Label, &trustedAuthorsJSON, &assigneeLoginsJSON, &r.WIPLimit, &validateAssigneeInt, &issueTemplatesJSON, &r.KeyPrefix)
 	if err != nil {
 		return nil, err
 	}
+	if allowedJSON != "" && allowedJSON != "[]" {
+		if err := json.Unmarshal([]byte(allowedJSON), &r.AllowedInboundActions); err != nil {
+			return nil, fmt.Errorf("unmarshal allowed_inbound_actions: %w", err)
+		}
+	}
+	if issueTypesJSON != "" && issueTypesJSON != "[]" {
+		if err := json.Unmarshal([]byte(issueTypesJSON), &r.IssueTypes); err != nil {
+			return nil, fmt.Errorf("unmarshal issue_types: %w", err)
+		}
+	}
+	if trustedAuthorsJSON != "" && trustedAuthorsJSON != "[]" {
+		if err := json.Unmarshal([]byte(trustedAuthorsJSON), &r.TrustedAuthors); err != nil {
+			return nil, fmt.Errorf("unmarshal trusted_authors: %w", err)
+		}
+	}
+	if assigneeLoginsJSON != "" && assigneeLoginsJSON != "{}" {
+		if err := json.Unmarshal([]byte(assigneeLoginsJSON), &r.AssigneeLogins); err != nil {
+			return nil, fmt.Errorf("unmarshal assignee_logins: %w", err)
+		}
+	}
+	if issueTemplatesJSON != "" && issueTemplatesJSON != "{}" {
+		if err := json.Unmarshal([]byte(issueTemplatesJSON), &r.IssueTemplates); err != nil {
+			return nil, fmt.Errorf("unmarshal issue_templates: %w", err)
+		}
+	}
 	r.TrustedAuthorsOnly = trustedInt != 0
 	r.SocketEnabled = socketInt != 0
 	r.QueueEnabled = queueInt != 0
+	r.EpicRollup = epicRollupInt != 0
+	r.IngestHumanComments = ingestHumanInt != 0
+	r.ValidateAssignee = validateAssigneeInt != 0
 	r.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
 	if r.CreatedAt.IsZero() {
 		// Fallback: the SQLite default uses datetime('now') which is "2006-01-02 15:04:05"
@@ -561,12 +1732,16 @@ func scanIssue(row scanner) (*model.Issue, error) {
 	var labelsJSON string
 	var commentsJSON string
 	var createdAt, updatedAt string
-	var closedAt sql.NullString
+	var closedAt, snoozedUntil sql.NullString
+	var parentID sql.NullInt64
+	var blockedByJSON string
+	var watchersJSON string
 
 	err := row.Scan(&iss.ID, &iss.RepoID, &githubID, &iss.Title,
 		&iss.Status, &iss.Priority, &iss.IssueType,
 		&iss.Description, &iss.Owner, &labelsJSON,
-		&createdAt, &updatedAt, &closedAt, &commentsJSON)
+		&createdAt, &updatedAt, &closedAt, &commentsJSON, &snoozedUntil, &iss.Estimate, &parentID,
+		&blockedByJSON, &watchersJSON)
 	if err != nil {
 		return nil, err
 	}
@@ -575,12 +1750,26 @@ func scanIssue(row scanner) (*model.Issue, error) {
 		v := int(githubID.Int64)
 		iss.GitHubID = &v
 	}
+	if parentID.Valid {
+		v := int(parentID.Int64)
+		iss.ParentID = &v
+	}
 	if err := json.Unmarshal([]byte(labelsJSON), &iss.Labels); err != nil {
 		iss.Labels = []string{}
 	}
 	if err := json.Unmarshal([]byte(commentsJSON), &iss.Comments); err != nil {
 		iss.Comments = []model.Comment{}
 	}
+	if err := json.Unmarshal([]byte(blockedByJSON), &iss.BlockedBy); err != nil || len(iss.BlockedBy) == 0 {
+		iss.BlockedBy = nil
+	}
+	sort.Ints(iss.BlockedBy)
+	if err := json.Unmarshal([]byte(watchersJSON), &iss.Watchers); err != nil || len(iss.Watchers) == 0 {
+		iss.Watchers = nil
+	}
+	sort.SliceStable(iss.Watchers, func(i, j int) bool {
+		return strings.ToLower(iss.Watchers[i]) < strings.ToLower(iss.Watchers[j])
+	})
 	iss.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
 	iss.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
 	if closedAt.Valid {
@@ -589,6 +1778,12 @@ func scanIssue(row scanner) (*model.Issue, error) {
 			iss.ClosedAt = &t
 		}
 	}
+	if snoozedUntil.Valid {
+		t, _ := time.Parse(time.RFC3339, snoozedUntil.String)
+		if !t.IsZero() {
+			iss.SnoozedUntil = &t
+		}
+	}
 	return &iss, nil
 }
 
@@ -598,11 +1793,14 @@ func scanEvent(row scanner) (*model.Event, error) {
 	var githubIssueNumber sql.NullInt64
 	var ts string
 
+	var idempotencyKey sql.NullString
+
 	err := row.Scan(&e.ID, &e.RepoID, &githubCommentID, &e.IssueID,
-		&githubIssueNumber, &ts, &e.Action, &e.Payload, &e.Agent, &e.Synced)
+		&githubIssueNumber, &ts, &e.Action, &e.Payload, &e.Agent, &e.Synced, &idempotencyKey)
 	if err != nil {
 		return nil, err
 	}
+	e.IdempotencyKey = idempotencyKey.String
 
 	if githubCommentID.Valid {
 		v := int(githubCommentID.Int64)
@@ -3,11 +3,18 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/jmaddaus/boxofrocks/internal/engine"
 	"github.com/jmaddaus/boxofrocks/internal/model"
 )
 
@@ -53,6 +60,9 @@ func TestAddRepo(t *testing.T) {
 	if repo.PollIntervalMs != 5000 {
 		t.Errorf("expected default poll_interval_ms=5000, got %d", repo.PollIntervalMs)
 	}
+	if repo.Label != model.DefaultTrackingLabel {
+		t.Errorf("expected default label %q, got %q", model.DefaultTrackingLabel, repo.Label)
+	}
 }
 
 func TestAddRepoDuplicate(t *testing.T) {
@@ -175,6 +185,64 @@ func TestUpdateRepoIssuesSince(t *testing.T) {
 	}
 }
 
+func TestUpdateRepoAllowedInboundActions(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	if len(repo.AllowedInboundActions) != 0 {
+		t.Errorf("expected no inbound action restriction by default, got %v", repo.AllowedInboundActions)
+	}
+
+	repo.AllowedInboundActions = []string{"comment", "status_change"}
+	if err := s.UpdateRepo(ctx, repo); err != nil {
+		t.Fatalf("UpdateRepo: %v", err)
+	}
+
+	got, err := s.GetRepo(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("GetRepo: %v", err)
+	}
+	if strings.Join(got.AllowedInboundActions, ",") != "comment,status_change" {
+		t.Errorf("AllowedInboundActions: want comment,status_change, got %v", got.AllowedInboundActions)
+	}
+	if got.AllowsInboundAction(model.ActionDelete) {
+		t.Error("expected delete to be disallowed")
+	}
+	if !got.AllowsInboundAction(model.ActionComment) {
+		t.Error("expected comment to be allowed")
+	}
+	if (&model.RepoConfig{}).AllowsInboundAction(model.ActionSnapshot) {
+		t.Error("expected snapshot to be disallowed inbound even with no allow list")
+	}
+}
+
+func TestUpdateRepoIssueTypes(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	if !repo.AllowsIssueType(model.IssueTypeBug) || repo.AllowsIssueType("spike") {
+		t.Errorf("expected default issue types, got %v", repo.ValidIssueTypes())
+	}
+
+	repo.IssueTypes = []string{"chore", "spike"}
+	if err := s.UpdateRepo(ctx, repo); err != nil {
+		t.Fatalf("UpdateRepo: %v", err)
+	}
+
+	got, err := s.GetRepo(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("GetRepo: %v", err)
+	}
+	if strings.Join(got.IssueTypes, ",") != "chore,spike" {
+		t.Errorf("IssueTypes: want chore,spike, got %v", got.IssueTypes)
+	}
+	if !got.AllowsIssueType("spike") || got.AllowsIssueType(model.IssueTypeTask) {
+		t.Errorf("expected only chore and spike, got %v", got.ValidIssueTypes())
+	}
+}
+
 func TestUpdateRepoSocketFields(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -400,6 +468,38 @@ func TestListIssuesFilterByStatus(t *testing.T) {
 	}
 }
 
+func TestListIssuesFilterByStatuses(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	for _, st := range model.Statuses {
+		iss, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: string(st)})
+		iss.Status = st
+		s.UpdateIssue(ctx, iss)
+	}
+
+	filter := IssueFilter{Statuses: []model.Status{model.StatusOpen, model.StatusInReview}}
+	issues, err := s.ListIssues(ctx, filter)
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(issues) != 2 || issues[0].Status != model.StatusOpen || issues[1].Status != model.StatusInReview {
+		t.Errorf("open or in_review: got %v", issues)
+	}
+	if n, err := s.CountIssues(ctx, filter); err != nil || n != 2 {
+		t.Errorf("CountIssues = %d, %v; want 2", n, err)
+	}
+
+	issues, err = s.ListIssues(ctx, IssueFilter{Statuses: model.ActionableStatuses})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(issues) != len(model.ActionableStatuses) {
+		t.Errorf("actionable: expected %d issues, got %d", len(model.ActionableStatuses), len(issues))
+	}
+}
+
 func TestListIssuesFilterByPriority(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -456,6 +556,131 @@ func TestListIssuesFilterByOwner(t *testing.T) {
 	}
 }
 
+func TestListIssuesOwnerAndLabelIgnoreCase(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "a", Owner: "Alice", Labels: []string{"Backend"}})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "b", Owner: " alice ", Labels: []string{"frontend"}})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "c", Owner: "bob", Labels: []string{"BACKEND"}})
+
+	issues, err := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID, Owner: "ALICE"})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Errorf("owner ALICE: expected 2 issues, got %d", len(issues))
+	}
+
+	issues, err = s.ListIssues(ctx, IssueFilter{RepoID: repo.ID, Labels: []string{"backend"}})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Errorf("label backend: expected 2 issues, got %d", len(issues))
+	}
+}
+
+func TestListIssuesAllLabels(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "both", Labels: []string{"backend", "urgent"}})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "backend only", Labels: []string{"backend"}})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "urgent only", Labels: []string{"Urgent", "ui"}})
+
+	tests := []struct {
+		labels []string
+		want   []string
+	}{
+		{[]string{"urgent"}, []string{"both", "urgent only"}},
+		{[]string{"backend", "URGENT"}, []string{"both"}},
+		{[]string{"backend", "ui"}, nil},
+		{[]string{" ", ""}, []string{"backend only", "both", "urgent only"}},
+	}
+	for _, tt := range tests {
+		issues, err := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID, Labels: tt.labels})
+		if err != nil {
+			t.Fatalf("ListIssues(%v): %v", tt.labels, err)
+		}
+		var got []string
+		for _, iss := range issues {
+			got = append(got, iss.Title)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("labels %q: got %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestListIssuesSort(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	// a: oldest, urgent, touched last. b: newest, low, touched first.
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "a", Priority: 0, CreatedAt: base, UpdatedAt: base.Add(3 * time.Minute)})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "b", Priority: 3, CreatedAt: base.Add(2 * time.Minute), UpdatedAt: base.Add(2 * time.Minute)})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "c", Priority: 1, CreatedAt: base.Add(time.Minute), UpdatedAt: base.Add(150 * time.Second)})
+
+	tests := []struct {
+		sort IssueSort
+		desc bool
+		want string
+	}{
+		{"", false, "acb"},
+		{SortPriority, true, "bca"},
+		{SortCreated, false, "acb"},
+		{SortCreated, true, "bca"},
+		{SortUpdated, false, "bca"},
+		{SortUpdated, true, "acb"},
+	}
+	for _, tt := range tests {
+		issues, err := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID, Sort: tt.sort, Desc: tt.desc})
+		if err != nil {
+			t.Fatalf("ListIssues(sort=%q): %v", tt.sort, err)
+		}
+		var got string
+		for _, iss := range issues {
+			got += iss.Title
+		}
+		if got != tt.want {
+			t.Errorf("sort=%q desc=%v: got %s, want %s", tt.sort, tt.desc, got, tt.want)
+		}
+	}
+
+	if _, err := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID, Sort: "title; DROP TABLE issues"}); err == nil {
+		t.Error("expected error for unknown sort")
+	}
+}
+
+func TestIssueOwnerAndLabelsNormalizedOnWrite(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	created, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "t", Owner: " Alice "})
+	created.Labels = []string{" Bug", "bug", "", "UI"}
+	if err := s.UpdateIssue(ctx, created); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+
+	got, err := s.GetIssue(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.Owner != "Alice" {
+		t.Errorf("owner: want 'Alice', got %q", got.Owner)
+	}
+	if len(got.Labels) != 2 || got.Labels[0] != "Bug" || got.Labels[1] != "UI" {
+		t.Errorf("labels: want [Bug UI], got %v", got.Labels)
+	}
+}
+
 func TestListIssuesFilterByRepoID(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -499,6 +724,276 @@ func TestListIssuesCombinedFilter(t *testing.T) {
 	}
 }
 
+func TestListIssuesPaging(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	for i := 0; i < 5; i++ {
+		s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: fmt.Sprintf("issue %d", i), Priority: i})
+	}
+	closed, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "closed", Priority: 0})
+	closed.Status = model.StatusClosed
+	s.UpdateIssue(ctx, closed)
+
+	var titles []string
+	for offset := 0; ; offset += 2 {
+		page, err := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID, ExcludeClosed: true, Limit: 2, Offset: offset})
+		if err != nil {
+			t.Fatalf("ListIssues offset %d: %v", offset, err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if len(page) > 2 {
+			t.Fatalf("offset %d: page has %d issues, limit is 2", offset, len(page))
+		}
+		for _, iss := range page {
+			titles = append(titles, iss.Title)
+		}
+	}
+	want := []string{"issue 0", "issue 1", "issue 2", "issue 3", "issue 4"}
+	if strings.Join(titles, ",") != strings.Join(want, ",") {
+		t.Errorf("paged titles = %v, want %v", titles, want)
+	}
+
+	// An offset without a limit returns the rest.
+	rest, err := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID, ExcludeClosed: true, Offset: 3})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(rest) != 2 {
+		t.Errorf("offset 3 without limit: got %d issues, want 2", len(rest))
+	}
+
+	total, err := s.CountIssues(ctx, IssueFilter{RepoID: repo.ID, ExcludeClosed: true, Limit: 2, Offset: 4})
+	if err != nil {
+		t.Fatalf("CountIssues: %v", err)
+	}
+	if total != 5 {
+		t.Errorf("CountIssues = %d, want 5 regardless of limit and offset", total)
+	}
+}
+
+func TestCountIssuesMatchesListIssues(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	other := addTestRepo(t, s, "octocat", "other")
+
+	statuses := []model.Status{model.StatusOpen, model.StatusInProgress, model.StatusClosed, model.StatusDeleted}
+	for i := 0; i < 12; i++ {
+		iss, _ := s.CreateIssue(ctx, &model.Issue{
+			RepoID:    repo.ID,
+			Title:     fmt.Sprintf("issue %d", i),
+			Priority:  i % 3,
+			IssueType: []model.IssueType{model.IssueTypeTask, model.IssueTypeBug}[i%2],
+			Owner:     []string{"", "alice", "bob"}[i%3],
+			Labels:    [][]string{{}, {"ui"}, {"ui", "backend"}}[i%3],
+		})
+		iss.Status = statuses[i%len(statuses)]
+		s.UpdateIssue(ctx, iss)
+	}
+	s.CreateIssue(ctx, &model.Issue{RepoID: other.ID, Title: "elsewhere"})
+
+	priority := 1
+	filters := map[string]IssueFilter{
+		"all":            {RepoID: repo.ID},
+		"exclude closed": {RepoID: repo.ID, ExcludeClosed: true},
+		"status":         {RepoID: repo.ID, Status: model.StatusInProgress},
+		"statuses":       {RepoID: repo.ID, Statuses: []model.Status{model.StatusOpen, model.StatusClosed}},
+		"priority":       {RepoID: repo.ID, Priority: &priority},
+		"type":           {RepoID: repo.ID, Type: model.IssueTypeBug},
+		"owner":          {RepoID: repo.ID, Owner: "alice"},
+		"labels":         {RepoID: repo.ID, Labels: []string{"ui", "backend"}},
+		"combined":       {RepoID: repo.ID, ExcludeClosed: true, Labels: []string{"ui"}, Type: model.IssueTypeTask},
+		"paged":          {RepoID: repo.ID, Limit: 2, Offset: 1, Sort: SortCreated, Desc: true},
+	}
+	for name, filter := range filters {
+		t.Run(name, func(t *testing.T) {
+			n, err := s.CountIssues(ctx, filter)
+			if err != nil {
+				t.Fatalf("CountIssues: %v", err)
+			}
+			unpaged := filter
+			unpaged.Limit, unpaged.Offset = 0, 0
+			issues, err := s.ListIssues(ctx, unpaged)
+			if err != nil {
+				t.Fatalf("ListIssues: %v", err)
+			}
+			if n != len(issues) {
+				t.Errorf("CountIssues = %d, len(ListIssues) = %d", n, len(issues))
+			}
+			if n == 0 {
+				t.Errorf("filter matches no issues")
+			}
+		})
+	}
+}
+
+func TestSearchIssues(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	other := addTestRepo(t, s, "octocat", "other")
+
+	login, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Fix authentication timeout", Description: "Sessions expire early"})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Dark mode", Description: "Add an authorization-free theme toggle"})
+	gone, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Authentication docs"})
+	s.DeleteIssue(ctx, gone.ID)
+	s.CreateIssue(ctx, &model.Issue{RepoID: other.ID, Title: "Authentication in other repo"})
+
+	search := func(q string) []string {
+		t.Helper()
+		issues, err := s.SearchIssues(ctx, repo.ID, q)
+		if err != nil {
+			t.Fatalf("SearchIssues(%q): %v", q, err)
+		}
+		var titles []string
+		for _, iss := range issues {
+			titles = append(titles, iss.Title)
+		}
+		return titles
+	}
+
+	if got := search("authent"); len(got) != 1 || got[0] != "Fix authentication timeout" {
+		t.Errorf("partial word: got %v", got)
+	}
+	if got := search("auth"); len(got) != 2 {
+		t.Errorf("prefix across title and description: got %v, want 2 issues", got)
+	}
+	if got := search("AUTH sess"); len(got) != 1 || got[0] != "Fix authentication timeout" {
+		t.Errorf("every term must match: got %v", got)
+	}
+	if got := search(`"time OR NEAR(`); got != nil {
+		t.Errorf("FTS syntax in the query should be matched literally, got %v", got)
+	}
+
+	// Edits reach the index through the update trigger.
+	login.Title = "Fix login timeout"
+	login.Description = ""
+	s.UpdateIssue(ctx, login)
+	if got := search("authent"); got != nil {
+		t.Errorf("after edit: got %v, want no match", got)
+	}
+	if got := search("logi"); len(got) != 1 {
+		t.Errorf("after edit: got %v, want the renamed issue", got)
+	}
+}
+
+func TestSearchIssuesWithoutFTS(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	for _, stmt := range []string{
+		`DROP TRIGGER issues_fts_insert`, `DROP TRIGGER issues_fts_update`,
+		`DROP TRIGGER issues_fts_delete`, `DROP TABLE issues_fts`,
+	} {
+		if _, err := s.db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	s.fts = false
+
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Fix authentication timeout"})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "100% CPU", Description: "spin_loop in worker"})
+
+	issues, err := s.SearchIssues(ctx, repo.ID, "AUTHENT time")
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Title != "Fix authentication timeout" {
+		t.Errorf("LIKE fallback: got %v", issues)
+	}
+	issues, _ = s.SearchIssues(ctx, repo.ID, "%")
+	if len(issues) != 1 || issues[0].Title != "100% CPU" {
+		t.Errorf("LIKE wildcards should match literally, got %v", issues)
+	}
+
+	// Migrating again recreates the index and backfills existing issues.
+	if err := runMigrations(s.db); err != nil {
+		t.Fatalf("runMigrations: %v", err)
+	}
+	if s.fts = hasFTS(s.db); !s.fts {
+		t.Fatal("expected issues_fts to be recreated")
+	}
+	issues, err = s.SearchIssues(ctx, repo.ID, "authent")
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Errorf("backfilled index: got %d issues, want 1", len(issues))
+	}
+}
+
+func TestDeleteRepoCascades(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	keep := addTestRepo(t, s, "octocat", "keep")
+
+	seed := func(r *model.RepoConfig, path string) {
+		t.Helper()
+		blocker, _ := s.CreateIssue(ctx, &model.Issue{RepoID: r.ID, Title: "blocker"})
+		blocked, _ := s.CreateIssue(ctx, &model.Issue{RepoID: r.ID, Title: "blocked", BlockedBy: []int{blocker.ID}, Watchers: []string{"alice"}})
+		if _, err := s.AppendEvent(ctx, &model.Event{RepoID: r.ID, IssueID: blocker.ID, Action: model.ActionCreate, Payload: `{}`}); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+		if _, err := s.AppendEvent(ctx, &model.Event{RepoID: r.ID, IssueID: blocked.ID, Action: model.ActionComment, Payload: `{}`}); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+		if err := s.SetIssueSyncState(ctx, r.ID, 1, 10, ""); err != nil {
+			t.Fatalf("SetIssueSyncState: %v", err)
+		}
+		if err := s.RecordPostedComment(ctx, r.ID, 1, "hash", 1, 10); err != nil {
+			t.Fatalf("RecordPostedComment: %v", err)
+		}
+		if _, err := s.AddLocalPath(ctx, r.ID, path, true, false); err != nil {
+			t.Fatalf("AddLocalPath: %v", err)
+		}
+	}
+	seed(repo, "/tmp/delete-me")
+	seed(keep, "/tmp/keep-me")
+
+	if err := s.DeleteRepo(ctx, repo.ID); err != nil {
+		t.Fatalf("DeleteRepo: %v", err)
+	}
+
+	for _, table := range []string{"repos", "issues", "events", "issue_sync_state", "posted_comments", "repo_local_paths", "notifications"} {
+		col := "repo_id"
+		if table == "repos" {
+			col = "id"
+		}
+		var orphans, kept int
+		s.db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE `+col+` = ?`, repo.ID).Scan(&orphans)
+		s.db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE `+col+` = ?`, keep.ID).Scan(&kept)
+		if orphans != 0 {
+			t.Errorf("%s: %d rows left for the deleted repo", table, orphans)
+		}
+		if kept == 0 {
+			t.Errorf("%s: rows of the other repo were deleted", table)
+		}
+	}
+	var deps int
+	s.db.QueryRow(`SELECT COUNT(*) FROM issue_dependencies`).Scan(&deps)
+	if deps != 1 {
+		t.Errorf("issue_dependencies: %d rows, want only the other repo's 1", deps)
+	}
+	var watchers int
+	s.db.QueryRow(`SELECT COUNT(*) FROM issue_watchers`).Scan(&watchers)
+	if watchers != 1 {
+		t.Errorf("issue_watchers: %d rows, want only the other repo's 1", watchers)
+	}
+	if _, err := s.GetRepoByName(ctx, "octocat", "hello-world"); err != sql.ErrNoRows {
+		t.Errorf("GetRepoByName after delete: want sql.ErrNoRows, got %v", err)
+	}
+
+	if err := s.DeleteRepo(ctx, repo.ID); err != sql.ErrNoRows {
+		t.Errorf("deleting again: want sql.ErrNoRows, got %v", err)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // NextIssue tests
 // ---------------------------------------------------------------------------
@@ -524,6 +1019,44 @@ func TestNextIssue(t *testing.T) {
 	}
 }
 
+func TestNextIssues(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "p3", Priority: 3})
+	time.Sleep(10 * time.Millisecond)
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "p1 older", Priority: 1})
+	time.Sleep(10 * time.Millisecond)
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "p1 newer", Priority: 1})
+	assigned, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "assigned", Priority: 0})
+	assigned.Owner = "alice"
+	s.UpdateIssue(ctx, assigned)
+	closed, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "closed", Priority: 0})
+	closed.Status = model.StatusClosed
+	s.UpdateIssue(ctx, closed)
+
+	issues, err := s.NextIssues(ctx, repo.ID, 2)
+	if err != nil {
+		t.Fatalf("NextIssues: %v", err)
+	}
+	if len(issues) != 2 || issues[0].Title != "p1 older" || issues[1].Title != "p1 newer" {
+		t.Errorf("n=2: expected [p1 older, p1 newer], got %v", issues)
+	}
+
+	issues, err = s.NextIssues(ctx, repo.ID, 10)
+	if err != nil {
+		t.Fatalf("NextIssues: %v", err)
+	}
+	var titles []string
+	for _, iss := range issues {
+		titles = append(titles, iss.Title)
+	}
+	if got := strings.Join(titles, ","); got != "p1 older,p1 newer,p3" {
+		t.Errorf("n=10: expected only the eligible issues in order, got %s", got)
+	}
+}
+
 func TestNextIssueSkipsAssigned(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -564,26 +1097,164 @@ func TestNextIssueSkipsClosed(t *testing.T) {
 	}
 }
 
-func TestNextIssueNoneAvailable(t *testing.T) {
+func TestNextIssueSkipsOpenBlockers(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
 	repo := addTestRepo(t, s, "octocat", "hello-world")
 
-	_, err := s.NextIssue(ctx, repo.ID)
-	if err == nil {
-		t.Fatal("expected error when no issues available")
+	dependent, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "dependent", Priority: 0})
+	blocker, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "blocker", Priority: 2})
+	blocker.Owner = "alice"
+	s.UpdateIssue(ctx, blocker)
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "other", Priority: 3})
+
+	dependent.BlockedBy = []int{blocker.ID}
+	if err := s.UpdateIssue(ctx, dependent); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
 	}
-	if err != sql.ErrNoRows {
-		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	got, err := s.GetIssue(ctx, dependent.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if len(got.BlockedBy) != 1 || got.BlockedBy[0] != blocker.ID {
+		t.Fatalf("blocked_by = %v, want [%d]", got.BlockedBy, blocker.ID)
+	}
+
+	next, err := s.NextIssue(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("NextIssue: %v", err)
+	}
+	if next.Title != "other" {
+		t.Errorf("while blocked: expected 'other', got '%s'", next.Title)
+	}
+	ex, err := s.NextIssueExclusions(ctx, repo.ID, -1)
+	if err != nil {
+		t.Fatalf("NextIssueExclusions: %v", err)
+	}
+	if ex.Blocked != 1 || ex.Assigned != 1 {
+		t.Errorf("exclusions = %+v, want 1 blocked and 1 assigned", ex)
+	}
+
+	blocker.Status = model.StatusClosed
+	s.UpdateIssue(ctx, blocker)
+
+	next, err = s.NextIssue(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("NextIssue: %v", err)
+	}
+	if next.Title != "dependent" {
+		t.Errorf("after blocker closed: expected 'dependent', got '%s'", next.Title)
+	}
+
+	dependent.BlockedBy = nil
+	s.UpdateIssue(ctx, dependent)
+	got, _ = s.GetIssue(ctx, dependent.ID)
+	if got.BlockedBy != nil {
+		t.Errorf("expected blockers cleared, got %v", got.BlockedBy)
 	}
 }
 
-func TestNextIssueSamePriorityOrderByCreated(t *testing.T) {
+func TestWatchersAndNotifications(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
 	repo := addTestRepo(t, s, "octocat", "hello-world")
 
-	s.CreateIssue(ctx, &model.Issue{
+	issue, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "watched", Watchers: []string{"bob", "alice"}})
+	other, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "unwatched"})
+	got, err := s.GetIssue(ctx, issue.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if len(got.Watchers) != 2 || got.Watchers[0] != "alice" || got.Watchers[1] != "bob" {
+		t.Fatalf("watchers = %v, want [alice bob]", got.Watchers)
+	}
+
+	// alice's own change notifies only bob; the other issue notifies no one.
+	if _, err := s.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: issue.ID, Action: model.ActionComment, Payload: `{"comment":"hi"}`, Agent: "Alice"}); err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+	if _, err := s.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: other.ID, Action: model.ActionComment, Payload: `{}`}); err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+	got.Status = model.StatusClosed
+	closeEvent := &model.Event{RepoID: repo.ID, IssueID: issue.ID, Action: model.ActionClose, Payload: `{}`}
+	if err := s.UpdateIssuesWithEvents(ctx, []IssueChange{{Issue: got, Event: closeEvent}}); err != nil {
+		t.Fatalf("UpdateIssuesWithEvents: %v", err)
+	}
+
+	alice, err := s.ListNotifications(ctx, repo.ID, "ALICE", 0)
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+	if len(alice) != 1 || alice[0].EventID != closeEvent.ID || alice[0].Action != model.ActionClose || alice[0].IssueID != issue.ID {
+		t.Errorf("alice's notifications = %+v, want only the close", alice)
+	}
+	bob, _ := s.ListNotifications(ctx, repo.ID, "bob", 0)
+	if len(bob) != 2 || bob[0].Action != model.ActionClose || bob[1].Action != model.ActionComment {
+		t.Errorf("bob's notifications = %+v, want close then comment", bob)
+	}
+	if limited, _ := s.ListNotifications(ctx, repo.ID, "bob", 1); len(limited) != 1 {
+		t.Errorf("limit 1: got %d notifications", len(limited))
+	}
+
+	// Unwatching stops notifications, and the unwatch itself notifies no one.
+	got.Watchers = []string{"alice"}
+	unwatch := &model.Event{RepoID: repo.ID, IssueID: issue.ID, Action: model.ActionUnwatch, Payload: `{"watcher":"bob"}`}
+	if err := s.UpdateIssuesWithEvents(ctx, []IssueChange{{Issue: got, Event: unwatch}}); err != nil {
+		t.Fatalf("UpdateIssuesWithEvents: %v", err)
+	}
+	s.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: issue.ID, Action: model.ActionReopen, Payload: `{}`})
+	if bob, _ := s.ListNotifications(ctx, repo.ID, "bob", 0); len(bob) != 2 {
+		t.Errorf("after unwatch: bob has %d notifications, want 2", len(bob))
+	}
+	if alice, _ := s.ListNotifications(ctx, repo.ID, "alice", 0); len(alice) != 2 {
+		t.Errorf("after reopen: alice has %d notifications, want 2", len(alice))
+	}
+}
+
+func TestNextIssueForOwner(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "open", Priority: 0, Owner: "alice"})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "in progress", Priority: 3, Owner: "alice", Status: model.StatusInProgress})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "closed", Priority: 0, Owner: "alice", Status: model.StatusClosed})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "someone else's", Priority: 0, Owner: "bob", Status: model.StatusInProgress})
+
+	next, err := s.NextIssueForOwner(ctx, repo.ID, "alice")
+	if err != nil {
+		t.Fatalf("NextIssueForOwner: %v", err)
+	}
+	if next.Title != "in progress" {
+		t.Errorf("expected 'in progress', got '%s'", next.Title)
+	}
+
+	if _, err := s.NextIssueForOwner(ctx, repo.ID, "carol"); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows for owner with no work, got %v", err)
+	}
+}
+
+func TestNextIssueNoneAvailable(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	_, err := s.NextIssue(ctx, repo.ID)
+	if err == nil {
+		t.Fatal("expected error when no issues available")
+	}
+	if err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestNextIssueSamePriorityOrderByCreated(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	s.CreateIssue(ctx, &model.Issue{
 		RepoID:    repo.ID,
 		Title:     "first created",
 		Priority:  1,
@@ -607,6 +1278,61 @@ func TestNextIssueSamePriorityOrderByCreated(t *testing.T) {
 	}
 }
 
+func TestNextIssueStrategies(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	now := time.Now().UTC()
+	for _, iss := range []struct {
+		title    string
+		priority int
+		age      time.Duration
+	}{
+		{"urgent new", 1, 0},
+		{"medium aged", 2, 10 * 24 * time.Hour},
+		{"low oldest", 4, 12 * 24 * time.Hour},
+	} {
+		s.CreateIssue(ctx, &model.Issue{
+			RepoID:    repo.ID,
+			Title:     iss.title,
+			Priority:  iss.priority,
+			CreatedAt: now.Add(-iss.age),
+			UpdatedAt: now.Add(-iss.age),
+		})
+	}
+
+	tests := []struct {
+		strategy model.NextStrategy
+		want     string
+	}{
+		{"", "urgent new"},
+		{model.NextStrategyPriority, "urgent new"},
+		{model.NextStrategyFIFO, "low oldest"},
+		{model.NextStrategyWeighted, "medium aged"},
+	}
+	for _, tt := range tests {
+		repo.NextStrategy = tt.strategy
+		if err := s.UpdateRepo(ctx, repo); err != nil {
+			t.Fatalf("UpdateRepo: %v", err)
+		}
+		next, err := s.NextIssue(ctx, repo.ID)
+		if err != nil {
+			t.Fatalf("NextIssue(%q): %v", tt.strategy, err)
+		}
+		if next.Title != tt.want {
+			t.Errorf("strategy %q: expected %q, got %q", tt.strategy, tt.want, next.Title)
+		}
+		plan, err := s.PlanIssues(ctx, repo.ID, 100)
+		if err != nil {
+			t.Fatalf("PlanIssues(%q): %v", tt.strategy, err)
+		}
+		if len(plan) != 3 || plan[0].Title != tt.want {
+			t.Errorf("strategy %q: expected plan to start with %q, got %d issues", tt.strategy, tt.want, len(plan))
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Event tests
 // ---------------------------------------------------------------------------
@@ -693,6 +1419,37 @@ func TestPendingEvents(t *testing.T) {
 	}
 }
 
+func TestPendingEventsWithTitles(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	first, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "first"})
+	second, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "second"})
+
+	for _, id := range []int{first.ID, second.ID, first.ID} {
+		s.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: id, Action: model.ActionUpdate, Payload: `{}`})
+	}
+	// An event whose issue row is gone keeps an empty title.
+	s.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: 999, Action: model.ActionUpdate, Payload: `{}`})
+
+	pending, err := s.PendingEventsWithTitles(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("PendingEventsWithTitles: %v", err)
+	}
+	want := []string{"first", "second", "first", ""}
+	if len(pending) != len(want) {
+		t.Fatalf("expected %d pending events, got %d", len(want), len(pending))
+	}
+	for i, p := range pending {
+		if p.IssueTitle != want[i] {
+			t.Errorf("event %d: expected title %q, got %q", i, want[i], p.IssueTitle)
+		}
+		if i > 0 && p.Event.ID <= pending[i-1].Event.ID {
+			t.Errorf("events not oldest first: %d after %d", p.Event.ID, pending[i-1].Event.ID)
+		}
+	}
+}
+
 func TestMarkEventSynced(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -722,6 +1479,84 @@ func TestMarkEventSynced(t *testing.T) {
 	}
 }
 
+func TestEventsSince(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "a", "one")
+	other := addTestRepo(t, s, "b", "two")
+	var issues []*model.Issue
+	for i := 0; i < 3; i++ {
+		iss, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: fmt.Sprintf("issue %d", i)})
+		issues = append(issues, iss)
+	}
+	elsewhere, _ := s.CreateIssue(ctx, &model.Issue{RepoID: other.ID, Title: "elsewhere"})
+
+	// Interleave events across the issues, and the other repo's among them.
+	var want []int
+	for round := 0; round < 3; round++ {
+		for _, iss := range issues {
+			ev, err := s.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: iss.ID, Action: model.ActionComment,
+				Payload: fmt.Sprintf(`{"comment":"round %d"}`, round)})
+			if err != nil {
+				t.Fatalf("AppendEvent: %v", err)
+			}
+			want = append(want, ev.ID)
+		}
+		s.AppendEvent(ctx, &model.Event{RepoID: other.ID, IssueID: elsewhere.ID, Action: model.ActionComment,
+			Payload: fmt.Sprintf(`{"comment":"round %d"}`, round)})
+	}
+
+	// Page through with the cursor, two at a time.
+	var got []int
+	seenIssues := make(map[int]bool)
+	cursor := 0
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatal("paging did not finish")
+		}
+		page, err := s.EventsSince(ctx, repo.ID, cursor, 2)
+		if err != nil {
+			t.Fatalf("EventsSince(%d): %v", cursor, err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if len(page) > 2 {
+			t.Fatalf("page after %d has %d events, limit is 2", cursor, len(page))
+		}
+		for _, ev := range page {
+			if ev.RepoID != repo.ID {
+				t.Errorf("event %d belongs to repo %d", ev.ID, ev.RepoID)
+			}
+			got = append(got, ev.ID)
+			seenIssues[ev.IssueID] = true
+		}
+		cursor = page[len(page)-1].ID
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("paged event IDs = %v, want %v", got, want)
+	}
+	if len(seenIssues) != len(issues) {
+		t.Errorf("events span %d issues, want %d", len(seenIssues), len(issues))
+	}
+
+	// Only events appended after the cursor come back next time.
+	late, _ := s.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: issues[1].ID, Action: model.ActionComment, Payload: `{"comment":"late"}`})
+	page, err := s.EventsSince(ctx, repo.ID, cursor, 0)
+	if err != nil {
+		t.Fatalf("EventsSince: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != late.ID {
+		t.Errorf("after cursor %d: got %d events, want only event %d", cursor, len(page), late.ID)
+	}
+
+	// A limit of 0 returns everything.
+	all, _ := s.EventsSince(ctx, repo.ID, 0, 0)
+	if len(all) != len(want)+1 {
+		t.Errorf("EventsSince with no limit returned %d events, want %d", len(all), len(want)+1)
+	}
+}
+
 func TestListEventsFiltersByRepoAndIssue(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -742,6 +1577,57 @@ func TestListEventsFiltersByRepoAndIssue(t *testing.T) {
 	}
 }
 
+func TestFillEventStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "owner", "stats")
+	busy, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "busy"})
+	quiet, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "quiet"})
+
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		ev := &model.Event{RepoID: repo.ID, IssueID: busy.ID, Action: model.ActionComment,
+			Payload: fmt.Sprintf(`{"comment":"%d"}`, i), Timestamp: start.Add(time.Duration(i) * time.Hour)}
+		if _, err := s.AppendEvent(ctx, ev); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+
+	issues, err := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if err := s.FillEventStats(ctx, issues); err != nil {
+		t.Fatalf("FillEventStats: %v", err)
+	}
+	for _, iss := range issues {
+		switch iss.ID {
+		case busy.ID:
+			if iss.EventCount != 5 || iss.LastEventAt == nil || !iss.LastEventAt.Equal(start.Add(4*time.Hour)) {
+				t.Errorf("busy: event_count %d, last_event_at %v; want 5 at %v", iss.EventCount, iss.LastEventAt, start.Add(4*time.Hour))
+			}
+		case quiet.ID:
+			if iss.EventCount != 0 || iss.LastEventAt != nil {
+				t.Errorf("quiet: event_count %d, last_event_at %v; want none", iss.EventCount, iss.LastEventAt)
+			}
+		}
+	}
+
+	// The stats are not stored: writing the issue back leaves them out.
+	busyIssue := issues[0]
+	if busyIssue.ID != busy.ID {
+		busyIssue = issues[1]
+	}
+	busyIssue.Title = "still busy"
+	if err := s.UpdateIssue(ctx, busyIssue); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	got, _ := s.GetIssue(ctx, busy.ID)
+	if got.Title != "still busy" || got.EventCount != 0 || got.LastEventAt != nil {
+		t.Errorf("GetIssue after write: %q with event_count %d, last_event_at %v", got.Title, got.EventCount, got.LastEventAt)
+	}
+}
+
 func TestPendingEventsFiltersByRepo(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -822,6 +1708,36 @@ func TestSetIssueSyncStateUpsert(t *testing.T) {
 	}
 }
 
+func TestCommentsETag(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	if etag, err := s.GetCommentsETag(ctx, 1, 100); err != nil || etag != "" {
+		t.Fatalf("expected empty etag, got %q, %v", etag, err)
+	}
+	if err := s.SetIssueSyncState(ctx, 1, 100, 500, "2024-01-15T10:30:00Z"); err != nil {
+		t.Fatalf("SetIssueSyncState: %v", err)
+	}
+	if err := s.SetCommentsETag(ctx, 1, 100, `W/"abc"`); err != nil {
+		t.Fatalf("SetCommentsETag: %v", err)
+	}
+	if etag, err := s.GetCommentsETag(ctx, 1, 100); err != nil || etag != `W/"abc"` {
+		t.Errorf("expected stored etag, got %q, %v", etag, err)
+	}
+
+	// Storing the ETag leaves the last-seen comment alone, and vice versa.
+	id, at, err := s.GetIssueSyncState(ctx, 1, 100)
+	if err != nil || id != 500 || at != "2024-01-15T10:30:00Z" {
+		t.Errorf("sync state changed: %d, %q, %v", id, at, err)
+	}
+	if err := s.SetIssueSyncState(ctx, 1, 100, 600, "2024-01-16T10:30:00Z"); err != nil {
+		t.Fatalf("SetIssueSyncState: %v", err)
+	}
+	if etag, _ := s.GetCommentsETag(ctx, 1, 100); etag != `W/"abc"` {
+		t.Errorf("etag lost on sync state update, got %q", etag)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Migration idempotency
 // ---------------------------------------------------------------------------
@@ -975,6 +1891,69 @@ func TestDowngradeDB(t *testing.T) {
 	}
 }
 
+func TestUpgradeDB(t *testing.T) {
+	db, err := OpenRawDB(":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	if err := UpgradeDB(db, 0, 1, nil); err != nil {
+		t.Fatalf("UpgradeDB to 1: %v", err)
+	}
+
+	var applied []int
+	if err := UpgradeDB(db, 1, 10, func(v int, _ string) { applied = append(applied, v) }); err != nil {
+		t.Fatalf("UpgradeDB to 10: %v", err)
+	}
+	if want := []int{5, 6, 7, 8, 9, 10}; !slices.Equal(applied, want) {
+		t.Errorf("applied steps %v, want %v", applied, want)
+	}
+	if version, _ := ReadDBVersion(db); version != 10 {
+		t.Errorf("expected version 10, got %d", version)
+	}
+
+	hasColumn := func(table, column string) bool {
+		var n int
+		db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n)
+		return n > 0
+	}
+	if !hasColumn("issues", "parent_id") {
+		t.Error("expected v10 column issues.parent_id")
+	}
+	if hasColumn("repos", "next_strategy") {
+		t.Error("v12 column repos.next_strategy should not exist at v10")
+	}
+
+	if err := UpgradeDB(db, 10, 10, nil); err == nil {
+		t.Error("expected error when target == current")
+	}
+	if err := UpgradeDB(db, 10, DBSchemaVersion+1, nil); err == nil {
+		t.Error("expected error for target newer than the binary")
+	}
+
+	// The store finishes the upgrade from an intermediate version.
+	if err := runMigrations(db); err != nil {
+		t.Fatalf("runMigrations: %v", err)
+	}
+	if !hasColumn("repos", "next_strategy") {
+		t.Error("expected runMigrations to apply the remaining steps")
+	}
+}
+
+func TestMigrationRegistryOrder(t *testing.T) {
+	prev := 0
+	for _, step := range upMigrations {
+		if step.version <= prev {
+			t.Errorf("step %d follows %d; versions must increase", step.version, prev)
+		}
+		prev = step.version
+	}
+	if prev != DBSchemaVersion {
+		t.Errorf("last step is %d, want DBSchemaVersion %d", prev, DBSchemaVersion)
+	}
+}
+
 func TestDowngradeDBRejectsInvalidTarget(t *testing.T) {
 	db, err := OpenRawDB(":memory:")
 	if err != nil {
@@ -1157,6 +2136,54 @@ func TestMultipleLocalPaths(t *testing.T) {
 	}
 }
 
+func TestResolveRepoByPathLongestPrefix(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	outer := addTestRepo(t, s, "octocat", "outer")
+	inner := addTestRepo(t, s, "octocat", "inner")
+
+	if _, err := s.AddLocalPath(ctx, outer.ID, "/home/user/code", false, false); err != nil {
+		t.Fatalf("AddLocalPath outer: %v", err)
+	}
+	if _, err := s.AddLocalPath(ctx, inner.ID, "/home/user/code/vendor/lib", false, false); err != nil {
+		t.Fatalf("AddLocalPath inner: %v", err)
+	}
+	if _, err := s.AddLocalPath(ctx, inner.ID, "/tmp/100%_done", false, false); err != nil {
+		t.Fatalf("AddLocalPath wildcard: %v", err)
+	}
+
+	tests := []struct {
+		dir    string
+		wantID int // 0 means no match
+	}{
+		{"/home/user/code", outer.ID},
+		{"/home/user/code/src/pkg", outer.ID},
+		{"/home/user/code/vendor/lib", inner.ID},
+		{"/home/user/code/vendor/lib/sub", inner.ID},
+		{"/home/user/code/vendor/library", outer.ID}, // sibling, not a subdirectory of lib
+		{"/home/user/codebase", 0},
+		{"/home/user", 0},
+		{"/tmp/100%_done/x", inner.ID},
+		{"/tmp/100xxdone", 0}, // % and _ must not act as wildcards
+	}
+	for _, tt := range tests {
+		repo, err := s.ResolveRepoByPath(ctx, tt.dir)
+		if tt.wantID == 0 {
+			if err != sql.ErrNoRows {
+				t.Errorf("ResolveRepoByPath(%q): expected sql.ErrNoRows, got repo=%v err=%v", tt.dir, repo, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ResolveRepoByPath(%q): %v", tt.dir, err)
+			continue
+		}
+		if repo.ID != tt.wantID {
+			t.Errorf("ResolveRepoByPath(%q): expected repo %d, got %d", tt.dir, tt.wantID, repo.ID)
+		}
+	}
+}
+
 func TestLocalPathMigration(t *testing.T) {
 	// Simulate a v4 database with local_path set on repos table,
 	// then run migrations to verify data is migrated to repo_local_paths.
@@ -1241,3 +2268,1396 @@ func TestLocalPathMigration(t *testing.T) {
 		t.Errorf("expected version %d, got %d", DBSchemaVersion, version)
 	}
 }
+
+func TestSnoozedIssuesExcluded(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "snoozed", Priority: 0, SnoozedUntil: &future})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "woken", Priority: 1, SnoozedUntil: &past})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "plain", Priority: 2})
+
+	next, err := s.NextIssue(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("NextIssue: %v", err)
+	}
+	if next.Title != "woken" {
+		t.Errorf("expected 'woken' (snooze expired), got '%s'", next.Title)
+	}
+
+	visible, err := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID, ExcludeSnoozed: true})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(visible) != 2 {
+		t.Errorf("expected 2 unsnoozed issues, got %d", len(visible))
+	}
+
+	all, err := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 issues without snooze filter, got %d", len(all))
+	}
+	if all[0].SnoozedUntil == nil || !all[0].SnoozedUntil.Equal(future.Truncate(time.Second)) {
+		t.Errorf("SnoozedUntil not round-tripped: got %v", all[0].SnoozedUntil)
+	}
+}
+
+func TestNextIssueWithinBudget(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "big", Priority: 1, Estimate: 13})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "small", Priority: 2, Estimate: 3})
+
+	next, err := s.NextIssueWithinBudget(ctx, repo.ID, 8)
+	if err != nil {
+		t.Fatalf("NextIssueWithinBudget: %v", err)
+	}
+	if next.Title != "small" {
+		t.Errorf("expected 'small', got '%s'", next.Title)
+	}
+	if next.Estimate != 3 {
+		t.Errorf("expected estimate 3, got %d", next.Estimate)
+	}
+
+	if _, err := s.NextIssueWithinBudget(ctx, repo.ID, 2); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows when nothing fits, got %v", err)
+	}
+}
+
+func TestPlanIssuesGreedy(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "a", Priority: 0, Estimate: 5})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "b", Priority: 1, Estimate: 8})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "c", Priority: 2, Estimate: 3})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "d", Priority: 3, Estimate: 2})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "assigned", Priority: 0, Estimate: 1, Owner: "bob"})
+
+	plan, err := s.PlanIssues(ctx, repo.ID, 10)
+	if err != nil {
+		t.Fatalf("PlanIssues: %v", err)
+	}
+
+	// a (5) fits, b (8) does not fit the remaining 5, c (3) fits, d (2) fits.
+	var titles []string
+	for _, iss := range plan {
+		titles = append(titles, iss.Title)
+	}
+	if strings.Join(titles, ",") != "a,c,d" {
+		t.Errorf("expected plan a,c,d, got %v", titles)
+	}
+}
+
+func TestIssuesUpdatedSince(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	other := addTestRepo(t, s, "octocat", "other")
+
+	old := time.Now().UTC().Add(-time.Hour)
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "stale", CreatedAt: old, UpdatedAt: old})
+	gone, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "gone", CreatedAt: old, UpdatedAt: old})
+	s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "fresh"})
+	s.CreateIssue(ctx, &model.Issue{RepoID: other.ID, Title: "other repo"})
+
+	if err := s.DeleteIssue(ctx, gone.ID); err != nil {
+		t.Fatalf("DeleteIssue: %v", err)
+	}
+
+	changed, err := s.IssuesUpdatedSince(ctx, repo.ID, old.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("IssuesUpdatedSince: %v", err)
+	}
+	got := map[string]model.Status{}
+	for _, iss := range changed {
+		got[iss.Title] = iss.Status
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 changed issues, got %v", got)
+	}
+	if got["gone"] != model.StatusDeleted {
+		t.Errorf("expected deleted issue to be returned as deleted, got %v", got)
+	}
+	if _, ok := got["fresh"]; !ok {
+		t.Errorf("expected fresh issue in result, got %v", got)
+	}
+
+	none, err := s.IssuesUpdatedSince(ctx, repo.ID, time.Now().UTC().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("IssuesUpdatedSince: %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("expected no issues updated in the future, got %d", len(none))
+	}
+}
+
+func TestStaleIssues(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	other := addTestRepo(t, s, "octocat", "other")
+
+	now := time.Now().UTC()
+	create := func(repoID int, title string, status model.Status, age time.Duration) {
+		t.Helper()
+		at := now.Add(-age)
+		if _, err := s.CreateIssue(ctx, &model.Issue{RepoID: repoID, Title: title, Status: status, CreatedAt: at, UpdatedAt: at}); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+	}
+	day := 24 * time.Hour
+	create(repo.ID, "idle open", model.StatusOpen, 20*day)
+	create(repo.ID, "oldest blocked", model.StatusBlocked, 40*day)
+	create(repo.ID, "recent open", model.StatusOpen, 2*day)
+	create(repo.ID, "idle in progress", model.StatusInProgress, 30*day)
+	create(repo.ID, "idle closed", model.StatusClosed, 50*day)
+	create(other.ID, "other repo", model.StatusOpen, 60*day)
+
+	stale, err := s.StaleIssues(ctx, repo.ID, 14*day)
+	if err != nil {
+		t.Fatalf("StaleIssues: %v", err)
+	}
+	var titles []string
+	for _, iss := range stale {
+		titles = append(titles, iss.Title)
+	}
+	if got := strings.Join(titles, ","); got != "oldest blocked,idle open" {
+		t.Errorf("expected oldest blocked,idle open, got %s", got)
+	}
+
+	none, err := s.StaleIssues(ctx, repo.ID, 100*day)
+	if err != nil {
+		t.Fatalf("StaleIssues: %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("expected no issues idle for 100 days, got %d", len(none))
+	}
+}
+
+func TestListIssuesUpdatedSince(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	boundary := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	for title, at := range map[string]time.Time{
+		"before": boundary.Add(-time.Second),
+		"at":     boundary,
+		"after":  boundary.Add(time.Minute),
+	} {
+		s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: title, CreatedAt: at, UpdatedAt: at})
+	}
+
+	filter := IssueFilter{RepoID: repo.ID, UpdatedSince: boundary}
+	issues, err := s.ListIssues(ctx, filter)
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	var titles []string
+	for _, iss := range issues {
+		titles = append(titles, iss.Title)
+	}
+	slices.Sort(titles)
+	if want := []string{"after", "at"}; !slices.Equal(titles, want) {
+		t.Errorf("UpdatedSince boundary: got %v, want %v (inclusive)", titles, want)
+	}
+	if n, _ := s.CountIssues(ctx, filter); n != 2 {
+		t.Errorf("CountIssues = %d, want 2", n)
+	}
+
+	all, _ := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID})
+	if len(all) != 3 {
+		t.Errorf("zero UpdatedSince should not filter, got %d issues", len(all))
+	}
+}
+
+func TestUpdateIssuesWithEvents(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	a, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "a", Priority: 1})
+	b, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "b", Priority: 2})
+
+	a.Priority, b.Priority = 4, 0
+	changes := []IssueChange{
+		{Issue: a, Event: &model.Event{RepoID: repo.ID, IssueID: a.ID, Action: model.ActionUpdate, Payload: `{"priority":4}`}},
+		{Issue: b, Event: &model.Event{RepoID: repo.ID, IssueID: b.ID, Action: model.ActionUpdate, Payload: `{"priority":0}`}},
+	}
+	if err := s.UpdateIssuesWithEvents(ctx, changes); err != nil {
+		t.Fatalf("UpdateIssuesWithEvents: %v", err)
+	}
+
+	for _, want := range []*model.Issue{a, b} {
+		got, err := s.GetIssue(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("GetIssue: %v", err)
+		}
+		if got.Priority != want.Priority {
+			t.Errorf("issue %d: priority %d, want %d", want.ID, got.Priority, want.Priority)
+		}
+	}
+	pending, _ := s.PendingEvents(ctx, repo.ID)
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 pending events, got %d", len(pending))
+	}
+	if changes[0].Event.ID != pending[0].ID {
+		t.Errorf("expected event ID %d to be set on the change, got %d", pending[0].ID, changes[0].Event.ID)
+	}
+
+	// A cancelled context aborts the whole batch.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	a.Priority = 5
+	err := s.UpdateIssuesWithEvents(cancelled, []IssueChange{
+		{Issue: a, Event: &model.Event{RepoID: repo.ID, IssueID: a.ID, Action: model.ActionUpdate, Payload: `{"priority":5}`}},
+	})
+	if err == nil {
+		t.Fatal("expected error with cancelled context")
+	}
+	if got, _ := s.GetIssue(ctx, a.ID); got.Priority != 4 {
+		t.Errorf("expected priority to stay 4 after failed batch, got %d", got.Priority)
+	}
+}
+
+func TestAppendEventAtVersion(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	issue, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "a"})
+
+	comment := func(text string) *model.Event {
+		return &model.Event{RepoID: repo.ID, IssueID: issue.ID, Timestamp: time.Now().UTC(), Action: model.ActionComment, Payload: `{"comment":"` + text + `"}`}
+	}
+
+	// An issue with no events is at version 0.
+	first, err := s.AppendEventAtVersion(ctx, comment("one"), 0)
+	if err != nil {
+		t.Fatalf("AppendEventAtVersion at 0: %v", err)
+	}
+	second, err := s.AppendEventAtVersion(ctx, comment("two"), first.ID)
+	if err != nil {
+		t.Fatalf("AppendEventAtVersion at %d: %v", first.ID, err)
+	}
+
+	if _, err := s.AppendEventAtVersion(ctx, comment("three"), first.ID); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("stale version: expected ErrVersionConflict, got %v", err)
+	}
+	events, _ := s.ListEvents(ctx, repo.ID, issue.ID)
+	if len(events) != 2 {
+		t.Errorf("expected the refused event not to be stored, got %d events", len(events))
+	}
+
+	issues := []*model.Issue{issue}
+	if err := s.FillEventStats(ctx, issues); err != nil {
+		t.Fatalf("FillEventStats: %v", err)
+	}
+	if issue.Version != second.ID {
+		t.Errorf("expected version %d, got %d", second.ID, issue.Version)
+	}
+}
+
+func TestAppendEventIdempotencyKey(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	issue, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "a"})
+
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := func() *model.Event {
+		return &model.Event{RepoID: repo.ID, IssueID: issue.ID, Timestamp: ts, Action: model.ActionComment, Payload: `{"comment":"hi"}`}
+	}
+
+	// The same comment posted twice in one second is two changes.
+	first, err := s.AppendEvent(ctx, event())
+	if err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+	second, err := s.AppendEvent(ctx, event())
+	if err != nil {
+		t.Fatalf("AppendEvent repeat: %v", err)
+	}
+	if first.IdempotencyKey == "" || first.IdempotencyKey == second.IdempotencyKey {
+		t.Errorf("keys = %q and %q, want two distinct keys", first.IdempotencyKey, second.IdempotencyKey)
+	}
+
+	// The first event pulled back from GitHub carries its key and is
+	// refused.
+	commentID := 77
+	dup := event()
+	dup.GitHubCommentID = &commentID
+	dup.Synced = 1
+	dup.IdempotencyKey = first.IdempotencyKey
+	if _, err := s.AppendEvent(ctx, dup); !errors.Is(err, ErrDuplicateEvent) {
+		t.Errorf("AppendEvent pulled copy = %v, want ErrDuplicateEvent", err)
+	}
+
+	// A keyless inbound comment is keyed by its comment ID.
+	humanID := 78
+	human := func() *model.Event {
+		ev := event()
+		ev.GitHubCommentID = &humanID
+		ev.Synced = 1
+		return ev
+	}
+	if ev, err := s.AppendEvent(ctx, human()); err != nil || ev.IdempotencyKey != model.CommentIdempotencyKey(humanID) {
+		t.Fatalf("AppendEvent inbound = %v, %v; want keyed by comment %d", ev, err, humanID)
+	}
+	if _, err := s.AppendEvent(ctx, human()); !errors.Is(err, ErrDuplicateEvent) {
+		t.Errorf("AppendEvent inbound again = %v, want ErrDuplicateEvent", err)
+	}
+
+	// A duplicate in a batch fails it and leaves its issue untouched.
+	changed := *issue
+	changed.Title = "changed"
+	if err := s.UpdateIssuesWithEvents(ctx, []IssueChange{{Issue: &changed, Event: human()}}); !errors.Is(err, ErrDuplicateEvent) {
+		t.Errorf("UpdateIssuesWithEvents duplicate = %v, want ErrDuplicateEvent", err)
+	}
+	if got, _ := s.GetIssue(ctx, issue.ID); got.Title != "a" {
+		t.Errorf("title = %q after a duplicate change, want unchanged", got.Title)
+	}
+
+	events, _ := s.ListEvents(ctx, repo.ID, issue.ID)
+	if len(events) != 3 {
+		t.Errorf("stored %d events, want 3", len(events))
+	}
+}
+
+func TestConcurrentWritesAllSucceed(t *testing.T) {
+	// A file-backed database so that goroutines get distinct connections and
+	// genuinely contend for SQLite's write lock.
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "bor.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	const workers, perWorker = 8, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				issue, err := s.CreateIssue(ctx, &model.Issue{
+					RepoID: repo.ID,
+					Title:  fmt.Sprintf("worker %d issue %d", w, i),
+				})
+				if err != nil {
+					errs <- fmt.Errorf("CreateIssue: %w", err)
+					continue
+				}
+				if _, err := s.AppendEvent(ctx, &model.Event{
+					RepoID:  repo.ID,
+					IssueID: issue.ID,
+					Action:  model.ActionCreate,
+					Payload: `{}`,
+				}); err != nil {
+					errs <- fmt.Errorf("AppendEvent: %w", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	issues, err := s.ListIssues(ctx, IssueFilter{RepoID: repo.ID})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(issues) != workers*perWorker {
+		t.Errorf("expected %d issues, got %d", workers*perWorker, len(issues))
+	}
+}
+
+func TestClaimNextIssueConcurrent(t *testing.T) {
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "bor.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	const issues, workers = 5, 12
+	for i := 0; i < issues; i++ {
+		s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: fmt.Sprintf("issue %d", i), Priority: i})
+	}
+	taken, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "taken", Priority: 0})
+	taken.Owner = "carol"
+	s.UpdateIssue(ctx, taken)
+
+	var wg sync.WaitGroup
+	claimed := make(chan *model.Issue, workers)
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			owner := fmt.Sprintf("agent-%d", w)
+			iss, err := s.ClaimNextIssue(ctx, repo.ID, owner, func(iss *model.Issue) (IssueChange, error) {
+				iss.Owner = owner
+				return IssueChange{Issue: iss, Event: &model.Event{
+					RepoID: repo.ID, IssueID: iss.ID, Timestamp: time.Now().UTC(),
+					Action: model.ActionAssign, Payload: `{"owner":"` + owner + `"}`,
+				}}, nil
+			})
+			if err == sql.ErrNoRows {
+				return
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+			if iss.Owner != owner {
+				errs <- fmt.Errorf("%s claimed issue %d but it is owned by %q", owner, iss.ID, iss.Owner)
+			}
+			claimed <- iss
+		}(w)
+	}
+	wg.Wait()
+	close(claimed)
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	seen := map[int]bool{}
+	for iss := range claimed {
+		if seen[iss.ID] {
+			t.Errorf("issue %d claimed twice", iss.ID)
+		}
+		if iss.ID == taken.ID {
+			t.Error("an already assigned issue was claimed")
+		}
+		seen[iss.ID] = true
+	}
+	if len(seen) != issues {
+		t.Errorf("expected all %d open issues claimed once, got %d", issues, len(seen))
+	}
+	events, err := s.PendingEvents(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("PendingEvents: %v", err)
+	}
+	assigns := 0
+	for _, ev := range events {
+		if ev.Action == model.ActionAssign {
+			assigns++
+		}
+	}
+	if assigns != issues {
+		t.Errorf("expected %d assign events, got %d", issues, assigns)
+	}
+}
+
+func TestClaimNextIssueWIPLimitConcurrent(t *testing.T) {
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "bor.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	repo.WIPLimit = 2
+	if err := s.UpdateRepo(ctx, repo); err != nil {
+		t.Fatalf("UpdateRepo: %v", err)
+	}
+
+	const workers = 8
+	for i := 0; i < workers; i++ {
+		s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: fmt.Sprintf("issue %d", i)})
+	}
+
+	// Every worker claims for the same owner at once.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claimed, limited := 0, 0
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.ClaimNextIssue(ctx, repo.ID, "alice", func(iss *model.Issue) (IssueChange, error) {
+				iss.Owner = "alice"
+				return IssueChange{Issue: iss, Event: &model.Event{
+					RepoID: repo.ID, IssueID: iss.ID, Timestamp: time.Now().UTC(),
+					Action: model.ActionAssign, Payload: `{"owner":"alice"}`,
+				}}, nil
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			var wipErr *WIPLimitError
+			switch {
+			case err == nil:
+				claimed++
+			case errors.As(err, &wipErr):
+				limited++
+			default:
+				t.Errorf("ClaimNextIssue: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if claimed != repo.WIPLimit || limited != workers-repo.WIPLimit {
+		t.Errorf("expected %d claims and %d refused, got %d and %d", repo.WIPLimit, workers-repo.WIPLimit, claimed, limited)
+	}
+	if n, _ := s.CountIssues(ctx, IssueFilter{RepoID: repo.ID, Owner: "alice"}); n != repo.WIPLimit {
+		t.Errorf("alice holds %d issues, want %d", n, repo.WIPLimit)
+	}
+}
+
+func TestIsBusy(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{fmt.Errorf("database is locked (5) (SQLITE_BUSY)"), true},
+		{fmt.Errorf("database table is locked (6) (SQLITE_LOCKED)"), true},
+		{fmt.Errorf("UNIQUE constraint failed: repos.owner"), false},
+	}
+	for _, tt := range tests {
+		if got := isBusy(tt.err); got != tt.want {
+			t.Errorf("isBusy(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteOptionsApplied(t *testing.T) {
+	s, err := NewSQLiteStoreWithOptions(filepath.Join(t.TempDir(), "bor.db"), SQLiteOptions{
+		BusyTimeoutMs: 1234,
+		Synchronous:   "full",
+	})
+	if err != nil {
+		t.Fatalf("NewSQLiteStoreWithOptions: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	var busy, syncMode int
+	if err := s.db.QueryRow("PRAGMA busy_timeout").Scan(&busy); err != nil {
+		t.Fatalf("read busy_timeout: %v", err)
+	}
+	if err := s.db.QueryRow("PRAGMA synchronous").Scan(&syncMode); err != nil {
+		t.Fatalf("read synchronous: %v", err)
+	}
+	if busy != 1234 {
+		t.Errorf("busy_timeout: want 1234, got %d", busy)
+	}
+	if syncMode != 2 { // FULL
+		t.Errorf("synchronous: want 2 (FULL), got %d", syncMode)
+	}
+
+	if _, err := NewSQLiteStoreWithOptions(":memory:", SQLiteOptions{Synchronous: "sometimes"}); err == nil {
+		t.Error("expected error for invalid synchronous mode")
+	}
+}
+
+func TestOversizedCommentsStoredByReference(t *testing.T) {
+	s, err := NewSQLiteStoreWithOptions(":memory:", SQLiteOptions{MaxInlineCommentBytes: 32})
+	if err != nil {
+		t.Fatalf("NewSQLiteStoreWithOptions: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	issue, err := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Verbose"})
+	if err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	long := strings.Repeat("very long agent output ", 10)
+	payload := func(comment string) string {
+		data, _ := json.Marshal(map[string]string{"comment": comment})
+		return string(data)
+	}
+	rawPayload := func(id int) string {
+		var p string
+		if err := s.db.QueryRow(`SELECT payload FROM events WHERE id = ?`, id).Scan(&p); err != nil {
+			t.Fatalf("read raw payload: %v", err)
+		}
+		return p
+	}
+
+	// Inbound event (already on GitHub): compacted on append.
+	commentID := 501
+	inbound, err := s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: issue.ID, Action: model.ActionComment,
+		Timestamp: time.Now().UTC().Add(-time.Minute),
+		Payload:   payload(long), GitHubCommentID: &commentID, Synced: 1,
+	})
+	if err != nil {
+		t.Fatalf("AppendEvent inbound: %v", err)
+	}
+	if raw := rawPayload(inbound.ID); strings.Contains(raw, "agent output") || !strings.Contains(raw, "comment_ref") {
+		t.Errorf("expected inbound comment stored by reference, got %s", raw)
+	}
+
+	// Outbound event: inline until synced, compacted by MarkEventSynced.
+	outbound, err := s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: issue.ID, Action: model.ActionComment, Payload: payload(long),
+	})
+	if err != nil {
+		t.Fatalf("AppendEvent outbound: %v", err)
+	}
+	if raw := rawPayload(outbound.ID); !strings.Contains(raw, "agent output") {
+		t.Errorf("expected unsynced comment kept inline, got %s", raw)
+	}
+	if err := s.MarkEventSynced(ctx, outbound.ID, 502); err != nil {
+		t.Fatalf("MarkEventSynced: %v", err)
+	}
+	if raw := rawPayload(outbound.ID); strings.Contains(raw, "agent output") {
+		t.Errorf("expected synced comment compacted, got %s", raw)
+	}
+
+	// Small comments stay inline.
+	smallCommentID := 503
+	small, err := s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: issue.ID, Action: model.ActionComment,
+		Payload: payload("short"), GitHubCommentID: &smallCommentID, Synced: 1,
+	})
+	if err != nil {
+		t.Fatalf("AppendEvent small: %v", err)
+	}
+	if raw := rawPayload(small.ID); !strings.Contains(raw, "short") {
+		t.Errorf("expected small comment inline, got %s", raw)
+	}
+
+	// Without a fetcher, ListEvents returns the reference.
+	events, err := s.ListEvents(ctx, repo.ID, issue.ID)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if strings.Contains(events[0].Payload, "agent output") {
+		t.Error("expected no hydration without a fetcher")
+	}
+
+	// With a fetcher, ListEvents still returns the reference without a
+	// fetch; HydrateComments restores the full text.
+	var fetched []int
+	s.SetCommentFetcher(func(ctx context.Context, repoID, ghCommentID int) (string, error) {
+		fetched = append(fetched, ghCommentID)
+		return long, nil
+	})
+	events, err = s.ListEvents(ctx, repo.ID, issue.ID)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(fetched) != 0 || strings.Contains(events[0].Payload, "agent output") {
+		t.Errorf("expected ListEvents not to hydrate, fetched %v", fetched)
+	}
+	s.HydrateComments(ctx, events)
+	for i, ev := range events[:2] {
+		var p model.EventPayload
+		json.Unmarshal([]byte(ev.Payload), &p)
+		if p.Comment != long || p.CommentRef != nil {
+			t.Errorf("event %d: expected hydrated comment, got %s", i, ev.Payload)
+		}
+	}
+	if len(fetched) != 2 {
+		t.Errorf("expected 2 fetches (small comment is inline), got %v", fetched)
+	}
+
+	// A fetched body that doesn't match the stored hash is rejected.
+	s.SetCommentFetcher(func(ctx context.Context, repoID, ghCommentID int) (string, error) {
+		return "edited on GitHub", nil
+	})
+	events, _ = s.ListEvents(ctx, repo.ID, issue.ID)
+	s.HydrateComments(ctx, events)
+	if strings.Contains(events[0].Payload, "edited on GitHub") {
+		t.Error("expected mismatched hash not to be hydrated")
+	}
+}
+
+func TestPostedComments(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	id, err := s.PostedCommentID(ctx, repo.ID, 5, "abc", 1)
+	if err != nil {
+		t.Fatalf("PostedCommentID: %v", err)
+	}
+	if id != 0 {
+		t.Errorf("expected no record, got %d", id)
+	}
+
+	if err := s.RecordPostedComment(ctx, repo.ID, 5, "abc", 1, 100); err != nil {
+		t.Fatalf("RecordPostedComment: %v", err)
+	}
+	if id, _ := s.PostedCommentID(ctx, repo.ID, 5, "abc", 1); id != 100 {
+		t.Errorf("expected comment 100, got %d", id)
+	}
+	// A different event with the same body is not matched.
+	if id, _ := s.PostedCommentID(ctx, repo.ID, 5, "abc", 2); id != 0 {
+		t.Errorf("expected no match for another event, got %d", id)
+	}
+
+	// A later event posting the same body takes over the row.
+	if err := s.RecordPostedComment(ctx, repo.ID, 5, "abc", 2, 200); err != nil {
+		t.Fatalf("RecordPostedComment: %v", err)
+	}
+	if id, _ := s.PostedCommentID(ctx, repo.ID, 5, "abc", 2); id != 200 {
+		t.Errorf("expected comment 200, got %d", id)
+	}
+}
+
+func TestRebuildIssueSkipsWhenEventsAppended(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	issue, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Original"})
+	ev, err := s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: issue.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionCreate, Payload: `{"title":"Original"}`,
+	})
+	if err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+
+	issue.Title = "Rebuilt"
+	ok, err := s.RebuildIssue(ctx, issue, ev.ID)
+	if err != nil || !ok {
+		t.Fatalf("RebuildIssue: ok=%v err=%v", ok, err)
+	}
+
+	// A replay that predates the latest event is not written.
+	s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: issue.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionComment, Payload: `{"comment":"late"}`,
+	})
+	issue.Title = "Stale"
+	ok, err = s.RebuildIssue(ctx, issue, ev.ID)
+	if err != nil || ok {
+		t.Fatalf("stale RebuildIssue: ok=%v err=%v", ok, err)
+	}
+	got, _ := s.GetIssue(ctx, issue.ID)
+	if got.Title != "Rebuilt" {
+		t.Errorf("expected title 'Rebuilt', got %q", got.Title)
+	}
+}
+
+func TestTrendingIssues(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+
+	now := time.Now().UTC()
+	add := func(issueID int, action model.Action, payload, agent string, age time.Duration) {
+		t.Helper()
+		if _, err := s.AppendEvent(ctx, &model.Event{
+			RepoID: repo.ID, IssueID: issueID, Timestamp: now.Add(-age),
+			Action: action, Payload: payload, Agent: agent,
+		}); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+
+	hot, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "hot"})
+	add(hot.ID, model.ActionCreate, `{"title":"hot"}`, "alice", time.Hour)
+	add(hot.ID, model.ActionComment, `{"comment":"me too"}`, "bob", time.Hour)
+	add(hot.ID, model.ActionAssign, `{"owner":"alice"}`, "alice", time.Hour)
+
+	warm, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "warm"})
+	add(warm.ID, model.ActionCreate, `{"title":"warm"}`, "alice", time.Hour)
+
+	stale, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "stale"})
+	add(stale.ID, model.ActionCreate, `{"title":"stale"}`, "alice", 30*24*time.Hour)
+
+	closed, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "closed", Status: model.StatusClosed})
+	for i := 0; i < 5; i++ {
+		add(closed.ID, model.ActionComment, `{"comment":"done?"}`, "carol", time.Hour)
+	}
+
+	trending, err := s.TrendingIssues(ctx, repo.ID, now.AddDate(0, 0, -7), 10)
+	if err != nil {
+		t.Fatalf("TrendingIssues: %v", err)
+	}
+	if len(trending) != 2 {
+		t.Fatalf("expected 2 trending issues, got %d", len(trending))
+	}
+	top := trending[0]
+	if top.Issue.ID != hot.ID || top.Events != 3 || top.Comments != 1 || top.Agents != 2 || top.Score != 6 {
+		t.Errorf("unexpected top issue: id=%d %+v", top.Issue.ID, *top)
+	}
+	if trending[1].Issue.ID != warm.ID || trending[1].Score != 2 {
+		t.Errorf("expected warm second with score 2, got id=%d score=%d", trending[1].Issue.ID, trending[1].Score)
+	}
+
+	if limited, _ := s.TrendingIssues(ctx, repo.ID, now.AddDate(0, 0, -7), 1); len(limited) != 1 {
+		t.Errorf("expected limit 1 to return 1 issue, got %d", len(limited))
+	}
+}
+
+func TestIssueStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "octocat", "hello-world")
+	other := addTestRepo(t, s, "octocat", "other")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	add := func(repoID int, status model.Status, issueType model.IssueType, age time.Duration) *model.Issue {
+		t.Helper()
+		iss, err := s.CreateIssue(ctx, &model.Issue{
+			RepoID: repoID, Title: string(status), Status: status, IssueType: issueType,
+			CreatedAt: now.Add(-age),
+		})
+		if err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+		return iss
+	}
+
+	add(repo.ID, model.StatusOpen, model.IssueTypeTask, time.Hour)
+	add(repo.ID, model.StatusOpen, model.IssueTypeBug, 2*time.Hour)
+	oldest := add(repo.ID, model.StatusInProgress, model.IssueTypeBug, 48*time.Hour)
+	add(repo.ID, model.StatusBlocked, model.IssueTypeEpic, 3*time.Hour)
+	add(repo.ID, model.StatusClosed, model.IssueTypeTask, 100*time.Hour)
+	add(repo.ID, model.StatusClosed, model.IssueTypeFeature, 5*time.Hour)
+	add(repo.ID, model.StatusDeleted, model.IssueTypeFeature, 200*time.Hour)
+	add(other.ID, model.StatusOpen, model.IssueTypeTask, 500*time.Hour)
+
+	for i, synced := range []int{0, 0, 1} {
+		payload := fmt.Sprintf(`{"comment":"note %d"}`, i)
+		if _, err := s.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: oldest.ID, Action: model.ActionComment, Payload: payload, Synced: synced}); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+
+	stats, err := s.IssueStats(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("IssueStats: %v", err)
+	}
+	wantStatus := map[model.Status]int{
+		model.StatusOpen: 2, model.StatusInProgress: 1, model.StatusBlocked: 1,
+		model.StatusClosed: 2, model.StatusDeleted: 1,
+	}
+	if !maps.Equal(stats.ByStatus, wantStatus) {
+		t.Errorf("ByStatus = %v, want %v", stats.ByStatus, wantStatus)
+	}
+	wantType := map[model.IssueType]int{
+		model.IssueTypeTask: 2, model.IssueTypeBug: 2, model.IssueTypeEpic: 1, model.IssueTypeFeature: 1,
+	}
+	if !maps.Equal(stats.ByType, wantType) {
+		t.Errorf("ByType = %v, want %v", stats.ByType, wantType)
+	}
+	if stats.Open != 4 || stats.Closed != 2 {
+		t.Errorf("open/closed = %d/%d, want 4/2", stats.Open, stats.Closed)
+	}
+	if stats.PendingEvents != 2 {
+		t.Errorf("PendingEvents = %d, want 2", stats.PendingEvents)
+	}
+	if stats.OldestOpenID != oldest.ID || stats.OldestOpenAt == nil || !stats.OldestOpenAt.Equal(now.Add(-48*time.Hour)) {
+		t.Errorf("oldest open = #%d at %v, want #%d at %v", stats.OldestOpenID, stats.OldestOpenAt, oldest.ID, now.Add(-48*time.Hour))
+	}
+
+	// A repo with nothing open reports no oldest issue.
+	empty := addTestRepo(t, s, "octocat", "empty")
+	stats, err = s.IssueStats(ctx, empty.ID)
+	if err != nil {
+		t.Fatalf("IssueStats: %v", err)
+	}
+	if stats.OldestOpenAt != nil || stats.OldestOpenID != 0 || len(stats.ByStatus) != 0 {
+		t.Errorf("expected empty stats, got %+v", stats)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Export tests
+// ---------------------------------------------------------------------------
+
+func TestWriteDumpRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "owner", "one")
+	other := addTestRepo(t, s, "owner", "two")
+
+	ghID := 7
+	epic, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, GitHubID: &ghID, Title: "Epic", Labels: []string{"big"}})
+	child, _ := s.CreateIssue(ctx, &model.Issue{
+		RepoID: repo.ID, Title: "Child", Description: "d", Priority: 2, Owner: "agent",
+		ParentID: &epic.ID, BlockedBy: []int{epic.ID}, Watchers: []string{"alice"},
+		Comments: []model.Comment{{Text: "note", Author: "agent"}},
+	})
+	gone, _ := s.CreateIssue(ctx, &model.Issue{RepoID: other.ID, Title: "Gone", Status: model.StatusDeleted})
+
+	for _, e := range []*model.Event{
+		{RepoID: repo.ID, IssueID: epic.ID, Action: model.ActionCreate, Payload: `{"title":"Epic"}`, Agent: "agent"},
+		{RepoID: repo.ID, IssueID: child.ID, Action: model.ActionCreate, Payload: `{"title":"Child"}`, Agent: "agent"},
+		{RepoID: other.ID, IssueID: gone.ID, Action: model.ActionDelete, Payload: `{}`},
+	} {
+		if _, err := s.AppendEvent(ctx, e); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+	if err := s.MarkEventSynced(ctx, 1, 99); err != nil {
+		t.Fatalf("MarkEventSynced: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := WriteDump(ctx, s, &buf, 0); err != nil {
+		t.Fatalf("WriteDump: %v", err)
+	}
+	var dump Dump
+	if err := json.Unmarshal([]byte(buf.String()), &dump); err != nil {
+		t.Fatalf("decode dump: %v\n%s", err, buf.String())
+	}
+	if dump.SchemaVersion != DumpVersion {
+		t.Errorf("expected schema_version %d, got %d", DumpVersion, dump.SchemaVersion)
+	}
+	if len(dump.Repos) != 2 || len(dump.Issues) != 3 || len(dump.Events) != 3 {
+		t.Fatalf("expected 2 repos, 3 issues, 3 events; got %d, %d, %d",
+			len(dump.Repos), len(dump.Issues), len(dump.Events))
+	}
+
+	fresh := newTestStore(t)
+	result, err := fresh.ImportDump(ctx, &dump)
+	if err != nil {
+		t.Fatalf("ImportDump: %v", err)
+	}
+	if result.Repos != 2 || result.Issues != 3 || result.Events != 3 ||
+		len(result.RenumberedRepos)+len(result.RenumberedIssues)+len(result.RenumberedEvents) != 0 {
+		t.Errorf("expected 2 repos, 3 issues, 3 events with their IDs kept, got %+v", result)
+	}
+	if got, _ := fresh.GetRepo(ctx, repo.ID); got.FullName() != repo.FullName() {
+		t.Errorf("expected repo %d to be %s, got %s", repo.ID, repo.FullName(), got.FullName())
+	}
+
+	for _, want := range []*model.Issue{epic, child, gone} {
+		got, err := fresh.GetIssue(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("GetIssue(%d): %v", want.ID, err)
+		}
+		wantJSON, _ := json.Marshal(want)
+		gotJSON, _ := json.Marshal(got)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("issue %d changed on round trip:\nwant %s\ngot  %s", want.ID, wantJSON, gotJSON)
+		}
+	}
+	for _, iss := range dump.Issues {
+		wantEvents, _ := s.ListEvents(ctx, iss.RepoID, iss.ID)
+		gotEvents, _ := fresh.ListEvents(ctx, iss.RepoID, iss.ID)
+		wantJSON, _ := json.Marshal(wantEvents)
+		gotJSON, _ := json.Marshal(gotEvents)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("events of issue %d changed on round trip:\nwant %s\ngot  %s", iss.ID, wantJSON, gotJSON)
+		}
+	}
+
+	// A single-repo dump holds only that repo's rows.
+	buf.Reset()
+	if err := WriteDump(ctx, s, &buf, other.ID); err != nil {
+		t.Fatalf("WriteDump(repo): %v", err)
+	}
+	dump = Dump{}
+	if err := json.Unmarshal([]byte(buf.String()), &dump); err != nil {
+		t.Fatalf("decode repo dump: %v", err)
+	}
+	if len(dump.Repos) != 1 || dump.Repos[0].ID != other.ID || len(dump.Issues) != 1 || len(dump.Events) != 1 {
+		t.Errorf("expected only repo %d's rows, got %d repos, %d issues, %d events",
+			other.ID, len(dump.Repos), len(dump.Issues), len(dump.Events))
+	}
+}
+
+func TestImportDumpRenumbersTakenIDs(t *testing.T) {
+	src := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, src, "owner", "src")
+	parent, _ := src.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Parent"})
+	child, _ := src.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Child", ParentID: &parent.ID, BlockedBy: []int{parent.ID}})
+	src.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: child.ID, Action: model.ActionCreate, Payload: `{"title":"Child"}`})
+
+	var buf strings.Builder
+	if err := WriteDump(ctx, src, &buf, 0); err != nil {
+		t.Fatalf("WriteDump: %v", err)
+	}
+	var dump Dump
+	if err := json.Unmarshal([]byte(buf.String()), &dump); err != nil {
+		t.Fatalf("decode dump: %v", err)
+	}
+
+	// The destination already holds repo 1, issue 1 and event 1.
+	dst := newTestStore(t)
+	local := addTestRepo(t, dst, "owner", "local")
+	localIssue, _ := dst.CreateIssue(ctx, &model.Issue{RepoID: local.ID, Title: "Local"})
+	dst.AppendEvent(ctx, &model.Event{RepoID: local.ID, IssueID: localIssue.ID, Action: model.ActionCreate, Payload: `{}`})
+
+	result, err := dst.ImportDump(ctx, &dump)
+	if err != nil {
+		t.Fatalf("ImportDump: %v", err)
+	}
+	newRepo, ok := result.RenumberedRepos[repo.ID]
+	if !ok {
+		t.Fatalf("expected repo %d to be renumbered, got %+v", repo.ID, result)
+	}
+	newParent := result.RenumberedIssues[parent.ID]
+	if _, kept := result.RenumberedIssues[child.ID]; kept || newParent == 0 {
+		t.Errorf("expected only issue %d to be renumbered, got %v", parent.ID, result.RenumberedIssues)
+	}
+	got, err := dst.GetIssue(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.RepoID != newRepo || got.ParentID == nil || *got.ParentID != newParent || !slices.Equal(got.BlockedBy, []int{newParent}) {
+		t.Errorf("expected child in repo %d with parent and blocker %d, got %+v", newRepo, newParent, got)
+	}
+	events, _ := dst.ListEvents(ctx, newRepo, child.ID)
+	if len(events) != 1 || events[0].ID == 1 {
+		t.Errorf("expected the child's event under a new ID, got %+v", events)
+	}
+}
+
+func TestImportDumpRemapsEventPayloadIDs(t *testing.T) {
+	src := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, src, "owner", "src")
+	epic, _ := src.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Epic"})
+	child, _ := src.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Child"})
+	for _, e := range []*model.Event{
+		{RepoID: repo.ID, IssueID: epic.ID, Action: model.ActionCreate, Payload: `{"title":"Epic"}`},
+		{RepoID: repo.ID, IssueID: child.ID, Action: model.ActionCreate, Payload: fmt.Sprintf(`{"title":"Child","parent_id":%d}`, epic.ID)},
+		{RepoID: repo.ID, IssueID: child.ID, Action: model.ActionClearParent, Payload: fmt.Sprintf(`{"parent_id":%d}`, epic.ID)},
+		{RepoID: repo.ID, IssueID: child.ID, Action: model.ActionSetParent, Payload: fmt.Sprintf(`{"parent_id":%d}`, epic.ID)},
+		{RepoID: repo.ID, IssueID: child.ID, Action: model.ActionAddDependency, Payload: fmt.Sprintf(`{"blocker_id":%d}`, epic.ID)},
+	} {
+		if _, err := src.AppendEvent(ctx, e); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+	// A closed issue whose links live only in a snapshot after compaction.
+	done, _ := src.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Done", Status: model.StatusClosed})
+	for _, e := range []*model.Event{
+		{RepoID: repo.ID, IssueID: done.ID, Action: model.ActionCreate, Payload: fmt.Sprintf(`{"title":"Done","parent_id":%d}`, epic.ID), Synced: 1},
+		{RepoID: repo.ID, IssueID: done.ID, Action: model.ActionAddDependency, Payload: fmt.Sprintf(`{"blocker_id":%d}`, epic.ID), Synced: 1},
+		{RepoID: repo.ID, IssueID: done.ID, Action: model.ActionClose, Payload: `{"status":"closed","from_status":"open"}`, Synced: 1},
+	} {
+		if _, err := src.AppendEvent(ctx, e); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+	if result, err := src.CompactEvents(ctx, repo.ID, 0); err != nil || result.Issues != 1 {
+		t.Fatalf("CompactEvents = %+v, %v; want one issue compacted", result, err)
+	}
+
+	var buf strings.Builder
+	if err := WriteDump(ctx, src, &buf, 0); err != nil {
+		t.Fatalf("WriteDump: %v", err)
+	}
+	var dump Dump
+	if err := json.Unmarshal([]byte(buf.String()), &dump); err != nil {
+		t.Fatalf("decode dump: %v", err)
+	}
+
+	// Issue 1 is taken, so the epic is renumbered.
+	dst := newTestStore(t)
+	local := addTestRepo(t, dst, "owner", "local")
+	dst.CreateIssue(ctx, &model.Issue{RepoID: local.ID, Title: "Local"})
+
+	result, err := dst.ImportDump(ctx, &dump)
+	if err != nil {
+		t.Fatalf("ImportDump: %v", err)
+	}
+	newEpic, ok := result.RenumberedIssues[epic.ID]
+	if !ok {
+		t.Fatalf("expected issue %d to be renumbered, got %+v", epic.ID, result)
+	}
+	newRepo := result.RenumberedRepos[repo.ID]
+
+	var events []*model.Event
+	for _, id := range []int{newEpic, child.ID, done.ID} {
+		evs, err := dst.ListEvents(ctx, newRepo, id)
+		if err != nil {
+			t.Fatalf("ListEvents(%d): %v", id, err)
+		}
+		events = append(events, evs...)
+	}
+	issues, err := engine.Replay(events)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	got := issues[child.ID]
+	if got == nil || got.ParentID == nil || *got.ParentID != newEpic || !slices.Equal(got.BlockedBy, []int{newEpic}) {
+		t.Errorf("expected replayed child with parent and blocker %d, got %+v", newEpic, got)
+	}
+	got = issues[done.ID]
+	if got == nil || got.ParentID == nil || *got.ParentID != newEpic || !slices.Equal(got.BlockedBy, []int{newEpic}) {
+		t.Errorf("expected replayed snapshot with parent and blocker %d, got %+v", newEpic, got)
+	}
+}
+
+func TestImportDumpRejectsNewerVersion(t *testing.T) {
+	s := newTestStore(t)
+	dump := &Dump{SchemaVersion: DumpVersion + 1}
+	if _, err := s.ImportDump(context.Background(), dump); !errors.Is(err, ErrDumpTooNew) {
+		t.Errorf("expected ErrDumpTooNew, got %v", err)
+	}
+	if _, err := s.ImportDump(context.Background(), &Dump{}); err == nil {
+		t.Error("expected an error for a document without schema_version")
+	}
+}
+
+func TestImportDumpRollsBack(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	addTestRepo(t, s, "owner", "existing")
+
+	// The first repo imports cleanly; the second is already registered, so
+	// the whole import must roll back.
+	dump := &Dump{
+		SchemaVersion: DumpVersion,
+		Repos: []*model.RepoConfig{
+			{ID: 5, Owner: "owner", Name: "new"},
+			{ID: 6, Owner: "owner", Name: "existing"},
+		},
+		Issues: []*model.Issue{{ID: 1, RepoID: 5, Title: "Orphan"}},
+	}
+	if _, err := s.ImportDump(ctx, dump); !errors.Is(err, ErrRepoExists) {
+		t.Fatalf("expected ErrRepoExists, got %v", err)
+	}
+	repos, _ := s.ListRepos(ctx)
+	if len(repos) != 1 {
+		t.Errorf("expected only the existing repo after rollback, got %d repos", len(repos))
+	}
+	if _, err := s.GetIssue(ctx, 1); err != sql.ErrNoRows {
+		t.Errorf("expected no imported issue after rollback, got %v", err)
+	}
+
+	// A dump whose events point outside it is rejected before any write.
+	dump = &Dump{
+		SchemaVersion: DumpVersion,
+		Repos:         []*model.RepoConfig{{ID: 5, Owner: "owner", Name: "new"}},
+		Events:        []*model.Event{{ID: 1, RepoID: 5, IssueID: 42, Action: model.ActionCreate}},
+	}
+	if _, err := s.ImportDump(ctx, dump); err == nil || !strings.Contains(err.Error(), "issue 42") {
+		t.Errorf("expected an error about issue 42, got %v", err)
+	}
+	if _, err := s.GetRepoByName(ctx, "owner", "new"); err != sql.ErrNoRows {
+		t.Errorf("expected owner/new not to be imported, got %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Vacuum tests
+// ---------------------------------------------------------------------------
+
+func TestVacuum(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "bor.db")
+	s, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	repo := addTestRepo(t, s, "owner", "churn")
+	kept := addTestRepo(t, s, "owner", "kept")
+	payload := `{"description":"` + strings.Repeat("x", 2000) + `"}`
+	for i := 0; i < 200; i++ {
+		iss, err := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: fmt.Sprintf("issue %d", i)})
+		if err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+		if _, err := s.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: iss.ID, Action: model.ActionCreate, Payload: payload}); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+	keptIssue, _ := s.CreateIssue(ctx, &model.Issue{RepoID: kept.ID, Title: "kept"})
+	if err := s.DeleteRepo(ctx, repo.ID); err != nil {
+		t.Fatalf("DeleteRepo: %v", err)
+	}
+
+	var pagesBefore, pagesAfter int
+	s.db.QueryRow(`PRAGMA page_count`).Scan(&pagesBefore)
+	result, err := s.Vacuum(ctx, false)
+	if err != nil {
+		t.Fatalf("Vacuum: %v", err)
+	}
+	s.db.QueryRow(`PRAGMA page_count`).Scan(&pagesAfter)
+	if pagesAfter >= pagesBefore {
+		t.Errorf("expected vacuum to shrink the database, pages %d -> %d", pagesBefore, pagesAfter)
+	}
+	if result.PurgedRows != 0 {
+		t.Errorf("expected nothing purged without purge, got %d", result.PurgedRows)
+	}
+	if got, err := s.GetIssue(ctx, keptIssue.ID); err != nil || got.Title != "kept" {
+		t.Errorf("expected the kept repo's issue to survive, got %v, %v", got, err)
+	}
+}
+
+func TestVacuumPurgesOrphanedRows(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "bor.db")
+	s, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "owner", "gone")
+	blocker, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "blocker"})
+	blocked, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "blocked", BlockedBy: []int{blocker.ID}})
+	s.AppendEvent(ctx, &model.Event{RepoID: repo.ID, IssueID: blocked.ID, Action: model.ActionCreate, Payload: `{}`})
+	s.Close()
+
+	// A raw connection does not enforce foreign keys, so deleting only
+	// the repo row leaves its issues and events behind.
+	db, err := OpenRawDB(dbPath)
+	if err != nil {
+		t.Fatalf("OpenRawDB: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`DELETE FROM repos WHERE id = ?`, repo.ID); err != nil {
+		t.Fatalf("delete repo row: %v", err)
+	}
+
+	result, err := VacuumDB(ctx, db, true)
+	if err != nil {
+		t.Fatalf("VacuumDB: %v", err)
+	}
+	// Two issues, one event and one dependency.
+	if result.PurgedRows != 4 {
+		t.Errorf("expected 4 purged rows, got %d", result.PurgedRows)
+	}
+	var events int
+	db.QueryRow(`SELECT COUNT(*) FROM events`).Scan(&events)
+	if events != 0 {
+		t.Errorf("expected orphaned events to be purged, %d left", events)
+	}
+}
+
+func TestCompactEvents(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	repo := addTestRepo(t, s, "owner", "compact")
+
+	// appendChain records a long history for a new issue, one event a
+	// minute, and returns the issue.
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	appendChain := func(title string, status model.Status, unsyncedAt int) *model.Issue {
+		t.Helper()
+		iss, err := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: title})
+		if err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+		type step struct {
+			action  model.Action
+			payload string
+		}
+		payloads := []step{
+			{model.ActionCreate, `{"title":"` + title + `","labels":["a"]}`},
+			{model.ActionAssign, `{"owner":"alice"}`},
+			{model.ActionWatch, `{"watcher":"bob"}`},
+			{model.ActionStatusChange, `{"status":"in_progress","from_status":"open"}`},
+			{model.ActionLabelAdd, `{"label":"b"}`},
+		}
+		for i := 0; i < 30; i++ {
+			payloads = append(payloads, step{model.ActionComment, fmt.Sprintf(`{"comment":"note %d"}`, i)})
+		}
+		payloads = append(payloads,
+			step{model.ActionUpdate, `{"description":"done","priority":2}`},
+			step{model.ActionLabelRemove, `{"label":"a"}`},
+		)
+		if status == model.StatusClosed {
+			payloads = append(payloads, step{model.ActionClose, `{"comment":"shipped"}`})
+		}
+		for i, p := range payloads {
+			ev := &model.Event{
+				RepoID: repo.ID, IssueID: iss.ID, Action: p.action, Payload: p.payload,
+				Timestamp: start.Add(time.Duration(i) * time.Minute), Agent: "alice", Synced: 1,
+			}
+			if i == unsyncedAt {
+				ev.Synced = 0
+			}
+			if i%2 == 0 {
+				commentID := iss.ID*1000 + i
+				ev.GitHubCommentID = &commentID
+			}
+			if _, err := s.AppendEvent(ctx, ev); err != nil {
+				t.Fatalf("AppendEvent: %v", err)
+			}
+		}
+		iss.Status = status
+		if err := s.UpdateIssue(ctx, iss); err != nil {
+			t.Fatalf("UpdateIssue: %v", err)
+		}
+		return iss
+	}
+	replayJSON := func(issueID int) string {
+		t.Helper()
+		events, err := s.ListEvents(ctx, repo.ID, issueID)
+		if err != nil {
+			t.Fatalf("ListEvents: %v", err)
+		}
+		issues, err := engine.Replay(events)
+		if err != nil {
+			t.Fatalf("Replay: %v", err)
+		}
+		b, _ := json.Marshal(issues[issueID])
+		return string(b)
+	}
+
+	closed := appendChain("closed", model.StatusClosed, -1)
+	open := appendChain("open", model.StatusOpen, -1)
+	pending := appendChain("pending", model.StatusClosed, 10)
+	want := replayJSON(closed.ID)
+	pendingBefore, _ := s.ListEvents(ctx, repo.ID, pending.ID)
+
+	result, err := s.CompactEvents(ctx, repo.ID, 3)
+	if err != nil {
+		t.Fatalf("CompactEvents: %v", err)
+	}
+	// closed: 38 events become a snapshot and the newest 3. pending: the
+	// 10 events before the unsynced one become a snapshot.
+	if result.Issues != 2 || result.EventsRemoved != 34+9 {
+		t.Errorf("result = %+v, want 2 issues and 43 events removed", result)
+	}
+
+	events, _ := s.ListEvents(ctx, repo.ID, closed.ID)
+	if len(events) != 4 || events[0].Action != model.ActionSnapshot {
+		t.Fatalf("expected a snapshot and 3 kept events, got %d events starting with %s", len(events), events[0].Action)
+	}
+	if got := replayJSON(closed.ID); got != want {
+		t.Errorf("replay after compaction differs:\n got %s\nwant %s", got, want)
+	}
+	var payload model.EventPayload
+	json.Unmarshal([]byte(events[0].Payload), &payload)
+	if len(payload.CompactedCommentIDs) != 18 {
+		t.Errorf("snapshot covers %d GitHub comments, want 18", len(payload.CompactedCommentIDs))
+	}
+
+	if events, _ := s.ListEvents(ctx, repo.ID, open.ID); len(events) != 37 {
+		t.Errorf("open issue has %d events after compaction, want all 37", len(events))
+	}
+	pendingAfter, _ := s.ListEvents(ctx, repo.ID, pending.ID)
+	if len(pendingAfter) != len(pendingBefore)-9 || pendingAfter[1].Synced != 0 {
+		t.Errorf("pending issue: %d events after compaction, want %d ending the snapshot at the unsynced event", len(pendingAfter), len(pendingBefore)-9)
+	}
+
+	// Compacting again folds the earlier snapshot into a new one.
+	want = replayJSON(closed.ID)
+	if _, err := s.CompactEvents(ctx, repo.ID, 1); err != nil {
+		t.Fatalf("CompactEvents again: %v", err)
+	}
+	if got := replayJSON(closed.ID); got != want {
+		t.Errorf("replay after second compaction differs:\n got %s\nwant %s", got, want)
+	}
+	events, _ = s.ListEvents(ctx, repo.ID, closed.ID)
+	payload = model.EventPayload{}
+	json.Unmarshal([]byte(events[0].Payload), &payload)
+	if len(events) != 2 || len(payload.CompactedCommentIDs) != 19 {
+		t.Errorf("after second compaction: %d events, snapshot covers %d comments; want 2 and 19", len(events), len(payload.CompactedCommentIDs))
+	}
+}
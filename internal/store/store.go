@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/jmaddaus/boxofrocks/internal/model"
 )
@@ -10,9 +11,110 @@ import (
 type IssueFilter struct {
 	RepoID   int
 	Status   model.Status
+	Statuses []model.Status // issue must have one of these, if any are given
 	Priority *int
 	Type     model.IssueType
-	Owner    string
+	Owner    string   // matched case-insensitively
+	Labels   []string // issue must carry every one, matched case-insensitively
+	ParentID int      // keeps only direct children of this issue; 0 means any
+
+	// ExcludeSnoozed hides issues whose snoozed_until is still in the future.
+	ExcludeSnoozed bool
+	// ExcludeClosed hides closed and deleted issues.
+	ExcludeClosed bool
+	// UpdatedSince, if set, keeps only issues whose updated_at is at or
+	// after it. updated_at has one-second resolution, so the bound is
+	// inclusive.
+	UpdatedSince time.Time
+
+	// Sort picks the ListIssues order; empty means SortPriority. Desc
+	// reverses it. CountIssues ignores both.
+	Sort IssueSort
+	Desc bool
+
+	// Limit caps the number of issues returned; 0 means no limit. Offset
+	// skips that many issues first. CountIssues ignores both.
+	Limit  int
+	Offset int
+}
+
+// IssueSort selects the column ListIssues orders by.
+type IssueSort string
+
+const (
+	// SortPriority orders by priority, oldest first among equals. It is
+	// the default.
+	SortPriority IssueSort = "priority"
+	SortCreated  IssueSort = "created"
+	SortUpdated  IssueSort = "updated"
+)
+
+// IsValidIssueSort reports whether s is a known sort key. The empty string
+// is valid and means SortPriority.
+func IsValidIssueSort(s IssueSort) bool {
+	switch s {
+	case "", SortPriority, SortCreated, SortUpdated:
+		return true
+	}
+	return false
+}
+
+// NextExclusions counts why open issues were not eligible for NextIssue.
+// Each issue is counted once, under the first reason that applies in
+// field order.
+type NextExclusions struct {
+	Candidates int `json:"candidates"` // open or blocked issues considered
+	Blocked    int `json:"blocked"`
+	Assigned   int `json:"assigned"`
+	Snoozed    int `json:"snoozed"`
+	OverBudget int `json:"over_budget"`
+}
+
+// IssueActivity is an issue's recent activity, as ranked by TrendingIssues.
+type IssueActivity struct {
+	Issue    *model.Issue `json:"issue"`
+	Score    int          `json:"score"`    // Events + Comments + Agents
+	Events   int          `json:"events"`   // events in the window
+	Comments int          `json:"comments"` // events carrying a comment
+	Agents   int          `json:"agents"`   // distinct agents acting
+}
+
+// PendingEvent is an event not yet pushed to GitHub with its issue's title,
+// as returned by PendingEventsWithTitles.
+type PendingEvent struct {
+	Event      *model.Event
+	IssueTitle string // empty when the issue row is gone
+}
+
+// IssueStats summarizes a repo's issues, as returned by IssueStats.
+type IssueStats struct {
+	ByStatus map[model.Status]int    `json:"by_status"`
+	ByType   map[model.IssueType]int `json:"by_type"` // deleted issues excluded
+	Open     int                     `json:"open"`    // neither closed nor deleted
+	Closed   int                     `json:"closed"`
+	// PendingEvents counts events not yet pushed to GitHub.
+	PendingEvents int `json:"pending_events"`
+	// OldestOpenID and OldestOpenAt name the earliest-created open issue;
+	// both are empty when nothing is open.
+	OldestOpenID int        `json:"oldest_open_id,omitempty"`
+	OldestOpenAt *time.Time `json:"oldest_open_at,omitempty"`
+}
+
+// Notification records that an event changed an issue Watcher was watching.
+type Notification struct {
+	ID        int          `json:"id"`
+	RepoID    int          `json:"repo_id"`
+	Watcher   string       `json:"watcher"`
+	IssueID   int          `json:"issue_id"`
+	EventID   int          `json:"event_id"`
+	Action    model.Action `json:"action"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// IssueChange pairs an issue's new state with the event that produced it.
+type IssueChange struct {
+	Issue *model.Issue
+	Event *model.Event
 }
 
 // Store defines the persistence interface for the agent tracker.
@@ -23,29 +125,123 @@ type Store interface {
 	GetRepoByName(ctx context.Context, owner, name string) (*model.RepoConfig, error)
 	ListRepos(ctx context.Context) ([]*model.RepoConfig, error)
 	UpdateRepo(ctx context.Context, repo *model.RepoConfig) error
+	// DeleteRepo removes a repo and everything stored for it. Returns
+	// sql.ErrNoRows if the repo does not exist.
+	DeleteRepo(ctx context.Context, id int) error
 
 	// Local paths (worktree support)
 	AddLocalPath(ctx context.Context, repoID int, localPath string, socket, queue bool) (*model.LocalPathConfig, error)
 	RemoveLocalPath(ctx context.Context, repoID int, localPath string) error
 	ListLocalPaths(ctx context.Context, repoID int) ([]model.LocalPathConfig, error)
+	// ListAllLocalPaths returns every registered local path across all repos.
+	ListAllLocalPaths(ctx context.Context) ([]model.LocalPathConfig, error)
+	// ResolveRepoByPath returns the repo whose registered local path is the
+	// longest prefix of dir (exact match or ancestor directory).
+	// Returns sql.ErrNoRows if no local path contains dir.
+	ResolveRepoByPath(ctx context.Context, dir string) (*model.RepoConfig, error)
 
 	// Issues
 	CreateIssue(ctx context.Context, issue *model.Issue) (*model.Issue, error)
+	// CreateIssuesWithEvents stores each change's issue and its create
+	// event atomically, setting the event's IssueID, and returns the
+	// created issues in order.
+	CreateIssuesWithEvents(ctx context.Context, changes []IssueChange) ([]*model.Issue, error)
 	GetIssue(ctx context.Context, id int) (*model.Issue, error)
 	ListIssues(ctx context.Context, filter IssueFilter) ([]*model.Issue, error)
+	// CountIssues returns how many issues match filter, ignoring its Limit
+	// and Offset.
+	CountIssues(ctx context.Context, filter IssueFilter) (int, error)
+	// FillEventStats sets EventCount, LastEventAt and Version on each
+	// issue from its stored events.
+	FillEventStats(ctx context.Context, issues []*model.Issue) error
+	// SearchIssues returns the repo's issues, deleted ones excluded, whose
+	// title or description contains a word starting with each term of query.
+	SearchIssues(ctx context.Context, repoID int, query string) ([]*model.Issue, error)
 	UpdateIssue(ctx context.Context, issue *model.Issue) error
 	DeleteIssue(ctx context.Context, id int) error
+	// UpdateIssuesWithEvents appends each change's event and saves its issue
+	// atomically: either all changes are written or none are.
+	UpdateIssuesWithEvents(ctx context.Context, changes []IssueChange) error
+	// RebuildIssue overwrites an issue row with state replayed from its
+	// events, provided lastEventID is still the issue's latest event. It
+	// reports false, writing nothing, if events were appended since.
+	RebuildIssue(ctx context.Context, issue *model.Issue, lastEventID int) (bool, error)
 	NextIssue(ctx context.Context, repoID int) (*model.Issue, error)
+	// ClaimNextIssue atomically picks the issue NextIssue would return and
+	// stores the change claim builds for it, typically an assignment to
+	// owner, so two concurrent claims never get the same issue. Returns a
+	// *WIPLimitError if owner already holds the repo's wip_limit, and
+	// sql.ErrNoRows if no issue is eligible.
+	ClaimNextIssue(ctx context.Context, repoID int, owner string, claim func(*model.Issue) (IssueChange, error)) (*model.Issue, error)
+	// NextIssues returns up to n issues eligible to be picked up next, in
+	// the order NextIssue would return them one after another.
+	NextIssues(ctx context.Context, repoID, n int) ([]*model.Issue, error)
+	NextIssueWithinBudget(ctx context.Context, repoID, budget int) (*model.Issue, error)
+	// NextIssueExclusions explains why open issues are not next. A negative
+	// budget disables the over-budget check.
+	NextIssueExclusions(ctx context.Context, repoID, budget int) (*NextExclusions, error)
+	// NextIssueForOwner returns the owner's unfinished issue to resume,
+	// in-progress first. Returns sql.ErrNoRows if there is none.
+	NextIssueForOwner(ctx context.Context, repoID int, owner string) (*model.Issue, error)
+	PlanIssues(ctx context.Context, repoID, budget int) ([]*model.Issue, error)
+	// IssuesUpdatedSince returns the repo's issues, including deleted ones,
+	// with updated_at at or after since.
+	// TrendingIssues ranks open issues by activity since the given time,
+	// highest score first, returning at most limit.
+	TrendingIssues(ctx context.Context, repoID int, since time.Time, limit int) ([]*IssueActivity, error)
+	IssuesUpdatedSince(ctx context.Context, repoID int, since time.Time) ([]*model.Issue, error)
+	// StaleIssues returns the repo's open and blocked issues not updated
+	// within olderThan, oldest first.
+	StaleIssues(ctx context.Context, repoID int, olderThan time.Duration) ([]*model.Issue, error)
+	// IssueStats counts the repo's issues by status and type, and reports
+	// its pending events and oldest open issue.
+	IssueStats(ctx context.Context, repoID int) (*IssueStats, error)
 
 	// Events
 	AppendEvent(ctx context.Context, event *model.Event) (*model.Event, error)
+	// AppendEventAtVersion appends event only if its issue's newest event
+	// still has ID version, and returns ErrVersionConflict otherwise.
+	AppendEventAtVersion(ctx context.Context, event *model.Event, version int) (*model.Event, error)
 	ListEvents(ctx context.Context, repoID, issueID int) ([]*model.Event, error)
 	PendingEvents(ctx context.Context, repoID int) ([]*model.Event, error)
+	// PendingEventsWithTitles is PendingEvents with each event's issue title,
+	// read in the same query.
+	PendingEventsWithTitles(ctx context.Context, repoID int) ([]*PendingEvent, error)
+	// EventsSince returns the repo's events with an ID above afterID, across
+	// all its issues, in ID order. A limit of 0 returns all of them.
+	EventsSince(ctx context.Context, repoID, afterID, limit int) ([]*model.Event, error)
+	// HydrateComments restores in place the comment text of events whose
+	// oversized comment is stored by reference to GitHub. Events it cannot
+	// restore keep the reference.
+	HydrateComments(ctx context.Context, events []*model.Event)
 	MarkEventSynced(ctx context.Context, eventID int, githubCommentID int) error
 
+	// ListNotifications returns the watcher's notifications in the repo,
+	// newest first. A limit of 0 returns all of them.
+	ListNotifications(ctx context.Context, repoID int, watcher string, limit int) ([]*Notification, error)
+
+	// Export. Each walks rows of repoID, or of every repo if repoID is 0,
+	// in ID order, stopping at the first error fn returns.
+	StreamIssues(ctx context.Context, repoID int, fn func(*model.Issue) error) error
+	StreamEvents(ctx context.Context, repoID int, fn func(*model.Event) error) error
+	// ImportDump loads a dump in one transaction, keeping dumped IDs that
+	// are free. See SQLiteStore.ImportDump.
+	ImportDump(ctx context.Context, d *Dump) (*ImportResult, error)
+
 	// Sync state
 	GetIssueSyncState(ctx context.Context, repoID, githubIssueNumber int) (lastCommentID int, lastCommentAt string, err error)
 	SetIssueSyncState(ctx context.Context, repoID, githubIssueNumber, lastCommentID int, lastCommentAt string) error
+	// GetCommentsETag returns the ETag of the last comment listing for an
+	// issue, or "" if none was stored.
+	GetCommentsETag(ctx context.Context, repoID, githubIssueNumber int) (string, error)
+	SetCommentsETag(ctx context.Context, repoID, githubIssueNumber int, etag string) error
+
+	// PostedCommentID returns the GitHub comment that eventID was posted as,
+	// found by the hash of its comment body, or 0 if none was recorded.
+	PostedCommentID(ctx context.Context, repoID, githubIssueNumber int, bodyHash string, eventID int) (int, error)
+	RecordPostedComment(ctx context.Context, repoID, githubIssueNumber int, bodyHash string, eventID, githubCommentID int) error
 
+	// Ping runs a trivial query to check the database answers.
+	Ping(ctx context.Context) error
 	Close() error
 }
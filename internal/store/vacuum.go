@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// VacuumResult reports what VacuumDB removed before compacting.
+type VacuumResult struct {
+	// PurgedRows counts rows, events included, that belonged to repos no
+	// longer in the repos table.
+	PurgedRows int64 `json:"purged_rows"`
+}
+
+// orphanPurges delete rows whose repo is gone. DeleteRepo removes them
+// along with the repo, but older builds and raw edits without foreign keys
+// could leave them behind. Dependencies and watchers go before the issues
+// they name.
+var orphanPurges = []string{
+	`DELETE FROM events WHERE repo_id NOT IN (SELECT id FROM repos)`,
+	`DELETE FROM issue_sync_state WHERE repo_id NOT IN (SELECT id FROM repos)`,
+	`DELETE FROM posted_comments WHERE repo_id NOT IN (SELECT id FROM repos)`,
+	`DELETE FROM notifications WHERE repo_id NOT IN (SELECT id FROM repos)`,
+	`DELETE FROM issue_watchers WHERE issue_id IN (SELECT id FROM issues WHERE repo_id NOT IN (SELECT id FROM repos))`,
+	`DELETE FROM issue_dependencies
+	 WHERE issue_id IN (SELECT id FROM issues WHERE repo_id NOT IN (SELECT id FROM repos))
+	    OR blocker_id IN (SELECT id FROM issues WHERE repo_id NOT IN (SELECT id FROM repos))`,
+	`DELETE FROM issues WHERE repo_id NOT IN (SELECT id FROM repos)`,
+	`DELETE FROM repo_local_paths WHERE repo_id NOT IN (SELECT id FROM repos)`,
+}
+
+// VacuumDB rebuilds db to reclaim the pages freed by deleted rows, then
+// truncates the WAL so the space is returned to the filesystem. If purge
+// is set, rows of repos that no longer exist are deleted first, which
+// needs a database at DBSchemaVersion.
+func VacuumDB(ctx context.Context, db *sql.DB, purge bool) (*VacuumResult, error) {
+	result := &VacuumResult{}
+	if purge {
+		version, err := ReadDBVersion(db)
+		if err != nil {
+			return nil, err
+		}
+		if version != DBSchemaVersion {
+			return nil, fmt.Errorf("purge needs schema version %d, database is at %d", DBSchemaVersion, version)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("begin purge: %w", err)
+		}
+		defer tx.Rollback()
+		for _, stmt := range orphanPurges {
+			res, err := tx.ExecContext(ctx, stmt)
+			if err != nil {
+				return nil, fmt.Errorf("purge orphaned rows: %w", err)
+			}
+			n, _ := res.RowsAffected()
+			result.PurgedRows += n
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("commit purge: %w", err)
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, `VACUUM`); err != nil {
+		return nil, fmt.Errorf("vacuum: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
+	return result, nil
+}
+
+// Vacuum runs VacuumDB on the store's database. VACUUM briefly needs the
+// database to itself, so writers wait on busy_timeout while it runs.
+func (s *SQLiteStore) Vacuum(ctx context.Context, purge bool) (*VacuumResult, error) {
+	return VacuumDB(ctx, s.db, purge)
+}
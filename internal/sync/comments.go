@@ -0,0 +1,41 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmaddaus/boxofrocks/internal/github"
+	"github.com/jmaddaus/boxofrocks/internal/model"
+	"github.com/jmaddaus/boxofrocks/internal/store"
+)
+
+// CommentFetcher returns a store.CommentFetcher that re-reads an event's
+// comment text from the GitHub comment it was synced as, whether a
+// boxofrocks event or a plain human comment.
+func CommentFetcher(s store.Store, gh github.Client) store.CommentFetcher {
+	return func(ctx context.Context, repoID, githubCommentID int) (string, error) {
+		repo, err := s.GetRepo(ctx, repoID)
+		if err != nil {
+			return "", fmt.Errorf("get repo %d: %w", repoID, err)
+		}
+		c, err := gh.GetComment(ctx, repo.Owner, repo.Name, githubCommentID)
+		if err != nil {
+			return "", err
+		}
+		ev, err := github.ParseEventComment(c.Body)
+		if err != nil {
+			return "", err
+		}
+		if ev == nil {
+			// A human comment ingested as is; see humanCommentEvent.
+			return strings.TrimSpace(c.Body), nil
+		}
+		var payload model.EventPayload
+		if err := json.Unmarshal([]byte(ev.Payload), &payload); err != nil {
+			return "", fmt.Errorf("parse payload: %w", err)
+		}
+		return payload.Comment, nil
+	}
+}
@@ -0,0 +1,50 @@
+package sync
+
+import "time"
+
+// maxCycleLog bounds how many sync cycle results each RepoSyncer retains.
+const maxCycleLog = 100
+
+// CycleResult records the outcome of a single sync cycle.
+type CycleResult struct {
+	StartedAt time.Time `json:"started_at"`
+	Full      bool      `json:"full"`
+	Pushed    int       `json:"pushed"`
+	Pulled    int       `json:"pulled"`
+	Ignored   int       `json:"ignored,omitempty"` // inbound events rejected by allowed_inbound_actions
+	Error     string    `json:"error,omitempty"`
+}
+
+// cycleLog is a fixed-size ring buffer of recent cycle results.
+// It is not safe for concurrent use; RepoSyncer guards it with its mutex.
+type cycleLog struct {
+	entries []CycleResult
+	next    int  // index of the slot to write next
+	full    bool // true once the buffer has wrapped
+}
+
+func newCycleLog(size int) *cycleLog {
+	return &cycleLog{entries: make([]CycleResult, size)}
+}
+
+// add appends a result, overwriting the oldest entry when the buffer is full.
+func (l *cycleLog) add(r CycleResult) {
+	l.entries[l.next] = r
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// list returns the buffered results, oldest first.
+func (l *cycleLog) list() []CycleResult {
+	if !l.full {
+		out := make([]CycleResult, l.next)
+		copy(out, l.entries[:l.next])
+		return out
+	}
+	out := make([]CycleResult, 0, len(l.entries))
+	out = append(out, l.entries[l.next:]...)
+	out = append(out, l.entries[:l.next]...)
+	return out
+}
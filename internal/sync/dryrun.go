@@ -0,0 +1,270 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmaddaus/boxofrocks/internal/github"
+	"github.com/jmaddaus/boxofrocks/internal/model"
+)
+
+// Planned operations, named after the github.Client call each would make.
+const (
+	OpCreateIssue      = "create_issue"
+	OpCreateComment    = "create_comment"
+	OpUpdateIssueBody  = "update_issue_body"
+	OpUpdateIssueState = "update_issue_state"
+	OpAddAssignees     = "add_assignees"
+	OpRemoveAssignees  = "remove_assignees"
+)
+
+// PlannedOp is one GitHub write a push would make, with its arguments.
+// GitHubNumber is 0 for an issue the push would create, since GitHub has
+// not numbered it yet.
+type PlannedOp struct {
+	Op           string   `json:"op"`
+	IssueID      int      `json:"issue_id"`
+	EventID      int      `json:"event_id,omitempty"`
+	GitHubNumber int      `json:"github_number,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	Body         string   `json:"body,omitempty"`
+	Labels       []string `json:"labels,omitempty"`
+	State        string   `json:"state,omitempty"`
+	Logins       []string `json:"logins,omitempty"`
+}
+
+// DryRunReport is the push a sync would make, in the order it would make
+// it. Pulling only writes to the local store, so it is not planned.
+type DryRunReport struct {
+	Repo          string      `json:"repo"`
+	PendingEvents int         `json:"pending_events"`
+	Operations    []PlannedOp `json:"operations"`
+}
+
+type planResult struct {
+	report *DryRunReport
+	err    error
+}
+
+var errSyncerStopped = errors.New("syncer stopped")
+
+// forceDryRun asks the syncer goroutine for a dry run and waits for it. The
+// plan runs on that goroutine because it reads rs.ghKnown and rs.unmarked.
+func (rs *RepoSyncer) forceDryRun(ctx context.Context) (*DryRunReport, error) {
+	reply := make(chan planResult, 1)
+	select {
+	case rs.forceCh <- syncRequest{plan: reply, planCtx: ctx}:
+	case <-rs.doneCh:
+		return nil, errSyncerStopped
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case res := <-reply:
+		return res.report, res.err
+	case <-rs.doneCh:
+		return nil, errSyncerStopped
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dryRun plans the push a cycle would make now and logs each planned
+// operation. Nothing is written to GitHub, and no event is marked synced.
+func (rs *RepoSyncer) dryRun(ctx context.Context) planResult {
+	rs.refreshRepoSettings(ctx)
+
+	report := &DryRunReport{Repo: rs.repo.FullName(), Operations: []PlannedOp{}}
+	if !rs.repo.PushesToGitHub() {
+		return planResult{report: report}
+	}
+	if err := rs.planOutbound(ctx, report); err != nil {
+		return planResult{err: fmt.Errorf("plan push: %w", err)}
+	}
+	return planResult{report: report}
+}
+
+// planOutbound fills report with the GitHub writes pushOutbound would make
+// for the pending events. It makes the same decisions, reading GitHub only
+// where pushOutbound would look for an already-posted comment.
+func (rs *RepoSyncer) planOutbound(ctx context.Context, report *DryRunReport) error {
+	pending, err := rs.store.PendingEvents(ctx, rs.repo.ID)
+	if err != nil {
+		return fmt.Errorf("query pending events: %w", err)
+	}
+	report.PendingEvents = len(pending)
+
+	plan := func(op PlannedOp) {
+		report.Operations = append(report.Operations, op)
+		slog.Info("sync dry run",
+			"repo", rs.repo.FullName(), "op", op.Op, "issue_id", op.IssueID,
+			"event_id", op.EventID, "github_number", op.GitHubNumber,
+			"title", op.Title, "body", op.Body, "labels", op.Labels,
+			"state", op.State, "logins", op.Logins)
+	}
+
+	var stale pushStale
+	// created holds the issues the push would create on GitHub.
+	created := make(map[int]bool)
+	recent := make(recentComments)
+
+	for _, ev := range pending {
+		if _, ok := rs.unmarked[ev.ID]; ok {
+			// Already on GitHub; the push only records it locally.
+			stale.mark(ev.IssueID, ev.Action)
+			continue
+		}
+
+		issue, err := rs.store.GetIssue(ctx, ev.IssueID)
+		if err != nil {
+			return fmt.Errorf("get issue %d: %w", ev.IssueID, err)
+		}
+
+		if ev.Action == model.ActionCreate && issue.GitHubID == nil && !created[issue.ID] {
+			created[issue.ID] = true
+			plan(PlannedOp{
+				Op:      OpCreateIssue,
+				IssueID: issue.ID,
+				EventID: ev.ID,
+				Title:   issue.Title,
+				Body:    issue.Description,
+				Labels:  append([]string{rs.repo.TrackingLabel()}, issue.Labels...),
+			})
+			plan(PlannedOp{
+				Op:      OpCreateComment,
+				IssueID: issue.ID,
+				EventID: ev.ID,
+				Body:    github.FormatEventComment(ev),
+			})
+			stale.mark(issue.ID, ev.Action)
+
+			if rs.repo.EpicRollup && issue.ParentID != nil {
+				parent, err := rs.store.GetIssue(ctx, *issue.ParentID)
+				if err != nil {
+					return fmt.Errorf("get parent issue %d: %w", *issue.ParentID, err)
+				}
+				if parent.GitHubID != nil {
+					plan(PlannedOp{
+						Op:           OpCreateComment,
+						IssueID:      parent.ID,
+						GitHubNumber: *parent.GitHubID,
+						Body:         github.FormatRollupComment(0, issue.Title),
+					})
+				}
+			}
+			continue
+		}
+
+		var ghNumber int
+		switch {
+		case issue.GitHubID != nil:
+			ghNumber = *issue.GitHubID
+			posted, err := rs.postedComment(ctx, ev, ghNumber, recent)
+			if err != nil {
+				return err
+			}
+			if posted {
+				stale.mark(issue.ID, ev.Action)
+				continue
+			}
+		case !created[issue.ID]:
+			// Skip events whose issue has no GitHub counterpart yet.
+			continue
+		}
+
+		plan(PlannedOp{
+			Op:           OpCreateComment,
+			IssueID:      issue.ID,
+			EventID:      ev.ID,
+			GitHubNumber: ghNumber,
+			Body:         github.FormatEventComment(ev),
+		})
+		stale.mark(issue.ID, ev.Action)
+	}
+
+	// known returns the issue's GitHub number and what the push would know
+	// of it: an issue it creates starts open, unassigned, with its
+	// description as the body.
+	known := func(issue *model.Issue) (int, ghSnapshot, bool) {
+		if issue.GitHubID != nil {
+			return *issue.GitHubID, rs.ghKnown[*issue.GitHubID], true
+		}
+		if created[issue.ID] {
+			return 0, ghSnapshot{state: "open", body: issue.Description, assignees: []string{}}, true
+		}
+		return 0, ghSnapshot{}, false
+	}
+
+	for _, issueID := range stale.body.ids {
+		issue, err := rs.store.GetIssue(ctx, issueID)
+		if err != nil {
+			return fmt.Errorf("get issue %d: %w", issueID, err)
+		}
+		ghNumber, snap, ok := known(issue)
+		if !ok {
+			continue
+		}
+		if body := github.RenderBody(issue.Description, github.IssueMetadata(issue)); body != snap.body {
+			plan(PlannedOp{Op: OpUpdateIssueBody, IssueID: issue.ID, GitHubNumber: ghNumber, Body: body})
+		}
+	}
+	for _, issueID := range stale.state.ids {
+		issue, err := rs.store.GetIssue(ctx, issueID)
+		if err != nil {
+			return fmt.Errorf("get issue %d: %w", issueID, err)
+		}
+		ghNumber, snap, ok := known(issue)
+		if !ok {
+			continue
+		}
+		if state := githubState(issue); state != snap.state {
+			plan(PlannedOp{Op: OpUpdateIssueState, IssueID: issue.ID, GitHubNumber: ghNumber, State: state})
+		}
+	}
+	if len(rs.repo.AssigneeLogins) == 0 {
+		return nil
+	}
+	for _, issueID := range stale.assignee.ids {
+		issue, err := rs.store.GetIssue(ctx, issueID)
+		if err != nil {
+			return fmt.Errorf("get issue %d: %w", issueID, err)
+		}
+		ghNumber, snap, ok := known(issue)
+		if !ok {
+			continue
+		}
+		want := rs.repo.GitHubLogin(issue.Owner)
+		remove, add := rs.assigneeChanges(snap, want)
+		if len(remove) > 0 {
+			plan(PlannedOp{Op: OpRemoveAssignees, IssueID: issue.ID, GitHubNumber: ghNumber, Logins: remove})
+		}
+		if add {
+			plan(PlannedOp{Op: OpAddAssignees, IssueID: issue.ID, GitHubNumber: ghNumber, Logins: []string{want}})
+		}
+	}
+	return nil
+}
+
+// postedComment reports whether ev is already on GitHub issue ghNumber, by
+// the same checks postEventComment makes before posting.
+func (rs *RepoSyncer) postedComment(ctx context.Context, ev *model.Event, ghNumber int, recent recentComments) (bool, error) {
+	hash := github.CommentHash(github.FormatEventComment(ev))
+	commentID, err := rs.store.PostedCommentID(ctx, rs.repo.ID, ghNumber, hash, ev.ID)
+	if err != nil {
+		return false, fmt.Errorf("look up posted comment: %w", err)
+	}
+	if commentID != 0 {
+		return true, nil
+	}
+	if !ev.Timestamp.Before(rs.lastPushAt) {
+		return false, nil
+	}
+	commentID, err = rs.findPostedComment(ctx, ev, ghNumber, hash, recent)
+	if err != nil {
+		return false, err
+	}
+	return commentID != 0, nil
+}
@@ -0,0 +1,161 @@
+package sync
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jmaddaus/boxofrocks/internal/github"
+)
+
+const (
+	// rateLowWater is the remaining GitHub rate limit below which repos
+	// start sharing out what is left instead of calling freely.
+	rateLowWater = 100
+	// rateRepoAllotment is how many calls each repo is guaranteed once the
+	// limit is low, so one busy repo cannot starve the others.
+	rateRepoAllotment = 10
+)
+
+// RateBudget is a repo's view of the shared GitHub rate limit.
+type RateBudget struct {
+	// Remaining and ResetAt are the limit as GitHub last reported it.
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+	// Spent counts the repo's calls since the limit last reset.
+	Spent int `json:"spent"`
+	// Allotment is what is left of the calls guaranteed to the repo while
+	// the limit is low.
+	Allotment int `json:"allotment"`
+	// WaitingUntil is set while the repo is paused for the limit to reset.
+	WaitingUntil *time.Time `json:"waiting_until,omitempty"`
+}
+
+// rateBudget shares the GitHub rate limit between the repos of a
+// SyncManager. While plenty remains every call goes through. Once it drops
+// below rateLowWater each repo keeps rateRepoAllotment calls for itself, and
+// a repo that has used its own allotment may only spend what is not held
+// back for the others; past that it waits for the reset on its own, leaving
+// the other repos to carry on.
+type rateBudget struct {
+	mu      sync.Mutex
+	last    github.RateLimit
+	window  time.Time         // reset time the counts below belong to
+	repos   map[int]bool      // repos sharing the budget
+	spent   map[int]int       // calls per repo this window
+	lowUsed map[int]int       // calls per repo since the limit went low
+	waiting map[int]time.Time // repos paused until the given reset
+}
+
+func (b *rateBudget) add(repoID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.repos == nil {
+		b.repos = make(map[int]bool)
+	}
+	b.repos[repoID] = true
+}
+
+func (b *rateBudget) remove(repoID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.repos, repoID)
+	delete(b.spent, repoID)
+	delete(b.lowUsed, repoID)
+	delete(b.waiting, repoID)
+}
+
+// take records a call by the repo against rl, the limit GitHub last
+// reported. It returns how long the repo must wait before making the call,
+// zero if it may go ahead now.
+func (b *rateBudget) take(repoID int, rl github.RateLimit, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.last = rl
+	if !rl.Reset.Equal(b.window) {
+		b.window = rl.Reset
+		b.spent = make(map[int]int)
+		b.lowUsed = make(map[int]int)
+	}
+
+	low := rl.Remaining > 0 && rl.Remaining < rateLowWater && now.Before(rl.Reset)
+	if low && b.lowUsed[repoID] >= rateRepoAllotment {
+		held := 0
+		for id := range b.repos {
+			if id != repoID {
+				held += max(rateRepoAllotment-b.lowUsed[id], 0)
+			}
+		}
+		if rl.Remaining <= held {
+			return rl.Reset.Sub(now)
+		}
+	}
+
+	b.spent[repoID]++
+	if low {
+		b.lowUsed[repoID]++
+	}
+	return 0
+}
+
+func (b *rateBudget) setWaiting(repoID int, until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until.IsZero() {
+		delete(b.waiting, repoID)
+		return
+	}
+	if b.waiting == nil {
+		b.waiting = make(map[int]time.Time)
+	}
+	b.waiting[repoID] = until
+}
+
+// status returns the repo's view of the budget, nil before GitHub has
+// reported a limit.
+func (b *rateBudget) status(repoID int) *RateBudget {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.last.Reset.IsZero() {
+		return nil
+	}
+	st := &RateBudget{
+		Remaining: b.last.Remaining,
+		ResetAt:   b.last.Reset,
+		Spent:     b.spent[repoID],
+		Allotment: max(rateRepoAllotment-b.lowUsed[repoID], 0),
+	}
+	if until, ok := b.waiting[repoID]; ok {
+		st.WaitingUntil = &until
+	}
+	return st
+}
+
+// waitForBudget blocks until the repo may make another GitHub call, or ctx
+// is done. Only the calling repo waits; the others keep their allotment.
+func (sm *SyncManager) waitForBudget(ctx context.Context, repoID int) {
+	for {
+		rl := sm.ghClient.GetRateLimit()
+		wait := sm.budget.take(repoID, rl, time.Now())
+		if wait <= 0 {
+			return
+		}
+
+		slog.Info("rate limit budget spent, pausing repo until reset",
+			"repo_id", repoID, "remaining", rl.Remaining, "reset", rl.Reset)
+		sm.budget.setWaiting(repoID, rl.Reset)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+		sm.budget.setWaiting(repoID, time.Time{})
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
@@ -3,6 +3,7 @@ package sync
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -14,7 +15,7 @@ import (
 
 // ProcessNewComments processes a set of GitHub comments, parses boxofrocks
 // events from them, and applies them incrementally to the given issue.
-// It returns the updated issue state.
+// It returns the updated issue state. Events are applied with opts.
 func ProcessNewComments(
 	ctx context.Context,
 	issue *model.Issue,
@@ -22,6 +23,7 @@ func ProcessNewComments(
 	s store.Store,
 	repoID int,
 	ghIssueNumber int,
+	opts ...engine.Option,
 ) (*model.Issue, error) {
 	current := issue
 	for _, c := range comments {
@@ -41,16 +43,19 @@ func ProcessNewComments(
 		ev.Synced = 1
 
 		// Apply incrementally.
-		updated, err := engine.Apply(current, ev)
+		updated, err := engine.Apply(current, ev, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("apply event from comment %d: %w", c.ID, err)
 		}
-		current = updated
 
-		// Persist the event.
+		// Persist the event. A copy of one already stored is not applied.
 		if _, err := s.AppendEvent(ctx, ev); err != nil {
+			if errors.Is(err, store.ErrDuplicateEvent) {
+				continue
+			}
 			return nil, fmt.Errorf("append event from comment %d: %w", c.ID, err)
 		}
+		current = updated
 	}
 
 	// Persist the updated issue.
@@ -63,8 +68,8 @@ func ProcessNewComments(
 
 // GenerateSyntheticCreate creates a "create" event from a GitHub issue that
 // has no boxofrocks metadata. This is used when a user creates an issue on
-// the web with the boxofrocks label.
-func GenerateSyntheticCreate(ghIssue *github.GitHubIssue, repoID int, localIssueID int) *model.Event {
+// the web with the repo's tracking label, which is left out of its labels.
+func GenerateSyntheticCreate(ghIssue *github.GitHubIssue, repoID int, localIssueID int, trackingLabel string) *model.Event {
 	// Parse metadata if present.
 	meta, description, _ := github.ParseMetadata(ghIssue.Body)
 
@@ -83,11 +88,11 @@ func GenerateSyntheticCreate(ghIssue *github.GitHubIssue, repoID int, localIssue
 		payload.Description = ghIssue.Body
 	}
 
-	// Collect non-boxofrocks labels from the GitHub issue.
+	// Collect the GitHub issue's labels other than the tracking label.
 	if meta == nil {
 		var labels []string
 		for _, l := range ghIssue.Labels {
-			if l.Name != "boxofrocks" {
+			if l.Name != trackingLabel {
 				labels = append(labels, l.Name)
 			}
 		}
@@ -111,13 +116,36 @@ func GenerateSyntheticCreate(ghIssue *github.GitHubIssue, repoID int, localIssue
 	}
 }
 
+// GenerateSyntheticClose creates a synthetic close event for a GitHub issue
+// that was already closed when first pulled. It is timestamped at GitHub's
+// closed_at, or updated_at if that is missing.
+func GenerateSyntheticClose(ghIssue *github.GitHubIssue, repoID int, localIssueID int) *model.Event {
+	ts := ghIssue.UpdatedAt
+	if ghIssue.ClosedAt != nil {
+		ts = *ghIssue.ClosedAt
+	}
+	ghNum := ghIssue.Number
+	return &model.Event{
+		RepoID:            repoID,
+		IssueID:           localIssueID,
+		GitHubIssueNumber: &ghNum,
+		Timestamp:         ts.UTC(),
+		Action:            model.ActionClose,
+		Payload:           "{}",
+		Agent:             "github-sync",
+		Synced:            0,
+	}
+}
+
 // ReplayFromComments takes all comments from a GitHub issue, parses events,
 // and produces the replayed issue state. This is used for full sync recovery.
+// Events are applied with opts.
 func ReplayFromComments(
 	comments []*github.GitHubComment,
 	repoID int,
 	issueID int,
 	ghIssueNumber int,
+	opts ...engine.Option,
 ) (*model.Issue, []*model.Event, error) {
 	var events []*model.Event
 
@@ -143,7 +171,7 @@ func ReplayFromComments(
 		return nil, nil, fmt.Errorf("no boxofrocks events found in comments")
 	}
 
-	issueMap, err := engine.Replay(events)
+	issueMap, err := engine.Replay(events, opts...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("replay: %w", err)
 	}
@@ -3,8 +3,12 @@ package sync
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +21,12 @@ import (
 const (
 	slowInterval  = 60 * time.Second
 	idleThreshold = 2 * time.Minute
+	// backoffFactor is how much each empty cycle stretches the poll
+	// interval in adaptive mode.
+	backoffFactor = 2
+	// issueQueueSize is how many single-issue pulls may wait for a
+	// syncer before further ones fall back to a normal cycle.
+	issueQueueSize = 64
 )
 
 // SyncStatus describes the current sync state of a single repo.
@@ -27,17 +37,38 @@ type SyncStatus struct {
 	Syncing       bool       `json:"syncing"`
 	Idle          bool       `json:"idle"`
 	LastError     string     `json:"last_error,omitempty"`
+	// FirstSuccessAt is when the syncer first finished a cycle without
+	// error, nil until it has.
+	FirstSuccessAt *time.Time `json:"first_success_at,omitempty"`
+	// PollIntervalMs is the interval the syncer is currently polling at.
+	PollIntervalMs int64 `json:"poll_interval_ms"`
+	// RateBudget is the repo's share of the GitHub rate limit, nil until
+	// GitHub has reported one.
+	RateBudget *RateBudget `json:"rate_budget,omitempty"`
+}
+
+// ActiveSync describes a repo whose sync cycle is currently running.
+type ActiveSync struct {
+	RepoID         int       `json:"repo_id"`
+	RepoName       string    `json:"repo_name"`
+	StartedAt      time.Time `json:"started_at"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
 }
 
 // SyncManager orchestrates sync goroutines for multiple repositories.
 type SyncManager struct {
-	store     store.Store
-	ghClient  github.Client
-	syncers   map[int]*RepoSyncer // keyed by repo ID
-	mu        sync.Mutex
-	rateMu    sync.Mutex
-	rateLimit github.RateLimit
-	stopCh    chan struct{}
+	store    store.Store
+	ghClient github.Client
+	syncers  map[int]*RepoSyncer // keyed by repo ID
+	mu       sync.Mutex
+	budget   rateBudget
+	stopCh   chan struct{}
+	// maxPollInterval, if > 0, turns on adaptive polling for syncers
+	// added afterwards; see SetMaxPollInterval.
+	maxPollInterval time.Duration
+	// priorityRange, if set, is the inclusive priority range syncers added
+	// afterwards clamp pulled priorities to; see SetPriorityRange.
+	priorityRange *[2]int
 }
 
 // NewSyncManager creates a new SyncManager.
@@ -50,6 +81,26 @@ func NewSyncManager(s store.Store, gh github.Client) *SyncManager {
 	}
 }
 
+// SetMaxPollInterval turns on adaptive polling for repos added afterwards.
+// Each cycle that pushes and pulls nothing doubles a repo's poll interval,
+// up to max; any change, or a forced sync, drops it back to the base
+// interval. Zero, the default, keeps the fixed fast and idle intervals.
+func (sm *SyncManager) SetMaxPollInterval(max time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.maxPollInterval = max
+}
+
+// SetPriorityRange makes syncers for repos added afterwards clamp the
+// priorities they pull from GitHub to the inclusive range min to max, as
+// the API does for its own writes. By default pulled priorities are kept as
+// given.
+func (sm *SyncManager) SetPriorityRange(min, max int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.priorityRange = &[2]int{min, max}
+}
+
 // AddRepo starts a syncer goroutine for the given repo.
 func (sm *SyncManager) AddRepo(repo *model.RepoConfig) error {
 	sm.mu.Lock()
@@ -61,7 +112,10 @@ func (sm *SyncManager) AddRepo(repo *model.RepoConfig) error {
 
 	interval := sm.effectiveInterval()
 	rs := newRepoSyncer(repo, sm.store, sm.ghClient, sm, interval)
+	rs.maxInterval = sm.maxPollInterval
+	rs.priorityRange = sm.priorityRange
 	sm.syncers[repo.ID] = rs
+	sm.budget.add(repo.ID)
 
 	// Stagger start: repo gets a delay based on current count of syncers.
 	idx := len(sm.syncers) - 1
@@ -86,6 +140,7 @@ func (sm *SyncManager) RemoveRepo(repoID int) error {
 	delete(sm.syncers, repoID)
 	sm.mu.Unlock()
 
+	sm.budget.remove(repoID)
 	rs.stop()
 	return nil
 }
@@ -104,6 +159,21 @@ func (sm *SyncManager) ForceSync(repoID int) error {
 	return nil
 }
 
+// SyncIssue triggers an immediate pull of one GitHub issue for the given
+// repo, for a webhook reporting that the issue changed.
+func (sm *SyncManager) SyncIssue(repoID, number int) error {
+	sm.mu.Lock()
+	rs, ok := sm.syncers[repoID]
+	sm.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("repo %d not being synced", repoID)
+	}
+
+	rs.queueIssue(number)
+	return nil
+}
+
 // ForceSyncFull triggers an immediate full-replay sync for the given repo.
 func (sm *SyncManager) ForceSyncFull(repoID int) error {
 	sm.mu.Lock()
@@ -118,6 +188,21 @@ func (sm *SyncManager) ForceSyncFull(repoID int) error {
 	return nil
 }
 
+// ForceSyncDryRun plans the push an immediate sync of the given repo would
+// make, without writing to GitHub or marking any event synced. It waits for
+// the repo's syncer to finish any cycle in progress.
+func (sm *SyncManager) ForceSyncDryRun(ctx context.Context, repoID int) (*DryRunReport, error) {
+	sm.mu.Lock()
+	rs, ok := sm.syncers[repoID]
+	sm.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("repo %d not being synced", repoID)
+	}
+
+	return rs.forceDryRun(ctx)
+}
+
 // Status returns per-repo sync status.
 func (sm *SyncManager) Status() map[int]*SyncStatus {
 	sm.mu.Lock()
@@ -126,24 +211,99 @@ func (sm *SyncManager) Status() map[int]*SyncStatus {
 	result := make(map[int]*SyncStatus, len(sm.syncers))
 	for id, rs := range sm.syncers {
 		st := rs.getStatus()
+		st.RateBudget = sm.budget.status(id)
 		result[id] = &st
 	}
 	return result
 }
 
-// Stop stops all syncer goroutines.
+// Active returns the repos that are currently mid-cycle, ordered by repo ID.
+func (sm *SyncManager) Active() []ActiveSync {
+	sm.mu.Lock()
+	syncers := make(map[int]*RepoSyncer, len(sm.syncers))
+	for id, rs := range sm.syncers {
+		syncers[id] = rs
+	}
+	sm.mu.Unlock()
+
+	now := time.Now()
+	active := []ActiveSync{}
+	for id, rs := range syncers {
+		startedAt, ok := rs.activeSince()
+		if !ok {
+			continue
+		}
+		active = append(active, ActiveSync{
+			RepoID:         id,
+			RepoName:       rs.repo.FullName(),
+			StartedAt:      startedAt,
+			ElapsedSeconds: now.Sub(startedAt).Seconds(),
+		})
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].RepoID < active[j].RepoID })
+	return active
+}
+
+// CancelCycle interrupts the repo's in-flight sync cycle by cancelling its
+// context. It reports whether a cycle was running. The syncer keeps running
+// and will start its next cycle on schedule.
+func (sm *SyncManager) CancelCycle(repoID int) (bool, error) {
+	sm.mu.Lock()
+	rs, ok := sm.syncers[repoID]
+	sm.mu.Unlock()
+
+	if !ok {
+		return false, fmt.Errorf("repo %d not being synced", repoID)
+	}
+	return rs.cancelCycle(), nil
+}
+
+// CycleLog returns the recent sync cycle results for the given repo, oldest first.
+func (sm *SyncManager) CycleLog(repoID int) ([]CycleResult, error) {
+	sm.mu.Lock()
+	rs, ok := sm.syncers[repoID]
+	sm.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("repo %d not being synced", repoID)
+	}
+
+	return rs.getCycleLog(), nil
+}
+
+// Stop stops all syncer goroutines, letting running cycles finish.
 func (sm *SyncManager) Stop() {
+	sm.StopContext(context.Background())
+}
+
+// StopContext stops all syncer goroutines like Stop, but once ctx is done
+// it cancels the cycles still running, so a hung GitHub call cannot hold up
+// a shutdown past its deadline.
+func (sm *SyncManager) StopContext(ctx context.Context) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	for id, rs := range sm.syncers {
-		rs.stop()
-		delete(sm.syncers, id)
+	stopped := make(chan struct{})
+	go func() {
+		for _, rs := range sm.syncers {
+			rs.stop()
+		}
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		for _, rs := range sm.syncers {
+			rs.cancelCycle()
+		}
+		<-stopped
 	}
+	clear(sm.syncers)
 }
 
 // effectiveInterval computes the poll interval adjusted by repo count.
-// For N repos, effective interval = max(5s, 5s * N / 2).
+// For N repos, effective interval = max(5s, 5s * N / 2). It applies only to
+// repos without their own poll_interval_ms.
 func (sm *SyncManager) effectiveInterval() time.Duration {
 	n := len(sm.syncers) + 1 // +1 for the repo being added
 	interval := time.Duration(5*n/2) * time.Second
@@ -153,29 +313,17 @@ func (sm *SyncManager) effectiveInterval() time.Duration {
 	return interval
 }
 
-// checkRateLimit checks the shared rate limit and sleeps if necessary.
-func (sm *SyncManager) checkRateLimit() {
-	sm.rateMu.Lock()
-	defer sm.rateMu.Unlock()
-
-	rl := sm.ghClient.GetRateLimit()
-	sm.rateLimit = rl
-
-	if rl.Remaining > 0 && rl.Remaining < 100 {
-		sleepDuration := time.Until(rl.Reset)
-		if sleepDuration > 0 {
-			slog.Info("rate limit low, sleeping until reset", "remaining", rl.Remaining, "reset", rl.Reset)
-			time.Sleep(sleepDuration)
-		}
-	}
-}
-
 // ---------------------------------------------------------------------------
 // syncRequest
 // ---------------------------------------------------------------------------
 
 type syncRequest struct {
 	full bool // true for full replay
+
+	// plan, if set, makes the request a dry run: the syncer plans its
+	// push and sends the result here instead of running a cycle.
+	plan    chan<- planResult
+	planCtx context.Context
 }
 
 // ---------------------------------------------------------------------------
@@ -184,34 +332,104 @@ type syncRequest struct {
 
 // RepoSyncer runs a sync loop for a single repository.
 type RepoSyncer struct {
-	repo           *model.RepoConfig
-	store          store.Store
-	ghClient       github.Client
-	manager        *SyncManager // back-reference for rate limit
-	fastInterval   time.Duration
-	lastActivityAt time.Time
-	forceCh        chan syncRequest
-	stopCh         chan struct{}
-	doneCh         chan struct{} // closed when run() exits
-	status         SyncStatus
-	mu             sync.RWMutex
-	labelEnsured   bool
-}
-
-func newRepoSyncer(repo *model.RepoConfig, s store.Store, gh github.Client, mgr *SyncManager, fastInterval time.Duration) *RepoSyncer {
+	repo         *model.RepoConfig
+	store        store.Store
+	ghClient     github.Client
+	manager      *SyncManager // back-reference for rate limit
+	fastInterval time.Duration
+	// staggerInterval is the count-based interval used when the repo has
+	// no poll_interval_ms of its own.
+	staggerInterval time.Duration
+	lastActivityAt  time.Time
+	// maxInterval, if > 0, is the adaptive polling cap; emptyCycles counts
+	// the consecutive cycles that changed nothing, while the interval is
+	// still below it. Both are guarded by mu.
+	maxInterval time.Duration
+	// priorityRange, if set, bounds the priorities the syncer pulls.
+	priorityRange *[2]int
+	emptyCycles   int
+	forceCh       chan syncRequest
+	issueCh       chan int // GitHub issue numbers to pull on their own
+	stopCh        chan struct{}
+	doneCh        chan struct{} // closed when run() exits
+	status        SyncStatus
+	cycleLog      *cycleLog
+	mu            sync.RWMutex
+	labelEnsured  bool
+
+	// Set while a cycle is running; guarded by mu.
+	cycleCancel    context.CancelFunc
+	cycleStartedAt time.Time
+
+	// Per-cycle counters, reset at the start of each cycle. Only touched
+	// from the goroutine running the cycle.
+	pushedCount  int
+	pulledCount  int
+	ignoredCount int
+
+	// unmarked maps event IDs to the GitHub comment they were posted as,
+	// for events whose MarkEventSynced failed after a successful post. The
+	// next push records the comment instead of posting a duplicate. Only
+	// touched from the goroutine running the cycle.
+	unmarked map[int]int
+
+	// lastPushAt is when pushOutbound last started. Events older than it
+	// may have been posted by an earlier push that failed part way.
+	lastPushAt time.Time
+
+	// ghKnown maps GitHub issue numbers to the state and body last seen on
+	// or written to GitHub, so pushIssueState and pushIssueBody skip calls
+	// that would change nothing. Only touched from the goroutine running
+	// the cycle.
+	ghKnown map[int]ghSnapshot
+}
+
+// ghSnapshot is what the syncer last knew of a GitHub issue. Empty fields,
+// and nil assignees, are unknown.
+type ghSnapshot struct {
+	state     string
+	body      string
+	assignees []string
+}
+
+// snapshotOf records the state, body and assignees of a GitHub issue.
+func snapshotOf(ghIssue *github.GitHubIssue) ghSnapshot {
+	assignees := make([]string, 0, len(ghIssue.Assignees))
+	for _, a := range ghIssue.Assignees {
+		assignees = append(assignees, a.Login)
+	}
+	return ghSnapshot{state: ghIssue.State, body: ghIssue.Body, assignees: assignees}
+}
+
+// engineOptions returns the options the syncer applies pulled events with.
+func (rs *RepoSyncer) engineOptions() []engine.Option {
+	if rs.priorityRange == nil {
+		return nil
+	}
+	return []engine.Option{engine.WithPriorityRange(rs.priorityRange[0], rs.priorityRange[1])}
+}
+
+// newRepoSyncer creates a syncer polling at the repo's poll_interval_ms, or
+// at staggerInterval if the repo has none.
+func newRepoSyncer(repo *model.RepoConfig, s store.Store, gh github.Client, mgr *SyncManager, staggerInterval time.Duration) *RepoSyncer {
 	// Copy the repo config so the syncer owns its own copy and doesn't
 	// race with callers who hold the original pointer.
 	repoCopy := *repo
 	return &RepoSyncer{
-		repo:           &repoCopy,
-		store:          s,
-		ghClient:       gh,
-		manager:        mgr,
-		fastInterval:   fastInterval,
-		lastActivityAt: time.Now(),
-		forceCh:        make(chan syncRequest, 1),
-		stopCh:         make(chan struct{}),
-		doneCh:         make(chan struct{}),
+		repo:            &repoCopy,
+		store:           s,
+		ghClient:        gh,
+		manager:         mgr,
+		fastInterval:    pollInterval(&repoCopy, staggerInterval),
+		staggerInterval: staggerInterval,
+		lastActivityAt:  time.Now(),
+		forceCh:         make(chan syncRequest, 1),
+		issueCh:         make(chan int, issueQueueSize),
+		stopCh:          make(chan struct{}),
+		doneCh:          make(chan struct{}),
+		cycleLog:        newCycleLog(maxCycleLog),
+		unmarked:        make(map[int]int),
+		ghKnown:         make(map[int]ghSnapshot),
 		status: SyncStatus{
 			RepoName:   repoCopy.FullName(),
 			LastSyncAt: repoCopy.LastSyncAt,
@@ -238,12 +456,26 @@ func (rs *RepoSyncer) run(startDelay time.Duration) {
 	rs.cycle(false)
 
 	for {
+		// A stop that arrived during the cycle wins over a tick or force
+		// that is also ready, so no new cycle starts once stopping.
+		select {
+		case <-rs.stopCh:
+			return
+		default:
+		}
+
 		select {
 		case <-ticker.C:
 			rs.cycle(false)
 		case req := <-rs.forceCh:
+			if req.plan != nil {
+				req.plan <- rs.dryRun(req.planCtx)
+				continue
+			}
 			rs.setLastActivity() // force sync = activity
 			rs.cycle(req.full)
+		case number := <-rs.issueCh:
+			rs.syncIssue(number)
 		case <-rs.stopCh:
 			return
 		}
@@ -276,11 +508,23 @@ func (rs *RepoSyncer) force(full bool) {
 	}
 }
 
+// queueIssue asks for the GitHub issue to be pulled on its own. When too
+// many are already waiting it queues a normal cycle instead, which pulls
+// every changed issue.
+func (rs *RepoSyncer) queueIssue(number int) {
+	select {
+	case rs.issueCh <- number:
+	default:
+		rs.force(false)
+	}
+}
+
 func (rs *RepoSyncer) getStatus() SyncStatus {
 	rs.mu.RLock()
 	defer rs.mu.RUnlock()
 	st := rs.status
 	st.Idle = time.Since(rs.lastActivityAt) >= idleThreshold
+	st.PollIntervalMs = rs.intervalLocked().Milliseconds()
 	return st
 }
 
@@ -290,66 +534,180 @@ func (rs *RepoSyncer) setStatus(fn func(s *SyncStatus)) {
 	fn(&rs.status)
 }
 
+// getCycleLog returns the recent cycle results, oldest first.
+func (rs *RepoSyncer) getCycleLog() []CycleResult {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.cycleLog.list()
+}
+
+func (rs *RepoSyncer) recordCycle(r CycleResult) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.cycleLog.add(r)
+}
+
+// activeSince returns when the in-flight cycle started, if one is running.
+func (rs *RepoSyncer) activeSince() (time.Time, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.cycleStartedAt, rs.cycleCancel != nil
+}
+
+// cancelCycle cancels the in-flight cycle's context, if any.
+func (rs *RepoSyncer) cancelCycle() bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.cycleCancel == nil {
+		return false
+	}
+	rs.cycleCancel()
+	return true
+}
+
+// setLastActivity records activity now, which also ends any adaptive
+// backoff.
 func (rs *RepoSyncer) setLastActivity() {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 	rs.lastActivityAt = time.Now()
+	rs.emptyCycles = 0
+}
+
+// recordEmptyCycle stretches the adaptive interval after a cycle that
+// changed nothing. It does nothing unless adaptive polling is on, or once
+// the interval has reached its cap.
+func (rs *RepoSyncer) recordEmptyCycle() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.maxInterval > 0 && rs.intervalLocked() < rs.maxInterval {
+		rs.emptyCycles++
+	}
 }
 
+// currentInterval returns the interval the run loop should poll at.
 func (rs *RepoSyncer) currentInterval() time.Duration {
 	rs.mu.RLock()
 	defer rs.mu.RUnlock()
-	if time.Since(rs.lastActivityAt) < idleThreshold {
+	return rs.intervalLocked()
+}
+
+// intervalLocked is currentInterval for callers holding mu. In adaptive
+// mode it is the fast interval multiplied by backoffFactor for each empty
+// cycle, capped at maxInterval. Otherwise it is the fast interval while the
+// repo is active and slowInterval once idle. Either way a repo's own
+// interval is never shortened.
+func (rs *RepoSyncer) intervalLocked() time.Duration {
+	if rs.maxInterval > 0 {
+		interval := rs.fastInterval
+		for i := 0; i < rs.emptyCycles && interval < rs.maxInterval; i++ {
+			interval *= backoffFactor
+		}
+		if interval > rs.maxInterval && rs.fastInterval < rs.maxInterval {
+			interval = rs.maxInterval
+		}
+		return interval
+	}
+	if time.Since(rs.lastActivityAt) < idleThreshold || rs.fastInterval > slowInterval {
 		return rs.fastInterval
 	}
 	return slowInterval
 }
 
+// pollInterval returns the repo's poll_interval_ms as a duration, or
+// fallback if it is not set.
+func pollInterval(repo *model.RepoConfig, fallback time.Duration) time.Duration {
+	if repo.PollIntervalMs > 0 {
+		return time.Duration(repo.PollIntervalMs) * time.Millisecond
+	}
+	return fallback
+}
+
+// startCycle returns the context for a cycle starting at startedAt, which
+// SyncManager.CancelCycle can cancel to interrupt a cycle wedged on a hung
+// GitHub call. done must be called when the cycle ends.
+func (rs *RepoSyncer) startCycle(startedAt time.Time) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rs.mu.Lock()
+	rs.cycleCancel = cancel
+	rs.cycleStartedAt = startedAt
+	rs.mu.Unlock()
+	return ctx, func() {
+		rs.mu.Lock()
+		rs.cycleCancel = nil
+		rs.cycleStartedAt = time.Time{}
+		rs.mu.Unlock()
+		cancel()
+	}
+}
+
 func (rs *RepoSyncer) cycle(full bool) {
+	result := CycleResult{StartedAt: time.Now().UTC(), Full: full}
+	rs.pushedCount, rs.pulledCount, rs.ignoredCount = 0, 0, 0
+	defer func() {
+		result.Pushed = rs.pushedCount
+		result.Pulled = rs.pulledCount
+		result.Ignored = rs.ignoredCount
+		rs.recordCycle(result)
+	}()
+
 	rs.setStatus(func(s *SyncStatus) {
 		s.Syncing = true
 		s.LastError = ""
 	})
 
-	ctx := context.Background()
+	ctx, done := rs.startCycle(result.StartedAt)
+	defer done()
+
+	rs.refreshRepoSettings(ctx)
 
-	if !rs.labelEnsured {
-		rs.manager.checkRateLimit()
-		if err := rs.ghClient.CreateLabel(ctx, rs.repo.Owner, rs.repo.Name,
-			"boxofrocks", "6f42c1", "Tracked by boxofrocks"); err != nil {
-			slog.Warn("failed to ensure boxofrocks label", "repo", rs.repo.FullName(), "error", err)
+	if !rs.labelEnsured && rs.repo.PushesToGitHub() {
+		rs.manager.waitForBudget(ctx, rs.repo.ID)
+		if _, err := rs.ghClient.CreateLabel(ctx, rs.repo.Owner, rs.repo.Name,
+			rs.repo.TrackingLabel(), github.TrackingLabelColor, github.TrackingLabelDescription); err != nil {
+			slog.Warn("failed to ensure tracking label", "repo", rs.repo.FullName(), "label", rs.repo.TrackingLabel(), "error", err)
 		} else {
 			rs.labelEnsured = true
 		}
 	}
 
-	// Push outbound events first.
-	pushed, err := rs.pushOutbound(ctx)
-	if err != nil {
-		rs.setStatus(func(s *SyncStatus) {
-			s.Syncing = false
-			s.LastError = fmt.Sprintf("push: %v", err)
-		})
-		return
+	// Push outbound events first, unless the repo only pulls.
+	var pushed bool
+	var err error
+	if rs.repo.PushesToGitHub() {
+		pushed, err = rs.pushOutbound(ctx)
+		if err != nil {
+			result.Error = fmt.Sprintf("push: %v", err)
+			rs.setStatus(func(s *SyncStatus) {
+				s.Syncing = false
+				s.LastError = result.Error
+			})
+			return
+		}
 	}
 
-	// Pull inbound events.
+	// Pull inbound events, unless the repo only pushes.
 	var pulled bool
-	if full {
-		pulled, err = rs.pullInboundFull(ctx)
-	} else {
-		pulled, err = rs.pullInbound(ctx)
+	if rs.repo.PullsFromGitHub() {
+		if full {
+			pulled, err = rs.pullInboundFull(ctx)
+		} else {
+			pulled, err = rs.pullInbound(ctx)
+		}
 	}
 
 	if pushed || pulled {
 		rs.setLastActivity()
+	} else if err == nil {
+		rs.recordEmptyCycle()
 	}
 
 	now := time.Now().UTC()
 	if err != nil {
+		result.Error = fmt.Sprintf("pull: %v", err)
 		rs.setStatus(func(s *SyncStatus) {
 			s.Syncing = false
-			s.LastError = fmt.Sprintf("pull: %v", err)
+			s.LastError = result.Error
 			s.LastSyncAt = &now
 		})
 		return
@@ -361,6 +719,9 @@ func (rs *RepoSyncer) cycle(full bool) {
 		s.Syncing = false
 		s.LastSyncAt = &now
 		s.PendingEvents = len(pending)
+		if s.FirstSuccessAt == nil {
+			s.FirstSuccessAt = &now
+		}
 	})
 
 	// Persist last sync time.
@@ -368,6 +729,136 @@ func (rs *RepoSyncer) cycle(full bool) {
 	_ = rs.store.UpdateRepo(ctx, rs.repo)
 }
 
+// syncIssue pulls a single GitHub issue and its new comments, as a webhook
+// delivery for it asks, instead of listing every tracked issue. An issue
+// without the tracking label is ignored. If the pull fails, a normal cycle
+// is queued to pick the change up.
+func (rs *RepoSyncer) syncIssue(number int) {
+	ctx, done := rs.startCycle(time.Now().UTC())
+	defer done()
+
+	rs.refreshRepoSettings(ctx)
+	if !rs.repo.PullsFromGitHub() {
+		return
+	}
+
+	rs.manager.waitForBudget(ctx, rs.repo.ID)
+	ghIssue, err := rs.ghClient.GetIssue(ctx, rs.repo.Owner, rs.repo.Name, number)
+	if err == nil && !ghIssue.HasLabel(rs.repo.TrackingLabel()) {
+		return
+	}
+	if err == nil {
+		err = rs.processGitHubIssue(ctx, ghIssue, false)
+	}
+	if err != nil {
+		slog.Warn("issue sync failed, falling back to a full cycle",
+			"repo", rs.repo.FullName(), "issue", number, "error", err)
+		rs.force(false)
+		return
+	}
+	rs.setLastActivity()
+}
+
+// refreshRepoSettings reloads user-editable repo settings from the store so
+// that changes made via PATCH /repos apply from the next cycle, and are not
+// overwritten when the cycle persists the syncer's copy of the repo.
+func (rs *RepoSyncer) refreshRepoSettings(ctx context.Context) {
+	fresh, err := rs.store.GetRepo(ctx, rs.repo.ID)
+	if err != nil {
+		slog.Warn("failed to reload repo settings", "repo", rs.repo.FullName(), "error", err)
+		return
+	}
+	rs.repo.TrustedAuthorsOnly = fresh.TrustedAuthorsOnly
+	rs.repo.TrustedAuthors = fresh.TrustedAuthors
+	rs.repo.AllowedInboundActions = fresh.AllowedInboundActions
+	rs.repo.IssueTypes = fresh.IssueTypes
+	rs.repo.EpicRollup = fresh.EpicRollup
+	rs.repo.NextStrategy = fresh.NextStrategy
+	rs.repo.SyncDirection = fresh.SyncDirection
+	rs.repo.IngestHumanComments = fresh.IngestHumanComments
+	rs.repo.AssigneeLogins = fresh.AssigneeLogins
+	rs.repo.ValidateAssignee = fresh.ValidateAssignee
+	rs.repo.IssueTemplates = fresh.IssueTemplates
+	rs.repo.KeyPrefix = fresh.KeyPrefix
+	if fresh.TrackingLabel() != rs.repo.TrackingLabel() {
+		// A new label is a different issue query: ensure the label exists
+		// and drop the cached ETag and since bound of the old query.
+		rs.repo.Label = fresh.Label
+		rs.repo.IssuesETag = ""
+		rs.repo.IssuesSince = ""
+		rs.labelEnsured = false
+	}
+
+	// The run loop resets its ticker after this cycle if the interval moved.
+	rs.repo.PollIntervalMs = fresh.PollIntervalMs
+	rs.mu.Lock()
+	rs.fastInterval = pollInterval(rs.repo, rs.staggerInterval)
+	rs.mu.Unlock()
+}
+
+// humanCommentEvent turns a plain GitHub comment into a comment event
+// attributed to its author, when the repo ingests human comments. It returns
+// nil otherwise, and for empty bodies and epic rollup checklist lines.
+func (rs *RepoSyncer) humanCommentEvent(c *github.GitHubComment) *model.Event {
+	if !rs.repo.IngestHumanComments {
+		return nil
+	}
+	body := strings.TrimSpace(c.Body)
+	if body == "" || github.IsRollupComment(body) {
+		return nil
+	}
+	payload, err := json.Marshal(model.EventPayload{Comment: body})
+	if err != nil {
+		return nil
+	}
+	author := c.User.Login
+	if author == "" {
+		author = "github"
+	}
+	return &model.Event{
+		Timestamp: c.CreatedAt.UTC(),
+		Action:    model.ActionComment,
+		Payload:   string(payload),
+		Agent:     author,
+	}
+}
+
+// allowInbound reports whether an event parsed from a GitHub comment may be
+// applied under the repo's allowed_inbound_actions. Rejected events are
+// logged and counted in the cycle result, but not stored or applied.
+func (rs *RepoSyncer) allowInbound(ev *model.Event, ghIssueNumber, commentID int) bool {
+	if rs.repo.AllowsInboundAction(ev.Action) {
+		return true
+	}
+	slog.Warn("ignoring inbound event: action not allowed",
+		"repo", rs.repo.FullName(),
+		"github_number", ghIssueNumber,
+		"comment_id", commentID,
+		"action", ev.Action,
+		"agent", ev.Agent)
+	rs.ignoredCount++
+	return false
+}
+
+// dropLocalLinks removes parent_id and blocker_id from an inbound event's
+// payload. Both are local issue IDs, which mean nothing to another daemon.
+func dropLocalLinks(ev *model.Event) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(ev.Payload), &payload); err != nil {
+		return
+	}
+	_, hasParent := payload["parent_id"]
+	_, hasBlocker := payload["blocker_id"]
+	if !hasParent && !hasBlocker {
+		return
+	}
+	delete(payload, "parent_id")
+	delete(payload, "blocker_id")
+	if b, err := json.Marshal(payload); err == nil {
+		ev.Payload = string(b)
+	}
+}
+
 // pushOutbound sends locally-created events to GitHub.
 // Returns true if any events were pushed.
 func (rs *RepoSyncer) pushOutbound(ctx context.Context) (bool, error) {
@@ -380,8 +871,24 @@ func (rs *RepoSyncer) pushOutbound(ctx context.Context) (bool, error) {
 		return false, nil
 	}
 
+	var stale pushStale
+
+	prevPush := rs.lastPushAt
+	rs.lastPushAt = time.Now().UTC()
+	recent := make(recentComments)
+
 	for _, ev := range pending {
-		rs.manager.checkRateLimit()
+		if commentID, ok := rs.unmarked[ev.ID]; ok {
+			// Already on GitHub; only the local record is missing.
+			if err := rs.store.MarkEventSynced(ctx, ev.ID, commentID); err != nil {
+				return false, fmt.Errorf("mark event synced: %w", err)
+			}
+			delete(rs.unmarked, ev.ID)
+			stale.mark(ev.IssueID, ev.Action)
+			continue
+		}
+
+		rs.manager.waitForBudget(ctx, rs.repo.ID)
 
 		issue, err := rs.store.GetIssue(ctx, ev.IssueID)
 		if err != nil {
@@ -396,7 +903,7 @@ func (rs *RepoSyncer) pushOutbound(ctx context.Context) (bool, error) {
 				rs.repo.Name,
 				issue.Title,
 				issue.Description,
-				append([]string{"boxofrocks"}, issue.Labels...),
+				append([]string{rs.repo.TrackingLabel()}, issue.Labels...),
 			)
 			if err != nil {
 				return false, fmt.Errorf("create github issue: %w", err)
@@ -404,21 +911,31 @@ func (rs *RepoSyncer) pushOutbound(ctx context.Context) (bool, error) {
 
 			// Store the GitHub issue number on the local issue.
 			issue.GitHubID = &ghIssue.Number
+			known := snapshotOf(ghIssue)
+			known.state = "open"
+			rs.ghKnown[ghIssue.Number] = known
 			if err := rs.store.UpdateIssue(ctx, issue); err != nil {
 				return false, fmt.Errorf("update issue github_id: %w", err)
 			}
 
-			// Post the create event as the first comment.
-			rs.manager.checkRateLimit()
-			commentBody := github.FormatEventComment(ev)
-			ghComment, err := rs.ghClient.CreateComment(ctx, rs.repo.Owner, rs.repo.Name, ghIssue.Number, commentBody)
+			// Post the create event as the first comment. The GitHub issue
+			// is brand new, so there is nothing to check for duplicates.
+			commentID, err := rs.postEventComment(ctx, ev, ghIssue.Number, nil)
 			if err != nil {
 				return false, fmt.Errorf("create initial comment: %w", err)
 			}
 
-			if err := rs.store.MarkEventSynced(ctx, ev.ID, ghComment.ID); err != nil {
+			if err := rs.markSynced(ctx, ev.ID, commentID); err != nil {
 				return false, fmt.Errorf("mark event synced: %w", err)
 			}
+			stale.mark(issue.ID, ev.Action)
+
+			if rs.repo.EpicRollup && issue.ParentID != nil {
+				if err := rs.rollupToParent(ctx, issue); err != nil {
+					slog.Warn("failed to post epic rollup",
+						"repo", rs.repo.FullName(), "issue_id", issue.ID, "error", err)
+				}
+			}
 		} else {
 			// Post event as a comment on the existing GitHub issue.
 			if issue.GitHubID == nil {
@@ -426,32 +943,356 @@ func (rs *RepoSyncer) pushOutbound(ctx context.Context) (bool, error) {
 				continue
 			}
 
-			rs.manager.checkRateLimit()
-			commentBody := github.FormatEventComment(ev)
-			ghComment, err := rs.ghClient.CreateComment(ctx, rs.repo.Owner, rs.repo.Name, *issue.GitHubID, commentBody)
+			var verify recentComments
+			if ev.Timestamp.Before(prevPush) {
+				verify = recent
+			}
+			commentID, err := rs.postEventComment(ctx, ev, *issue.GitHubID, verify)
 			if err != nil {
 				return false, fmt.Errorf("create comment for event %d: %w", ev.ID, err)
 			}
 
-			if err := rs.store.MarkEventSynced(ctx, ev.ID, ghComment.ID); err != nil {
+			if err := rs.markSynced(ctx, ev.ID, commentID); err != nil {
 				return false, fmt.Errorf("mark event synced: %w", err)
 			}
+
+			stale.mark(issue.ID, ev.Action)
+		}
+	}
+
+	// The events are already synced at this point, so a failed body rewrite
+	// is logged rather than failing the cycle; the next field change (or the
+	// arbiter) renders the body again.
+	for _, issueID := range stale.body.ids {
+		if err := rs.pushIssueBody(ctx, issueID); err != nil {
+			slog.Warn("failed to update github issue body",
+				"repo", rs.repo.FullName(), "issue_id", issueID, "error", err)
+		}
+	}
+	for _, issueID := range stale.state.ids {
+		if err := rs.pushIssueState(ctx, issueID); err != nil {
+			slog.Warn("failed to update github issue state",
+				"repo", rs.repo.FullName(), "issue_id", issueID, "error", err)
+		}
+	}
+	for _, issueID := range stale.assignee.ids {
+		if err := rs.pushIssueAssignee(ctx, issueID); err != nil {
+			slog.Warn("failed to update github issue assignees",
+				"repo", rs.repo.FullName(), "issue_id", issueID, "error", err)
 		}
 	}
 
 	return true, nil
 }
 
+// pushStale collects the issues whose GitHub body, open/closed state or
+// assignees may no longer match once their events are pushed.
+type pushStale struct {
+	body, state, assignee staleIssues
+}
+
+func (p *pushStale) mark(issueID int, action model.Action) {
+	if rewritesBody(action) {
+		p.body.add(issueID)
+	}
+	if changesState(action) {
+		p.state.add(issueID)
+	}
+	if changesAssignee(action) {
+		p.assignee.add(issueID)
+	}
+}
+
+// staleIssues collects issue IDs once each, in the order first added.
+type staleIssues struct {
+	ids  []int
+	seen map[int]bool
+}
+
+func (s *staleIssues) add(issueID int) {
+	if s.seen[issueID] {
+		return
+	}
+	if s.seen == nil {
+		s.seen = make(map[int]bool)
+	}
+	s.seen[issueID] = true
+	s.ids = append(s.ids, issueID)
+}
+
+// recentComments caches GitHub comments per issue number for one push.
+type recentComments map[int][]*github.GitHubComment
+
+// postEventComment posts ev as a comment on GitHub issue ghNumber and returns
+// the comment ID. Each posted body is recorded by hash, so an event that was
+// posted but never marked synced is not posted again. If verify is non-nil,
+// the issue's recent GitHub comments are also searched for an unclaimed
+// comment with the same body; this covers a push that failed before the hash
+// was recorded. pushedCount only counts comments actually posted.
+func (rs *RepoSyncer) postEventComment(ctx context.Context, ev *model.Event, ghNumber int, verify recentComments) (int, error) {
+	body := github.FormatEventComment(ev)
+	hash := github.CommentHash(body)
+
+	commentID, err := rs.store.PostedCommentID(ctx, rs.repo.ID, ghNumber, hash, ev.ID)
+	if err != nil {
+		return 0, fmt.Errorf("look up posted comment: %w", err)
+	}
+	if commentID != 0 {
+		return commentID, nil
+	}
+
+	if verify != nil {
+		commentID, err := rs.findPostedComment(ctx, ev, ghNumber, hash, verify)
+		if err != nil {
+			return 0, err
+		}
+		if commentID != 0 {
+			rs.recordPostedComment(ctx, ghNumber, hash, ev.ID, commentID)
+			return commentID, nil
+		}
+	}
+
+	rs.manager.waitForBudget(ctx, rs.repo.ID)
+	ghComment, err := rs.ghClient.CreateComment(ctx, rs.repo.Owner, rs.repo.Name, ghNumber, body)
+	if err != nil {
+		return 0, err
+	}
+	rs.pushedCount++
+	rs.recordPostedComment(ctx, ghNumber, hash, ev.ID, ghComment.ID)
+	return ghComment.ID, nil
+}
+
+// findPostedComment looks for a comment on GitHub issue ghNumber, created no
+// earlier than ev, whose body hashes to hash and that no local event claims.
+func (rs *RepoSyncer) findPostedComment(ctx context.Context, ev *model.Event, ghNumber int, hash string, cache recentComments) (int, error) {
+	comments, ok := cache[ghNumber]
+	if !ok {
+		// Pending events are oldest first, so the first lookup per issue
+		// fetches everything later events could match too.
+		rs.manager.waitForBudget(ctx, rs.repo.ID)
+		var err error
+		comments, _, err = rs.ghClient.ListComments(ctx, rs.repo.Owner, rs.repo.Name, ghNumber,
+			github.ListOpts{Since: ev.Timestamp.UTC().Format(time.RFC3339)})
+		if err != nil {
+			return 0, fmt.Errorf("list comments on github issue %d: %w", ghNumber, err)
+		}
+		cache[ghNumber] = comments
+	}
+	for _, c := range comments {
+		if github.CommentHash(c.Body) == hash && !rs.hasGitHubComment(ctx, ev.IssueID, c.ID) {
+			return c.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+// recordPostedComment saves a posted comment's hash. A failure is logged:
+// the comment is already on GitHub, and markSynced still guards the retry.
+func (rs *RepoSyncer) recordPostedComment(ctx context.Context, ghNumber int, hash string, eventID, commentID int) {
+	if err := rs.store.RecordPostedComment(ctx, rs.repo.ID, ghNumber, hash, eventID, commentID); err != nil {
+		slog.Warn("failed to record posted comment",
+			"repo", rs.repo.FullName(), "event_id", eventID, "comment_id", commentID, "error", err)
+	}
+}
+
+// markSynced records a posted event as synced. If that fails, the comment ID
+// is remembered in rs.unmarked so the retry does not post the event again.
+func (rs *RepoSyncer) markSynced(ctx context.Context, eventID, commentID int) error {
+	if err := rs.store.MarkEventSynced(ctx, eventID, commentID); err != nil {
+		rs.unmarked[eventID] = commentID
+		return err
+	}
+	return nil
+}
+
+// rollupToParent posts a checklist item for a newly pushed child issue on its
+// parent's GitHub issue, giving the epic a task list that GitHub renders with
+// the child's live state. The comment is plain markdown, not an event, so it
+// is skipped on pull. A parent not yet on GitHub gets no rollup.
+func (rs *RepoSyncer) rollupToParent(ctx context.Context, child *model.Issue) error {
+	parent, err := rs.store.GetIssue(ctx, *child.ParentID)
+	if err != nil {
+		return fmt.Errorf("get parent issue %d: %w", *child.ParentID, err)
+	}
+	if parent.GitHubID == nil {
+		return nil
+	}
+
+	rs.manager.waitForBudget(ctx, rs.repo.ID)
+	body := github.FormatRollupComment(*child.GitHubID, child.Title)
+	if _, err := rs.ghClient.CreateComment(ctx, rs.repo.Owner, rs.repo.Name, *parent.GitHubID, body); err != nil {
+		return fmt.Errorf("comment on github issue %d: %w", *parent.GitHubID, err)
+	}
+	return nil
+}
+
+// rewritesBody reports whether an event changes a field rendered into the
+// GitHub issue body (the description or the metadata block).
+func rewritesBody(action model.Action) bool {
+	switch action {
+	case model.ActionUpdate, model.ActionStatusChange, model.ActionAssign,
+		model.ActionClose, model.ActionReopen, model.ActionDelete, model.ActionRestore,
+		model.ActionLabelAdd, model.ActionLabelRemove, model.ActionPriorityChange:
+		return true
+	}
+	return false
+}
+
+// changesState reports whether an event of this action can move an issue
+// between open and closed.
+func changesState(action model.Action) bool {
+	switch action {
+	case model.ActionStatusChange, model.ActionClose, model.ActionReopen, model.ActionDelete, model.ActionRestore:
+		return true
+	}
+	return false
+}
+
+// pushIssueState closes or reopens the GitHub issue to match the local
+// status; deleted issues are closed, as the arbiter does. Without this the
+// next pull would see the stale GitHub state and undo the local change.
+func (rs *RepoSyncer) pushIssueState(ctx context.Context, issueID int) error {
+	issue, err := rs.store.GetIssue(ctx, issueID)
+	if err != nil {
+		return fmt.Errorf("get issue %d: %w", issueID, err)
+	}
+	if issue.GitHubID == nil {
+		return nil
+	}
+
+	want := githubState(issue)
+	known := rs.ghKnown[*issue.GitHubID]
+	if known.state == want {
+		return nil
+	}
+
+	rs.manager.waitForBudget(ctx, rs.repo.ID)
+	if err := rs.ghClient.UpdateIssueState(ctx, rs.repo.Owner, rs.repo.Name, *issue.GitHubID, want); err != nil {
+		return fmt.Errorf("set state of github issue %d: %w", *issue.GitHubID, err)
+	}
+	known.state = want
+	rs.ghKnown[*issue.GitHubID] = known
+	return nil
+}
+
+// githubState returns the GitHub state matching the issue's local status.
+func githubState(issue *model.Issue) string {
+	if issue.Status == model.StatusClosed || issue.Status == model.StatusDeleted {
+		return "closed"
+	}
+	return "open"
+}
+
+// changesAssignee reports whether an event of this action can set the
+// issue's owner.
+func changesAssignee(action model.Action) bool {
+	return action == model.ActionCreate || action == model.ActionAssign
+}
+
+// pushIssueAssignee assigns the GitHub issue to the login the local owner
+// maps to under the repo's assignee_logins, and unassigns the other mapped
+// logins. Assignees outside the mapping, such as people assigned on the
+// web, are left alone.
+func (rs *RepoSyncer) pushIssueAssignee(ctx context.Context, issueID int) error {
+	if len(rs.repo.AssigneeLogins) == 0 {
+		return nil
+	}
+	issue, err := rs.store.GetIssue(ctx, issueID)
+	if err != nil {
+		return fmt.Errorf("get issue %d: %w", issueID, err)
+	}
+	if issue.GitHubID == nil {
+		return nil
+	}
+	ghNumber := *issue.GitHubID
+
+	want := rs.repo.GitHubLogin(issue.Owner)
+	known := rs.ghKnown[ghNumber]
+	remove, add := rs.assigneeChanges(known, want)
+
+	if len(remove) > 0 {
+		rs.manager.waitForBudget(ctx, rs.repo.ID)
+		if err := rs.ghClient.RemoveAssignees(ctx, rs.repo.Owner, rs.repo.Name, ghNumber, remove); err != nil {
+			return fmt.Errorf("unassign github issue %d: %w", ghNumber, err)
+		}
+		if known.assignees != nil {
+			known.assignees = slices.DeleteFunc(known.assignees, func(a string) bool {
+				return slices.ContainsFunc(remove, func(r string) bool { return strings.EqualFold(a, r) })
+			})
+		}
+	}
+	if add {
+		rs.manager.waitForBudget(ctx, rs.repo.ID)
+		if err := rs.ghClient.AddAssignees(ctx, rs.repo.Owner, rs.repo.Name, ghNumber, []string{want}); err != nil {
+			return fmt.Errorf("assign github issue %d: %w", ghNumber, err)
+		}
+		if known.assignees != nil {
+			known.assignees = append(known.assignees, want)
+		}
+	}
+	rs.ghKnown[ghNumber] = known
+	return nil
+}
+
+// assigneeChanges returns the mapped logins to unassign from a GitHub issue
+// last seen as known, and whether want must be assigned.
+func (rs *RepoSyncer) assigneeChanges(known ghSnapshot, want string) (remove []string, add bool) {
+	// Unknown assignees: remove every other mapped login and add want;
+	// GitHub ignores logins that are already in the requested state.
+	assigned := func(login string) bool {
+		return known.assignees == nil || slices.ContainsFunc(known.assignees, func(a string) bool {
+			return strings.EqualFold(a, login)
+		})
+	}
+
+	for _, login := range rs.repo.AssigneeLogins {
+		if !strings.EqualFold(login, want) && assigned(login) && !slices.Contains(remove, login) {
+			remove = append(remove, login)
+		}
+	}
+	slices.Sort(remove)
+	add = want != "" && (known.assignees == nil || !assigned(want))
+	return remove, add
+}
+
+// pushIssueBody rewrites the GitHub issue body from the local issue so the
+// human text matches the current description and the metadata block matches
+// the current state. Event comments remain the source of truth; the body is
+// a rendered view of them. A body that already matches is not written again.
+func (rs *RepoSyncer) pushIssueBody(ctx context.Context, issueID int) error {
+	issue, err := rs.store.GetIssue(ctx, issueID)
+	if err != nil {
+		return fmt.Errorf("get issue %d: %w", issueID, err)
+	}
+	if issue.GitHubID == nil {
+		return nil
+	}
+
+	body := github.RenderBody(issue.Description, github.IssueMetadata(issue))
+	known := rs.ghKnown[*issue.GitHubID]
+	if known.body == body {
+		return nil
+	}
+
+	rs.manager.waitForBudget(ctx, rs.repo.ID)
+	if err := rs.ghClient.UpdateIssueBody(ctx, rs.repo.Owner, rs.repo.Name, *issue.GitHubID, body); err != nil {
+		return fmt.Errorf("update body of github issue %d: %w", *issue.GitHubID, err)
+	}
+	known.body = body
+	rs.ghKnown[*issue.GitHubID] = known
+	return nil
+}
+
 // pullInbound fetches new comments from GitHub and applies them incrementally.
 // Returns true if issues were returned (i.e. not a 304 Not Modified).
 func (rs *RepoSyncer) pullInbound(ctx context.Context) (bool, error) {
-	rs.manager.checkRateLimit()
+	rs.manager.waitForBudget(ctx, rs.repo.ID)
 
-	// List GitHub issues with boxofrocks label.
+	// List GitHub issues with the repo's tracking label.
 	issues, newETag, err := rs.ghClient.ListIssues(ctx, rs.repo.Owner, rs.repo.Name, github.ListOpts{
 		ETag:   rs.repo.IssuesETag,
 		Since:  rs.repo.IssuesSince,
-		Labels: "boxofrocks",
+		Labels: rs.repo.TrackingLabel(),
 	})
 	if err != nil {
 		return false, fmt.Errorf("list issues: %w", err)
@@ -488,10 +1329,10 @@ func (rs *RepoSyncer) pullInbound(ctx context.Context) (bool, error) {
 // pullInboundFull fetches all comments and uses full replay.
 // Returns true if issues were returned (i.e. not a 304 Not Modified).
 func (rs *RepoSyncer) pullInboundFull(ctx context.Context) (bool, error) {
-	rs.manager.checkRateLimit()
+	rs.manager.waitForBudget(ctx, rs.repo.ID)
 
 	issues, newETag, err := rs.ghClient.ListIssues(ctx, rs.repo.Owner, rs.repo.Name, github.ListOpts{
-		Labels: "boxofrocks",
+		Labels: rs.repo.TrackingLabel(),
 	})
 	if err != nil {
 		return false, fmt.Errorf("list issues (full): %w", err)
@@ -514,6 +1355,8 @@ func (rs *RepoSyncer) pullInboundFull(ctx context.Context) (bool, error) {
 
 // processGitHubIssue handles a single GitHub issue, syncing comments locally.
 func (rs *RepoSyncer) processGitHubIssue(ctx context.Context, ghIssue *github.GitHubIssue, full bool) error {
+	rs.ghKnown[ghIssue.Number] = snapshotOf(ghIssue)
+
 	// Find the local issue with this GitHub ID.
 	localIssue := rs.findLocalIssueByGitHubID(ctx, ghIssue.Number)
 
@@ -532,28 +1375,55 @@ func (rs *RepoSyncer) processGitHubIssue(ctx context.Context, ghIssue *github.Gi
 		return fmt.Errorf("get sync state: %w", err)
 	}
 
-	// Build list opts: if not full, only fetch comments since last sync.
+	commentsETag, err := rs.store.GetCommentsETag(ctx, rs.repo.ID, ghIssue.Number)
+	if err != nil {
+		return fmt.Errorf("get comments etag: %w", err)
+	}
+
+	// Build list opts: if not full, only fetch comments since last sync,
+	// and only if they changed since the last listing. A full replay needs
+	// every comment, so it never sends the ETag.
 	opts := github.ListOpts{}
-	if !full && lastCommentAt != "" {
-		opts.Since = lastCommentAt
+	if !full {
+		if lastCommentAt != "" {
+			opts.Since = lastCommentAt
+		}
+		opts.ETag = commentsETag
 	}
 
-	rs.manager.checkRateLimit()
-	comments, _, err := rs.ghClient.ListComments(ctx, rs.repo.Owner, rs.repo.Name, ghIssue.Number, opts)
+	rs.manager.waitForBudget(ctx, rs.repo.ID)
+	comments, newETag, err := rs.ghClient.ListComments(ctx, rs.repo.Owner, rs.repo.Name, ghIssue.Number, opts)
 	if err != nil {
 		return fmt.Errorf("list comments: %w", err)
 	}
+	if newETag != commentsETag {
+		if err := rs.store.SetCommentsETag(ctx, rs.repo.ID, ghIssue.Number, newETag); err != nil {
+			return fmt.Errorf("set comments etag: %w", err)
+		}
+	}
 
-	// Filter out untrusted author comments when TrustedAuthorsOnly is enabled.
+	// A 304 Not Modified returns nil comments: nothing new to process, but
+	// the issue itself changed, so its open/closed state still reconciles.
+	if comments == nil && opts.ETag != "" && newETag == opts.ETag {
+		if err := rs.reconcileGitHubState(ctx, localIssue, ghIssue); err != nil {
+			return fmt.Errorf("reconcile state: %w", err)
+		}
+		return nil
+	}
+
+	// Filter out untrusted author comments when TrustedAuthorsOnly is
+	// enabled. A comment is trusted by its author association, or by its
+	// author being the repo owner or a listed trusted author.
 	if rs.repo.TrustedAuthorsOnly {
 		trusted := make([]*github.GitHubComment, 0, len(comments))
 		for _, c := range comments {
-			if github.IsTrustedAuthor(c.AuthorAssociation) {
+			if github.IsTrustedAuthor(c.AuthorAssociation) || rs.repo.TrustsLogin(c.User.Login) {
 				trusted = append(trusted, c)
-			} else if c.AuthorAssociation != "" {
+			} else {
 				slog.Debug("skipping comment from untrusted author",
 					"repo", rs.repo.FullName(),
 					"comment_id", c.ID,
+					"author", c.User.Login,
 					"author_association", c.AuthorAssociation)
 			}
 		}
@@ -574,9 +1444,15 @@ func (rs *RepoSyncer) processGitHubIssue(ctx context.Context, ghIssue *github.Gi
 
 			ev, err := github.ParseEventComment(c.Body)
 			if err != nil || ev == nil {
-				// Not a boxofrocks comment; skip.
+				// Not a boxofrocks comment; skip unless ingesting humans.
+				if ev = rs.humanCommentEvent(c); ev == nil {
+					continue
+				}
+			}
+			if !rs.allowInbound(ev, ghIssue.Number, c.ID) {
 				continue
 			}
+			dropLocalLinks(ev)
 
 			// Check if we already have this comment in our events.
 			if rs.hasGitHubComment(ctx, localIssue.ID, c.ID) {
@@ -592,18 +1468,22 @@ func (rs *RepoSyncer) processGitHubIssue(ctx context.Context, ghIssue *github.Gi
 			ev.GitHubIssueNumber = &ghIssueNum
 			ev.Synced = 1
 
-			updated, err := engine.Apply(localIssue, ev)
+			updated, err := engine.Apply(localIssue, ev, rs.engineOptions()...)
 			if err != nil {
 				return fmt.Errorf("apply event from comment %d: %w", c.ID, err)
 			}
-			localIssue = updated
 
-			if err := rs.store.UpdateIssue(ctx, localIssue); err != nil {
-				return fmt.Errorf("update issue: %w", err)
-			}
-
-			if _, err := rs.store.AppendEvent(ctx, ev); err != nil {
-				return fmt.Errorf("append event: %w", err)
+			err = rs.store.UpdateIssuesWithEvents(ctx, []store.IssueChange{{Issue: updated, Event: ev}})
+			switch {
+			case errors.Is(err, store.ErrDuplicateEvent):
+				// Another copy of an event already applied, as when a
+				// push was retried and posted twice.
+				slog.Debug("skipping duplicate event comment", "repo", rs.repo.FullName(), "comment_id", c.ID)
+			case err != nil:
+				return fmt.Errorf("store event: %w", err)
+			default:
+				localIssue = updated
+				rs.pulledCount++
 			}
 
 			lastCommentID = c.ID
@@ -642,11 +1522,15 @@ func (rs *RepoSyncer) processGitHubIssue(ctx context.Context, ghIssue *github.Gi
 // reconcileGitHubState detects when the GitHub issue state (open/closed) diverges
 // from the local issue status and generates a synthetic close or reopen event.
 // This handles cases where someone closes/reopens an issue via GitHub's UI
-// without a [boxofrocks] comment.
+// without a [boxofrocks] comment. Issues with local events not yet pushed are
+// skipped: local state is ahead, and the push will bring GitHub in line.
 func (rs *RepoSyncer) reconcileGitHubState(ctx context.Context, localIssue *model.Issue, ghIssue *github.GitHubIssue) error {
 	if engine.IsTerminal(localIssue.Status) {
 		return nil // deleted issues are never reconciled
 	}
+	if rs.hasPendingEvents(ctx, localIssue.ID) {
+		return nil
+	}
 
 	now := time.Now().UTC()
 	ghIssueNum := ghIssue.Number
@@ -672,7 +1556,7 @@ func (rs *RepoSyncer) reconcileGitHubState(ctx context.Context, localIssue *mode
 			Synced:            1, // originated from GitHub
 		}
 
-		updated, err := engine.Apply(localIssue, ev)
+		updated, err := engine.Apply(localIssue, ev, rs.engineOptions()...)
 		if err != nil {
 			return fmt.Errorf("apply close: %w", err)
 		}
@@ -683,6 +1567,7 @@ func (rs *RepoSyncer) reconcileGitHubState(ctx context.Context, localIssue *mode
 		if _, err := rs.store.AppendEvent(ctx, ev); err != nil {
 			return fmt.Errorf("append close event: %w", err)
 		}
+		rs.pulledCount++
 
 		slog.Info("reconciled GitHub close", "repo", rs.repo.FullName(), "issue", localIssue.ID, "github_number", ghIssue.Number)
 
@@ -698,7 +1583,7 @@ func (rs *RepoSyncer) reconcileGitHubState(ctx context.Context, localIssue *mode
 			Synced:            1,
 		}
 
-		updated, err := engine.Apply(localIssue, ev)
+		updated, err := engine.Apply(localIssue, ev, rs.engineOptions()...)
 		if err != nil {
 			return fmt.Errorf("apply reopen: %w", err)
 		}
@@ -709,6 +1594,7 @@ func (rs *RepoSyncer) reconcileGitHubState(ctx context.Context, localIssue *mode
 		if _, err := rs.store.AppendEvent(ctx, ev); err != nil {
 			return fmt.Errorf("append reopen event: %w", err)
 		}
+		rs.pulledCount++
 
 		slog.Info("reconciled GitHub reopen", "repo", rs.repo.FullName(), "issue", localIssue.ID, "github_number", ghIssue.Number)
 	}
@@ -729,8 +1615,8 @@ func (rs *RepoSyncer) fullReplayComments(ctx context.Context, localIssue *model.
 	// Build a set of known github_comment_ids.
 	knownComments := make(map[int]bool, len(existing))
 	for _, e := range existing {
-		if e.GitHubCommentID != nil {
-			knownComments[*e.GitHubCommentID] = true
+		for _, id := range eventCommentIDs(e) {
+			knownComments[id] = true
 		}
 	}
 
@@ -743,8 +1629,14 @@ func (rs *RepoSyncer) fullReplayComments(ctx context.Context, localIssue *model.
 
 		ev, err := github.ParseEventComment(c.Body)
 		if err != nil || ev == nil {
+			if ev = rs.humanCommentEvent(c); ev == nil {
+				continue
+			}
+		}
+		if !rs.allowInbound(ev, ghIssueNumber, c.ID) {
 			continue
 		}
+		dropLocalLinks(ev)
 
 		ev.RepoID = rs.repo.ID
 		ev.IssueID = localIssue.ID
@@ -754,16 +1646,20 @@ func (rs *RepoSyncer) fullReplayComments(ctx context.Context, localIssue *model.
 		ev.GitHubIssueNumber = &ghIssueNum
 		ev.Synced = 1
 
+		// Persist the new event. A copy of one already stored is left to
+		// the replay, which skips it too.
 		events = append(events, ev)
-
-		// Persist the new event.
 		if _, err := rs.store.AppendEvent(ctx, ev); err != nil {
+			if errors.Is(err, store.ErrDuplicateEvent) {
+				continue
+			}
 			return fmt.Errorf("append event: %w", err)
 		}
+		rs.pulledCount++
 	}
 
 	// Replay all events.
-	issueMap, err := engine.Replay(events)
+	issueMap, err := engine.Replay(events, rs.engineOptions()...)
 	if err != nil {
 		return fmt.Errorf("replay: %w", err)
 	}
@@ -804,6 +1700,9 @@ func (rs *RepoSyncer) handleWebCreatedIssue(ctx context.Context, ghIssue *github
 			localIssue.Status = model.Status(meta.Status)
 		}
 		localIssue.Priority = meta.Priority
+		if r := rs.priorityRange; r != nil {
+			localIssue.Priority = engine.ClampPriority(meta.Priority, r[0], r[1])
+		}
 		if meta.IssueType != "" {
 			localIssue.IssueType = model.IssueType(meta.IssueType)
 		}
@@ -815,26 +1714,64 @@ func (rs *RepoSyncer) handleWebCreatedIssue(ctx context.Context, ghIssue *github
 		localIssue.Description = ghIssue.Body
 	}
 
+	closedOnGitHub := ghIssue.State == "closed"
+	if closedOnGitHub && localIssue.Status == model.StatusClosed && ghIssue.ClosedAt != nil {
+		closedAt := ghIssue.ClosedAt.UTC()
+		localIssue.ClosedAt = &closedAt
+	}
+
 	created, err := rs.store.CreateIssue(ctx, localIssue)
 	if err != nil {
 		return nil, fmt.Errorf("create local issue: %w", err)
 	}
 
 	// Generate and persist synthetic create event.
-	syntheticEvent := GenerateSyntheticCreate(ghIssue, rs.repo.ID, created.ID)
-	syntheticEvent.Synced = 1 // It came from GitHub, so it is already synced.
+	syntheticEvent := GenerateSyntheticCreate(ghIssue, rs.repo.ID, created.ID, rs.repo.TrackingLabel())
+	if err := rs.postSyntheticEvent(ctx, ghIssue.Number, syntheticEvent); err != nil {
+		return nil, fmt.Errorf("synthetic create: %w", err)
+	}
+
+	// An issue already closed on GitHub is closed locally too, as of
+	// GitHub's closed_at.
+	if closedOnGitHub && created.Status != model.StatusClosed {
+		closeEvent := GenerateSyntheticClose(ghIssue, rs.repo.ID, created.ID)
+		if err := rs.postSyntheticEvent(ctx, ghIssue.Number, closeEvent); err != nil {
+			return nil, fmt.Errorf("synthetic close: %w", err)
+		}
+		closed, err := engine.Apply(created, closeEvent, rs.engineOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("apply synthetic close: %w", err)
+		}
+		if err := rs.store.UpdateIssue(ctx, closed); err != nil {
+			return nil, fmt.Errorf("update closed issue: %w", err)
+		}
+		created = closed
+	}
+
+	return created, nil
+}
+
+// postSyntheticEvent stores an event generated from GitHub issue state as
+// already synced, and posts it as a comment so other syncers can see it.
+func (rs *RepoSyncer) postSyntheticEvent(ctx context.Context, ghNumber int, ev *model.Event) error {
+	ev.Synced = 1 // It came from GitHub, so it is already synced.
 
-	storedEvent, err := rs.store.AppendEvent(ctx, syntheticEvent)
+	storedEvent, err := rs.store.AppendEvent(ctx, ev)
 	if err != nil {
-		return nil, fmt.Errorf("append synthetic create: %w", err)
+		return fmt.Errorf("append: %w", err)
 	}
+	rs.pulledCount++
 
-	// Post the create event as a comment on GitHub so other syncers can see it.
-	rs.manager.checkRateLimit()
-	commentBody := github.FormatEventComment(syntheticEvent)
-	ghComment, err := rs.ghClient.CreateComment(ctx, rs.repo.Owner, rs.repo.Name, ghIssue.Number, commentBody)
+	if !rs.repo.PushesToGitHub() {
+		// A pull-only repo never writes to GitHub.
+		return nil
+	}
+
+	rs.manager.waitForBudget(ctx, rs.repo.ID)
+	commentBody := github.FormatEventComment(ev)
+	ghComment, err := rs.ghClient.CreateComment(ctx, rs.repo.Owner, rs.repo.Name, ghNumber, commentBody)
 	if err != nil {
-		return nil, fmt.Errorf("post synthetic create comment: %w", err)
+		return fmt.Errorf("post comment: %w", err)
 	}
 
 	// Mark the synthetic event synced with the comment ID.
@@ -844,11 +1781,10 @@ func (rs *RepoSyncer) handleWebCreatedIssue(ctx context.Context, ghIssue *github
 	}
 
 	// Update the sync state so subsequent comment fetches skip this comment.
-	if err := rs.store.SetIssueSyncState(ctx, rs.repo.ID, ghIssue.Number, ghComment.ID, ghComment.CreatedAt.UTC().Format(time.RFC3339)); err != nil {
+	if err := rs.store.SetIssueSyncState(ctx, rs.repo.ID, ghNumber, ghComment.ID, ghComment.CreatedAt.UTC().Format(time.RFC3339)); err != nil {
 		slog.Error("failed to set sync state after web-created issue", "error", err)
 	}
-
-	return created, nil
+	return nil
 }
 
 // findLocalIssueByGitHubID looks for a local issue matching the given GitHub issue number.
@@ -865,16 +1801,49 @@ func (rs *RepoSyncer) findLocalIssueByGitHubID(ctx context.Context, ghIssueNumbe
 	return nil
 }
 
-// hasGitHubComment checks whether we already have an event with the given github_comment_id.
+// hasPendingEvents reports whether the issue has local events not yet pushed.
+func (rs *RepoSyncer) hasPendingEvents(ctx context.Context, issueID int) bool {
+	events, err := rs.store.ListEvents(ctx, rs.repo.ID, issueID)
+	if err != nil {
+		return false
+	}
+	for _, ev := range events {
+		if ev.Synced == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGitHubComment checks whether we already have an event with the given
+// github_comment_id, or a snapshot that replaced one.
 func (rs *RepoSyncer) hasGitHubComment(ctx context.Context, issueID, ghCommentID int) bool {
 	events, err := rs.store.ListEvents(ctx, rs.repo.ID, issueID)
 	if err != nil {
 		return false
 	}
 	for _, e := range events {
-		if e.GitHubCommentID != nil && *e.GitHubCommentID == ghCommentID {
-			return true
+		for _, id := range eventCommentIDs(e) {
+			if id == ghCommentID {
+				return true
+			}
 		}
 	}
 	return false
 }
+
+// eventCommentIDs returns the GitHub comments an event accounts for: its
+// own, and for a snapshot those of the events it replaced.
+func eventCommentIDs(e *model.Event) []int {
+	var ids []int
+	if e.GitHubCommentID != nil {
+		ids = append(ids, *e.GitHubCommentID)
+	}
+	if e.Action == model.ActionSnapshot {
+		var payload model.EventPayload
+		if err := json.Unmarshal([]byte(e.Payload), &payload); err == nil {
+			ids = append(ids, payload.CompactedCommentIDs...)
+		}
+	}
+	return ids
+}
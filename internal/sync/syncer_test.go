@@ -3,11 +3,15 @@ package sync
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/jmaddaus/boxofrocks/internal/engine"
 	"github.com/jmaddaus/boxofrocks/internal/github"
 	"github.com/jmaddaus/boxofrocks/internal/model"
 	"github.com/jmaddaus/boxofrocks/internal/store"
@@ -38,6 +42,26 @@ type mockGitHubClient struct {
 	nextIssueNumber int
 	nextCommentID   int
 	rateLimitVal    github.RateLimit
+
+	// listIssuesErr, if set, is returned from ListIssues.
+	listIssuesErr error
+
+	// blockListIssues makes ListIssues hang until its context is cancelled,
+	// simulating a wedged GitHub call.
+	blockListIssues bool
+
+	// commentsNotModified makes ListComments answer a conditional request
+	// with 304 Not Modified; commentETags records the ETag of each call.
+	commentsNotModified bool
+	commentETags        []string
+
+	// stateUpdates records the state passed to each UpdateIssueState call.
+	stateUpdates []string
+	// bodyUpdates counts UpdateIssueBody calls.
+	bodyUpdates int
+	// assigneeCalls records AddAssignees and RemoveAssignees calls as
+	// "add:login,..." and "remove:login,...".
+	assigneeCalls []string
 }
 
 type createdIssueRecord struct {
@@ -73,9 +97,21 @@ func (m *mockGitHubClient) commentKey(owner, repo string, number int) string {
 }
 
 func (m *mockGitHubClient) ListIssues(ctx context.Context, owner, repo string, opts github.ListOpts) ([]*github.GitHubIssue, string, error) {
+	m.mu.Lock()
+	block := m.blockListIssues
+	m.mu.Unlock()
+	if block {
+		<-ctx.Done()
+		return nil, "", ctx.Err()
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.listIssuesErr != nil {
+		return nil, "", m.listIssuesErr
+	}
+
 	key := m.repoKey(owner, repo)
 	issues := m.issues[key]
 
@@ -132,6 +168,7 @@ func (m *mockGitHubClient) UpdateIssueBody(ctx context.Context, owner, repo stri
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.bodyUpdates++
 	key := m.repoKey(owner, repo)
 	for _, iss := range m.issues[key] {
 		if iss.Number == number {
@@ -146,6 +183,11 @@ func (m *mockGitHubClient) ListComments(ctx context.Context, owner, repo string,
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.commentETags = append(m.commentETags, opts.ETag)
+	if m.commentsNotModified && opts.ETag != "" {
+		return nil, opts.ETag, nil
+	}
+
 	key := m.commentKey(owner, repo, number)
 	comments := m.comments[key]
 
@@ -187,6 +229,24 @@ func (m *mockGitHubClient) CreateComment(ctx context.Context, owner, repo string
 	return comment, nil
 }
 
+func (m *mockGitHubClient) GetComment(ctx context.Context, owner, repo string, commentID int) (*github.GitHubComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix := m.repoKey(owner, repo) + "/"
+	for key, comments := range m.comments {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		for _, c := range comments {
+			if c.ID == commentID {
+				return c, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("comment %d not found", commentID)
+}
+
 func (m *mockGitHubClient) GetIssue(ctx context.Context, owner, repo string, number int) (*github.GitHubIssue, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -200,19 +260,20 @@ func (m *mockGitHubClient) GetIssue(ctx context.Context, owner, repo string, num
 	return nil, fmt.Errorf("issue %d not found", number)
 }
 
-func (m *mockGitHubClient) CreateLabel(ctx context.Context, owner, repo, name, color, description string) error {
+func (m *mockGitHubClient) CreateLabel(ctx context.Context, owner, repo, name, color, description string) (bool, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.createLabelCalls = append(m.createLabelCalls, createLabelRecord{
 		Owner: owner, Repo: repo, Name: name, Color: color, Description: description,
 	})
-	return nil
+	return true, nil
 }
 
 func (m *mockGitHubClient) UpdateIssueState(ctx context.Context, owner, repo string, number int, state string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.stateUpdates = append(m.stateUpdates, state)
 	key := m.repoKey(owner, repo)
 	for _, iss := range m.issues[key] {
 		if iss.Number == number {
@@ -238,10 +299,71 @@ func (m *mockGitHubClient) AddLabelsToIssue(ctx context.Context, owner, repo str
 	return fmt.Errorf("issue %d not found", number)
 }
 
+func (m *mockGitHubClient) RemoveLabelFromIssue(ctx context.Context, owner, repo string, number int, label string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := m.repoKey(owner, repo)
+	for i, iss := range m.issues[key] {
+		if iss.Number == number {
+			kept := iss.Labels[:0]
+			for _, l := range iss.Labels {
+				if l.Name != label {
+					kept = append(kept, l)
+				}
+			}
+			m.issues[key][i].Labels = kept
+			return nil
+		}
+	}
+	return fmt.Errorf("issue %d not found", number)
+}
+
+func (m *mockGitHubClient) UpdateLabel(ctx context.Context, owner, repo, name, color, description string) error {
+	return nil
+}
+
+func (m *mockGitHubClient) AddAssignees(ctx context.Context, owner, repo string, number int, logins []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.assigneeCalls = append(m.assigneeCalls, "add:"+strings.Join(logins, ","))
+	for _, iss := range m.issues[m.repoKey(owner, repo)] {
+		if iss.Number == number {
+			for _, login := range logins {
+				iss.Assignees = append(iss.Assignees, github.GitHubUser{Login: login})
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("issue %d not found", number)
+}
+
+func (m *mockGitHubClient) RemoveAssignees(ctx context.Context, owner, repo string, number int, logins []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.assigneeCalls = append(m.assigneeCalls, "remove:"+strings.Join(logins, ","))
+	for _, iss := range m.issues[m.repoKey(owner, repo)] {
+		if iss.Number == number {
+			kept := iss.Assignees[:0]
+			for _, a := range iss.Assignees {
+				if !slices.Contains(logins, a.Login) {
+					kept = append(kept, a)
+				}
+			}
+			iss.Assignees = kept
+			return nil
+		}
+	}
+	return fmt.Errorf("issue %d not found", number)
+}
+
 func (m *mockGitHubClient) GetRepo(ctx context.Context, owner, repo string) (*github.GitHubRepo, error) {
 	return &github.GitHubRepo{Private: true}, nil
 }
 
+func (m *mockGitHubClient) ListCollaborators(ctx context.Context, owner, repo string) ([]string, error) {
+	return nil, nil
+}
+
 func (m *mockGitHubClient) GetRateLimit() github.RateLimit {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -592,6 +714,277 @@ func TestPullInbound_WebCreatedIssue(t *testing.T) {
 	}
 }
 
+func TestSyncIssue_PullsOnlyThatIssue(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	for _, ghIssue := range []*github.GitHubIssue{
+		{Number: 7, Title: "Tracked", State: "open", Labels: []github.GitHubLabel{{Name: "BoxOfRocks"}}},
+		{Number: 8, Title: "Also tracked", State: "open", Labels: []github.GitHubLabel{{Name: "boxofrocks"}}},
+		{Number: 9, Title: "Untracked", State: "open"},
+	} {
+		ghIssue.UpdatedAt = time.Now().UTC()
+		gh.addGitHubIssue("testowner", "testrepo", ghIssue)
+	}
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+	rs.syncIssue(9)
+	rs.syncIssue(7)
+
+	issues, err := s.ListIssues(ctx, store.IssueFilter{RepoID: repo.ID})
+	if err != nil {
+		t.Fatalf("list issues: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Title != "Tracked" {
+		t.Fatalf("expected only issue #7 pulled, got %v", issues)
+	}
+	select {
+	case <-rs.forceCh:
+		t.Error("a successful issue sync should not queue a cycle")
+	default:
+	}
+
+	// An issue GitHub cannot return falls back to a normal cycle.
+	rs.syncIssue(404)
+	select {
+	case req := <-rs.forceCh:
+		if req.full {
+			t.Error("fallback cycle should be incremental")
+		}
+	default:
+		t.Error("expected a failed issue sync to queue a cycle")
+	}
+}
+
+func TestPullInbound_CustomTrackingLabel(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	repo.Label = "tracked"
+	if err := s.UpdateRepo(ctx, repo); err != nil {
+		t.Fatalf("update repo: %v", err)
+	}
+
+	gh.addGitHubIssue("testowner", "testrepo", &github.GitHubIssue{
+		Number:    1,
+		Title:     "Default Label",
+		State:     "open",
+		Labels:    []github.GitHubLabel{{Name: "boxofrocks"}},
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	})
+	gh.addGitHubIssue("testowner", "testrepo", &github.GitHubIssue{
+		Number:    2,
+		Title:     "Custom Label",
+		State:     "open",
+		Labels:    []github.GitHubLabel{{Name: "tracked"}, {Name: "bug"}},
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	})
+
+	rs := newRepoSyncer(repo, s, gh, NewSyncManager(s, gh), 5*time.Second)
+	if _, err := rs.pullInbound(ctx); err != nil {
+		t.Fatalf("pullInbound: %v", err)
+	}
+
+	issues, err := s.ListIssues(ctx, store.IssueFilter{RepoID: repo.ID})
+	if err != nil {
+		t.Fatalf("list issues: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Title != "Custom Label" {
+		t.Fatalf("expected only the issue carrying the custom label, got %d issues", len(issues))
+	}
+	events, _ := s.ListEvents(ctx, repo.ID, issues[0].ID)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 synthetic create event, got %d", len(events))
+	}
+	var payload model.EventPayload
+	json.Unmarshal([]byte(events[0].Payload), &payload)
+	if len(payload.Labels) != 1 || payload.Labels[0] != "bug" {
+		t.Errorf("expected labels [bug] without the tracking label, got %v", payload.Labels)
+	}
+
+	// Issues created locally are pushed with the custom label.
+	created, err := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Local", Status: model.StatusOpen})
+	if err != nil {
+		t.Fatalf("create issue: %v", err)
+	}
+	if _, err := s.AppendEvent(ctx, &model.Event{
+		RepoID:  repo.ID,
+		IssueID: created.ID,
+		Action:  model.ActionCreate,
+		Payload: makeCreatePayload("Local", ""),
+	}); err != nil {
+		t.Fatalf("append event: %v", err)
+	}
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("pushOutbound: %v", err)
+	}
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	if len(gh.createdIssues) != 1 || len(gh.createdIssues[0].Labels) == 0 || gh.createdIssues[0].Labels[0] != "tracked" {
+		t.Errorf("expected created issue labeled tracked first, got %+v", gh.createdIssues)
+	}
+}
+
+func TestPullInbound_WebCreatedClosedIssue(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	closedAt := time.Now().UTC().Add(-10 * time.Minute).Truncate(time.Second)
+	gh.addGitHubIssue("testowner", "testrepo", &github.GitHubIssue{
+		Number:    98,
+		Title:     "Already Closed",
+		Body:      "Closed before boxofrocks saw it",
+		State:     "closed",
+		Labels:    []github.GitHubLabel{{Name: "boxofrocks"}},
+		CreatedAt: time.Now().UTC().Add(-time.Hour),
+		UpdatedAt: closedAt,
+		ClosedAt:  &closedAt,
+	})
+
+	rs := newRepoSyncer(repo, s, gh, NewSyncManager(s, gh), 5*time.Second)
+	if _, err := rs.pullInbound(ctx); err != nil {
+		t.Fatalf("pullInbound: %v", err)
+	}
+
+	issues, err := s.ListIssues(ctx, store.IssueFilter{RepoID: repo.ID})
+	if err != nil {
+		t.Fatalf("list issues: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	got := issues[0]
+	if got.Status != model.StatusClosed {
+		t.Errorf("expected status closed, got %q", got.Status)
+	}
+	if got.ClosedAt == nil || !got.ClosedAt.Equal(closedAt) {
+		t.Errorf("expected closed_at %v, got %v", closedAt, got.ClosedAt)
+	}
+
+	events, _ := s.ListEvents(ctx, repo.ID, got.ID)
+	if len(events) != 2 || events[0].Action != model.ActionCreate || events[1].Action != model.ActionClose {
+		t.Fatalf("expected create then close events, got %d events", len(events))
+	}
+	if pending, _ := s.PendingEvents(ctx, repo.ID); len(pending) != 0 {
+		t.Errorf("expected synthetic events to be synced, got %d pending", len(pending))
+	}
+}
+
+func TestPullInbound_ReconcilesGitHubState(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	ghNum := 30
+	local, _ := s.CreateIssue(ctx, &model.Issue{
+		RepoID: repo.ID, GitHubID: &ghNum, Title: "Closed On Web",
+		Status: model.StatusOpen, IssueType: model.IssueTypeTask, Labels: []string{},
+	})
+	s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: local.ID, Timestamp: time.Now().UTC().Add(-time.Hour),
+		Action: model.ActionCreate, Payload: makeCreatePayload("Closed On Web", ""), Agent: "test", Synced: 1,
+	})
+	ghIssue := &github.GitHubIssue{
+		Number: ghNum, Title: "Closed On Web", State: "closed",
+		Labels:    []github.GitHubLabel{{Name: "boxofrocks"}},
+		CreatedAt: time.Now().UTC().Add(-time.Hour), UpdatedAt: time.Now().UTC(),
+	}
+	gh.addGitHubIssue("testowner", "testrepo", ghIssue)
+
+	rs := newRepoSyncer(repo, s, gh, NewSyncManager(s, gh), 5*time.Second)
+	status := func() model.Status {
+		t.Helper()
+		if _, err := rs.pullInbound(ctx); err != nil {
+			t.Fatalf("pullInbound: %v", err)
+		}
+		got, err := s.GetIssue(ctx, local.ID)
+		if err != nil {
+			t.Fatalf("get issue: %v", err)
+		}
+		return got.Status
+	}
+
+	if got := status(); got != model.StatusClosed {
+		t.Errorf("closed on GitHub: expected closed, got %q", got)
+	}
+
+	ghIssue.State = "open"
+	if got := status(); got != model.StatusOpen {
+		t.Errorf("reopened on GitHub: expected open, got %q", got)
+	}
+
+	// A local close not yet pushed is not undone by GitHub's stale state.
+	closing, _ := s.GetIssue(ctx, local.ID)
+	closing.Status = model.StatusClosed
+	s.UpdateIssue(ctx, closing)
+	s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: local.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionClose, Payload: "{}", Agent: "agent-1",
+	})
+	if got := status(); got != model.StatusClosed {
+		t.Errorf("pending local close: expected closed, got %q", got)
+	}
+}
+
+func TestPullInbound_IngestHumanComments(t *testing.T) {
+	for _, ingest := range []bool{false, true} {
+		s, gh, repo := setupTest(t)
+		ctx := context.Background()
+
+		repo.IngestHumanComments = ingest
+		if err := s.UpdateRepo(ctx, repo); err != nil {
+			t.Fatalf("update repo: %v", err)
+		}
+
+		ghNum := 40
+		local, _ := s.CreateIssue(ctx, &model.Issue{
+			RepoID: repo.ID, GitHubID: &ghNum, Title: "Discussed",
+			Status: model.StatusOpen, IssueType: model.IssueTypeTask, Labels: []string{},
+		})
+		s.AppendEvent(ctx, &model.Event{
+			RepoID: repo.ID, IssueID: local.ID, Timestamp: time.Now().UTC().Add(-time.Hour),
+			Action: model.ActionCreate, Payload: makeCreatePayload("Discussed", ""), Agent: "test", Synced: 1,
+		})
+		gh.addGitHubIssue("testowner", "testrepo", &github.GitHubIssue{
+			Number: ghNum, Title: "Discussed", State: "open",
+			Labels:    []github.GitHubLabel{{Name: "boxofrocks"}},
+			CreatedAt: time.Now().UTC().Add(-time.Hour), UpdatedAt: time.Now().UTC(),
+		})
+		gh.addGitHubComment("testowner", "testrepo", ghNum, &github.GitHubComment{
+			ID: 4001, Body: "Can we also cover the edge case?", User: github.GitHubUser{Login: "octocat"},
+			CreatedAt: time.Now().UTC().Add(-10 * time.Minute),
+		})
+		gh.addGitHubComment("testowner", "testrepo", ghNum, &github.GitHubComment{
+			ID: 4002, Body: github.FormatRollupComment(41, "Child"), User: github.GitHubUser{Login: "bor-bot"},
+			CreatedAt: time.Now().UTC().Add(-5 * time.Minute),
+		})
+
+		rs := newRepoSyncer(repo, s, gh, NewSyncManager(s, gh), 5*time.Second)
+		if _, err := rs.pullInbound(ctx); err != nil {
+			t.Fatalf("pullInbound: %v", err)
+		}
+
+		got, _ := s.GetIssue(ctx, local.ID)
+		if !ingest {
+			if len(got.Comments) != 0 {
+				t.Errorf("ingest off: expected no comments, got %+v", got.Comments)
+			}
+			continue
+		}
+		if len(got.Comments) != 1 {
+			t.Fatalf("ingest on: expected 1 comment, got %+v", got.Comments)
+		}
+		if c := got.Comments[0]; c.Author != "octocat" || c.Text != "Can we also cover the edge case?" {
+			t.Errorf("unexpected comment %+v", c)
+		}
+		if pending, _ := s.PendingEvents(ctx, repo.ID); len(pending) != 0 {
+			t.Errorf("ingested comments must not be pushed back, got %d pending", len(pending))
+		}
+	}
+}
+
 func TestPullInbound_Incremental(t *testing.T) {
 	s, gh, repo := setupTest(t)
 	ctx := context.Background()
@@ -695,6 +1088,92 @@ func TestPullInbound_Incremental(t *testing.T) {
 	}
 }
 
+func TestProcessGitHubIssue_CommentsNotModified(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	ghID := 20
+	created, err := s.CreateIssue(ctx, &model.Issue{
+		RepoID:    repo.ID,
+		GitHubID:  &ghID,
+		Title:     "ETag Test",
+		Status:    model.StatusOpen,
+		IssueType: model.IssueTypeTask,
+		Labels:    []string{},
+	})
+	if err != nil {
+		t.Fatalf("create issue: %v", err)
+	}
+
+	ghIssue := &github.GitHubIssue{
+		Number:    20,
+		Title:     "ETag Test",
+		State:     "open",
+		Labels:    []github.GitHubLabel{{Name: "boxofrocks"}},
+		CreatedAt: time.Now().UTC().Add(-2 * time.Hour),
+		UpdatedAt: time.Now().UTC(),
+	}
+	gh.addGitHubIssue("testowner", "testrepo", ghIssue)
+	gh.addGitHubComment("testowner", "testrepo", 20, &github.GitHubComment{
+		ID: 200,
+		Body: github.FormatEventComment(&model.Event{
+			Timestamp: time.Now().UTC(),
+			Action:    model.ActionAssign,
+			Payload:   `{"owner":"bob"}`,
+			Agent:     "agent",
+		}),
+		CreatedAt: time.Now().UTC(),
+	})
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+	if err := rs.processGitHubIssue(ctx, ghIssue, false); err != nil {
+		t.Fatalf("first poll: %v", err)
+	}
+	etag, err := s.GetCommentsETag(ctx, repo.ID, 20)
+	if err != nil {
+		t.Fatalf("GetCommentsETag: %v", err)
+	}
+	if etag != "comment-etag" {
+		t.Fatalf("expected stored etag 'comment-etag', got %q", etag)
+	}
+
+	// The second poll is answered with 304, so a comment that would
+	// otherwise change the owner must not be seen.
+	gh.commentsNotModified = true
+	gh.addGitHubComment("testowner", "testrepo", 20, &github.GitHubComment{
+		ID: 300,
+		Body: github.FormatEventComment(&model.Event{
+			Timestamp: time.Now().UTC(),
+			Action:    model.ActionAssign,
+			Payload:   `{"owner":"carol"}`,
+			Agent:     "agent",
+		}),
+		CreatedAt: time.Now().UTC(),
+	})
+	if err := rs.processGitHubIssue(ctx, ghIssue, false); err != nil {
+		t.Fatalf("second poll: %v", err)
+	}
+
+	if len(gh.commentETags) != 2 || gh.commentETags[0] != "" || gh.commentETags[1] != "comment-etag" {
+		t.Errorf("expected ETags [\"\" \"comment-etag\"], got %q", gh.commentETags)
+	}
+	events, err := s.ListEvents(ctx, repo.ID, created.ID)
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("expected 1 event after the 304 poll, got %d", len(events))
+	}
+	updated, err := s.GetIssue(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("get issue: %v", err)
+	}
+	if updated.Owner != "bob" {
+		t.Errorf("expected owner 'bob', got %q", updated.Owner)
+	}
+}
+
 func TestForceSync_TriggersImmediateCycle(t *testing.T) {
 	s, gh, repo := setupTest(t)
 
@@ -795,6 +1274,66 @@ func TestMultiRepo(t *testing.T) {
 	}
 }
 
+func TestSyncManager_PerRepoPollInterval(t *testing.T) {
+	s, gh, _ := setupTest(t)
+	ctx := context.Background()
+
+	fast, err := s.AddRepo(ctx, "owner1", "fast")
+	if err != nil {
+		t.Fatalf("add fast repo: %v", err)
+	}
+	slow, err := s.AddRepo(ctx, "owner2", "slow")
+	if err != nil {
+		t.Fatalf("add slow repo: %v", err)
+	}
+	fast.PollIntervalMs = 2000
+	slow.PollIntervalMs = 90000
+	for _, r := range []*model.RepoConfig{fast, slow} {
+		if err := s.UpdateRepo(ctx, r); err != nil {
+			t.Fatalf("update repo: %v", err)
+		}
+	}
+
+	sm := NewSyncManager(s, gh)
+	defer sm.Stop()
+	if err := sm.AddRepo(fast); err != nil {
+		t.Fatalf("add fast repo to sync: %v", err)
+	}
+	if err := sm.AddRepo(slow); err != nil {
+		t.Fatalf("add slow repo to sync: %v", err)
+	}
+
+	interval := func(id int) time.Duration {
+		sm.mu.Lock()
+		rs := sm.syncers[id]
+		sm.mu.Unlock()
+		return rs.currentInterval()
+	}
+	if got := interval(fast.ID); got != 2*time.Second {
+		t.Errorf("fast repo interval = %v, want 2s", got)
+	}
+	if got := interval(slow.ID); got != 90*time.Second {
+		t.Errorf("slow repo interval = %v, want 90s", got)
+	}
+
+	// Clearing the interval falls back to the count-based stagger once the
+	// syncer reloads its settings.
+	fast.PollIntervalMs = 0
+	if err := s.UpdateRepo(ctx, fast); err != nil {
+		t.Fatalf("update repo: %v", err)
+	}
+	if err := sm.ForceSync(fast.ID); err != nil {
+		t.Fatalf("force sync: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for interval(fast.ID) == 2*time.Second && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := interval(fast.ID); got != 5*time.Second {
+		t.Errorf("fast repo interval after clearing = %v, want 5s", got)
+	}
+}
+
 func TestGenerateSyntheticCreate(t *testing.T) {
 	ghIssue := &github.GitHubIssue{
 		Number:    55,
@@ -806,7 +1345,7 @@ func TestGenerateSyntheticCreate(t *testing.T) {
 		UpdatedAt: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
 	}
 
-	ev := GenerateSyntheticCreate(ghIssue, 1, 42)
+	ev := GenerateSyntheticCreate(ghIssue, 1, 42, "boxofrocks")
 
 	if ev.Action != model.ActionCreate {
 		t.Errorf("expected action 'create', got '%s'", ev.Action)
@@ -1102,6 +1641,58 @@ func TestCycleCreatesLabel(t *testing.T) {
 	}
 }
 
+func TestCycleSyncDirection(t *testing.T) {
+	tests := []struct {
+		direction  model.SyncDirection
+		wantPushed bool
+		wantPulled bool
+	}{
+		{model.SyncBoth, true, true},
+		{model.SyncPull, false, true},
+		{model.SyncPush, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.direction), func(t *testing.T) {
+			s, gh, repo := setupTest(t)
+			ctx := context.Background()
+
+			repo.SyncDirection = tt.direction
+			if err := s.UpdateRepo(ctx, repo); err != nil {
+				t.Fatalf("update repo: %v", err)
+			}
+
+			// A local issue with an unpushed create event.
+			local, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Local"})
+			s.AppendEvent(ctx, &model.Event{
+				RepoID: repo.ID, IssueID: local.ID, Timestamp: time.Now().UTC(),
+				Action: model.ActionCreate, Payload: makeCreatePayload("Local", ""), Agent: "agent-1",
+			})
+			// A web-created GitHub issue with no local counterpart.
+			gh.addGitHubIssue("testowner", "testrepo", &github.GitHubIssue{
+				Number: 500, Title: "Web", State: "open",
+				Labels:    []github.GitHubLabel{{Name: "boxofrocks"}},
+				CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(),
+			})
+
+			rs := newRepoSyncer(repo, s, gh, NewSyncManager(s, gh), 5*time.Second)
+			rs.cycle(false)
+
+			pushed, _ := s.GetIssue(ctx, local.ID)
+			if got := pushed.GitHubID != nil; got != tt.wantPushed {
+				t.Errorf("local issue pushed = %v, want %v", got, tt.wantPushed)
+			}
+			if got := rs.findLocalIssueByGitHubID(ctx, 500) != nil; got != tt.wantPulled {
+				t.Errorf("web issue pulled = %v, want %v", got, tt.wantPulled)
+			}
+			if tt.direction == model.SyncPull {
+				if len(gh.createdComments) != 0 || len(gh.createLabelCalls) != 0 {
+					t.Errorf("pull-only wrote to GitHub: %d comments, %d labels", len(gh.createdComments), len(gh.createLabelCalls))
+				}
+			}
+		})
+	}
+}
+
 func TestCycleCreatesLabelOnlyOnce(t *testing.T) {
 	s, gh, repo := setupTest(t)
 
@@ -1150,6 +1741,117 @@ func TestRepoSyncer_CurrentInterval(t *testing.T) {
 	}
 }
 
+func TestRepoSyncer_AdaptiveInterval(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+	rs.maxInterval = 30 * time.Second
+
+	// Each cycle that changes nothing doubles the interval, up to the cap.
+	for _, want := range []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second} {
+		rs.cycle(false)
+		if got := rs.currentInterval(); got != want {
+			t.Fatalf("after empty cycle: interval = %v, want %v", got, want)
+		}
+	}
+	if got := rs.getStatus().PollIntervalMs; got != 30000 {
+		t.Errorf("status poll_interval_ms = %d, want 30000", got)
+	}
+
+	// A cycle that pushes an event drops back to the base interval.
+	ghID := 7
+	issue, err := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, GitHubID: &ghID, Title: "Wake up", Status: model.StatusOpen, Labels: []string{}})
+	if err != nil {
+		t.Fatalf("create issue: %v", err)
+	}
+	if _, err := s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: issue.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionStatusChange, Payload: makeStatusChangePayload(model.StatusInProgress),
+	}); err != nil {
+		t.Fatalf("append event: %v", err)
+	}
+	rs.cycle(false)
+	if got := rs.currentInterval(); got != 5*time.Second {
+		t.Errorf("after push: interval = %v, want 5s", got)
+	}
+
+	// So does a forced sync, which the run loop records as activity.
+	rs.cycle(false)
+	if got := rs.currentInterval(); got != 10*time.Second {
+		t.Fatalf("after empty cycle: interval = %v, want 10s", got)
+	}
+	rs.setLastActivity()
+	if got := rs.currentInterval(); got != 5*time.Second {
+		t.Errorf("after force: interval = %v, want 5s", got)
+	}
+}
+
+func TestSyncManager_PriorityRange(t *testing.T) {
+	s, gh, repo := setupTest(t)
+
+	sm := NewSyncManager(s, gh)
+	sm.SetPriorityRange(1, 3)
+	defer sm.Stop()
+	if err := sm.AddRepo(repo); err != nil {
+		t.Fatalf("add repo: %v", err)
+	}
+	sm.mu.Lock()
+	rs := sm.syncers[repo.ID]
+	sm.mu.Unlock()
+
+	create := &model.Event{IssueID: 1, Timestamp: time.Now().UTC(), Action: model.ActionCreate, Payload: `{"title":"t","priority":9}`}
+	issue, err := engine.Apply(nil, create, rs.engineOptions()...)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if issue.Priority != 3 {
+		t.Errorf("expected pulled priority clamped to 3, got %d", issue.Priority)
+	}
+
+	// Without a range, as for a bare manager, priorities are kept.
+	if opts := newRepoSyncer(repo, s, gh, NewSyncManager(s, gh), time.Second).engineOptions(); len(opts) != 0 {
+		t.Errorf("expected no engine options without a range, got %d", len(opts))
+	}
+}
+
+func TestSyncManager_AdaptiveIntervalResetByForceSync(t *testing.T) {
+	s, gh, repo := setupTest(t)
+
+	sm := NewSyncManager(s, gh)
+	sm.SetMaxPollInterval(time.Hour)
+	defer sm.Stop()
+	if err := sm.AddRepo(repo); err != nil {
+		t.Fatalf("add repo: %v", err)
+	}
+	sm.mu.Lock()
+	rs := sm.syncers[repo.ID]
+	sm.mu.Unlock()
+
+	// The initial cycle changes nothing, so the interval grows.
+	deadline := time.Now().Add(2 * time.Second)
+	for rs.currentInterval() == 5*time.Second && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := rs.currentInterval(); got <= 5*time.Second {
+		t.Fatalf("expected interval to grow after an empty cycle, got %v", got)
+	}
+
+	// ForceSync counts as activity; the forced cycle is empty again, so the
+	// interval restarts from one doubling of the base.
+	if err := sm.ForceSync(repo.ID); err != nil {
+		t.Fatalf("force sync: %v", err)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for len(rs.getCycleLog()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := rs.currentInterval(); got != 10*time.Second {
+		t.Errorf("after force sync: interval = %v, want 10s", got)
+	}
+}
+
 func TestRepoSyncer_ActivityResetOnPush(t *testing.T) {
 	s, gh, repo := setupTest(t)
 	ctx := context.Background()
@@ -1330,6 +2032,222 @@ func TestPullInbound_TrustedAuthorsOnly_SkipsUntrusted(t *testing.T) {
 	}
 }
 
+func TestPullInbound_TrustedAuthorsOnly_TrustedLogins(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	repo.TrustedAuthorsOnly = true
+	repo.TrustedAuthors = []string{"Carol"}
+	if err := s.UpdateRepo(ctx, repo); err != nil {
+		t.Fatalf("update repo: %v", err)
+	}
+
+	ghID := 32
+	created, err := s.CreateIssue(ctx, &model.Issue{
+		RepoID:    repo.ID,
+		GitHubID:  &ghID,
+		Title:     "Trusted Login Test",
+		Status:    model.StatusOpen,
+		IssueType: model.IssueTypeTask,
+		Labels:    []string{},
+	})
+	if err != nil {
+		t.Fatalf("create issue: %v", err)
+	}
+	if _, err := s.AppendEvent(ctx, &model.Event{
+		RepoID:    repo.ID,
+		IssueID:   created.ID,
+		Timestamp: time.Now().UTC().Add(-1 * time.Hour),
+		Action:    model.ActionCreate,
+		Payload:   makeCreatePayload("Trusted Login Test", ""),
+		Agent:     "test",
+		Synced:    1,
+	}); err != nil {
+		t.Fatalf("append create event: %v", err)
+	}
+	gh.addGitHubIssue("testowner", "testrepo", &github.GitHubIssue{
+		Number:    32,
+		Title:     "Trusted Login Test",
+		State:     "open",
+		Labels:    []github.GitHubLabel{{Name: "boxofrocks"}},
+		CreatedAt: time.Now().UTC().Add(-1 * time.Hour),
+		UpdatedAt: time.Now().UTC(),
+	})
+
+	// All three comments come from users GitHub does not associate with the
+	// repo; only the listed login and the repo owner are trusted.
+	base := time.Now().UTC()
+	for i, c := range []struct {
+		login   string
+		ev      *model.Event
+		trusted bool
+	}{
+		{"mallory", &model.Event{Action: model.ActionStatusChange, Payload: makeStatusChangePayload(model.StatusInProgress)}, false},
+		{"carol", &model.Event{Action: model.ActionAssign, Payload: `{"owner":"carol"}`}, true},
+		{"TestOwner", &model.Event{Action: model.ActionUpdate, Payload: `{"title":"Renamed by owner"}`}, true},
+	} {
+		c.ev.Timestamp = base.Add(time.Duration(i) * time.Second)
+		c.ev.Agent = c.login
+		gh.addGitHubComment("testowner", "testrepo", 32, &github.GitHubComment{
+			ID:                6001 + i,
+			Body:              github.FormatEventComment(c.ev),
+			User:              github.GitHubUser{Login: c.login},
+			AuthorAssociation: "NONE",
+			CreatedAt:         c.ev.Timestamp,
+		})
+	}
+
+	rs := newRepoSyncer(repo, s, gh, NewSyncManager(s, gh), 5*time.Second)
+	if _, err := rs.pullInbound(ctx); err != nil {
+		t.Fatalf("pullInbound: %v", err)
+	}
+
+	updated, err := s.GetIssue(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("get issue: %v", err)
+	}
+	if updated.Status != model.StatusOpen {
+		t.Errorf("expected status open (untrusted login skipped), got %s", updated.Status)
+	}
+	if updated.Owner != "carol" {
+		t.Errorf("expected owner carol (trusted login applied), got %q", updated.Owner)
+	}
+	if updated.Title != "Renamed by owner" {
+		t.Errorf("expected repo owner's rename applied, got title %q", updated.Title)
+	}
+	events, _ := s.ListEvents(ctx, repo.ID, created.ID)
+	for _, ev := range events {
+		if ev.Agent == "mallory" {
+			t.Errorf("untrusted event was stored: %+v", ev)
+		}
+	}
+}
+
+func TestRefreshRepoSettings_PreservesEditsOnPersist(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+
+	// Edit settings through a separate copy, as PATCH /repos does.
+	edited, err := s.GetRepo(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("get repo: %v", err)
+	}
+	edited.AllowedInboundActions = []string{"comment"}
+	edited.IssueTypes = []string{"chore", "spike"}
+	edited.NextStrategy = model.NextStrategyFIFO
+	edited.SyncDirection = model.SyncPull
+	edited.Label = "tracked"
+	edited.TrustedAuthors = []string{"carol"}
+	if err := s.UpdateRepo(ctx, edited); err != nil {
+		t.Fatalf("update repo: %v", err)
+	}
+
+	// A cycle refreshes settings, then persists its copy of the repo.
+	rs.refreshRepoSettings(ctx)
+	if err := s.UpdateRepo(ctx, rs.repo); err != nil {
+		t.Fatalf("persist syncer repo: %v", err)
+	}
+
+	got, err := s.GetRepo(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("get repo: %v", err)
+	}
+	if len(got.AllowedInboundActions) != 1 || len(got.IssueTypes) != 2 || got.NextStrategy != model.NextStrategyFIFO || got.SyncDirection != model.SyncPull || got.Label != "tracked" ||
+		len(got.TrustedAuthors) != 1 {
+		t.Errorf("settings overwritten by syncer: allowed=%v issue_types=%v next_strategy=%q sync_direction=%q label=%q",
+			got.AllowedInboundActions, got.IssueTypes, got.NextStrategy, got.SyncDirection, got.Label)
+	}
+}
+
+func TestPullInbound_AllowedInboundActions_IgnoresOthers(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	repo.AllowedInboundActions = []string{"comment", "status_change"}
+	if err := s.UpdateRepo(ctx, repo); err != nil {
+		t.Fatalf("update repo: %v", err)
+	}
+
+	ghID := 31
+	created, err := s.CreateIssue(ctx, &model.Issue{
+		RepoID:    repo.ID,
+		GitHubID:  &ghID,
+		Title:     "Allowed Actions Test",
+		Status:    model.StatusOpen,
+		IssueType: model.IssueTypeTask,
+		Labels:    []string{},
+	})
+	if err != nil {
+		t.Fatalf("create issue: %v", err)
+	}
+	if _, err := s.AppendEvent(ctx, &model.Event{
+		RepoID:    repo.ID,
+		IssueID:   created.ID,
+		Timestamp: time.Now().UTC().Add(-1 * time.Hour),
+		Action:    model.ActionCreate,
+		Payload:   makeCreatePayload("Allowed Actions Test", ""),
+		Agent:     "test",
+		Synced:    1,
+	}); err != nil {
+		t.Fatalf("append create event: %v", err)
+	}
+
+	gh.addGitHubIssue("testowner", "testrepo", &github.GitHubIssue{
+		Number:    31,
+		Title:     "Allowed Actions Test",
+		State:     "open",
+		Labels:    []github.GitHubLabel{{Name: "boxofrocks"}},
+		CreatedAt: time.Now().UTC().Add(-1 * time.Hour),
+		UpdatedAt: time.Now().UTC(),
+	})
+
+	// A crafted delete (ignored) followed by a status change (applied).
+	deleteEv := &model.Event{Timestamp: time.Now().UTC(), Action: model.ActionDelete, Payload: "{}", Agent: "attacker"}
+	gh.addGitHubComment("testowner", "testrepo", 31, &github.GitHubComment{
+		ID: 6001, Body: github.FormatEventComment(deleteEv), CreatedAt: time.Now().UTC(),
+	})
+	statusEv := &model.Event{Timestamp: time.Now().UTC(), Action: model.ActionStatusChange, Payload: makeStatusChangePayload(model.StatusInProgress), Agent: "agent-1"}
+	gh.addGitHubComment("testowner", "testrepo", 31, &github.GitHubComment{
+		ID: 6002, Body: github.FormatEventComment(statusEv), CreatedAt: time.Now().UTC(),
+	})
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+	if _, err := rs.pullInbound(ctx); err != nil {
+		t.Fatalf("pullInbound: %v", err)
+	}
+
+	updated, err := s.GetIssue(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("get issue: %v", err)
+	}
+	if updated.Status != model.StatusInProgress {
+		t.Errorf("expected status in_progress (delete ignored, status change applied), got %s", updated.Status)
+	}
+	if rs.ignoredCount != 1 {
+		t.Errorf("expected 1 ignored event, got %d", rs.ignoredCount)
+	}
+
+	events, _ := s.ListEvents(ctx, repo.ID, created.ID)
+	for _, ev := range events {
+		if ev.Action == model.ActionDelete {
+			t.Error("ignored delete event should not be stored")
+		}
+	}
+
+	// A later full replay must not resurrect the ignored delete either.
+	if _, err := rs.pullInboundFull(ctx); err != nil {
+		t.Fatalf("pullInboundFull: %v", err)
+	}
+	updated, _ = s.GetIssue(ctx, created.ID)
+	if updated.Status == model.StatusDeleted {
+		t.Error("full replay applied a disallowed delete")
+	}
+}
+
 func TestPullInbound_TrustedAuthorsOnly_DisabledAllowsAll(t *testing.T) {
 	s, gh, repo := setupTest(t)
 	ctx := context.Background()
@@ -1402,3 +2320,792 @@ func TestPullInbound_TrustedAuthorsOnly_DisabledAllowsAll(t *testing.T) {
 		t.Errorf("expected status in_progress (no filter), got %s", updated.Status)
 	}
 }
+
+func TestCycleLog_RecordsPushAndError(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	ghID := 42
+	created, err := s.CreateIssue(ctx, &model.Issue{
+		RepoID:   repo.ID,
+		GitHubID: &ghID,
+		Title:    "Logged",
+		Status:   model.StatusOpen,
+	})
+	if err != nil {
+		t.Fatalf("create issue: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.AppendEvent(ctx, &model.Event{
+			RepoID:    repo.ID,
+			IssueID:   created.ID,
+			Timestamp: time.Now().UTC(),
+			Action:    model.ActionComment,
+			Payload:   fmt.Sprintf(`{"comment":"hi %d"}`, i),
+		}); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+
+	rs.cycle(false)
+
+	gh.mu.Lock()
+	gh.listIssuesErr = fmt.Errorf("boom")
+	gh.mu.Unlock()
+	rs.cycle(true)
+
+	log := rs.getCycleLog()
+	if len(log) != 2 {
+		t.Fatalf("expected 2 cycle results, got %d", len(log))
+	}
+	if log[0].Pushed != 2 {
+		t.Errorf("expected first cycle to push 2 events, got %d", log[0].Pushed)
+	}
+	if log[0].Error != "" {
+		t.Errorf("expected no error on first cycle, got %q", log[0].Error)
+	}
+	if !log[1].Full {
+		t.Error("expected second cycle to be marked full")
+	}
+	if log[1].Error == "" {
+		t.Error("expected second cycle to record the pull error")
+	}
+	if log[1].StartedAt.Before(log[0].StartedAt) {
+		t.Error("expected results in chronological order")
+	}
+}
+
+func TestCancelCycle_InterruptsHungCall(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	gh.blockListIssues = true
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+
+	if rs.cancelCycle() {
+		t.Fatal("expected cancelCycle to report false when no cycle is running")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		rs.cycle(false)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := rs.activeSince(); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cycle never became active")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if !rs.cancelCycle() {
+		t.Fatal("expected cancelCycle to report true for a running cycle")
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("cycle did not return after cancellation")
+	}
+
+	if _, ok := rs.activeSince(); ok {
+		t.Error("expected no active cycle after cancellation")
+	}
+	log := rs.getCycleLog()
+	if len(log) != 1 || !strings.Contains(log[0].Error, "context canceled") {
+		t.Errorf("expected cancelled cycle to be logged with context canceled, got %+v", log)
+	}
+	if st := rs.getStatus(); st.Syncing {
+		t.Error("expected Syncing=false after cancellation")
+	}
+}
+
+func TestSyncManager_StopContextCancelsHungCycle(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	gh.blockListIssues = true
+
+	sm := NewSyncManager(s, gh)
+	if err := sm.AddRepo(repo); err != nil {
+		t.Fatalf("add repo: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sm.Active()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("cycle never became active")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Stop would wait on the hung call forever; the deadline cuts it off.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		sm.StopContext(ctx)
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("StopContext did not return after its deadline")
+	}
+	if st := sm.Status(); len(st) != 0 {
+		t.Errorf("expected no syncers after stop, got %d", len(st))
+	}
+	// A later Stop, as the daemon command defers, is a no-op.
+	sm.Stop()
+}
+
+func TestSyncManager_RateBudgetPausesOnlyExhaustedRepo(t *testing.T) {
+	s, gh, repoA := setupTest(t)
+	gh.rateLimitVal = github.RateLimit{Remaining: rateRepoAllotment, Reset: time.Now().Add(time.Hour)}
+	repoB, err := s.AddRepo(context.Background(), "testowner", "otherrepo")
+	if err != nil {
+		t.Fatalf("add repo: %v", err)
+	}
+
+	sm := NewSyncManager(s, gh)
+	sm.budget.add(repoA.ID)
+	sm.budget.add(repoB.ID)
+
+	// Repo A spends its own allotment; what remains is held for repo B.
+	for i := 0; i < rateRepoAllotment; i++ {
+		sm.waitForBudget(context.Background(), repoA.ID)
+	}
+	ctxA, cancelA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() {
+		sm.waitForBudget(ctxA, repoA.ID)
+		close(doneA)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if st := sm.budget.status(repoA.ID); st != nil && st.WaitingUntil != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("repo A never paused for the rate limit")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Repo B's cycle runs while repo A waits for the reset.
+	rsB := newRepoSyncer(repoB, s, gh, sm, 5*time.Second)
+	doneB := make(chan struct{})
+	go func() {
+		rsB.cycle(false)
+		close(doneB)
+	}()
+	select {
+	case <-doneB:
+	case <-time.After(2 * time.Second):
+		t.Fatal("repo B's cycle was blocked by repo A's budget")
+	}
+	if st := sm.budget.status(repoB.ID); st == nil || st.Spent == 0 || st.WaitingUntil != nil {
+		t.Errorf("repo B budget = %+v, want calls spent and no wait", st)
+	}
+
+	select {
+	case <-doneA:
+		t.Fatal("repo A resumed before the reset")
+	default:
+	}
+	cancelA()
+	<-doneA
+	if st := sm.budget.status(repoA.ID); st.WaitingUntil != nil || st.Allotment != 0 {
+		t.Errorf("repo A budget = %+v, want allotment spent and no wait", st)
+	}
+}
+
+func TestSyncManager_CancelAndActiveUnknownRepo(t *testing.T) {
+	s, gh, _ := setupTest(t)
+	sm := NewSyncManager(s, gh)
+
+	if _, err := sm.CancelCycle(999); err == nil {
+		t.Error("expected error cancelling an unknown repo")
+	}
+	if active := sm.Active(); len(active) != 0 {
+		t.Errorf("expected no active syncs, got %+v", active)
+	}
+}
+
+func TestPushOutbound_RewritesIssueBody(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	ghIssue, _ := gh.CreateIssue(ctx, repo.Owner, repo.Name, "Body Test", "old description", []string{"boxofrocks"})
+	ghNum := ghIssue.Number
+	created, err := s.CreateIssue(ctx, &model.Issue{
+		RepoID:      repo.ID,
+		GitHubID:    &ghNum,
+		Title:       "Body Test",
+		Description: "new description",
+		Status:      model.StatusInProgress,
+		Priority:    1,
+		IssueType:   model.IssueTypeBug,
+		Owner:       "agent-1",
+		Labels:      []string{},
+	})
+	if err != nil {
+		t.Fatalf("create issue: %v", err)
+	}
+
+	payload, _ := json.Marshal(model.EventPayload{Description: "new description"})
+	for _, ev := range []*model.Event{
+		{Action: model.ActionUpdate, Payload: string(payload)},
+		{Action: model.ActionStatusChange, Payload: makeStatusChangePayload(model.StatusInProgress)},
+	} {
+		ev.RepoID = repo.ID
+		ev.IssueID = created.ID
+		ev.Timestamp = time.Now().UTC()
+		ev.Agent = "agent-1"
+		if _, err := s.AppendEvent(ctx, ev); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("pushOutbound: %v", err)
+	}
+
+	meta, humanText, err := github.ParseMetadata(gh.issues[gh.repoKey(repo.Owner, repo.Name)][0].Body)
+	if err != nil {
+		t.Fatalf("parse body: %v", err)
+	}
+	if humanText != "new description" {
+		t.Errorf("expected body text %q, got %q", "new description", humanText)
+	}
+	if meta == nil {
+		t.Fatal("expected metadata block in rewritten body")
+	}
+	if meta.Status != "in_progress" || meta.Owner != "agent-1" || meta.Priority != 1 || meta.IssueType != "bug" {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+}
+
+func TestPushOutbound_SkipsUnchangedBody(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	ghIssue, _ := gh.CreateIssue(ctx, repo.Owner, repo.Name, "Body Test", "text", []string{"boxofrocks"})
+	ghNum := ghIssue.Number
+	created, _ := s.CreateIssue(ctx, &model.Issue{
+		RepoID: repo.ID, GitHubID: &ghNum, Title: "Body Test", Description: "text",
+		Status: model.StatusOpen, IssueType: model.IssueTypeTask, Labels: []string{},
+	})
+
+	appendStatus := func(status model.Status) {
+		if _, err := s.AppendEvent(ctx, &model.Event{
+			RepoID: repo.ID, IssueID: created.ID, Timestamp: time.Now().UTC(),
+			Action: model.ActionStatusChange, Payload: makeStatusChangePayload(status), Agent: "agent-1",
+		}); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+
+	// Two field changes in one push still cost a single body write.
+	created.Status = model.StatusInProgress
+	if err := s.UpdateIssue(ctx, created); err != nil {
+		t.Fatalf("update issue: %v", err)
+	}
+	appendStatus(model.StatusInProgress)
+	appendStatus(model.StatusInProgress)
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("pushOutbound: %v", err)
+	}
+	if gh.bodyUpdates != 1 {
+		t.Fatalf("expected 1 body update, got %d", gh.bodyUpdates)
+	}
+	meta, _, err := github.ParseMetadata(gh.issues[gh.repoKey(repo.Owner, repo.Name)][0].Body)
+	if err != nil || meta == nil || meta.Status != "in_progress" {
+		t.Fatalf("expected in_progress status block, got %+v (%v)", meta, err)
+	}
+
+	// A later event that leaves the rendered body unchanged is not written.
+	appendStatus(model.StatusInProgress)
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("second pushOutbound: %v", err)
+	}
+	if gh.bodyUpdates != 1 {
+		t.Errorf("expected unchanged body to be skipped, got %d updates", gh.bodyUpdates)
+	}
+}
+
+func TestPushOutbound_EpicRollup(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		s, gh, repo := setupTest(t)
+		ctx := context.Background()
+
+		repo.EpicRollup = enabled
+		if err := s.UpdateRepo(ctx, repo); err != nil {
+			t.Fatalf("update repo: %v", err)
+		}
+
+		ghEpic, _ := gh.CreateIssue(ctx, repo.Owner, repo.Name, "Epic", "", []string{"boxofrocks"})
+		epicNum := ghEpic.Number
+		epic, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, GitHubID: &epicNum, Title: "Epic", IssueType: model.IssueTypeEpic})
+
+		child, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Child task", ParentID: &epic.ID})
+		s.AppendEvent(ctx, &model.Event{
+			RepoID: repo.ID, IssueID: child.ID, Timestamp: time.Now().UTC(),
+			Action: model.ActionCreate, Payload: makeCreatePayload("Child task", ""), Agent: "agent-1",
+		})
+
+		sm := NewSyncManager(s, gh)
+		rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+		if _, err := rs.pushOutbound(ctx); err != nil {
+			t.Fatalf("pushOutbound: %v", err)
+		}
+
+		pushed, _ := s.GetIssue(ctx, child.ID)
+		if pushed.GitHubID == nil {
+			t.Fatal("expected child to be pushed")
+		}
+		var rollups []string
+		for _, c := range gh.createdComments {
+			if c.Number == epicNum {
+				rollups = append(rollups, c.Body)
+			}
+		}
+		if !enabled {
+			if len(rollups) != 0 {
+				t.Errorf("epic_rollup off: expected no comments on the epic, got %v", rollups)
+			}
+			continue
+		}
+		want := fmt.Sprintf("- [ ] #%d Child task", *pushed.GitHubID)
+		if len(rollups) != 1 || rollups[0] != want {
+			t.Errorf("expected rollup %q on the epic, got %v", want, rollups)
+		}
+	}
+}
+
+func TestDropLocalLinks(t *testing.T) {
+	ev := &model.Event{Payload: `{"title":"t","parent_id":7,"blocker_id":3,"future_field":1}`}
+	dropLocalLinks(ev)
+	if strings.Contains(ev.Payload, "parent_id") {
+		t.Errorf("parent_id not dropped: %s", ev.Payload)
+	}
+	if strings.Contains(ev.Payload, "blocker_id") {
+		t.Errorf("blocker_id not dropped: %s", ev.Payload)
+	}
+	if !strings.Contains(ev.Payload, "future_field") || !strings.Contains(ev.Payload, `"title":"t"`) {
+		t.Errorf("other fields lost: %s", ev.Payload)
+	}
+}
+
+// failMarkStore fails the next failMarks calls to MarkEventSynced.
+type failMarkStore struct {
+	store.Store
+	failMarks int
+}
+
+func (f *failMarkStore) MarkEventSynced(ctx context.Context, eventID, commentID int) error {
+	if f.failMarks > 0 {
+		f.failMarks--
+		return errors.New("database is locked")
+	}
+	return f.Store.MarkEventSynced(ctx, eventID, commentID)
+}
+
+func TestPushOutbound_MarkSyncedFailureDoesNotRepost(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	ghIssue, _ := gh.CreateIssue(ctx, repo.Owner, repo.Name, "Retry Test", "", []string{"boxofrocks"})
+	ghNum := ghIssue.Number
+	created, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, GitHubID: &ghNum, Title: "Retry Test"})
+	payload, _ := json.Marshal(model.EventPayload{Comment: "progress"})
+	ev, _ := s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: created.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionComment, Payload: string(payload), Agent: "agent-1",
+	})
+
+	fs := &failMarkStore{Store: s, failMarks: 1}
+	sm := NewSyncManager(fs, gh)
+	rs := newRepoSyncer(repo, fs, gh, sm, 5*time.Second)
+
+	if _, err := rs.pushOutbound(ctx); err == nil {
+		t.Fatal("expected first push to fail on MarkEventSynced")
+	}
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("second push: %v", err)
+	}
+
+	posted := 0
+	for _, c := range gh.createdComments {
+		if c.Number == ghNum {
+			posted++
+		}
+	}
+	if posted != 1 {
+		t.Errorf("expected the event to be posted once, got %d comments", posted)
+	}
+
+	pending, _ := s.PendingEvents(ctx, repo.ID)
+	for _, p := range pending {
+		if p.ID == ev.ID {
+			t.Error("expected event to be marked synced after retry")
+		}
+	}
+}
+
+func TestPushOutbound_PostedHashSurvivesRestart(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	ghIssue, _ := gh.CreateIssue(ctx, repo.Owner, repo.Name, "Restart Test", "", []string{"boxofrocks"})
+	ghNum := ghIssue.Number
+	created, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, GitHubID: &ghNum, Title: "Restart Test"})
+	payload, _ := json.Marshal(model.EventPayload{Comment: "progress"})
+	s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: created.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionComment, Payload: string(payload), Agent: "agent-1",
+	})
+
+	fs := &failMarkStore{Store: s, failMarks: 1}
+	rs := newRepoSyncer(repo, fs, gh, NewSyncManager(fs, gh), 5*time.Second)
+	if _, err := rs.pushOutbound(ctx); err == nil {
+		t.Fatal("expected first push to fail on MarkEventSynced")
+	}
+
+	// A fresh syncer has no in-memory record of the post.
+	rs = newRepoSyncer(repo, s, gh, NewSyncManager(s, gh), 5*time.Second)
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("second push: %v", err)
+	}
+
+	if len(gh.createdComments) != 1 {
+		t.Errorf("expected the event to be posted once, got %d comments", len(gh.createdComments))
+	}
+	if pending, _ := s.PendingEvents(ctx, repo.ID); len(pending) != 0 {
+		t.Errorf("expected no pending events, got %d", len(pending))
+	}
+}
+
+func TestPushOutbound_AdoptsUnrecordedGitHubComment(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	ghIssue, _ := gh.CreateIssue(ctx, repo.Owner, repo.Name, "Adopt Test", "", []string{"boxofrocks"})
+	ghNum := ghIssue.Number
+	created, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, GitHubID: &ghNum, Title: "Adopt Test"})
+	payload, _ := json.Marshal(model.EventPayload{Comment: "progress"})
+	ev, _ := s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: created.ID, Timestamp: time.Now().UTC().Add(-time.Hour),
+		Action: model.ActionComment, Payload: string(payload), Agent: "agent-1",
+	})
+
+	// An earlier push posted the comment but crashed before recording it.
+	gh.addGitHubComment(repo.Owner, repo.Name, ghNum, &github.GitHubComment{
+		ID: 777, Body: github.FormatEventComment(ev), CreatedAt: time.Now().UTC().Add(-30 * time.Minute),
+	})
+
+	rs := newRepoSyncer(repo, s, gh, NewSyncManager(s, gh), 5*time.Second)
+	rs.lastPushAt = time.Now().UTC().Add(-10 * time.Minute)
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("pushOutbound: %v", err)
+	}
+
+	if len(gh.createdComments) != 0 {
+		t.Errorf("expected no new comments, got %d", len(gh.createdComments))
+	}
+	events, _ := s.ListEvents(ctx, repo.ID, created.ID)
+	for _, e := range events {
+		if e.ID == ev.ID && (e.GitHubCommentID == nil || *e.GitHubCommentID != 777) {
+			t.Errorf("expected event linked to comment 777, got %v", e.GitHubCommentID)
+		}
+	}
+}
+
+func TestPushOutbound_CommentLeavesBody(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	ghIssue, _ := gh.CreateIssue(ctx, repo.Owner, repo.Name, "Comment Test", "original body", []string{"boxofrocks"})
+	ghNum := ghIssue.Number
+	created, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, GitHubID: &ghNum, Title: "Comment Test", Description: "changed locally"})
+
+	payload, _ := json.Marshal(model.EventPayload{Comment: "just a note"})
+	s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: created.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionComment, Payload: string(payload), Agent: "agent-1",
+	})
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("pushOutbound: %v", err)
+	}
+
+	if body := gh.issues[gh.repoKey(repo.Owner, repo.Name)][0].Body; body != "original body" {
+		t.Errorf("expected comment push to leave body untouched, got %q", body)
+	}
+}
+
+func TestPushOutbound_SetsIssueState(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	ghIssue, _ := gh.CreateIssue(ctx, repo.Owner, repo.Name, "State Test", "", []string{"boxofrocks"})
+	ghNum := ghIssue.Number
+	created, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, GitHubID: &ghNum, Title: "State Test", Status: model.StatusClosed})
+
+	appendClose := func() {
+		payload, _ := json.Marshal(model.EventPayload{FromStatus: model.StatusOpen})
+		if _, err := s.AppendEvent(ctx, &model.Event{
+			RepoID: repo.ID, IssueID: created.ID, Timestamp: time.Now().UTC(),
+			Action: model.ActionClose, Payload: string(payload), Agent: "agent-1",
+		}); err != nil {
+			t.Fatalf("append close event: %v", err)
+		}
+	}
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+	appendClose()
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("pushOutbound: %v", err)
+	}
+	if len(gh.stateUpdates) != 1 || gh.stateUpdates[0] != "closed" {
+		t.Fatalf("expected one UpdateIssueState(closed) call, got %q", gh.stateUpdates)
+	}
+	if state := gh.issues[gh.repoKey(repo.Owner, repo.Name)][0].State; state != "closed" {
+		t.Errorf("expected github issue closed, got %q", state)
+	}
+
+	// The GitHub issue is known to be closed, so a second close is not sent.
+	appendClose()
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("second pushOutbound: %v", err)
+	}
+	if len(gh.stateUpdates) != 1 {
+		t.Errorf("expected no redundant UpdateIssueState call, got %q", gh.stateUpdates)
+	}
+
+	// The next pull sees GitHub closed too, so the issue is not reopened.
+	if _, err := rs.pullInbound(ctx); err != nil {
+		t.Fatalf("pullInbound: %v", err)
+	}
+	if got, _ := s.GetIssue(ctx, created.ID); got.Status != model.StatusClosed {
+		t.Errorf("expected issue to stay closed after pull, got %s", got.Status)
+	}
+}
+
+func TestPushOutbound_AssignsGitHubIssue(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	repo.AssigneeLogins = map[string]string{"agent-1": "alice-gh", "agent-2": "bob-gh"}
+	if err := s.UpdateRepo(ctx, repo); err != nil {
+		t.Fatalf("update repo: %v", err)
+	}
+
+	ghIssue, _ := gh.CreateIssue(ctx, repo.Owner, repo.Name, "Assign Test", "", []string{"boxofrocks"})
+	ghIssue.Assignees = []github.GitHubUser{{Login: "bob-gh"}, {Login: "human"}}
+	ghNum := ghIssue.Number
+	created, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, GitHubID: &ghNum, Title: "Assign Test", Status: model.StatusOpen})
+
+	sm := NewSyncManager(s, gh)
+	rs := newRepoSyncer(repo, s, gh, sm, 5*time.Second)
+	// The syncer has seen the GitHub issue, assigned to bob-gh and a human.
+	rs.ghKnown[ghNum] = snapshotOf(ghIssue)
+
+	created.Owner = "agent-1"
+	if err := s.UpdateIssue(ctx, created); err != nil {
+		t.Fatalf("update issue: %v", err)
+	}
+	if _, err := s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: created.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionAssign, Payload: `{"owner":"agent-1"}`, Agent: "agent-1",
+	}); err != nil {
+		t.Fatalf("append assign event: %v", err)
+	}
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("pushOutbound: %v", err)
+	}
+
+	want := []string{"remove:bob-gh", "add:alice-gh"}
+	if !slices.Equal(gh.assigneeCalls, want) {
+		t.Errorf("expected assignee calls %q, got %q", want, gh.assigneeCalls)
+	}
+	var logins []string
+	for _, a := range gh.issues[gh.repoKey(repo.Owner, repo.Name)][0].Assignees {
+		logins = append(logins, a.Login)
+	}
+	if !slices.Equal(logins, []string{"human", "alice-gh"}) {
+		t.Errorf("expected assignees [human alice-gh], got %v", logins)
+	}
+
+	// An owner without a mapping unassigns the mapped login only.
+	created.Owner = "carol"
+	s.UpdateIssue(ctx, created)
+	s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: created.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionAssign, Payload: `{"owner":"carol"}`, Agent: "agent-1",
+	})
+	if _, err := rs.pushOutbound(ctx); err != nil {
+		t.Fatalf("second pushOutbound: %v", err)
+	}
+	if got := gh.assigneeCalls[len(gh.assigneeCalls)-1]; got != "remove:alice-gh" || len(gh.assigneeCalls) != 3 {
+		t.Errorf("expected a single remove:alice-gh call, got %q", gh.assigneeCalls)
+	}
+}
+
+func TestCycleLog_Bounded(t *testing.T) {
+	l := newCycleLog(3)
+	for i := 1; i <= 5; i++ {
+		l.add(CycleResult{Pushed: i})
+	}
+
+	got := l.list()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(got))
+	}
+	for i, want := range []int{3, 4, 5} {
+		if got[i].Pushed != want {
+			t.Errorf("entry %d: expected Pushed=%d, got %d", i, want, got[i].Pushed)
+		}
+	}
+}
+
+func TestSyncManager_CycleLogUnknownRepo(t *testing.T) {
+	s, gh, _ := setupTest(t)
+	sm := NewSyncManager(s, gh)
+
+	if _, err := sm.CycleLog(999); err == nil {
+		t.Error("expected error for repo that is not being synced")
+	}
+}
+
+func TestCommentFetcher_ReadsSyncedComment(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	comment := strings.Repeat("long comment text ", 100)
+	payload, _ := json.Marshal(model.EventPayload{Comment: comment})
+	body := github.FormatEventComment(&model.Event{
+		Timestamp: time.Now().UTC(),
+		Action:    model.ActionComment,
+		Payload:   string(payload),
+		Agent:     "agent-1",
+	})
+	created, err := gh.CreateComment(ctx, repo.Owner, repo.Name, 1, body)
+	if err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+
+	fetch := CommentFetcher(s, gh)
+	got, err := fetch(ctx, repo.ID, created.ID)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if got != comment {
+		t.Errorf("expected comment of length %d, got length %d", len(comment), len(got))
+	}
+
+	// A human comment ingested as is hydrates to its trimmed body.
+	human, err := gh.CreateComment(ctx, repo.Owner, repo.Name, 1, "\n"+comment+"\n")
+	if err != nil {
+		t.Fatalf("CreateComment human: %v", err)
+	}
+	if got, err := fetch(ctx, repo.ID, human.ID); err != nil || got != strings.TrimSpace(comment) {
+		t.Errorf("fetch human comment = %d bytes, %v; want the trimmed body", len(got), err)
+	}
+
+	if _, err := fetch(ctx, repo.ID, created.ID+100); err == nil {
+		t.Error("expected error for unknown comment id")
+	}
+}
+
+func TestForceSyncDryRun_NoGitHubWrites(t *testing.T) {
+	s, gh, repo := setupTest(t)
+	ctx := context.Background()
+
+	sm := NewSyncManager(s, gh)
+	defer sm.Stop()
+	if err := sm.AddRepo(repo); err != nil {
+		t.Fatalf("add repo: %v", err)
+	}
+	// Let the initial cycle finish, so the only GitHub calls after this
+	// point would come from the dry run.
+	time.Sleep(100 * time.Millisecond)
+
+	gh.mu.Lock()
+	labelCalls := len(gh.createLabelCalls)
+	gh.mu.Unlock()
+
+	// A new local issue, and a closed issue already on GitHub.
+	fresh, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, Title: "Fresh", Description: "desc", Status: model.StatusOpen, Labels: []string{"bug"}})
+	s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: fresh.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionCreate, Payload: makeCreatePayload("Fresh", "desc"), Agent: "test",
+	})
+	ghIssue, _ := gh.CreateIssue(ctx, repo.Owner, repo.Name, "Existing", "", []string{"boxofrocks"})
+	ghNum := ghIssue.Number
+	existing, _ := s.CreateIssue(ctx, &model.Issue{RepoID: repo.ID, GitHubID: &ghNum, Title: "Existing", Status: model.StatusClosed})
+	s.AppendEvent(ctx, &model.Event{
+		RepoID: repo.ID, IssueID: existing.ID, Timestamp: time.Now().UTC(),
+		Action: model.ActionClose, Payload: `{"from_status":"open"}`, Agent: "test",
+	})
+
+	report, err := sm.ForceSyncDryRun(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+
+	var ops []string
+	for _, op := range report.Operations {
+		ops = append(ops, op.Op)
+	}
+	want := []string{OpCreateIssue, OpCreateComment, OpCreateComment, OpUpdateIssueBody, OpUpdateIssueState}
+	if !slices.Equal(ops, want) {
+		t.Fatalf("expected operations %q, got %q", want, ops)
+	}
+	if report.PendingEvents != 2 {
+		t.Errorf("expected 2 pending events, got %d", report.PendingEvents)
+	}
+	create := report.Operations[0]
+	if create.Title != "Fresh" || create.Body != "desc" || !slices.Equal(create.Labels, []string{"boxofrocks", "bug"}) {
+		t.Errorf("unexpected create_issue arguments: %+v", create)
+	}
+	if report.Operations[1].GitHubNumber != 0 {
+		t.Errorf("expected no github number for the issue to create, got %d", report.Operations[1].GitHubNumber)
+	}
+	if op := report.Operations[4]; op.GitHubNumber != ghNum || op.State != "closed" {
+		t.Errorf("expected update_issue_state(#%d, closed), got %+v", ghNum, op)
+	}
+
+	gh.mu.Lock()
+	// The one CreateIssue is the test's own setup call.
+	writes := len(gh.createdIssues) - 1 + len(gh.createdComments) + len(gh.stateUpdates) +
+		gh.bodyUpdates + len(gh.assigneeCalls) + len(gh.createLabelCalls) - labelCalls
+	gh.mu.Unlock()
+	if writes != 0 {
+		t.Errorf("expected no GitHub writes in a dry run, got %d", writes)
+	}
+
+	pending, _ := s.PendingEvents(ctx, repo.ID)
+	if len(pending) != 2 {
+		t.Errorf("expected both events still pending, got %d", len(pending))
+	}
+	if got, _ := s.GetIssue(ctx, fresh.ID); got.GitHubID != nil {
+		t.Errorf("expected no github_id on the fresh issue, got %d", *got.GitHubID)
+	}
+
+	if _, err := sm.ForceSyncDryRun(ctx, 9999); err == nil {
+		t.Error("expected error for repo that is not being synced")
+	}
+}